@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/pocketping/bridge-server/internal/api"
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// deliveryRecorder matches the messages widgets send with the ones bridges
+// receive, by content.
+type deliveryRecorder struct {
+	mu      sync.Mutex
+	sentAt  map[string]time.Time
+	lags    []time.Duration
+	pending int
+	done    chan struct{}
+}
+
+func newDeliveryRecorder() *deliveryRecorder {
+	return &deliveryRecorder{sentAt: make(map[string]time.Time), done: make(chan struct{}, 1)}
+}
+
+// sent notes a message about to be sent.
+func (r *deliveryRecorder) sent(content string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sentAt[content] = time.Now()
+	r.pending++
+}
+
+// forget drops a message whose send failed.
+func (r *deliveryRecorder) forget(content string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sentAt[content]; ok {
+		delete(r.sentAt, content)
+		r.pending--
+	}
+}
+
+// delivered notes a message reaching a bridge.
+func (r *deliveryRecorder) delivered(content string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sentAt, ok := r.sentAt[content]
+	if !ok {
+		return
+	}
+	delete(r.sentAt, content)
+	r.lags = append(r.lags, time.Since(sentAt))
+	r.pending--
+	if r.pending == 0 {
+		select {
+		case r.done <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wait blocks until every sent message was delivered, or timeout.
+func (r *deliveryRecorder) wait(timeout time.Duration) {
+	r.mu.Lock()
+	pending := r.pending
+	r.mu.Unlock()
+	if pending == 0 {
+		return
+	}
+	select {
+	case <-r.done:
+	case <-time.After(timeout):
+	}
+}
+
+// results returns the delivery lags and the number of undelivered messages.
+func (r *deliveryRecorder) results() ([]time.Duration, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Duration(nil), r.lags...), r.pending
+}
+
+// startInProcess serves target on a local test server, with a bridge
+// reporting to recorder. stop shuts it down.
+func startInProcess(target string, recorder *deliveryRecorder) (string, func(), error) {
+	var handler http.Handler
+	switch target {
+	case "sdk":
+		pp := pocketping.New(pocketping.Config{
+			Bridges: []pocketping.Bridge{&sdkRecordingBridge{BaseBridge: pocketping.BaseBridge{BridgeName: "loadtest"}, recorder: recorder}},
+		})
+		handler = sdkHandler(pp)
+	case "bridge-server":
+		server := api.NewServer([]bridges.Bridge{&recordingBridge{BaseBridge: bridges.NewBaseBridge("loadtest"), recorder: recorder}}, &config.Config{})
+		mux := http.NewServeMux()
+		server.SetupRoutes(mux)
+		handler = mux
+	default:
+		return "", nil, fmt.Errorf("unknown target %q (want sdk or bridge-server)", target)
+	}
+
+	// The handlers log per request; keep the report readable
+	logOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	srv := httptest.NewServer(handler)
+	return srv.URL, func() {
+		srv.Close()
+		log.SetOutput(logOutput)
+	}, nil
+}
+
+// sdkHandler serves the widget endpoints the way an application embedding
+// the SDK does.
+func sdkHandler(pp *pocketping.PocketPing) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /connect", sdkEndpoint(pp.HandleConnect))
+	mux.HandleFunc("POST /message", sdkEndpoint(pp.HandleVisitorMessage))
+	mux.HandleFunc("POST /typing", sdkEndpoint(func(ctx context.Context, req pocketping.TypingRequest) (interface{}, error) {
+		return map[string]bool{"ok": true}, pp.HandleTyping(ctx, req)
+	}))
+	mux.HandleFunc("POST /read", sdkEndpoint(pp.HandleRead))
+	return mux
+}
+
+func sdkEndpoint[Req, Resp any](handle func(context.Context, Req) (Resp, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			pocketping.WriteError(w, pocketping.ErrInvalidJSON)
+			return
+		}
+		resp, err := handle(r.Context(), req)
+		if err != nil {
+			pocketping.WriteError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// sdkRecordingBridge is an SDK bridge reporting visitor messages.
+type sdkRecordingBridge struct {
+	pocketping.BaseBridge
+	recorder *deliveryRecorder
+}
+
+func (b *sdkRecordingBridge) OnVisitorMessage(ctx context.Context, message *pocketping.Message, session *pocketping.Session) error {
+	b.recorder.delivered(message.Content)
+	return nil
+}
+
+// recordingBridge is a bridge-server bridge reporting visitor messages.
+type recordingBridge struct {
+	*bridges.BaseBridge
+	recorder *deliveryRecorder
+}
+
+func (b *recordingBridge) OnNewSession(ctx context.Context, session *types.Session) error {
+	return nil
+}
+
+func (b *recordingBridge) OnVisitorMessage(ctx context.Context, message *types.Message, session *types.Session, reply *bridges.ReplyContext) (*types.BridgeMessageIDs, error) {
+	b.recorder.delivered(message.Content)
+	return nil, nil
+}
+
+func (b *recordingBridge) OnOperatorMessage(ctx context.Context, message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
+	return nil
+}
+
+func (b *recordingBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
+	return nil
+}
+
+func (b *recordingBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status types.MessageStatus) error {
+	return nil
+}
+
+func (b *recordingBridge) OnCustomEvent(ctx context.Context, event *types.CustomEvent, session *types.Session) error {
+	return nil
+}
+
+func (b *recordingBridge) OnIdentityUpdate(ctx context.Context, session *types.Session) error {
+	return nil
+}
+
+func (b *recordingBridge) OnAITakeover(ctx context.Context, session *types.Session, reason string) error {
+	return nil
+}
+
+func (b *recordingBridge) OnVisitorMessageEdited(ctx context.Context, sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
+	return nil, nil
+}
+
+func (b *recordingBridge) OnVisitorMessageDeleted(ctx context.Context, sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
+	return nil
+}
+
+func (b *recordingBridge) OnVisitorDisconnect(ctx context.Context, session *types.Session, message string) error {
+	return nil
+}
+
+// Ensure recordingBridge implements bridges.Bridge interface
+var _ bridges.Bridge = (*recordingBridge)(nil)
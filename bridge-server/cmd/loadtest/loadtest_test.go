@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sloOptions is a small run with release SLOs loose enough for CI machines:
+// every request succeeds, every message reaches the bridge, and p99 stays
+// well under a second.
+func sloOptions(target string) options {
+	return options{
+		Target:          target,
+		Widgets:         20,
+		Messages:        10,
+		Burst:           5,
+		Pause:           5 * time.Millisecond,
+		Typing:          true,
+		DeliveryTimeout: 5 * time.Second,
+		RequestTimeout:  5 * time.Second,
+		SLOP99:          500 * time.Millisecond,
+		SLOLagP99:       time.Second,
+	}
+}
+
+func TestLoadSLOs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+	for _, target := range []string{"sdk", "bridge-server"} {
+		t.Run(target, func(t *testing.T) {
+			opts := sloOptions(target)
+			rep, err := run(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			var out bytes.Buffer
+			rep.print(&out)
+			t.Log("\n" + out.String())
+
+			if violations := rep.checkSLOs(opts); len(violations) > 0 {
+				t.Errorf("SLOs missed: %s", strings.Join(violations, "; "))
+			}
+			if got := rep.summary(opMessage).Count; got != opts.Widgets*opts.Messages {
+				t.Errorf("messages = %d, want %d", got, opts.Widgets*opts.Messages)
+			}
+			if len(rep.lags) != opts.Widgets*opts.Messages {
+				t.Errorf("deliveries = %d", len(rep.lags))
+			}
+		})
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	opts := sloOptions("sdk")
+	opts.Widgets, opts.Messages = 1, 1
+	opts.URL = "http://127.0.0.1:1"
+	opts.RequestTimeout = time.Second
+	rep, err := run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if s := rep.summary(opConnect); s.Errors != 1 {
+		t.Errorf("connect = %+v", s)
+	}
+	if violations := rep.checkSLOs(opts); len(violations) != 1 || !strings.Contains(violations[0], "1 failed requests") {
+		t.Errorf("violations = %q", violations)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	s := summarize(latencies)
+	if s.P50 != 50*time.Millisecond || s.P90 != 90*time.Millisecond || s.P99 != 99*time.Millisecond || s.Max != 100*time.Millisecond {
+		t.Errorf("summary = %+v", s)
+	}
+	if s := summarize([]time.Duration{time.Second}); s.P50 != time.Second || s.P99 != time.Second {
+		t.Errorf("single = %+v", s)
+	}
+}
+
+func TestUnknownTarget(t *testing.T) {
+	opts := sloOptions("carrier-pigeon")
+	if _, err := run(context.Background(), opts); err == nil {
+		t.Error("expected an error")
+	}
+}
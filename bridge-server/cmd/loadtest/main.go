@@ -0,0 +1,181 @@
+// Command loadtest simulates concurrent chat widgets against the SDK HTTP
+// handlers or the bridge server, and reports request latency percentiles and
+// bridge delivery lag. Without -url it runs the target in-process behind a
+// recording bridge, so delivery lag is measured end to end.
+//
+// Usage:
+//
+//	go run ./cmd/loadtest -target sdk -widgets 200 -messages 20
+//	go run ./cmd/loadtest -target bridge-server -url http://localhost:3001 -api-key $API_KEY
+//
+// The exit code is 1 when a -slo-* threshold is exceeded or a request fails,
+// so the tool can gate a release pipeline.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// options configures a load test run.
+type options struct {
+	// Target is the protocol spoken: "sdk" (the widget endpoints /connect,
+	// /message, /typing and /read) or "bridge-server" (/api/sessions,
+	// /api/messages and /api/events).
+	Target string
+	// URL is the base URL of a running server; empty runs the target
+	// in-process.
+	URL    string
+	APIKey string
+
+	Widgets  int
+	Messages int
+	// Burst is how many messages a widget sends back to back, with Pause
+	// between bursts.
+	Burst int
+	Pause time.Duration
+	// Typing sends typing indicators around each burst.
+	Typing bool
+	// DeliveryTimeout is how long to wait for in-process bridges to receive
+	// every message after the last send.
+	DeliveryTimeout time.Duration
+	RequestTimeout  time.Duration
+
+	// SLOs, checked when non-zero
+	SLOP99      time.Duration
+	SLOLagP99   time.Duration
+	SLOMinRate  float64
+	SLOMaxError int
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.Target, "target", "sdk", "protocol to load: sdk or bridge-server")
+	flag.StringVar(&opts.URL, "url", "", "base URL of a running server (default: run the target in-process)")
+	flag.StringVar(&opts.APIKey, "api-key", os.Getenv("API_KEY"), "bridge-server API key")
+	flag.IntVar(&opts.Widgets, "widgets", 50, "concurrent widgets")
+	flag.IntVar(&opts.Messages, "messages", 20, "messages per widget")
+	flag.IntVar(&opts.Burst, "burst", 5, "messages per burst")
+	flag.DurationVar(&opts.Pause, "pause", 100*time.Millisecond, "pause between bursts")
+	flag.BoolVar(&opts.Typing, "typing", true, "send typing indicators around bursts")
+	flag.DurationVar(&opts.DeliveryTimeout, "delivery-timeout", 10*time.Second, "wait for bridge deliveries after the last send")
+	flag.DurationVar(&opts.RequestTimeout, "request-timeout", 10*time.Second, "per-request timeout")
+	flag.DurationVar(&opts.SLOP99, "slo-p99", 0, "fail if any operation's p99 latency exceeds this")
+	flag.DurationVar(&opts.SLOLagP99, "slo-lag-p99", 0, "fail if the p99 bridge delivery lag exceeds this (in-process only)")
+	flag.Float64Var(&opts.SLOMinRate, "slo-min-rate", 0, "fail below this many messages per second")
+	flag.IntVar(&opts.SLOMaxError, "slo-max-errors", 0, "fail above this many failed requests")
+	flag.Parse()
+
+	report, err := run(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(2)
+	}
+	report.print(os.Stdout)
+	if violations := report.checkSLOs(opts); len(violations) > 0 {
+		fmt.Println()
+		for _, v := range violations {
+			fmt.Printf("❌ SLO: %s\n", v)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("\n✅ SLOs met")
+}
+
+// run performs one load test and returns its report.
+func run(ctx context.Context, opts options) (*report, error) {
+	if opts.Widgets <= 0 || opts.Messages < 0 {
+		return nil, fmt.Errorf("widgets must be positive and messages non-negative")
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = 1
+	}
+
+	var recorder *deliveryRecorder
+	baseURL := opts.URL
+	if baseURL == "" {
+		recorder = newDeliveryRecorder()
+		url, stop, err := startInProcess(opts.Target, recorder)
+		if err != nil {
+			return nil, err
+		}
+		defer stop()
+		baseURL = url
+	}
+	t, err := newTarget(opts.Target, baseURL, opts.APIKey, opts.RequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := newReport(opts.Target, baseURL, recorder == nil)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Widgets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runWidget(ctx, t, opts, fmt.Sprintf("loadtest-%d", i), rep, recorder)
+		}(i)
+	}
+	wg.Wait()
+	rep.Elapsed = time.Since(start)
+
+	if recorder != nil {
+		recorder.wait(opts.DeliveryTimeout)
+		rep.setDeliveries(recorder)
+	}
+	return rep, nil
+}
+
+// runWidget plays one widget: connect, then bursts of messages wrapped in
+// typing indicators, each followed by a read receipt.
+func runWidget(ctx context.Context, t target, opts options, visitorID string, rep *report, recorder *deliveryRecorder) {
+	var sessionID string
+	err := rep.time(opConnect, func() (err error) {
+		sessionID, err = t.connect(ctx, visitorID)
+		return err
+	})
+	if err != nil {
+		return
+	}
+
+	for sent := 0; sent < opts.Messages; {
+		if opts.Typing && t.supportsTyping() {
+			_ = rep.time(opTyping, func() error { return t.typing(ctx, sessionID, true) })
+		}
+
+		var ids []string
+		for n := 0; n < opts.Burst && sent < opts.Messages; n++ {
+			content := fmt.Sprintf("%s message %d", visitorID, sent)
+			sent++
+			if recorder != nil {
+				recorder.sent(content)
+			}
+			var id string
+			err := rep.time(opMessage, func() (err error) {
+				id, err = t.message(ctx, sessionID, content)
+				return err
+			})
+			if err == nil {
+				ids = append(ids, id)
+				rep.addMessage()
+			} else if recorder != nil {
+				recorder.forget(content)
+			}
+		}
+
+		if opts.Typing && t.supportsTyping() {
+			_ = rep.time(opTyping, func() error { return t.typing(ctx, sessionID, false) })
+		}
+		if len(ids) > 0 {
+			_ = rep.time(opRead, func() error { return t.read(ctx, sessionID, ids) })
+		}
+		if sent < opts.Messages && opts.Pause > 0 {
+			time.Sleep(opts.Pause)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operations timed by the load test
+const (
+	opConnect = "connect"
+	opMessage = "message"
+	opTyping  = "typing"
+	opRead    = "read"
+)
+
+var operations = []string{opConnect, opMessage, opTyping, opRead}
+
+// latencySummary sums up the latencies of one operation.
+type latencySummary struct {
+	Count  int
+	Errors int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// report collects the results of a run.
+type report struct {
+	Target string
+	URL    string
+	// Remote is set for a running server, where delivery lag isn't known.
+	Remote  bool
+	Elapsed time.Duration
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	firstErr  map[string]error
+	messages  int
+
+	lags        []time.Duration
+	undelivered int
+}
+
+func newReport(target, url string, remote bool) *report {
+	return &report{
+		Target:    target,
+		URL:       url,
+		Remote:    remote,
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		firstErr:  make(map[string]error),
+	}
+}
+
+// time runs fn, recording its latency or error under op.
+func (r *report) time(op string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[op]++
+		if r.firstErr[op] == nil {
+			r.firstErr[op] = err
+		}
+		return err
+	}
+	r.latencies[op] = append(r.latencies[op], elapsed)
+	return nil
+}
+
+func (r *report) addMessage() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages++
+}
+
+func (r *report) setDeliveries(recorder *deliveryRecorder) {
+	r.lags, r.undelivered = recorder.results()
+}
+
+// summary returns the latency summary of op.
+func (r *report) summary(op string) latencySummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := summarize(r.latencies[op])
+	s.Errors = r.errors[op]
+	return s
+}
+
+// totalErrors returns the number of failed requests.
+func (r *report) totalErrors() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0
+	for _, n := range r.errors {
+		total += n
+	}
+	return total
+}
+
+// messageRate returns the messages sent per second.
+func (r *report) messageRate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.messages) / r.Elapsed.Seconds()
+}
+
+func summarize(latencies []time.Duration) latencySummary {
+	if len(latencies) == 0 {
+		return latencySummary{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return latencySummary{
+		Count: len(sorted),
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (r *report) print(w io.Writer) {
+	where := r.URL
+	if !r.Remote {
+		where = "in-process"
+	}
+	fmt.Fprintf(w, "PocketPing load test: %s (%s)\n", r.Target, where)
+	fmt.Fprintf(w, "%d messages in %s (%.1f msg/s)\n\n", r.messages, r.Elapsed.Round(time.Millisecond), r.messageRate())
+
+	fmt.Fprintf(w, "%-10s %7s %7s %10s %10s %10s %10s\n", "operation", "count", "errors", "p50", "p90", "p99", "max")
+	for _, op := range operations {
+		s := r.summary(op)
+		if s.Count == 0 && s.Errors == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%-10s %7d %7d %10s %10s %10s %10s\n", op, s.Count, s.Errors, round(s.P50), round(s.P90), round(s.P99), round(s.Max))
+	}
+
+	if r.Remote {
+		fmt.Fprintln(w, "\nbridge delivery lag: n/a (remote target)")
+	} else {
+		lag := summarize(r.lags)
+		fmt.Fprintf(w, "\nbridge delivery lag: p50 %s, p90 %s, p99 %s, max %s (%d delivered, %d undelivered)\n",
+			round(lag.P50), round(lag.P90), round(lag.P99), round(lag.Max), lag.Count, r.undelivered)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, op := range operations {
+		if err := r.firstErr[op]; err != nil {
+			fmt.Fprintf(w, "first %s error: %v\n", op, err)
+		}
+	}
+}
+
+// checkSLOs returns the SLOs of opts the run missed.
+func (r *report) checkSLOs(opts options) []string {
+	var violations []string
+	if errs := r.totalErrors(); errs > opts.SLOMaxError {
+		violations = append(violations, fmt.Sprintf("%d failed requests (max %d)", errs, opts.SLOMaxError))
+	}
+	if opts.SLOP99 > 0 {
+		for _, op := range operations {
+			if s := r.summary(op); s.P99 > opts.SLOP99 {
+				violations = append(violations, fmt.Sprintf("%s p99 %s > %s", op, round(s.P99), opts.SLOP99))
+			}
+		}
+	}
+	if !r.Remote {
+		if r.undelivered > 0 {
+			violations = append(violations, fmt.Sprintf("%d messages never reached the bridge", r.undelivered))
+		}
+		if opts.SLOLagP99 > 0 {
+			if lag := summarize(r.lags); lag.P99 > opts.SLOLagP99 {
+				violations = append(violations, fmt.Sprintf("delivery lag p99 %s > %s", round(lag.P99), opts.SLOLagP99))
+			}
+		}
+	}
+	if opts.SLOMinRate > 0 && r.messageRate() < opts.SLOMinRate {
+		violations = append(violations, fmt.Sprintf("%.1f msg/s < %.1f msg/s", r.messageRate(), opts.SLOMinRate))
+	}
+	return violations
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
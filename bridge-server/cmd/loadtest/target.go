@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// target is the server protocol a widget speaks.
+type target interface {
+	// connect opens a session for visitorID and returns its ID.
+	connect(ctx context.Context, visitorID string) (string, error)
+	// message sends a visitor message and returns its ID.
+	message(ctx context.Context, sessionID, content string) (string, error)
+	typing(ctx context.Context, sessionID string, isTyping bool) error
+	read(ctx context.Context, sessionID string, messageIDs []string) error
+	supportsTyping() bool
+}
+
+func newTarget(name, baseURL, apiKey string, timeout time.Duration) (target, error) {
+	c := &httpClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+	switch name {
+	case "sdk":
+		return &sdkTarget{c}, nil
+	case "bridge-server":
+		return &bridgeServerTarget{httpClient: c, sessions: make(map[string]*types.Session)}, nil
+	}
+	return nil, fmt.Errorf("unknown target %q (want sdk or bridge-server)", name)
+}
+
+// httpClient posts JSON to a base URL.
+type httpClient struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// post sends payload to path and decodes the response into out, if not nil.
+func (c *httpClient) post(ctx context.Context, path string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PocketPing-LoadTest/1.0")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("POST %s: status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// sdkTarget speaks the widget protocol of the SDK HTTP handlers.
+type sdkTarget struct {
+	*httpClient
+}
+
+func (t *sdkTarget) connect(ctx context.Context, visitorID string) (string, error) {
+	var resp pocketping.ConnectResponse
+	err := t.post(ctx, "/connect", pocketping.ConnectRequest{
+		VisitorID: visitorID,
+		Metadata:  &pocketping.SessionMetadata{URL: "https://example.com/loadtest"},
+	}, &resp)
+	return resp.SessionID, err
+}
+
+func (t *sdkTarget) message(ctx context.Context, sessionID, content string) (string, error) {
+	var resp pocketping.SendMessageResponse
+	err := t.post(ctx, "/message", pocketping.SendMessageRequest{
+		SessionID: sessionID,
+		Content:   content,
+		Sender:    pocketping.SenderVisitor,
+	}, &resp)
+	return resp.MessageID, err
+}
+
+func (t *sdkTarget) typing(ctx context.Context, sessionID string, isTyping bool) error {
+	return t.post(ctx, "/typing", pocketping.TypingRequest{
+		SessionID: sessionID,
+		Sender:    pocketping.SenderVisitor,
+		IsTyping:  isTyping,
+	}, nil)
+}
+
+func (t *sdkTarget) read(ctx context.Context, sessionID string, messageIDs []string) error {
+	return t.post(ctx, "/read", pocketping.ReadRequest{
+		SessionID:  sessionID,
+		MessageIDs: messageIDs,
+		Status:     pocketping.MessageStatusRead,
+	}, nil)
+}
+
+func (t *sdkTarget) supportsTyping() bool { return true }
+
+// bridgeServerTarget speaks the backend protocol of the bridge server: the
+// widget's backend posts sessions and messages it created itself.
+type bridgeServerTarget struct {
+	*httpClient
+
+	mu       sync.Mutex
+	sessions map[string]*types.Session
+}
+
+func (t *bridgeServerTarget) register(session *types.Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions[session.ID] = session
+}
+
+func (t *bridgeServerTarget) session(id string) *types.Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[id]
+}
+
+func (t *bridgeServerTarget) connect(ctx context.Context, visitorID string) (string, error) {
+	now := time.Now()
+	session := &types.Session{
+		ID:           "lt-" + randomID(),
+		VisitorID:    visitorID,
+		CreatedAt:    now,
+		LastActivity: now,
+		Metadata:     &types.SessionMetadata{URL: "https://example.com/loadtest"},
+	}
+	if err := t.post(ctx, "/api/sessions", session, nil); err != nil {
+		return "", err
+	}
+	t.register(session)
+	return session.ID, nil
+}
+
+func (t *bridgeServerTarget) message(ctx context.Context, sessionID, content string) (string, error) {
+	message := &types.Message{
+		ID:        "lt-" + randomID(),
+		SessionID: sessionID,
+		Content:   content,
+		Sender:    types.SenderVisitor,
+		Timestamp: time.Now(),
+	}
+	err := t.post(ctx, "/api/messages", map[string]interface{}{
+		"message": message,
+		"session": t.session(sessionID),
+	}, nil)
+	return message.ID, err
+}
+
+func (t *bridgeServerTarget) typing(ctx context.Context, sessionID string, isTyping bool) error {
+	return nil
+}
+
+func (t *bridgeServerTarget) read(ctx context.Context, sessionID string, messageIDs []string) error {
+	return t.post(ctx, "/api/events", types.MessageReadEvent{
+		Type:       events.TypeMessageRead,
+		SessionID:  sessionID,
+		MessageIDs: messageIDs,
+		Status:     types.StatusRead,
+	}, nil)
+}
+
+// supportsTyping is false: the bridge server has no visitor typing endpoint.
+func (t *bridgeServerTarget) supportsTyping() bool { return false }
+
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+const usage = `Usage: server [command] [flags]
+
+Commands:
+  serve               Run the bridge server (default)
+  validate            Check the configuration and each bridge's credentials
+  send-test           Post a test message (--bridge telegram|discord|slack, default all)
+  register-webhooks   Point the platform webhooks at this server (--url https://...)
+  preview             Render every notification against sample sessions and lint
+                      it against platform limits, without sending (--bridge, --json)
+`
+
+// cliTimeout bounds the platform API calls of a subcommand.
+const cliTimeout = 30 * time.Second
+
+// runCommand runs a subcommand and returns the process exit code.
+func runCommand(name string, args []string) int {
+	var err error
+	switch name {
+	case "validate":
+		err = runValidate(args)
+	case "send-test":
+		err = runSendTest(args)
+	case "register-webhooks":
+		err = runRegisterWebhooks(args)
+	case "preview":
+		err = runPreview(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runValidate reports configuration problems, then checks each bridge's
+// credentials against its platform.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	offline := fs.Bool("offline", false, "only check the configuration, without calling the platform APIs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := config.Load()
+	failed := 0
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			failed += len(invalid.Problems)
+		}
+	} else {
+		fmt.Println("✅ Configuration OK")
+	}
+	if *offline {
+		return problemsError(failed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+	for _, bridge := range initBridges(cfg) {
+		checker, ok := bridge.(bridges.Checker)
+		if !ok {
+			continue
+		}
+		detail, err := checker.Check(ctx)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", bridge.Name(), err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s: %s\n", bridge.Name(), detail)
+	}
+	return problemsError(failed)
+}
+
+func problemsError(n int) error {
+	if n == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d problem(s) found", n)
+}
+
+// runSendTest posts a test message through the selected bridges.
+func runSendTest(args []string) error {
+	fs := flag.NewFlagSet("send-test", flag.ContinueOnError)
+	only := fs.String("bridge", "", "bridge to test: telegram, discord or slack (default all configured)")
+	message := fs.String("message", "✅ PocketPing test message from the bridge server", "text to send")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	selected, err := selectBridges(config.Load(), *only)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	// A visitor message goes to the bridge's channel in every mode, while
+	// the disconnect notice needs a thread this session doesn't have
+	session := &types.Session{ID: "send-test", VisitorID: "send-test"}
+	failed := 0
+	for _, bridge := range selected {
+		msg := &types.Message{ID: "send-test", SessionID: session.ID, Content: *message, Sender: types.SenderVisitor, Timestamp: time.Now()}
+		if _, err := bridge.OnVisitorMessage(ctx, msg, session, nil); err != nil {
+			fmt.Printf("❌ %s: %v\n", bridge.Name(), err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s: test message sent\n", bridge.Name())
+	}
+	return problemsError(failed)
+}
+
+// runRegisterWebhooks points each bridge's platform webhook at --url.
+func runRegisterWebhooks(args []string) error {
+	fs := flag.NewFlagSet("register-webhooks", flag.ContinueOnError)
+	baseURL := fs.String("url", "", "public base URL of this server, like https://bridge.example.com (required)")
+	only := fs.String("bridge", "", "bridge to register: telegram, discord or slack (default all configured)")
+	slackAppID := fs.String("slack-app-id", os.Getenv("SLACK_APP_ID"), "Slack app ID (SLACK_APP_ID)")
+	slackConfigToken := fs.String("slack-config-token", os.Getenv("SLACK_APP_CONFIG_TOKEN"), "Slack app configuration token (SLACK_APP_CONFIG_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(*baseURL, "https://") {
+		return errors.New("--url must be the public https:// URL of this server (platforms only deliver webhooks over HTTPS)")
+	}
+
+	selected, err := selectBridges(config.Load(), *only)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	failed := 0
+	for _, bridge := range selected {
+		var url string
+		var err error
+		switch b := bridge.(type) {
+		case bridges.WebhookRegistrar:
+			url, err = b.RegisterWebhook(ctx, *baseURL)
+		case *bridges.SlackBridge:
+			url = bridges.WebhookURL(*baseURL, "slack")
+			if *slackAppID == "" || *slackConfigToken == "" {
+				fmt.Printf("ℹ️  slack: set Event Subscriptions and Interactivity to %s in your app settings, or pass --slack-app-id and --slack-config-token to do it automatically\n", url)
+				continue
+			}
+			url, err = b.RegisterSlackWebhooks(ctx, *slackAppID, *slackConfigToken, *baseURL)
+		case *bridges.DiscordBridge:
+			fmt.Println("ℹ️  discord: nothing to register; replies arrive through the Gateway (DISCORD_ENABLE_GATEWAY=true with bot mode)")
+			continue
+		default:
+			continue
+		}
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", bridge.Name(), err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s: webhook set to %s\n", bridge.Name(), url)
+	}
+	return problemsError(failed)
+}
+
+// runPreview renders the selected bridges' notifications against
+// bridges.DefaultPreviewSamples and reports the payloads that break a
+// platform constraint. Nothing is sent.
+func runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	only := fs.String("bridge", "", "bridge to preview: telegram, discord or slack (default all configured)")
+	sample := fs.String("sample", "", "sample to render (default all): basic, identified, markup or long")
+	asJSON := fs.Bool("json", false, "print the previews as JSON")
+	problemsOnly := fs.Bool("problems", false, "only print payloads with problems")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	selected, err := selectBridges(config.Load(), *only)
+	if err != nil {
+		return err
+	}
+	samples := bridges.DefaultPreviewSamples()
+	if *sample != "" {
+		var names []string
+		for _, s := range samples {
+			names = append(names, s.Name)
+			if s.Name == *sample {
+				samples = []bridges.PreviewSample{s}
+			}
+		}
+		if len(samples) != 1 {
+			return fmt.Errorf("unknown sample %q (samples: %s)", *sample, strings.Join(names, ", "))
+		}
+	}
+
+	var previews []bridges.Preview
+	for _, bridge := range selected {
+		rendered, err := bridges.PreviewBridge(context.Background(), bridge, samples)
+		if err != nil {
+			return err
+		}
+		previews = append(previews, rendered...)
+	}
+
+	failed := 0
+	var shown []bridges.Preview
+	for _, preview := range previews {
+		failed += len(preview.Problems)
+		if !*problemsOnly || len(preview.Problems) > 0 {
+			shown = append(shown, preview)
+		}
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(shown); err != nil {
+			return err
+		}
+		return problemsError(failed)
+	}
+	for _, preview := range shown {
+		fmt.Printf("── %s · %s · %s\n%s %s\n", preview.Bridge, preview.Sample, preview.Event, preview.Method, preview.URL)
+		var payload bytes.Buffer
+		if json.Indent(&payload, preview.Payload, "", "  ") == nil {
+			fmt.Println(payload.String())
+		} else {
+			fmt.Println(string(preview.Payload))
+		}
+		for _, problem := range preview.Problems {
+			fmt.Printf("⚠️  %s\n", problem)
+		}
+		fmt.Println()
+	}
+	if failed == 0 {
+		fmt.Printf("✅ %d payload(s) within platform limits\n", len(previews))
+	}
+	return problemsError(failed)
+}
+
+// selectBridges returns the configured bridges, or only the named one.
+func selectBridges(cfg *config.Config, name string) ([]bridges.Bridge, error) {
+	all := initBridges(cfg)
+	if name == "" {
+		if len(all) == 0 {
+			return nil, errors.New("no bridge is configured")
+		}
+		return all, nil
+	}
+	for _, bridge := range all {
+		if bridge.Name() == name {
+			return []bridges.Bridge{bridge}, nil
+		}
+	}
+	return nil, fmt.Errorf("bridge %q is not configured (configured: %s)", name, strings.Join(cfg.EnabledBridges(), ", "))
+}
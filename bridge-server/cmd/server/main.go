@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -20,61 +21,40 @@ import (
 )
 
 func main() {
-	fmt.Println("🚀 PocketPing Bridge Server (Go) starting...")
-
 	// Load .env file if present
 	if err := godotenv.Load(); err != nil {
 		// Not an error if .env doesn't exist
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Load configuration
-	cfg := config.Load()
+	// Subcommands (validate, send-test, register-webhooks); none runs the server
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	serve()
+}
 
-	// Initialize bridges
-	var bridgeList []bridges.Bridge
+// serve runs the bridge server until SIGINT or SIGTERM.
+func serve() {
+	fmt.Println("🚀 PocketPing Bridge Server (Go) starting...")
 
-	if cfg.Telegram != nil {
-		log.Println("[Bridge Server] Initializing Telegram bridge...")
-		bridge, err := bridges.NewTelegramBridge(cfg.Telegram)
-		if err != nil {
-			if setupErr, ok := err.(*pocketping.SetupError); ok {
-				fmt.Println(setupErr.FormattedGuide())
-			} else {
-				log.Printf("[Bridge Server] Telegram bridge error: %v", err)
-			}
-		} else {
-			bridgeList = append(bridgeList, bridge)
+	// Load configuration
+	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		if cfg.StrictConfig {
+			fmt.Printf("\n❌ %v\n\nFix them, or unset CONFIG_STRICT to start anyway.\n", err)
+			os.Exit(1)
 		}
-	}
-
-	if cfg.Discord != nil {
-		log.Println("[Bridge Server] Initializing Discord bridge...")
-		bridge, err := bridges.NewDiscordBridge(cfg.Discord)
-		if err != nil {
-			if setupErr, ok := err.(*pocketping.SetupError); ok {
-				fmt.Println(setupErr.FormattedGuide())
-			} else {
-				log.Printf("[Bridge Server] Discord bridge error: %v", err)
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			for _, problem := range invalid.Problems {
+				log.Printf("[Bridge Server] ⚠️  Config: %s", problem)
 			}
-		} else {
-			bridgeList = append(bridgeList, bridge)
 		}
 	}
 
-	if cfg.Slack != nil {
-		log.Println("[Bridge Server] Initializing Slack bridge...")
-		bridge, err := bridges.NewSlackBridge(cfg.Slack)
-		if err != nil {
-			if setupErr, ok := err.(*pocketping.SetupError); ok {
-				fmt.Println(setupErr.FormattedGuide())
-			} else {
-				log.Printf("[Bridge Server] Slack bridge error: %v", err)
-			}
-		} else {
-			bridgeList = append(bridgeList, bridge)
-		}
-	}
+	// Initialize bridges
+	bridgeList := initBridges(cfg)
 
 	if len(bridgeList) == 0 {
 		fmt.Println("\n⚠️  No bridges configured! Set environment variables to enable bridges.")
@@ -100,13 +80,13 @@ func main() {
 			ChannelID:     cfg.Discord.ChannelID,
 			AllowedBotIDs: cfg.TestBotIDs,
 			OnOperatorMessageWithIDs: func(ctx context.Context, sessionID, content, operatorName string, attachments []pocketping.Attachment, replyToBridgeMessageID *int, bridgeMessageID string) {
-				server.RecordOperatorMessage(sessionID, content, operatorName, "discord", attachments, replyToBridgeMessageID, bridgeMessageID)
+				server.RecordOperatorMessage(ctx, sessionID, content, operatorName, "discord", attachments, replyToBridgeMessageID, bridgeMessageID)
 			},
 			OnOperatorMessageEdit: func(ctx context.Context, sessionID, bridgeMessageID, content string, editedAt time.Time) {
-				server.RecordOperatorMessageEdit(sessionID, bridgeMessageID, content, "discord", editedAt)
+				server.RecordOperatorMessageEdit(ctx, sessionID, bridgeMessageID, content, "discord", editedAt)
 			},
 			OnOperatorMessageDelete: func(ctx context.Context, sessionID, bridgeMessageID string, deletedAt time.Time) {
-				server.RecordOperatorMessageDelete(sessionID, bridgeMessageID, "discord", deletedAt)
+				server.RecordOperatorMessageDelete(ctx, sessionID, bridgeMessageID, "discord", deletedAt)
 			},
 		})
 
@@ -125,15 +105,20 @@ func main() {
 		log.Printf("   Enabled bridges: %s", strings.Join(cfg.EnabledBridges(), ", "))
 		fmt.Println("\nEndpoints:")
 		fmt.Println("   GET  /health              - Health check")
+		fmt.Println("   GET  /health/selftest     - Self-test: emit → bridges (dry run) → SSE")
+		fmt.Println("   GET  /metrics             - Prometheus per-bridge delivery metrics")
 		fmt.Println("   POST /api/events          - Receive events from backend")
 		fmt.Println("   POST /api/sessions        - New session notification")
 		fmt.Println("   POST /api/messages        - Visitor message notification")
-		fmt.Println("   POST /api/operator/status - Update operator status")
+		fmt.Println("   POST /api/operator/status - Update operator status (heartbeat with operatorId)")
+		fmt.Println("   GET  /api/operator/status - Operator presence")
 		fmt.Println("   POST /api/custom-events   - Custom event notification")
 		fmt.Println("   GET  /api/events/stream   - SSE stream of operator events")
+		fmt.Println("   GET  /api/events/ws       - Operator events over WebSocket")
+		fmt.Println("   POST /api/admin/maintenance - Maintenance mode (hold bridge notifications)")
 		fmt.Println("   GET  /api/v1/stats        - Mini support-stats (period=7d|30d; also /stats)")
 
-		if err := http.ListenAndServe(addr, mux); err != nil {
+		if err := server.HTTPServer(addr, mux).ListenAndServe(); err != nil {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -152,3 +137,52 @@ func main() {
 		}
 	}
 }
+
+// initBridges creates the configured bridges, printing a setup guide for the
+// ones that are misconfigured.
+func initBridges(cfg *config.Config) []bridges.Bridge {
+	var bridgeList []bridges.Bridge
+
+	if cfg.Telegram != nil {
+		log.Println("[Bridge Server] Initializing Telegram bridge...")
+		bridge, err := bridges.NewTelegramBridge(cfg.Telegram)
+		if err != nil {
+			if setupErr, ok := err.(*pocketping.SetupError); ok {
+				fmt.Println(setupErr.FormattedGuide())
+			} else {
+				log.Printf("[Bridge Server] Telegram bridge error: %v", err)
+			}
+		} else {
+			bridgeList = append(bridgeList, bridge)
+		}
+	}
+
+	if cfg.Discord != nil {
+		log.Println("[Bridge Server] Initializing Discord bridge...")
+		bridge, err := bridges.NewDiscordBridge(cfg.Discord)
+		if err != nil {
+			if setupErr, ok := err.(*pocketping.SetupError); ok {
+				fmt.Println(setupErr.FormattedGuide())
+			} else {
+				log.Printf("[Bridge Server] Discord bridge error: %v", err)
+			}
+		} else {
+			bridgeList = append(bridgeList, bridge)
+		}
+	}
+
+	if cfg.Slack != nil {
+		log.Println("[Bridge Server] Initializing Slack bridge...")
+		bridge, err := bridges.NewSlackBridge(cfg.Slack)
+		if err != nil {
+			if setupErr, ok := err.(*pocketping.SetupError); ok {
+				fmt.Println(setupErr.FormattedGuide())
+			} else {
+				log.Printf("[Bridge Server] Slack bridge error: %v", err)
+			}
+		} else {
+			bridgeList = append(bridgeList, bridge)
+		}
+	}
+	return bridgeList
+}
@@ -7,7 +7,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 )
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
 
 // Use local sdk-go package
 replace github.com/Ruwad-io/pocketping/sdk-go => ../packages/sdk-go
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+)
+
+// maxRequestIDLength bounds caller-supplied request IDs; longer or non-printable
+// ones are replaced with a generated ID.
+const maxRequestIDLength = 128
+
+// accessLogMiddleware gives each request an ID, taken from X-Request-ID or
+// generated, and echoes it in the response. The ID travels in the request
+// context to bridge API calls and webhook forwards. When access logging is
+// enabled it logs one line per request with method, path, status, latency and
+// request ID.
+func (s *Server) accessLogMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(bridges.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(bridges.RequestIDHeader, id)
+		r = r.WithContext(bridges.WithRequestID(r.Context(), id))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		if s.config.AccessLog {
+			log.Printf("[Access] method=%s path=%s status=%d latency=%s request_id=%s",
+				r.Method, r.URL.Path, rec.statusCode(), time.Since(start).Round(time.Microsecond), id)
+		}
+	}
+}
+
+// setRequestIDHeader copies the request ID in ctx, if any, onto an outgoing
+// request.
+func setRequestIDHeader(ctx context.Context, req *http.Request) {
+	if id := bridges.RequestID(ctx); id != "" {
+		req.Header.Set(bridges.RequestIDHeader, id)
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the response status for the access log. It passes
+// Flush and Hijack through so the SSE and WebSocket streams keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode returns the recorded status; a handler that wrote nothing
+// answered 200.
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	_, mux := setupTestServer(nil, &config.Config{AccessLog: true})
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if got := w.Header().Get("X-Request-ID"); got != "req-123" {
+		t.Errorf("expected the caller's request ID echoed, got %q", got)
+	}
+	line := logs.String()
+	for _, want := range []string{"[Access] method=GET path=/health status=200", "request_id=req-123"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("access log missing %q:\n%s", want, line)
+		}
+	}
+
+	// A missing or malformed ID is replaced with a generated one.
+	req = httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got == "" || got == "bad id\n" {
+		t.Errorf("expected a generated request ID, got %q", got)
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	forwarded := make(chan string, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded <- r.Header.Get("X-Request-ID")
+	}))
+	defer webhook.Close()
+
+	bridge := newMockBridge("test")
+	_, mux := setupTestServer([]bridges.Bridge{bridge}, &config.Config{EventsWebhookURL: webhook.URL})
+
+	req := httptest.NewRequest("POST", "/api/sessions", strings.NewReader(`{"id":"s1","visitorId":"v1"}`))
+	req.Header.Set("X-Request-ID", "req-456")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	bridge.mu.Lock()
+	got := bridge.lastRequestID
+	bridge.mu.Unlock()
+	if got != "req-456" {
+		t.Errorf("bridge call request ID = %q, want req-456", got)
+	}
+
+	select {
+	case id := <-forwarded:
+		if id != "req-456" {
+			t.Errorf("webhook forward request ID = %q, want req-456", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("events webhook not called")
+	}
+}
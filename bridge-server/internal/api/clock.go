@@ -0,0 +1,16 @@
+package api
+
+import "time"
+
+// clockSourceBackend names the backend's timestamps (visitor messages, CSAT
+// responses) in clock skew stats; bridge timestamps use the bridge name.
+const clockSourceBackend = "backend"
+
+// notBefore returns t, or min when t is earlier, so an edit or deletion
+// never predates its message.
+func notBefore(t, min time.Time) time.Time {
+	if !min.IsZero() && t.Before(min) {
+		return min
+	}
+	return t
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+func TestRecordOperatorMessageEdit_NormalizesSkewedTime(t *testing.T) {
+	server, mux := setupTestServer(nil, nil)
+	sent := time.Now().Add(-time.Minute)
+	server.saveMessage(&types.Message{ID: "telegram:42", SessionID: "s1", Content: "hi", Sender: types.SenderOperator, Timestamp: sent})
+
+	platform := time.Now().Add(10 * time.Minute)
+	server.RecordOperatorMessageEdit(context.Background(), "s1", "42", "hello", "telegram", platform)
+
+	msg := server.getMessage("telegram:42")
+	if msg.PlatformEditedAt == nil || !msg.PlatformEditedAt.Equal(platform) {
+		t.Errorf("expected platform edit time %v, got %v", platform, msg.PlatformEditedAt)
+	}
+	if msg.EditedAt == nil || msg.EditedAt.After(time.Now()) || msg.EditedAt.Before(sent) {
+		t.Errorf("expected edit time normalized to local time, got %v", msg.EditedAt)
+	}
+
+	metrics := getMetrics(t, mux)
+	if !strings.Contains(metrics, `pocketping_clock_skewed_timestamps_total{source="telegram"} 1`) {
+		t.Errorf("expected a skewed telegram timestamp:\n%s", metrics)
+	}
+}
+
+func TestRecordOperatorMessageDelete_NeverBeforeMessage(t *testing.T) {
+	server, _ := setupTestServer(nil, nil)
+	sent := time.Now()
+	server.saveMessage(&types.Message{ID: "discord:7", SessionID: "s1", Content: "hi", Sender: types.SenderOperator, Timestamp: sent})
+
+	// Within the threshold, but the platform clock is behind ours.
+	server.RecordOperatorMessageDelete(context.Background(), "s1", "7", "discord", sent.Add(-5*time.Second))
+
+	msg := server.getMessage("discord:7")
+	if msg.DeletedAt == nil || msg.DeletedAt.Before(sent) {
+		t.Errorf("expected deletion at or after %v, got %v", sent, msg.DeletedAt)
+	}
+}
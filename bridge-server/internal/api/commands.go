@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"log"
 	"strings"
 	"time"
@@ -22,8 +23,7 @@ type operatorCommand struct {
 // case-insensitively on the first whitespace-delimited token; the rest of the
 // line is returned as Args.
 //
-// This is intentionally minimal — today only "!csat" is wired up — but it
-// establishes the convention for future bridge commands.
+// Wired up today: "!csat" and "!close [reason]".
 func parseOperatorCommand(content string) *operatorCommand {
 	trimmed := strings.TrimSpace(content)
 	if !strings.HasPrefix(trimmed, "!") || trimmed == "!" {
@@ -51,19 +51,25 @@ func parseOperatorCommand(content string) *operatorCommand {
 // when the command was recognised and consumed (and must not be relayed to the
 // visitor), or false for an unknown command, which falls through to ordinary
 // message handling.
-func (s *Server) handleOperatorCommand(sessionID string, cmd *operatorCommand) bool {
+func (s *Server) handleOperatorCommand(ctx context.Context, sessionID, sourceBridge string, cmd *operatorCommand) bool {
 	switch cmd.Name {
 	case "csat":
 		// Ask the visitor to rate the conversation. The widget filters on its
 		// own sessionId, so the broadcast only surfaces the card for this
 		// session. Mirrors the SDK/SaaS `requestCsat` → `csat_request` flow.
-		s.EmitEvent(&types.CsatRequestEvent{
+		s.emitEvent(ctx, &types.CsatRequestEvent{
 			Type:        "csat_request",
 			SessionID:   sessionID,
 			RequestedAt: time.Now().UTC().Format(time.RFC3339),
 		})
 		log.Printf("[API] !csat requested for session %s", sessionID)
 		return true
+	case "close":
+		// Close the conversation: ends SLA tracking and emits session_closed
+		// so workforce tools stop counting it against the assignee.
+		s.closeSession(ctx, sessionID, sourceBridge, cmd.Args)
+		log.Printf("[API] !close for session %s from %s", sessionID, sourceBridge)
+		return true
 	default:
 		return false
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -52,11 +53,9 @@ func TestRecordOperatorMessage_csatCommand(t *testing.T) {
 	bridge := newMockBridge("telegram")
 	server, _ := setupTestServer([]bridges.Bridge{bridge}, nil)
 
-	eventChan := make(chan types.OutgoingEvent, 10)
-	server.eventListeners.Store(eventChan, struct{}{})
-	defer server.eventListeners.Delete(eventChan)
+	eventChan := listenEvents(t, server)
 
-	server.RecordOperatorMessage("s1", "!csat", "Op", "telegram", nil, nil, "100")
+	server.RecordOperatorMessage(context.Background(), "s1", "!csat", "Op", "telegram", nil, nil, "100")
 
 	select {
 	case ev := <-eventChan:
@@ -89,7 +88,7 @@ func TestRecordOperatorMessage_unknownCommand_relayed(t *testing.T) {
 	bridge := newMockBridge("telegram")
 	server, _ := setupTestServer([]bridges.Bridge{bridge}, nil)
 
-	server.RecordOperatorMessage("s1", "!notacommand hi", "Op", "telegram", nil, nil, "101")
+	server.RecordOperatorMessage(context.Background(), "s1", "!notacommand hi", "Op", "telegram", nil, nil, "101")
 
 	msg := server.getMessage(buildOperatorMessageID("telegram", "101"))
 	if msg == nil {
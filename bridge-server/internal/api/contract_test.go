@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
+	"github.com/Ruwad-io/pocketping/sdk-go/events/eventstest"
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// backendEventTypes are the events backends send, with the bridge-server's
+// type for each and the mock bridge counter it bumps.
+var backendEventTypes = map[string]struct {
+	newEvent func() interface{}
+	calls    func(*mockBridge) int
+}{
+	events.TypeNewSession: {
+		func() interface{} { return &types.NewSessionEvent{} },
+		func(b *mockBridge) int { return b.newSessionCalled },
+	},
+	events.TypeVisitorMessage: {
+		func() interface{} { return &types.VisitorMessageEvent{} },
+		func(b *mockBridge) int { return b.visitorMsgCalled },
+	},
+	events.TypeMessageRead: {
+		func() interface{} { return &types.MessageReadEvent{} },
+		func(b *mockBridge) int { return b.messageReadCalled },
+	},
+	events.TypeCustomEvent: {
+		func() interface{} { return &types.CustomEventEvent{} },
+		func(b *mockBridge) int { return b.customEventCalled },
+	},
+	events.TypeIdentityUpdate: {
+		func() interface{} { return &types.IdentityUpdateEvent{} },
+		func(b *mockBridge) int { return b.identityUpCalled },
+	},
+	events.TypeVisitorMessageEdited: {
+		func() interface{} { return &types.VisitorMessageEditedEvent{} },
+		func(b *mockBridge) int { return b.msgEditedCalled },
+	},
+	events.TypeVisitorMessageDeleted: {
+		func() interface{} { return &types.VisitorMessageDeletedEvent{} },
+		func(b *mockBridge) int { return b.msgDeletedCalled },
+	},
+}
+
+func TestContract_BackendPayloadsParse(t *testing.T) {
+	bridge := newMockBridge("test")
+	_, mux := setupTestServer([]bridges.Bridge{bridge}, nil)
+
+	payloads := eventstest.Payloads(t, eventstest.Backend)
+	for _, eventType := range eventstest.Types(payloads) {
+		payload := payloads[eventType]
+		known, ok := backendEventTypes[eventType]
+		if !ok {
+			t.Errorf("the SDK sends %s, which the bridge-server doesn't know", eventType)
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(known.newEvent()); err != nil {
+			t.Errorf("%s doesn't parse into the bridge-server's type: %v", eventType, err)
+		}
+
+		req := httptest.NewRequest("POST", "/api/events", bytes.NewReader(payload))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		bridge.mu.Lock()
+		calls := known.calls(bridge)
+		bridge.mu.Unlock()
+		if w.Code != http.StatusOK || calls != 1 {
+			t.Errorf("%s: status %d, bridge called %d times", eventType, w.Code, calls)
+		}
+	}
+
+	if bridge.lastSession == nil || bridge.lastSession.Identity == nil || bridge.lastSession.Identity.Extra["plan"] != "pro" {
+		t.Errorf("identity custom fields lost: %+v", bridge.lastSession)
+	}
+	if bridge.lastMessage == nil || len(bridge.lastMessage.Attachments) != 1 || bridge.lastMessage.Metadata["source"] != "widget" {
+		t.Errorf("message fields lost: %+v", bridge.lastMessage)
+	}
+}
+
+func TestContract_BridgePayloads(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]byte)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var base struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal(body, &base)
+		mu.Lock()
+		received[base.Type] = body
+		mu.Unlock()
+	}))
+	defer backend.Close()
+
+	server, _ := setupTestServer(nil, &config.Config{BackendWebhookURL: backend.URL})
+	ctx := context.Background()
+	// The first reply also assigns the session (assignment_changed).
+	server.RecordOperatorMessage(ctx, "sess-1", "On it", "Bob", "telegram", []pocketping.Attachment{{
+		ID:       "att-1",
+		Filename: "screenshot.png",
+		MimeType: "image/png",
+		Size:     2048,
+		URL:      "https://files.example.com/screenshot.png",
+	}}, nil, "42")
+	server.RecordOperatorMessageEdit(ctx, "sess-1", "42", "On it now", "telegram", time.Now())
+	server.RecordOperatorMessageDelete(ctx, "sess-1", "42", "telegram", time.Now())
+	if err := server.processSessionClose(ctx, &types.SessionCloseEvent{Type: events.TypeCloseSession, SessionID: "sess-1", Reason: "resolved"}); err != nil {
+		t.Fatalf("processSessionClose: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 5 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 5 {
+		t.Fatalf("backend received %d event types, want 5", len(received))
+	}
+	for eventType, payload := range received {
+		eventstest.Check(t, eventstest.Bridge, eventType, payload)
+	}
+}
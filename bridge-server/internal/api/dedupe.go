@@ -0,0 +1,40 @@
+package api
+
+import (
+	"log"
+	"strconv"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+)
+
+// newDeduper returns the deduper of visitor messages: shared through Redis
+// with DEDUPE_REDIS_URL, so a backend whose SDK posts to the same chats
+// doesn't notify them twice, or in memory otherwise.
+func newDeduper(cfg *config.Config) pocketping.Deduper {
+	if cfg.DedupeRedisURL != "" {
+		deduper, err := pocketping.NewRedisDeduper(cfg.DedupeRedisURL, pocketping.DefaultDedupeTTL)
+		if err == nil {
+			return deduper
+		}
+		log.Printf("[Bridge Server] ⚠️  DEDUPE_REDIS_URL: %v; deduplicating in memory", err)
+	}
+	return pocketping.NewMemoryDeduper(pocketping.DefaultDedupeTTL)
+}
+
+// dedupeKey returns the deduper key of bridge: its destination, which the
+// SDK's bridges to the same chat share, or its name and position.
+func (s *Server) dedupeKey(bridge bridges.Bridge) string {
+	if d, ok := bridge.(interface{ Destination() string }); ok {
+		if destination := d.Destination(); destination != "" {
+			return destination
+		}
+	}
+	for i, b := range s.bridges {
+		if b == bridge {
+			return bridge.Name() + "#" + strconv.Itoa(i)
+		}
+	}
+	return bridge.Name()
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// handleExport serves GET /api/export: the conversations the relay has seen
+// as CSV or NDJSON, in the SDK's export format, for BI teams without access
+// to the backend's database. Sessions come from the stats store and messages
+// from the reply-preview cache, so the export has the same limits as /stats:
+// nothing from before the last restart, and no visitor details.
+//
+// Query params: format=csv|ndjson (default csv), from/to (RFC3339).
+//
+// The export contains message content, so it is refused unless API_KEY is
+// set.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if s.config.APIKey == "" {
+		http.Error(w, `{"error":"Export requires API_KEY"}`, http.StatusForbidden)
+		return
+	}
+	opts, err := pocketping.ParseExportQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error":"`+pocketping.AsError(err).Message+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	sessions, messages := s.exportEntries()
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="pocketping-export.`+string(opts.Format)+`"`)
+	ew, err := pocketping.NewExportWriter(w, opts.Format)
+	if err != nil {
+		log.Printf("[API] Export failed: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		if !opts.IncludesSession(session) {
+			continue
+		}
+		if err := ew.WriteSession(session); err != nil {
+			log.Printf("[API] Export failed: %v", err)
+			return
+		}
+		for i := range messages[session.ID] {
+			message := &messages[session.ID][i]
+			if !opts.IncludesMessage(message) {
+				continue
+			}
+			if err := ew.WriteMessage(message); err != nil {
+				log.Printf("[API] Export failed: %v", err)
+				return
+			}
+		}
+		if err := ew.Flush(); err != nil {
+			log.Printf("[API] Export failed: %v", err)
+			return
+		}
+	}
+	if err := ew.Flush(); err != nil {
+		log.Printf("[API] Export failed: %v", err)
+	}
+}
+
+// exportEntries returns the known sessions, oldest first, and their cached
+// messages in order, by session ID. A session's LastActivity is its latest
+// message.
+func (s *Server) exportEntries() ([]*pocketping.Session, map[string][]pocketping.Message) {
+	messages := make(map[string][]pocketping.Message)
+	s.messages.Range(func(_, v interface{}) bool {
+		message := exportMessage(v.(*types.Message))
+		messages[message.SessionID] = append(messages[message.SessionID], message)
+		return true
+	})
+
+	entries := s.stats.entries()
+	sessions := make([]*pocketping.Session, 0, len(entries))
+	for _, entry := range entries {
+		session := entry.Session
+		session.LastActivity = session.CreatedAt
+		list := messages[session.ID]
+		sort.Slice(list, func(i, j int) bool { return list[i].Timestamp.Before(list[j].Timestamp) })
+		if len(list) > 0 && list[len(list)-1].Timestamp.After(session.LastActivity) {
+			session.LastActivity = list[len(list)-1].Timestamp
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions, messages
+}
+
+// exportMessage converts a cached message to the SDK type.
+func exportMessage(m *types.Message) pocketping.Message {
+	message := pocketping.Message{
+		ID:          m.ID,
+		SessionID:   m.SessionID,
+		Content:     m.Content,
+		Sender:      pocketping.Sender(m.Sender),
+		Timestamp:   m.Timestamp,
+		ReplyTo:     m.ReplyTo,
+		Metadata:    m.Metadata,
+		Status:      pocketping.MessageStatus(m.Status),
+		DeliveredAt: m.DeliveredAt,
+		ReadAt:      m.ReadAt,
+		EditedAt:    m.EditedAt,
+		DeletedAt:   m.DeletedAt,
+	}
+	for _, a := range m.Attachments {
+		message.Attachments = append(message.Attachments, pocketping.Attachment{
+			ID:           a.ID,
+			MessageID:    m.ID,
+			Filename:     a.Filename,
+			MimeType:     a.MimeType,
+			Size:         a.Size,
+			URL:          a.URL,
+			ThumbnailURL: a.ThumbnailURL,
+			Status:       pocketping.AttachmentStatus(a.Status),
+			UploadedFrom: pocketping.UploadSource(a.UploadedFrom),
+			BridgeFileID: a.BridgeFileID,
+		})
+	}
+	return message
+}
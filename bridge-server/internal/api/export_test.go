@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+func TestServer_handleExport(t *testing.T) {
+	server, mux := setupTestServer([]bridges.Bridge{newMockBridge("telegram")}, &config.Config{APIKey: "secret"})
+	now := time.Now()
+	server.stats.recordSession("s1", now.Add(-time.Hour))
+	server.saveMessage(&types.Message{ID: "m2", SessionID: "s1", Content: "Hello", Sender: types.SenderOperator, Timestamp: now.Add(-30 * time.Minute)})
+	server.saveMessage(&types.Message{ID: "m1", SessionID: "s1", Content: "Hi, there", Sender: types.SenderVisitor, Timestamp: now.Add(-50 * time.Minute)})
+	server.stats.recordSession("old", now.Add(-10*24*time.Hour))
+
+	get := func(query, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/export"+query, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without API key: expected 401, got %d", w.Code)
+	}
+	if w := get("?format=xml", "secret"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad format: expected 400, got %d", w.Code)
+	}
+
+	from := now.Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	w := get("?from="+from, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected header, session and 2 messages, got %q", rows)
+	}
+	if rows[1][0] != "session" || rows[1][1] != "s1" {
+		t.Errorf("session row = %q", rows[1])
+	}
+	if rows[2][6] != "m1" || rows[2][8] != "Hi, there" || rows[3][6] != "m2" || rows[3][7] != "operator" {
+		t.Errorf("message rows = %q", rows[2:])
+	}
+}
+
+func TestServer_handleExport_requiresAPIKey(t *testing.T) {
+	_, mux := setupTestServer([]bridges.Bridge{newMockBridge("telegram")}, &config.Config{})
+	req := httptest.NewRequest("GET", "/api/export", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without API_KEY, got %d", w.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+)
+
+// streamRetryAfter is the Retry-After sent when a stream is refused.
+const streamRetryAfter = 5 * time.Second
+
+// streamLimiter counts open SSE and WebSocket streams, in total and per
+// client IP.
+type streamLimiter struct {
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+func newStreamLimiter() *streamLimiter {
+	return &streamLimiter{perIP: make(map[string]int)}
+}
+
+// acquire reserves a stream slot for ip. It returns false when the total or
+// per-IP cap (zero meaning unlimited) is reached.
+func (l *streamLimiter) acquire(ip string, maxTotal, maxPerIP int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if maxTotal > 0 && l.total >= maxTotal {
+		return false
+	}
+	if maxPerIP > 0 && l.perIP[ip] >= maxPerIP {
+		return false
+	}
+	l.total++
+	l.perIP[ip]++
+	return true
+}
+
+// release frees a slot taken by acquire.
+func (l *streamLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	if l.perIP[ip]--; l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+}
+
+// open returns the number of open streams.
+func (l *streamLimiter) open() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
+
+// streamLimitMiddleware enforces MaxStreamConnections and
+// MaxStreamConnectionsPerIP, answering 429 with Retry-After beyond them. The
+// client IP honors the usual proxy headers, like the SDK IP filter.
+func (s *Server) streamLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := pocketping.GetClientIPSimple(r)
+		if !s.streams.acquire(ip, s.config.MaxStreamConnections, s.config.MaxStreamConnectionsPerIP) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(streamRetryAfter.Seconds())))
+			writeJSONError(w, http.StatusTooManyRequests, "Too many stream connections")
+			return
+		}
+		defer s.streams.release(ip)
+		next(w, r)
+	}
+}
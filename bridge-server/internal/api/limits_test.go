@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pocketping/bridge-server/internal/config"
+)
+
+func TestStreamLimitPerIP(t *testing.T) {
+	server, mux := setupTestServer(nil, &config.Config{MaxStreamConnectionsPerIP: 1})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conn := dialEvents(t, srv, "", nil)
+	waitSubscribers(t, server, 1)
+
+	// A second stream from the same IP is refused, over either transport.
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/events/ws", nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for a second WebSocket, got %v (err %v)", resp, err)
+	}
+	if resp.Header.Get("Retry-After") != "5" {
+		t.Errorf("Retry-After = %q, want 5", resp.Header.Get("Retry-After"))
+	}
+	sse, err := http.Get(srv.URL + "/api/events/stream")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	sse.Body.Close()
+	if sse.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for SSE, got %d", sse.StatusCode)
+	}
+
+	// Closing the stream frees its slot.
+	conn.Close()
+	deadline := time.Now().Add(time.Second)
+	for server.streams.open() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	again := dialEvents(t, srv, "", nil)
+	again.Close()
+}
+
+func TestStreamLimiterTotal(t *testing.T) {
+	limiter := newStreamLimiter()
+	if !limiter.acquire("1.1.1.1", 2, 0) || !limiter.acquire("2.2.2.2", 2, 0) {
+		t.Fatal("expected the first two streams to be accepted")
+	}
+	if limiter.acquire("3.3.3.3", 2, 0) {
+		t.Error("expected the total cap to refuse a third stream")
+	}
+	limiter.release("1.1.1.1")
+	if !limiter.acquire("3.3.3.3", 2, 0) {
+		t.Error("expected a released slot to be reusable")
+	}
+	if limiter.open() != 2 {
+		t.Errorf("open = %d, want 2", limiter.open())
+	}
+}
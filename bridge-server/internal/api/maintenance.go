@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// maintenanceGate holds bridge notifications back during maintenance, so
+// chat-platform changes can be deployed and tested without spamming the
+// channels. Stats, SSE events and webhooks keep flowing.
+type maintenanceGate struct {
+	mu       sync.Mutex
+	enabled  bool
+	mode     string
+	message  string
+	since    time.Time
+	buffered []func()
+	dropped  int
+	// replay serializes the replays of buffered notifications.
+	replay sync.Mutex
+}
+
+// notifyBridges calls notify for each bridge, or buffers or drops the
+// notification while maintenance is on. notify gets ctx without its
+// cancellation, so buffered notifications still run after the request ends
+// and keep its request ID. A failed notify is retried per
+// BridgeRetryMaxAttempts. Its outcome, retries and latency feed /metrics.
+func (s *Server) notifyBridges(ctx context.Context, notify func(ctx context.Context, bridge bridges.Bridge) error) {
+	ctx = context.WithoutCancel(ctx)
+	run := func() {
+		for _, bridge := range s.bridges {
+			start := time.Now()
+			err := s.retry.Do(ctx, bridge.Name(), "notification", "", "", func(ctx context.Context) error {
+				return notify(ctx, bridge)
+			})
+			s.metrics.observe(bridge.Name(), time.Since(start), err)
+		}
+	}
+
+	m := &s.maintenance
+	m.mu.Lock()
+	if !m.enabled {
+		m.mu.Unlock()
+		s.countQueued(1)
+		run()
+		return
+	}
+	defer m.mu.Unlock()
+
+	if m.mode == config.MaintenanceDrop {
+		m.dropped++
+		s.countDropped(1)
+		return
+	}
+	m.buffered = append(m.buffered, run)
+	s.countQueued(1)
+	if limit := s.maintenanceBufferSize(); len(m.buffered) > limit {
+		m.dropped += len(m.buffered) - limit
+		s.countDropped(len(m.buffered) - limit)
+		s.countQueued(limit - len(m.buffered))
+		m.buffered = m.buffered[len(m.buffered)-limit:]
+	}
+}
+
+func (s *Server) maintenanceBufferSize() int {
+	if s.config.MaintenanceBufferSize > 0 {
+		return s.config.MaintenanceBufferSize
+	}
+	return config.DefaultMaintenanceBufferSize
+}
+
+// startMaintenance turns maintenance on, or updates its mode and message.
+func (s *Server) startMaintenance(mode, message string) {
+	m := &s.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.enabled {
+		m.enabled = true
+		m.since = time.Now()
+		m.dropped = 0
+	}
+	m.mode = mode
+	m.message = message
+	log.Printf("[API] Maintenance mode on (%s)", mode)
+}
+
+// stopMaintenance turns maintenance off and sends the buffered notifications
+// in order, or discards them. Notifications arriving during the replay are
+// queued behind it. Returns how many were sent.
+func (s *Server) stopMaintenance(discard bool) int {
+	m := &s.maintenance
+	m.replay.Lock()
+	defer m.replay.Unlock()
+
+	replayed := 0
+	for {
+		m.mu.Lock()
+		pending := m.buffered
+		m.buffered = nil
+		if len(pending) == 0 || discard {
+			m.dropped += len(pending)
+			s.countDropped(len(pending))
+			s.countQueued(-len(pending))
+			wasEnabled := m.enabled
+			m.enabled = false
+			m.message = ""
+			m.mu.Unlock()
+			if wasEnabled {
+				log.Printf("[API] Maintenance mode off (%d notifications sent)", replayed)
+			}
+			return replayed
+		}
+		m.mu.Unlock()
+
+		for _, run := range pending {
+			run()
+		}
+		replayed += len(pending)
+	}
+}
+
+// maintenanceStatus snapshots the maintenance state.
+func (s *Server) maintenanceStatus() *types.MaintenanceStatus {
+	m := &s.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &types.MaintenanceStatus{
+		Enabled:  m.enabled,
+		Buffered: len(m.buffered),
+		Dropped:  m.dropped,
+	}
+	if m.enabled {
+		status.Mode = m.mode
+		status.Message = m.message
+		status.Since = formatTime(m.since)
+	}
+	return status
+}
+
+// handleMaintenance handles POST /api/admin/maintenance
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Enabled bool `json:"enabled"`
+		// Mode is "buffer" or "drop". Defaults to the configured mode.
+		Mode string `json:"mode"`
+		// Message is shown as the maintenance banner on /health.
+		Message string `json:"message"`
+		// Discard drops buffered notifications instead of sending them when
+		// maintenance ends.
+		Discard bool `json:"discard"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !payload.Enabled {
+		replayed := s.stopMaintenance(payload.Discard)
+		status := s.maintenanceStatus()
+		status.Replayed = replayed
+		writeJSON(w, status)
+		return
+	}
+
+	mode := payload.Mode
+	if mode == "" {
+		mode = s.config.MaintenanceMode
+	}
+	if mode == "" {
+		mode = config.MaintenanceBuffer
+	}
+	if mode != config.MaintenanceBuffer && mode != config.MaintenanceDrop {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("mode must be %q or %q", config.MaintenanceBuffer, config.MaintenanceDrop))
+		return
+	}
+	s.startMaintenance(mode, payload.Message)
+	writeJSON(w, s.maintenanceStatus())
+}
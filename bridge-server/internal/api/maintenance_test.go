@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+func postMaintenance(t *testing.T, mux *http.ServeMux, body string) (int, types.MaintenanceStatus) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/admin/maintenance", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var status types.MaintenanceStatus
+	_ = json.NewDecoder(w.Body).Decode(&status)
+	return w.Code, status
+}
+
+func getHealth(t *testing.T, mux *http.ServeMux) map[string]interface{} {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	var health map[string]interface{}
+	_ = json.NewDecoder(w.Body).Decode(&health)
+	return health
+}
+
+func TestMaintenance_BuffersAndReplays(t *testing.T) {
+	bridge := newMockBridge("test")
+	server, mux := setupTestServer([]bridges.Bridge{bridge}, nil)
+
+	if code, status := postMaintenance(t, mux, `{"enabled":true,"message":"Deploying Slack app"}`); code != http.StatusOK || !status.Enabled || status.Mode != config.MaintenanceBuffer {
+		t.Fatalf("enable: %d %+v", code, status)
+	}
+	health := getHealth(t, mux)
+	if health["maintenance"] != true || health["maintenanceMessage"] != "Deploying Slack app" || health["status"] != "ok" {
+		t.Errorf("unexpected health: %v", health)
+	}
+
+	_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: "s1"}})
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	if bridge.newSessionCalled != 0 || bridge.visitorMsgCalled != 0 {
+		t.Fatalf("bridges notified during maintenance")
+	}
+
+	_, status := postMaintenance(t, mux, `{"enabled":false}`)
+	if status.Enabled || status.Replayed != 2 || status.Buffered != 0 {
+		t.Errorf("disable: %+v", status)
+	}
+	if bridge.newSessionCalled != 1 || bridge.visitorMsgCalled != 1 {
+		t.Errorf("expected buffered notifications replayed, got %d sessions, %d messages", bridge.newSessionCalled, bridge.visitorMsgCalled)
+	}
+	if health := getHealth(t, mux); health["maintenance"] != false {
+		t.Errorf("unexpected health: %v", health)
+	}
+}
+
+func TestMaintenance_ReplaysEditsWithBridgeIDs(t *testing.T) {
+	bridge := newMockBridge("test")
+	bridge.returnBridgeIDs = &types.BridgeMessageIDs{TelegramMessageID: 42}
+	server, mux := setupTestServer([]bridges.Bridge{bridge}, nil)
+	postMaintenance(t, mux, `{"enabled":true}`)
+
+	ctx := context.Background()
+	_ = server.processVisitorMessage(ctx, visitorMessage("s1", "m1"))
+	_ = server.processVisitorMessageEdited(ctx, &types.VisitorMessageEditedEvent{Type: "visitor_message_edited", SessionID: "s1", MessageID: "m1", Content: "edited"})
+	postMaintenance(t, mux, `{"enabled":false}`)
+	if bridge.msgEditedCalled != 1 || bridge.lastBridgeIDs == nil || bridge.lastBridgeIDs.TelegramMessageID != 42 {
+		t.Errorf("replayed edit got bridge IDs %+v", bridge.lastBridgeIDs)
+	}
+
+	postMaintenance(t, mux, `{"enabled":true}`)
+	_ = server.processVisitorMessage(ctx, visitorMessage("s1", "m2"))
+	_ = server.processVisitorMessageDeleted(ctx, &types.VisitorMessageDeletedEvent{Type: "visitor_message_deleted", SessionID: "s1", MessageID: "m2"})
+	postMaintenance(t, mux, `{"enabled":false}`)
+	if bridge.msgDeletedCalled != 1 || bridge.lastBridgeIDs == nil || bridge.lastBridgeIDs.TelegramMessageID != 42 {
+		t.Errorf("replayed delete got bridge IDs %+v", bridge.lastBridgeIDs)
+	}
+}
+
+func TestMaintenance_DropMode(t *testing.T) {
+	bridge := newMockBridge("test")
+	server, mux := setupTestServer([]bridges.Bridge{bridge}, &config.Config{MaintenanceMode: config.MaintenanceDrop})
+
+	postMaintenance(t, mux, `{"enabled":true}`)
+	_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: "s1"}})
+
+	_, status := postMaintenance(t, mux, `{"enabled":false}`)
+	if status.Replayed != 0 || status.Dropped != 1 || bridge.newSessionCalled != 0 {
+		t.Errorf("drop mode: %+v, %d sessions", status, bridge.newSessionCalled)
+	}
+}
+
+func TestMaintenance_BufferLimitAndDiscard(t *testing.T) {
+	bridge := newMockBridge("test")
+	server, mux := setupTestServer([]bridges.Bridge{bridge}, &config.Config{MaintenanceBufferSize: 2})
+
+	postMaintenance(t, mux, `{"enabled":true,"mode":"buffer"}`)
+	for _, id := range []string{"s1", "s2", "s3"} {
+		_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: id}})
+	}
+	if status := server.maintenanceStatus(); status.Buffered != 2 || status.Dropped != 1 {
+		t.Errorf("buffer limit: %+v", status)
+	}
+
+	_, status := postMaintenance(t, mux, `{"enabled":false,"discard":true}`)
+	if status.Replayed != 0 || status.Dropped != 3 || bridge.newSessionCalled != 0 {
+		t.Errorf("discard: %+v, %d sessions", status, bridge.newSessionCalled)
+	}
+}
+
+func TestMaintenance_InvalidMode(t *testing.T) {
+	_, mux := setupTestServer(nil, nil)
+	if code, _ := postMaintenance(t, mux, `{"enabled":true,"mode":"pause"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", code)
+	}
+}
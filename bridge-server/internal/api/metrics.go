@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+)
+
+var (
+	// errNotifyDuplicate is returned by a notification suppressed by the
+	// deduper; it counts as dropped.
+	errNotifyDuplicate = errors.New("duplicate notification")
+	// errNotifySkipped is returned by a notification that doesn't apply to
+	// the bridge, such as the source bridge of a synced message; it isn't
+	// counted.
+	errNotifySkipped = errors.New("notification skipped")
+)
+
+// bridgeRetryMaxBackoff caps the wait before retrying a notification.
+const bridgeRetryMaxBackoff = 5 * time.Second
+
+// notifyDurationBuckets are the upper bounds, in seconds, of the
+// notification latency histogram.
+var notifyDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// bridgeMetrics holds the delivery counters of one bridge.
+type bridgeMetrics struct {
+	sent        int64
+	failed      int64
+	dropped     int64
+	retried     int64
+	queued      int64   // waiting for or being delivered to the bridge
+	buckets     []int64 // per bucket, plus +Inf
+	durationSum float64
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// metricsStore keeps per-bridge delivery metrics for /metrics and /health.
+type metricsStore struct {
+	mu      sync.Mutex
+	bridges map[string]*bridgeMetrics
+}
+
+func newMetricsStore() *metricsStore {
+	return &metricsStore{bridges: make(map[string]*bridgeMetrics)}
+}
+
+func (m *metricsStore) bridge(name string) *bridgeMetrics {
+	b, ok := m.bridges[name]
+	if !ok {
+		b = &bridgeMetrics{buckets: make([]int64, len(notifyDurationBuckets)+1)}
+		m.bridges[name] = b
+	}
+	return b
+}
+
+// observe records the outcome of one notification sent to a bridge, which
+// leaves its queue.
+func (m *metricsStore) observe(name string, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.bridge(name)
+	b.queued--
+	if errors.Is(err, errNotifySkipped) {
+		return
+	}
+	if errors.Is(err, errNotifyDuplicate) {
+		b.dropped++
+		return
+	}
+
+	seconds := elapsed.Seconds()
+	i := 0
+	for i < len(notifyDurationBuckets) && seconds > notifyDurationBuckets[i] {
+		i++
+	}
+	b.buckets[i]++
+	b.durationSum += seconds
+
+	if err == nil {
+		b.sent++
+		return
+	}
+	b.failed++
+	b.lastError = errorMessage(err)
+	b.lastErrorAt = time.Now()
+}
+
+// drop records n notifications dropped for a bridge before being sent.
+func (m *metricsStore) drop(name string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bridge(name).dropped += int64(n)
+}
+
+// retry counts a retry of a failed notification.
+func (m *metricsStore) retry(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bridge(name).retried++
+}
+
+// queue adds n notifications (removes them when negative) to a bridge's
+// queue.
+func (m *metricsStore) queue(name string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bridge(name).queued += int64(n)
+}
+
+// errorMessage returns the error text without the request URL, which holds
+// the bot token for Telegram.
+func errorMessage(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// countDropped records n notifications dropped for every bridge, as
+// maintenance mode drops them before the bridges are called.
+func (s *Server) countDropped(n int) {
+	for _, bridge := range s.bridges {
+		s.metrics.drop(bridge.Name(), n)
+	}
+}
+
+// countQueued adds n notifications to the queue of every bridge, until each
+// bridge observes or drops them.
+func (s *Server) countQueued(n int) {
+	for _, bridge := range s.bridges {
+		s.metrics.queue(bridge.Name(), n)
+	}
+}
+
+// newRetryPolicy returns the retries of failed bridge notifications, each
+// counted in /metrics, or nil when BridgeRetryMaxAttempts disables them.
+// Waits are capped, as notifications are delivered within the request.
+func (s *Server) newRetryPolicy() *pocketping.RetryPolicy {
+	if s.config.BridgeRetryMaxAttempts <= 1 {
+		return nil
+	}
+	return &pocketping.RetryPolicy{
+		MaxAttempts: s.config.BridgeRetryMaxAttempts,
+		MaxBackoff:  bridgeRetryMaxBackoff,
+		Jitter:      0.2,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, errNotifySkipped) && !errors.Is(err, errNotifyDuplicate)
+		},
+		OnRetry: func(ctx context.Context, bridge, operation string, attempt int, err error) {
+			s.metrics.retry(bridge)
+		},
+	}
+}
+
+// bridgeErrors returns the last error of each bridge that has failed, for
+// /health.
+func (s *Server) bridgeErrors() map[string]interface{} {
+	s.metrics.mu.Lock()
+	defer s.metrics.mu.Unlock()
+	errs := make(map[string]interface{})
+	for name, b := range s.metrics.bridges {
+		if b.lastErrorAt.IsZero() {
+			continue
+		}
+		errs[name] = map[string]interface{}{
+			"lastError":   b.lastError,
+			"lastErrorAt": formatTime(b.lastErrorAt),
+		}
+	}
+	return errs
+}
+
+// handleMetrics handles GET /metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var out strings.Builder
+
+	s.metrics.mu.Lock()
+	metrics := make(map[string]bridgeMetrics, len(s.bridges))
+	for _, bridge := range s.bridges {
+		b := *s.metrics.bridge(bridge.Name())
+		b.buckets = append([]int64(nil), b.buckets...)
+		metrics[bridge.Name()] = b
+	}
+	s.metrics.mu.Unlock()
+
+	out.WriteString("# HELP pocketping_bridge_notifications_total Bridge notifications by outcome.\n")
+	out.WriteString("# TYPE pocketping_bridge_notifications_total counter\n")
+	for _, bridge := range s.bridges {
+		name := bridge.Name()
+		b := metrics[name]
+		fmt.Fprintf(&out, "pocketping_bridge_notifications_total{bridge=%q,outcome=\"sent\"} %d\n", name, b.sent)
+		fmt.Fprintf(&out, "pocketping_bridge_notifications_total{bridge=%q,outcome=\"failed\"} %d\n", name, b.failed)
+		fmt.Fprintf(&out, "pocketping_bridge_notifications_total{bridge=%q,outcome=\"retried\"} %d\n", name, b.retried)
+		fmt.Fprintf(&out, "pocketping_bridge_notifications_total{bridge=%q,outcome=\"dropped\"} %d\n", name, b.dropped)
+	}
+
+	out.WriteString("# HELP pocketping_bridge_notification_duration_seconds Time spent delivering a notification to a bridge.\n")
+	out.WriteString("# TYPE pocketping_bridge_notification_duration_seconds histogram\n")
+	for _, bridge := range s.bridges {
+		name := bridge.Name()
+		b := metrics[name]
+		var count int64
+		for i, bound := range notifyDurationBuckets {
+			count += b.buckets[i]
+			fmt.Fprintf(&out, "pocketping_bridge_notification_duration_seconds_bucket{bridge=%q,le=\"%g\"} %d\n", name, bound, count)
+		}
+		count += b.buckets[len(notifyDurationBuckets)]
+		fmt.Fprintf(&out, "pocketping_bridge_notification_duration_seconds_bucket{bridge=%q,le=\"+Inf\"} %d\n", name, count)
+		fmt.Fprintf(&out, "pocketping_bridge_notification_duration_seconds_sum{bridge=%q} %g\n", name, b.durationSum)
+		fmt.Fprintf(&out, "pocketping_bridge_notification_duration_seconds_count{bridge=%q} %d\n", name, count)
+	}
+
+	out.WriteString("# HELP pocketping_bridge_queue_depth Notifications waiting for or being delivered to a bridge, maintenance buffer included.\n")
+	out.WriteString("# TYPE pocketping_bridge_queue_depth gauge\n")
+	for _, bridge := range s.bridges {
+		fmt.Fprintf(&out, "pocketping_bridge_queue_depth{bridge=%q} %d\n", bridge.Name(), metrics[bridge.Name()].queued)
+	}
+
+	out.WriteString("# HELP pocketping_bridge_last_error_timestamp_seconds Unix time of the last failed notification.\n")
+	out.WriteString("# TYPE pocketping_bridge_last_error_timestamp_seconds gauge\n")
+	for _, bridge := range s.bridges {
+		var at int64
+		if b := metrics[bridge.Name()]; !b.lastErrorAt.IsZero() {
+			at = b.lastErrorAt.Unix()
+		}
+		fmt.Fprintf(&out, "pocketping_bridge_last_error_timestamp_seconds{bridge=%q} %d\n", bridge.Name(), at)
+	}
+
+	skews := s.clock.Stats()
+	sources := make([]string, 0, len(skews))
+	for source := range skews {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	out.WriteString("# HELP pocketping_clock_skew_seconds Platform minus local time of the last timestamp from each source.\n")
+	out.WriteString("# TYPE pocketping_clock_skew_seconds gauge\n")
+	for _, source := range sources {
+		fmt.Fprintf(&out, "pocketping_clock_skew_seconds{source=%q} %g\n", source, skews[source].LastSkew.Seconds())
+	}
+	out.WriteString("# HELP pocketping_clock_skewed_timestamps_total Timestamps beyond the skew threshold, replaced with local time.\n")
+	out.WriteString("# TYPE pocketping_clock_skewed_timestamps_total counter\n")
+	for _, source := range sources {
+		fmt.Fprintf(&out, "pocketping_clock_skewed_timestamps_total{source=%q} %d\n", source, skews[source].Skewed)
+	}
+
+	out.WriteString("# HELP pocketping_open_streams Open SSE and WebSocket streams.\n")
+	out.WriteString("# TYPE pocketping_open_streams gauge\n")
+	fmt.Fprintf(&out, "pocketping_open_streams %d\n", s.streams.open())
+	out.WriteString("# HELP pocketping_recovered_panics_total Handler panics recovered.\n")
+	out.WriteString("# TYPE pocketping_recovered_panics_total counter\n")
+	fmt.Fprintf(&out, "pocketping_recovered_panics_total %d\n", pocketping.RecoveredPanics())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(out.String()))
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// failingBridge is a mock bridge whose new-session notifications fail.
+type failingBridge struct {
+	*mockBridge
+}
+
+func (b failingBridge) OnNewSession(ctx context.Context, session *types.Session) error {
+	return errors.New("slack API error: ratelimited")
+}
+
+// flakyBridge is a mock bridge whose first failures new-session
+// notifications fail.
+type flakyBridge struct {
+	*mockBridge
+	failures int
+}
+
+func (b *flakyBridge) OnNewSession(ctx context.Context, session *types.Session) error {
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("telegram API error: 502 bad gateway")
+	}
+	return b.mockBridge.OnNewSession(ctx, session)
+}
+
+// blockingBridge is a mock bridge whose new-session notifications wait for
+// release.
+type blockingBridge struct {
+	*mockBridge
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b blockingBridge) OnNewSession(ctx context.Context, session *types.Session) error {
+	b.entered <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func getMetrics(t *testing.T, mux *http.ServeMux) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics_PerBridgeDelivery(t *testing.T) {
+	ok := newMockBridge("telegram")
+	failing := failingBridge{newMockBridge("slack")}
+	server, mux := setupTestServer([]bridges.Bridge{ok, failing}, nil)
+
+	_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: "s1"}})
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+
+	metrics := getMetrics(t, mux)
+	for _, want := range []string{
+		`pocketping_bridge_notifications_total{bridge="telegram",outcome="sent"} 2`,
+		`pocketping_bridge_notifications_total{bridge="telegram",outcome="dropped"} 1`,
+		`pocketping_bridge_notifications_total{bridge="slack",outcome="failed"} 1`,
+		`pocketping_bridge_notifications_total{bridge="slack",outcome="sent"} 1`,
+		`pocketping_bridge_notification_duration_seconds_bucket{bridge="telegram",le="+Inf"} 2`,
+		`pocketping_bridge_notification_duration_seconds_count{bridge="slack"} 2`,
+		`pocketping_bridge_queue_depth{bridge="slack"} 0`,
+	} {
+		if !strings.Contains(metrics, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, metrics)
+		}
+	}
+
+	errs, _ := getHealth(t, mux)["bridgeErrors"].(map[string]interface{})
+	slack, _ := errs["slack"].(map[string]interface{})
+	if len(errs) != 1 || slack["lastError"] != "slack API error: ratelimited" || slack["lastErrorAt"] == "" {
+		t.Errorf("unexpected bridgeErrors: %v", errs)
+	}
+}
+
+func TestMetrics_CountsRetries(t *testing.T) {
+	flaky := &flakyBridge{newMockBridge("telegram"), 1}
+	failing := failingBridge{newMockBridge("slack")}
+	server, mux := setupTestServer([]bridges.Bridge{flaky, failing}, &config.Config{BridgeRetryMaxAttempts: 3})
+	server.retry.InitialBackoff = time.Millisecond
+
+	_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: "s1"}})
+
+	metrics := getMetrics(t, mux)
+	for _, want := range []string{
+		`pocketping_bridge_notifications_total{bridge="telegram",outcome="retried"} 1`,
+		`pocketping_bridge_notifications_total{bridge="telegram",outcome="sent"} 1`,
+		`pocketping_bridge_notifications_total{bridge="telegram",outcome="failed"} 0`,
+		`pocketping_bridge_notifications_total{bridge="slack",outcome="retried"} 2`,
+		`pocketping_bridge_notifications_total{bridge="slack",outcome="failed"} 1`,
+	} {
+		if !strings.Contains(metrics, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, metrics)
+		}
+	}
+
+	// Duplicates aren't retried
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	if metrics := getMetrics(t, mux); !strings.Contains(metrics, `pocketping_bridge_notifications_total{bridge="telegram",outcome="retried"} 1`+"\n") {
+		t.Errorf("expected no retry of the duplicate:\n%s", metrics)
+	}
+}
+
+func TestMetrics_CountsMaintenanceQueueAndDrops(t *testing.T) {
+	server, mux := setupTestServer([]bridges.Bridge{newMockBridge("test")}, nil)
+
+	postMaintenance(t, mux, `{"enabled":true}`)
+	_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: "s1"}})
+	if metrics := getMetrics(t, mux); !strings.Contains(metrics, `pocketping_bridge_queue_depth{bridge="test"} 1`) {
+		t.Errorf("expected a queued notification:\n%s", metrics)
+	}
+
+	postMaintenance(t, mux, `{"enabled":false,"discard":true}`)
+	metrics := getMetrics(t, mux)
+	if !strings.Contains(metrics, `pocketping_bridge_notifications_total{bridge="test",outcome="dropped"} 1`) ||
+		!strings.Contains(metrics, `pocketping_bridge_queue_depth{bridge="test"} 0`) {
+		t.Errorf("expected the discarded notification counted as dropped:\n%s", metrics)
+	}
+}
+
+func TestMetrics_QueueDepthPerBridge(t *testing.T) {
+	slow := blockingBridge{newMockBridge("discord"), make(chan struct{}), make(chan struct{})}
+	server, mux := setupTestServer([]bridges.Bridge{newMockBridge("telegram"), slow}, nil)
+
+	done := make(chan struct{})
+	for _, id := range []string{"s1", "s2"} {
+		go func(id string) {
+			_ = server.processNewSession(context.Background(), &types.NewSessionEvent{Type: "new_session", Session: &types.Session{ID: id}})
+			done <- struct{}{}
+		}(id)
+		<-slow.entered
+	}
+
+	metrics := getMetrics(t, mux)
+	for _, want := range []string{
+		`pocketping_bridge_queue_depth{bridge="telegram"} 0`,
+		`pocketping_bridge_queue_depth{bridge="discord"} 2`,
+	} {
+		if !strings.Contains(metrics, want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, metrics)
+		}
+	}
+
+	close(slow.release)
+	<-done
+	<-done
+	if metrics := getMetrics(t, mux); !strings.Contains(metrics, `pocketping_bridge_queue_depth{bridge="discord"} 0`) {
+		t.Errorf("expected the discord queue drained:\n%s", metrics)
+	}
+}
+
+func TestErrorMessageStripsURL(t *testing.T) {
+	err := &url.Error{Op: "Post", URL: "https://api.telegram.org/bot123:secret/sendMessage", Err: errors.New("connection refused")}
+	if got := errorMessage(err); got != "connection refused" {
+		t.Errorf("errorMessage = %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// presenceStore is the registry of named operators. Each status update with
+// an operator ID is a heartbeat; operators that miss it for the presence TTL
+// go offline. In-memory, like the rest of the relay's state.
+type presenceStore struct {
+	mu        sync.Mutex
+	operators map[string]*operatorPresence
+	// globalOnline is the legacy status set by updates without an operator ID.
+	globalOnline bool
+}
+
+type operatorPresence struct {
+	name     string
+	lastSeen time.Time
+	expiry   *time.Timer
+}
+
+func newPresenceStore() *presenceStore {
+	return &presenceStore{operators: make(map[string]*operatorPresence)}
+}
+
+// anyOnline reports whether an operator is online. Callers hold mu.
+func (ps *presenceStore) anyOnline() bool {
+	return ps.globalOnline || len(ps.operators) > 0
+}
+
+func (s *Server) presenceTTL() time.Duration {
+	if s.config.OperatorPresenceTTL > 0 {
+		return s.config.OperatorPresenceTTL
+	}
+	return config.DefaultOperatorPresenceTTL
+}
+
+// updateOperatorPresence records a heartbeat (online) or sign-off (offline)
+// for a named operator and emits operator_presence when their status changes.
+func (s *Server) updateOperatorPresence(id, name string, online bool) {
+	now := time.Now()
+
+	s.presence.mu.Lock()
+	state, known := s.presence.operators[id]
+	if !online {
+		if !known {
+			s.presence.mu.Unlock()
+			return
+		}
+		state.expiry.Stop()
+		delete(s.presence.operators, id)
+		event := s.presenceEvent(id, state.name, false, now, "status")
+		s.presence.mu.Unlock()
+
+		s.EmitEvent(event)
+		return
+	}
+
+	if known {
+		state.lastSeen = now
+		if name != "" {
+			state.name = name
+		}
+		state.expiry.Reset(s.presenceTTL())
+		s.presence.mu.Unlock()
+		return
+	}
+	state = &operatorPresence{name: name, lastSeen: now}
+	state.expiry = time.AfterFunc(s.presenceTTL(), func() {
+		s.expireOperatorPresence(id, state)
+	})
+	s.presence.operators[id] = state
+	event := s.presenceEvent(id, name, true, now, "status")
+	s.presence.mu.Unlock()
+
+	s.EmitEvent(event)
+}
+
+// expireOperatorPresence takes an operator offline after a missed heartbeat,
+// unless they signed off or came back in the meantime.
+func (s *Server) expireOperatorPresence(id string, expired *operatorPresence) {
+	s.presence.mu.Lock()
+	if s.presence.operators[id] != expired {
+		s.presence.mu.Unlock()
+		return
+	}
+	delete(s.presence.operators, id)
+	event := s.presenceEvent(id, expired.name, false, expired.lastSeen, "expired")
+	s.presence.mu.Unlock()
+
+	log.Printf("[API] Operator %s presence expired", id)
+	s.EmitEvent(event)
+}
+
+// presenceEvent builds an operator_presence event. Callers hold mu.
+func (s *Server) presenceEvent(id, name string, online bool, lastSeen time.Time, reason string) *types.OperatorPresenceEvent {
+	return &types.OperatorPresenceEvent{
+		Type: "operator_presence",
+		Operator: &types.OperatorPresence{
+			ID:       id,
+			Name:     name,
+			Online:   online,
+			LastSeen: formatTime(lastSeen),
+		},
+		Online:    s.presence.anyOnline(),
+		Reason:    reason,
+		ChangedAt: formatTime(time.Now()),
+	}
+}
+
+// operatorStatus snapshots the presence registry, operators sorted by ID.
+func (s *Server) operatorStatus() *types.OperatorStatusResponse {
+	s.presence.mu.Lock()
+	defer s.presence.mu.Unlock()
+
+	response := &types.OperatorStatusResponse{
+		Online:    s.presence.anyOnline(),
+		Operators: make([]*types.OperatorPresence, 0, len(s.presence.operators)),
+	}
+	for id, state := range s.presence.operators {
+		response.Operators = append(response.Operators, &types.OperatorPresence{
+			ID:       id,
+			Name:     state.name,
+			Online:   true,
+			LastSeen: formatTime(state.lastSeen),
+		})
+	}
+	sort.Slice(response.Operators, func(i, j int) bool {
+		return response.Operators[i].ID < response.Operators[j].ID
+	})
+	return response
+}
+
+// handleGetOperatorStatus handles GET /api/operator/status
+func (s *Server) handleGetOperatorStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.operatorStatus())
+}
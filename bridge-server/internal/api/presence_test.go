@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+func postOperatorStatus(t *testing.T, mux *http.ServeMux, body string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/operator/status", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/operator/status = %d", w.Code)
+	}
+}
+
+func getOperatorStatus(t *testing.T, mux *http.ServeMux) types.OperatorStatusResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/operator/status", nil))
+	var status types.OperatorStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	return status
+}
+
+func TestPresence_NamedOperators(t *testing.T) {
+	server, mux := setupTestServer([]bridges.Bridge{newMockBridge("test")}, &config.Config{OperatorPresenceTTL: time.Minute})
+	events := listenEvents(t, server)
+
+	postOperatorStatus(t, mux, `{"online":true,"operatorId":"op-2","operatorName":"Bob"}`)
+	joined := nextEvent(t, events, "operator_presence").(*types.OperatorPresenceEvent)
+	if joined.Operator.ID != "op-2" || joined.Operator.Name != "Bob" || !joined.Operator.Online || !joined.Online || joined.Reason != "status" {
+		t.Errorf("unexpected presence event: %+v", joined)
+	}
+
+	// Heartbeats don't emit again.
+	postOperatorStatus(t, mux, `{"online":true,"operatorId":"op-2"}`)
+	postOperatorStatus(t, mux, `{"online":true,"operatorId":"op-1","operatorName":"Alice"}`)
+	if ev := nextEvent(t, events, "operator_presence").(*types.OperatorPresenceEvent); ev.Operator.ID != "op-1" {
+		t.Errorf("expected op-1 to join, got %+v", ev.Operator)
+	}
+
+	status := getOperatorStatus(t, mux)
+	if !status.Online || len(status.Operators) != 2 || status.Operators[0].Name != "Alice" || status.Operators[1].Name != "Bob" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	postOperatorStatus(t, mux, `{"online":false,"operatorId":"op-1"}`)
+	postOperatorStatus(t, mux, `{"online":false,"operatorId":"op-2"}`)
+	nextEvent(t, events, "operator_presence")
+	left := nextEvent(t, events, "operator_presence").(*types.OperatorPresenceEvent)
+	if left.Operator.ID != "op-2" || left.Operator.Online || left.Online {
+		t.Errorf("unexpected leave event: %+v", left)
+	}
+	if status := getOperatorStatus(t, mux); status.Online || len(status.Operators) != 0 {
+		t.Errorf("expected nobody online, got %+v", status)
+	}
+}
+
+func TestPresence_HeartbeatExpiry(t *testing.T) {
+	server, mux := setupTestServer(nil, &config.Config{OperatorPresenceTTL: 20 * time.Millisecond})
+	events := listenEvents(t, server)
+
+	postOperatorStatus(t, mux, `{"online":true,"operatorId":"op-1","operatorName":"Alice"}`)
+	nextEvent(t, events, "operator_presence")
+
+	expired := nextEvent(t, events, "operator_presence").(*types.OperatorPresenceEvent)
+	if expired.Reason != "expired" || expired.Operator.Online || expired.Online {
+		t.Errorf("unexpected expiry event: %+v", expired)
+	}
+	if status := getOperatorStatus(t, mux); len(status.Operators) != 0 {
+		t.Errorf("expected expired operator removed, got %+v", status.Operators)
+	}
+}
+
+func TestPresence_LegacyGlobalStatus(t *testing.T) {
+	_, mux := setupTestServer(nil, nil)
+
+	postOperatorStatus(t, mux, `{"online":true}`)
+	if status := getOperatorStatus(t, mux); !status.Online || len(status.Operators) != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
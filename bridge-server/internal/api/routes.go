@@ -17,6 +17,7 @@ import (
 	"time"
 
 	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
 	"github.com/pocketping/bridge-server/internal/bridges"
 	"github.com/pocketping/bridge-server/internal/config"
 	"github.com/pocketping/bridge-server/internal/types"
@@ -24,56 +25,113 @@ import (
 
 // Server handles HTTP requests for the bridge server
 type Server struct {
-	bridges        []bridges.Bridge
-	config         *config.Config
-	eventListeners sync.Map // map[chan types.OutgoingEvent]struct{}
-	bridgeIDs      sync.Map // map[string]*types.BridgeMessageIDs (messageID -> bridgeIDs)
-	messages       sync.Map // map[string]*types.Message (messageID -> message)
-	stats          *statsStore
+	bridges     []bridges.Bridge
+	config      *config.Config
+	bridgeIDs   sync.Map // map[string]*types.BridgeMessageIDs (messageID -> bridgeIDs)
+	messages    sync.Map // map[string]*types.Message (messageID -> message)
+	stats       *statsStore
+	deduper     pocketping.Deduper
+	workforce   *workforceStore
+	presence    *presenceStore
+	events      *eventLog
+	maintenance maintenanceGate
+	streams     *streamLimiter
+	metrics     *metricsStore
+	clock       *pocketping.ClockSkewDetector
+	retry       *pocketping.RetryPolicy
 }
 
 // NewServer creates a new API server
 func NewServer(bridgeList []bridges.Bridge, cfg *config.Config) *Server {
-	return &Server{
-		bridges: bridgeList,
-		config:  cfg,
-		stats:   newStatsStore(),
-	}
+	s := &Server{
+		bridges:   bridgeList,
+		config:    cfg,
+		stats:     newStatsStore(),
+		deduper:   newDeduper(cfg),
+		workforce: newWorkforceStore(),
+		presence:  newPresenceStore(),
+		events:    newEventLog(),
+		streams:   newStreamLimiter(),
+		metrics:   newMetricsStore(),
+		clock:     pocketping.NewClockSkewDetector(cfg.ClockSkewThreshold),
+	}
+	s.retry = s.newRetryPolicy()
+	return s
 }
 
 // SetupRoutes configures all HTTP routes
 func (s *Server) SetupRoutes(mux *http.ServeMux) {
+	// Every route gets a request ID and recovers from panics, so one bad
+	// request can't take the server down.
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, s.accessLogMiddleware(recoverMiddleware(handler)))
+	}
+
 	// Health check
-	mux.HandleFunc("GET /health", s.handleHealth)
+	handle("GET /health", s.handleHealth)
+	handle("GET /health/selftest", s.authMiddleware(s.handleSelfTest))
+
+	// Per-bridge delivery metrics in the Prometheus text format
+	handle("GET /metrics", s.authMiddleware(s.handleMetrics))
 
 	// Main event endpoint (incoming from app/SDK)
 	// UA filter is applied to block bot traffic before processing
-	mux.HandleFunc("POST /api/events", s.uaFilterMiddleware(s.authMiddleware(s.handleEvents)))
+	handle("POST /api/events", s.uaFilterMiddleware(s.authMiddleware(s.handleEvents)))
 
 	// Convenience endpoints
-	mux.HandleFunc("POST /api/sessions", s.uaFilterMiddleware(s.authMiddleware(s.handleNewSession)))
-	mux.HandleFunc("POST /api/messages", s.uaFilterMiddleware(s.authMiddleware(s.handleMessage)))
-	mux.HandleFunc("POST /api/operator/status", s.authMiddleware(s.handleOperatorStatus))
-	mux.HandleFunc("POST /api/custom-events", s.uaFilterMiddleware(s.authMiddleware(s.handleCustomEvent)))
-	mux.HandleFunc("POST /api/disconnect", s.uaFilterMiddleware(s.authMiddleware(s.handleDisconnect)))
+	handle("POST /api/sessions", s.uaFilterMiddleware(s.authMiddleware(s.handleNewSession)))
+	handle("POST /api/messages", s.uaFilterMiddleware(s.authMiddleware(s.handleMessage)))
+	handle("POST /api/operator/status", s.authMiddleware(s.handleOperatorStatus))
+	handle("GET /api/operator/status", s.authMiddleware(s.handleGetOperatorStatus))
+	handle("POST /api/custom-events", s.uaFilterMiddleware(s.authMiddleware(s.handleCustomEvent)))
+	handle("POST /api/disconnect", s.uaFilterMiddleware(s.authMiddleware(s.handleDisconnect)))
+
+	// Assignment pushes from workforce management tools
+	handle("POST /api/assignments", s.authMiddleware(s.handleAssignment))
+
+	// Maintenance mode: hold bridge notifications back during deploys
+	handle("POST /api/admin/maintenance", s.authMiddleware(s.handleMaintenance))
 
 	// SSE stream (outgoing to app/SDK)
-	mux.HandleFunc("GET /api/events/stream", s.authMiddleware(s.handleSSEStream))
+	handle("GET /api/events/stream", s.sseStreamHandler())
+	handle("GET /api/events/ws", s.authMiddleware(s.streamLimitMiddleware(s.handleWSStream)))
 
 	// Mini support-stats over the in-memory store, in the same JSON shape as the
 	// SaaS /api/v1/stats and the SDK GetStats. Registered at /api/v1/stats — the
 	// path the `pocketping stats` CLI and the MCP client already request — so
 	// pointing POCKETPING_API_URL at this instance works unchanged. /stats is
 	// kept as a convenience alias.
-	mux.HandleFunc("GET /api/v1/stats", s.authMiddleware(s.handleStats))
-	mux.HandleFunc("GET /stats", s.authMiddleware(s.handleStats))
+	handle("GET /api/v1/stats", s.authMiddleware(s.handleStats))
+	handle("GET /stats", s.authMiddleware(s.handleStats))
+
+	// Bulk CSV/NDJSON export of the conversations seen by the relay
+	handle("GET /api/export", s.authMiddleware(s.handleExport))
 
 	// Bridge webhooks (incoming from Telegram/Slack/Discord)
 	// These receive operator messages and forward them via SSE/webhook
 	// Note: These are not UA-filtered as they come from trusted bridge platforms
-	mux.HandleFunc("POST /webhooks/telegram", s.handleTelegramWebhook)
-	mux.HandleFunc("POST /webhooks/slack", s.handleSlackWebhook)
-	mux.HandleFunc("POST /webhooks/discord", s.handleDiscordWebhook)
+	handle("POST /webhooks/telegram", s.handleTelegramWebhook)
+	handle("POST /webhooks/slack", s.handleSlackWebhook)
+	handle("POST /webhooks/discord", s.handleDiscordWebhook)
+}
+
+// sseStreamHandler returns the handler chain of GET /api/events/stream,
+// which the self-test streams through too.
+func (s *Server) sseStreamHandler() http.HandlerFunc {
+	return s.authMiddleware(s.streamLimitMiddleware(s.handleSSEStream))
+}
+
+// HTTPServer returns the http.Server for handler on addr, with the configured
+// timeouts.
+func (s *Server) HTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.config.ReadHeaderTimeout,
+		ReadTimeout:       s.config.ReadTimeout,
+		WriteTimeout:      s.config.WriteTimeout,
+		IdleTimeout:       s.config.IdleTimeout,
+	}
 }
 
 // authMiddleware checks API key if configured
@@ -90,6 +148,12 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// recoverMiddleware turns a handler panic into a 500 with the stack trace
+// logged, counted in /health as "panics".
+func recoverMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return pocketping.RecoverHandler(next).ServeHTTP
+}
+
 // uaFilterMiddleware checks User-Agent against configured filters
 func (s *Server) uaFilterMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -138,10 +202,19 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		bridgeNames[i] = b.Name()
 	}
 
-	writeJSON(w, map[string]interface{}{
-		"status":  "ok",
-		"bridges": bridgeNames,
-	})
+	health := map[string]interface{}{
+		"status":       "ok",
+		"bridges":      bridgeNames,
+		"maintenance":  false,
+		"panics":       pocketping.RecoveredPanics(),
+		"streams":      s.streams.open(),
+		"bridgeErrors": s.bridgeErrors(),
+	}
+	if status := s.maintenanceStatus(); status.Enabled {
+		health["maintenance"] = true
+		health["maintenanceMessage"] = status.Message
+	}
+	writeJSON(w, health)
 }
 
 // handleEvents processes incoming events
@@ -161,57 +234,68 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := r.Context()
 	var handleErr error
 	switch base.Type {
-	case "new_session":
+	case events.TypeNewSession:
 		var event types.NewSessionEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processNewSession(&event)
+			handleErr = s.processNewSession(ctx, &event)
 		}
-	case "visitor_message":
+	case events.TypeVisitorMessage:
 		var event types.VisitorMessageEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processVisitorMessage(&event)
+			handleErr = s.processVisitorMessage(ctx, &event)
 		}
-	case "ai_takeover":
+	case events.TypeAITakeover:
 		var event types.AITakeoverEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processAITakeover(&event)
+			handleErr = s.processAITakeover(ctx, &event)
 		}
-	case "operator_status":
+	case events.TypeOperatorStatus:
 		var event types.OperatorStatusEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processOperatorStatus(&event)
+			handleErr = s.processOperatorStatus(ctx, &event)
 		}
-	case "message_read":
+	case events.TypeMessageRead:
 		var event types.MessageReadEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processMessageRead(&event)
+			handleErr = s.processMessageRead(ctx, &event)
 		}
-	case "custom_event":
+	case events.TypeCustomEvent:
 		var event types.CustomEventEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processCustomEvent(&event)
+			handleErr = s.processCustomEvent(ctx, &event)
 		}
-	case "identity_update":
+	case events.TypeIdentityUpdate:
 		var event types.IdentityUpdateEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processIdentityUpdate(&event)
+			handleErr = s.processIdentityUpdate(ctx, &event)
 		}
-	case "visitor_message_edited":
+	case events.TypeVisitorMessageEdited:
 		var event types.VisitorMessageEditedEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processVisitorMessageEdited(&event)
+			handleErr = s.processVisitorMessageEdited(ctx, &event)
 		}
-	case "visitor_message_deleted":
+	case events.TypeVisitorMessageDeleted:
 		var event types.VisitorMessageDeletedEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processVisitorMessageDeleted(&event)
+			handleErr = s.processVisitorMessageDeleted(ctx, &event)
 		}
-	case "csat_submitted":
+	case events.TypeCsatSubmitted:
 		var event types.CsatSubmittedEvent
 		if err := json.Unmarshal(body, &event); err == nil {
-			handleErr = s.processCsatSubmitted(&event)
+			handleErr = s.processCsatSubmitted(ctx, &event)
+		}
+	case events.TypeAssignmentUpdate:
+		var event types.AssignmentUpdateEvent
+		if err := json.Unmarshal(body, &event); err == nil {
+			handleErr = s.processAssignmentUpdate(ctx, &event)
+		}
+	case events.TypeCloseSession:
+		var event types.SessionCloseEvent
+		if err := json.Unmarshal(body, &event); err == nil {
+			handleErr = s.processSessionClose(ctx, &event)
 		}
 	default:
 		http.Error(w, `{"error":"Unknown event type"}`, http.StatusBadRequest)
@@ -233,8 +317,8 @@ func (s *Server) handleNewSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event := &types.NewSessionEvent{Type: "new_session", Session: &session}
-	if err := s.processNewSession(event); err != nil {
+	event := &types.NewSessionEvent{Type: events.TypeNewSession, Session: &session}
+	if err := s.processNewSession(r.Context(), event); err != nil {
 		log.Printf("[API] Error handling new session: %v", err)
 	}
 
@@ -253,11 +337,11 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	event := &types.VisitorMessageEvent{
-		Type:    "visitor_message",
+		Type:    events.TypeVisitorMessage,
 		Message: payload.Message,
 		Session: payload.Session,
 	}
-	if err := s.processVisitorMessage(event); err != nil {
+	if err := s.processVisitorMessage(r.Context(), event); err != nil {
 		log.Printf("[API] Error handling message: %v", err)
 	}
 
@@ -266,16 +350,14 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 
 // handleOperatorStatus handles POST /api/operator/status
 func (s *Server) handleOperatorStatus(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		Online bool `json:"online"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	var event types.OperatorStatusEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
 		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
 		return
 	}
+	event.Type = "operator_status"
 
-	event := &types.OperatorStatusEvent{Type: "operator_status", Online: payload.Online}
-	if err := s.processOperatorStatus(event); err != nil {
+	if err := s.processOperatorStatus(r.Context(), &event); err != nil {
 		log.Printf("[API] Error handling operator status: %v", err)
 	}
 
@@ -298,7 +380,7 @@ func (s *Server) handleCustomEvent(w http.ResponseWriter, r *http.Request) {
 		Event:   payload.Event,
 		Session: payload.Session,
 	}
-	if err := s.processCustomEvent(event); err != nil {
+	if err := s.processCustomEvent(r.Context(), event); err != nil {
 		log.Printf("[API] Error handling custom event: %v", err)
 	}
 
@@ -329,7 +411,7 @@ func (s *Server) handleDisconnect(w http.ResponseWriter, r *http.Request) {
 		Duration: payload.Duration,
 		Reason:   payload.Reason,
 	}
-	if err := s.processDisconnect(event); err != nil {
+	if err := s.processDisconnect(r.Context(), event); err != nil {
 		log.Printf("[API] Error handling disconnect: %v", err)
 	}
 
@@ -349,57 +431,59 @@ func (s *Server) handleSSEStream(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	eventChan := make(chan types.OutgoingEvent, 10)
-	s.eventListeners.Store(eventChan, struct{}{})
-	defer func() {
-		s.eventListeners.Delete(eventChan)
-		close(eventChan)
-	}()
-
-	// Heartbeat ticker
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case event := <-eventChan:
-			data, _ := json.Marshal(event)
-			fmt.Fprintf(w, "data: %s\n\n", data)
-			flusher.Flush()
-		case <-ticker.C:
-			fmt.Fprint(w, ": heartbeat\n\n")
-			flusher.Flush()
-		case <-r.Context().Done():
-			return
-		}
+	// The stream outlives the server WriteTimeout; each write gets its own
+	// deadline instead, so a stalled client is dropped.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(s.streamWriteDeadline())
+	// Send the headers now so clients see the stream open before the first
+	// event or heartbeat.
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	write := func(frame string) error {
+		_ = rc.SetWriteDeadline(s.streamWriteDeadline())
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
 	}
-}
 
-// EmitEvent broadcasts an event to all SSE listeners (exported for bridges)
-func (s *Server) EmitEvent(event types.OutgoingEvent) {
-	s.eventListeners.Range(func(key, _ interface{}) bool {
-		if ch, ok := key.(chan types.OutgoingEvent); ok {
-			select {
-			case ch <- event:
-			default:
-				// Channel full, skip
-			}
+	// Each event carries its cursor as the SSE id, so reconnecting clients
+	// resume through Last-Event-ID.
+	s.streamEvents(r.Context(), r, func(entry streamEvent) error {
+		data, err := json.Marshal(entry.event)
+		if err != nil {
+			return err
 		}
-		return true
+		return write(fmt.Sprintf("id: %d\ndata: %s\n\n", entry.cursor, data))
+	}, func() error {
+		return write(": heartbeat\n\n")
 	})
+}
+
+// EmitEvent broadcasts an event to the event streams (exported for bridges)
+func (s *Server) EmitEvent(event types.OutgoingEvent) {
+	s.emitEvent(context.Background(), event)
+}
+
+// emitEvent is EmitEvent for events caused by a request: webhook forwards
+// carry the request ID from ctx.
+func (s *Server) emitEvent(ctx context.Context, event types.OutgoingEvent) {
+	s.events.append(event)
 
 	// Send to backend webhook if configured
 	if s.config.BackendWebhookURL != "" {
-		go s.sendToWebhook(event)
+		go s.sendToWebhook(ctx, event)
 	}
 
 	// Also forward outgoing events (operator messages, edits, deletes, …) to the
 	// events webhook, so automations see the full conversation in both directions.
-	s.emitWebhookEvent(event.EventType(), map[string]interface{}{"event": event})
+	s.emitWebhookEvent(ctx, event.EventType(), map[string]interface{}{"event": event})
 }
 
 // sendToWebhook sends an event to the backend webhook
-func (s *Server) sendToWebhook(event types.OutgoingEvent) {
+func (s *Server) sendToWebhook(ctx context.Context, event types.OutgoingEvent) {
 	body, err := json.Marshal(event)
 	if err != nil {
 		return
@@ -410,6 +494,7 @@ func (s *Server) sendToWebhook(event types.OutgoingEvent) {
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setRequestIDHeader(ctx, req)
 	if s.config.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
 	}
@@ -528,7 +613,7 @@ func (s *Server) buildReplyQuote(messageID string) string {
 // Event Processors
 // ─────────────────────────────────────────────────────────────────
 
-func (s *Server) processNewSession(event *types.NewSessionEvent) error {
+func (s *Server) processNewSession(ctx context.Context, event *types.NewSessionEvent) error {
 	if event.Session != nil {
 		s.stats.recordSession(event.Session.ID, event.Session.CreatedAt)
 	}
@@ -539,16 +624,18 @@ func (s *Server) processNewSession(event *types.NewSessionEvent) error {
 	// is still recorded and the events webhook still fires.
 	if s.isBotSession(event.Session) {
 		log.Printf("[API] Skipping bridge notification for bot session %s", sessionID(event.Session))
-		s.emitWebhookEvent("new_session", map[string]interface{}{"session": event.Session, "isBot": true})
+		s.emitWebhookEvent(ctx, "new_session", map[string]interface{}{"session": event.Session, "isBot": true})
 		return nil
 	}
 
-	for _, bridge := range s.bridges {
-		if err := bridge.OnNewSession(event.Session); err != nil {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnNewSession(ctx, event.Session); err != nil {
 			log.Printf("[%s] OnNewSession error: %v", bridge.Name(), err)
+			return err
 		}
-	}
-	s.emitWebhookEvent("new_session", map[string]interface{}{"session": event.Session})
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "new_session", map[string]interface{}{"session": event.Session})
 	return nil
 }
 
@@ -572,63 +659,90 @@ func sessionID(session *types.Session) string {
 	return session.ID
 }
 
-func (s *Server) processVisitorMessage(event *types.VisitorMessageEvent) error {
+func (s *Server) processVisitorMessage(ctx context.Context, event *types.VisitorMessageEvent) error {
 	s.saveMessage(event.Message)
 	s.recordVisitorMessageStats(event)
-
-	var replyContext *bridges.ReplyContext
-	if event.Message.ReplyTo != "" {
-		replyIDs := s.getBridgeIDs(event.Message.ReplyTo)
-		replyQuote := s.buildReplyQuote(event.Message.ReplyTo)
-		if replyIDs != nil || replyQuote != "" {
-			replyContext = &bridges.ReplyContext{
-				BridgeIDs: replyIDs,
-				Quote:     replyQuote,
+	if id := event.Message.SessionID; id != "" {
+		s.trackVisitorWaiting(id, event.Message.Timestamp)
+	} else {
+		s.trackVisitorWaiting(sessionID(event.Session), event.Message.Timestamp)
+	}
+
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		// Looked up when sent, like the IDs of edits
+		var replyContext *bridges.ReplyContext
+		if event.Message.ReplyTo != "" {
+			replyIDs := s.getBridgeIDs(event.Message.ReplyTo)
+			replyQuote := s.buildReplyQuote(event.Message.ReplyTo)
+			if replyIDs != nil || replyQuote != "" {
+				replyContext = &bridges.ReplyContext{
+					BridgeIDs: replyIDs,
+					Quote:     replyQuote,
+				}
 			}
 		}
-	}
-
-	for _, bridge := range s.bridges {
-		ids, err := bridge.OnVisitorMessage(event.Message, event.Session, replyContext)
+		if !s.deduper.MarkDelivered(context.Background(), event.Message.ID, s.dedupeKey(bridge)) {
+			log.Printf("[%s] Suppressed duplicate visitor message %s (is the backend sending it twice, or is the SDK also configured with this bridge?)", bridge.Name(), event.Message.ID)
+			return errNotifyDuplicate
+		}
+		ids, err := bridge.OnVisitorMessage(ctx, event.Message, event.Session, replyContext)
 		if err != nil {
 			log.Printf("[%s] OnVisitorMessage error: %v", bridge.Name(), err)
-			continue
+			return err
 		}
 		if ids != nil {
 			s.saveBridgeIDs(event.Message.ID, ids)
 		}
-	}
-	s.emitWebhookEvent("visitor_message", map[string]interface{}{
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "visitor_message", map[string]interface{}{
 		"message": event.Message,
 		"session": event.Session,
 	})
 	return nil
 }
 
-func (s *Server) processAITakeover(event *types.AITakeoverEvent) error {
-	for _, bridge := range s.bridges {
-		if err := bridge.OnAITakeover(event.Session, event.Reason); err != nil {
+func (s *Server) processAITakeover(ctx context.Context, event *types.AITakeoverEvent) error {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnAITakeover(ctx, event.Session, event.Reason); err != nil {
 			log.Printf("[%s] OnAITakeover error: %v", bridge.Name(), err)
+			return err
 		}
-	}
-	s.emitWebhookEvent("ai_takeover", map[string]interface{}{"session": event.Session, "reason": event.Reason})
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "ai_takeover", map[string]interface{}{"session": event.Session, "reason": event.Reason})
 	return nil
 }
 
-func (s *Server) processOperatorStatus(event *types.OperatorStatusEvent) error {
+func (s *Server) processOperatorStatus(ctx context.Context, event *types.OperatorStatusEvent) error {
 	// Operator status is typically handled at the app level
 	// Bridges can react to this if needed
-	s.emitWebhookEvent("operator_status", map[string]interface{}{"online": event.Online})
+	if event.OperatorID != "" {
+		s.updateOperatorPresence(event.OperatorID, event.OperatorName, event.Online)
+	} else {
+		s.presence.mu.Lock()
+		s.presence.globalOnline = event.Online
+		s.presence.mu.Unlock()
+	}
+
+	payload := map[string]interface{}{"online": event.Online}
+	if event.OperatorID != "" {
+		payload["operatorId"] = event.OperatorID
+		payload["operatorName"] = event.OperatorName
+	}
+	s.emitWebhookEvent(ctx, "operator_status", payload)
 	return nil
 }
 
-func (s *Server) processMessageRead(event *types.MessageReadEvent) error {
-	for _, bridge := range s.bridges {
-		if err := bridge.OnMessageRead(event.SessionID, event.MessageIDs, event.Status); err != nil {
+func (s *Server) processMessageRead(ctx context.Context, event *types.MessageReadEvent) error {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnMessageRead(ctx, event.SessionID, event.MessageIDs, event.Status); err != nil {
 			log.Printf("[%s] OnMessageRead error: %v", bridge.Name(), err)
+			return err
 		}
-	}
-	s.emitWebhookEvent("message_read", map[string]interface{}{
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "message_read", map[string]interface{}{
 		"sessionId":  event.SessionID,
 		"messageIds": event.MessageIDs,
 		"status":     event.Status,
@@ -636,14 +750,17 @@ func (s *Server) processMessageRead(event *types.MessageReadEvent) error {
 	return nil
 }
 
-func (s *Server) processCustomEvent(event *types.CustomEventEvent) error {
-	for _, bridge := range s.bridges {
-		if err := bridge.OnCustomEvent(event.Event, event.Session); err != nil {
+func (s *Server) processCustomEvent(ctx context.Context, event *types.CustomEventEvent) error {
+	s.forgetEndedSession(event)
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnCustomEvent(ctx, event.Event, event.Session); err != nil {
 			log.Printf("[%s] OnCustomEvent error: %v", bridge.Name(), err)
+			return err
 		}
-	}
+		return nil
+	})
 
-	s.emitWebhookEvent("custom_event", map[string]interface{}{
+	s.emitWebhookEvent(ctx, "custom_event", map[string]interface{}{
 		"event":   event.Event,
 		"session": event.Session,
 	})
@@ -651,35 +768,40 @@ func (s *Server) processCustomEvent(event *types.CustomEventEvent) error {
 	return nil
 }
 
-func (s *Server) processIdentityUpdate(event *types.IdentityUpdateEvent) error {
-	for _, bridge := range s.bridges {
-		if err := bridge.OnIdentityUpdate(event.Session); err != nil {
+func (s *Server) processIdentityUpdate(ctx context.Context, event *types.IdentityUpdateEvent) error {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnIdentityUpdate(ctx, event.Session); err != nil {
 			log.Printf("[%s] OnIdentityUpdate error: %v", bridge.Name(), err)
+			return err
 		}
-	}
-	s.emitWebhookEvent("identity_update", map[string]interface{}{"session": event.Session})
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "identity_update", map[string]interface{}{"session": event.Session})
 	return nil
 }
 
-func (s *Server) processVisitorMessageEdited(event *types.VisitorMessageEditedEvent) error {
-	bridgeIDs := s.getBridgeIDs(event.MessageID)
+func (s *Server) processVisitorMessageEdited(ctx context.Context, event *types.VisitorMessageEditedEvent) error {
 	now := time.Now()
 	s.updateMessage(event.MessageID, func(msg *types.Message) {
 		msg.Content = event.Content
 		msg.EditedAt = &now
 	})
 
-	for _, bridge := range s.bridges {
-		ids, err := bridge.OnVisitorMessageEdited(event.SessionID, event.MessageID, event.Content, bridgeIDs)
+	// The bridge IDs are looked up when the edit is sent: after maintenance,
+	// the buffered message before it has only just been posted
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		bridgeIDs := s.getBridgeIDs(event.MessageID)
+		ids, err := bridge.OnVisitorMessageEdited(ctx, event.SessionID, event.MessageID, event.Content, bridgeIDs)
 		if err != nil {
 			log.Printf("[%s] OnVisitorMessageEdited error: %v", bridge.Name(), err)
-			continue
+			return err
 		}
 		if ids != nil {
 			s.saveBridgeIDs(event.MessageID, ids)
 		}
-	}
-	s.emitWebhookEvent("visitor_message_edited", map[string]interface{}{
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "visitor_message_edited", map[string]interface{}{
 		"sessionId": event.SessionID,
 		"messageId": event.MessageID,
 		"content":   event.Content,
@@ -687,26 +809,28 @@ func (s *Server) processVisitorMessageEdited(event *types.VisitorMessageEditedEv
 	return nil
 }
 
-func (s *Server) processVisitorMessageDeleted(event *types.VisitorMessageDeletedEvent) error {
-	bridgeIDs := s.getBridgeIDs(event.MessageID)
+func (s *Server) processVisitorMessageDeleted(ctx context.Context, event *types.VisitorMessageDeletedEvent) error {
 	now := time.Now()
 	s.updateMessage(event.MessageID, func(msg *types.Message) {
 		msg.DeletedAt = &now
 	})
 
-	for _, bridge := range s.bridges {
-		if err := bridge.OnVisitorMessageDeleted(event.SessionID, event.MessageID, bridgeIDs); err != nil {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		bridgeIDs := s.getBridgeIDs(event.MessageID)
+		if err := bridge.OnVisitorMessageDeleted(ctx, event.SessionID, event.MessageID, bridgeIDs); err != nil {
 			log.Printf("[%s] OnVisitorMessageDeleted error: %v", bridge.Name(), err)
+			return err
 		}
-	}
-	s.emitWebhookEvent("visitor_message_deleted", map[string]interface{}{
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "visitor_message_deleted", map[string]interface{}{
 		"sessionId": event.SessionID,
 		"messageId": event.MessageID,
 	})
 	return nil
 }
 
-func (s *Server) processDisconnect(event *types.VisitorDisconnectEvent) error {
+func (s *Server) processDisconnect(ctx context.Context, event *types.VisitorDisconnectEvent) error {
 	// Format duration for display
 	formatDuration := func(seconds int) string {
 		if seconds < 60 {
@@ -743,12 +867,14 @@ func (s *Server) processDisconnect(event *types.VisitorDisconnectEvent) error {
 	message := fmt.Sprintf("👋 %s left (was here for %s)", visitorName, formatDuration(event.Duration))
 
 	// Notify all bridges
-	for _, bridge := range s.bridges {
-		if err := bridge.OnVisitorDisconnect(event.Session, message); err != nil {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnVisitorDisconnect(ctx, event.Session, message); err != nil {
 			log.Printf("[%s] OnVisitorDisconnect error: %v", bridge.Name(), err)
+			return err
 		}
-	}
-	s.emitWebhookEvent("visitor_disconnect", map[string]interface{}{
+		return nil
+	})
+	s.emitWebhookEvent(ctx, "visitor_disconnect", map[string]interface{}{
 		"session":  event.Session,
 		"duration": event.Duration,
 		"reason":   event.Reason,
@@ -771,7 +897,7 @@ func csatFace(score int) string {
 // processCsatSubmitted relays a visitor's CSAT rating: it posts a one-line
 // caption to the session's bridge thread and forwards a `csat_submitted` events
 // webhook — the same notification + webhook the SaaS and SDKs emit.
-func (s *Server) processCsatSubmitted(event *types.CsatSubmittedEvent) error {
+func (s *Server) processCsatSubmitted(ctx context.Context, event *types.CsatSubmittedEvent) error {
 	if event.Session == nil {
 		return fmt.Errorf("csat_submitted: session is required")
 	}
@@ -794,13 +920,15 @@ func (s *Server) processCsatSubmitted(event *types.CsatSubmittedEvent) error {
 	// Reuse OnVisitorDisconnect as the plain-text thread channel: every bridge
 	// implements it as "send this message to the session's thread", which is
 	// exactly what the CSAT one-liner needs (no dedicated method required).
-	for _, bridge := range s.bridges {
-		if err := bridge.OnVisitorDisconnect(event.Session, caption); err != nil {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnVisitorDisconnect(ctx, event.Session, caption); err != nil {
 			log.Printf("[%s] OnVisitorDisconnect (csat) error: %v", bridge.Name(), err)
+			return err
 		}
-	}
+		return nil
+	})
 
-	s.emitWebhookEvent("csat_submitted", map[string]interface{}{
+	s.emitWebhookEvent(ctx, "csat_submitted", map[string]interface{}{
 		"sessionId":   event.Session.ID,
 		"score":       event.Score,
 		"comment":     event.Comment,
@@ -812,7 +940,7 @@ func (s *Server) processCsatSubmitted(event *types.CsatSubmittedEvent) error {
 	if err != nil {
 		respAt = time.Now()
 	}
-	s.stats.recordCsat(event.Session.ID, event.Score, respAt)
+	s.stats.recordCsat(event.Session.ID, event.Score, s.clock.Normalize(clockSourceBackend, respAt))
 	return nil
 }
 
@@ -830,22 +958,25 @@ func (s *Server) recordVisitorMessageStats(event *types.VisitorMessageEvent) {
 		}
 		createdAt = event.Session.CreatedAt
 	}
-	s.stats.recordMessage(sessionID, pocketping.SenderVisitor, event.Message.Timestamp, createdAt)
+	// The backend's clock may disagree with ours; operator replies are
+	// stamped locally, so normalize to keep response times positive.
+	ts := s.clock.Normalize(clockSourceBackend, event.Message.Timestamp)
+	s.stats.recordMessage(sessionID, pocketping.SenderVisitor, ts, createdAt)
 }
 
 // emitWebhookEvent forwards an event to the configured events webhook (Zapier,
 // Make, n8n, or any custom endpoint). No-op when no webhook is configured, and
 // runs in the background so it never blocks bridge delivery.
-func (s *Server) emitWebhookEvent(eventType string, data map[string]interface{}) {
+func (s *Server) emitWebhookEvent(ctx context.Context, eventType string, data map[string]interface{}) {
 	if s.config.EventsWebhookURL == "" {
 		return
 	}
-	go s.sendEventsWebhook(eventType, data)
+	go s.sendEventsWebhook(ctx, eventType, data)
 }
 
 // sendEventsWebhook POSTs a {type, data, sentAt} envelope, HMAC-signed with the
 // events webhook secret (X-PocketPing-Signature: sha256=<hex>).
-func (s *Server) sendEventsWebhook(eventType string, data map[string]interface{}) {
+func (s *Server) sendEventsWebhook(ctx context.Context, eventType string, data map[string]interface{}) {
 	payload := map[string]interface{}{
 		"type":   eventType,
 		"data":   data,
@@ -863,6 +994,7 @@ func (s *Server) sendEventsWebhook(eventType string, data map[string]interface{}
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-PocketPing-Event", eventType)
+	setRequestIDHeader(ctx, req)
 
 	// Add HMAC signature if secret is configured
 	if s.config.EventsWebhookSecret != "" {
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
 	"github.com/pocketping/bridge-server/internal/bridges"
 	"github.com/pocketping/bridge-server/internal/config"
 	"github.com/pocketping/bridge-server/internal/types"
@@ -32,8 +34,10 @@ type mockBridge struct {
 	lastSession       *types.Session
 	lastMessage       *types.Message
 	lastDisconnectMsg string
+	lastRequestID     string
 	eventCallback     bridges.EventCallback
 	returnBridgeIDs   *types.BridgeMessageIDs
+	lastBridgeIDs     *types.BridgeMessageIDs
 	mu                sync.Mutex
 }
 
@@ -47,15 +51,16 @@ func (m *mockBridge) SetEventCallback(cb bridges.EventCallback) {
 	m.eventCallback = cb
 }
 
-func (m *mockBridge) OnNewSession(session *types.Session) error {
+func (m *mockBridge) OnNewSession(ctx context.Context, session *types.Session) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.newSessionCalled++
 	m.lastSession = session
+	m.lastRequestID = bridges.RequestID(ctx)
 	return nil
 }
 
-func (m *mockBridge) OnVisitorMessage(msg *types.Message, session *types.Session, reply *bridges.ReplyContext) (*types.BridgeMessageIDs, error) {
+func (m *mockBridge) OnVisitorMessage(ctx context.Context, msg *types.Message, session *types.Session, reply *bridges.ReplyContext) (*types.BridgeMessageIDs, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.visitorMsgCalled++
@@ -64,63 +69,65 @@ func (m *mockBridge) OnVisitorMessage(msg *types.Message, session *types.Session
 	return m.returnBridgeIDs, nil
 }
 
-func (m *mockBridge) OnOperatorMessage(msg *types.Message, session *types.Session, sourceBridge, operatorName string) error {
+func (m *mockBridge) OnOperatorMessage(ctx context.Context, msg *types.Message, session *types.Session, sourceBridge, operatorName string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.operatorMsgCalled++
 	return nil
 }
 
-func (m *mockBridge) OnTyping(sessionID string, isTyping bool) error {
+func (m *mockBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.typingCalled++
 	return nil
 }
 
-func (m *mockBridge) OnMessageRead(sessionID string, messageIDs []string, status types.MessageStatus) error {
+func (m *mockBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status types.MessageStatus) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.messageReadCalled++
 	return nil
 }
 
-func (m *mockBridge) OnCustomEvent(event *types.CustomEvent, session *types.Session) error {
+func (m *mockBridge) OnCustomEvent(ctx context.Context, event *types.CustomEvent, session *types.Session) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.customEventCalled++
 	return nil
 }
 
-func (m *mockBridge) OnIdentityUpdate(session *types.Session) error {
+func (m *mockBridge) OnIdentityUpdate(ctx context.Context, session *types.Session) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.identityUpCalled++
 	return nil
 }
 
-func (m *mockBridge) OnAITakeover(session *types.Session, reason string) error {
+func (m *mockBridge) OnAITakeover(ctx context.Context, session *types.Session, reason string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.aiTakeoverCalled++
 	return nil
 }
 
-func (m *mockBridge) OnVisitorMessageEdited(sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
+func (m *mockBridge) OnVisitorMessageEdited(ctx context.Context, sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.msgEditedCalled++
+	m.lastBridgeIDs = bridgeIDs
 	return m.returnBridgeIDs, nil
 }
 
-func (m *mockBridge) OnVisitorMessageDeleted(sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
+func (m *mockBridge) OnVisitorMessageDeleted(ctx context.Context, sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.msgDeletedCalled++
+	m.lastBridgeIDs = bridgeIDs
 	return nil
 }
 
-func (m *mockBridge) OnVisitorDisconnect(session *types.Session, message string) error {
+func (m *mockBridge) OnVisitorDisconnect(ctx context.Context, session *types.Session, message string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.lastSession = session
@@ -179,6 +186,27 @@ func TestServer_handleHealth(t *testing.T) {
 	}
 }
 
+func TestRecoverMiddleware(t *testing.T) {
+	_, mux := setupTestServer(nil, nil)
+	handler := recoverMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/events", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	var response map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if panics, _ := response["panics"].(float64); panics < 1 {
+		t.Errorf("expected /health to count the panic, got %v", response["panics"])
+	}
+}
+
 func TestServer_authMiddleware(t *testing.T) {
 	bridge := newMockBridge("test")
 	cfg := &config.Config{APIKey: "secret123"}
@@ -465,9 +493,7 @@ func TestServer_EmitEvent(t *testing.T) {
 	server, _ := setupTestServer([]bridges.Bridge{bridge}, nil)
 
 	// Create an event listener
-	eventChan := make(chan types.OutgoingEvent, 10)
-	server.eventListeners.Store(eventChan, struct{}{})
-	defer server.eventListeners.Delete(eventChan)
+	eventChan := listenEvents(t, server)
 
 	// Emit event
 	event := &types.OperatorMessageEvent{
@@ -738,13 +764,66 @@ func TestServer_ProcessorsWithReply(t *testing.T) {
 		Session: &types.Session{ID: "s1"},
 	}
 
-	server.processVisitorMessage(event)
+	server.processVisitorMessage(context.Background(), event)
 
 	if bridge.visitorMsgCalled != 1 {
 		t.Errorf("expected OnVisitorMessage to be called")
 	}
 }
 
+func TestServer_ProcessVisitorMessageSuppressesDuplicates(t *testing.T) {
+	bridge := newMockBridge("telegram")
+	server, _ := setupTestServer([]bridges.Bridge{bridge}, nil)
+
+	event := &types.VisitorMessageEvent{
+		Type:    "visitor_message",
+		Message: &types.Message{ID: "msg-1", Content: "Hello", Sender: types.SenderVisitor},
+		Session: &types.Session{ID: "s1"},
+	}
+
+	// The backend retries the same event (or posts it to both /api/events and /api/messages)
+	server.processVisitorMessage(context.Background(), event)
+	server.processVisitorMessage(context.Background(), event)
+
+	if bridge.visitorMsgCalled != 1 {
+		t.Errorf("expected 1 delivery, got %d", bridge.visitorMsgCalled)
+	}
+}
+
+// destinationMockBridge is a mockBridge posting to a destination.
+type destinationMockBridge struct {
+	*mockBridge
+	destination string
+}
+
+func (b *destinationMockBridge) Destination() string { return b.destination }
+
+func TestServer_ProcessVisitorMessageDedupeKeys(t *testing.T) {
+	first := newMockBridge("custom")
+	second := newMockBridge("custom")
+	telegram := &destinationMockBridge{newMockBridge("telegram"), pocketping.BridgeDestination("telegram", "-100")}
+	server, _ := setupTestServer([]bridges.Bridge{first, second, telegram}, nil)
+
+	// The backend's SDK posted msg-1 to the same Telegram chat, through a
+	// deduper the bridge-server shares
+	shared := pocketping.NewMemoryDeduper(0)
+	shared.MarkDelivered(context.Background(), "msg-1", pocketping.BridgeDestination("telegram", "-100"))
+	server.deduper = shared
+
+	server.processVisitorMessage(context.Background(), &types.VisitorMessageEvent{
+		Type:    "visitor_message",
+		Message: &types.Message{ID: "msg-1", Content: "Hello", Sender: types.SenderVisitor},
+		Session: &types.Session{ID: "s1"},
+	})
+
+	if first.visitorMsgCalled != 1 || second.visitorMsgCalled != 1 {
+		t.Errorf("bridges sharing a name got %d and %d deliveries, want 1 each", first.visitorMsgCalled, second.visitorMsgCalled)
+	}
+	if telegram.visitorMsgCalled != 0 {
+		t.Errorf("the chat the SDK notified got %d deliveries", telegram.visitorMsgCalled)
+	}
+}
+
 func TestWriteJSON(t *testing.T) {
 	w := httptest.NewRecorder()
 	data := map[string]string{"key": "value"}
@@ -1025,14 +1104,14 @@ func TestServer_processCsatSubmitted_validation(t *testing.T) {
 	bridge := newMockBridge("telegram")
 	server, _ := setupTestServer([]bridges.Bridge{bridge}, nil)
 
-	if err := server.processCsatSubmitted(&types.CsatSubmittedEvent{Session: nil, Score: 5}); err == nil {
+	if err := server.processCsatSubmitted(context.Background(), &types.CsatSubmittedEvent{Session: nil, Score: 5}); err == nil {
 		t.Error("expected error for nil session")
 	}
 	sess := &types.Session{ID: "s1"}
-	if err := server.processCsatSubmitted(&types.CsatSubmittedEvent{Session: sess, Score: 0}); err == nil {
+	if err := server.processCsatSubmitted(context.Background(), &types.CsatSubmittedEvent{Session: sess, Score: 0}); err == nil {
 		t.Error("expected error for score 0")
 	}
-	if err := server.processCsatSubmitted(&types.CsatSubmittedEvent{Session: sess, Score: 6}); err == nil {
+	if err := server.processCsatSubmitted(context.Background(), &types.CsatSubmittedEvent{Session: sess, Score: 6}); err == nil {
 		t.Error("expected error for score 6")
 	}
 }
@@ -1057,7 +1136,7 @@ func TestProcessNewSession_botGating(t *testing.T) {
 	t.Run("datacenter IP is treated as bot, no bridge notification", func(t *testing.T) {
 		bridge := newMockBridge("telegram")
 		server, _ := setupTestServer([]bridges.Bridge{bridge}, &config.Config{BotHeuristicsEnabled: true})
-		_ = server.processNewSession(newEvent("34.72.176.129")) // GCP
+		_ = server.processNewSession(context.Background(), newEvent("34.72.176.129")) // GCP
 		if bridge.newSessionCalled != 0 {
 			t.Errorf("expected bot session to skip OnNewSession, called %d", bridge.newSessionCalled)
 		}
@@ -1066,7 +1145,7 @@ func TestProcessNewSession_botGating(t *testing.T) {
 	t.Run("residential IP notifies bridges", func(t *testing.T) {
 		bridge := newMockBridge("telegram")
 		server, _ := setupTestServer([]bridges.Bridge{bridge}, &config.Config{BotHeuristicsEnabled: true})
-		_ = server.processNewSession(newEvent("86.247.12.34"))
+		_ = server.processNewSession(context.Background(), newEvent("86.247.12.34"))
 		if bridge.newSessionCalled != 1 {
 			t.Errorf("expected human session to notify once, called %d", bridge.newSessionCalled)
 		}
@@ -1075,7 +1154,7 @@ func TestProcessNewSession_botGating(t *testing.T) {
 	t.Run("disabled heuristics notifies even datacenter IP", func(t *testing.T) {
 		bridge := newMockBridge("telegram")
 		server, _ := setupTestServer([]bridges.Bridge{bridge}, &config.Config{BotHeuristicsEnabled: false})
-		_ = server.processNewSession(newEvent("34.72.176.129"))
+		_ = server.processNewSession(context.Background(), newEvent("34.72.176.129"))
 		if bridge.newSessionCalled != 1 {
 			t.Errorf("expected notification when heuristics disabled, called %d", bridge.newSessionCalled)
 		}
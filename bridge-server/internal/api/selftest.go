@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// selfTestTimeout bounds the SSE stage of the self-test.
+const selfTestTimeout = 5 * time.Second
+
+// selfTestEvent is the fake event the self-test sends through a stream.
+type selfTestEvent struct {
+	Type  string `json:"type"`
+	Nonce string `json:"nonce"`
+}
+
+func (e *selfTestEvent) EventType() string { return e.Type }
+
+// selfTestStage is the result of one self-test stage.
+type selfTestStage struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	DurationMs int64  `json:"durationMs"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// handleSelfTest handles GET /health/selftest: an internal round trip through
+// event emission, each bridge in dry-run mode and SSE delivery, reported stage
+// by stage. It answers 503 when a stage fails, so orchestrators catch partial
+// failures that /health can't see. Nothing reaches the platforms or the
+// connected clients: the bridges are dry-run copies and the events go to a
+// private event log, streamed through the same handler chain as
+// /api/events/stream.
+func (s *Server) handleSelfTest(w http.ResponseWriter, r *http.Request) {
+	probe := &Server{config: s.config, events: newEventLog(), streams: s.streams}
+	nonce := newRequestID()
+
+	var stages []selfTestStage
+	run := func(name string, stage func() (string, error)) {
+		start := time.Now()
+		detail, err := stage()
+		result := selfTestStage{Name: name, OK: err == nil, DurationMs: time.Since(start).Milliseconds(), Detail: detail}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		stages = append(stages, result)
+	}
+
+	run("emit", func() (string, error) {
+		cursor, wake := probe.events.subscribe()
+		defer probe.events.unsubscribe(wake)
+		probe.events.append(&selfTestEvent{Type: "selftest", Nonce: nonce})
+		select {
+		case <-wake:
+		default:
+			return "", fmt.Errorf("event log didn't wake its subscribers")
+		}
+		if entries := probe.events.since(cursor); len(entries) != 1 {
+			return "", fmt.Errorf("event log returned %d events, want 1", len(entries))
+		}
+		return "", nil
+	})
+
+	for _, bridge := range s.bridges {
+		bridge := bridge
+		run("bridge:"+bridge.Name(), func() (string, error) {
+			dryRunner, ok := bridge.(bridges.DryRunner)
+			if !ok {
+				return "skipped: no dry run", nil
+			}
+			session := &types.Session{ID: "selftest", VisitorID: "selftest"}
+			message := &types.Message{ID: "selftest-" + nonce, SessionID: session.ID, Content: "PocketPing self-test", Sender: types.SenderVisitor, Timestamp: time.Now()}
+			if _, err := dryRunner.DryRun().OnVisitorMessage(r.Context(), message, session, nil); err != nil {
+				return "", err
+			}
+			return "dry run", nil
+		})
+	}
+
+	run("sse", func() (string, error) {
+		probe.events = newEventLog()
+		return probe.selfTestSSE(r.Context(), r.Header.Get("Authorization"), nonce)
+	})
+
+	ok := true
+	for _, stage := range stages {
+		ok = ok && stage.OK
+	}
+	status := "ok"
+	if !ok {
+		status = "fail"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, map[string]interface{}{"status": status, "stages": stages})
+}
+
+// selfTestSSE opens an SSE stream on a loopback listener, authorized as the
+// self-test request was, emits an event and waits for it to arrive.
+func (s *Server) selfTestSSE(ctx context.Context, authorization, nonce string) (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	srv := &http.Server{Handler: s.sseStreamHandler()}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	// Resume from the current cursor, so the stream gets the event even if
+	// it is emitted before the handler subscribes.
+	cursor, wake := s.events.subscribe()
+	s.events.unsubscribe(wake)
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://%s/?cursor=%d", ln.Addr(), cursor), nil)
+	if err != nil {
+		return "", err
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("open stream: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("open stream: status %d", resp.StatusCode)
+	}
+
+	s.events.append(&selfTestEvent{Type: "selftest", Nonce: nonce})
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data: ") && strings.Contains(scanner.Text(), nonce) {
+			return "event delivered", nil
+		}
+	}
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("event not delivered within %s", selfTestTimeout)
+	}
+	return "", fmt.Errorf("stream closed before the event: %v", scanner.Err())
+}
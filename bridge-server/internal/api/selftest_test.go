@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// brokenBridge is a bridge whose dry-run copy fails to notify.
+type brokenBridge struct {
+	*mockBridge
+}
+
+func (b *brokenBridge) DryRun() bridges.Bridge { return b }
+
+func (b *brokenBridge) OnVisitorMessage(ctx context.Context, msg *types.Message, session *types.Session, reply *bridges.ReplyContext) (*types.BridgeMessageIDs, error) {
+	return nil, errors.New("formatting failed")
+}
+
+type selfTestResult struct {
+	Status string          `json:"status"`
+	Stages []selfTestStage `json:"stages"`
+}
+
+func selfTest(t *testing.T, mux *http.ServeMux, apiKey string) (int, selfTestResult) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/health/selftest", nil)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var result selfTestResult
+	if w.Code != http.StatusUnauthorized {
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return w.Code, result
+}
+
+func TestSelfTest(t *testing.T) {
+	// Real bridges: the self-test must use their dry-run copies, since a
+	// platform call would fail here.
+	telegram, err := bridges.NewTelegramBridge(&config.TelegramConfig{BotToken: "123:abc", ChatID: "-100"})
+	if err != nil {
+		t.Fatalf("NewTelegramBridge: %v", err)
+	}
+	slack, err := bridges.NewSlackBridge(&config.SlackConfig{WebhookURL: "https://hooks.slack.com/services/T/B/X"})
+	if err != nil {
+		t.Fatalf("NewSlackBridge: %v", err)
+	}
+	mock := newMockBridge("test")
+	server, mux := setupTestServer([]bridges.Bridge{telegram, slack, mock}, &config.Config{APIKey: "secret"})
+
+	// Connected clients must not see the self-test events.
+	_, wake := server.events.subscribe()
+	defer server.events.unsubscribe(wake)
+
+	if code, _ := selfTest(t, mux, ""); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated selftest = %d, want 401", code)
+	}
+	code, result := selfTest(t, mux, "secret")
+	if code != http.StatusOK || result.Status != "ok" {
+		t.Fatalf("selftest = %d %+v", code, result)
+	}
+	var names []string
+	for _, stage := range result.Stages {
+		names = append(names, stage.Name)
+		if !stage.OK {
+			t.Errorf("stage %s failed: %s", stage.Name, stage.Error)
+		}
+	}
+	if len(names) != 5 || names[0] != "emit" || names[1] != "bridge:telegram" || names[2] != "bridge:slack" || names[3] != "bridge:test" || names[4] != "sse" {
+		t.Errorf("unexpected stages: %v", names)
+	}
+	if detail := result.Stages[3].Detail; detail != "skipped: no dry run" {
+		t.Errorf("bridge without dry run: %q", detail)
+	}
+	if mock.visitorMsgCalled != 0 {
+		t.Error("self-test notified a bridge without dry run")
+	}
+	select {
+	case <-wake:
+		t.Error("self-test event reached the shared event log")
+	default:
+	}
+}
+
+func TestSelfTestReportsFailedStage(t *testing.T) {
+	_, mux := setupTestServer([]bridges.Bridge{&brokenBridge{newMockBridge("broken")}}, nil)
+
+	code, result := selfTest(t, mux, "")
+	if code != http.StatusServiceUnavailable || result.Status != "fail" {
+		t.Fatalf("selftest = %d %+v, want 503 fail", code, result)
+	}
+	for _, stage := range result.Stages {
+		if stage.Name == "bridge:broken" && (stage.OK || stage.Error == "") {
+			t.Errorf("expected the broken stage to fail: %+v", stage)
+		}
+		if stage.Name == "sse" && !stage.OK {
+			t.Errorf("expected the sse stage to pass: %+v", stage)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+const (
+	// eventLogSize is how many recent events the stream endpoints can replay
+	// to a client reconnecting with a cursor.
+	eventLogSize = 1000
+
+	// streamHeartbeat is the interval of SSE heartbeats and WebSocket pings.
+	streamHeartbeat = 30 * time.Second
+)
+
+// streamEvent is an outgoing event with its cursor: a sequence number that
+// grows by one per emitted event, shared by the SSE and WebSocket streams.
+type streamEvent struct {
+	cursor uint64
+	event  types.OutgoingEvent
+}
+
+// eventLog keeps the most recent events so reconnecting stream clients can
+// resume from their last cursor, and wakes the open streams on each event.
+type eventLog struct {
+	mu      sync.Mutex
+	entries []streamEvent // oldest first, at most eventLogSize
+	last    uint64
+	waiters map[chan struct{}]struct{}
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{waiters: make(map[chan struct{}]struct{})}
+}
+
+func (l *eventLog) append(event types.OutgoingEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.last++
+	l.entries = append(l.entries, streamEvent{cursor: l.last, event: event})
+	if len(l.entries) > eventLogSize {
+		l.entries = l.entries[len(l.entries)-eventLogSize:]
+	}
+	for wake := range l.waiters {
+		select {
+		case wake <- struct{}{}:
+		default:
+			// Already woken
+		}
+	}
+}
+
+// since returns the events after cursor. Events older than the log are lost.
+func (l *eventLog) since(cursor uint64) []streamEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, entry := range l.entries {
+		if entry.cursor > cursor {
+			return append([]streamEvent(nil), l.entries[i:]...)
+		}
+	}
+	return nil
+}
+
+// subscribe returns the current cursor and a channel woken on new events.
+func (l *eventLog) subscribe() (uint64, chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	wake := make(chan struct{}, 1)
+	l.waiters[wake] = struct{}{}
+	return l.last, wake
+}
+
+func (l *eventLog) unsubscribe(wake chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.waiters, wake)
+}
+
+// streamCursor reads the resume cursor of a stream request: the "cursor"
+// query parameter, or the Last-Event-ID header SSE clients send when they
+// reconnect. ok is false when the client starts fresh.
+func streamCursor(r *http.Request) (cursor uint64, ok bool) {
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		raw = r.Header.Get("Last-Event-ID")
+	}
+	if raw == "" {
+		return 0, false
+	}
+	cursor, err := strconv.ParseUint(raw, 10, 64)
+	return cursor, err == nil
+}
+
+// streamEvents sends the events emitted after the request's cursor (or from
+// now on, without one) until ctx is done or a send fails. heartbeat runs every
+// streamHeartbeat.
+func (s *Server) streamEvents(ctx context.Context, r *http.Request, send func(streamEvent) error, heartbeat func() error) {
+	current, wake := s.events.subscribe()
+	defer s.events.unsubscribe(wake)
+
+	cursor, ok := streamCursor(r)
+	if !ok || cursor > current {
+		cursor = current
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		for _, entry := range s.events.since(cursor) {
+			if err := send(entry); err != nil {
+				return
+			}
+			cursor = entry.cursor
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamWriteDeadline is the deadline of the next SSE or WebSocket write:
+// WriteTimeout from now, or none when WriteTimeout is disabled.
+func (s *Server) streamWriteDeadline() time.Time {
+	if s.config.WriteTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(s.config.WriteTimeout)
+}
+
+// eventFrame is the JSON of an outgoing event with its "cursor" added, so
+// WebSocket clients can resume with ?cursor= after a reconnect.
+func eventFrame(entry streamEvent) ([]byte, error) {
+	data, err := json.Marshal(entry.event)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return data, nil
+	}
+	frame := []byte(fmt.Sprintf(`{"cursor":%d`, entry.cursor))
+	if len(data) > 2 {
+		frame = append(frame, ',')
+	}
+	return append(frame, data[1:]...), nil
+}
+
+var wsUpgrader = websocket.Upgrader{
+	// Clients authenticate with the API key, like the SSE stream, which
+	// allows any origin.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleWSStream handles GET /api/events/ws: the SSE stream's events over
+// WebSocket, one JSON event per text frame with its "cursor". The server
+// pings every streamHeartbeat and drops clients that stop answering.
+func (s *Server) handleWSStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[API] WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Read pongs and the close frame; clients send nothing else.
+	_ = conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	s.streamEvents(ctx, r, func(entry streamEvent) error {
+		frame, err := eventFrame(entry)
+		if err != nil {
+			return err
+		}
+		_ = conn.SetWriteDeadline(s.streamWriteDeadline())
+		return conn.WriteMessage(websocket.TextMessage, frame)
+	}, func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+	})
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+func operatorMessageEvent(content string) *types.OperatorMessageEvent {
+	return &types.OperatorMessageEvent{Type: "operator_message", SessionID: "s1", Content: content}
+}
+
+func dialEvents(t *testing.T, srv *httptest.Server, query string, header http.Header) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/events/ws" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type wsFrame struct {
+	Cursor  uint64 `json:"cursor"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) wsFrame {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var frame wsFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	return frame
+}
+
+// waitSubscribers waits until n streams are subscribed to the event log.
+func waitSubscribers(t *testing.T, server *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		server.events.mu.Lock()
+		count := len(server.events.waiters)
+		server.events.mu.Unlock()
+		if count >= n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %d stream subscribers", n)
+}
+
+func TestWSStream_EventsAndResume(t *testing.T) {
+	server, mux := setupTestServer(nil, &config.Config{APIKey: "secret"})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	auth := http.Header{"Authorization": {"Bearer secret"}}
+
+	conn := dialEvents(t, srv, "", auth)
+	waitSubscribers(t, server, 1)
+	server.EmitEvent(operatorMessageEvent("first"))
+
+	first := readFrame(t, conn)
+	if first.Type != "operator_message" || first.Content != "first" || first.Cursor == 0 {
+		t.Fatalf("unexpected frame: %+v", first)
+	}
+	conn.Close()
+
+	// Events emitted while disconnected are replayed from the cursor.
+	server.EmitEvent(operatorMessageEvent("second"))
+	server.EmitEvent(operatorMessageEvent("third"))
+
+	resumed := dialEvents(t, srv, "?cursor="+strconv.FormatUint(first.Cursor, 10), auth)
+	for _, want := range []string{"second", "third"} {
+		if frame := readFrame(t, resumed); frame.Content != want {
+			t.Errorf("replayed %q, want %q", frame.Content, want)
+		}
+	}
+}
+
+func TestWSStream_RequiresAuth(t *testing.T) {
+	_, mux := setupTestServer(nil, &config.Config{APIKey: "secret"})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/events/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got resp=%v err=%v", resp, err)
+	}
+}
+
+func TestSSEStream_ResumeWithLastEventID(t *testing.T) {
+	server, mux := setupTestServer(nil, nil)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	server.EmitEvent(operatorMessageEvent("one"))
+	server.EmitEvent(operatorMessageEvent("two"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/events/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for scanner.Scan() && len(lines) < 2 {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 || lines[0] != "id: 2" || !strings.Contains(lines[1], `"content":"two"`) {
+		t.Errorf("unexpected SSE lines: %q", lines)
+	}
+}
+
+func TestEventLog_DropsOldest(t *testing.T) {
+	log := newEventLog()
+	for i := 0; i < eventLogSize+5; i++ {
+		log.append(operatorMessageEvent("x"))
+	}
+	events := log.since(0)
+	if len(events) != eventLogSize || events[0].cursor != 6 {
+		t.Errorf("got %d events starting at %d, want %d starting at 6", len(events), events[0].cursor, eventLogSize)
+	}
+}
+
+func TestStreamsOutliveWriteTimeout(t *testing.T) {
+	server, mux := setupTestServer(nil, &config.Config{WriteTimeout: 200 * time.Millisecond})
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config = server.HTTPServer("", mux)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/events/stream")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+	conn := dialEvents(t, srv, "", nil)
+	defer conn.Close()
+	waitSubscribers(t, server, 2)
+
+	// Past the server WriteTimeout, both streams still deliver.
+	time.Sleep(400 * time.Millisecond)
+	server.EmitEvent(operatorMessageEvent("late"))
+
+	if frame := readFrame(t, conn); frame.Content != "late" {
+		t.Errorf("unexpected WebSocket frame: %+v", frame)
+	}
+	done := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "data: ") {
+				done <- scanner.Text()
+				return
+			}
+		}
+		done <- ""
+	}()
+	select {
+	case line := <-done:
+		if !strings.Contains(line, `"late"`) {
+			t.Errorf("unexpected SSE data: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SSE stream delivered nothing after WriteTimeout")
+	}
+}
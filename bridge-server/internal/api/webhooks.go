@@ -11,6 +11,7 @@ import (
 	"time"
 
 	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/pocketping/bridge-server/internal/bridges"
 	"github.com/pocketping/bridge-server/internal/types"
 )
 
@@ -25,13 +26,13 @@ func (s *Server) InitWebhooks() {
 		DiscordBotToken:  s.getDiscordBotToken(),
 		AllowedBotIDs:    s.getAllowedBotIDs(),
 		OnOperatorMessageWithIDs: func(ctx context.Context, sessionID, content, operatorName, sourceBridge string, attachments []pocketping.Attachment, replyToBridgeMessageID *int, bridgeMessageID string) {
-			s.RecordOperatorMessage(sessionID, content, operatorName, sourceBridge, attachments, replyToBridgeMessageID, bridgeMessageID)
+			s.RecordOperatorMessage(ctx, sessionID, content, operatorName, sourceBridge, attachments, replyToBridgeMessageID, bridgeMessageID)
 		},
 		OnOperatorMessageEdit: func(ctx context.Context, sessionID, bridgeMessageID, content, sourceBridge string, editedAt time.Time) {
-			s.RecordOperatorMessageEdit(sessionID, bridgeMessageID, content, sourceBridge, editedAt)
+			s.RecordOperatorMessageEdit(ctx, sessionID, bridgeMessageID, content, sourceBridge, editedAt)
 		},
 		OnOperatorMessageDelete: func(ctx context.Context, sessionID, bridgeMessageID, sourceBridge string, deletedAt time.Time) {
-			s.RecordOperatorMessageDelete(sessionID, bridgeMessageID, sourceBridge, deletedAt)
+			s.RecordOperatorMessageDelete(ctx, sessionID, bridgeMessageID, sourceBridge, deletedAt)
 		},
 	})
 }
@@ -71,11 +72,11 @@ func (s *Server) getAllowedBotIDs() []string {
 	return nil
 }
 
-func (s *Server) RecordOperatorMessage(sessionID, content, operatorName, sourceBridge string, attachments []pocketping.Attachment, replyToBridgeMessageID *int, bridgeMessageID string) {
+func (s *Server) RecordOperatorMessage(ctx context.Context, sessionID, content, operatorName, sourceBridge string, attachments []pocketping.Attachment, replyToBridgeMessageID *int, bridgeMessageID string) {
 	// Operator commands (e.g. "!csat") are consumed by the relay rather than
 	// relayed to the visitor as a chat message.
 	if cmd := parseOperatorCommand(content); cmd != nil {
-		if s.handleOperatorCommand(sessionID, cmd) {
+		if s.handleOperatorCommand(ctx, sessionID, sourceBridge, cmd) {
 			return
 		}
 	}
@@ -130,18 +131,19 @@ func (s *Server) RecordOperatorMessage(sessionID, content, operatorName, sourceB
 		Attachments:            bridgeAttachments,
 		ReplyToBridgeMessageID: replyToBridgeMessageID,
 	}
-	s.EmitEvent(event)
+	s.emitEvent(ctx, event)
 
 	// Record for GET /stats: an operator reply marks the conversation answered
 	// and feeds first-response-time.
 	s.stats.recordMessage(sessionID, pocketping.SenderOperator, message.Timestamp, time.Time{})
+	s.trackOperatorReply(sessionID, operatorName, sourceBridge, message.Timestamp)
 
 	// Sync to other bridges (cross-bridge sync)
-	s.syncOperatorMessageToBridges(message, sessionID, sourceBridge, operatorName, bridgeAttachments)
+	s.syncOperatorMessageToBridges(ctx, message, sessionID, sourceBridge, operatorName, bridgeAttachments)
 }
 
 // syncOperatorMessageToBridges sends operator messages to all bridges except the source
-func (s *Server) syncOperatorMessageToBridges(message *types.Message, sessionID, sourceBridge, operatorName string, attachments []*types.Attachment) {
+func (s *Server) syncOperatorMessageToBridges(ctx context.Context, message *types.Message, sessionID, sourceBridge, operatorName string, attachments []*types.Attachment) {
 	// Build content with attachment links for cross-bridge sync
 	contentWithAttachments := message.Content
 	if len(attachments) > 0 {
@@ -166,14 +168,16 @@ func (s *Server) syncOperatorMessageToBridges(message *types.Message, sessionID,
 	}
 
 	// Call OnOperatorMessage on all bridges except the source
-	for _, bridge := range s.bridges {
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
 		if bridge.Name() == sourceBridge {
-			continue
+			return errNotifySkipped
 		}
-		if err := bridge.OnOperatorMessage(syncMessage, session, sourceBridge, operatorName); err != nil {
+		if err := bridge.OnOperatorMessage(ctx, syncMessage, session, sourceBridge, operatorName); err != nil {
 			log.Printf("[%s] OnOperatorMessage sync error: %v", bridge.Name(), err)
+			return err
 		}
-	}
+		return nil
+	})
 }
 
 // formatAttachmentLinks formats attachment URLs for display in bridges
@@ -201,11 +205,18 @@ func formatAttachmentLinks(attachments []*types.Attachment) string {
 	return "\n\n" + strings.Join(links, "\n")
 }
 
-func (s *Server) RecordOperatorMessageEdit(sessionID, bridgeMessageID, content, sourceBridge string, editedAt time.Time) {
+// RecordOperatorMessageEdit records an operator edit made on a bridge.
+// editedAt is the platform's time; the message and event carry it normalized
+// to local time, never before the message itself.
+func (s *Server) RecordOperatorMessageEdit(ctx context.Context, sessionID, bridgeMessageID, content, sourceBridge string, editedAt time.Time) {
 	messageID := buildOperatorMessageID(sourceBridge, bridgeMessageID)
+	platformEditedAt := editedAt
+	editedAt = s.clock.Normalize(sourceBridge, editedAt)
 	s.updateMessage(messageID, func(msg *types.Message) {
+		editedAt = notBefore(editedAt, msg.Timestamp)
 		msg.Content = content
 		msg.EditedAt = &editedAt
+		msg.PlatformEditedAt = &platformEditedAt
 	})
 
 	event := &types.OperatorMessageEditedEvent{
@@ -215,13 +226,19 @@ func (s *Server) RecordOperatorMessageEdit(sessionID, bridgeMessageID, content,
 		Content:   content,
 		EditedAt:  editedAt,
 	}
-	s.EmitEvent(event)
+	s.emitEvent(ctx, event)
 }
 
-func (s *Server) RecordOperatorMessageDelete(sessionID, bridgeMessageID, sourceBridge string, deletedAt time.Time) {
+// RecordOperatorMessageDelete records an operator deletion made on a bridge,
+// normalizing deletedAt like RecordOperatorMessageEdit.
+func (s *Server) RecordOperatorMessageDelete(ctx context.Context, sessionID, bridgeMessageID, sourceBridge string, deletedAt time.Time) {
 	messageID := buildOperatorMessageID(sourceBridge, bridgeMessageID)
+	platformDeletedAt := deletedAt
+	deletedAt = s.clock.Normalize(sourceBridge, deletedAt)
 	s.updateMessage(messageID, func(msg *types.Message) {
+		deletedAt = notBefore(deletedAt, msg.Timestamp)
 		msg.DeletedAt = &deletedAt
+		msg.PlatformDeletedAt = &platformDeletedAt
 	})
 
 	event := &types.OperatorMessageDeletedEvent{
@@ -230,7 +247,7 @@ func (s *Server) RecordOperatorMessageDelete(sessionID, bridgeMessageID, sourceB
 		MessageID: messageID,
 		DeletedAt: deletedAt,
 	}
-	s.EmitEvent(event)
+	s.emitEvent(ctx, event)
 }
 
 // handleTelegramWebhook handles incoming Telegram webhook requests
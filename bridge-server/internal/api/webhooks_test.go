@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -22,11 +23,10 @@ func TestHandleTelegramWebhook_EditedMessageEmitsEvent(t *testing.T) {
 	}
 	server := NewServer(nil, cfg)
 
-	eventChan := make(chan types.OutgoingEvent, 1)
-	server.eventListeners.Store(eventChan, struct{}{})
-	defer server.eventListeners.Delete(eventChan)
+	eventChan := listenEvents(t, server)
 
-	payload := []byte(`{"edited_message":{"message_id":123,"message_thread_id":456,"text":"Updated message","edit_date":1700000000}}`)
+	editDate := time.Now().Add(-2 * time.Second).Unix()
+	payload := []byte(fmt.Sprintf(`{"edited_message":{"message_id":123,"message_thread_id":456,"text":"Updated message","edit_date":%d}}`, editDate))
 	req := httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload))
 	rec := httptest.NewRecorder()
 
@@ -51,7 +51,7 @@ func TestHandleTelegramWebhook_EditedMessageEmitsEvent(t *testing.T) {
 		if edited.Content != "Updated message" {
 			t.Errorf("expected content 'Updated message', got %q", edited.Content)
 		}
-		expectedTime := time.Unix(1700000000, 0)
+		expectedTime := time.Unix(editDate, 0)
 		if !edited.EditedAt.Equal(expectedTime) {
 			t.Errorf("expected editedAt %v, got %v", expectedTime, edited.EditedAt)
 		}
@@ -71,9 +71,7 @@ func TestHandleTelegramWebhook_DeleteCommandEmitsEvent(t *testing.T) {
 	}
 	server := NewServer(nil, cfg)
 
-	eventChan := make(chan types.OutgoingEvent, 1)
-	server.eventListeners.Store(eventChan, struct{}{})
-	defer server.eventListeners.Delete(eventChan)
+	eventChan := listenEvents(t, server)
 
 	payload := []byte(`{"message":{"message_id":200,"message_thread_id":456,"text":"/delete","reply_to_message":{"message_id":999}}}`)
 	req := httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload))
@@ -113,11 +111,10 @@ func TestHandleTelegramWebhook_ReactionEmitsEvent(t *testing.T) {
 	}
 	server := NewServer(nil, cfg)
 
-	eventChan := make(chan types.OutgoingEvent, 1)
-	server.eventListeners.Store(eventChan, struct{}{})
-	defer server.eventListeners.Delete(eventChan)
+	eventChan := listenEvents(t, server)
 
-	payload := []byte(`{"message_reaction":{"message_id":999,"message_thread_id":456,"new_reaction":[{"type":"emoji","emoji":"🗑️"}],"date":1700000000}}`)
+	date := time.Now().Add(-2 * time.Second).Unix()
+	payload := []byte(fmt.Sprintf(`{"message_reaction":{"message_id":999,"message_thread_id":456,"new_reaction":[{"type":"emoji","emoji":"🗑️"}],"date":%d}}`, date))
 	req := httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload))
 	rec := httptest.NewRecorder()
 
@@ -139,7 +136,7 @@ func TestHandleTelegramWebhook_ReactionEmitsEvent(t *testing.T) {
 		if deleted.MessageID != "telegram:999" {
 			t.Errorf("expected messageID 'telegram:999', got %q", deleted.MessageID)
 		}
-		expectedTime := time.Unix(1700000000, 0)
+		expectedTime := time.Unix(date, 0)
 		if !deleted.DeletedAt.Equal(expectedTime) {
 			t.Errorf("expected deletedAt %v, got %v", expectedTime, deleted.DeletedAt)
 		}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+const (
+	// workforceIdleTTL is how long a session's state is kept without
+	// activity: sessions that end without a close (the visitor just leaves)
+	// are forgotten after it.
+	workforceIdleTTL = 24 * time.Hour
+	// workforceSweepInterval is how often idle states are looked for.
+	workforceSweepInterval = time.Minute
+)
+
+// workforceStore tracks per-session assignment and first-response SLA state,
+// so workforce management tools can compute agent workload from the
+// assignment_changed / sla_state_changed / session_closed events.
+// In-memory, like the rest of the relay's state. A session's state is
+// dropped when it is closed, when the backend reports it closed, or after
+// workforceIdleTTL without activity.
+type workforceStore struct {
+	mu        sync.Mutex
+	sessions  map[string]*workforceSession
+	lastSweep time.Time
+}
+
+type workforceSession struct {
+	assignee     *types.Assignee
+	waitingSince time.Time // zero when nobody is waiting for a reply
+	breached     bool
+	slaTimer     *time.Timer
+	lastActivity time.Time
+}
+
+func newWorkforceStore() *workforceStore {
+	return &workforceStore{sessions: make(map[string]*workforceSession)}
+}
+
+// session returns the state for sessionID, creating it, and marks it
+// active. Callers hold mu.
+func (ws *workforceStore) session(sessionID string) *workforceSession {
+	now := time.Now()
+	if now.Sub(ws.lastSweep) >= workforceSweepInterval {
+		ws.sweep(now)
+	}
+	state, ok := ws.sessions[sessionID]
+	if !ok {
+		state = &workforceSession{}
+		ws.sessions[sessionID] = state
+	}
+	state.lastActivity = now
+	return state
+}
+
+// sweep forgets the sessions idle for workforceIdleTTL. Callers hold mu.
+func (ws *workforceStore) sweep(now time.Time) {
+	ws.lastSweep = now
+	for sessionID, state := range ws.sessions {
+		if now.Sub(state.lastActivity) >= workforceIdleTTL {
+			ws.forget(sessionID)
+		}
+	}
+}
+
+// forget stops the session's SLA clock and drops its state, returning its
+// assignee. Callers hold mu.
+func (ws *workforceStore) forget(sessionID string) *types.Assignee {
+	state, ok := ws.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	if state.slaTimer != nil {
+		state.slaTimer.Stop()
+	}
+	delete(ws.sessions, sessionID)
+	return state.assignee
+}
+
+func sameAssignee(a, b *types.Assignee) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// assignSession sets the session's assignee and emits assignment_changed when
+// it changes. Returns false when the assignee was already set.
+func (s *Server) assignSession(sessionID string, assignee *types.Assignee, source string) bool {
+	s.workforce.mu.Lock()
+	state := s.workforce.session(sessionID)
+	previous := state.assignee
+	if sameAssignee(previous, assignee) {
+		s.workforce.mu.Unlock()
+		return false
+	}
+	state.assignee = assignee
+	s.workforce.mu.Unlock()
+
+	s.EmitEvent(&types.AssignmentChangedEvent{
+		Type:             "assignment_changed",
+		SchemaVersion:    types.WorkforceSchemaVersion,
+		SessionID:        sessionID,
+		Assignee:         assignee,
+		PreviousAssignee: previous,
+		Source:           source,
+		ChangedAt:        formatTime(time.Now()),
+	})
+	return true
+}
+
+// trackVisitorWaiting starts the first-response SLA clock when a visitor
+// writes and nobody is already waiting for a reply.
+func (s *Server) trackVisitorWaiting(sessionID string, at time.Time) {
+	sla := s.config.FirstResponseSLA
+	if sla <= 0 || sessionID == "" {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	s.workforce.mu.Lock()
+	state := s.workforce.session(sessionID)
+	if !state.waitingSince.IsZero() {
+		s.workforce.mu.Unlock()
+		return
+	}
+	state.waitingSince = at
+	state.breached = false
+	deadline := at.Add(sla)
+	state.slaTimer = time.AfterFunc(time.Until(deadline), func() {
+		s.markSLABreached(sessionID, at)
+	})
+	event := s.slaEvent(sessionID, state, types.SLAStateWaiting)
+	s.workforce.mu.Unlock()
+
+	s.EmitEvent(event)
+}
+
+// markSLABreached fires when the deadline passes, unless the wait it was
+// armed for has ended.
+func (s *Server) markSLABreached(sessionID string, waitingSince time.Time) {
+	s.workforce.mu.Lock()
+	state, ok := s.workforce.sessions[sessionID]
+	if !ok || !state.waitingSince.Equal(waitingSince) {
+		s.workforce.mu.Unlock()
+		return
+	}
+	state.breached = true
+	event := s.slaEvent(sessionID, state, types.SLAStateBreached)
+	s.workforce.mu.Unlock()
+
+	s.EmitEvent(event)
+}
+
+// trackOperatorReply ends the current wait and auto-assigns unassigned
+// sessions to the replying operator.
+func (s *Server) trackOperatorReply(sessionID, operatorName, sourceBridge string, at time.Time) {
+	if sessionID == "" {
+		return
+	}
+
+	if operatorName != "" {
+		s.workforce.mu.Lock()
+		unassigned := s.workforce.session(sessionID).assignee == nil
+		s.workforce.mu.Unlock()
+		if unassigned {
+			s.assignSession(sessionID, &types.Assignee{
+				ID:   fmt.Sprintf("%s:%s", sourceBridge, operatorName),
+				Name: operatorName,
+			}, sourceBridge)
+		}
+	}
+
+	s.workforce.mu.Lock()
+	state, ok := s.workforce.sessions[sessionID]
+	if !ok || state.waitingSince.IsZero() {
+		s.workforce.mu.Unlock()
+		return
+	}
+	if state.slaTimer != nil {
+		state.slaTimer.Stop()
+		state.slaTimer = nil
+	}
+	event := s.slaEvent(sessionID, state, types.SLAStateResponded)
+	event.RespondedAt = formatTime(at)
+	event.Breached = at.After(state.waitingSince.Add(s.config.FirstResponseSLA))
+	state.waitingSince = time.Time{}
+	state.breached = false
+	s.workforce.mu.Unlock()
+
+	s.EmitEvent(event)
+}
+
+// slaEvent builds an SLA event from the current state. Callers hold mu.
+func (s *Server) slaEvent(sessionID string, state *workforceSession, slaState string) *types.SLAStateChangedEvent {
+	return &types.SLAStateChangedEvent{
+		Type:          "sla_state_changed",
+		SchemaVersion: types.WorkforceSchemaVersion,
+		SessionID:     sessionID,
+		State:         slaState,
+		Assignee:      state.assignee,
+		WaitingSince:  formatTime(state.waitingSince),
+		Deadline:      formatTime(state.waitingSince.Add(s.config.FirstResponseSLA)),
+		Breached:      state.breached,
+		ChangedAt:     formatTime(time.Now()),
+	}
+}
+
+// closeSession stops SLA tracking for the session, forgets its assignment and
+// emits session_closed.
+func (s *Server) closeSession(ctx context.Context, sessionID, source, reason string) {
+	s.workforce.mu.Lock()
+	assignee := s.workforce.forget(sessionID)
+	s.workforce.mu.Unlock()
+
+	s.emitEvent(ctx, &types.SessionClosedEvent{
+		Type:          "session_closed",
+		SchemaVersion: types.WorkforceSchemaVersion,
+		SessionID:     sessionID,
+		SourceBridge:  source,
+		Assignee:      assignee,
+		Reason:        reason,
+		ClosedAt:      formatTime(time.Now()),
+	})
+}
+
+// processAssignmentUpdate applies an assignment pushed through the inbound API
+// and posts a one-liner to the session's bridge threads.
+func (s *Server) processAssignmentUpdate(ctx context.Context, event *types.AssignmentUpdateEvent) error {
+	if event.SessionID == "" {
+		return fmt.Errorf("assignment_update: sessionId is required")
+	}
+	if event.Assignee != nil && event.Assignee.ID == "" {
+		return fmt.Errorf("assignment_update: assignee.id is required")
+	}
+	if !s.assignSession(event.SessionID, event.Assignee, "api") {
+		return nil
+	}
+
+	caption := "👤 Unassigned"
+	if event.Assignee != nil {
+		name := event.Assignee.Name
+		if name == "" {
+			name = event.Assignee.ID
+		}
+		caption = fmt.Sprintf("👤 Assigned to %s", name)
+	}
+	// OnVisitorDisconnect is the plain-text thread channel (see
+	// processCsatSubmitted).
+	session := &types.Session{ID: event.SessionID}
+	s.notifyBridges(ctx, func(ctx context.Context, bridge bridges.Bridge) error {
+		if err := bridge.OnVisitorDisconnect(ctx, session, caption); err != nil {
+			log.Printf("[%s] OnVisitorDisconnect (assignment) error: %v", bridge.Name(), err)
+			return err
+		}
+		return nil
+	})
+	return nil
+}
+
+// forgetEndedSession drops the state of a session the backend reports
+// closed with its session_closed custom event (the SDK's inactivity
+// lifecycle), which reaches the relay as a custom event.
+func (s *Server) forgetEndedSession(event *types.CustomEventEvent) {
+	if event.Event == nil || event.Event.Name != "session_closed" {
+		return
+	}
+	sessionID := event.Event.SessionID
+	if sessionID == "" && event.Session != nil {
+		sessionID = event.Session.ID
+	}
+	s.workforce.mu.Lock()
+	s.workforce.forget(sessionID)
+	s.workforce.mu.Unlock()
+}
+
+func (s *Server) processSessionClose(ctx context.Context, event *types.SessionCloseEvent) error {
+	if event.SessionID == "" {
+		return fmt.Errorf("close_session: sessionId is required")
+	}
+	s.closeSession(ctx, event.SessionID, "api", event.Reason)
+	return nil
+}
+
+// handleAssignment handles POST /api/assignments
+func (s *Server) handleAssignment(w http.ResponseWriter, r *http.Request) {
+	var event types.AssignmentUpdateEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+	event.Type = "assignment_update"
+
+	if err := s.processAssignmentUpdate(r.Context(), &event); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeOK(w)
+}
+
+// writeJSONError writes {"error": message} with the given status.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
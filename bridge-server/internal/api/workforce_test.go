@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pocketping/bridge-server/internal/bridges"
+	"github.com/pocketping/bridge-server/internal/config"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// listenEvents follows server's event log from now on, like a stream
+// client, for the test's duration.
+func listenEvents(t *testing.T, server *Server) chan types.OutgoingEvent {
+	t.Helper()
+	ch := make(chan types.OutgoingEvent, 20)
+	cursor, wake := server.events.subscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		server.events.unsubscribe(wake)
+	})
+	go func() {
+		for {
+			for _, entry := range server.events.since(cursor) {
+				select {
+				case ch <- entry.event:
+				case <-ctx.Done():
+					return
+				}
+				cursor = entry.cursor
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// nextEvent waits for the next event of the given type, skipping others.
+func nextEvent(t *testing.T, ch chan types.OutgoingEvent, eventType string) types.OutgoingEvent {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-ch:
+			if ev.EventType() == eventType {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s event", eventType)
+			return nil
+		}
+	}
+}
+
+func visitorMessage(sessionID, messageID string) *types.VisitorMessageEvent {
+	return &types.VisitorMessageEvent{
+		Type:    "visitor_message",
+		Message: &types.Message{ID: messageID, SessionID: sessionID, Content: "hi", Sender: types.SenderVisitor, Timestamp: time.Now()},
+		Session: &types.Session{ID: sessionID},
+	}
+}
+
+func TestWorkforce_OperatorReplyAssignsAndMeetsSLA(t *testing.T) {
+	server, _ := setupTestServer([]bridges.Bridge{newMockBridge("telegram")}, &config.Config{FirstResponseSLA: time.Minute})
+	events := listenEvents(t, server)
+
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	waiting := nextEvent(t, events, "sla_state_changed").(*types.SLAStateChangedEvent)
+	if waiting.State != types.SLAStateWaiting || waiting.SchemaVersion != types.WorkforceSchemaVersion {
+		t.Errorf("unexpected waiting event: %+v", waiting)
+	}
+
+	server.RecordOperatorMessage(context.Background(), "s1", "hello!", "Alice", "telegram", nil, nil, "200")
+
+	assigned := nextEvent(t, events, "assignment_changed").(*types.AssignmentChangedEvent)
+	if assigned.Assignee == nil || assigned.Assignee.Name != "Alice" || assigned.PreviousAssignee != nil || assigned.Source != "telegram" {
+		t.Errorf("unexpected assignment event: %+v", assigned)
+	}
+	responded := nextEvent(t, events, "sla_state_changed").(*types.SLAStateChangedEvent)
+	if responded.State != types.SLAStateResponded || responded.Breached || responded.Assignee == nil {
+		t.Errorf("unexpected responded event: %+v", responded)
+	}
+
+	// A second reply from another operator doesn't steal the assignment.
+	server.RecordOperatorMessage(context.Background(), "s1", "me too", "Bob", "telegram", nil, nil, "201")
+	select {
+	case ev := <-events:
+		if ev.EventType() == "assignment_changed" || ev.EventType() == "sla_state_changed" {
+			t.Errorf("unexpected %s after second reply", ev.EventType())
+		}
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWorkforce_SLABreach(t *testing.T) {
+	server, _ := setupTestServer(nil, &config.Config{FirstResponseSLA: 20 * time.Millisecond})
+	events := listenEvents(t, server)
+
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	nextEvent(t, events, "sla_state_changed")
+
+	breached := nextEvent(t, events, "sla_state_changed").(*types.SLAStateChangedEvent)
+	if breached.State != types.SLAStateBreached || !breached.Breached {
+		t.Errorf("unexpected breach event: %+v", breached)
+	}
+
+	server.RecordOperatorMessage(context.Background(), "s1", "sorry for the wait", "Alice", "slack", nil, nil, "1.2")
+	responded := nextEvent(t, events, "sla_state_changed").(*types.SLAStateChangedEvent)
+	if responded.State != types.SLAStateResponded || !responded.Breached {
+		t.Errorf("expected late response to be flagged as breached: %+v", responded)
+	}
+}
+
+func TestWorkforce_SLADisabled(t *testing.T) {
+	server, _ := setupTestServer(nil, nil)
+	events := listenEvents(t, server)
+
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	select {
+	case ev := <-events:
+		t.Errorf("expected no SLA events without FirstResponseSLA, got %s", ev.EventType())
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestWorkforce_AssignmentPush(t *testing.T) {
+	bridge := newMockBridge("telegram")
+	server, mux := setupTestServer([]bridges.Bridge{bridge}, nil)
+	events := listenEvents(t, server)
+
+	post := func(body string) int {
+		req := httptest.NewRequest("POST", "/api/assignments", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(`{"sessionId":"s1","assignee":{"id":"agent-7","name":"Alice"}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	ev := nextEvent(t, events, "assignment_changed").(*types.AssignmentChangedEvent)
+	if ev.Source != "api" || ev.Assignee.ID != "agent-7" {
+		t.Errorf("unexpected assignment event: %+v", ev)
+	}
+	if bridge.lastDisconnectMsg != "👤 Assigned to Alice" {
+		t.Errorf("expected bridge thread notice, got %q", bridge.lastDisconnectMsg)
+	}
+
+	// Re-pushing the same assignee is a no-op.
+	bridge.lastDisconnectMsg = ""
+	post(`{"sessionId":"s1","assignee":{"id":"agent-7","name":"Alice"}}`)
+	if bridge.lastDisconnectMsg != "" {
+		t.Errorf("expected no notice for unchanged assignment, got %q", bridge.lastDisconnectMsg)
+	}
+
+	// Unassign through the generic events endpoint.
+	req := httptest.NewRequest("POST", "/api/events", bytes.NewBufferString(`{"type":"assignment_update","sessionId":"s1","assignee":null}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	ev = nextEvent(t, events, "assignment_changed").(*types.AssignmentChangedEvent)
+	if ev.Assignee != nil || ev.PreviousAssignee == nil || ev.PreviousAssignee.ID != "agent-7" {
+		t.Errorf("unexpected unassignment event: %+v", ev)
+	}
+
+	for _, body := range []string{`{"assignee":{"id":"x"}}`, `{"sessionId":"s1","assignee":{"name":"No ID"}}`, `nope`} {
+		if code := post(body); code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, code)
+		}
+	}
+}
+
+func TestWorkforce_CloseCommand(t *testing.T) {
+	server, _ := setupTestServer([]bridges.Bridge{newMockBridge("slack")}, &config.Config{FirstResponseSLA: time.Minute})
+	events := listenEvents(t, server)
+
+	server.assignSession("s1", &types.Assignee{ID: "agent-7"}, "api")
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	server.RecordOperatorMessage(context.Background(), "s1", "!close resolved", "Alice", "slack", nil, nil, "1.3")
+
+	closed := nextEvent(t, events, "session_closed").(*types.SessionClosedEvent)
+	if closed.SourceBridge != "slack" || closed.Reason != "resolved" || closed.Assignee == nil || closed.Assignee.ID != "agent-7" {
+		t.Errorf("unexpected close event: %+v", closed)
+	}
+	if _, err := time.Parse(time.RFC3339, closed.ClosedAt); err != nil {
+		t.Errorf("closedAt %q is not RFC3339", closed.ClosedAt)
+	}
+	if msg := server.getMessage(buildOperatorMessageID("slack", "1.3")); msg != nil {
+		t.Error("expected !close not to be relayed as a message")
+	}
+
+	server.workforce.mu.Lock()
+	_, tracked := server.workforce.sessions["s1"]
+	server.workforce.mu.Unlock()
+	if tracked {
+		t.Error("expected closed session state to be released")
+	}
+}
+
+func TestWorkforce_CloseSessionEvent(t *testing.T) {
+	server, mux := setupTestServer(nil, nil)
+	events := listenEvents(t, server)
+
+	req := httptest.NewRequest("POST", "/api/events", bytes.NewBufferString(`{"type":"close_session","sessionId":"s1","reason":"inactive"}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	closed := nextEvent(t, events, "session_closed").(*types.SessionClosedEvent)
+	if closed.SourceBridge != "api" || closed.Reason != "inactive" {
+		t.Errorf("unexpected close event: %+v", closed)
+	}
+}
+
+func TestWorkforce_ForgetsEndedAndIdleSessions(t *testing.T) {
+	server, _ := setupTestServer(nil, &config.Config{FirstResponseSLA: time.Minute})
+	tracked := func(sessionID string) bool {
+		server.workforce.mu.Lock()
+		defer server.workforce.mu.Unlock()
+		_, ok := server.workforce.sessions[sessionID]
+		return ok
+	}
+
+	// Closed by the backend's lifecycle
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s1", "m1"))
+	_ = server.processCustomEvent(context.Background(), &types.CustomEventEvent{
+		Type:    "custom_event",
+		Event:   &types.CustomEvent{Name: "session_closed", Data: map[string]interface{}{"reason": "inactive"}},
+		Session: &types.Session{ID: "s1"},
+	})
+	if tracked("s1") {
+		t.Error("expected the backend-closed session to be forgotten")
+	}
+
+	// Left idle
+	_ = server.processVisitorMessage(context.Background(), visitorMessage("s2", "m2"))
+	server.workforce.mu.Lock()
+	server.workforce.sessions["s2"].lastActivity = time.Now().Add(-workforceIdleTTL)
+	server.workforce.lastSweep = time.Time{}
+	server.workforce.session("s3")
+	server.workforce.mu.Unlock()
+	if tracked("s2") || !tracked("s3") {
+		t.Error("expected only the idle session to be swept")
+	}
+}
@@ -2,6 +2,11 @@
 package bridges
 
 import (
+	"context"
+	"net/http"
+	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
 	"github.com/pocketping/bridge-server/internal/types"
 )
 
@@ -23,38 +28,45 @@ type Bridge interface {
 	SetEventCallback(callback EventCallback)
 
 	// OnNewSession is called when a new chat session starts
-	OnNewSession(session *types.Session) error
+	OnNewSession(ctx context.Context, session *types.Session) error
 
 	// OnVisitorMessage is called when a visitor sends a message
 	// Returns bridge message IDs for edit/delete sync
-	OnVisitorMessage(message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error)
+	OnVisitorMessage(ctx context.Context, message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error)
 
 	// OnOperatorMessage is called when an operator sends a message from another bridge
-	OnOperatorMessage(message *types.Message, session *types.Session, sourceBridge, operatorName string) error
+	OnOperatorMessage(ctx context.Context, message *types.Message, session *types.Session, sourceBridge, operatorName string) error
 
 	// OnTyping is called when a visitor starts/stops typing
-	OnTyping(sessionID string, isTyping bool) error
+	OnTyping(ctx context.Context, sessionID string, isTyping bool) error
 
 	// OnMessageRead is called when messages are marked as read
-	OnMessageRead(sessionID string, messageIDs []string, status types.MessageStatus) error
+	OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status types.MessageStatus) error
 
 	// OnCustomEvent is called when a custom event is triggered
-	OnCustomEvent(event *types.CustomEvent, session *types.Session) error
+	OnCustomEvent(ctx context.Context, event *types.CustomEvent, session *types.Session) error
 
 	// OnIdentityUpdate is called when a user's identity is updated
-	OnIdentityUpdate(session *types.Session) error
+	OnIdentityUpdate(ctx context.Context, session *types.Session) error
 
 	// OnAITakeover is called when AI takes over the conversation
-	OnAITakeover(session *types.Session, reason string) error
+	OnAITakeover(ctx context.Context, session *types.Session, reason string) error
 
 	// OnVisitorMessageEdited is called when a visitor edits a message
-	OnVisitorMessageEdited(sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error)
+	OnVisitorMessageEdited(ctx context.Context, sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error)
 
 	// OnVisitorMessageDeleted is called when a visitor deletes a message
-	OnVisitorMessageDeleted(sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error
+	OnVisitorMessageDeleted(ctx context.Context, sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error
 
 	// OnVisitorDisconnect is called when a visitor leaves the page
-	OnVisitorDisconnect(session *types.Session, message string) error
+	OnVisitorDisconnect(ctx context.Context, session *types.Session, message string) error
+}
+
+// DryRunner is implemented by bridges that can make a dry-run copy of
+// themselves, for the self-test: the copy formats notifications for the same
+// chat, but logs its platform calls instead of sending them.
+type DryRunner interface {
+	DryRun() Bridge
 }
 
 // BaseBridge provides common functionality for all bridges
@@ -84,3 +96,53 @@ func (b *BaseBridge) EmitEvent(event types.OutgoingEvent) {
 		b.eventCallback(event)
 	}
 }
+
+// RequestIDHeader carries the request ID of the API call that triggered a
+// bridge notification or webhook forward.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDTransport stamps the context's request ID on outgoing requests.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := RequestID(req.Context()); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, id)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// NewRequestIDTransport wraps next (http.DefaultTransport when nil) so each
+// request carries its context's request ID in the X-Request-ID header.
+func NewRequestIDTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return requestIDTransport{next: next}
+}
+
+// newHTTPClient returns the platform API client of a bridge. Requests carry
+// the triggering request ID; in dry-run mode they are logged instead of sent
+// (see pocketping.NewDryRunTransport).
+func newHTTPClient(bridgeName string, dryRun bool) *http.Client {
+	var transport http.RoundTripper
+	if dryRun {
+		transport = pocketping.NewDryRunTransport(bridgeName)
+	}
+	return &http.Client{Timeout: 30 * time.Second, Transport: NewRequestIDTransport(transport)}
+}
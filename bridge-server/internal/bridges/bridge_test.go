@@ -1,8 +1,13 @@
 package bridges
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/pocketping/bridge-server/internal/config"
 	"github.com/pocketping/bridge-server/internal/types"
 )
 
@@ -77,3 +82,51 @@ func TestBridgeInterface(t *testing.T) {
 	var _ Bridge = (*DiscordBridge)(nil)
 	var _ Bridge = (*SlackBridge)(nil)
 }
+
+func TestRequestIDTransport(t *testing.T) {
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(RequestIDHeader)
+	}))
+	defer srv.Close()
+
+	client := newHTTPClient("test", false)
+	req, _ := http.NewRequestWithContext(WithRequestID(context.Background(), "req-789"), "POST", srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := <-received; got != "req-789" {
+		t.Errorf("X-Request-ID = %q, want req-789", got)
+	}
+}
+
+func TestDestinationsMatchSDK(t *testing.T) {
+	slackHook := "https://hooks.slack.com/services/T0/B0/x"
+	discordHook := "https://discord.com/api/webhooks/1/x"
+
+	telegram, _ := NewTelegramBridge(&config.TelegramConfig{BotToken: "123:ABC", ChatID: "-100"})
+	slackBot, _ := NewSlackBridge(&config.SlackConfig{BotToken: "xoxb-1", ChannelID: "C1"})
+	slackWebhook, _ := NewSlackBridge(&config.SlackConfig{WebhookURL: slackHook})
+	discordBot, _ := NewDiscordBridge(&config.DiscordConfig{BotToken: "bot", ChannelID: "D1"})
+	discordWebhook, _ := NewDiscordBridge(&config.DiscordConfig{WebhookURL: discordHook})
+
+	sdkTelegram, _ := pocketping.NewTelegramBridge("123:ABC", "-100")
+	sdkSlackBot, _ := pocketping.NewSlackBotBridge("xoxb-1", "C1")
+	sdkSlackWebhook, _ := pocketping.NewSlackWebhookBridge(slackHook)
+	sdkDiscordWebhook, _ := pocketping.NewDiscordWebhookBridge(discordHook)
+
+	for name, pair := range map[string][2]string{
+		"telegram":        {telegram.Destination(), sdkTelegram.Destination()},
+		"slack bot":       {slackBot.Destination(), sdkSlackBot.Destination()},
+		"slack webhook":   {slackWebhook.Destination(), sdkSlackWebhook.Destination()},
+		"discord bot":     {discordBot.Destination(), pocketping.NewDiscordBotBridge("bot", "D1").Destination()},
+		"discord webhook": {discordWebhook.Destination(), sdkDiscordWebhook.Destination()},
+	} {
+		if pair[0] == "" || pair[0] != pair[1] {
+			t.Errorf("%s: bridge-server key %q, SDK key %q", name, pair[0], pair[1])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package bridges
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -51,9 +52,7 @@ func NewDiscordBridge(cfg *config.DiscordConfig) (*DiscordBridge, error) {
 		webhookURL: cfg.WebhookURL,
 		username:   cfg.Username,
 		avatarURL:  cfg.AvatarURL,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:     newHTTPClient("discord", cfg.DryRun),
 	}, nil
 }
 
@@ -62,6 +61,23 @@ func (b *DiscordBridge) isBotMode() bool {
 	return b.botToken != "" && b.channelID != ""
 }
 
+// DryRun implements DryRunner.
+func (b *DiscordBridge) DryRun() Bridge {
+	dry := *b
+	dry.BaseBridge = NewBaseBridge(b.Name())
+	dry.client = newHTTPClient(b.Name(), true)
+	return &dry
+}
+
+// Destination returns the dedupe key of the bridge's channel, or of its
+// webhook, the same as the SDK's Discord bridges for them.
+func (b *DiscordBridge) Destination() string {
+	if b.isBotMode() {
+		return pocketping.BridgeDestination("discord", b.channelID)
+	}
+	return pocketping.BridgeDestination("discord", b.webhookURL)
+}
+
 // discordEmbed represents a Discord embed
 type discordEmbed struct {
 	Title       string              `json:"title,omitempty"`
@@ -88,7 +104,7 @@ type discordMessageResponse struct {
 }
 
 // sendMessage sends a message to Discord
-func (b *DiscordBridge) sendMessage(content string, embeds []discordEmbed, replyToMessageID string) (string, error) {
+func (b *DiscordBridge) sendMessage(ctx context.Context, content string, embeds []discordEmbed, replyToMessageID string) (string, error) {
 	data := map[string]interface{}{}
 
 	if content != "" {
@@ -127,7 +143,7 @@ func (b *DiscordBridge) sendMessage(content string, embeds []discordEmbed, reply
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return "", err
 	}
@@ -162,7 +178,7 @@ func (b *DiscordBridge) sendMessage(content string, embeds []discordEmbed, reply
 }
 
 // OnNewSession announces a new chat session
-func (b *DiscordBridge) OnNewSession(session *types.Session) error {
+func (b *DiscordBridge) OnNewSession(ctx context.Context, session *types.Session) error {
 	visitorName := session.VisitorID
 	if session.Identity != nil && session.Identity.Name != "" {
 		visitorName = session.Identity.Name
@@ -186,12 +202,12 @@ func (b *DiscordBridge) OnNewSession(session *types.Session) error {
 		})
 	}
 
-	_, err := b.sendMessage("", []discordEmbed{embed}, "")
+	_, err := b.sendMessage(ctx, "", []discordEmbed{embed}, "")
 	return err
 }
 
 // OnVisitorMessage sends a visitor message to Discord
-func (b *DiscordBridge) OnVisitorMessage(message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error) {
+func (b *DiscordBridge) OnVisitorMessage(ctx context.Context, message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error) {
 	visitorName := session.VisitorID
 	if session.Identity != nil && session.Identity.Name != "" {
 		visitorName = session.Identity.Name
@@ -208,7 +224,7 @@ func (b *DiscordBridge) OnVisitorMessage(message *types.Message, session *types.
 		replyToMessageID = reply.BridgeIDs.DiscordMessageID
 	}
 
-	msgID, err := b.sendMessage(content, nil, replyToMessageID)
+	msgID, err := b.sendMessage(ctx, content, nil, replyToMessageID)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +236,7 @@ func (b *DiscordBridge) OnVisitorMessage(message *types.Message, session *types.
 }
 
 // OnOperatorMessage relays an operator message from another bridge
-func (b *DiscordBridge) OnOperatorMessage(message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
+func (b *DiscordBridge) OnOperatorMessage(ctx context.Context, message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
 	if sourceBridge == "discord" {
 		return nil
 	}
@@ -231,19 +247,19 @@ func (b *DiscordBridge) OnOperatorMessage(message *types.Message, session *types
 	}
 
 	content := fmt.Sprintf("**%s** (via %s): %s", name, sourceBridge, message.Content)
-	_, err := b.sendMessage(content, nil, "")
+	_, err := b.sendMessage(ctx, content, nil, "")
 	return err
 }
 
 // OnTyping sends a typing indicator
-func (b *DiscordBridge) OnTyping(sessionID string, isTyping bool) error {
+func (b *DiscordBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
 	if !isTyping || !b.isBotMode() {
 		return nil
 	}
 
 	url := fmt.Sprintf("%s/channels/%s/typing", discordAPIBase, b.channelID)
 
-	req, err := http.NewRequest("POST", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return err
 	}
@@ -259,12 +275,12 @@ func (b *DiscordBridge) OnTyping(sessionID string, isTyping bool) error {
 }
 
 // OnMessageRead handles read receipts (no-op for Discord)
-func (b *DiscordBridge) OnMessageRead(sessionID string, messageIDs []string, status types.MessageStatus) error {
+func (b *DiscordBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status types.MessageStatus) error {
 	return nil
 }
 
 // OnCustomEvent sends a custom event notification
-func (b *DiscordBridge) OnCustomEvent(event *types.CustomEvent, session *types.Session) error {
+func (b *DiscordBridge) OnCustomEvent(ctx context.Context, event *types.CustomEvent, session *types.Session) error {
 	embed := discordEmbed{
 		Title:     fmt.Sprintf("Event: %s", event.Name),
 		Color:     0x5865F2, // Discord blurple
@@ -276,12 +292,12 @@ func (b *DiscordBridge) OnCustomEvent(event *types.CustomEvent, session *types.S
 		embed.Description = fmt.Sprintf("```json\n%s\n```", string(data))
 	}
 
-	_, err := b.sendMessage("", []discordEmbed{embed}, "")
+	_, err := b.sendMessage(ctx, "", []discordEmbed{embed}, "")
 	return err
 }
 
 // OnIdentityUpdate sends an identity update notification
-func (b *DiscordBridge) OnIdentityUpdate(session *types.Session) error {
+func (b *DiscordBridge) OnIdentityUpdate(ctx context.Context, session *types.Session) error {
 	if session.Identity == nil {
 		return nil
 	}
@@ -316,24 +332,24 @@ func (b *DiscordBridge) OnIdentityUpdate(session *types.Session) error {
 		})
 	}
 
-	_, err := b.sendMessage("", []discordEmbed{embed}, "")
+	_, err := b.sendMessage(ctx, "", []discordEmbed{embed}, "")
 	return err
 }
 
 // OnAITakeover sends an AI takeover notification
-func (b *DiscordBridge) OnAITakeover(session *types.Session, reason string) error {
+func (b *DiscordBridge) OnAITakeover(ctx context.Context, session *types.Session, reason string) error {
 	embed := discordEmbed{
 		Title:       "AI Takeover",
 		Description: reason,
 		Color:       0xFEE75C, // Yellow
 	}
 
-	_, err := b.sendMessage("", []discordEmbed{embed}, "")
+	_, err := b.sendMessage(ctx, "", []discordEmbed{embed}, "")
 	return err
 }
 
 // OnVisitorMessageEdited syncs a message edit to Discord (bot mode only)
-func (b *DiscordBridge) OnVisitorMessageEdited(sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
+func (b *DiscordBridge) OnVisitorMessageEdited(ctx context.Context, sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
 	if !b.isBotMode() || bridgeIDs == nil || bridgeIDs.DiscordMessageID == "" {
 		return nil, nil
 	}
@@ -349,7 +365,7 @@ func (b *DiscordBridge) OnVisitorMessageEdited(sessionID, messageID, content str
 		return nil, err
 	}
 
-	req, err := http.NewRequest("PATCH", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -371,14 +387,14 @@ func (b *DiscordBridge) OnVisitorMessageEdited(sessionID, messageID, content str
 }
 
 // OnVisitorMessageDeleted syncs a message delete to Discord (bot mode only)
-func (b *DiscordBridge) OnVisitorMessageDeleted(sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
+func (b *DiscordBridge) OnVisitorMessageDeleted(ctx context.Context, sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
 	if !b.isBotMode() || bridgeIDs == nil || bridgeIDs.DiscordMessageID == "" {
 		return nil
 	}
 
 	url := fmt.Sprintf("%s/channels/%s/messages/%s", discordAPIBase, b.channelID, bridgeIDs.DiscordMessageID)
 
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return err
 	}
@@ -398,7 +414,7 @@ func (b *DiscordBridge) OnVisitorMessageDeleted(sessionID, messageID string, bri
 }
 
 // OnVisitorDisconnect sends a notification when visitor leaves the page
-func (b *DiscordBridge) OnVisitorDisconnect(session *types.Session, message string) error {
+func (b *DiscordBridge) OnVisitorDisconnect(ctx context.Context, session *types.Session, message string) error {
 	if !b.isBotMode() || session.DiscordThreadID == "" {
 		return nil
 	}
@@ -411,7 +427,7 @@ func (b *DiscordBridge) OnVisitorDisconnect(session *types.Session, message stri
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package bridges
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -209,7 +210,7 @@ func TestDiscordBridge_OnOperatorMessage(t *testing.T) {
 	}
 
 	t.Run("skips message from same bridge", func(t *testing.T) {
-		err := bridge.OnOperatorMessage(
+		err := bridge.OnOperatorMessage(context.Background(),
 			&types.Message{Content: "test"},
 			&types.Session{},
 			"discord",
@@ -239,7 +240,7 @@ func TestDiscordBridge_OnTyping(t *testing.T) {
 		bridge := &DiscordBridge{
 			BaseBridge: NewBaseBridge("discord"),
 		}
-		err := bridge.OnTyping("session123", false)
+		err := bridge.OnTyping(context.Background(), "session123", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -250,7 +251,7 @@ func TestDiscordBridge_OnTyping(t *testing.T) {
 			BaseBridge: NewBaseBridge("discord"),
 			webhookURL: "https://discord.com/webhook",
 		}
-		err := bridge.OnTyping("session123", true)
+		err := bridge.OnTyping(context.Background(), "session123", true)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -263,7 +264,7 @@ func TestDiscordBridge_OnMessageRead(t *testing.T) {
 	}
 
 	// Should be a no-op
-	err := bridge.OnMessageRead("session123", []string{"msg1"}, types.StatusRead)
+	err := bridge.OnMessageRead(context.Background(), "session123", []string{"msg1"}, types.StatusRead)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -300,7 +301,7 @@ func TestDiscordBridge_OnIdentityUpdate(t *testing.T) {
 
 	t.Run("returns early when no identity", func(t *testing.T) {
 		session := &types.Session{ID: "s1"}
-		err := bridge.OnIdentityUpdate(session)
+		err := bridge.OnIdentityUpdate(context.Background(), session)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -376,7 +377,7 @@ func TestDiscordBridge_OnVisitorMessageEdited(t *testing.T) {
 			BaseBridge: NewBaseBridge("discord"),
 			webhookURL: "https://discord.com/webhook",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new", &types.BridgeMessageIDs{DiscordMessageID: "123"})
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new", &types.BridgeMessageIDs{DiscordMessageID: "123"})
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -391,7 +392,7 @@ func TestDiscordBridge_OnVisitorMessageEdited(t *testing.T) {
 			botToken:   "token",
 			channelID:  "channel",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new", nil)
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new", nil)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -406,7 +407,7 @@ func TestDiscordBridge_OnVisitorMessageEdited(t *testing.T) {
 			botToken:   "token",
 			channelID:  "channel",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new", &types.BridgeMessageIDs{TelegramMessageID: 123})
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new", &types.BridgeMessageIDs{TelegramMessageID: 123})
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -422,7 +423,7 @@ func TestDiscordBridge_OnVisitorMessageDeleted(t *testing.T) {
 			BaseBridge: NewBaseBridge("discord"),
 			webhookURL: "https://discord.com/webhook",
 		}
-		err := bridge.OnVisitorMessageDeleted("s1", "m1", &types.BridgeMessageIDs{DiscordMessageID: "123"})
+		err := bridge.OnVisitorMessageDeleted(context.Background(), "s1", "m1", &types.BridgeMessageIDs{DiscordMessageID: "123"})
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -434,7 +435,7 @@ func TestDiscordBridge_OnVisitorMessageDeleted(t *testing.T) {
 			botToken:   "token",
 			channelID:  "channel",
 		}
-		err := bridge.OnVisitorMessageDeleted("s1", "m1", nil)
+		err := bridge.OnVisitorMessageDeleted(context.Background(), "s1", "m1", nil)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -0,0 +1,415 @@
+package bridges
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/pocketping/bridge-server/internal/types"
+)
+
+// PreviewSample is a session and visitor message that notifications are
+// rendered against.
+type PreviewSample struct {
+	Name    string
+	Session *types.Session
+	Message *types.Message
+}
+
+// Preview is one rendered platform API call.
+type Preview struct {
+	Bridge string `json:"bridge"`
+	Sample string `json:"sample"`
+	Event  string `json:"event"`
+	// Method and URL identify the API call; credentials are masked.
+	Method  string          `json:"method"`
+	URL     string          `json:"url"`
+	Payload json.RawMessage `json:"payload"`
+	// Problems are the platform constraints the payload breaks.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// DefaultPreviewSamples covers the cases that tend to break notifications:
+// a bare session, full visitor details, markup characters, and a message
+// over the platforms' length limits.
+func DefaultPreviewSamples() []PreviewSample {
+	now := time.Now()
+	session := func(id string) *types.Session {
+		return &types.Session{
+			ID:              id,
+			VisitorID:       "visitor-" + id,
+			CreatedAt:       now,
+			LastActivity:    now,
+			TelegramTopicID: 1,
+			DiscordThreadID: "1",
+			SlackThreadTS:   "1.000001",
+		}
+	}
+	message := func(sessionID, content string) *types.Message {
+		return &types.Message{ID: "msg-" + sessionID, SessionID: sessionID, Content: content, Sender: types.SenderVisitor, Timestamp: now}
+	}
+
+	basic := session("basic")
+	identified := session("identified")
+	identified.Identity = &types.UserIdentity{ID: "user_42", Name: "Jane Doe", Email: "jane@example.com"}
+	identified.UserPhone = "+33612345678"
+	identified.Metadata = &types.SessionMetadata{URL: "https://example.com/pricing?plan=pro&ref=ad", Country: "France", City: "Paris"}
+	markup := session("markup")
+	markup.Identity = &types.UserIdentity{ID: "user_<7>", Name: "Tom & Jerry <Ltd>"}
+	long := session("long")
+
+	return []PreviewSample{
+		{Name: "basic", Session: basic, Message: message(basic.ID, "Hi, I have a question about my order.")},
+		{Name: "identified", Session: identified, Message: message(identified.ID, "Can I upgrade to the Pro plan?")},
+		{Name: "markup", Session: markup, Message: message(markup.ID, "Is 5 < 6 & 7 > 3? <b>bold</b> *star* _under_ `tick` <https://x.y|z> @here")},
+		{Name: "long", Session: long, Message: message(long.ID, strings.Repeat("This message is far too long. ", 200))},
+	}
+}
+
+// PreviewBridge renders the bridge's notifications for each sample without
+// calling the platform, and lints every payload against the platform's
+// constraints (length, markup escaping, embed and block counts).
+func PreviewBridge(ctx context.Context, bridge Bridge, samples []PreviewSample) ([]Preview, error) {
+	recorder := &previewTransport{}
+	client := &http.Client{Transport: recorder}
+	var preview Bridge
+	switch b := bridge.(type) {
+	case *TelegramBridge:
+		c := *b
+		c.client = client
+		preview = &c
+	case *DiscordBridge:
+		c := *b
+		c.client = client
+		preview = &c
+	case *SlackBridge:
+		c := *b
+		c.client = client
+		preview = &c
+	default:
+		return nil, fmt.Errorf("bridge %s does not support previews", bridge.Name())
+	}
+
+	var previews []Preview
+	for _, sample := range samples {
+		bridgeIDs := &types.BridgeMessageIDs{TelegramMessageID: 1, DiscordMessageID: "1", SlackMessageTS: "1.000001"}
+		event := &types.CustomEvent{Name: "clicked_pricing", Data: map[string]interface{}{"plan": "pro", "seats": 5}, SessionID: sample.Session.ID}
+		calls := []struct {
+			name string
+			call func() error
+		}{
+			{"new_session", func() error { return preview.OnNewSession(ctx, sample.Session) }},
+			{"visitor_message", func() error {
+				_, err := preview.OnVisitorMessage(ctx, sample.Message, sample.Session, nil)
+				return err
+			}},
+			{"operator_message", func() error {
+				return preview.OnOperatorMessage(ctx, sample.Message, sample.Session, "api", "Bob")
+			}},
+			{"identity_update", func() error { return preview.OnIdentityUpdate(ctx, sample.Session) }},
+			{"custom_event", func() error { return preview.OnCustomEvent(ctx, event, sample.Session) }},
+			{"ai_takeover", func() error { return preview.OnAITakeover(ctx, sample.Session, "No operator replied within 5 minutes") }},
+			{"message_edited", func() error {
+				_, err := preview.OnVisitorMessageEdited(ctx, sample.Session.ID, sample.Message.ID, sample.Message.Content, bridgeIDs)
+				return err
+			}},
+			{"visitor_disconnect", func() error {
+				return preview.OnVisitorDisconnect(ctx, sample.Session, "👋 Visitor left the page")
+			}},
+		}
+		for _, c := range calls {
+			if err := c.call(); err != nil {
+				return nil, fmt.Errorf("%s %s/%s: %w", bridge.Name(), sample.Name, c.name, err)
+			}
+			for _, request := range recorder.take() {
+				request.Bridge, request.Sample, request.Event = bridge.Name(), sample.Name, c.name
+				request.Problems = LintPayload(request.URL, request.Payload)
+				previews = append(previews, request)
+			}
+		}
+	}
+	return previews, nil
+}
+
+// previewTransport records requests and answers with a fake success that
+// the Telegram, Discord and Slack clients all accept.
+type previewTransport struct {
+	mu       sync.Mutex
+	requests []Preview
+	seq      int
+}
+
+func (p *previewTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var payload []byte
+	if req.Body != nil {
+		var err error
+		if payload, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(payload))
+	}
+
+	p.mu.Lock()
+	p.seq++
+	id := p.seq
+	p.requests = append(p.requests, Preview{Method: req.Method, URL: pocketping.RedactURL(req.URL), Payload: payload})
+	p.mu.Unlock()
+
+	body := fmt.Sprintf(`{"ok":true,"result":{"message_id":%d,"message_thread_id":%d},"id":"%d","ts":"%d.000001","channel":"preview"}`, id, id, id, id)
+	if req.URL.Host == "hooks.slack.com" {
+		body = "ok"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}, nil
+}
+
+func (p *previewTransport) take() []Preview {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	requests := p.requests
+	p.requests = nil
+	return requests
+}
+
+// Platform limits checked by LintPayload.
+const (
+	telegramMaxText        = 4096
+	discordMaxContent      = 2000
+	discordMaxEmbeds       = 10
+	discordMaxEmbedTitle   = 256
+	discordMaxEmbedDesc    = 4096
+	discordMaxEmbedFields  = 25
+	discordMaxFieldName    = 256
+	discordMaxFieldValue   = 1024
+	discordMaxEmbedsTotal  = 6000
+	slackMaxText           = 40000
+	slackMaxBlocks         = 50
+	slackMaxSectionText    = 3000
+	slackMaxSectionFields  = 10
+	slackMaxSectionFieldTx = 2000
+	slackMaxHeaderText     = 150
+)
+
+// LintPayload checks a platform API payload against the constraints of the
+// platform it is sent to (recognised by the URL host) and returns the
+// problems found.
+func LintPayload(url string, payload []byte) []string {
+	switch {
+	case strings.HasPrefix(url, "api.telegram.org"):
+		return lintTelegram(payload)
+	case strings.Contains(url, "discord.com") || strings.Contains(url, "discordapp.com"):
+		return lintDiscord(payload)
+	case strings.Contains(url, "slack.com"):
+		return lintSlack(payload)
+	}
+	return nil
+}
+
+func lintTelegram(payload []byte) []string {
+	var message struct {
+		Text      *string `json:"text"`
+		ParseMode string  `json:"parse_mode"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil || message.Text == nil {
+		return nil
+	}
+	var problems []string
+	visible := *message.Text
+	if message.ParseMode == "HTML" {
+		problems = append(problems, lintTelegramHTML(visible)...)
+		visible = html.UnescapeString(telegramTag.ReplaceAllString(visible, ""))
+	}
+	if strings.TrimSpace(visible) == "" {
+		problems = append(problems, "text: empty message")
+	}
+	if n := len(utf16.Encode([]rune(visible))); n > telegramMaxText {
+		problems = append(problems, fmt.Sprintf("text: %d characters, limit %d", n, telegramMaxText))
+	}
+	return problems
+}
+
+var (
+	telegramTag    = regexp.MustCompile(`</?([a-zA-Z-]+)(\s[^<>]*)?>`)
+	telegramEntity = regexp.MustCompile(`^&(lt|gt|amp|quot|#[0-9]+|#x[0-9a-fA-F]+);`)
+	// telegramTags are the tags Telegram's HTML parse mode supports.
+	telegramTags = map[string]bool{
+		"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true, "s": true, "strike": true,
+		"del": true, "span": true, "tg-spoiler": true, "a": true, "code": true, "pre": true,
+		"blockquote": true, "tg-emoji": true,
+	}
+)
+
+// lintTelegramHTML reports unsupported or unbalanced tags, and "<" or "&"
+// that aren't escaped; Telegram rejects such messages outright.
+func lintTelegramHTML(text string) []string {
+	var problems []string
+	var open []string
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '<':
+			loc := telegramTag.FindStringSubmatchIndex(text[i:])
+			if loc == nil || loc[0] != 0 {
+				problems = append(problems, fmt.Sprintf("text: unescaped \"<\" at byte %d (use &lt;)", i))
+				continue
+			}
+			tag := strings.ToLower(text[i+loc[2] : i+loc[3]])
+			if !telegramTags[tag] {
+				problems = append(problems, fmt.Sprintf("text: unsupported tag <%s>", tag))
+			} else if text[i+1] == '/' {
+				if len(open) == 0 || open[len(open)-1] != tag {
+					problems = append(problems, fmt.Sprintf("text: unbalanced </%s>", tag))
+				} else {
+					open = open[:len(open)-1]
+				}
+			} else {
+				open = append(open, tag)
+			}
+			i += loc[1] - 1
+		case '&':
+			if !telegramEntity.MatchString(text[i:]) {
+				problems = append(problems, fmt.Sprintf("text: unescaped \"&\" at byte %d (use &amp;)", i))
+			}
+		}
+	}
+	for _, tag := range open {
+		problems = append(problems, fmt.Sprintf("text: unclosed <%s>", tag))
+	}
+	return problems
+}
+
+func lintDiscord(payload []byte) []string {
+	var message struct {
+		Content string         `json:"content"`
+		Embeds  []discordEmbed `json:"embeds"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return nil
+	}
+	var problems []string
+	if message.Content == "" && len(message.Embeds) == 0 {
+		problems = append(problems, "empty message (no content or embeds)")
+	}
+	problems = appendOverLimit(problems, "content", message.Content, discordMaxContent)
+	if len(message.Embeds) > discordMaxEmbeds {
+		problems = append(problems, fmt.Sprintf("embeds: %d, limit %d", len(message.Embeds), discordMaxEmbeds))
+	}
+	total := 0
+	for i, embed := range message.Embeds {
+		prefix := fmt.Sprintf("embeds[%d].", i)
+		problems = appendOverLimit(problems, prefix+"title", embed.Title, discordMaxEmbedTitle)
+		problems = appendOverLimit(problems, prefix+"description", embed.Description, discordMaxEmbedDesc)
+		if len(embed.Fields) > discordMaxEmbedFields {
+			problems = append(problems, fmt.Sprintf("%sfields: %d, limit %d", prefix, len(embed.Fields), discordMaxEmbedFields))
+		}
+		total += utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+		for j, field := range embed.Fields {
+			if field.Name == "" || field.Value == "" {
+				problems = append(problems, fmt.Sprintf("%sfields[%d]: name and value are required", prefix, j))
+			}
+			problems = appendOverLimit(problems, fmt.Sprintf("%sfields[%d].name", prefix, j), field.Name, discordMaxFieldName)
+			problems = appendOverLimit(problems, fmt.Sprintf("%sfields[%d].value", prefix, j), field.Value, discordMaxFieldValue)
+			total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		}
+		if embed.Footer != nil {
+			total += utf8.RuneCountInString(embed.Footer.Text)
+		}
+	}
+	if total > discordMaxEmbedsTotal {
+		problems = append(problems, fmt.Sprintf("embeds: %d characters in total, limit %d", total, discordMaxEmbedsTotal))
+	}
+	return problems
+}
+
+func lintSlack(payload []byte) []string {
+	var message struct {
+		Text   string       `json:"text"`
+		Blocks []slackBlock `json:"blocks"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return nil
+	}
+	var problems []string
+	if message.Text == "" && len(message.Blocks) == 0 {
+		problems = append(problems, "empty message (no text or blocks)")
+	}
+	problems = appendOverLimit(problems, "text", message.Text, slackMaxText)
+	problems = append(problems, lintSlackEscaping("text", message.Text)...)
+	if len(message.Blocks) > slackMaxBlocks {
+		problems = append(problems, fmt.Sprintf("blocks: %d, limit %d", len(message.Blocks), slackMaxBlocks))
+	}
+	for i, block := range message.Blocks {
+		prefix := fmt.Sprintf("blocks[%d].", i)
+		if block.Text != nil {
+			limit := slackMaxSectionText
+			if block.Type == "header" {
+				limit = slackMaxHeaderText
+			}
+			problems = appendOverLimit(problems, prefix+"text", block.Text.Text, limit)
+			if block.Text.Type == "mrkdwn" {
+				problems = append(problems, lintSlackEscaping(prefix+"text", block.Text.Text)...)
+			}
+		}
+		if len(block.Fields) > slackMaxSectionFields {
+			problems = append(problems, fmt.Sprintf("%sfields: %d, limit %d", prefix, len(block.Fields), slackMaxSectionFields))
+		}
+		for j, field := range block.Fields {
+			name := fmt.Sprintf("%sfields[%d]", prefix, j)
+			problems = appendOverLimit(problems, name, field.Text, slackMaxSectionFieldTx)
+			if field.Type == "mrkdwn" {
+				problems = append(problems, lintSlackEscaping(name, field.Text)...)
+			}
+		}
+	}
+	return problems
+}
+
+// slackControl matches Slack's <...> control sequences: links, mentions and
+// special commands.
+var slackControl = regexp.MustCompile(`^<(https?://|mailto:|[@#!])[^<>]*>`)
+
+// lintSlackEscaping reports "<" and "&" that are neither escaped nor part of
+// a control sequence; Slack would treat them as markup.
+func lintSlackEscaping(name, text string) []string {
+	var problems []string
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '<':
+			if loc := slackControl.FindStringIndex(text[i:]); loc != nil {
+				i += loc[1] - 1
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s: unescaped \"<\" at byte %d (use &lt;)", name, i))
+		case '&':
+			if !telegramEntity.MatchString(text[i:]) {
+				problems = append(problems, fmt.Sprintf("%s: unescaped \"&\" at byte %d (use &amp;)", name, i))
+			}
+		}
+	}
+	return problems
+}
+
+func appendOverLimit(problems []string, name, text string, limit int) []string {
+	if n := utf8.RuneCountInString(text); n > limit {
+		problems = append(problems, fmt.Sprintf("%s: %d characters, limit %d", name, n, limit))
+	}
+	return problems
+}
@@ -0,0 +1,88 @@
+package bridges
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pocketping/bridge-server/internal/config"
+)
+
+func TestPreviewBridge(t *testing.T) {
+	bridge, err := NewTelegramBridge(&config.TelegramConfig{BotToken: "123456:ABCdefGHIjklMNOpqrSTUvwxYZ", ChatID: "-1001234567890"})
+	if err != nil {
+		t.Fatalf("NewTelegramBridge: %v", err)
+	}
+	previews, err := PreviewBridge(context.Background(), bridge, DefaultPreviewSamples())
+	if err != nil {
+		t.Fatalf("PreviewBridge: %v", err)
+	}
+
+	events := make(map[string]bool)
+	problems := make(map[string][]string)
+	for _, preview := range previews {
+		events[preview.Event] = true
+		if strings.Contains(preview.URL, "ABCdef") {
+			t.Errorf("token not masked in %s", preview.URL)
+		}
+		key := preview.Sample + "/" + preview.Event
+		problems[key] = append(problems[key], preview.Problems...)
+	}
+	for _, event := range []string{"new_session", "visitor_message", "operator_message", "identity_update", "custom_event", "ai_takeover", "message_edited", "visitor_disconnect"} {
+		if !events[event] {
+			t.Errorf("no preview for %s", event)
+		}
+	}
+	if len(problems["basic/visitor_message"]) != 0 {
+		t.Errorf("basic sample problems = %q", problems["basic/visitor_message"])
+	}
+	if !strings.Contains(strings.Join(problems["markup/visitor_message"], "\n"), `unescaped "<"`) {
+		t.Errorf("markup sample problems = %q", problems["markup/visitor_message"])
+	}
+	if !strings.Contains(strings.Join(problems["long/visitor_message"], "\n"), "limit 4096") {
+		t.Errorf("long sample problems = %q", problems["long/visitor_message"])
+	}
+}
+
+func TestPreviewBridgeRedactsWebhookURL(t *testing.T) {
+	bridge, err := NewSlackBridge(&config.SlackConfig{WebhookURL: "https://hooks.slack.com/services/T000/B000/XXXX"})
+	if err != nil {
+		t.Fatalf("NewSlackBridge: %v", err)
+	}
+	previews, err := PreviewBridge(context.Background(), bridge, DefaultPreviewSamples())
+	if err != nil {
+		t.Fatalf("PreviewBridge: %v", err)
+	}
+	for _, preview := range previews {
+		if preview.URL != "hooks.slack.com/***" {
+			t.Errorf("preview URL = %q, want the webhook URL redacted", preview.URL)
+		}
+	}
+}
+
+func TestLintPayload(t *testing.T) {
+	long := strings.Repeat("x", 2001)
+	tests := []struct {
+		name    string
+		url     string
+		payload string
+		want    string
+	}{
+		{"telegram ok", "api.telegram.org/***/sendMessage", `{"text":"<b>Hi</b> &amp; 5 &lt; 6","parse_mode":"HTML"}`, ""},
+		{"telegram unclosed", "api.telegram.org/***/sendMessage", `{"text":"<b>Hi","parse_mode":"HTML"}`, "unclosed <b>"},
+		{"telegram unsupported", "api.telegram.org/***/sendMessage", `{"text":"<div>Hi</div>","parse_mode":"HTML"}`, "unsupported tag <div>"},
+		{"telegram plain text", "api.telegram.org/***/sendMessage", `{"text":"5 < 6 & 7"}`, ""},
+		{"discord content", "discord.com/api/webhooks/1/***", `{"content":"` + long + `"}`, "content: 2001 characters, limit 2000"},
+		{"discord empty", "discord.com/api/channels/1/messages", `{}`, "empty message"},
+		{"discord field", "discord.com/api/channels/1/messages", `{"embeds":[{"fields":[{"name":"","value":"x"}]}]}`, "name and value are required"},
+		{"slack control", "slack.com/api/chat.postMessage", `{"text":"<https://x.y|link> <@U123> &amp;"}`, ""},
+		{"slack unescaped", "slack.com/api/chat.postMessage", `{"text":"a","blocks":[{"type":"section","text":{"type":"mrkdwn","text":"5 < 6"}}]}`, `blocks[0].text: unescaped "<"`},
+		{"slack header", "hooks.slack.com/services/***", `{"text":"a","blocks":[{"type":"header","text":{"type":"plain_text","text":"` + long + `"}}]}`, "limit 150"},
+	}
+	for _, tt := range tests {
+		problems := strings.Join(LintPayload(tt.url, []byte(tt.payload)), "\n")
+		if tt.want == "" && problems != "" || !strings.Contains(problems, tt.want) {
+			t.Errorf("%s: problems = %q, want %q", tt.name, problems, tt.want)
+		}
+	}
+}
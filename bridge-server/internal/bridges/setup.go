@@ -0,0 +1,237 @@
+package bridges
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Checker is implemented by bridges that can verify their credentials and
+// target chat against the platform API without posting anything.
+type Checker interface {
+	// Check returns a short description of what was verified.
+	Check(ctx context.Context) (string, error)
+}
+
+// WebhookRegistrar is implemented by bridges that can point the platform's
+// webhook at the bridge server.
+type WebhookRegistrar interface {
+	// RegisterWebhook registers baseURL's webhook endpoint and returns it.
+	RegisterWebhook(ctx context.Context, baseURL string) (string, error)
+}
+
+var (
+	_ Checker          = (*TelegramBridge)(nil)
+	_ Checker          = (*SlackBridge)(nil)
+	_ Checker          = (*DiscordBridge)(nil)
+	_ WebhookRegistrar = (*TelegramBridge)(nil)
+)
+
+// WebhookURL returns the bridge server endpoint a platform posts to.
+func WebhookURL(baseURL, bridgeName string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/webhooks/" + bridgeName
+}
+
+// telegramWebhookUpdates are the update types the Telegram webhook handles.
+// message_reaction must be requested explicitly.
+var telegramWebhookUpdates = []string{"message", "edited_message", "message_reaction", "inline_query", "callback_query"}
+
+// Check verifies the bot token with getMe and the chat with getChat.
+func (b *TelegramBridge) Check(ctx context.Context) (string, error) {
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := b.callResult(ctx, "getMe", map[string]interface{}{}, &me); err != nil {
+		return "", fmt.Errorf("bot token: %w", err)
+	}
+	var chat struct {
+		Title   string `json:"title"`
+		IsForum bool   `json:"is_forum"`
+	}
+	if err := b.callResult(ctx, "getChat", map[string]interface{}{"chat_id": b.chatID}, &chat); err != nil {
+		return "", fmt.Errorf("chat %s: %w (is @%s a member?)", b.chatID, err, me.Username)
+	}
+	return fmt.Sprintf("bot @%s in %q", me.Username, chat.Title), nil
+}
+
+// RegisterWebhook points the bot's webhook at the bridge server with setWebhook.
+func (b *TelegramBridge) RegisterWebhook(ctx context.Context, baseURL string) (string, error) {
+	url := WebhookURL(baseURL, "telegram")
+	err := b.callResult(ctx, "setWebhook", map[string]interface{}{
+		"url":             url,
+		"allowed_updates": telegramWebhookUpdates,
+	}, nil)
+	return url, err
+}
+
+// callResult calls a Telegram method and decodes its result into v, if any.
+func (b *TelegramBridge) callResult(ctx context.Context, method string, data map[string]interface{}, v interface{}) error {
+	resp, err := b.callAPI(ctx, method, data)
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("telegram API error: %s", resp.Description)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, v)
+}
+
+// Check verifies the bot token with auth.test and the channel with
+// conversations.info. An incoming webhook can't be checked without posting.
+func (b *SlackBridge) Check(ctx context.Context) (string, error) {
+	if !b.isBotMode() {
+		return "webhook mode, only checked by posting (send-test)", nil
+	}
+	var auth struct {
+		slackResponse
+		User string `json:"user"`
+		Team string `json:"team"`
+	}
+	if err := b.callAPI(ctx, "auth.test", b.botToken, map[string]interface{}{}, &auth); err != nil {
+		return "", fmt.Errorf("bot token: %w", err)
+	}
+	var info struct {
+		slackResponse
+		Channel struct {
+			Name     string `json:"name"`
+			IsMember bool   `json:"is_member"`
+		} `json:"channel"`
+	}
+	if err := b.callAPI(ctx, "conversations.info", b.botToken, map[string]interface{}{"channel": b.channelID}, &info); err != nil {
+		return "", fmt.Errorf("channel %s: %w", b.channelID, err)
+	}
+	if !info.Channel.IsMember {
+		return "", fmt.Errorf("channel #%s: @%s is not a member (invite it with /invite @%s)", info.Channel.Name, auth.User, auth.User)
+	}
+	return fmt.Sprintf("bot @%s in #%s (%s)", auth.User, info.Channel.Name, auth.Team), nil
+}
+
+// callAPI calls a Slack Web API method with token and decodes the response
+// into v, which must embed slackResponse.
+func (b *SlackBridge) callAPI(ctx context.Context, method, token string, data map[string]interface{}, v interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", slackAPIBase+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var status slackResponse
+	if err := json.Unmarshal(respBody, &status); err != nil {
+		return err
+	}
+	if !status.OK {
+		return fmt.Errorf("slack API error: %s", status.Error)
+	}
+	return json.Unmarshal(respBody, v)
+}
+
+// RegisterSlackWebhooks sets the Event Subscriptions and Interactivity request
+// URLs of a Slack app to the bridge server, through the app manifest.
+// configToken is an app configuration token (xoxe.xoxp-...), from
+// api.slack.com/apps > Your App Configuration Tokens; bot tokens can't edit
+// manifests. Slack verifies the events URL, so the server must be reachable.
+func (b *SlackBridge) RegisterSlackWebhooks(ctx context.Context, appID, configToken, baseURL string) (string, error) {
+	url := WebhookURL(baseURL, "slack")
+	var exported struct {
+		slackResponse
+		Manifest map[string]interface{} `json:"manifest"`
+	}
+	if err := b.callAPI(ctx, "apps.manifest.export", configToken, map[string]interface{}{"app_id": appID}, &exported); err != nil {
+		return url, fmt.Errorf("export manifest: %w", err)
+	}
+
+	settings, _ := exported.Manifest["settings"].(map[string]interface{})
+	if settings == nil {
+		settings = map[string]interface{}{}
+		exported.Manifest["settings"] = settings
+	}
+	events, _ := settings["event_subscriptions"].(map[string]interface{})
+	if events == nil {
+		events = map[string]interface{}{}
+		settings["event_subscriptions"] = events
+	}
+	events["request_url"] = url
+	settings["interactivity"] = map[string]interface{}{"is_enabled": true, "request_url": url}
+
+	manifest, err := json.Marshal(exported.Manifest)
+	if err != nil {
+		return url, err
+	}
+	var updated slackResponse
+	if err := b.callAPI(ctx, "apps.manifest.update", configToken, map[string]interface{}{
+		"app_id":   appID,
+		"manifest": string(manifest),
+	}, &updated); err != nil {
+		return url, fmt.Errorf("update manifest: %w", err)
+	}
+	return url, nil
+}
+
+// Check verifies the bot token and channel, or that the webhook exists.
+func (b *DiscordBridge) Check(ctx context.Context) (string, error) {
+	if !b.isBotMode() {
+		var hook struct {
+			Name string `json:"name"`
+		}
+		if err := b.get(ctx, b.webhookURL, &hook); err != nil {
+			return "", fmt.Errorf("webhook: %w", err)
+		}
+		return fmt.Sprintf("webhook %q", hook.Name), nil
+	}
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := b.get(ctx, discordAPIBase+"/users/@me", &me); err != nil {
+		return "", fmt.Errorf("bot token: %w", err)
+	}
+	var channel struct {
+		Name string `json:"name"`
+	}
+	if err := b.get(ctx, discordAPIBase+"/channels/"+b.channelID, &channel); err != nil {
+		return "", fmt.Errorf("channel %s: %w (can %s see it?)", b.channelID, err, me.Username)
+	}
+	return fmt.Sprintf("bot %s in #%s", me.Username, channel.Name), nil
+}
+
+// get fetches a Discord API resource into v.
+func (b *DiscordBridge) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if b.isBotMode() {
+		req.Header.Set("Authorization", "Bot "+b.botToken)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("discord API error: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,124 @@
+package bridges
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// redirectTransport sends every request to a test server, keeping the path.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func redirectClient(t *testing.T, handler http.HandlerFunc) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return &http.Client{Transport: redirectTransport{target: target}}
+}
+
+func TestTelegramBridge_CheckAndRegisterWebhook(t *testing.T) {
+	var webhook map[string]interface{}
+	client := redirectClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			w.Write([]byte(`{"ok":true,"result":{"username":"pp_bot"}}`))
+		case strings.HasSuffix(r.URL.Path, "/getChat"):
+			w.Write([]byte(`{"ok":true,"result":{"title":"Support","is_forum":true}}`))
+		case strings.HasSuffix(r.URL.Path, "/setWebhook"):
+			json.NewDecoder(r.Body).Decode(&webhook)
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Errorf("unexpected call %s", r.URL.Path)
+		}
+	})
+	bridge := &TelegramBridge{BaseBridge: NewBaseBridge("telegram"), botToken: "1:x", chatID: "-100", client: client}
+
+	detail, err := bridge.Check(context.Background())
+	if err != nil || detail != `bot @pp_bot in "Support"` {
+		t.Errorf("Check = %q, %v", detail, err)
+	}
+	url, err := bridge.RegisterWebhook(context.Background(), "https://bridge.example.com/")
+	if err != nil || url != "https://bridge.example.com/webhooks/telegram" {
+		t.Fatalf("RegisterWebhook = %q, %v", url, err)
+	}
+	if webhook["url"] != url || len(webhook["allowed_updates"].([]interface{})) != len(telegramWebhookUpdates) {
+		t.Errorf("unexpected setWebhook payload: %v", webhook)
+	}
+}
+
+func TestTelegramBridge_CheckReportsBadChat(t *testing.T) {
+	client := redirectClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			w.Write([]byte(`{"ok":true,"result":{"username":"pp_bot"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+	})
+	bridge := &TelegramBridge{BaseBridge: NewBaseBridge("telegram"), botToken: "1:x", chatID: "-100", client: client}
+
+	if _, err := bridge.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("expected a chat error, got %v", err)
+	}
+}
+
+func TestSlackBridge_CheckRequiresMembership(t *testing.T) {
+	client := redirectClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth.test":
+			w.Write([]byte(`{"ok":true,"user":"pocketping","team":"Acme"}`))
+		case "/api/conversations.info":
+			w.Write([]byte(`{"ok":true,"channel":{"name":"support","is_member":false}}`))
+		}
+	})
+	bridge := &SlackBridge{BaseBridge: NewBaseBridge("slack"), botToken: "xoxb-1", channelID: "C1", client: client}
+
+	if _, err := bridge.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "/invite @pocketping") {
+		t.Errorf("expected a membership error, got %v", err)
+	}
+}
+
+func TestSlackBridge_RegisterSlackWebhooks(t *testing.T) {
+	var updated map[string]interface{}
+	client := redirectClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxe.xoxp-config" {
+			t.Errorf("expected the configuration token, got %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/api/apps.manifest.export":
+			w.Write([]byte(`{"ok":true,"manifest":{"display_information":{"name":"PocketPing"},"settings":{"event_subscriptions":{"bot_events":["message.channels"]}}}}`))
+		case "/api/apps.manifest.update":
+			var payload struct {
+				Manifest string `json:"manifest"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			json.Unmarshal([]byte(payload.Manifest), &updated)
+			w.Write([]byte(`{"ok":true}`))
+		}
+	})
+	bridge := &SlackBridge{BaseBridge: NewBaseBridge("slack"), botToken: "xoxb-1", channelID: "C1", client: client}
+
+	url, err := bridge.RegisterSlackWebhooks(context.Background(), "A1", "xoxe.xoxp-config", "https://bridge.example.com")
+	if err != nil || url != "https://bridge.example.com/webhooks/slack" {
+		t.Fatalf("RegisterSlackWebhooks = %q, %v", url, err)
+	}
+	settings := updated["settings"].(map[string]interface{})
+	events := settings["event_subscriptions"].(map[string]interface{})
+	interactivity := settings["interactivity"].(map[string]interface{})
+	if events["request_url"] != url || events["bot_events"] == nil || interactivity["request_url"] != url {
+		t.Errorf("unexpected manifest: %v", updated)
+	}
+}
@@ -2,13 +2,13 @@ package bridges
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html"
 	"io"
 	"log"
 	"net/http"
-	"time"
 
 	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
 	"github.com/pocketping/bridge-server/internal/config"
@@ -52,9 +52,7 @@ func NewSlackBridge(cfg *config.SlackConfig) (*SlackBridge, error) {
 		webhookURL: cfg.WebhookURL,
 		username:   cfg.Username,
 		iconEmoji:  cfg.IconEmoji,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:     newHTTPClient("slack", cfg.DryRun),
 	}, nil
 }
 
@@ -63,6 +61,23 @@ func (b *SlackBridge) isBotMode() bool {
 	return b.botToken != "" && b.channelID != ""
 }
 
+// DryRun implements DryRunner.
+func (b *SlackBridge) DryRun() Bridge {
+	dry := *b
+	dry.BaseBridge = NewBaseBridge(b.Name())
+	dry.client = newHTTPClient(b.Name(), true)
+	return &dry
+}
+
+// Destination returns the dedupe key of the bridge's channel, or of its
+// webhook, the same as the SDK's Slack bridges for them.
+func (b *SlackBridge) Destination() string {
+	if b.isBotMode() {
+		return pocketping.BridgeDestination("slack", b.channelID)
+	}
+	return pocketping.BridgeDestination("slack", b.webhookURL)
+}
+
 // slackBlock represents a Slack block
 type slackBlock struct {
 	Type   string          `json:"type"`
@@ -94,7 +109,7 @@ func escapeSlack(s string) string {
 }
 
 // sendMessage sends a message to Slack
-func (b *SlackBridge) sendMessage(text string, blocks []slackBlock) (string, error) {
+func (b *SlackBridge) sendMessage(ctx context.Context, text string, blocks []slackBlock) (string, error) {
 	data := map[string]interface{}{
 		"text": text,
 	}
@@ -128,7 +143,7 @@ func (b *SlackBridge) sendMessage(text string, blocks []slackBlock) (string, err
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return "", err
 	}
@@ -172,7 +187,7 @@ func (b *SlackBridge) sendMessage(text string, blocks []slackBlock) (string, err
 }
 
 // OnNewSession announces a new chat session
-func (b *SlackBridge) OnNewSession(session *types.Session) error {
+func (b *SlackBridge) OnNewSession(ctx context.Context, session *types.Session) error {
 	visitorName := session.VisitorID
 	if session.Identity != nil && session.Identity.Name != "" {
 		visitorName = session.Identity.Name
@@ -203,12 +218,12 @@ func (b *SlackBridge) OnNewSession(session *types.Session) error {
 		})
 	}
 
-	_, err := b.sendMessage(text, blocks)
+	_, err := b.sendMessage(ctx, text, blocks)
 	return err
 }
 
 // OnVisitorMessage sends a visitor message to Slack
-func (b *SlackBridge) OnVisitorMessage(message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error) {
+func (b *SlackBridge) OnVisitorMessage(ctx context.Context, message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error) {
 	visitorName := session.VisitorID
 	if session.Identity != nil && session.Identity.Name != "" {
 		visitorName = session.Identity.Name
@@ -223,7 +238,7 @@ func (b *SlackBridge) OnVisitorMessage(message *types.Message, session *types.Se
 		text += fmt.Sprintf(" _(+%d attachment(s))_", len(message.Attachments))
 	}
 
-	ts, err := b.sendMessage(text, nil)
+	ts, err := b.sendMessage(ctx, text, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -235,7 +250,7 @@ func (b *SlackBridge) OnVisitorMessage(message *types.Message, session *types.Se
 }
 
 // OnOperatorMessage relays an operator message from another bridge
-func (b *SlackBridge) OnOperatorMessage(message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
+func (b *SlackBridge) OnOperatorMessage(ctx context.Context, message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
 	if sourceBridge == "slack" {
 		return nil
 	}
@@ -246,22 +261,22 @@ func (b *SlackBridge) OnOperatorMessage(message *types.Message, session *types.S
 	}
 
 	text := fmt.Sprintf("*%s* (via %s): %s", escapeSlack(name), sourceBridge, escapeSlack(message.Content))
-	_, err := b.sendMessage(text, nil)
+	_, err := b.sendMessage(ctx, text, nil)
 	return err
 }
 
 // OnTyping is a no-op for Slack (no typing indicator API for channels)
-func (b *SlackBridge) OnTyping(sessionID string, isTyping bool) error {
+func (b *SlackBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
 	return nil
 }
 
 // OnMessageRead handles read receipts (no-op for Slack)
-func (b *SlackBridge) OnMessageRead(sessionID string, messageIDs []string, status types.MessageStatus) error {
+func (b *SlackBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status types.MessageStatus) error {
 	return nil
 }
 
 // OnCustomEvent sends a custom event notification
-func (b *SlackBridge) OnCustomEvent(event *types.CustomEvent, session *types.Session) error {
+func (b *SlackBridge) OnCustomEvent(ctx context.Context, event *types.CustomEvent, session *types.Session) error {
 	text := fmt.Sprintf("Event: %s", event.Name)
 
 	blocks := []slackBlock{
@@ -282,12 +297,12 @@ func (b *SlackBridge) OnCustomEvent(event *types.CustomEvent, session *types.Ses
 		})
 	}
 
-	_, err := b.sendMessage(text, blocks)
+	_, err := b.sendMessage(ctx, text, blocks)
 	return err
 }
 
 // OnIdentityUpdate sends an identity update notification
-func (b *SlackBridge) OnIdentityUpdate(session *types.Session) error {
+func (b *SlackBridge) OnIdentityUpdate(ctx context.Context, session *types.Session) error {
 	if session.Identity == nil {
 		return nil
 	}
@@ -326,12 +341,12 @@ func (b *SlackBridge) OnIdentityUpdate(session *types.Session) error {
 		})
 	}
 
-	_, err := b.sendMessage(text, blocks)
+	_, err := b.sendMessage(ctx, text, blocks)
 	return err
 }
 
 // OnAITakeover sends an AI takeover notification
-func (b *SlackBridge) OnAITakeover(session *types.Session, reason string) error {
+func (b *SlackBridge) OnAITakeover(ctx context.Context, session *types.Session, reason string) error {
 	text := fmt.Sprintf("AI Takeover: %s", reason)
 
 	blocks := []slackBlock{
@@ -345,12 +360,12 @@ func (b *SlackBridge) OnAITakeover(session *types.Session, reason string) error
 		},
 	}
 
-	_, err := b.sendMessage(text, blocks)
+	_, err := b.sendMessage(ctx, text, blocks)
 	return err
 }
 
 // OnVisitorMessageEdited syncs a message edit to Slack (bot mode only)
-func (b *SlackBridge) OnVisitorMessageEdited(sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
+func (b *SlackBridge) OnVisitorMessageEdited(ctx context.Context, sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
 	if !b.isBotMode() || bridgeIDs == nil || bridgeIDs.SlackMessageTS == "" {
 		return nil, nil
 	}
@@ -366,7 +381,7 @@ func (b *SlackBridge) OnVisitorMessageEdited(sessionID, messageID, content strin
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", slackAPIBase+"/chat.update", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", slackAPIBase+"/chat.update", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -398,7 +413,7 @@ func (b *SlackBridge) OnVisitorMessageEdited(sessionID, messageID, content strin
 }
 
 // OnVisitorMessageDeleted syncs a message delete to Slack (bot mode only)
-func (b *SlackBridge) OnVisitorMessageDeleted(sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
+func (b *SlackBridge) OnVisitorMessageDeleted(ctx context.Context, sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
 	if !b.isBotMode() || bridgeIDs == nil || bridgeIDs.SlackMessageTS == "" {
 		return nil
 	}
@@ -413,7 +428,7 @@ func (b *SlackBridge) OnVisitorMessageDeleted(sessionID, messageID string, bridg
 		return err
 	}
 
-	req, err := http.NewRequest("POST", slackAPIBase+"/chat.delete", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", slackAPIBase+"/chat.delete", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -444,7 +459,7 @@ func (b *SlackBridge) OnVisitorMessageDeleted(sessionID, messageID string, bridg
 }
 
 // OnVisitorDisconnect sends a notification when visitor leaves the page
-func (b *SlackBridge) OnVisitorDisconnect(session *types.Session, message string) error {
+func (b *SlackBridge) OnVisitorDisconnect(ctx context.Context, session *types.Session, message string) error {
 	if !b.isBotMode() || session.SlackThreadTS == "" {
 		return nil
 	}
@@ -460,7 +475,7 @@ func (b *SlackBridge) OnVisitorDisconnect(session *types.Session, message string
 		return err
 	}
 
-	req, err := http.NewRequest("POST", slackAPIBase+"/chat.postMessage", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", slackAPIBase+"/chat.postMessage", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
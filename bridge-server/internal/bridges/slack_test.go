@@ -1,6 +1,7 @@
 package bridges
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -215,7 +216,7 @@ func TestSlackBridge_OnOperatorMessage(t *testing.T) {
 	}
 
 	t.Run("skips message from same bridge", func(t *testing.T) {
-		err := bridge.OnOperatorMessage(
+		err := bridge.OnOperatorMessage(context.Background(),
 			&types.Message{Content: "test"},
 			&types.Session{},
 			"slack",
@@ -245,7 +246,7 @@ func TestSlackBridge_OnTyping(t *testing.T) {
 	}
 
 	// Should be a no-op
-	err := bridge.OnTyping("session123", true)
+	err := bridge.OnTyping(context.Background(), "session123", true)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -257,7 +258,7 @@ func TestSlackBridge_OnMessageRead(t *testing.T) {
 	}
 
 	// Should be a no-op
-	err := bridge.OnMessageRead("session123", []string{"msg1"}, types.StatusRead)
+	err := bridge.OnMessageRead(context.Background(), "session123", []string{"msg1"}, types.StatusRead)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -303,7 +304,7 @@ func TestSlackBridge_OnIdentityUpdate(t *testing.T) {
 
 	t.Run("returns early when no identity", func(t *testing.T) {
 		session := &types.Session{ID: "s1"}
-		err := bridge.OnIdentityUpdate(session)
+		err := bridge.OnIdentityUpdate(context.Background(), session)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -390,7 +391,7 @@ func TestSlackBridge_OnVisitorMessageEdited(t *testing.T) {
 			BaseBridge: NewBaseBridge("slack"),
 			webhookURL: "https://hooks.slack.com/webhook",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new", &types.BridgeMessageIDs{SlackMessageTS: "ts123"})
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new", &types.BridgeMessageIDs{SlackMessageTS: "ts123"})
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -405,7 +406,7 @@ func TestSlackBridge_OnVisitorMessageEdited(t *testing.T) {
 			botToken:   "token",
 			channelID:  "channel",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new", nil)
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new", nil)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -420,7 +421,7 @@ func TestSlackBridge_OnVisitorMessageEdited(t *testing.T) {
 			botToken:   "token",
 			channelID:  "channel",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new", &types.BridgeMessageIDs{TelegramMessageID: 123})
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new", &types.BridgeMessageIDs{TelegramMessageID: 123})
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -436,7 +437,7 @@ func TestSlackBridge_OnVisitorMessageDeleted(t *testing.T) {
 			BaseBridge: NewBaseBridge("slack"),
 			webhookURL: "https://hooks.slack.com/webhook",
 		}
-		err := bridge.OnVisitorMessageDeleted("s1", "m1", &types.BridgeMessageIDs{SlackMessageTS: "ts123"})
+		err := bridge.OnVisitorMessageDeleted(context.Background(), "s1", "m1", &types.BridgeMessageIDs{SlackMessageTS: "ts123"})
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -448,7 +449,7 @@ func TestSlackBridge_OnVisitorMessageDeleted(t *testing.T) {
 			botToken:   "token",
 			channelID:  "channel",
 		}
-		err := bridge.OnVisitorMessageDeleted("s1", "m1", nil)
+		err := bridge.OnVisitorMessageDeleted(context.Background(), "s1", "m1", nil)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
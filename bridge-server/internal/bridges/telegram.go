@@ -2,12 +2,12 @@ package bridges
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"time"
 
 	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
 	"github.com/pocketping/bridge-server/internal/config"
@@ -34,9 +34,7 @@ func NewTelegramBridge(cfg *config.TelegramConfig) (*TelegramBridge, error) {
 		BaseBridge: NewBaseBridge("telegram"),
 		botToken:   cfg.BotToken,
 		chatID:     cfg.ChatID,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:     newHTTPClient("telegram", cfg.DryRun),
 	}, nil
 }
 
@@ -52,8 +50,22 @@ type telegramMessageResult struct {
 	MessageID int `json:"message_id"`
 }
 
+// DryRun implements DryRunner.
+func (b *TelegramBridge) DryRun() Bridge {
+	dry := *b
+	dry.BaseBridge = NewBaseBridge(b.Name())
+	dry.client = newHTTPClient(b.Name(), true)
+	return &dry
+}
+
+// Destination returns the dedupe key of the bridge's chat, the same as the
+// SDK's TelegramBridge for that chat.
+func (b *TelegramBridge) Destination() string {
+	return pocketping.BridgeDestination("telegram", b.chatID)
+}
+
 // callAPI makes a request to the Telegram Bot API
-func (b *TelegramBridge) callAPI(method string, data map[string]interface{}) (*telegramResponse, error) {
+func (b *TelegramBridge) callAPI(ctx context.Context, method string, data map[string]interface{}) (*telegramResponse, error) {
 	url := fmt.Sprintf("%s%s/%s", telegramAPIBase, b.botToken, method)
 
 	body, err := json.Marshal(data)
@@ -61,7 +73,7 @@ func (b *TelegramBridge) callAPI(method string, data map[string]interface{}) (*t
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +99,7 @@ func (b *TelegramBridge) callAPI(method string, data map[string]interface{}) (*t
 }
 
 // sendMessage sends a message to the configured chat
-func (b *TelegramBridge) sendMessage(text string, replyToMessageID *int) (int, error) {
+func (b *TelegramBridge) sendMessage(ctx context.Context, text string, replyToMessageID *int) (int, error) {
 	data := map[string]interface{}{
 		"chat_id":    b.chatID,
 		"text":       text,
@@ -97,7 +109,7 @@ func (b *TelegramBridge) sendMessage(text string, replyToMessageID *int) (int, e
 		data["reply_to_message_id"] = *replyToMessageID
 	}
 
-	resp, err := b.callAPI("sendMessage", data)
+	resp, err := b.callAPI(ctx, "sendMessage", data)
 	if err != nil {
 		return 0, err
 	}
@@ -116,7 +128,7 @@ func (b *TelegramBridge) sendMessage(text string, replyToMessageID *int) (int, e
 }
 
 // OnNewSession announces a new chat session
-func (b *TelegramBridge) OnNewSession(session *types.Session) error {
+func (b *TelegramBridge) OnNewSession(ctx context.Context, session *types.Session) error {
 	visitorName := session.VisitorID
 	if session.Identity != nil && session.Identity.Name != "" {
 		visitorName = session.Identity.Name
@@ -133,12 +145,12 @@ func (b *TelegramBridge) OnNewSession(session *types.Session) error {
 		}
 	}
 
-	_, err := b.sendMessage(text, nil)
+	_, err := b.sendMessage(ctx, text, nil)
 	return err
 }
 
 // OnVisitorMessage sends a visitor message to the chat
-func (b *TelegramBridge) OnVisitorMessage(message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error) {
+func (b *TelegramBridge) OnVisitorMessage(ctx context.Context, message *types.Message, session *types.Session, reply *ReplyContext) (*types.BridgeMessageIDs, error) {
 	visitorName := session.VisitorID
 	if session.Identity != nil && session.Identity.Name != "" {
 		visitorName = session.Identity.Name
@@ -156,7 +168,7 @@ func (b *TelegramBridge) OnVisitorMessage(message *types.Message, session *types
 		replyToMessageID = &id
 	}
 
-	msgID, err := b.sendMessage(text, replyToMessageID)
+	msgID, err := b.sendMessage(ctx, text, replyToMessageID)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +177,7 @@ func (b *TelegramBridge) OnVisitorMessage(message *types.Message, session *types
 }
 
 // OnOperatorMessage relays an operator message from another bridge
-func (b *TelegramBridge) OnOperatorMessage(message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
+func (b *TelegramBridge) OnOperatorMessage(ctx context.Context, message *types.Message, session *types.Session, sourceBridge, operatorName string) error {
 	// Don't echo messages from this bridge
 	if sourceBridge == "telegram" {
 		return nil
@@ -177,12 +189,12 @@ func (b *TelegramBridge) OnOperatorMessage(message *types.Message, session *type
 	}
 
 	text := fmt.Sprintf("👤 <b>%s</b> (via %s):\n%s", name, sourceBridge, message.Content)
-	_, err := b.sendMessage(text, nil)
+	_, err := b.sendMessage(ctx, text, nil)
 	return err
 }
 
 // OnTyping sends a typing indicator
-func (b *TelegramBridge) OnTyping(sessionID string, isTyping bool) error {
+func (b *TelegramBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
 	if !isTyping {
 		return nil
 	}
@@ -192,28 +204,28 @@ func (b *TelegramBridge) OnTyping(sessionID string, isTyping bool) error {
 		"action":  "typing",
 	}
 
-	_, err := b.callAPI("sendChatAction", data)
+	_, err := b.callAPI(ctx, "sendChatAction", data)
 	return err
 }
 
 // OnMessageRead handles read receipts (no-op for Telegram)
-func (b *TelegramBridge) OnMessageRead(sessionID string, messageIDs []string, status types.MessageStatus) error {
+func (b *TelegramBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status types.MessageStatus) error {
 	return nil
 }
 
 // OnCustomEvent sends a custom event notification
-func (b *TelegramBridge) OnCustomEvent(event *types.CustomEvent, session *types.Session) error {
+func (b *TelegramBridge) OnCustomEvent(ctx context.Context, event *types.CustomEvent, session *types.Session) error {
 	text := fmt.Sprintf("⚡ <b>Event: %s</b>", event.Name)
 	if event.Data != nil {
 		data, _ := json.Marshal(event.Data)
 		text += fmt.Sprintf("\n<code>%s</code>", string(data))
 	}
-	_, err := b.sendMessage(text, nil)
+	_, err := b.sendMessage(ctx, text, nil)
 	return err
 }
 
 // OnIdentityUpdate sends an identity update notification
-func (b *TelegramBridge) OnIdentityUpdate(session *types.Session) error {
+func (b *TelegramBridge) OnIdentityUpdate(ctx context.Context, session *types.Session) error {
 	if session.Identity == nil {
 		return nil
 	}
@@ -229,19 +241,19 @@ func (b *TelegramBridge) OnIdentityUpdate(session *types.Session) error {
 		text += fmt.Sprintf("\n📱 Phone: %s", session.UserPhone)
 	}
 
-	_, err := b.sendMessage(text, nil)
+	_, err := b.sendMessage(ctx, text, nil)
 	return err
 }
 
 // OnAITakeover sends an AI takeover notification
-func (b *TelegramBridge) OnAITakeover(session *types.Session, reason string) error {
+func (b *TelegramBridge) OnAITakeover(ctx context.Context, session *types.Session, reason string) error {
 	text := fmt.Sprintf("🤖 <b>AI Takeover</b>\nReason: %s", reason)
-	_, err := b.sendMessage(text, nil)
+	_, err := b.sendMessage(ctx, text, nil)
 	return err
 }
 
 // OnVisitorMessageEdited syncs a message edit to Telegram
-func (b *TelegramBridge) OnVisitorMessageEdited(sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
+func (b *TelegramBridge) OnVisitorMessageEdited(ctx context.Context, sessionID, messageID, content string, bridgeIDs *types.BridgeMessageIDs) (*types.BridgeMessageIDs, error) {
 	if bridgeIDs == nil || bridgeIDs.TelegramMessageID == 0 {
 		return nil, nil
 	}
@@ -253,7 +265,7 @@ func (b *TelegramBridge) OnVisitorMessageEdited(sessionID, messageID, content st
 		"parse_mode": "HTML",
 	}
 
-	resp, err := b.callAPI("editMessageText", data)
+	resp, err := b.callAPI(ctx, "editMessageText", data)
 	if err != nil {
 		return nil, err
 	}
@@ -267,7 +279,7 @@ func (b *TelegramBridge) OnVisitorMessageEdited(sessionID, messageID, content st
 }
 
 // OnVisitorMessageDeleted syncs a message delete to Telegram
-func (b *TelegramBridge) OnVisitorMessageDeleted(sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
+func (b *TelegramBridge) OnVisitorMessageDeleted(ctx context.Context, sessionID, messageID string, bridgeIDs *types.BridgeMessageIDs) error {
 	if bridgeIDs == nil || bridgeIDs.TelegramMessageID == 0 {
 		return nil
 	}
@@ -277,7 +289,7 @@ func (b *TelegramBridge) OnVisitorMessageDeleted(sessionID, messageID string, br
 		"message_id": bridgeIDs.TelegramMessageID,
 	}
 
-	resp, err := b.callAPI("deleteMessage", data)
+	resp, err := b.callAPI(ctx, "deleteMessage", data)
 	if err != nil {
 		return err
 	}
@@ -290,7 +302,7 @@ func (b *TelegramBridge) OnVisitorMessageDeleted(sessionID, messageID string, br
 }
 
 // OnVisitorDisconnect sends a notification when visitor leaves the page
-func (b *TelegramBridge) OnVisitorDisconnect(session *types.Session, message string) error {
+func (b *TelegramBridge) OnVisitorDisconnect(ctx context.Context, session *types.Session, message string) error {
 	if session.TelegramTopicID == 0 {
 		return nil
 	}
@@ -301,7 +313,7 @@ func (b *TelegramBridge) OnVisitorDisconnect(session *types.Session, message str
 		"text":              message,
 	}
 
-	resp, err := b.callAPI("sendMessage", data)
+	resp, err := b.callAPI(ctx, "sendMessage", data)
 	if err != nil {
 		return err
 	}
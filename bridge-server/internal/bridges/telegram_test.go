@@ -1,6 +1,7 @@
 package bridges
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -117,7 +118,7 @@ func TestTelegramBridge_OnOperatorMessage(t *testing.T) {
 			BaseBridge: NewBaseBridge("telegram"),
 		}
 
-		err := bridge.OnOperatorMessage(
+		err := bridge.OnOperatorMessage(context.Background(),
 			&types.Message{Content: "test"},
 			&types.Session{},
 			"telegram",
@@ -146,7 +147,7 @@ func TestTelegramBridge_OnTyping(t *testing.T) {
 	}
 
 	t.Run("returns early when not typing", func(t *testing.T) {
-		err := bridge.OnTyping("session123", false)
+		err := bridge.OnTyping(context.Background(), "session123", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -159,7 +160,7 @@ func TestTelegramBridge_OnMessageRead(t *testing.T) {
 	}
 
 	// Should be a no-op
-	err := bridge.OnMessageRead("session123", []string{"msg1", "msg2"}, types.StatusRead)
+	err := bridge.OnMessageRead(context.Background(), "session123", []string{"msg1", "msg2"}, types.StatusRead)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -188,7 +189,7 @@ func TestTelegramBridge_OnIdentityUpdate(t *testing.T) {
 
 	t.Run("returns early when no identity", func(t *testing.T) {
 		session := &types.Session{ID: "s1"}
-		err := bridge.OnIdentityUpdate(session)
+		err := bridge.OnIdentityUpdate(context.Background(), session)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -243,7 +244,7 @@ func TestTelegramBridge_OnVisitorMessageEdited(t *testing.T) {
 	}
 
 	t.Run("returns nil when no bridge IDs", func(t *testing.T) {
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new content", nil)
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new content", nil)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -256,7 +257,7 @@ func TestTelegramBridge_OnVisitorMessageEdited(t *testing.T) {
 		bridgeIDs := &types.BridgeMessageIDs{
 			DiscordMessageID: "discord123",
 		}
-		result, err := bridge.OnVisitorMessageEdited("s1", "m1", "new content", bridgeIDs)
+		result, err := bridge.OnVisitorMessageEdited(context.Background(), "s1", "m1", "new content", bridgeIDs)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -272,7 +273,7 @@ func TestTelegramBridge_OnVisitorMessageDeleted(t *testing.T) {
 	}
 
 	t.Run("returns nil when no bridge IDs", func(t *testing.T) {
-		err := bridge.OnVisitorMessageDeleted("s1", "m1", nil)
+		err := bridge.OnVisitorMessageDeleted(context.Background(), "s1", "m1", nil)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -282,7 +283,7 @@ func TestTelegramBridge_OnVisitorMessageDeleted(t *testing.T) {
 		bridgeIDs := &types.BridgeMessageIDs{
 			SlackMessageTS: "slack.ts",
 		}
-		err := bridge.OnVisitorMessageDeleted("s1", "m1", bridgeIDs)
+		err := bridge.OnVisitorMessageDeleted(context.Background(), "s1", "m1", bridgeIDs)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
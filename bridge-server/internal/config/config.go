@@ -5,14 +5,43 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
 )
 
+// DefaultOperatorPresenceTTL is the default OperatorPresenceTTL.
+const DefaultOperatorPresenceTTL = 90 * time.Second
+
+// Maintenance modes: what happens to bridge notifications during maintenance.
+const (
+	MaintenanceBuffer = "buffer" // keep them and send them when maintenance ends
+	MaintenanceDrop   = "drop"   // discard them
+)
+
+// DefaultMaintenanceBufferSize is the default MaintenanceBufferSize.
+const DefaultMaintenanceBufferSize = 1000
+
+// DefaultBridgeRetryMaxAttempts is the default BridgeRetryMaxAttempts.
+const DefaultBridgeRetryMaxAttempts = 2
+
+// DefaultMaxStreamConnections is the default MaxStreamConnections.
+const DefaultMaxStreamConnections = 1000
+
+// Default HTTP server timeouts.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+)
+
 // TelegramConfig holds Telegram bridge configuration
 type TelegramConfig struct {
 	BotToken string
 	ChatID   string
+	// DryRun logs outgoing payloads instead of calling the API
+	DryRun bool
 }
 
 // DiscordConfig holds Discord bridge configuration
@@ -27,6 +56,8 @@ type DiscordConfig struct {
 	// Optional
 	Username  string
 	AvatarURL string
+	// DryRun logs outgoing payloads instead of calling the API
+	DryRun bool
 }
 
 // SlackConfig holds Slack bridge configuration
@@ -39,6 +70,8 @@ type SlackConfig struct {
 	// Optional
 	Username  string
 	IconEmoji string
+	// DryRun logs outgoing payloads instead of calling the API
+	DryRun bool
 }
 
 // Config holds the complete server configuration
@@ -63,6 +96,70 @@ type Config struct {
 	// as bots and skips the new_session bridge notification for them (default
 	// true). Set BOT_HEURISTICS_ENABLED=false to disable.
 	BotHeuristicsEnabled bool
+
+	// FirstResponseSLA is the first-response deadline used for
+	// sla_state_changed events (SLA_FIRST_RESPONSE_SECONDS). Zero disables
+	// SLA tracking.
+	FirstResponseSLA time.Duration
+
+	// OperatorPresenceTTL is how long a named operator stays online without a
+	// new status heartbeat (OPERATOR_PRESENCE_TTL_SECONDS, default 90).
+	OperatorPresenceTTL time.Duration
+
+	// MaintenanceMode is the default mode of POST /api/admin/maintenance,
+	// MaintenanceBuffer or MaintenanceDrop (MAINTENANCE_MODE, default buffer).
+	MaintenanceMode string
+
+	// MaintenanceBufferSize bounds the notifications buffered during
+	// maintenance; the oldest are dropped beyond it (MAINTENANCE_BUFFER_SIZE,
+	// default 1000).
+	MaintenanceBufferSize int
+
+	// BridgeRetryMaxAttempts is how many times a failed bridge notification
+	// is tried, the first attempt included, with pocketping.RetryPolicy
+	// backoff (BRIDGE_RETRY_MAX_ATTEMPTS, default 2). 0 or 1 disables
+	// retries.
+	BridgeRetryMaxAttempts int
+
+	// AccessLog logs one line per HTTP request with method, path, status,
+	// latency and request ID (default true). Set ACCESS_LOG=false to disable.
+	AccessLog bool
+
+	// HTTP server timeouts (HTTP_READ_HEADER_TIMEOUT_SECONDS,
+	// HTTP_READ_TIMEOUT_SECONDS, HTTP_WRITE_TIMEOUT_SECONDS,
+	// HTTP_IDLE_TIMEOUT_SECONDS). Zero disables a timeout. The SSE and
+	// WebSocket streams outlive WriteTimeout; instead each of their writes
+	// must finish within it.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxStreamConnections caps the concurrent SSE and WebSocket streams
+	// (STREAM_MAX_CONNECTIONS, default 1000), and MaxStreamConnectionsPerIP
+	// the streams of one client IP (STREAM_MAX_CONNECTIONS_PER_IP, default
+	// unlimited). Zero means unlimited; excess streams get 429.
+	MaxStreamConnections      int
+	MaxStreamConnectionsPerIP int
+
+	// ClockSkewThreshold is how far platform and backend timestamps may be
+	// from local time before they are replaced with it and the skew is
+	// logged (CLOCK_SKEW_THRESHOLD_SECONDS, default 30).
+	ClockSkewThreshold time.Duration
+
+	// DedupeRedisURL shares suppressed duplicate visitor messages through
+	// Redis (DEDUPE_REDIS_URL, redis:// or rediss://), with the Go SDK's
+	// RedisDeduper on the same server. By default they are remembered in
+	// memory, per process.
+	DedupeRedisURL string
+
+	// StrictConfig refuses to start when Validate finds a problem
+	// (CONFIG_STRICT). By default the server starts degraded and logs them.
+	StrictConfig bool
+
+	// problems are the environment variables Load had to ignore, reported
+	// by Validate.
+	problems []Problem
 }
 
 // Load reads configuration from environment variables
@@ -80,7 +177,47 @@ func Load() *Config {
 		BackendWebhookURL:    os.Getenv("BACKEND_WEBHOOK_URL"),
 		EventsWebhookURL:     os.Getenv("EVENTS_WEBHOOK_URL"),
 		EventsWebhookSecret:  os.Getenv("EVENTS_WEBHOOK_SECRET"),
+		DedupeRedisURL:       os.Getenv("DEDUPE_REDIS_URL"),
 		BotHeuristicsEnabled: os.Getenv("BOT_HEURISTICS_ENABLED") != "false" && os.Getenv("BOT_HEURISTICS_ENABLED") != "0",
+		AccessLog:            os.Getenv("ACCESS_LOG") != "false" && os.Getenv("ACCESS_LOG") != "0",
+		StrictConfig:         envFlag("CONFIG_STRICT"),
+		problems:             envProblems(),
+	}
+
+	cfg.OperatorPresenceTTL = DefaultOperatorPresenceTTL
+	if ttl := os.Getenv("OPERATOR_PRESENCE_TTL_SECONDS"); ttl != "" {
+		if seconds, err := strconv.Atoi(ttl); err == nil && seconds > 0 {
+			cfg.OperatorPresenceTTL = time.Duration(seconds) * time.Second
+		}
+	}
+
+	cfg.MaintenanceMode = MaintenanceBuffer
+	if os.Getenv("MAINTENANCE_MODE") == MaintenanceDrop {
+		cfg.MaintenanceMode = MaintenanceDrop
+	}
+	cfg.MaintenanceBufferSize = DefaultMaintenanceBufferSize
+	if size := os.Getenv("MAINTENANCE_BUFFER_SIZE"); size != "" {
+		if n, err := strconv.Atoi(size); err == nil && n > 0 {
+			cfg.MaintenanceBufferSize = n
+		}
+	}
+
+	cfg.BridgeRetryMaxAttempts = envInt("BRIDGE_RETRY_MAX_ATTEMPTS", DefaultBridgeRetryMaxAttempts)
+
+	cfg.ReadHeaderTimeout = envSeconds("HTTP_READ_HEADER_TIMEOUT_SECONDS", DefaultReadHeaderTimeout)
+	cfg.ReadTimeout = envSeconds("HTTP_READ_TIMEOUT_SECONDS", DefaultReadTimeout)
+	cfg.WriteTimeout = envSeconds("HTTP_WRITE_TIMEOUT_SECONDS", DefaultWriteTimeout)
+	cfg.IdleTimeout = envSeconds("HTTP_IDLE_TIMEOUT_SECONDS", DefaultIdleTimeout)
+
+	cfg.MaxStreamConnections = envInt("STREAM_MAX_CONNECTIONS", DefaultMaxStreamConnections)
+	cfg.MaxStreamConnectionsPerIP = envInt("STREAM_MAX_CONNECTIONS_PER_IP", 0)
+
+	cfg.ClockSkewThreshold = envSeconds("CLOCK_SKEW_THRESHOLD_SECONDS", pocketping.DefaultClockSkewThreshold)
+
+	if sla := os.Getenv("SLA_FIRST_RESPONSE_SECONDS"); sla != "" {
+		if seconds, err := strconv.Atoi(sla); err == nil && seconds > 0 {
+			cfg.FirstResponseSLA = time.Duration(seconds) * time.Second
+		}
 	}
 
 	if ids := os.Getenv("BRIDGE_TEST_BOT_IDS"); ids != "" {
@@ -136,6 +273,18 @@ func Load() *Config {
 		}
 	}
 
+	// Dry-run mode (DRY_RUN for every bridge, or <BRIDGE>_DRY_RUN)
+	dryRun := envFlag("DRY_RUN")
+	if cfg.Telegram != nil {
+		cfg.Telegram.DryRun = dryRun || envFlag("TELEGRAM_DRY_RUN")
+	}
+	if cfg.Discord != nil {
+		cfg.Discord.DryRun = dryRun || envFlag("DISCORD_DRY_RUN")
+	}
+	if cfg.Slack != nil {
+		cfg.Slack.DryRun = dryRun || envFlag("SLACK_DRY_RUN")
+	}
+
 	// User-Agent Filtering config
 	uaFilterEnabled := os.Getenv("UA_FILTER_ENABLED") == "true" || os.Getenv("UA_FILTER_ENABLED") == "1"
 	if uaFilterEnabled {
@@ -181,6 +330,29 @@ func Load() *Config {
 	return cfg
 }
 
+// envInt reads a non-negative integer, falling back to def when the variable
+// is unset or invalid.
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+// envSeconds reads a duration in whole seconds, falling back to def when the
+// variable is unset or invalid. "0" disables the timeout it configures.
+func envSeconds(name string, def time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv(name)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return def
+}
+
+// envFlag reports whether the environment variable is "true" or "1".
+func envFlag(name string) bool {
+	return os.Getenv(name) == "true" || os.Getenv(name) == "1"
+}
+
 // HasBridges returns true if at least one bridge is configured
 func (c *Config) HasBridges() bool {
 	return c.Telegram != nil || c.Discord != nil || c.Slack != nil
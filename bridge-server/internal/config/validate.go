@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+)
+
+// Problem is one configuration mistake found by Validate.
+type Problem struct {
+	// Var is the environment variable to fix, if a single one is at fault.
+	Var string
+	// Message says what is wrong and how to fix it.
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Var == "" {
+		return p.Message
+	}
+	return p.Var + ": " + p.Message
+}
+
+// ValidationError lists every problem found by Validate.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = "  - " + p.String()
+	}
+	return fmt.Sprintf("%d configuration problem(s):\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// numericVars are the variables Load parses as non-negative integers. An
+// invalid value falls back to the default, which Validate reports.
+var numericVars = []string{
+	"PORT", "SLA_FIRST_RESPONSE_SECONDS", "OPERATOR_PRESENCE_TTL_SECONDS", "MAINTENANCE_BUFFER_SIZE", "BRIDGE_RETRY_MAX_ATTEMPTS",
+	"HTTP_READ_HEADER_TIMEOUT_SECONDS", "HTTP_READ_TIMEOUT_SECONDS", "HTTP_WRITE_TIMEOUT_SECONDS", "HTTP_IDLE_TIMEOUT_SECONDS",
+	"STREAM_MAX_CONNECTIONS", "STREAM_MAX_CONNECTIONS_PER_IP", "CLOCK_SKEW_THRESHOLD_SECONDS",
+}
+
+// envProblems reports environment variables that Load ignores: values that
+// don't parse, and bridge settings without the token that enables the bridge.
+func envProblems() []Problem {
+	var problems []Problem
+	for _, name := range numericVars {
+		if v := os.Getenv(name); v != "" {
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				problems = append(problems, Problem{name, fmt.Sprintf("%q is not a non-negative whole number; the default is used", v)})
+			}
+		}
+	}
+	if mode := os.Getenv("MAINTENANCE_MODE"); mode != "" && mode != MaintenanceBuffer && mode != MaintenanceDrop {
+		problems = append(problems, Problem{"MAINTENANCE_MODE", fmt.Sprintf("must be %q or %q, got %q", MaintenanceBuffer, MaintenanceDrop, mode)})
+	}
+
+	orphans := []struct{ name, needs string }{
+		{"TELEGRAM_CHAT_ID", "TELEGRAM_BOT_TOKEN"},
+		{"DISCORD_CHANNEL_ID", "DISCORD_BOT_TOKEN"},
+		{"SLACK_CHANNEL_ID", "SLACK_BOT_TOKEN"},
+	}
+	for _, o := range orphans {
+		if os.Getenv(o.name) != "" && os.Getenv(o.needs) == "" {
+			problems = append(problems, Problem{o.needs, fmt.Sprintf("is missing, so %s is ignored and the bridge is disabled", o.name)})
+		}
+	}
+	if os.Getenv("DISCORD_BOT_TOKEN") != "" && os.Getenv("DISCORD_WEBHOOK_URL") != "" {
+		problems = append(problems, Problem{"DISCORD_WEBHOOK_URL", "is ignored because DISCORD_BOT_TOKEN is set (bot mode); unset one of them"})
+	}
+	if os.Getenv("SLACK_BOT_TOKEN") != "" && os.Getenv("SLACK_WEBHOOK_URL") != "" {
+		problems = append(problems, Problem{"SLACK_WEBHOOK_URL", "is ignored because SLACK_BOT_TOKEN is set (bot mode); unset one of them"})
+	}
+	return problems
+}
+
+// Validate checks the configuration and reports every problem at once: half
+// configured bridges, malformed URLs and values Load had to ignore. It
+// returns nil or a *ValidationError. With StrictConfig the server refuses to
+// start on it; otherwise it starts degraded and logs each problem.
+func (c *Config) Validate() error {
+	problems := append([]Problem(nil), c.problems...)
+	add := func(name, format string, args ...interface{}) {
+		problems = append(problems, Problem{name, fmt.Sprintf(format, args...)})
+	}
+
+	if c.Port < 1 || c.Port > 65535 {
+		add("PORT", "must be between 1 and 65535, got %d", c.Port)
+	}
+	if !c.HasBridges() {
+		add("", "no bridge is configured; set TELEGRAM_BOT_TOKEN, DISCORD_BOT_TOKEN/DISCORD_WEBHOOK_URL or SLACK_BOT_TOKEN/SLACK_WEBHOOK_URL")
+	}
+
+	if t := c.Telegram; t != nil {
+		if t.ChatID == "" {
+			add("TELEGRAM_CHAT_ID", "is required with TELEGRAM_BOT_TOKEN: the ID of the forum group, like -1001234567890")
+		} else if _, err := strconv.ParseInt(t.ChatID, 10, 64); err != nil && !strings.HasPrefix(t.ChatID, "@") {
+			add("TELEGRAM_CHAT_ID", "%q is neither a numeric chat ID (like -1001234567890) nor an @username", t.ChatID)
+		}
+		if !strings.Contains(t.BotToken, ":") {
+			add("TELEGRAM_BOT_TOKEN", "doesn't look like a bot token from @BotFather (123456:ABC...)")
+		}
+	}
+
+	if d := c.Discord; d != nil {
+		if d.BotToken != "" {
+			if d.ChannelID == "" {
+				add("DISCORD_CHANNEL_ID", "is required with DISCORD_BOT_TOKEN: the ID of the forum channel (enable Developer Mode, right-click the channel, Copy ID)")
+			}
+		} else {
+			if err := pocketping.ValidateDiscordWebhookConfig(d.WebhookURL); err != nil {
+				add("DISCORD_WEBHOOK_URL", "must start with https://discord.com/api/webhooks/")
+			}
+			if d.EnableGateway {
+				add("DISCORD_ENABLE_GATEWAY", "needs DISCORD_BOT_TOKEN and DISCORD_CHANNEL_ID; webhook mode can't receive replies")
+			}
+		}
+		if d.AvatarURL != "" && !isHTTPURL(d.AvatarURL) {
+			add("DISCORD_AVATAR_URL", "%q is not an http(s) URL", d.AvatarURL)
+		}
+	}
+
+	if s := c.Slack; s != nil {
+		if s.BotToken != "" {
+			if !strings.HasPrefix(s.BotToken, "xoxb-") {
+				add("SLACK_BOT_TOKEN", "must be a bot token starting with xoxb- (OAuth & Permissions > Bot User OAuth Token)")
+			}
+			if s.ChannelID == "" {
+				add("SLACK_CHANNEL_ID", "is required with SLACK_BOT_TOKEN: the channel ID, like C0123456789 (channel details > About)")
+			}
+		} else if err := pocketping.ValidateSlackWebhookConfig(s.WebhookURL); err != nil {
+			add("SLACK_WEBHOOK_URL", "must start with https://hooks.slack.com/")
+		}
+	}
+
+	for _, u := range []struct{ name, value string }{
+		{"BACKEND_WEBHOOK_URL", c.BackendWebhookURL},
+		{"EVENTS_WEBHOOK_URL", c.EventsWebhookURL},
+	} {
+		if u.value != "" && !isHTTPURL(u.value) {
+			add(u.name, "%q is not an http(s) URL", u.value)
+		}
+	}
+	if c.DedupeRedisURL != "" {
+		if _, err := pocketping.NewRedisDeduper(c.DedupeRedisURL, 0); err != nil {
+			add("DEDUPE_REDIS_URL", "must be a redis:// or rediss:// URL")
+		}
+	}
+	if c.EventsWebhookSecret != "" && c.EventsWebhookURL == "" {
+		add("EVENTS_WEBHOOK_SECRET", "is set without EVENTS_WEBHOOK_URL, so no webhook is signed")
+	}
+
+	if f := c.UaFilter; f != nil && f.Enabled && f.Mode == pocketping.UaFilterModeAllowlist && len(f.Allowlist) == 0 {
+		add("UA_FILTER_ALLOWLIST", "is empty in allowlist mode, so every visitor is blocked")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
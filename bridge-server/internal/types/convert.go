@@ -0,0 +1,129 @@
+package types
+
+import "github.com/Ruwad-io/pocketping/sdk-go/events"
+
+// SessionFromEvent returns a session holding the shared fields of an SDK
+// session. It returns nil for a nil session.
+func SessionFromEvent(session *events.Session) *Session {
+	if session == nil {
+		return nil
+	}
+	return &Session{
+		ID:               session.ID,
+		VisitorID:        session.VisitorID,
+		CreatedAt:        session.CreatedAt,
+		LastActivity:     session.LastActivity,
+		OperatorOnline:   session.OperatorOnline,
+		AIActive:         session.AIActive,
+		Metadata:         session.Metadata,
+		Identity:         session.Identity,
+		UserPhone:        session.UserPhone,
+		UserPhoneCountry: session.UserPhoneCountry,
+	}
+}
+
+// SessionToEvent returns the shared fields of a session, without the bridge
+// thread IDs. It returns nil for a nil session.
+func SessionToEvent(s *Session) *events.Session {
+	if s == nil {
+		return nil
+	}
+	return &events.Session{
+		ID:               s.ID,
+		VisitorID:        s.VisitorID,
+		CreatedAt:        s.CreatedAt,
+		LastActivity:     s.LastActivity,
+		OperatorOnline:   s.OperatorOnline,
+		AIActive:         s.AIActive,
+		Metadata:         s.Metadata,
+		Identity:         s.Identity,
+		UserPhone:        s.UserPhone,
+		UserPhoneCountry: s.UserPhoneCountry,
+	}
+}
+
+// MessageFromEvent returns a message holding the shared fields of an SDK
+// message. It returns nil for a nil message.
+func MessageFromEvent(message *events.Message) *Message {
+	if message == nil {
+		return nil
+	}
+	local := &Message{
+		ID:          message.ID,
+		SessionID:   message.SessionID,
+		Content:     message.Content,
+		Sender:      message.Sender,
+		Timestamp:   message.Timestamp,
+		ReplyTo:     message.ReplyTo,
+		Metadata:    message.Metadata,
+		Status:      message.Status,
+		DeliveredAt: message.DeliveredAt,
+		ReadAt:      message.ReadAt,
+		EditedAt:    message.EditedAt,
+		DeletedAt:   message.DeletedAt,
+	}
+	for _, attachment := range message.Attachments {
+		local.Attachments = append(local.Attachments, AttachmentFromEvent(attachment))
+	}
+	return local
+}
+
+// MessageToEvent returns the shared fields of a message, without the
+// platform edit and delete times. It returns nil for a nil message.
+func MessageToEvent(m *Message) *events.Message {
+	if m == nil {
+		return nil
+	}
+	shared := &events.Message{
+		ID:          m.ID,
+		SessionID:   m.SessionID,
+		Content:     m.Content,
+		Sender:      m.Sender,
+		Timestamp:   m.Timestamp,
+		ReplyTo:     m.ReplyTo,
+		Metadata:    m.Metadata,
+		Status:      m.Status,
+		DeliveredAt: m.DeliveredAt,
+		ReadAt:      m.ReadAt,
+		EditedAt:    m.EditedAt,
+		DeletedAt:   m.DeletedAt,
+	}
+	for _, attachment := range m.Attachments {
+		if attachment != nil {
+			shared.Attachments = append(shared.Attachments, AttachmentToEvent(attachment))
+		}
+	}
+	return shared
+}
+
+// AttachmentFromEvent returns an attachment holding the shared fields of an
+// SDK attachment.
+func AttachmentFromEvent(attachment events.Attachment) *Attachment {
+	return &Attachment{
+		ID:           attachment.ID,
+		Filename:     attachment.Filename,
+		MimeType:     attachment.MimeType,
+		Size:         attachment.Size,
+		URL:          attachment.URL,
+		ThumbnailURL: attachment.ThumbnailURL,
+		Status:       attachment.Status,
+		UploadedFrom: attachment.UploadedFrom,
+		BridgeFileID: attachment.BridgeFileID,
+	}
+}
+
+// AttachmentToEvent returns the shared fields of an attachment, without
+// its data.
+func AttachmentToEvent(a *Attachment) events.Attachment {
+	return events.Attachment{
+		ID:           a.ID,
+		Filename:     a.Filename,
+		MimeType:     a.MimeType,
+		Size:         a.Size,
+		URL:          a.URL,
+		ThumbnailURL: a.ThumbnailURL,
+		Status:       a.Status,
+		UploadedFrom: a.UploadedFrom,
+		BridgeFileID: a.BridgeFileID,
+	}
+}
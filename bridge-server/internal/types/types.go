@@ -1,32 +1,18 @@
 // Package types defines the core types for PocketPing Bridge Server
 package types
 
-import "time"
+import (
+	"time"
 
-// UserIdentity represents user identity data from PocketPing.identify()
-type UserIdentity struct {
-	ID           string                 `json:"id"`
-	Email        string                 `json:"email,omitempty"`
-	Name         string                 `json:"name,omitempty"`
-	CustomFields map[string]interface{} `json:"-"`
-}
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
+)
+
+// UserIdentity represents user identity data from PocketPing.identify().
+// Custom fields are kept in Extra.
+type UserIdentity = events.UserIdentity
 
 // SessionMetadata contains metadata about a chat session
-type SessionMetadata struct {
-	URL              string `json:"url,omitempty"`
-	Referrer         string `json:"referrer,omitempty"`
-	PageTitle        string `json:"pageTitle,omitempty"`
-	UserAgent        string `json:"userAgent,omitempty"`
-	Timezone         string `json:"timezone,omitempty"`
-	Language         string `json:"language,omitempty"`
-	ScreenResolution string `json:"screenResolution,omitempty"`
-	IP               string `json:"ip,omitempty"`
-	Country          string `json:"country,omitempty"`
-	City             string `json:"city,omitempty"`
-	DeviceType       string `json:"deviceType,omitempty"`
-	Browser          string `json:"browser,omitempty"`
-	OS               string `json:"os,omitempty"`
-}
+type SessionMetadata = events.SessionMetadata
 
 // Session represents a chat session
 type Session struct {
@@ -47,22 +33,22 @@ type Session struct {
 }
 
 // SenderType represents who sent a message
-type SenderType string
+type SenderType = events.Sender
 
 const (
-	SenderVisitor  SenderType = "visitor"
-	SenderOperator SenderType = "operator"
-	SenderAI       SenderType = "ai"
+	SenderVisitor  = events.SenderVisitor
+	SenderOperator = events.SenderOperator
+	SenderAI       = events.SenderAI
 )
 
 // MessageStatus represents the delivery status of a message
-type MessageStatus string
+type MessageStatus = events.MessageStatus
 
 const (
-	StatusSending   MessageStatus = "sending"
-	StatusSent      MessageStatus = "sent"
-	StatusDelivered MessageStatus = "delivered"
-	StatusRead      MessageStatus = "read"
+	StatusSending   = events.MessageStatusSending
+	StatusSent      = events.MessageStatusSent
+	StatusDelivered = events.MessageStatusDelivered
+	StatusRead      = events.MessageStatusRead
 )
 
 // Attachment represents a file attachment
@@ -81,18 +67,24 @@ type Attachment struct {
 
 // Message represents a chat message
 type Message struct {
-	ID          string        `json:"id"`
-	SessionID   string        `json:"sessionId"`
-	Content     string        `json:"content"`
-	Sender      SenderType    `json:"sender"`
-	Timestamp   time.Time     `json:"timestamp"`
-	ReplyTo     string        `json:"replyTo,omitempty"`
-	Attachments []*Attachment `json:"attachments,omitempty"`
-	Status      MessageStatus `json:"status,omitempty"`
-	DeliveredAt *time.Time    `json:"deliveredAt,omitempty"`
-	ReadAt      *time.Time    `json:"readAt,omitempty"`
-	EditedAt    *time.Time    `json:"editedAt,omitempty"`
-	DeletedAt   *time.Time    `json:"deletedAt,omitempty"`
+	ID          string                 `json:"id"`
+	SessionID   string                 `json:"sessionId"`
+	Content     string                 `json:"content"`
+	Sender      SenderType             `json:"sender"`
+	Timestamp   time.Time              `json:"timestamp"`
+	ReplyTo     string                 `json:"replyTo,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Attachments []*Attachment          `json:"attachments,omitempty"`
+	Status      MessageStatus          `json:"status,omitempty"`
+	DeliveredAt *time.Time             `json:"deliveredAt,omitempty"`
+	ReadAt      *time.Time             `json:"readAt,omitempty"`
+	EditedAt    *time.Time             `json:"editedAt,omitempty"`
+	DeletedAt   *time.Time             `json:"deletedAt,omitempty"`
+	// PlatformEditedAt and PlatformDeletedAt are the times reported by the
+	// bridge platform; EditedAt and DeletedAt are normalized to local time
+	// (see pocketping.ClockSkewDetector).
+	PlatformEditedAt  *time.Time `json:"platformEditedAt,omitempty"`
+	PlatformDeletedAt *time.Time `json:"platformDeletedAt,omitempty"`
 }
 
 // CustomEvent represents a custom event from the widget
@@ -132,10 +124,14 @@ type AITakeoverEvent struct {
 	Reason  string   `json:"reason"`
 }
 
-// OperatorStatusEvent is sent when operator status changes
+// OperatorStatusEvent is sent when operator status changes. With OperatorID
+// it is a per-operator heartbeat (see OperatorPresenceEvent); without, it sets
+// the legacy global status.
 type OperatorStatusEvent struct {
-	Type   string `json:"type"`
-	Online bool   `json:"online"`
+	Type         string `json:"type"`
+	Online       bool   `json:"online"`
+	OperatorID   string `json:"operatorId,omitempty"`
+	OperatorName string `json:"operatorName,omitempty"`
 }
 
 // MessageReadEvent is sent when messages are marked as read
@@ -197,6 +193,21 @@ type CsatSubmittedEvent struct {
 	RespondedAt string   `json:"respondedAt,omitempty"` // ISO-8601
 }
 
+// AssignmentUpdateEvent is sent by a backend or workforce tool to (re)assign a
+// session. A nil Assignee unassigns it.
+type AssignmentUpdateEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	Assignee  *Assignee `json:"assignee"`
+}
+
+// SessionCloseEvent is sent by a backend to close a session.
+type SessionCloseEvent struct {
+	Type      string `json:"type"`
+	SessionID string `json:"sessionId"`
+	Reason    string `json:"reason,omitempty"`
+}
+
 // ─────────────────────────────────────────────────────────────────
 // Outgoing Events (from bridge-server to backends)
 // ─────────────────────────────────────────────────────────────────
@@ -218,7 +229,7 @@ type OperatorMessageEvent struct {
 	ReplyToBridgeMessageID *int          `json:"replyToBridgeMessageId,omitempty"` // Telegram message_id being replied to
 }
 
-func (e *OperatorMessageEvent) EventType() string { return "operator_message" }
+func (e *OperatorMessageEvent) EventType() string { return events.TypeOperatorMessage }
 
 // OperatorMessageEditedEvent is sent when an operator edits a message from a bridge
 type OperatorMessageEditedEvent struct {
@@ -229,7 +240,7 @@ type OperatorMessageEditedEvent struct {
 	EditedAt  time.Time `json:"editedAt"`
 }
 
-func (e *OperatorMessageEditedEvent) EventType() string { return "operator_message_edited" }
+func (e *OperatorMessageEditedEvent) EventType() string { return events.TypeOperatorMessageEdited }
 
 // OperatorMessageDeletedEvent is sent when an operator deletes a message from a bridge
 type OperatorMessageDeletedEvent struct {
@@ -239,7 +250,7 @@ type OperatorMessageDeletedEvent struct {
 	DeletedAt time.Time `json:"deletedAt"`
 }
 
-func (e *OperatorMessageDeletedEvent) EventType() string { return "operator_message_deleted" }
+func (e *OperatorMessageDeletedEvent) EventType() string { return events.TypeOperatorMessageDeleted }
 
 // OperatorTypingEvent is sent when an operator starts/stops typing
 type OperatorTypingEvent struct {
@@ -249,16 +260,21 @@ type OperatorTypingEvent struct {
 	SourceBridge string `json:"sourceBridge"`
 }
 
-func (e *OperatorTypingEvent) EventType() string { return "operator_typing" }
+func (e *OperatorTypingEvent) EventType() string { return events.TypeOperatorTyping }
 
-// SessionClosedEvent is sent when a session is closed from a bridge
+// SessionClosedEvent is sent when a session is closed from a bridge or the
+// API. SourceBridge is "api" for closes pushed through the inbound API.
 type SessionClosedEvent struct {
-	Type         string `json:"type"`
-	SessionID    string `json:"sessionId"`
-	SourceBridge string `json:"sourceBridge"`
+	Type          string    `json:"type"`
+	SchemaVersion int       `json:"schemaVersion,omitempty"`
+	SessionID     string    `json:"sessionId"`
+	SourceBridge  string    `json:"sourceBridge"`
+	Assignee      *Assignee `json:"assignee,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	ClosedAt      string    `json:"closedAt,omitempty"` // ISO-8601
 }
 
-func (e *SessionClosedEvent) EventType() string { return "session_closed" }
+func (e *SessionClosedEvent) EventType() string { return events.TypeSessionClosed }
 
 // CsatRequestEvent is sent to the widget (over SSE) to ask the visitor to rate
 // the conversation. The widget shows its rating card on receipt. The trigger
@@ -273,6 +289,104 @@ type CsatRequestEvent struct {
 
 func (e *CsatRequestEvent) EventType() string { return "csat_request" }
 
+// ─────────────────────────────────────────────────────────────────
+// Workforce Events (assignment, SLA, closure)
+// ─────────────────────────────────────────────────────────────────
+
+// WorkforceSchemaVersion is the version of the assignment, SLA and closure
+// event payloads. Fields are only ever added within a version.
+const WorkforceSchemaVersion = 1
+
+// SLA states reported by SLAStateChangedEvent.
+const (
+	SLAStateWaiting   = "waiting"   // visitor is waiting for a first reply
+	SLAStateBreached  = "breached"  // the deadline passed without a reply
+	SLAStateResponded = "responded" // an operator replied (see Breached)
+)
+
+// Assignee identifies the operator a session is assigned to.
+type Assignee = events.Assignee
+
+// AssignmentChangedEvent is sent when a session's assignee changes. Source is
+// "api" for pushes through the inbound API, or the bridge an operator replied
+// from (first reply auto-assigns).
+type AssignmentChangedEvent struct {
+	Type             string    `json:"type"`
+	SchemaVersion    int       `json:"schemaVersion"`
+	SessionID        string    `json:"sessionId"`
+	Assignee         *Assignee `json:"assignee"`
+	PreviousAssignee *Assignee `json:"previousAssignee"`
+	Source           string    `json:"source"`
+	ChangedAt        string    `json:"changedAt"` // ISO-8601
+}
+
+func (e *AssignmentChangedEvent) EventType() string { return events.TypeAssignmentChanged }
+
+// SLAStateChangedEvent is sent when a session's first-response SLA changes
+// state: a visitor starts waiting, the deadline passes, or an operator replies.
+type SLAStateChangedEvent struct {
+	Type          string    `json:"type"`
+	SchemaVersion int       `json:"schemaVersion"`
+	SessionID     string    `json:"sessionId"`
+	State         string    `json:"state"`
+	Assignee      *Assignee `json:"assignee"`
+	WaitingSince  string    `json:"waitingSince"` // ISO-8601
+	Deadline      string    `json:"deadline"`     // ISO-8601
+	RespondedAt   string    `json:"respondedAt,omitempty"`
+	Breached      bool      `json:"breached"`
+	ChangedAt     string    `json:"changedAt"` // ISO-8601
+}
+
+func (e *SLAStateChangedEvent) EventType() string { return "sla_state_changed" }
+
+// ─────────────────────────────────────────────────────────────────
+// Operator Presence
+// ─────────────────────────────────────────────────────────────────
+
+// OperatorPresence is the presence of a named operator.
+type OperatorPresence struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Online   bool   `json:"online"`
+	LastSeen string `json:"lastSeen"` // ISO-8601
+}
+
+// OperatorPresenceEvent is sent when a named operator comes online, goes
+// offline, or misses their heartbeat. Online tells whether any operator is
+// still online afterwards.
+type OperatorPresenceEvent struct {
+	Type     string            `json:"type"`
+	Operator *OperatorPresence `json:"operator"`
+	Online   bool              `json:"online"`
+	// Reason is "status" for explicit updates and "expired" for missed
+	// heartbeats.
+	Reason    string `json:"reason"`
+	ChangedAt string `json:"changedAt"` // ISO-8601
+}
+
+func (e *OperatorPresenceEvent) EventType() string { return "operator_presence" }
+
+// MaintenanceStatus is the maintenance state returned by
+// POST /api/admin/maintenance.
+type MaintenanceStatus struct {
+	Enabled bool   `json:"enabled"`
+	Mode    string `json:"mode,omitempty"`
+	Message string `json:"message,omitempty"`
+	Since   string `json:"since,omitempty"` // ISO-8601
+	// Buffered notifications are waiting for maintenance to end.
+	Buffered int `json:"buffered"`
+	// Dropped counts notifications discarded in this maintenance window.
+	Dropped int `json:"dropped"`
+	// Replayed counts buffered notifications sent when maintenance ended.
+	Replayed int `json:"replayed,omitempty"`
+}
+
+// OperatorStatusResponse is returned by GET /api/operator/status.
+type OperatorStatusResponse struct {
+	Online    bool                `json:"online"`
+	Operators []*OperatorPresence `json:"operators"`
+}
+
 // ─────────────────────────────────────────────────────────────────
 // Bridge Message IDs (for edit/delete sync)
 // ─────────────────────────────────────────────────────────────────
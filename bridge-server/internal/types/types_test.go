@@ -2,8 +2,12 @@ package types
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
+
+	pocketping "github.com/Ruwad-io/pocketping/sdk-go"
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
 )
 
 func TestSenderTypeConstants(t *testing.T) {
@@ -480,3 +484,74 @@ func TestIncomingEvents_JSON(t *testing.T) {
 		}
 	})
 }
+
+func TestSharedEventTypesCompatible(t *testing.T) {
+	for _, pair := range []struct{ shared, local interface{} }{
+		{events.Session{}, Session{}},
+		{events.Message{}, Message{}},
+		{events.Attachment{}, Attachment{}},
+		{events.NewSessionEvent{}, NewSessionEvent{}},
+		{events.VisitorMessageEvent{}, VisitorMessageEvent{}},
+		{events.IdentityUpdateEvent{}, IdentityUpdateEvent{}},
+		{events.MessageReadEvent{}, MessageReadEvent{}},
+		{events.VisitorMessageEditedEvent{}, VisitorMessageEditedEvent{}},
+		{events.VisitorMessageDeletedEvent{}, VisitorMessageDeletedEvent{}},
+		{events.OperatorMessageEvent{}, OperatorMessageEvent{}},
+		{events.OperatorMessageEditedEvent{}, OperatorMessageEditedEvent{}},
+		{events.OperatorMessageDeletedEvent{}, OperatorMessageDeletedEvent{}},
+		{events.OperatorTypingEvent{}, OperatorTypingEvent{}},
+		{events.SessionClosedEvent{}, SessionClosedEvent{}},
+		{events.AssignmentChangedEvent{}, AssignmentChangedEvent{}},
+		// The SDK's own structs, so both halves agree with each other.
+		{events.Session{}, pocketping.Session{}},
+		{events.Message{}, pocketping.Message{}},
+	} {
+		if err := events.Compatible(pair.shared, pair.local); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestVisitorMessageFromSDK(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	sdkSession := &pocketping.Session{
+		ID:        "s1",
+		VisitorID: "v1",
+		CreatedAt: now,
+		Identity:  &pocketping.UserIdentity{ID: "u1", Extra: map[string]interface{}{"plan": "pro"}},
+	}
+	sdkMessage := &pocketping.Message{
+		ID:          "m1",
+		SessionID:   "s1",
+		Content:     "hi",
+		Sender:      pocketping.SenderVisitor,
+		Timestamp:   now,
+		Metadata:    map[string]interface{}{"source": "widget"},
+		Attachments: []pocketping.Attachment{{ID: "a1", Filename: "f.png", Status: pocketping.AttachmentStatusReady}},
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"type":    events.TypeVisitorMessage,
+		"session": sdkSession,
+		"message": sdkMessage,
+	})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var event VisitorMessageEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if event.Session.Identity.Extra["plan"] != "pro" {
+		t.Errorf("identity custom fields lost: %+v", event.Session.Identity)
+	}
+	if event.Message.Sender != SenderVisitor || event.Message.Metadata["source"] != "widget" {
+		t.Errorf("message = %+v", event.Message)
+	}
+	if got, want := MessageToEvent(event.Message), pocketping.MessageToEvent(sdkMessage); !reflect.DeepEqual(got, want) {
+		t.Errorf("message decoded as %+v, want %+v", got, want)
+	}
+	if got := SessionFromEvent(SessionToEvent(event.Session)); !reflect.DeepEqual(got, event.Session) {
+		t.Errorf("session round trip = %+v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultAckTimeout is how long an operator message may go unacknowledged
+// before UndeliveredMessages reports it, when Config.AckTimeout is zero.
+const DefaultAckTimeout = 30 * time.Second
+
+// ErrAcksUnsupported is returned by HandleAck and UndeliveredMessages unless
+// event replay is on (Config.AffinitySecret) and the storage implements
+// StorageWithAcks.
+var ErrAcksUnsupported = errors.New("acknowledgments need event replay and storage implementing StorageWithAcks")
+
+// AckRequest is a widget's acknowledgment of every event up to Seq.
+type AckRequest struct {
+	SessionID string `json:"sessionId"`
+	Seq       int64  `json:"seq"`
+}
+
+// ackedMessage is the part of a "message" event's data acks look at.
+type ackedMessage struct {
+	ID     string `json:"id"`
+	Sender Sender `json:"sender"`
+}
+
+// ackStorage returns the storage tracking acks, or nil.
+func (pp *PocketPing) ackStorage() StorageWithAcks {
+	if pp.replayStorage() == nil {
+		return nil
+	}
+	store, _ := pp.storage.(StorageWithAcks)
+	return store
+}
+
+// HandleAck records that the widget received the session's events up to
+// request.Seq. Operator and AI messages among them are marked delivered, as
+// with HandleRead, so bridges and operator consoles see it. Only messages
+// still in the replay buffer (the last MaxReplayEvents events) are marked.
+func (pp *PocketPing) HandleAck(ctx context.Context, request AckRequest) error {
+	store := pp.ackStorage()
+	if store == nil {
+		return ErrAcksUnsupported
+	}
+	session, err := pp.storage.GetSession(ctx, request.SessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrSessionNotFound
+	}
+
+	acked, err := store.GetAckedSeq(ctx, request.SessionID)
+	if err != nil {
+		return err
+	}
+	if request.Seq <= acked {
+		return nil
+	}
+	messages, err := pp.sentMessages(ctx, request.SessionID, acked, request.Seq)
+	if err != nil {
+		return err
+	}
+	if err := store.SetAckedSeq(ctx, request.SessionID, request.Seq); err != nil {
+		return err
+	}
+
+	var delivered []string
+	for _, message := range messages {
+		delivered = append(delivered, message.ID)
+	}
+	if len(delivered) == 0 {
+		return nil
+	}
+	_, err = pp.HandleRead(ctx, ReadRequest{
+		SessionID:  request.SessionID,
+		MessageIDs: delivered,
+		Status:     MessageStatusDelivered,
+	})
+	return err
+}
+
+// UndeliveredMessages returns the operator and AI messages the widget hasn't
+// acknowledged within Config.AckTimeout, oldest first. They are re-sent when
+// the widget reconnects. Messages already trimmed from the replay buffer
+// aren't tracked: a widget that missed them gets resync_required instead
+// (see MissedEvents).
+func (pp *PocketPing) UndeliveredMessages(ctx context.Context, sessionID string) ([]Message, error) {
+	store := pp.ackStorage()
+	if store == nil {
+		return nil, ErrAcksUnsupported
+	}
+	acked, err := store.GetAckedSeq(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := pp.sentMessages(ctx, sessionID, acked, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := pp.config.AckTimeout
+	if timeout <= 0 {
+		timeout = DefaultAckTimeout
+	}
+	undelivered := []Message{}
+	for _, message := range messages {
+		if time.Since(message.Timestamp) >= timeout {
+			undelivered = append(undelivered, message)
+		}
+	}
+	return undelivered, nil
+}
+
+// sentMessages returns the operator and AI messages broadcast after
+// afterSeq (up to uptoSeq, when set) that are still only sent.
+func (pp *PocketPing) sentMessages(ctx context.Context, sessionID string, afterSeq, uptoSeq int64) ([]Message, error) {
+	events, err := pp.replayStorage().GetSessionEvents(ctx, sessionID, afterSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []Message{}
+	for _, event := range events {
+		if event.Type != "message" || (uptoSeq > 0 && event.Seq > uptoSeq) {
+			continue
+		}
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			continue
+		}
+		var sent ackedMessage
+		if json.Unmarshal(data, &sent) != nil || (sent.Sender != SenderOperator && sent.Sender != SenderAI) {
+			continue
+		}
+		message, err := pp.storage.GetMessage(ctx, sent.ID)
+		if err != nil {
+			return nil, err
+		}
+		if message != nil && message.DeletedAt == nil && (message.Status == MessageStatusSent || message.Status == "") {
+			messages = append(messages, *message)
+		}
+	}
+	return messages, nil
+}
+
+// resendFrom moves a reconnecting widget's replay point back to its last
+// acknowledgment, so unacknowledged events are sent again. Widgets that
+// never acknowledged keep their own replay point.
+func (pp *PocketPing) resendFrom(ctx context.Context, sessionID string, afterSeq int64) int64 {
+	store := pp.ackStorage()
+	if store == nil || afterSeq <= 0 {
+		return afterSeq
+	}
+	if acked, err := store.GetAckedSeq(ctx, sessionID); err == nil && acked > 0 && acked < afterSeq {
+		return acked
+	}
+	return afterSeq
+}
@@ -0,0 +1,76 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandleAck(t *testing.T) {
+	ctx := context.Background()
+	bridge := NewMockBridge("slack")
+	pp := New(Config{Bridges: []Bridge{bridge}, AffinitySecret: "s3cret", AckTimeout: time.Nanosecond})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	first, err := pp.SendOperatorMessage(ctx, sessionID, "first", "slack", "Ann")
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	sendVisitorMessage(t, pp, sessionID, "thanks")
+	second, err := pp.SendOperatorMessage(ctx, sessionID, "second", "slack", "Ann")
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+
+	undelivered, err := pp.UndeliveredMessages(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("UndeliveredMessages: %v", err)
+	}
+	if len(undelivered) != 2 {
+		t.Fatalf("undelivered = %d messages, want 2", len(undelivered))
+	}
+
+	// Events 1 and 2: the first reply and the visitor message.
+	if err := pp.HandleAck(ctx, AckRequest{SessionID: sessionID, Seq: 2}); err != nil {
+		t.Fatalf("HandleAck: %v", err)
+	}
+	if msg, _ := pp.storage.GetMessage(ctx, first.ID); msg.Status != MessageStatusDelivered || msg.DeliveredAt == nil {
+		t.Errorf("acked message status = %q", msg.Status)
+	}
+	pp.dispatcher.wait()
+	bridge.mu.Lock()
+	reads := len(bridge.ReadCalls)
+	bridge.mu.Unlock()
+	if reads != 1 {
+		t.Errorf("bridge got %d read notifications, want 1", reads)
+	}
+	undelivered, _ = pp.UndeliveredMessages(ctx, sessionID)
+	if len(undelivered) != 1 || undelivered[0].ID != second.ID {
+		t.Errorf("undelivered after ack = %+v, want the second reply", undelivered)
+	}
+
+	// A stale ack changes nothing.
+	if err := pp.HandleAck(ctx, AckRequest{SessionID: sessionID, Seq: 1}); err != nil {
+		t.Fatalf("HandleAck: %v", err)
+	}
+
+	// The widget says it saw everything, but only acked up to 2: the second
+	// reply is sent again.
+	ws := &mockWSConn{}
+	if err := pp.ResumeWebSocket(ctx, sessionID, 5, ws); err != nil {
+		t.Fatalf("ResumeWebSocket: %v", err)
+	}
+	if ws.count() == 0 || ws.events[0].Seq != 3 {
+		t.Errorf("resent events = %+v, want from seq 3", ws.events)
+	}
+}
+
+func TestHandleAckUnsupported(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	if err := pp.HandleAck(ctx, AckRequest{SessionID: sessionID, Seq: 1}); !errors.Is(err, ErrAcksUnsupported) {
+		t.Errorf("err = %v, want ErrAcksUnsupported", err)
+	}
+}
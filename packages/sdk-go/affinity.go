@@ -0,0 +1,181 @@
+package pocketping
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAffinityTokenTTL is how long an affinity token stays valid.
+const DefaultAffinityTokenTTL = 24 * time.Hour
+
+// MaxReplayEvents is the most events MemoryStorage and RedisReplayStore
+// buffer per session for replay.
+const MaxReplayEvents = 100
+
+// ErrInvalidAffinityToken is returned by ParseAffinityToken for tokens that
+// are malformed, wrongly signed or expired.
+var ErrInvalidAffinityToken = newError("invalid_affinity_token", http.StatusBadRequest, "invalid affinity token")
+
+// AffinityToken identifies a widget's session and the node serving it. Load
+// balancers can route on Node; any node accepts the token when that one is
+// gone.
+type AffinityToken struct {
+	SessionID string `json:"sid"`
+	VisitorID string `json:"vid"`
+	Node      string `json:"node"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+// transientEvents aren't buffered for replay.
+var transientEvents = map[string]bool{"typing": true}
+
+// ParseAffinityToken verifies a token signed with secret and returns its
+// claims.
+func ParseAffinityToken(secret, token string) (*AffinityToken, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return nil, ErrInvalidAffinityToken
+	}
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, signAffinity(secret, payload)) {
+		return nil, ErrInvalidAffinityToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidAffinityToken
+	}
+	var claims AffinityToken
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, ErrInvalidAffinityToken
+	}
+	if time.Since(time.Unix(claims.IssuedAt, 0)) > DefaultAffinityTokenTTL {
+		return nil, ErrInvalidAffinityToken
+	}
+	return &claims, nil
+}
+
+func signAffinity(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// NodeID returns this node's name: Config.NodeID, or a random ID.
+func (pp *PocketPing) NodeID() string {
+	if pp.config.NodeID != "" {
+		return pp.config.NodeID
+	}
+	return pp.replicaID
+}
+
+// issueAffinityToken signs a token for the session on this node, or returns
+// "" unless Config.AffinitySecret is set.
+func (pp *PocketPing) issueAffinityToken(session *Session) string {
+	if pp.config.AffinitySecret == "" {
+		return ""
+	}
+	data, _ := json.Marshal(AffinityToken{
+		SessionID: session.ID,
+		VisitorID: session.VisitorID,
+		Node:      pp.NodeID(),
+		IssuedAt:  time.Now().Unix(),
+	})
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signAffinity(pp.config.AffinitySecret, payload))
+}
+
+// resumeSessionID returns the session a connect request's affinity token
+// points to, or "" if it has none or it doesn't check out.
+func (pp *PocketPing) resumeSessionID(request ConnectRequest) string {
+	if request.AffinityToken == "" || pp.config.AffinitySecret == "" {
+		return ""
+	}
+	token, err := ParseAffinityToken(pp.config.AffinitySecret, request.AffinityToken)
+	if err != nil || token.VisitorID != request.VisitorID {
+		return ""
+	}
+	if token.Node != pp.NodeID() {
+		log.Printf("[PocketPing] Session %s handed off from node %s", token.SessionID, token.Node)
+	}
+	return token.SessionID
+}
+
+// replayStorage returns the store buffering events for replay:
+// Config.ReplayStore, or the storage if it supports it. It returns nil
+// unless Config.AffinitySecret is set.
+func (pp *PocketPing) replayStorage() ReplayStore {
+	if pp.config.AffinitySecret == "" {
+		return nil
+	}
+	if pp.config.ReplayStore != nil {
+		return pp.config.ReplayStore
+	}
+	if store, ok := pp.storage.(StorageWithEventReplay); ok {
+		return store
+	}
+	return nil
+}
+
+// recordEvent buffers a session event for replay and numbers it.
+func (pp *PocketPing) recordEvent(sessionID string, event WebSocketEvent) WebSocketEvent {
+	store := pp.replayStorage()
+	if store == nil || transientEvents[event.Type] {
+		return event
+	}
+	seq, err := store.AppendSessionEvent(context.Background(), sessionID, event)
+	if err != nil {
+		log.Printf("[PocketPing] Buffering %s event for replay failed: %v", event.Type, err)
+		return event
+	}
+	event.Seq = seq
+	return event
+}
+
+// MissedEvents returns a session's events after afterSeq, oldest first, or
+// nil when event replay is off. When some of them were already trimmed from
+// the buffer (see MaxReplayEvents), a resync_required event comes first:
+// the widget must reload the conversation rather than rely on the replay.
+func (pp *PocketPing) MissedEvents(ctx context.Context, sessionID string, afterSeq int64) ([]WebSocketEvent, error) {
+	store := pp.replayStorage()
+	if store == nil {
+		return nil, nil
+	}
+	events, err := store.GetSessionEvents(ctx, sessionID, afterSeq)
+	if err != nil || len(events) == 0 || events[0].Seq <= afterSeq+1 {
+		return events, err
+	}
+	resync := WebSocketEvent{Type: "resync_required", Data: map[string]int64{"afterSeq": afterSeq, "oldestSeq": events[0].Seq}}
+	return append([]WebSocketEvent{resync}, events...), nil
+}
+
+// ResumeWebSocket registers a reconnecting widget's socket and replays the
+// events it missed since afterSeq (or its last acknowledgment, if earlier;
+// see HandleAck), on whichever node it landed. Events
+// arriving meanwhile may be sent twice; widgets skip any Seq they have seen.
+func (pp *PocketPing) ResumeWebSocket(ctx context.Context, sessionID string, afterSeq int64, conn WebSocketConn) error {
+	writer := pp.registerWebSocket(sessionID, conn)
+	pp.waitQueueDisconnected(sessionID, true)
+	if pp.replayStorage() == nil {
+		pp.flushOffline(sessionID, conn, writer)
+		return nil
+	}
+	pp.takeOffline(sessionID) // replay covers them
+	events, err := pp.MissedEvents(ctx, sessionID, pp.resendFrom(ctx, sessionID, afterSeq))
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := writer.WriteJSON(event); err != nil {
+			pp.UnregisterWebSocket(sessionID, conn)
+			return err
+		}
+	}
+	return nil
+}
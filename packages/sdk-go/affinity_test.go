@@ -0,0 +1,179 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAffinityToken(t *testing.T) {
+	pp := New(Config{AffinitySecret: "s3cret", NodeID: "node-a"})
+	token := pp.issueAffinityToken(&Session{ID: "sess-1", VisitorID: "v1"})
+
+	claims, err := ParseAffinityToken("s3cret", token)
+	if err != nil {
+		t.Fatalf("ParseAffinityToken: %v", err)
+	}
+	if claims.SessionID != "sess-1" || claims.VisitorID != "v1" || claims.Node != "node-a" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := ParseAffinityToken("other", token); !errors.Is(err, ErrInvalidAffinityToken) {
+		t.Errorf("wrong secret: err = %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _ := json.Marshal(AffinityToken{SessionID: "sess-2", VisitorID: "v1", IssuedAt: time.Now().Unix()})
+	if _, err := ParseAffinityToken("s3cret", base64.RawURLEncoding.EncodeToString(forged)+"."+signature); !errors.Is(err, ErrInvalidAffinityToken) {
+		t.Errorf("forged payload: err = %v", err)
+	}
+	if _, err := ParseAffinityToken("s3cret", payload); !errors.Is(err, ErrInvalidAffinityToken) {
+		t.Errorf("unsigned: err = %v", err)
+	}
+
+	stale, _ := json.Marshal(AffinityToken{SessionID: "sess-1", VisitorID: "v1", IssuedAt: time.Now().Add(-DefaultAffinityTokenTTL - time.Minute).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(stale)
+	expired := encoded + "." + base64.RawURLEncoding.EncodeToString(signAffinity("s3cret", encoded))
+	if _, err := ParseAffinityToken("s3cret", expired); !errors.Is(err, ErrInvalidAffinityToken) {
+		t.Errorf("expired: err = %v", err)
+	}
+}
+
+func TestHandoffAcrossNodes(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	bus := &memoryBus{}
+	a := New(Config{Storage: storage, Broadcaster: bus, AffinitySecret: "s3cret", NodeID: "node-a"})
+	b := New(Config{Storage: storage, Broadcaster: bus, AffinitySecret: "s3cret", NodeID: "node-b"})
+	for _, pp := range []*PocketPing{a, b} {
+		if err := pp.Start(ctx); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	}
+
+	first, err := a.HandleConnect(ctx, ConnectRequest{VisitorID: "v1"})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if first.AffinityToken == "" || first.Node != "node-a" {
+		t.Fatalf("response token = %q, node = %q", first.AffinityToken, first.Node)
+	}
+	ws := &mockWSConn{}
+	a.RegisterWebSocket(first.SessionID, ws)
+	sendVisitorMessage(t, a, first.SessionID, "hello")
+	a.UnregisterWebSocket(first.SessionID, ws)
+	if ws.count() != 1 || ws.events[0].Seq != 1 {
+		t.Fatalf("events before disconnect = %+v", ws.events)
+	}
+
+	// While the widget is away: a reply and a typing indicator.
+	if _, err := a.SendOperatorMessage(ctx, first.SessionID, "hi there", "slack", "Ann"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	a.BroadcastToSession(first.SessionID, WebSocketEvent{Type: "typing", Data: map[string]bool{"isTyping": true}})
+
+	resumed, err := b.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", AffinityToken: first.AffinityToken, LastEventSeq: 1})
+	if err != nil {
+		t.Fatalf("HandleConnect on node b: %v", err)
+	}
+	if resumed.SessionID != first.SessionID || resumed.Node != "node-b" {
+		t.Errorf("resumed session = %q on %q, want %q on node-b", resumed.SessionID, resumed.Node, first.SessionID)
+	}
+	if len(resumed.MissedEvents) != 1 || resumed.MissedEvents[0].Type != "message" || resumed.MissedEvents[0].Seq != 2 {
+		t.Errorf("missed events = %+v, want the operator message", resumed.MissedEvents)
+	}
+
+	replay := &mockWSConn{}
+	if err := b.ResumeWebSocket(ctx, first.SessionID, 0, replay); err != nil {
+		t.Fatalf("ResumeWebSocket: %v", err)
+	}
+	if got := replay.types(); len(got) != 2 {
+		t.Errorf("replayed = %v, want both messages", got)
+	}
+	a.BroadcastToSession(first.SessionID, WebSocketEvent{Type: "custom"})
+	if replay.count() != 3 || replay.events[2].Seq != 3 {
+		t.Errorf("live events on node b = %+v", replay.events)
+	}
+
+	// A token for another visitor doesn't resume the session.
+	other, err := b.HandleConnect(ctx, ConnectRequest{VisitorID: "v2", AffinityToken: first.AffinityToken})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if other.SessionID == first.SessionID {
+		t.Error("token resumed another visitor's session")
+	}
+}
+
+func TestMemoryStorageReplayBuffer(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	for i := 0; i < MaxReplayEvents+5; i++ {
+		if _, err := storage.AppendSessionEvent(ctx, "s1", WebSocketEvent{Type: "message"}); err != nil {
+			t.Fatalf("AppendSessionEvent: %v", err)
+		}
+	}
+	events, _ := storage.GetSessionEvents(ctx, "s1", 0)
+	if len(events) != MaxReplayEvents || events[0].Seq != 6 {
+		t.Errorf("buffered %d events starting at %d", len(events), events[0].Seq)
+	}
+
+	_ = storage.CreateSession(ctx, &Session{ID: "s1", VisitorID: "v1"})
+	_ = storage.DeleteSession(ctx, "s1")
+	if events, _ := storage.GetSessionEvents(ctx, "s1", 0); len(events) != 0 {
+		t.Errorf("%d events left after DeleteSession", len(events))
+	}
+}
+
+func TestMissedEventsResyncRequired(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	pp := New(Config{Storage: storage, AffinitySecret: "s3cret"})
+	for i := 0; i < MaxReplayEvents+5; i++ {
+		pp.recordEvent("s1", WebSocketEvent{Type: "message"})
+	}
+
+	events, err := pp.MissedEvents(ctx, "s1", 2)
+	if err != nil {
+		t.Fatalf("MissedEvents: %v", err)
+	}
+	if len(events) != MaxReplayEvents+1 || events[0].Type != "resync_required" || events[1].Seq != 6 {
+		t.Errorf("missed %d events, first %+v; want resync_required before the buffer", len(events), events[0])
+	}
+	if events, _ := pp.MissedEvents(ctx, "s1", 5); len(events) != MaxReplayEvents || events[0].Type == "resync_required" {
+		t.Errorf("missed %d events, first %+v; want no resync when nothing was trimmed", len(events), events[0])
+	}
+}
+
+func TestRedisReplayStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewRedisReplayStore("redis://" + fakeRedis(t))
+	if err != nil {
+		t.Fatalf("NewRedisReplayStore: %v", err)
+	}
+	defer store.Close()
+
+	pp := New(Config{AffinitySecret: "s3cret", ReplayStore: store})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "hello")
+	for i := 0; i < MaxReplayEvents+5; i++ {
+		if _, err := store.AppendSessionEvent(ctx, "s1", WebSocketEvent{Type: "custom", Data: map[string]int{"n": i}}); err != nil {
+			t.Fatalf("AppendSessionEvent: %v", err)
+		}
+	}
+
+	events, err := store.GetSessionEvents(ctx, "s1", 0)
+	if err != nil || len(events) != MaxReplayEvents || events[0].Seq != 6 {
+		t.Fatalf("buffered %d events (%v), want the last %d", len(events), err, MaxReplayEvents)
+	}
+	if string(events[0].Data.(json.RawMessage)) != `{"n":5}` {
+		t.Errorf("data = %s", events[0].Data)
+	}
+	missed, _ := pp.MissedEvents(ctx, session.ID, 0)
+	if len(missed) != 1 || missed[0].Type != "message" || missed[0].Seq != 1 {
+		t.Errorf("session events = %+v, want the message from the replay store", missed)
+	}
+}
@@ -1,6 +1,7 @@
 package pocketping
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -19,6 +20,10 @@ const DefaultAISystemPrompt = "You are a helpful customer support assistant. " +
 // over a session when no operator is online.
 const DefaultAITakeoverDelay = 300
 
+// AIDisplayName is the label bridges show on AI replies, so operators can
+// tell them apart from human operator messages.
+const AIDisplayName = "AI"
+
 // AIProvider is the interface for AI providers used for the offline-takeover
 // fallback. Implementations generate a reply from the conversation history.
 type AIProvider interface {
@@ -33,6 +38,17 @@ type AIProvider interface {
 	IsAvailable(ctx context.Context) bool
 }
 
+// StreamingAIProvider is an AIProvider that can stream its reply. When the
+// configured provider implements it, the AI fallback sends each delta to the
+// widget as a "message_chunk" event before storing the full message.
+type StreamingAIProvider interface {
+	AIProvider
+
+	// StreamResponse generates a reply like GenerateResponse, calling onChunk
+	// with each content delta as it arrives, and returns the full reply.
+	StreamResponse(ctx context.Context, messages []Message, systemPrompt string, onChunk func(delta string)) (string, error)
+}
+
 // roleForSender maps a message sender to the "user"/"assistant" role used by
 // chat-completion style APIs.
 func roleForSender(sender Sender) string {
@@ -93,9 +109,8 @@ func (p *OpenAIProvider) baseURL() string {
 	return "https://api.openai.com/v1"
 }
 
-// GenerateResponse calls POST {baseURL}/chat/completions and returns
-// choices[0].message.content.
-func (p *OpenAIProvider) GenerateResponse(ctx context.Context, messages []Message, systemPrompt string) (string, error) {
+// chatMessages builds the chat-completions message list.
+func (p *OpenAIProvider) chatMessages(messages []Message, systemPrompt string) []map[string]string {
 	chatMessages := make([]map[string]string, 0, len(messages)+1)
 	if systemPrompt != "" {
 		chatMessages = append(chatMessages, map[string]string{
@@ -109,33 +124,53 @@ func (p *OpenAIProvider) GenerateResponse(ctx context.Context, messages []Messag
 			"content": msg.Content,
 		})
 	}
+	return chatMessages
+}
 
-	body, err := json.Marshal(map[string]interface{}{
+// postChatCompletion sends a chat-completions request, with extra fields
+// merged into the payload, and checks the status. The caller closes the
+// response body.
+func (p *OpenAIProvider) postChatCompletion(ctx context.Context, messages []Message, systemPrompt string, extra map[string]interface{}) (*http.Response, error) {
+	payload := map[string]interface{}{
 		"model":       p.model(),
-		"messages":    chatMessages,
+		"messages":    p.chatMessages(messages, systemPrompt),
 		"max_tokens":  1000,
 		"temperature": 0.7,
-	})
+	}
+	for key, value := range extra {
+		payload[key] = value
+	}
+	body, err := json.Marshal(payload)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL()+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.APIKey)
 
 	resp, err := httpClientOr(p.HTTPClient).Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("openai: unexpected status %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, fmt.Errorf("openai: unexpected status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// GenerateResponse calls POST {baseURL}/chat/completions and returns
+// choices[0].message.content.
+func (p *OpenAIProvider) GenerateResponse(ctx context.Context, messages []Message, systemPrompt string) (string, error) {
+	resp, err := p.postChatCompletion(ctx, messages, systemPrompt, nil)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
 	var parsed struct {
 		Choices []struct {
@@ -169,7 +204,111 @@ func (p *OpenAIProvider) IsAvailable(ctx context.Context) bool {
 	return resp.StatusCode >= 200 && resp.StatusCode < 300
 }
 
-var _ AIProvider = (*OpenAIProvider)(nil)
+// StreamResponse calls POST {baseURL}/chat/completions with "stream": true
+// and reads the server-sent choices[0].delta.content chunks.
+func (p *OpenAIProvider) StreamResponse(ctx context.Context, messages []Message, systemPrompt string, onChunk func(delta string)) (string, error) {
+	resp, err := p.postChatCompletion(ctx, messages, systemPrompt, map[string]interface{}{"stream": true})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("openai: invalid stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		full.WriteString(delta)
+		onChunk(delta)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return full.String(), nil
+}
+
+// GenerateWithActions offers the actions to the model as function tools and
+// returns the reply text with the tool calls it made.
+func (p *OpenAIProvider) GenerateWithActions(ctx context.Context, messages []Message, systemPrompt string, actions []AIAction) (*AIResult, error) {
+	tools := make([]map[string]interface{}, 0, len(actions))
+	for _, action := range actions {
+		tools = append(tools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        action.Name,
+				"description": action.Description,
+				"parameters":  action.JSONSchema(),
+			},
+		})
+	}
+
+	resp, err := p.postChatCompletion(ctx, messages, systemPrompt, map[string]interface{}{"tools": tools})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	result := &AIResult{}
+	if len(parsed.Choices) == 0 {
+		return result, nil
+	}
+	message := parsed.Choices[0].Message
+	result.Reply = message.Content
+	for _, call := range message.ToolCalls {
+		var args map[string]interface{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("openai: invalid arguments for %s: %w", call.Function.Name, err)
+			}
+		}
+		result.Actions = append(result.Actions, AIActionCall{Name: call.Function.Name, Arguments: args})
+	}
+	return result, nil
+}
+
+var (
+	_ StreamingAIProvider = (*OpenAIProvider)(nil)
+	_ ActionAIProvider    = (*OpenAIProvider)(nil)
+)
 
 // ─────────────────────────────────────────────────────────────────
 // AnthropicProvider
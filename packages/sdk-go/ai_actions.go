@@ -0,0 +1,281 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AIAction is a structured action the AI may take during the AI fallback
+// (create a ticket, schedule a callback, tag the session...). Actions are
+// offered to providers implementing ActionAIProvider; calls are validated
+// against Params before Handler runs.
+type AIAction struct {
+	// Name identifies the action, e.g. "create_ticket".
+	Name string
+	// Description tells the model when to use the action.
+	Description string
+	// Params are the accepted arguments. Unknown arguments are rejected.
+	Params []AIActionParam
+	// Handler performs the action and returns a short result for the audit
+	// record and the bridge notice (e.g. a ticket number).
+	Handler AIActionHandler
+}
+
+// AIActionParam describes an argument of an AIAction.
+type AIActionParam struct {
+	Name string
+	// Type is "string", "number", "integer" or "boolean".
+	Type        string
+	Description string
+	Required    bool
+	// Enum restricts a string argument to the listed values.
+	Enum []string
+}
+
+// AIActionHandler executes a validated AI action for a session.
+type AIActionHandler func(ctx context.Context, session *Session, args map[string]interface{}) (string, error)
+
+// AIActionCall is an action requested by the AI provider.
+type AIActionCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// AIResult is a reply with the actions the provider requested.
+type AIResult struct {
+	Reply   string
+	Actions []AIActionCall
+	// QuickReplies are suggestion chips attached to the reply.
+	QuickReplies []QuickReply
+}
+
+// ActionAIProvider is an AIProvider that can request structured actions
+// (function calling). When Config.AIActions is set and the provider
+// implements it, the AI fallback uses GenerateWithActions instead of
+// GenerateResponse.
+type ActionAIProvider interface {
+	AIProvider
+
+	// GenerateWithActions generates a reply and may request any of actions.
+	GenerateWithActions(ctx context.Context, messages []Message, systemPrompt string, actions []AIAction) (*AIResult, error)
+}
+
+// AIActionStatus is the outcome of an AI action call.
+type AIActionStatus string
+
+const (
+	// AIActionExecuted: the handler ran successfully.
+	AIActionExecuted AIActionStatus = "executed"
+	// AIActionRejected: the call named an unknown action or had invalid arguments.
+	AIActionRejected AIActionStatus = "rejected"
+	// AIActionFailed: the handler returned an error.
+	AIActionFailed AIActionStatus = "failed"
+)
+
+// AIActionRecord is the audit record of an AI action call, passed to
+// Config.OnAIAction.
+type AIActionRecord struct {
+	SessionID string                 `json:"sessionId"`
+	Action    string                 `json:"action"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Status    AIActionStatus         `json:"status"`
+	Result    string                 `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	At        time.Time              `json:"at"`
+}
+
+// AIActionAuditor receives the audit record of every AI action call.
+type AIActionAuditor func(ctx context.Context, record AIActionRecord)
+
+// JSONSchema returns the action's parameters as a JSON Schema object, the
+// format function-calling APIs expect.
+func (a AIAction) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(a.Params))
+	required := []string{}
+	for _, param := range a.Params {
+		property := map[string]interface{}{"type": param.Type}
+		if param.Description != "" {
+			property["description"] = param.Description
+		}
+		if len(param.Enum) > 0 {
+			property["enum"] = param.Enum
+		}
+		properties[param.Name] = property
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// Validate checks call arguments against the action's params.
+func (a AIAction) Validate(args map[string]interface{}) error {
+	params := make(map[string]AIActionParam, len(a.Params))
+	for _, param := range a.Params {
+		params[param.Name] = param
+		if _, ok := args[param.Name]; param.Required && !ok {
+			return fmt.Errorf("missing required argument %q", param.Name)
+		}
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		param, ok := params[name]
+		if !ok {
+			return fmt.Errorf("unknown argument %q", name)
+		}
+		if err := param.check(args[name]); err != nil {
+			return fmt.Errorf("argument %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p AIActionParam) check(value interface{}) error {
+	switch p.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("want string, got %T", value)
+		}
+		if len(p.Enum) > 0 {
+			for _, allowed := range p.Enum {
+				if s == allowed {
+					return nil
+				}
+			}
+			return fmt.Errorf("%q is not one of %s", s, strings.Join(p.Enum, ", "))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("want number, got %T", value)
+		}
+	case "integer":
+		f, ok := value.(float64)
+		if !ok || f != float64(int64(f)) {
+			return fmt.Errorf("want integer, got %v", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("want boolean, got %T", value)
+		}
+	default:
+		return fmt.Errorf("unsupported param type %q", p.Type)
+	}
+	return nil
+}
+
+// generateAIActions asks an ActionAIProvider for a reply and runs the
+// requested actions. A response with actions but no reply is followed up:
+// the model is asked again, without actions, with their outcomes in the
+// system prompt, so the visitor still gets an answer. ok is false when
+// actions are not in use.
+func (pp *PocketPing) generateAIActions(ctx context.Context, session *Session, messages []Message) (result AIResult, ok bool, err error) {
+	actor, isActor := pp.aiProvider.(ActionAIProvider)
+	if !isActor || len(pp.config.AIActions) == 0 {
+		return AIResult{}, false, nil
+	}
+
+	generated, err := actor.GenerateWithActions(ctx, messages, pp.aiSystemPrompt, pp.config.AIActions)
+	if err != nil {
+		return AIResult{}, true, err
+	}
+	records := make([]AIActionRecord, 0, len(generated.Actions))
+	for _, call := range generated.Actions {
+		records = append(records, pp.runAIAction(ctx, session, call))
+	}
+	if generated.Reply != "" || len(records) == 0 {
+		return *generated, true, nil
+	}
+
+	reply, err := actor.GenerateResponse(ctx, messages, aiActionsFollowUpPrompt(pp.aiSystemPrompt, records))
+	return AIResult{Reply: reply}, true, err
+}
+
+// aiActionsFollowUpPrompt is systemPrompt with the outcomes of the actions
+// the model just requested, for the reply that follows them.
+func aiActionsFollowUpPrompt(systemPrompt string, records []AIActionRecord) string {
+	var b strings.Builder
+	b.WriteString(systemPrompt)
+	b.WriteString("\n\nYou just requested these actions. Tell the visitor the outcome; don't request them again.\n")
+	for _, record := range records {
+		fmt.Fprintf(&b, "- %s: %s", record.Action, record.Status)
+		if detail := record.Result + record.Error; detail != "" {
+			fmt.Fprintf(&b, " (%s)", detail)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// runAIAction validates and executes one action call, then audits it and
+// tells the bridges.
+func (pp *PocketPing) runAIAction(ctx context.Context, session *Session, call AIActionCall) AIActionRecord {
+	record := AIActionRecord{
+		SessionID: session.ID,
+		Action:    call.Name,
+		Arguments: call.Arguments,
+		At:        time.Now(),
+	}
+
+	if action, found := pp.aiAction(call.Name); !found {
+		record.Status = AIActionRejected
+		record.Error = "unknown action"
+	} else if err := action.Validate(call.Arguments); err != nil {
+		record.Status = AIActionRejected
+		record.Error = err.Error()
+	} else if result, err := action.Handler(ctx, session, call.Arguments); err != nil {
+		record.Status = AIActionFailed
+		record.Error = err.Error()
+	} else {
+		record.Status = AIActionExecuted
+		record.Result = result
+	}
+
+	if record.Error != "" {
+		log.Printf("[PocketPing] AI action %s for session %s %s: %s", record.Action, record.SessionID, record.Status, record.Error)
+	} else {
+		log.Printf("[PocketPing] AI action %s for session %s executed", record.Action, record.SessionID)
+	}
+	if pp.config.OnAIAction != nil {
+		pp.config.OnAIAction(ctx, record)
+	}
+	pp.notifyBridgesNotice(ctx, session, aiActionCaption(record))
+	return record
+}
+
+func (pp *PocketPing) aiAction(name string) (AIAction, bool) {
+	for _, action := range pp.config.AIActions {
+		if action.Name == name && action.Handler != nil {
+			return action, true
+		}
+	}
+	return AIAction{}, false
+}
+
+// aiActionCaption is the one-line bridge notice for an action call.
+func aiActionCaption(record AIActionRecord) string {
+	switch record.Status {
+	case AIActionExecuted:
+		if record.Result != "" {
+			return fmt.Sprintf("⚡ %s ran %s: %s", AIDisplayName, record.Action, record.Result)
+		}
+		return fmt.Sprintf("⚡ %s ran %s", AIDisplayName, record.Action)
+	case AIActionFailed:
+		return fmt.Sprintf("⚠️ %s action %s failed: %s", AIDisplayName, record.Action, record.Error)
+	default:
+		return fmt.Sprintf("⛔ %s action %s rejected: %s", AIDisplayName, record.Action, record.Error)
+	}
+}
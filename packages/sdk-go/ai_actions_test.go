@@ -0,0 +1,217 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeActionProvider returns a fixed AIResult.
+type fakeActionProvider struct {
+	fakeAIProvider
+	result         AIResult
+	offered        []AIAction
+	followUpPrompt string
+}
+
+func (f *fakeActionProvider) GenerateWithActions(ctx context.Context, messages []Message, systemPrompt string, actions []AIAction) (*AIResult, error) {
+	f.offered = actions
+	result := f.result
+	return &result, nil
+}
+
+// GenerateResponse records the follow-up prompt of a response without reply.
+func (f *fakeActionProvider) GenerateResponse(ctx context.Context, messages []Message, systemPrompt string) (string, error) {
+	f.followUpPrompt = systemPrompt
+	return f.fakeAIProvider.GenerateResponse(ctx, messages, systemPrompt)
+}
+
+func ticketAction(created *[]string) AIAction {
+	return AIAction{
+		Name:        "create_ticket",
+		Description: "Open a support ticket",
+		Params: []AIActionParam{
+			{Name: "subject", Type: "string", Required: true},
+			{Name: "priority", Type: "string", Enum: []string{"low", "high"}},
+		},
+		Handler: func(ctx context.Context, session *Session, args map[string]interface{}) (string, error) {
+			*created = append(*created, args["subject"].(string))
+			return "ticket #42", nil
+		},
+	}
+}
+
+func TestAIActionsValidatedExecutedAndAudited(t *testing.T) {
+	ctx := context.Background()
+	var created []string
+	var mu sync.Mutex
+	var audit []AIActionRecord
+	provider := &fakeActionProvider{result: AIResult{
+		Reply: "I opened a ticket for you.",
+		Actions: []AIActionCall{
+			{Name: "create_ticket", Arguments: map[string]interface{}{"subject": "Refund", "priority": "high"}},
+			{Name: "create_ticket", Arguments: map[string]interface{}{"priority": "urgent"}},
+			{Name: "delete_account"},
+		},
+	}}
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{
+		AIProvider:      provider,
+		AITakeoverDelay: -1,
+		Bridges:         []Bridge{bridge},
+		AIActions:       []AIAction{ticketAction(&created)},
+		OnAIAction: func(ctx context.Context, record AIActionRecord) {
+			mu.Lock()
+			defer mu.Unlock()
+			audit = append(audit, record)
+		},
+	})
+	session := newSession(ctx, t, pp)
+
+	sendVisitorMessage(t, pp, session.ID, "I want a refund")
+	pp.dispatcher.wait()
+
+	if len(provider.offered) != 1 || provider.offered[0].Name != "create_ticket" {
+		t.Errorf("offered actions = %+v", provider.offered)
+	}
+	if len(created) != 1 || created[0] != "Refund" {
+		t.Errorf("created tickets = %v, want [Refund]", created)
+	}
+
+	wantStatus := []AIActionStatus{AIActionExecuted, AIActionRejected, AIActionRejected}
+	if len(audit) != len(wantStatus) {
+		t.Fatalf("audit = %+v", audit)
+	}
+	for i, status := range wantStatus {
+		if audit[i].Status != status || audit[i].SessionID != session.ID {
+			t.Errorf("audit %d = %+v, want status %s", i, audit[i], status)
+		}
+	}
+	if audit[0].Result != "ticket #42" || audit[2].Error != "unknown action" {
+		t.Errorf("audit details = %+v", audit)
+	}
+
+	if len(bridge.notices) != 3 || bridge.notices[0] != "⚡ AI ran create_ticket: ticket #42" {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+	if ai := aiMessages(ctx, t, pp, session.ID); len(ai) != 1 || ai[0].Content != "I opened a ticket for you." {
+		t.Errorf("AI messages = %+v", ai)
+	}
+}
+
+func TestAIActionHandlerFailure(t *testing.T) {
+	ctx := context.Background()
+	var audit []AIActionRecord
+	pp := New(Config{
+		AIProvider: &fakeActionProvider{result: AIResult{Actions: []AIActionCall{{Name: "callback"}}}},
+		AIActions: []AIAction{{
+			Name: "callback",
+			Handler: func(ctx context.Context, session *Session, args map[string]interface{}) (string, error) {
+				return "", errors.New("calendar down")
+			},
+		}},
+		AITakeoverDelay: -1,
+		OnAIAction:      func(ctx context.Context, record AIActionRecord) { audit = append(audit, record) },
+	})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "call me")
+
+	if len(audit) != 1 || audit[0].Status != AIActionFailed || audit[0].Error != "calendar down" {
+		t.Errorf("audit = %+v", audit)
+	}
+	// The follow-up has no reply either, so nothing is sent
+	if got := len(aiMessages(ctx, t, pp, session.ID)); got != 0 {
+		t.Errorf("AI message count = %d, want 0 for an empty reply", got)
+	}
+}
+
+func TestAIActionsWithoutReplyFollowUp(t *testing.T) {
+	ctx := context.Background()
+	var created []string
+	provider := &fakeActionProvider{
+		fakeAIProvider: fakeAIProvider{reply: "Your ticket is #42."},
+		result: AIResult{Actions: []AIActionCall{
+			{Name: "create_ticket", Arguments: map[string]interface{}{"subject": "Refund"}},
+		}},
+	}
+	pp := New(Config{
+		AIProvider:      provider,
+		AITakeoverDelay: -1,
+		AIActions:       []AIAction{ticketAction(&created)},
+	})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "I want a refund")
+
+	// The model is asked again with the outcome, and its answer is sent
+	if len(created) != 1 || provider.callCount() != 1 {
+		t.Fatalf("created = %v, follow-ups = %d", created, provider.callCount())
+	}
+	if !strings.Contains(provider.followUpPrompt, "- create_ticket: executed (ticket #42)") {
+		t.Errorf("follow-up prompt = %q", provider.followUpPrompt)
+	}
+	if ai := aiMessages(ctx, t, pp, session.ID); len(ai) != 1 || ai[0].Content != "Your ticket is #42." {
+		t.Errorf("AI messages = %+v", ai)
+	}
+}
+
+func TestAIActionValidate(t *testing.T) {
+	action := AIAction{Params: []AIActionParam{
+		{Name: "count", Type: "integer", Required: true},
+		{Name: "ratio", Type: "number"},
+		{Name: "urgent", Type: "boolean"},
+	}}
+	cases := []struct {
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{map[string]interface{}{"count": 2.0, "ratio": 0.5, "urgent": true}, ""},
+		{map[string]interface{}{}, `missing required argument "count"`},
+		{map[string]interface{}{"count": 2.5}, "want integer"},
+		{map[string]interface{}{"count": 1.0, "urgent": "yes"}, "want boolean"},
+		{map[string]interface{}{"count": 1.0, "extra": 1.0}, `unknown argument "extra"`},
+	}
+	for _, tc := range cases {
+		err := action.Validate(tc.args)
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("Validate(%v) = %v, want nil", tc.args, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("Validate(%v) = %v, want %q", tc.args, err, tc.wantErr)
+		}
+	}
+}
+
+func TestOpenAIProviderGenerateWithActions(t *testing.T) {
+	var gotTools []interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotTools, _ = body["tools"].([]interface{})
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"Done.","tool_calls":[` +
+			`{"type":"function","function":{"name":"create_ticket","arguments":"{\"subject\":\"Refund\"}"}}]}}]}`))
+	}))
+	defer srv.Close()
+
+	var created []string
+	p := &OpenAIProvider{APIKey: "sk-test", BaseURL: srv.URL}
+	result, err := p.GenerateWithActions(context.Background(), []Message{{Sender: SenderVisitor, Content: "refund"}}, "", []AIAction{ticketAction(&created)})
+	if err != nil {
+		t.Fatalf("GenerateWithActions: %v", err)
+	}
+	if result.Reply != "Done." || len(result.Actions) != 1 || result.Actions[0].Arguments["subject"] != "Refund" {
+		t.Errorf("result = %+v", result)
+	}
+	if len(gotTools) != 1 {
+		t.Fatalf("tools = %v", gotTools)
+	}
+	fn := gotTools[0].(map[string]interface{})["function"].(map[string]interface{})
+	params := fn["parameters"].(map[string]interface{})
+	if fn["name"] != "create_ticket" || params["required"].([]interface{})[0] != "subject" {
+		t.Errorf("tool = %v", fn)
+	}
+}
@@ -610,3 +610,271 @@ func TestFallbackEmptyReplyNoMessage(t *testing.T) {
 		t.Errorf("AI message count = %d, want 0 for empty reply", got)
 	}
 }
+
+// ─────────────────────────────────────────────────────────────────
+// AI replies in bridges
+// ─────────────────────────────────────────────────────────────────
+
+// aiRecordingBridge records which hook received AI replies.
+type aiRecordingBridge struct {
+	BaseBridge
+	mu       sync.Mutex
+	ai       []string
+	operator []string // "sourceBridge/operatorName: content"
+}
+
+func (b *aiRecordingBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ai = append(b.ai, message.Content)
+	return nil
+}
+
+func (b *aiRecordingBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.operator = append(b.operator, sourceBridge+"/"+operatorName+": "+message.Content)
+	return nil
+}
+
+// operatorOnlyBridge records operator messages and has no OnAIMessage hook.
+type operatorOnlyBridge struct {
+	BaseBridge
+	mu       sync.Mutex
+	operator []string
+}
+
+func (b *operatorOnlyBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.operator = append(b.operator, sourceBridge+"/"+operatorName+": "+message.Content)
+	return nil
+}
+
+func triggerAIReply(ctx context.Context, t *testing.T, pp *PocketPing) {
+	t.Helper()
+	session := newSession(ctx, t, pp)
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{
+		SessionID: session.ID,
+		Content:   "anyone there?",
+		Sender:    SenderVisitor,
+	}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	pp.dispatcher.wait()
+}
+
+func TestAIReplyRoutedToOnAIMessage(t *testing.T) {
+	ctx := context.Background()
+	withHook := &aiRecordingBridge{BaseBridge: BaseBridge{BridgeName: "hook"}}
+	fallback := &operatorOnlyBridge{BaseBridge: BaseBridge{BridgeName: "fallback"}}
+	pp := New(Config{
+		AIProvider:      &fakeAIProvider{reply: "AI says hi"},
+		AITakeoverDelay: -1,
+		Bridges:         []Bridge{withHook, fallback},
+	})
+
+	triggerAIReply(ctx, t, pp)
+
+	if len(withHook.ai) != 1 || withHook.ai[0] != "AI says hi" {
+		t.Errorf("OnAIMessage calls = %v, want [AI says hi]", withHook.ai)
+	}
+	if len(withHook.operator) != 0 {
+		t.Errorf("OnOperatorMessage calls = %v, want none when OnAIMessage is implemented", withHook.operator)
+	}
+	if len(fallback.operator) != 1 || fallback.operator[0] != "ai/AI: AI says hi" {
+		t.Errorf("fallback operator calls = %v, want [ai/AI: AI says hi]", fallback.operator)
+	}
+}
+
+func TestDisableAIMirroring(t *testing.T) {
+	ctx := context.Background()
+	bridge := &aiRecordingBridge{BaseBridge: BaseBridge{BridgeName: "hook"}}
+	pp := New(Config{
+		AIProvider:         &fakeAIProvider{reply: "AI says hi"},
+		AITakeoverDelay:    -1,
+		DisableAIMirroring: true,
+		Bridges:            []Bridge{bridge},
+	})
+
+	session := newSession(ctx, t, pp)
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{
+		SessionID: session.ID,
+		Content:   "anyone there?",
+		Sender:    SenderVisitor,
+	}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	pp.dispatcher.wait()
+
+	if len(bridge.ai) != 0 || len(bridge.operator) != 0 {
+		t.Errorf("bridge got ai=%v operator=%v, want nothing", bridge.ai, bridge.operator)
+	}
+	if got := len(aiMessages(ctx, t, pp, session.ID)); got != 1 {
+		t.Errorf("AI message count = %d, want 1 (still stored)", got)
+	}
+}
+
+func TestCompositeBridgeOnAIMessage(t *testing.T) {
+	ctx := context.Background()
+	withHook := &aiRecordingBridge{BaseBridge: BaseBridge{BridgeName: "hook"}}
+	fallback := &operatorOnlyBridge{BaseBridge: BaseBridge{BridgeName: "fallback"}}
+	c := NewCompositeBridge(withHook, fallback)
+
+	if err := c.OnAIMessage(ctx, &Message{Content: "hi", Sender: SenderAI}, sampleSession()); err != nil {
+		t.Fatalf("OnAIMessage: %v", err)
+	}
+	if len(withHook.ai) != 1 {
+		t.Errorf("OnAIMessage calls = %d, want 1", len(withHook.ai))
+	}
+	if len(fallback.operator) != 1 {
+		t.Errorf("fallback operator calls = %d, want 1", len(fallback.operator))
+	}
+}
+
+func TestTelegramBridgeLabelsAIMessages(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		texts = append(texts, r.PostForm.Get("text"))
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	b := telegramBridgeTo(t, srv)
+	s := sampleSession()
+
+	_ = b.OnOperatorMessage(ctx, &Message{Content: "human", Sender: SenderOperator}, s, "slack", "Bob")
+	_ = b.OnOperatorMessage(ctx, &Message{Content: "robot", Sender: SenderAI}, s, "ai", "AI")
+	_ = b.OnAIMessage(ctx, &Message{Content: "robot2", Sender: SenderAI}, s)
+
+	if len(texts) != 3 {
+		t.Fatalf("sent %d messages, want 3: %v", len(texts), texts)
+	}
+	if !strings.HasPrefix(texts[0], "👨‍💼 Bob:") {
+		t.Errorf("operator text = %q", texts[0])
+	}
+	for _, text := range texts[1:] {
+		if !strings.HasPrefix(text, "🤖 AI:") {
+			t.Errorf("AI text = %q, want 🤖 AI label", text)
+		}
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────
+// Streaming
+// ─────────────────────────────────────────────────────────────────
+
+// fakeStreamingProvider streams chunks, then fails with err if set.
+type fakeStreamingProvider struct {
+	fakeAIProvider
+	chunks []string
+}
+
+func (f *fakeStreamingProvider) StreamResponse(ctx context.Context, messages []Message, systemPrompt string, onChunk func(delta string)) (string, error) {
+	var full strings.Builder
+	for _, chunk := range f.chunks {
+		full.WriteString(chunk)
+		onChunk(chunk)
+	}
+	if f.err != nil {
+		return "", f.err
+	}
+	return full.String(), nil
+}
+
+func TestFallbackStreamsChunksBeforeMessage(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{
+		AIProvider:      &fakeStreamingProvider{chunks: []string{"Hel", "lo ", "there"}},
+		AITakeoverDelay: -1,
+	})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	sendVisitorMessage(t, pp, session.ID, "hi")
+
+	var chunks []MessageChunk
+	var committed *Message
+	for _, e := range conn.events {
+		switch e.Type {
+		case "message_chunk":
+			chunks = append(chunks, e.Data.(MessageChunk))
+		case "message":
+			if m, ok := e.Data.(*Message); ok && m.Sender == SenderAI {
+				committed = m
+			}
+		}
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %+v, want 3", chunks)
+	}
+	if committed == nil || committed.Content != "Hello there" {
+		t.Fatalf("committed message = %+v, want full reply", committed)
+	}
+	for i, chunk := range chunks {
+		if chunk.MessageID != committed.ID || chunk.Seq != i || chunk.Aborted {
+			t.Errorf("chunk %d = %+v, want seq %d of %s", i, chunk, i, committed.ID)
+		}
+	}
+	if got := aiMessages(ctx, t, pp, session.ID); len(got) != 1 || got[0].Content != "Hello there" {
+		t.Errorf("stored AI messages = %+v", got)
+	}
+}
+
+func TestFallbackStreamFailureAbortsChunks(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeStreamingProvider{chunks: []string{"Hel"}}
+	provider.err = errors.New("stream broke")
+	pp := New(Config{AIProvider: provider, AITakeoverDelay: -1})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	sendVisitorMessage(t, pp, session.ID, "hi")
+
+	last := conn.events[len(conn.events)-1]
+	chunk, ok := last.Data.(MessageChunk)
+	if last.Type != "message_chunk" || !ok || !chunk.Aborted || chunk.Seq != 1 {
+		t.Errorf("last event = %+v, want aborted chunk", last)
+	}
+	if got := len(aiMessages(ctx, t, pp, session.ID)); got != 0 {
+		t.Errorf("AI message count = %d, want 0", got)
+	}
+}
+
+func TestOpenAIProviderStreamResponse(t *testing.T) {
+	var gotStream interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotStream = body["stream"]
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n"+
+			"data: {\"choices\":[{\"delta\":{\"content\":\"Hi \"}}]}\n\n"+
+			": keep-alive\n\n"+
+			"data: {\"choices\":[{\"delta\":{\"content\":\"there\"}}]}\n\n"+
+			"data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := &OpenAIProvider{APIKey: "sk-test", BaseURL: srv.URL}
+	var deltas []string
+	reply, err := p.StreamResponse(context.Background(), []Message{{Sender: SenderVisitor, Content: "hi"}}, "", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("StreamResponse: %v", err)
+	}
+	if reply != "Hi there" {
+		t.Errorf("reply = %q, want %q", reply, "Hi there")
+	}
+	if len(deltas) != 2 || deltas[0] != "Hi " || deltas[1] != "there" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if gotStream != true {
+		t.Errorf("stream = %v, want true", gotStream)
+	}
+}
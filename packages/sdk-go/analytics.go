@@ -0,0 +1,252 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAnalyticsBufferSize is how many events are batched before a
+	// write to the analytics sink.
+	DefaultAnalyticsBufferSize = 500
+	// DefaultAnalyticsFlushInterval is the longest an event waits for its
+	// batch.
+	DefaultAnalyticsFlushInterval = 5 * time.Second
+	// analyticsMaxPendingBatches bounds the events kept while the sink
+	// fails, in batches; the oldest are dropped beyond it.
+	analyticsMaxPendingBatches = 10
+)
+
+// AnalyticsEventType is the kind of an AnalyticsEvent.
+type AnalyticsEventType string
+
+const (
+	AnalyticsSessionStarted AnalyticsEventType = "session_started"
+	AnalyticsMessageSent    AnalyticsEventType = "message_sent"
+	AnalyticsCustomEvent    AnalyticsEventType = "custom_event"
+)
+
+// AnalyticsEvent is one row streamed to an AnalyticsSink. Its JSON field
+// names are the column names of the tables created by the sinks' schema
+// helpers. Message content isn't included, only its length.
+type AnalyticsEvent struct {
+	// ID is unique per event, for deduplicating retried batches.
+	ID        string             `json:"id"`
+	Type      AnalyticsEventType `json:"type"`
+	Time      time.Time          `json:"time"`
+	SessionID string             `json:"session_id"`
+	VisitorID string             `json:"visitor_id"`
+	Region    string             `json:"region"`
+
+	// Message fields, for AnalyticsMessageSent
+	MessageID     string `json:"message_id"`
+	Sender        Sender `json:"sender"`
+	ContentLength int    `json:"content_length"`
+	Attachments   int    `json:"attachments"`
+
+	// Custom event fields, for AnalyticsCustomEvent. EventData is the
+	// event's data as JSON.
+	EventName string `json:"event_name"`
+	EventData string `json:"event_data"`
+}
+
+// AnalyticsSink receives batches of analytics events (see
+// Config.AnalyticsSink). ClickHouseSink and BigQuerySink implement it.
+type AnalyticsSink interface {
+	// WriteEvents inserts events, in order. A failed batch is retried
+	// with the next one, so inserts should be idempotent by ID where the
+	// store allows it.
+	WriteEvents(ctx context.Context, events []AnalyticsEvent) error
+}
+
+// analyticsBuffer batches events for Config.AnalyticsSink.
+type analyticsBuffer struct {
+	mu      sync.Mutex
+	events  []AnalyticsEvent
+	dropped int
+
+	// flushMu serializes writes, so batches arrive in order.
+	flushMu sync.Mutex
+
+	// full is signalled when a batch is ready; stop and done end the flush
+	// loop started by Start.
+	full chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (pp *PocketPing) analyticsBufferSize() int {
+	if pp.config.AnalyticsBufferSize > 0 {
+		return pp.config.AnalyticsBufferSize
+	}
+	return DefaultAnalyticsBufferSize
+}
+
+// trackAnalytics queues an event for the analytics sink.
+func (pp *PocketPing) trackAnalytics(event AnalyticsEvent) {
+	buf := pp.analytics
+	if buf == nil {
+		return
+	}
+	event.ID = randomHex(12)
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	size := pp.analyticsBufferSize()
+	buf.mu.Lock()
+	buf.events = append(buf.events, event)
+	if max := size * analyticsMaxPendingBatches; len(buf.events) > max {
+		buf.dropped += len(buf.events) - max
+		buf.events = buf.events[len(buf.events)-max:]
+	}
+	ready := len(buf.events) >= size
+	buf.mu.Unlock()
+
+	if ready {
+		select {
+		case buf.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (pp *PocketPing) trackSessionStarted(session *Session) {
+	pp.trackAnalytics(AnalyticsEvent{
+		Type:      AnalyticsSessionStarted,
+		Time:      session.CreatedAt,
+		SessionID: session.ID,
+		VisitorID: session.VisitorID,
+		Region:    session.Region,
+	})
+}
+
+func (pp *PocketPing) trackMessageSent(message *Message, session *Session) {
+	pp.trackAnalytics(AnalyticsEvent{
+		Type:          AnalyticsMessageSent,
+		Time:          message.Timestamp,
+		SessionID:     session.ID,
+		VisitorID:     session.VisitorID,
+		Region:        session.Region,
+		MessageID:     message.ID,
+		Sender:        message.Sender,
+		ContentLength: len([]rune(message.Content)),
+		Attachments:   len(message.Attachments),
+	})
+}
+
+func (pp *PocketPing) trackCustomEvent(event CustomEvent, session *Session) {
+	data := ""
+	if len(event.Data) > 0 {
+		if b, err := json.Marshal(event.Data); err == nil {
+			data = string(b)
+		}
+	}
+	pp.trackAnalytics(AnalyticsEvent{
+		Type:      AnalyticsCustomEvent,
+		Time:      event.Timestamp,
+		SessionID: session.ID,
+		VisitorID: session.VisitorID,
+		Region:    session.Region,
+		EventName: event.Name,
+		EventData: data,
+	})
+}
+
+// FlushAnalytics writes the buffered events to Config.AnalyticsSink now,
+// in batches of Config.AnalyticsBufferSize. Events of a failed batch stay
+// buffered for the next flush.
+func (pp *PocketPing) FlushAnalytics(ctx context.Context) error {
+	buf := pp.analytics
+	if buf == nil {
+		return nil
+	}
+	buf.flushMu.Lock()
+	defer buf.flushMu.Unlock()
+
+	size := pp.analyticsBufferSize()
+	for {
+		buf.mu.Lock()
+		if buf.dropped > 0 {
+			log.Printf("[PocketPing] Analytics sink is behind: dropped %d events", buf.dropped)
+			buf.dropped = 0
+		}
+		n := len(buf.events)
+		if n > size {
+			n = size
+		}
+		batch := append([]AnalyticsEvent(nil), buf.events[:n]...)
+		buf.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := pp.config.AnalyticsSink.WriteEvents(ctx, batch); err != nil {
+			return err
+		}
+
+		// Events may have been dropped from the front meanwhile: remove the
+		// batch by ID.
+		buf.mu.Lock()
+		written := make(map[string]struct{}, len(batch))
+		for _, event := range batch {
+			written[event.ID] = struct{}{}
+		}
+		kept := buf.events[:0]
+		for _, event := range buf.events {
+			if _, ok := written[event.ID]; !ok {
+				kept = append(kept, event)
+			}
+		}
+		buf.events = kept
+		buf.mu.Unlock()
+	}
+}
+
+// startAnalytics flushes the analytics buffer each
+// Config.AnalyticsFlushInterval, or as soon as a batch is full, until Stop.
+func (pp *PocketPing) startAnalytics() {
+	buf := pp.analytics
+	if buf == nil || buf.stop != nil {
+		return
+	}
+	interval := pp.config.AnalyticsFlushInterval
+	if interval <= 0 {
+		interval = DefaultAnalyticsFlushInterval
+	}
+	buf.stop = make(chan struct{})
+	buf.done = make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			case <-buf.full:
+			}
+			if err := pp.FlushAnalytics(context.Background()); err != nil {
+				log.Printf("[PocketPing] Analytics sink write failed: %v", err)
+			}
+		}
+	}(buf.stop, buf.done)
+}
+
+// stopAnalytics ends the flush loop and writes the remaining events.
+func (pp *PocketPing) stopAnalytics(ctx context.Context) {
+	buf := pp.analytics
+	if buf == nil || buf.stop == nil {
+		return
+	}
+	close(buf.stop)
+	<-buf.done
+	buf.stop, buf.done = nil, nil
+	if err := pp.FlushAnalytics(ctx); err != nil {
+		log.Printf("[PocketPing] Analytics sink write failed: %v", err)
+	}
+}
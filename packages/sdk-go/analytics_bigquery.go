@@ -0,0 +1,176 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BigQuerySink implements AnalyticsSink with the BigQuery streaming insert
+// API (tabledata.insertAll). Event IDs are sent as insert IDs, so BigQuery
+// drops retried rows on a best-effort basis.
+type BigQuerySink struct {
+	// ProjectID and DatasetID locate the table (required).
+	ProjectID string
+	DatasetID string
+	// TableID is the table name (default "pocketping_events").
+	TableID string
+	// AccessToken returns an OAuth 2.0 access token with the BigQuery scope
+	// (required), e.g. from golang.org/x/oauth2/google.
+	AccessToken func(ctx context.Context) (string, error)
+	// BaseURL is the API base URL (default "https://bigquery.googleapis.com/bigquery/v2").
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// BigQueryField is a column of a BigQuery table schema.
+type BigQueryField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode,omitempty"`
+}
+
+func (b *BigQuerySink) baseURL() string {
+	if b.BaseURL != "" {
+		return strings.TrimRight(b.BaseURL, "/")
+	}
+	return "https://bigquery.googleapis.com/bigquery/v2"
+}
+
+func (b *BigQuerySink) tableID() string {
+	if b.TableID != "" {
+		return b.TableID
+	}
+	return "pocketping_events"
+}
+
+func (b *BigQuerySink) datasetURL() string {
+	return b.baseURL() + "/projects/" + url.PathEscape(b.ProjectID) + "/datasets/" + url.PathEscape(b.DatasetID)
+}
+
+// Schema returns the columns of the events table.
+func (b *BigQuerySink) Schema() []BigQueryField {
+	return []BigQueryField{
+		{Name: "id", Type: "STRING", Mode: "REQUIRED"},
+		{Name: "type", Type: "STRING", Mode: "REQUIRED"},
+		{Name: "time", Type: "TIMESTAMP", Mode: "REQUIRED"},
+		{Name: "session_id", Type: "STRING"},
+		{Name: "visitor_id", Type: "STRING"},
+		{Name: "region", Type: "STRING"},
+		{Name: "message_id", Type: "STRING"},
+		{Name: "sender", Type: "STRING"},
+		{Name: "content_length", Type: "INTEGER"},
+		{Name: "attachments", Type: "INTEGER"},
+		{Name: "event_name", Type: "STRING"},
+		{Name: "event_data", Type: "STRING"},
+	}
+}
+
+// CreateTable creates the events table, partitioned by day on time and
+// clustered by type and session. An existing table is left as is.
+func (b *BigQuerySink) CreateTable(ctx context.Context) error {
+	table := map[string]interface{}{
+		"tableReference": map[string]string{
+			"projectId": b.ProjectID,
+			"datasetId": b.DatasetID,
+			"tableId":   b.tableID(),
+		},
+		"schema":           map[string]interface{}{"fields": b.Schema()},
+		"timePartitioning": map[string]string{"type": "DAY", "field": "time"},
+		"clustering":       map[string][]string{"fields": {"type", "session_id"}},
+	}
+	status, body, err := b.post(ctx, b.datasetURL()+"/tables", table)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		return nil
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("bigquery: create table: unexpected status %d: %s", status, body)
+	}
+	return nil
+}
+
+// WriteEvents streams events into the table.
+func (b *BigQuerySink) WriteEvents(ctx context.Context, events []AnalyticsEvent) error {
+	type row struct {
+		InsertID string          `json:"insertId"`
+		JSON     json.RawMessage `json:"json"`
+	}
+	rows := make([]row, 0, len(events))
+	for i := range events {
+		data, err := json.Marshal(&events[i])
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row{InsertID: events[i].ID, JSON: data})
+	}
+
+	status, body, err := b.post(ctx, b.datasetURL()+"/tables/"+url.PathEscape(b.tableID())+"/insertAll", map[string]interface{}{"rows": rows})
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("bigquery: insertAll: unexpected status %d: %s", status, body)
+	}
+	var result struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("bigquery: insertAll: %w", err)
+	}
+	if len(result.InsertErrors) > 0 {
+		first := result.InsertErrors[0]
+		reason := ""
+		if len(first.Errors) > 0 {
+			reason = first.Errors[0].Reason + ": " + first.Errors[0].Message
+		}
+		return fmt.Errorf("bigquery: insertAll: %d rows rejected (row %d: %s)", len(result.InsertErrors), first.Index, reason)
+	}
+	return nil
+}
+
+// post sends payload as JSON and returns the status and (truncated) body.
+func (b *BigQuerySink) post(ctx context.Context, endpoint string, payload interface{}) (int, []byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	token, err := b.AccessToken(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("bigquery: access token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httpClientOr(b.HTTPClient).Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// Ensure BigQuerySink implements AnalyticsSink interface
+var _ AnalyticsSink = (*BigQuerySink)(nil)
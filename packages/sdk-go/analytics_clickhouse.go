@@ -0,0 +1,114 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ClickHouseSink implements AnalyticsSink with the ClickHouse HTTP
+// interface: each batch is one INSERT ... FORMAT JSONEachRow.
+type ClickHouseSink struct {
+	// URL is the HTTP interface URL (required, e.g. "https://ch.example.com:8443").
+	URL string
+	// Database is the database (default "default").
+	Database string
+	// Table is the table name (default "pocketping_events").
+	Table string
+	// Username and Password are the credentials (optional).
+	Username string
+	Password string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+func (c *ClickHouseSink) table() string {
+	table := c.Table
+	if table == "" {
+		table = "pocketping_events"
+	}
+	database := c.Database
+	if database == "" {
+		database = "default"
+	}
+	return "`" + database + "`.`" + table + "`"
+}
+
+// Schema returns the CREATE TABLE statement of the events table: a
+// ReplacingMergeTree on the event ID, so retried batches collapse, ordered
+// by session and partitioned by month.
+func (c *ClickHouseSink) Schema() string {
+	return "CREATE TABLE IF NOT EXISTS " + c.table() + ` (
+    id String,
+    type LowCardinality(String),
+    time DateTime64(3, 'UTC'),
+    session_id String,
+    visitor_id String,
+    region LowCardinality(String),
+    message_id String,
+    sender LowCardinality(String),
+    content_length UInt32,
+    attachments UInt16,
+    event_name LowCardinality(String),
+    event_data String
+)
+ENGINE = ReplacingMergeTree
+PARTITION BY toYYYYMM(time)
+ORDER BY (session_id, time, id)`
+}
+
+// CreateTable creates the events table if it doesn't exist.
+func (c *ClickHouseSink) CreateTable(ctx context.Context) error {
+	return c.exec(ctx, c.Schema(), nil)
+}
+
+// WriteEvents inserts events.
+func (c *ClickHouseSink) WriteEvents(ctx context.Context, events []AnalyticsEvent) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for i := range events {
+		if err := enc.Encode(&events[i]); err != nil {
+			return err
+		}
+	}
+	return c.exec(ctx, "INSERT INTO "+c.table()+" FORMAT JSONEachRow", &body)
+}
+
+// exec runs query, with body as its input data.
+func (c *ClickHouseSink) exec(ctx context.Context, query string, body io.Reader) error {
+	params := url.Values{
+		"query": {query},
+		// Times are sent in RFC 3339
+		"date_time_input_format": {"best_effort"},
+	}
+	if body == nil {
+		body = strings.NewReader("")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.URL, "/")+"/?"+params.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" {
+		req.Header.Set("X-ClickHouse-User", c.Username)
+		req.Header.Set("X-ClickHouse-Key", c.Password)
+	}
+
+	resp, err := httpClientOr(c.HTTPClient).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("clickhouse: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Ensure ClickHouseSink implements AnalyticsSink interface
+var _ AnalyticsSink = (*ClickHouseSink)(nil)
@@ -0,0 +1,238 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink is an AnalyticsSink keeping its batches; fail fails writes
+// while set.
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]AnalyticsEvent
+	fail    bool
+}
+
+func (s *recordingSink) WriteEvents(ctx context.Context, events []AnalyticsEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("sink down")
+	}
+	s.batches = append(s.batches, append([]AnalyticsEvent(nil), events...))
+	return nil
+}
+
+func (s *recordingSink) events() []AnalyticsEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var all []AnalyticsEvent
+	for _, batch := range s.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func TestAnalyticsEvents(t *testing.T) {
+	ctx := context.Background()
+	sink := &recordingSink{}
+	pp := New(Config{AnalyticsSink: sink, AnalyticsBufferSize: 2})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "Héllo", Sender: SenderVisitor}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	if err := pp.TriggerEvent(ctx, sessionID, "clicked_pricing", map[string]interface{}{"plan": "pro"}); err != nil {
+		t.Fatalf("TriggerEvent: %v", err)
+	}
+
+	if err := pp.FlushAnalytics(ctx); err != nil {
+		t.Fatalf("FlushAnalytics: %v", err)
+	}
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 2 {
+		t.Fatalf("batches = %+v", sink.batches)
+	}
+	events := sink.events()
+	if events[0].Type != AnalyticsSessionStarted || events[0].VisitorID != "v1" {
+		t.Errorf("session event = %+v", events[0])
+	}
+	if events[1].Type != AnalyticsMessageSent || events[1].Sender != SenderVisitor || events[1].ContentLength != 5 {
+		t.Errorf("message event = %+v", events[1])
+	}
+	if events[2].Type != AnalyticsCustomEvent || events[2].EventName != "clicked_pricing" || events[2].EventData != `{"plan":"pro"}` {
+		t.Errorf("custom event = %+v", events[2])
+	}
+	if events[0].ID == "" || events[0].ID == events[1].ID {
+		t.Errorf("event IDs = %q, %q", events[0].ID, events[1].ID)
+	}
+}
+
+func TestAnalyticsRetriesFailedBatch(t *testing.T) {
+	ctx := context.Background()
+	sink := &recordingSink{fail: true}
+	pp := New(Config{AnalyticsSink: sink})
+	connectVisitor(ctx, t, pp, "v1")
+
+	if err := pp.FlushAnalytics(ctx); err == nil {
+		t.Fatal("expected the failed write to be reported")
+	}
+	sink.fail = false
+	if err := pp.FlushAnalytics(ctx); err != nil {
+		t.Fatalf("FlushAnalytics: %v", err)
+	}
+	if events := sink.events(); len(events) != 1 || events[0].Type != AnalyticsSessionStarted {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestAnalyticsDropsOldestWhenBehind(t *testing.T) {
+	sink := &recordingSink{}
+	pp := New(Config{AnalyticsSink: sink, AnalyticsBufferSize: 1})
+	for i := 0; i < analyticsMaxPendingBatches+3; i++ {
+		pp.trackAnalytics(AnalyticsEvent{Type: AnalyticsCustomEvent, EventName: string(rune('a' + i))})
+	}
+	if err := pp.FlushAnalytics(context.Background()); err != nil {
+		t.Fatalf("FlushAnalytics: %v", err)
+	}
+	events := sink.events()
+	if len(events) != analyticsMaxPendingBatches || events[0].EventName != "d" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestAnalyticsFlushLoop(t *testing.T) {
+	ctx := context.Background()
+	sink := &recordingSink{}
+	pp := New(Config{AnalyticsSink: sink, AnalyticsBufferSize: 1, AnalyticsFlushInterval: time.Hour})
+	if err := pp.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	connectVisitor(ctx, t, pp, "v1")
+
+	// A full batch is written without waiting for the interval
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.events()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(sink.events()) != 1 {
+		t.Fatalf("events = %+v", sink.events())
+	}
+	if err := pp.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
+
+func TestClickHouseSink(t *testing.T) {
+	var queries []string
+	var rows []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "default" || r.Header.Get("X-ClickHouse-Key") != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		queries = append(queries, r.URL.Query().Get("query"))
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rows = append(rows, row)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sink := &ClickHouseSink{URL: server.URL, Database: "support", Username: "default", Password: "pw"}
+	if err := sink.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	events := []AnalyticsEvent{
+		{ID: "e1", Type: AnalyticsSessionStarted, Time: time.Now(), SessionID: "s1"},
+		{ID: "e2", Type: AnalyticsMessageSent, Time: time.Now(), SessionID: "s1", MessageID: "m1", Sender: SenderVisitor, ContentLength: 3},
+	}
+	if err := sink.WriteEvents(ctx, events); err != nil {
+		t.Fatalf("WriteEvents: %v", err)
+	}
+
+	if len(queries) != 2 || !strings.HasPrefix(queries[0], "CREATE TABLE IF NOT EXISTS `support`.`pocketping_events`") {
+		t.Fatalf("queries = %q", queries)
+	}
+	if queries[1] != "INSERT INTO `support`.`pocketping_events` FORMAT JSONEachRow" {
+		t.Errorf("insert = %q", queries[1])
+	}
+	if len(rows) != 2 || rows[1]["message_id"] != "m1" || rows[1]["content_length"] != float64(3) {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	sink.Password = "wrong"
+	if err := sink.WriteEvents(ctx, events); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBigQuerySink(t *testing.T) {
+	var inserted []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/projects/acme/datasets/support/tables":
+			w.WriteHeader(http.StatusConflict)
+		case "/projects/acme/datasets/support/tables/pocketping_events/insertAll":
+			var req struct {
+				Rows []struct {
+					InsertID string                 `json:"insertId"`
+					JSON     map[string]interface{} `json:"json"`
+				} `json:"rows"`
+			}
+			_ = json.Unmarshal(body, &req)
+			for _, row := range req.Rows {
+				if row.InsertID != row.JSON["id"] {
+					t.Errorf("insertId %q for row %v", row.InsertID, row.JSON)
+				}
+				if row.JSON["type"] == "bad" {
+					_, _ = w.Write([]byte(`{"insertErrors":[{"index":0,"errors":[{"reason":"invalid","message":"no such field"}]}]}`))
+					return
+				}
+				inserted = append(inserted, row.JSON)
+			}
+			_, _ = w.Write([]byte(`{"kind":"bigquery#tableDataInsertAllResponse"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sink := &BigQuerySink{
+		ProjectID:   "acme",
+		DatasetID:   "support",
+		BaseURL:     server.URL,
+		AccessToken: func(ctx context.Context) (string, error) { return "ya29.token", nil },
+	}
+	if err := sink.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable on an existing table: %v", err)
+	}
+	if err := sink.WriteEvents(ctx, []AnalyticsEvent{{ID: "e1", Type: AnalyticsCustomEvent, Time: time.Now(), EventName: "signup"}}); err != nil {
+		t.Fatalf("WriteEvents: %v", err)
+	}
+	if len(inserted) != 1 || inserted[0]["event_name"] != "signup" {
+		t.Errorf("inserted = %+v", inserted)
+	}
+	if err := sink.WriteEvents(ctx, []AnalyticsEvent{{ID: "e2", Type: "bad"}}); err == nil || !strings.Contains(err.Error(), "no such field") {
+		t.Errorf("err = %v", err)
+	}
+}
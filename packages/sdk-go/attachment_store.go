@@ -0,0 +1,333 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAttachmentStoreNotConfigured is returned by HandleUploadAttachment and
+// AttachmentFile when Config.AttachmentStore is nil.
+var ErrAttachmentStoreNotConfigured = newError("not_configured", http.StatusNotFound, "no attachment store configured")
+
+// AttachmentStore keeps attachment files, whose records are kept by
+// StorageWithAttachments (see Config.AttachmentStore).
+type AttachmentStore interface {
+	// Put stores the file of an attachment.
+	Put(ctx context.Context, attachmentID string, data []byte) error
+	// Get returns the file of an attachment. Returns (nil, nil) if not
+	// found.
+	Get(ctx context.Context, attachmentID string) ([]byte, error)
+	// Delete removes the file of an attachment, if any.
+	Delete(ctx context.Context, attachmentID string) error
+}
+
+// MemoryAttachmentStore keeps attachment files in memory, for tests and
+// single-instance deployments with few files.
+type MemoryAttachmentStore struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewMemoryAttachmentStore returns an empty MemoryAttachmentStore.
+func NewMemoryAttachmentStore() *MemoryAttachmentStore {
+	return &MemoryAttachmentStore{files: make(map[string][]byte)}
+}
+
+// Put stores the file of an attachment.
+func (s *MemoryAttachmentStore) Put(ctx context.Context, attachmentID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[attachmentID] = append([]byte(nil), data...)
+	return nil
+}
+
+// Get returns the file of an attachment. Returns (nil, nil) if not found.
+func (s *MemoryAttachmentStore) Get(ctx context.Context, attachmentID string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.files[attachmentID]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Delete removes the file of an attachment, if any.
+func (s *MemoryAttachmentStore) Delete(ctx context.Context, attachmentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, attachmentID)
+	return nil
+}
+
+// DirAttachmentStore keeps attachment files in a directory, one file per
+// attachment named by its ID.
+type DirAttachmentStore struct {
+	Dir string
+}
+
+// NewDirAttachmentStore returns a DirAttachmentStore writing into dir,
+// which is created if missing.
+func NewDirAttachmentStore(dir string) (*DirAttachmentStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DirAttachmentStore{Dir: dir}, nil
+}
+
+// path returns the file of an attachment ID, which must be a plain name.
+func (s *DirAttachmentStore) path(attachmentID string) (string, error) {
+	if attachmentID == "" || attachmentID != filepath.Base(attachmentID) || strings.HasPrefix(attachmentID, ".") {
+		return "", fmt.Errorf("invalid attachment ID %q", attachmentID)
+	}
+	return filepath.Join(s.Dir, attachmentID), nil
+}
+
+// Put stores the file of an attachment.
+func (s *DirAttachmentStore) Put(ctx context.Context, attachmentID string, data []byte) error {
+	path, err := s.path(attachmentID)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Get returns the file of an attachment. Returns (nil, nil) if not found.
+func (s *DirAttachmentStore) Get(ctx context.Context, attachmentID string) ([]byte, error) {
+	path, err := s.path(attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Delete removes the file of an attachment, if any.
+func (s *DirAttachmentStore) Delete(ctx context.Context, attachmentID string) error {
+	path, err := s.path(attachmentID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+var (
+	_ AttachmentStore = (*MemoryAttachmentStore)(nil)
+	_ AttachmentStore = (*DirAttachmentStore)(nil)
+)
+
+// AttachmentUploadRequest is a file the visitor uploads through the SDK.
+type AttachmentUploadRequest struct {
+	SessionID string
+	Filename  string
+	// MimeType is the type the widget declares. Empty means sniffed from
+	// the content.
+	MimeType string
+	// Body is the file. Reading stops past Config.MaxAttachmentSize.
+	Body io.Reader
+}
+
+// HandleUploadAttachment stores a visitor's file in Config.AttachmentStore
+// and records a ready attachment. Send its ID in
+// SendMessageRequest.AttachmentIDs to attach it to a message; the bridges
+// implementing BridgeWithAttachments then get the file itself.
+//
+// It checks, in order: the session (ErrSessionNotFound), the
+// FlagAttachments feature flag (ErrAttachmentsDisabled), the size
+// (ErrFileTooLarge) and the MIME type (ErrInvalidMimeType).
+func (pp *PocketPing) HandleUploadAttachment(ctx context.Context, request AttachmentUploadRequest) (*Attachment, error) {
+	files := pp.config.AttachmentStore
+	if files == nil {
+		return nil, ErrAttachmentStoreNotConfigured
+	}
+	store, err := pp.attachmentStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := pp.storage.GetSession(ctx, request.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	if enabled, ok := pp.FeatureFlags(ctx, session)[FlagAttachments]; ok && !enabled {
+		return nil, ErrAttachmentsDisabled
+	}
+
+	if request.Body == nil {
+		return nil, ErrFileTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(request.Body, pp.maxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || int64(len(data)) > pp.maxAttachmentSize {
+		return nil, ErrFileTooLarge
+	}
+
+	mimeType := request.MimeType
+	if mimeType == "" {
+		mimeType, _, _ = strings.Cut(http.DetectContentType(data), ";")
+	}
+	if !pp.isMimeTypeAllowed(mimeType) {
+		return nil, ErrInvalidMimeType
+	}
+
+	id := generateAttachmentID()
+	if err := files.Put(ctx, id, data); err != nil {
+		return nil, err
+	}
+	attachment := &Attachment{
+		ID:           id,
+		Filename:     uploadFilename(request.Filename),
+		MimeType:     mimeType,
+		Size:         int64(len(data)),
+		URL:          fmt.Sprintf("%s/%s", pp.uploadBaseURL, id),
+		Status:       AttachmentStatusReady,
+		UploadedFrom: UploadSourceWidget,
+		CreatedAt:    time.Now(),
+	}
+	if err := store.SaveAttachment(ctx, attachment); err != nil {
+		_ = files.Delete(ctx, id)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// storeOperatorFile gives an operator attachment that only has Data, such
+// as a file downloaded by the webhook handlers, a URL the widget can load:
+// with Config.AttachmentStore the file is stored and served by
+// HandleAttachmentFile, otherwise it is inlined as a base64 data URL. An
+// empty MIME type is sniffed from the content.
+func (pp *PocketPing) storeOperatorFile(ctx context.Context, attachment *Attachment) error {
+	if len(attachment.Data) == 0 || int64(len(attachment.Data)) > pp.maxAttachmentSize {
+		return ErrFileTooLarge
+	}
+	if attachment.MimeType == "" {
+		attachment.MimeType, _, _ = strings.Cut(http.DetectContentType(attachment.Data), ";")
+	}
+	attachment.Size = int64(len(attachment.Data))
+
+	files := pp.config.AttachmentStore
+	if files == nil {
+		attachment.URL = "data:" + attachment.MimeType + ";base64," + base64.StdEncoding.EncodeToString(attachment.Data)
+		return nil
+	}
+	if err := files.Put(ctx, attachment.ID, attachment.Data); err != nil {
+		return err
+	}
+	attachment.URL = fmt.Sprintf("%s/%s", pp.uploadBaseURL, attachment.ID)
+	return nil
+}
+
+// AttachmentFile returns a ready attachment and its file from
+// Config.AttachmentStore. Returns ErrAttachmentNotFound when either is
+// missing.
+func (pp *PocketPing) AttachmentFile(ctx context.Context, attachmentID string) (*Attachment, []byte, error) {
+	files := pp.config.AttachmentStore
+	if files == nil {
+		return nil, nil, ErrAttachmentStoreNotConfigured
+	}
+	store, err := pp.attachmentStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment, err := store.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment == nil || attachment.Status != AttachmentStatusReady {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	data, err := files.Get(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if data == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	return attachment, data, nil
+}
+
+// HandleAttachmentFile returns the endpoint serving the files of
+// Config.AttachmentStore: mount it at Config.UploadBaseURL, the last path
+// segment being the attachment ID. Files are sent with their recorded MIME
+// type, in a sandbox so an HTML or SVG file can't run scripts.
+//
+// The only access check is the attachment ID: the SDK generates 128 random
+// bits for the attachments it creates, so knowing the URL is knowing the
+// file. Wrap the handler to add your own checks.
+func (pp *PocketPing) HandleAttachmentFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			WriteError(w, ErrMethodNotAllowed)
+			return
+		}
+		attachment, data, err := pp.AttachmentFile(r.Context(), path.Base(r.URL.Path))
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", attachment.MimeType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		if attachment.Filename != "" {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+		}
+		http.ServeContent(w, r, attachment.Filename, attachment.CreatedAt, bytes.NewReader(data))
+	}
+}
+
+// attachmentFiles returns the attachments of a message that have a file in
+// Config.AttachmentStore, with Data loaded.
+func (pp *PocketPing) attachmentFiles(ctx context.Context, message *Message) []Attachment {
+	files := pp.config.AttachmentStore
+	if files == nil {
+		return nil
+	}
+	var out []Attachment
+	for _, attachment := range message.Attachments {
+		if attachment.Data == nil {
+			data, err := files.Get(ctx, attachment.ID)
+			if err != nil {
+				log.Printf("[PocketPing] Attachment %s not loaded: %v", attachment.ID, err)
+				continue
+			}
+			attachment.Data = data
+		}
+		if attachment.Data != nil {
+			out = append(out, attachment)
+		}
+	}
+	return out
+}
+
+// uploadFilename strips the directories some browsers send with a filename.
+func uploadFilename(filename string) string {
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+	return filename
+}
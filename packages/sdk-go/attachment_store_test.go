@@ -0,0 +1,235 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// uploadRecordingBridge records the files passed to OnVisitorAttachments.
+type uploadRecordingBridge struct {
+	BaseBridge
+	mu    sync.Mutex
+	files []Attachment
+}
+
+func (b *uploadRecordingBridge) OnVisitorAttachments(ctx context.Context, message *Message, session *Session, attachments []Attachment) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.files = append(b.files, attachments...)
+	return nil
+}
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestAttachmentStores(t *testing.T) {
+	ctx := context.Background()
+	dir, err := NewDirAttachmentStore(t.TempDir() + "/files")
+	if err != nil {
+		t.Fatalf("NewDirAttachmentStore: %v", err)
+	}
+	for name, store := range map[string]AttachmentStore{"memory": NewMemoryAttachmentStore(), "dir": dir} {
+		if data, err := store.Get(ctx, "att-1"); data != nil || err != nil {
+			t.Errorf("%s: missing file = %q, %v", name, data, err)
+		}
+		if err := store.Put(ctx, "att-1", []byte("hello")); err != nil {
+			t.Fatalf("%s: Put: %v", name, err)
+		}
+		if data, err := store.Get(ctx, "att-1"); string(data) != "hello" || err != nil {
+			t.Errorf("%s: Get = %q, %v", name, data, err)
+		}
+		if err := store.Delete(ctx, "att-1"); err != nil {
+			t.Errorf("%s: Delete: %v", name, err)
+		}
+		if data, _ := store.Get(ctx, "att-1"); data != nil {
+			t.Errorf("%s: deleted file = %q", name, data)
+		}
+	}
+	if err := dir.Put(ctx, "../escape", []byte("x")); err == nil {
+		t.Error("dir store accepted a path as attachment ID")
+	}
+}
+
+func TestHandleUploadAttachment(t *testing.T) {
+	ctx := context.Background()
+	if _, err := New(Config{}).HandleUploadAttachment(ctx, AttachmentUploadRequest{}); !errors.Is(err, ErrAttachmentStoreNotConfigured) {
+		t.Errorf("without store = %v, want ErrAttachmentStoreNotConfigured", err)
+	}
+
+	files := NewMemoryAttachmentStore()
+	pp := New(Config{AttachmentStore: files, MaxAttachmentSize: 64})
+	sessionID := newSessionFixture(t, pp)
+
+	for _, tt := range []struct {
+		request AttachmentUploadRequest
+		want    error
+	}{
+		{AttachmentUploadRequest{SessionID: "missing", Body: bytes.NewReader(pngHeader)}, ErrSessionNotFound},
+		{AttachmentUploadRequest{SessionID: sessionID, Body: bytes.NewReader(nil)}, ErrFileTooLarge},
+		{AttachmentUploadRequest{SessionID: sessionID, Body: strings.NewReader(strings.Repeat("x", 65))}, ErrFileTooLarge},
+		{AttachmentUploadRequest{SessionID: sessionID, MimeType: "application/x-msdownload", Body: strings.NewReader("MZ")}, ErrInvalidMimeType},
+		{AttachmentUploadRequest{SessionID: sessionID, Body: strings.NewReader("<html><script>")}, ErrInvalidMimeType},
+	} {
+		if _, err := pp.HandleUploadAttachment(ctx, tt.request); !errors.Is(err, tt.want) {
+			t.Errorf("HandleUploadAttachment(%q) = %v, want %v", tt.request.MimeType, err, tt.want)
+		}
+	}
+
+	attachment, err := pp.HandleUploadAttachment(ctx, AttachmentUploadRequest{SessionID: sessionID, Filename: `C:\Users\jane\shot.png`, Body: bytes.NewReader(pngHeader)})
+	if err != nil {
+		t.Fatalf("HandleUploadAttachment: %v", err)
+	}
+	if attachment.Filename != "shot.png" || attachment.MimeType != "image/png" || attachment.Size != int64(len(pngHeader)) || attachment.Status != AttachmentStatusReady {
+		t.Errorf("attachment = %+v", attachment)
+	}
+	if data, _ := files.Get(ctx, attachment.ID); !bytes.Equal(data, pngHeader) {
+		t.Errorf("stored file = %q", data)
+	}
+	stored, _ := pp.storage.(StorageWithAttachments).GetAttachment(ctx, attachment.ID)
+	if stored == nil || stored.UploadedFrom != UploadSourceWidget {
+		t.Errorf("stored attachment = %+v", stored)
+	}
+}
+
+func TestUploadedAttachmentsReachBridges(t *testing.T) {
+	ctx := context.Background()
+	uploader := &uploadRecordingBridge{BaseBridge: BaseBridge{BridgeName: "uploader"}}
+	pp := New(Config{Bridges: []Bridge{uploader}, AttachmentStore: NewMemoryAttachmentStore()})
+	sessionID := newSessionFixture(t, pp)
+
+	attachment, err := pp.HandleUploadAttachment(ctx, AttachmentUploadRequest{SessionID: sessionID, Filename: "notes.txt", MimeType: "text/plain", Body: strings.NewReader("order #42")})
+	if err != nil {
+		t.Fatalf("HandleUploadAttachment: %v", err)
+	}
+	sendVisitorMessage(t, pp, sessionID, "no files")
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "see attached", Sender: SenderVisitor, AttachmentIDs: []string{attachment.ID}}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	pp.dispatcher.wait()
+
+	uploader.mu.Lock()
+	defer uploader.mu.Unlock()
+	if len(uploader.files) != 1 || uploader.files[0].ID != attachment.ID || string(uploader.files[0].Data) != "order #42" {
+		t.Errorf("uploaded files = %+v", uploader.files)
+	}
+}
+
+func TestTelegramBridge_OnVisitorAttachments(t *testing.T) {
+	var filename, content, chatID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sendDocument" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		file, header, err := r.FormFile("document")
+		if err != nil {
+			t.Fatalf("FormFile: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		filename, content, chatID = header.Filename, string(data), r.FormValue("chat_id")
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]interface{}{"message_id": 7}})
+	}))
+	defer server.Close()
+
+	bridge, err := NewTelegramBridge("test-token", "test-chat")
+	if err != nil {
+		t.Fatalf("NewTelegramBridge: %v", err)
+	}
+	bridge.httpClient = &http.Client{Transport: &testTransport{baseURL: server.URL, token: "test-token"}}
+
+	message := createTestMessage("msg-1", "sess-1", "see attached")
+	session := createTestSession("sess-1", "visitor-1", nil, nil)
+	if err := bridge.OnVisitorAttachments(context.Background(), message, session, []Attachment{{ID: "att-1", Filename: "notes.txt", Data: []byte("order #42")}}); err != nil {
+		t.Fatalf("OnVisitorAttachments: %v", err)
+	}
+	if filename != "notes.txt" || content != "order #42" || chatID != "test-chat" {
+		t.Errorf("uploaded %q = %q to chat %q", filename, content, chatID)
+	}
+}
+
+func TestSendOperatorMessageWithBridgeFile(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{AttachmentStore: NewMemoryAttachmentStore(), UploadBaseURL: "https://chat.example.com/files"})
+	sessionID := newSessionFixture(t, pp)
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(sessionID, ws)
+
+	// As downloaded by the Telegram webhook: no URL, only the bytes
+	msg, err := pp.SendOperatorMessage(ctx, sessionID, "screenshot", "telegram", "Alice", WithAttachments(Attachment{Filename: "shot.png", Data: pngHeader}))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("message attachments = %+v", msg.Attachments)
+	}
+	att := msg.Attachments[0]
+	if len(att.ID) != 32 || att.URL != "https://chat.example.com/files/"+att.ID || att.MimeType != "image/png" || att.Size != int64(len(pngHeader)) {
+		t.Errorf("attachment = %+v", att)
+	}
+	ws.mu.Lock()
+	var broadcast *Message
+	for _, event := range ws.events {
+		if m, ok := event.Data.(*Message); ok && event.Type == "message" {
+			broadcast = m
+		}
+	}
+	ws.mu.Unlock()
+	if broadcast == nil || len(broadcast.Attachments) != 1 || broadcast.Attachments[0].URL != att.URL {
+		t.Errorf("widget broadcast = %+v", broadcast)
+	}
+
+	handler := pp.HandleAttachmentFile()
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/files/"+att.ID, nil))
+	if recorder.Code != http.StatusOK || !bytes.Equal(recorder.Body.Bytes(), pngHeader) {
+		t.Fatalf("GET = %d %q", recorder.Code, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := recorder.Header().Get("Content-Disposition"); got != `inline; filename=shot.png` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/files/missing", http.StatusNotFound},
+		{"POST", "/files/" + att.ID, http.StatusMethodNotAllowed},
+	} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+		if recorder.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, recorder.Code, tt.want)
+		}
+	}
+}
+
+func TestSendOperatorMessageInlinesBridgeFile(t *testing.T) {
+	pp := New(Config{MaxAttachmentSize: 64})
+	sessionID := newSessionFixture(t, pp)
+
+	msg, err := pp.SendOperatorMessage(context.Background(), sessionID, "", "slack", "Alice", WithAttachments(Attachment{Filename: "notes.txt", MimeType: "text/plain", Data: []byte("hi")}))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].URL != "data:text/plain;base64,aGk=" {
+		t.Errorf("message attachments = %+v", msg.Attachments)
+	}
+	if got := attachmentsText(msg.Attachments); got != "\n📎 notes.txt" {
+		t.Errorf("attachmentsText = %q", got)
+	}
+
+	_, err = pp.SendOperatorMessage(context.Background(), sessionID, "", "slack", "Alice", WithAttachments(Attachment{Filename: "big.bin", Data: make([]byte, 65)}))
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("oversized file = %v, want ErrFileTooLarge", err)
+	}
+}
@@ -3,6 +3,7 @@ package pocketping
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,12 @@ func (pp *PocketPing) attachmentStorage() (StorageWithAttachments, error) {
 	return store, nil
 }
 
+// generateAttachmentID returns a random attachment ID. Attachment URLs are
+// built from it, so unlike generateID it can't be guessed.
+func generateAttachmentID() string {
+	return randomHex(16)
+}
+
 // isMimeTypeAllowed reports whether the given MIME type is in the allow list.
 func (pp *PocketPing) isMimeTypeAllowed(mimeType string) bool {
 	_, ok := pp.allowedMimeTypes[mimeType]
@@ -46,6 +53,10 @@ func (pp *PocketPing) HandleUploadRequest(ctx context.Context, request UploadReq
 		return nil, ErrSessionNotFound
 	}
 
+	if enabled, ok := pp.FeatureFlags(ctx, session)[FlagAttachments]; ok && !enabled {
+		return nil, ErrAttachmentsDisabled
+	}
+
 	if !pp.isMimeTypeAllowed(request.MimeType) {
 		return nil, ErrInvalidMimeType
 	}
@@ -55,7 +66,7 @@ func (pp *PocketPing) HandleUploadRequest(ctx context.Context, request UploadReq
 	}
 
 	now := time.Now()
-	id := pp.generateID()
+	id := generateAttachmentID()
 	url := fmt.Sprintf("%s/%s", pp.uploadBaseURL, id)
 
 	attachment := &Attachment{
@@ -128,6 +139,103 @@ func (pp *PocketPing) HandleUploadFailed(ctx context.Context, attachmentID strin
 	return attachment, nil
 }
 
+// WithAttachments sends files with an operator message. Each attachment
+// needs a URL the widget can download it from, or Data, as with the files
+// the webhook handlers download: see storeOperatorFile. IDs are generated;
+// an empty status or upload source is filled in. With
+// StorageWithAttachments they're saved and linked to the message.
+func WithAttachments(attachments ...Attachment) OperatorMessageOption {
+	return func(o *operatorMessageOptions) {
+		o.attachments = append(o.attachments, attachments...)
+	}
+}
+
+// WithAttachmentIDs sends files uploaded beforehand with HandleUploadRequest
+// and HandleUploadComplete with an operator message.
+func WithAttachmentIDs(attachmentIDs ...string) OperatorMessageOption {
+	return func(o *operatorMessageOptions) {
+		o.attachmentIDs = append(o.attachmentIDs, attachmentIDs...)
+	}
+}
+
+// prepareOperatorAttachments returns a copy of the attachments sent with an
+// operator message, with missing fields filled in; HandleMessage gives them
+// IDs and stores their Data. The upload source is the
+// bridge the message came from, or UploadSourceAPI.
+func (pp *PocketPing) prepareOperatorAttachments(attachments []Attachment, sourceBridge string) ([]Attachment, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	source := UploadSourceAPI
+	for _, platform := range []UploadSource{UploadSourceTelegram, UploadSourceDiscord, UploadSourceSlack} {
+		if strings.HasPrefix(sourceBridge, string(platform)) {
+			source = platform
+			break
+		}
+	}
+
+	now := time.Now()
+	out := make([]Attachment, len(attachments))
+	for i, attachment := range attachments {
+		if attachment.URL == "" && attachment.Data == nil {
+			return nil, ErrAttachmentURLRequired
+		}
+		if attachment.Status == "" {
+			attachment.Status = AttachmentStatusReady
+		}
+		if attachment.UploadedFrom == "" {
+			attachment.UploadedFrom = source
+		}
+		if attachment.CreatedAt.IsZero() {
+			attachment.CreatedAt = now
+		}
+		out[i] = attachment
+	}
+	return out, nil
+}
+
+// saveInlineAttachments links the inline attachments of an operator message
+// to it and saves them when the storage supports attachments. They get new
+// IDs, so a caller can't overwrite another attachment; files that only have
+// Data are stored with storeOperatorFile.
+func (pp *PocketPing) saveInlineAttachments(ctx context.Context, messageID string, attachments []Attachment) ([]Attachment, error) {
+	out := make([]Attachment, len(attachments))
+	store, ok := pp.storage.(StorageWithAttachments)
+	for i, attachment := range attachments {
+		attachment.ID = generateAttachmentID()
+		attachment.MessageID = messageID
+		if attachment.URL == "" {
+			if attachment.Data == nil {
+				return nil, ErrAttachmentURLRequired
+			}
+			if err := pp.storeOperatorFile(ctx, &attachment); err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			if err := store.SaveAttachment(ctx, &attachment); err != nil {
+				return nil, err
+			}
+		}
+		out[i] = attachment
+	}
+	return out, nil
+}
+
+// attachmentsText lists attachments with their download URLs for bridges
+// that only post text. Inline data URLs are left out.
+func attachmentsText(attachments []Attachment) string {
+	var b strings.Builder
+	for _, attachment := range attachments {
+		fmt.Fprintf(&b, "\n📎 %s", attachment.Filename)
+		if attachment.URL != "" && !strings.HasPrefix(attachment.URL, "data:") {
+			fmt.Fprintf(&b, ": %s", attachment.URL)
+		}
+	}
+	return b.String()
+}
+
 // linkAttachments links the given attachment IDs to a message and returns the
 // collected attachments. Unknown IDs are skipped.
 func (pp *PocketPing) linkAttachments(ctx context.Context, messageID string, attachmentIDs []string) ([]Attachment, error) {
@@ -15,6 +15,7 @@ type recordingBridge struct {
 	mu          sync.Mutex
 	messages    []Message
 	attachments [][]Attachment
+	operator    []Message
 }
 
 func newRecordingBridge(name string) *recordingBridge {
@@ -31,6 +32,13 @@ func (r *recordingBridge) OnVisitorMessage(ctx context.Context, message *Message
 	return nil
 }
 
+func (r *recordingBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operator = append(r.operator, *message)
+	return nil
+}
+
 func (r *recordingBridge) lastAttachments() ([]Attachment, bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -337,6 +345,132 @@ func TestHandleUploadRequestSessionNotFound(t *testing.T) {
 	}
 }
 
+func TestSendOperatorMessageWithAttachments(t *testing.T) {
+	bridge := newRecordingBridge("mirror")
+	pp := New(Config{Bridges: []Bridge{bridge}})
+	ctx := context.Background()
+	sessionID := newSessionFixture(t, pp)
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(sessionID, ws)
+
+	msg, err := pp.SendOperatorMessage(ctx, sessionID, "Here's the invoice", "telegram", "Alice", WithAttachments(Attachment{
+		Filename: "invoice.pdf",
+		MimeType: "application/pdf",
+		Size:     2048,
+		URL:      "https://files.example.com/invoice.pdf",
+	}))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("message attachments = %+v", msg.Attachments)
+	}
+	att := msg.Attachments[0]
+	if att.ID == "" || att.MessageID != msg.ID || att.Status != AttachmentStatusReady || att.UploadedFrom != UploadSourceTelegram {
+		t.Errorf("attachment = %+v", att)
+	}
+
+	stored, err := pp.storage.(StorageWithAttachments).GetMessageAttachments(ctx, msg.ID)
+	if err != nil || len(stored) != 1 || stored[0].URL != "https://files.example.com/invoice.pdf" {
+		t.Errorf("stored attachments = %+v, %v", stored, err)
+	}
+
+	var broadcast *Message
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, event := range ws.events {
+		if m, ok := event.Data.(*Message); ok && event.Type == "message" {
+			broadcast = m
+		}
+	}
+	if broadcast == nil || len(broadcast.Attachments) != 1 || broadcast.Attachments[0].URL == "" {
+		t.Errorf("widget broadcast = %+v", broadcast)
+	}
+
+	waitFor(t, "operator message mirrored", func() bool {
+		bridge.mu.Lock()
+		defer bridge.mu.Unlock()
+		return len(bridge.operator) == 1
+	})
+	bridge.mu.Lock()
+	mirrored := bridge.operator[0]
+	bridge.mu.Unlock()
+	if len(mirrored.Attachments) != 1 || mirrored.Attachments[0].ID != att.ID {
+		t.Errorf("mirrored attachments = %+v", mirrored.Attachments)
+	}
+}
+
+func TestSendOperatorMessageWithAttachmentIDs(t *testing.T) {
+	pp := New(Config{})
+	ctx := context.Background()
+	sessionID := newSessionFixture(t, pp)
+
+	upload, err := pp.HandleUploadRequest(ctx, UploadRequest{SessionID: sessionID, Filename: "photo.png", MimeType: "image/png", Size: 1024})
+	if err != nil {
+		t.Fatalf("HandleUploadRequest: %v", err)
+	}
+	if _, err := pp.HandleUploadComplete(ctx, upload.AttachmentID); err != nil {
+		t.Fatalf("HandleUploadComplete: %v", err)
+	}
+
+	msg, err := pp.SendOperatorMessage(ctx, sessionID, "", "api", "Alice", WithAttachmentIDs(upload.AttachmentID))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].ID != upload.AttachmentID || msg.Attachments[0].URL != upload.UploadURL {
+		t.Errorf("message attachments = %+v", msg.Attachments)
+	}
+}
+
+func TestSendOperatorMessageAttachmentURLRequired(t *testing.T) {
+	pp := New(Config{})
+	sessionID := newSessionFixture(t, pp)
+
+	_, err := pp.SendOperatorMessage(context.Background(), sessionID, "file", "api", "", WithAttachments(Attachment{Filename: "x.pdf"}))
+	if !errors.Is(err, ErrAttachmentURLRequired) {
+		t.Errorf("err = %v, want ErrAttachmentURLRequired", err)
+	}
+}
+
+func TestHandleMessageInlineAttachments(t *testing.T) {
+	pp := New(Config{})
+	ctx := context.Background()
+	sessionID := newSessionFixture(t, pp)
+	store := pp.storage.(StorageWithAttachments)
+	existing := &Attachment{ID: "att-1", Filename: "contract.pdf", URL: "https://files.example.com/contract.pdf", Status: AttachmentStatusReady}
+	if err := store.SaveAttachment(ctx, existing); err != nil {
+		t.Fatalf("SaveAttachment: %v", err)
+	}
+	inline := []Attachment{{ID: "att-1", Filename: "evil.pdf", URL: "https://evil.example.com/evil.pdf"}}
+
+	visitor, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "hi", Sender: SenderVisitor, Attachments: inline})
+	if err != nil {
+		t.Fatalf("visitor HandleMessage: %v", err)
+	}
+	if got, _ := store.GetMessageAttachments(ctx, visitor.MessageID); len(got) != 0 {
+		t.Errorf("visitor inline attachments saved: %+v", got)
+	}
+
+	operator, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "file", Sender: SenderOperator, Attachments: inline})
+	if err != nil {
+		t.Fatalf("operator HandleMessage: %v", err)
+	}
+	got, _ := store.GetMessageAttachments(ctx, operator.MessageID)
+	if len(got) != 1 || got[0].ID == "att-1" {
+		t.Errorf("operator inline attachments = %+v, want a new ID", got)
+	}
+	if att, _ := store.GetAttachment(ctx, "att-1"); att.URL != existing.URL {
+		t.Errorf("existing attachment overwritten: %+v", att)
+	}
+}
+
+func TestAttachmentsText(t *testing.T) {
+	got := attachmentsText([]Attachment{{Filename: "a.pdf", URL: "https://x/a.pdf"}, {Filename: "b.png"}})
+	if want := "\n📎 a.pdf: https://x/a.pdf\n📎 b.png"; got != want {
+		t.Errorf("attachmentsText = %q, want %q", got, want)
+	}
+}
+
 // contains is a tiny substring helper to avoid importing strings in tests.
 func contains(haystack, needle string) bool {
 	if needle == "" {
@@ -64,6 +64,47 @@ type BridgeWithNotify interface {
 	Notify(ctx context.Context, session *Session, message string) error
 }
 
+// BridgeWithAttachments extends Bridge with file uploads. Bridges that
+// implement it post the files of visitor attachments on their platform,
+// rather than only their names.
+type BridgeWithAttachments interface {
+	Bridge
+
+	// OnVisitorAttachments is called after OnVisitorMessage for a message
+	// with attachments, with the files from Config.AttachmentStore in
+	// Data. Attachments without a stored file are left out.
+	OnVisitorAttachments(ctx context.Context, message *Message, session *Session, attachments []Attachment) error
+}
+
+// BridgeWithRegions extends Bridge with regional routing. A bridge declaring
+// regions only receives notifications for sessions in those regions; see
+// Session.Region. Bridges embedding BaseBridge implement it via BridgeRegions.
+type BridgeWithRegions interface {
+	Bridge
+
+	// Regions returns the regions served. Empty means all regions.
+	Regions() []string
+}
+
+// BridgeWithAIMessage extends Bridge with a dedicated hook for AI replies.
+// Bridges that don't implement it receive AI replies through
+// OnOperatorMessage with sourceBridge "ai" and message.Sender == SenderAI.
+type BridgeWithAIMessage interface {
+	Bridge
+
+	// OnAIMessage is called when the AI fallback replies to a visitor.
+	OnAIMessage(ctx context.Context, message *Message, session *Session) error
+}
+
+// deliverAIMessage sends an AI reply to b via OnAIMessage when implemented,
+// falling back to OnOperatorMessage.
+func deliverAIMessage(ctx context.Context, b Bridge, message *Message, session *Session) error {
+	if ab, ok := b.(BridgeWithAIMessage); ok {
+		return ab.OnAIMessage(ctx, message, session)
+	}
+	return b.OnOperatorMessage(ctx, message, session, "ai", AIDisplayName)
+}
+
 // BridgeMessageResult contains the result of a bridge operation.
 type BridgeMessageResult struct {
 	// TelegramMessageID is the Telegram message ID.
@@ -72,12 +113,17 @@ type BridgeMessageResult struct {
 	DiscordMessageID string `json:"discordMessageId,omitempty"`
 	// SlackMessageTS is the Slack message timestamp.
 	SlackMessageTS string `json:"slackMessageTs,omitempty"`
+	// TeamsMessageID is the Microsoft Graph chat message ID.
+	TeamsMessageID string `json:"teamsMessageId,omitempty"`
 }
 
 // BaseBridge provides a default implementation of the Bridge interface.
 // Embed this in your bridge implementation to only override methods you need.
 type BaseBridge struct {
 	BridgeName string
+	// BridgeRegions limits the bridge to sessions of these regions
+	// (Session.Region). Empty serves every region.
+	BridgeRegions []string
 }
 
 // Name returns the bridge name.
@@ -85,6 +131,11 @@ func (b *BaseBridge) Name() string {
 	return b.BridgeName
 }
 
+// Regions returns the regions the bridge serves.
+func (b *BaseBridge) Regions() []string {
+	return b.BridgeRegions
+}
+
 // Init is a no-op by default.
 func (b *BaseBridge) Init(ctx context.Context, pp *PocketPing) error {
 	return nil
@@ -133,6 +184,9 @@ func (b *BaseBridge) Destroy(ctx context.Context) error {
 // Ensure BaseBridge implements Bridge interface
 var _ Bridge = (*BaseBridge)(nil)
 
+// Ensure BaseBridge implements BridgeWithRegions interface
+var _ BridgeWithRegions = (*BaseBridge)(nil)
+
 // CompositeBridge forwards events to multiple bridges.
 type CompositeBridge struct {
 	bridges []Bridge
@@ -189,6 +243,41 @@ func (c *CompositeBridge) OnOperatorMessage(ctx context.Context, message *Messag
 	return nil
 }
 
+// OnAIMessage notifies all child bridges.
+func (c *CompositeBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	for _, bridge := range c.bridges {
+		if err := deliverAIMessage(ctx, bridge, message, session); err != nil {
+			continue
+		}
+	}
+	return nil
+}
+
+// Notify notifies the child bridges that implement BridgeWithNotify.
+func (c *CompositeBridge) Notify(ctx context.Context, session *Session, message string) error {
+	for _, bridge := range c.bridges {
+		if notifier, ok := bridge.(BridgeWithNotify); ok {
+			if err := notifier.Notify(ctx, session, message); err != nil {
+				continue
+			}
+		}
+	}
+	return nil
+}
+
+// OnVisitorAttachments passes the files to the child bridges implementing
+// BridgeWithAttachments.
+func (c *CompositeBridge) OnVisitorAttachments(ctx context.Context, message *Message, session *Session, attachments []Attachment) error {
+	for _, bridge := range c.bridges {
+		if uploader, ok := bridge.(BridgeWithAttachments); ok {
+			if err := uploader.OnVisitorAttachments(ctx, message, session, attachments); err != nil {
+				continue
+			}
+		}
+	}
+	return nil
+}
+
 // OnTyping notifies all child bridges.
 func (c *CompositeBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
 	for _, bridge := range c.bridges {
@@ -246,3 +335,12 @@ func (c *CompositeBridge) AddBridge(bridge Bridge) {
 
 // Ensure CompositeBridge implements Bridge interface
 var _ Bridge = (*CompositeBridge)(nil)
+
+// Ensure CompositeBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*CompositeBridge)(nil)
+
+// Ensure CompositeBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*CompositeBridge)(nil)
+
+// Ensure CompositeBridge implements BridgeWithAttachments interface
+var _ BridgeWithAttachments = (*CompositeBridge)(nil)
@@ -245,10 +245,10 @@ func TestTelegramBridge_OnVisitorMessage_HandlesAPIErrors(t *testing.T) {
 	session := createTestSession("sess-1", "visitor-1", nil, nil)
 	message := createTestMessage("msg-1", "sess-1", "Test message")
 
-	// Should not return error (logs but doesn't fail)
+	// Returns the error (and logs it), so Config.BridgeRetry can retry
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (error should be logged, not returned), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -453,7 +453,7 @@ func TestTelegramBridge_OnMessageEdit_ReturnsNilOnSuccess(t *testing.T) {
 	}
 }
 
-func TestTelegramBridge_OnMessageEdit_ReturnsNilOnFailure(t *testing.T) {
+func TestTelegramBridge_OnMessageEdit_ReturnsErrorOnFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -480,9 +480,9 @@ func TestTelegramBridge_OnMessageEdit_ReturnsNilOnFailure(t *testing.T) {
 
 	result, err := bridge.OnMessageEdit(context.Background(), "sess-1", "msg-1", "Updated", time.Now())
 
-	// Error is logged but returns nil
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	// The error is returned, for Config.BridgeRetry
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 	if result != nil {
 		t.Errorf("expected nil result on failure, got %v", result)
@@ -574,7 +574,7 @@ func TestTelegramBridge_OnMessageDelete_ReturnsNilOnSuccess(t *testing.T) {
 	}
 }
 
-func TestTelegramBridge_OnMessageDelete_ReturnsNilOnFailure(t *testing.T) {
+func TestTelegramBridge_OnMessageDelete_ReturnsErrorOnFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -600,15 +600,15 @@ func TestTelegramBridge_OnMessageDelete_ReturnsNilOnFailure(t *testing.T) {
 	bridge.Init(context.Background(), pp)
 
 	err = bridge.OnMessageDelete(context.Background(), "sess-1", "msg-1", time.Now())
-	// Error is logged but returns nil
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	// The error is returned, for Config.BridgeRetry
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
 // --- Error Handling Tests ---
 
-func TestTelegramBridge_LogsErrorButDoesNotPanicOnAPIFailure(t *testing.T) {
+func TestTelegramBridge_ReturnsErrorOnAPIFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Internal Server Error"))
@@ -635,8 +635,8 @@ func TestTelegramBridge_LogsErrorButDoesNotPanicOnAPIFailure(t *testing.T) {
 	}()
 
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -658,10 +658,10 @@ func TestTelegramBridge_HandlesNetworkErrors(t *testing.T) {
 	session := createTestSession("sess-1", "visitor-1", nil, nil)
 	message := createTestMessage("msg-1", "sess-1", "Test")
 
-	// Should not return error (logged)
+	// Returns the error (and logs it), so Config.BridgeRetry can retry
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error for network errors, got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -686,8 +686,8 @@ func TestTelegramBridge_HandlesInvalidResponses(t *testing.T) {
 
 	// Should not panic, error is logged
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error for invalid JSON, got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -818,10 +818,10 @@ func TestDiscordWebhookBridge_OnVisitorMessage_HandlesAPIErrors(t *testing.T) {
 	session := createTestSession("sess-1", "visitor-1", nil, nil)
 	message := createTestMessage("msg-1", "sess-1", "Test message")
 
-	// Should not return error (logs but doesn't fail)
+	// Returns the error (and logs it), so Config.BridgeRetry can retry
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1060,7 +1060,7 @@ func TestDiscordBotBridge_OnMessageEdit_CallsEditAPI(t *testing.T) {
 	}
 }
 
-func TestDiscordBotBridge_OnMessageEdit_ReturnsNilOnFailure(t *testing.T) {
+func TestDiscordBotBridge_OnMessageEdit_ReturnsErrorOnFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(`{"message": "Missing Permissions"}`))
@@ -1081,8 +1081,8 @@ func TestDiscordBotBridge_OnMessageEdit_ReturnsNilOnFailure(t *testing.T) {
 
 	result, err := bridge.OnMessageEdit(context.Background(), "sess-1", "msg-1", "Updated", time.Now())
 
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 	if result != nil {
 		t.Errorf("expected nil result on failure, got %v", result)
@@ -1138,7 +1138,7 @@ func TestDiscordBotBridge_OnMessageDelete_CallsDeleteAPI(t *testing.T) {
 
 // --- Error Handling Tests ---
 
-func TestDiscordBotBridge_LogsErrorButDoesNotPanicOnAPIFailure(t *testing.T) {
+func TestDiscordBotBridge_ReturnsErrorOnAPIFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Internal Server Error"))
@@ -1161,8 +1161,8 @@ func TestDiscordBotBridge_LogsErrorButDoesNotPanicOnAPIFailure(t *testing.T) {
 	}()
 
 	err := bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1180,8 +1180,8 @@ func TestDiscordBotBridge_HandlesNetworkErrors(t *testing.T) {
 	message := createTestMessage("msg-1", "sess-1", "Test")
 
 	err := bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error for network errors, got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1313,10 +1313,10 @@ func TestSlackWebhookBridge_OnVisitorMessage_HandlesAPIErrors(t *testing.T) {
 	session := createTestSession("sess-1", "visitor-1", nil, nil)
 	message := createTestMessage("msg-1", "sess-1", "Test message")
 
-	// Should not return error (logs but doesn't fail)
+	// Returns the error (and logs it), so Config.BridgeRetry can retry
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1534,10 +1534,10 @@ func TestSlackBotBridge_OnVisitorMessage_HandlesAPIErrors(t *testing.T) {
 	session := createTestSession("sess-1", "visitor-1", nil, nil)
 	message := createTestMessage("msg-1", "sess-1", "Test message")
 
-	// Should not return error (logs but doesn't fail)
+	// Returns the error (and logs it), so Config.BridgeRetry can retry
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1705,7 +1705,7 @@ func TestSlackBotBridge_OnMessageEdit_CallsEditAPI(t *testing.T) {
 	}
 }
 
-func TestSlackBotBridge_OnMessageEdit_ReturnsNilOnFailure(t *testing.T) {
+func TestSlackBotBridge_OnMessageEdit_ReturnsErrorOnFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1732,8 +1732,8 @@ func TestSlackBotBridge_OnMessageEdit_ReturnsNilOnFailure(t *testing.T) {
 
 	result, err := bridge.OnMessageEdit(context.Background(), "sess-1", "msg-1", "Updated", time.Now())
 
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 	if result != nil {
 		t.Errorf("expected nil result on failure, got %v", result)
@@ -1823,7 +1823,7 @@ func TestSlackBotBridge_OnMessageDelete_ReturnsNilOnSuccess(t *testing.T) {
 	}
 }
 
-func TestSlackBotBridge_OnMessageDelete_ReturnsNilOnFailure(t *testing.T) {
+func TestSlackBotBridge_OnMessageDelete_ReturnsErrorOnFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1849,14 +1849,14 @@ func TestSlackBotBridge_OnMessageDelete_ReturnsNilOnFailure(t *testing.T) {
 	bridge.Init(context.Background(), pp)
 
 	err = bridge.OnMessageDelete(context.Background(), "sess-1", "msg-1", time.Now())
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
 // --- Error Handling Tests ---
 
-func TestSlackBotBridge_LogsErrorButDoesNotPanicOnAPIFailure(t *testing.T) {
+func TestSlackBotBridge_ReturnsErrorOnAPIFailure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Internal Server Error"))
@@ -1882,8 +1882,8 @@ func TestSlackBotBridge_LogsErrorButDoesNotPanicOnAPIFailure(t *testing.T) {
 	}()
 
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error (logged), got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1904,8 +1904,8 @@ func TestSlackBotBridge_HandlesNetworkErrors(t *testing.T) {
 	message := createTestMessage("msg-1", "sess-1", "Test")
 
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error for network errors, got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -1929,8 +1929,8 @@ func TestSlackBotBridge_HandlesInvalidResponses(t *testing.T) {
 	message := createTestMessage("msg-1", "sess-1", "Test")
 
 	err = bridge.OnVisitorMessage(ctx, message, session)
-	if err != nil {
-		t.Errorf("expected nil error for invalid JSON, got %v", err)
+	if err == nil {
+		t.Error("expected the error to be returned, for Config.BridgeRetry")
 	}
 }
 
@@ -0,0 +1,103 @@
+package pocketping
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// DefaultBroadcastChannel is the Redis channel / NATS subject used when none
+// is configured.
+const DefaultBroadcastChannel = "pocketping.broadcast"
+
+// broadcastRetryDelay is the pause before a broadcaster reconnects.
+const broadcastRetryDelay = time.Second
+
+// Broadcaster carries WebSocket events between app replicas, so
+// BroadcastToSession reaches sockets connected to any of them. See
+// RedisBroadcaster and NATSBroadcaster.
+type Broadcaster interface {
+	// Publish sends payload to every subscribed replica, this one included.
+	Publish(ctx context.Context, payload []byte) error
+
+	// Subscribe calls deliver with each published payload until Close. It
+	// returns once the subscription is set up, reconnecting in the
+	// background when the connection drops.
+	Subscribe(ctx context.Context, deliver func(payload []byte)) error
+
+	// Close stops the subscription and releases connections.
+	Close() error
+}
+
+// broadcastEnvelope is a WebSocket event on the wire between replicas.
+type broadcastEnvelope struct {
+	// Origin is the publishing replica, which has delivered it already.
+	Origin    string `json:"origin"`
+	SessionID string `json:"sessionId"`
+	// Operators is set for events only sent to operator consoles.
+	Operators bool `json:"operators,omitempty"`
+	Event     struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+		Seq  int64           `json:"seq,omitempty"`
+	} `json:"event"`
+}
+
+// newReplicaID returns a random ID for this process.
+func newReplicaID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startBroadcaster subscribes to Config.Broadcaster.
+func (pp *PocketPing) startBroadcaster(ctx context.Context) error {
+	if pp.config.Broadcaster == nil {
+		return nil
+	}
+	return pp.config.Broadcaster.Subscribe(ctx, pp.receiveBroadcast)
+}
+
+// publishBroadcast sends an event to the other replicas.
+func (pp *PocketPing) publishBroadcast(sessionID string, event WebSocketEvent, operators bool) {
+	if pp.config.Broadcaster == nil {
+		return
+	}
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Printf("[PocketPing] Broadcast of %s event failed: %v", event.Type, err)
+		return
+	}
+	envelope := broadcastEnvelope{Origin: pp.replicaID, SessionID: sessionID, Operators: operators}
+	envelope.Event.Type = event.Type
+	envelope.Event.Data = data
+	envelope.Event.Seq = event.Seq
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[PocketPing] Broadcast of %s event failed: %v", event.Type, err)
+		return
+	}
+	if err := pp.config.Broadcaster.Publish(context.Background(), payload); err != nil {
+		log.Printf("[PocketPing] Broadcast of %s event failed: %v", event.Type, err)
+	}
+}
+
+// receiveBroadcast delivers another replica's event to this one's sockets.
+func (pp *PocketPing) receiveBroadcast(payload []byte) {
+	var envelope broadcastEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		log.Printf("[PocketPing] Ignored malformed broadcast: %v", err)
+		return
+	}
+	if envelope.Origin == pp.replicaID {
+		return
+	}
+	event := WebSocketEvent{Type: envelope.Event.Type, Data: envelope.Event.Data, Seq: envelope.Event.Seq}
+	pp.broadcastToOperators(envelope.SessionID, event)
+	if !envelope.Operators {
+		pp.broadcastLocal(envelope.SessionID, event)
+	}
+}
@@ -0,0 +1,323 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsTimeout bounds connecting to NATS and each write.
+const natsTimeout = 5 * time.Second
+
+var errNATSClosed = errors.New("broadcaster is closed")
+
+// NATSBroadcaster is a Broadcaster over NATS core pub/sub. It speaks the
+// NATS protocol directly, so no client library is needed.
+type NATSBroadcaster struct {
+	// TLSConfig configures TLS connections, e.g. with a private CA or a
+	// client certificate. Defaults to the system roots, checking the URL's
+	// host name.
+	TLSConfig *tls.Config
+
+	addr    string
+	host    string
+	useTLS  bool
+	user    string
+	pass    string
+	token   string
+	subject string
+
+	// mu guards conn and deliver, and serializes writes. It isn't held
+	// while connecting.
+	mu      sync.Mutex
+	conn    net.Conn
+	deliver func(payload []byte)
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewNATSBroadcaster creates a broadcaster for the NATS server at natsURL
+// ("nats://[user:password@ or token@]host[:port]", or "tls://" for TLS), on
+// subject (DefaultBroadcastChannel when empty). Connections also switch to
+// TLS when the server requires it.
+func NewNATSBroadcaster(natsURL, subject string) (*NATSBroadcaster, error) {
+	u, err := url.Parse(natsURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid NATS URL scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if subject == "" {
+		subject = DefaultBroadcastChannel
+	}
+	b := &NATSBroadcaster{addr: addr, host: u.Hostname(), useTLS: u.Scheme == "tls", subject: subject, closed: make(chan struct{})}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			b.user, b.pass = u.User.Username(), pass
+		} else {
+			b.token = u.User.Username()
+		}
+	}
+	return b, nil
+}
+
+// Publish implements Broadcaster.
+func (b *NATSBroadcaster) Publish(ctx context.Context, payload []byte) error {
+	if err := b.connect(ctx); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return errors.New("write to NATS: connection lost")
+	}
+	return b.writeLocked(fmt.Sprintf("PUB %s %d\r\n%s\r\n", b.subject, len(payload), payload))
+}
+
+// Subscribe implements Broadcaster.
+func (b *NATSBroadcaster) Subscribe(ctx context.Context, deliver func(payload []byte)) error {
+	b.mu.Lock()
+	b.deliver = deliver
+	if b.conn != nil {
+		defer b.mu.Unlock()
+		return b.writeLocked(fmt.Sprintf("SUB %s 1\r\n", b.subject))
+	}
+	b.mu.Unlock()
+	return b.connect(ctx)
+}
+
+// Close implements Broadcaster.
+func (b *NATSBroadcaster) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+	return nil
+}
+
+// connect connects to NATS and starts reading, unless connected already.
+// Dialing and the handshake run without b.mu, so a slow server doesn't hold
+// up Close or the other callers; when two connect at once, the first
+// connection is kept and the other closed.
+func (b *NATSBroadcaster) connect(ctx context.Context) error {
+	b.mu.Lock()
+	connected, subscribed := b.conn != nil, b.deliver != nil
+	b.mu.Unlock()
+	if connected {
+		return nil
+	}
+
+	conn, r, err := b.dial(ctx, subscribed)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-b.closed:
+		conn.Close()
+		return errNATSClosed
+	default:
+	}
+	if b.conn != nil {
+		conn.Close()
+		return nil
+	}
+	b.conn = conn
+	go b.read(conn, r)
+	if b.deliver != nil && !subscribed {
+		// Subscribe was called while dialing
+		return b.writeLocked(fmt.Sprintf("SUB %s 1\r\n", b.subject))
+	}
+	return nil
+}
+
+// dial opens a connection to NATS, authenticates and, with subscribe,
+// subscribes.
+func (b *NATSBroadcaster) dial(ctx context.Context, subscribe bool) (net.Conn, *bufio.Reader, error) {
+	select {
+	case <-b.closed:
+		return nil, nil, errNATSClosed
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(natsTimeout))
+	r := bufio.NewReader(conn)
+
+	// The server opens with INFO, then the connection is upgraded to TLS
+	// if either side asks for it; PING after CONNECT confirms the login.
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("connect to NATS: unexpected greeting %q: %v", line, err)
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	_ = json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "INFO"))), &info)
+	if b.useTLS || info.TLSRequired {
+		config := &tls.Config{ServerName: b.host}
+		if b.TLSConfig != nil {
+			config = b.TLSConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = b.host
+			}
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("connect to NATS: TLS handshake: %w", err)
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+	options, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "pocketping",
+		"lang":       "go",
+		"user":       b.user,
+		"pass":       b.pass,
+		"auth_token": b.token,
+	})
+	handshake := "CONNECT " + string(options) + "\r\n"
+	if subscribe {
+		handshake += fmt.Sprintf("SUB %s 1\r\n", b.subject)
+	}
+	if _, err := io.WriteString(conn, handshake+"PING\r\n"); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("connect to NATS: %w", err)
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, nil, fmt.Errorf("connect to NATS: %s", strings.TrimSpace(line))
+		}
+		if strings.HasPrefix(line, "PONG") {
+			break
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// writeLocked writes a protocol line. Callers hold b.mu.
+func (b *NATSBroadcaster) writeLocked(data string) error {
+	_ = b.conn.SetWriteDeadline(time.Now().Add(natsTimeout))
+	if _, err := io.WriteString(b.conn, data); err != nil {
+		b.conn.Close()
+		b.conn = nil
+		return fmt.Errorf("write to NATS: %w", err)
+	}
+	return nil
+}
+
+// read handles server messages until the connection drops, then reconnects
+// if subscribed.
+func (b *NATSBroadcaster) read(conn net.Conn, r *bufio.Reader) {
+	err := b.readMessages(conn, r)
+
+	b.mu.Lock()
+	if b.conn == conn {
+		b.conn.Close()
+		b.conn = nil
+	}
+	subscribed := b.deliver != nil
+	b.mu.Unlock()
+	select {
+	case <-b.closed:
+		return
+	default:
+	}
+	if !subscribed {
+		return
+	}
+
+	log.Printf("[PocketPing] NATS broadcast connection lost: %v", err)
+	for {
+		select {
+		case <-b.closed:
+			return
+		case <-time.After(broadcastRetryDelay):
+		}
+		// connect keeps a connection Publish made in the meantime
+		err := b.connect(context.Background())
+		if err == nil || errors.Is(err, errNATSClosed) {
+			return
+		}
+		log.Printf("[PocketPing] NATS broadcast reconnect failed: %v", err)
+	}
+}
+
+// readMessages delivers MSG payloads and answers PINGs.
+func (b *NATSBroadcaster) readMessages(conn net.Conn, r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("bad MSG line %q", line)
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return err
+			}
+			b.mu.Lock()
+			deliver := b.deliver
+			b.mu.Unlock()
+			if deliver != nil {
+				deliver(payload[:size])
+			}
+		case line == "PING":
+			b.mu.Lock()
+			if b.conn == conn {
+				_ = b.writeLocked("PONG\r\n")
+			}
+			b.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Printf("[PocketPing] NATS error: %s", line)
+		}
+	}
+}
+
+var _ Broadcaster = (*NATSBroadcaster)(nil)
@@ -0,0 +1,334 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisDialTimeout bounds connecting to Redis and each PUBLISH.
+const redisDialTimeout = 5 * time.Second
+
+// RedisBroadcaster is a Broadcaster over Redis pub/sub. It speaks the Redis
+// protocol directly, so no client library is needed.
+type RedisBroadcaster struct {
+	redisEndpoint
+	channel string
+
+	// pub runs PUBLISH.
+	pub redisClient
+
+	subMu sync.Mutex
+	sub   *redisConn
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRedisBroadcaster creates a broadcaster for the Redis server at redisURL
+// ("redis://[user:password@]host[:port]", or "rediss://" for TLS), on
+// channel (DefaultBroadcastChannel when empty).
+func NewRedisBroadcaster(redisURL, channel string) (*RedisBroadcaster, error) {
+	endpoint, err := parseRedisURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if channel == "" {
+		channel = DefaultBroadcastChannel
+	}
+	return &RedisBroadcaster{
+		redisEndpoint: endpoint,
+		channel:       channel,
+		pub:           redisClient{redisEndpoint: endpoint},
+		closed:        make(chan struct{}),
+	}, nil
+}
+
+// Publish implements Broadcaster.
+func (b *RedisBroadcaster) Publish(ctx context.Context, payload []byte) error {
+	_, err := b.pub.do(ctx, "PUBLISH", b.channel, string(payload))
+	return err
+}
+
+// Subscribe implements Broadcaster.
+func (b *RedisBroadcaster) Subscribe(ctx context.Context, deliver func(payload []byte)) error {
+	conn, err := b.subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	go b.listen(conn, deliver)
+	return nil
+}
+
+// subscribe opens the subscriber connection.
+func (b *RedisBroadcaster) subscribe(ctx context.Context) (*redisConn, error) {
+	conn, err := b.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.do(ctx, "SUBSCRIBE", b.channel); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	select {
+	case <-b.closed:
+		conn.Close()
+		return nil, errors.New("broadcaster is closed")
+	default:
+	}
+	b.sub = conn
+	return conn, nil
+}
+
+// listen delivers messages, resubscribing when the connection drops.
+func (b *RedisBroadcaster) listen(conn *redisConn, deliver func(payload []byte)) {
+	for {
+		for {
+			reply, err := conn.read()
+			if err != nil {
+				break
+			}
+			// Pushed messages are ["message", channel, payload].
+			if fields, ok := reply.([]interface{}); ok && len(fields) == 3 {
+				if kind, _ := fields[0].([]byte); string(kind) == "message" {
+					if payload, ok := fields[2].([]byte); ok {
+						deliver(payload)
+					}
+				}
+			}
+		}
+		conn.Close()
+
+		for {
+			select {
+			case <-b.closed:
+				return
+			case <-time.After(broadcastRetryDelay):
+			}
+			var err error
+			if conn, err = b.subscribe(context.Background()); err == nil {
+				break
+			}
+			log.Printf("[PocketPing] Redis broadcast resubscribe failed: %v", err)
+		}
+	}
+}
+
+// Close implements Broadcaster.
+func (b *RedisBroadcaster) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+
+	b.subMu.Lock()
+	if b.sub != nil {
+		b.sub.Close()
+		b.sub = nil
+	}
+	b.subMu.Unlock()
+
+	return b.pub.Close()
+}
+
+// redisEndpoint is a Redis server and its credentials.
+type redisEndpoint struct {
+	addr     string
+	username string
+	password string
+	useTLS   bool
+}
+
+// parseRedisURL parses "redis://[user:password@]host[:port]", or
+// "rediss://" for TLS.
+func parseRedisURL(redisURL string) (redisEndpoint, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return redisEndpoint{}, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return redisEndpoint{}, fmt.Errorf("invalid Redis URL scheme %q", u.Scheme)
+	}
+	e := redisEndpoint{addr: u.Host, useTLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		e.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		e.username = u.User.Username()
+		e.password, _ = u.User.Password()
+	}
+	return e, nil
+}
+
+// connect dials Redis and authenticates.
+func (b redisEndpoint) connect(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if b.useTLS {
+		host, _, _ := net.SplitHostPort(b.addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", b.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to Redis: %w", err)
+	}
+
+	rc := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	if b.password != "" {
+		args := []string{"AUTH", b.password}
+		if b.username != "" {
+			args = []string{"AUTH", b.username, b.password}
+		}
+		if _, err := rc.do(ctx, args...); err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("authenticate to Redis: %w", err)
+		}
+	}
+	return rc, nil
+}
+
+// redisClient runs commands on a connection opened on first use. A stale
+// connection is replaced once.
+type redisClient struct {
+	redisEndpoint
+
+	// mu guards conn.
+	mu   sync.Mutex
+	conn *redisConn
+}
+
+// do sends a command and reads its reply.
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if c.conn, err = c.connect(ctx); err != nil {
+				return nil, err
+			}
+		}
+		var reply interface{}
+		if reply, err = c.conn.do(ctx, args...); err == nil {
+			return reply, nil
+		}
+		var redisErr redisError
+		if errors.As(err, &redisErr) {
+			return nil, err
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	return nil, err
+}
+
+// Close closes the connection to Redis.
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return nil
+}
+
+// redisError is an error reply from Redis.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn is a connection speaking RESP.
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do sends a command and reads its reply.
+func (c *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisDialTimeout)
+	}
+	_ = c.SetDeadline(deadline)
+	defer c.SetDeadline(time.Time{})
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, cmd.String()); err != nil {
+		return nil, err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// read reads one reply: a string, redisError, int64, []byte (nil for a
+// null bulk string) or []interface{}.
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return []byte(nil), err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return []interface{}(nil), err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+var _ Broadcaster = (*RedisBroadcaster)(nil)
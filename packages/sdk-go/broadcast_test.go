@@ -0,0 +1,475 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBus is an in-process Broadcaster shared by several PocketPing
+// instances.
+type memoryBus struct {
+	mu          sync.Mutex
+	subscribers []func([]byte)
+}
+
+func (b *memoryBus) Publish(ctx context.Context, payload []byte) error {
+	b.mu.Lock()
+	subscribers := append([]func([]byte){}, b.subscribers...)
+	b.mu.Unlock()
+	for _, deliver := range subscribers {
+		deliver(payload)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, deliver func([]byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, deliver)
+	return nil
+}
+
+func (b *memoryBus) Close() error { return nil }
+
+func TestBroadcastAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	bus := &memoryBus{}
+	storage := NewMemoryStorage()
+	authenticate := func(ctx context.Context, token string) (string, error) { return "Ann", nil }
+	a := New(Config{Storage: storage, Broadcaster: bus, OperatorAuthenticator: authenticate})
+	b := New(Config{Storage: storage, Broadcaster: bus, OperatorAuthenticator: authenticate})
+	for _, pp := range []*PocketPing{a, b} {
+		if err := pp.Start(ctx); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	}
+
+	sessionID := connectVisitor(ctx, t, a, "v1")
+	console := &operatorWSConn{}
+	b.ConnectOperator("Ann", console)
+	console.mu.Lock()
+	console.events = nil
+	console.mu.Unlock()
+	onA, onB := &mockWSConn{}, &mockWSConn{}
+	a.RegisterWebSocket(sessionID, onA)
+	b.RegisterWebSocket(sessionID, onB)
+
+	a.BroadcastToSession(sessionID, WebSocketEvent{Type: "custom", Data: map[string]string{"k": "v"}})
+	if got := onA.types(); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("local socket events = %v, want [custom]", got)
+	}
+	if got := onB.types(); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("remote socket events = %v, want [custom]", got)
+	}
+	if got := console.types(); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("remote console events = %v, want [custom]", got)
+	}
+
+	// Operator-only events skip visitor sockets on the other replica.
+	connectVisitor(ctx, t, a, "v2")
+	if got := onB.count(); got != 1 {
+		t.Errorf("remote socket got %d events after new_session, want 1", got)
+	}
+	if got := console.types(); len(got) != 2 || got[1] != "new_session" {
+		t.Errorf("remote console events = %v, want new_session last", got)
+	}
+}
+
+func TestBroadcastIgnoresMalformedPayload(t *testing.T) {
+	pp := New(Config{})
+	pp.receiveBroadcast([]byte("not json"))
+}
+
+// fakeRedis serves just enough of Redis for SUBSCRIBE, PUBLISH and SET NX.
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	subscribers := map[net.Conn]string{}
+	keys := map[string]bool{}
+	counters := map[string]int64{}
+	lists := map[string][]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(r)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(args[0]) {
+					case "SUBSCRIBE":
+						mu.Lock()
+						subscribers[conn] = args[1]
+						mu.Unlock()
+						fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+					case "PUBLISH":
+						mu.Lock()
+						n := 0
+						for sub, channel := range subscribers {
+							if channel == args[1] {
+								fmt.Fprintf(sub, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n",
+									len(channel), channel, len(args[2]), args[2])
+								n++
+							}
+						}
+						mu.Unlock()
+						fmt.Fprintf(conn, ":%d\r\n", n)
+					case "SET":
+						mu.Lock()
+						set := keys[args[1]]
+						keys[args[1]] = true
+						mu.Unlock()
+						if set {
+							fmt.Fprint(conn, "$-1\r\n")
+						} else {
+							fmt.Fprint(conn, "+OK\r\n")
+						}
+					case "INCR":
+						mu.Lock()
+						counters[args[1]]++
+						n := counters[args[1]]
+						mu.Unlock()
+						fmt.Fprintf(conn, ":%d\r\n", n)
+					case "RPUSH":
+						mu.Lock()
+						lists[args[1]] = append(lists[args[1]], args[2:]...)
+						n := len(lists[args[1]])
+						mu.Unlock()
+						fmt.Fprintf(conn, ":%d\r\n", n)
+					case "LTRIM": // only LTRIM key -n -1
+						n, _ := strconv.Atoi(args[2])
+						mu.Lock()
+						if list := lists[args[1]]; len(list) > -n {
+							lists[args[1]] = list[len(list)+n:]
+						}
+						mu.Unlock()
+						fmt.Fprint(conn, "+OK\r\n")
+					case "LRANGE": // only LRANGE key 0 -1
+						mu.Lock()
+						list := lists[args[1]]
+						fmt.Fprintf(conn, "*%d\r\n", len(list))
+						for _, item := range list {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(item), item)
+						}
+						mu.Unlock()
+					case "PEXPIRE":
+						fmt.Fprint(conn, ":1\r\n")
+					default:
+						fmt.Fprint(conn, "-ERR unknown command\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// fakeNATS serves just enough of NATS for SUB and PUB, over TLS when
+// tlsConfig is set.
+func fakeNATS(t *testing.T, tlsConfig *tls.Config) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	subscribers := map[net.Conn]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if tlsConfig != nil {
+					fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"tls_required\":true}\r\n")
+					conn = tls.Server(conn, tlsConfig)
+				} else {
+					fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+				}
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch fields[0] {
+					case "PING":
+						fmt.Fprint(conn, "PONG\r\n")
+					case "SUB":
+						mu.Lock()
+						subscribers[conn] = fields[1] + " " + fields[2]
+						mu.Unlock()
+					case "PUB":
+						size, _ := strconv.Atoi(fields[2])
+						payload := make([]byte, size+2)
+						if _, err := io.ReadFull(r, payload); err != nil {
+							return
+						}
+						mu.Lock()
+						for sub, subscription := range subscribers {
+							if subject, sid, _ := strings.Cut(subscription, " "); subject == fields[1] {
+								fmt.Fprintf(sub, "MSG %s %s %d\r\n%s\r\n", subject, sid, size, payload[:size])
+							}
+						}
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// slowNATS is a NATS server that greets each client once release is closed.
+// It reports each accepted connection on accepted, and each one the client
+// closed on dropped.
+func slowNATS(t *testing.T, release <-chan struct{}) (addr string, accepted, dropped chan struct{}) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	accepted, dropped = make(chan struct{}, 10), make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer conn.Close()
+				<-release
+				fmt.Fprint(conn, "INFO {\"server_id\":\"slow\"}\r\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						dropped <- struct{}{}
+						return
+					}
+					if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "PING" {
+						fmt.Fprint(conn, "PONG\r\n")
+					} else if len(fields) == 3 && fields[0] == "PUB" {
+						size, _ := strconv.Atoi(fields[2])
+						_, _ = io.ReadFull(r, make([]byte, size+2))
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), accepted, dropped
+}
+
+func TestNATSBroadcasterConnectsWithoutLock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("close while connecting", func(t *testing.T) {
+		release := make(chan struct{})
+		addr, accepted, _ := slowNATS(t, release)
+		b, _ := NewNATSBroadcaster("nats://"+addr, "")
+
+		published := make(chan error, 1)
+		go func() { published <- b.Publish(ctx, []byte("hello")) }()
+		<-accepted
+
+		closed := make(chan struct{})
+		go func() {
+			b.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("Close waited for the connection in progress")
+		}
+
+		close(release)
+		if err := <-published; !errors.Is(err, errNATSClosed) {
+			t.Errorf("Publish: err = %v, want errNATSClosed", err)
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.conn != nil {
+			t.Error("connection installed after Close")
+		}
+	})
+
+	t.Run("concurrent connects", func(t *testing.T) {
+		release := make(chan struct{})
+		addr, accepted, dropped := slowNATS(t, release)
+		b, _ := NewNATSBroadcaster("nats://"+addr, "")
+		defer b.Close()
+
+		published := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() { published <- b.Publish(ctx, []byte("hello")) }()
+			<-accepted
+		}
+		close(release)
+		for i := 0; i < 2; i++ {
+			if err := <-published; err != nil {
+				t.Errorf("Publish: %v", err)
+			}
+		}
+
+		// The connection that lost the race is closed, the other kept
+		select {
+		case <-dropped:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the second connection wasn't closed")
+		}
+		select {
+		case <-dropped:
+			t.Error("both connections were closed")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestProtocolBroadcasters(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(t *testing.T) (Broadcaster, Broadcaster)
+	}{
+		{"redis", func(t *testing.T) (Broadcaster, Broadcaster) {
+			url := "redis://" + fakeRedis(t)
+			sub, err := NewRedisBroadcaster(url, "")
+			if err != nil {
+				t.Fatalf("NewRedisBroadcaster: %v", err)
+			}
+			pub, _ := NewRedisBroadcaster(url, "")
+			return sub, pub
+		}},
+		{"nats", func(t *testing.T) (Broadcaster, Broadcaster) {
+			url := "nats://" + fakeNATS(t, nil)
+			sub, err := NewNATSBroadcaster(url, "")
+			if err != nil {
+				t.Fatalf("NewNATSBroadcaster: %v", err)
+			}
+			pub, _ := NewNATSBroadcaster(url, "")
+			return sub, pub
+		}},
+		{"nats tls", func(t *testing.T) (Broadcaster, Broadcaster) {
+			// httptest's certificate is valid for 127.0.0.1
+			certs := httptest.NewTLSServer(http.NotFoundHandler())
+			t.Cleanup(certs.Close)
+			roots := certs.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+			url := "tls://" + fakeNATS(t, &tls.Config{Certificates: certs.TLS.Certificates})
+			sub, err := NewNATSBroadcaster(url, "")
+			if err != nil {
+				t.Fatalf("NewNATSBroadcaster: %v", err)
+			}
+			pub, _ := NewNATSBroadcaster(url, "")
+			sub.TLSConfig = &tls.Config{RootCAs: roots}
+			pub.TLSConfig = &tls.Config{RootCAs: roots}
+			return sub, pub
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sub, pub := tt.new(t)
+			defer sub.Close()
+			defer pub.Close()
+
+			received := make(chan string, 1)
+			if err := sub.Subscribe(ctx, func(payload []byte) { received <- string(payload) }); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if err := pub.Publish(ctx, []byte("hello\r\nworld")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			select {
+			case got := <-received:
+				if got != "hello\r\nworld" {
+					t.Errorf("payload = %q", got)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no message delivered")
+			}
+		})
+	}
+}
+
+func TestBroadcasterURLs(t *testing.T) {
+	if _, err := NewRedisBroadcaster("http://localhost", ""); err == nil {
+		t.Error("Redis: expected error for http scheme")
+	}
+	if _, err := NewNATSBroadcaster("redis://localhost", ""); err == nil {
+		t.Error("NATS: expected error for redis scheme")
+	}
+	b, err := NewRedisBroadcaster("rediss://user:pw@cache.example.com", "chat")
+	if err != nil {
+		t.Fatalf("NewRedisBroadcaster: %v", err)
+	}
+	if b.addr != "cache.example.com:6379" || !b.useTLS || b.username != "user" || b.password != "pw" || b.channel != "chat" {
+		t.Errorf("parsed Redis URL = %+v", b)
+	}
+	n, err := NewNATSBroadcaster("nats://s3cret@nats.example.com", "")
+	if err != nil {
+		t.Fatalf("NewNATSBroadcaster: %v", err)
+	}
+	if n.addr != "nats.example.com:4222" || n.token != "s3cret" || n.subject != DefaultBroadcastChannel {
+		t.Errorf("parsed NATS URL = %+v", n)
+	}
+	n, err = NewNATSBroadcaster("tls://nats.example.com:4443", "")
+	if err != nil {
+		t.Fatalf("NewNATSBroadcaster: %v", err)
+	}
+	if n.addr != "nats.example.com:4443" || !n.useTLS || n.host != "nats.example.com" {
+		t.Errorf("parsed NATS TLS URL = %+v", n)
+	}
+}
@@ -0,0 +1,332 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCallbackWindow is how far ahead callback slots are offered when
+// Config.CallbackWindow is zero.
+const DefaultCallbackWindow = 7 * 24 * time.Hour
+
+// DefaultMaxCallbacksPerSession is the cap on a session's upcoming callbacks
+// when Config.MaxCallbacksPerSession is zero.
+const DefaultMaxCallbacksPerSession = 1
+
+var (
+	// ErrCalendarNotConfigured is returned by the callback methods when
+	// Config.CalendarProvider is nil.
+	ErrCalendarNotConfigured = newError("not_configured", http.StatusNotFound, "no calendar provider configured")
+	// ErrSlotUnavailable is returned by HandleScheduleCallback when the
+	// chosen slot is no longer free.
+	ErrSlotUnavailable = newError("slot_unavailable", http.StatusConflict, "callback slot is no longer available")
+	// ErrCallbackLimit is returned by HandleScheduleCallback when the
+	// session already has Config.MaxCallbacksPerSession upcoming callbacks.
+	ErrCallbackLimit = newError("callback_limit", http.StatusConflict, "session already has a callback booked")
+)
+
+// callbackBookings serializes HandleScheduleCallback, so checking a slot
+// and booking it are one step, and remembers the slots booked until they
+// end, in case the calendar's availability lags behind its bookings.
+type callbackBookings struct {
+	mu     sync.Mutex
+	booked []TimeSlot
+}
+
+// TimeSlot is a bookable period.
+type TimeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Booking is a callback booked in a calendar.
+type Booking struct {
+	// Provider names the calendar, e.g. "google", "calcom".
+	Provider string `json:"provider"`
+	// ID is the event or booking ID in the calendar.
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// MeetingURL is the video call link, when the calendar created one.
+	MeetingURL string `json:"meetingUrl,omitempty"`
+}
+
+// BookingRequest is what a CalendarProvider books.
+type BookingRequest struct {
+	Session *Session
+	Slot    TimeSlot
+	// Name and Email identify the visitor; Email receives the invitation.
+	Name  string
+	Email string
+	// Notes is the visitor's note about the call.
+	Notes string
+}
+
+// CalendarProvider offers callback slots and books them, e.g. Google
+// Calendar or Cal.com. Availability returns the free slots starting in
+// [from, to), earliest first.
+type CalendarProvider interface {
+	Availability(ctx context.Context, from, to time.Time) ([]TimeSlot, error)
+	Book(ctx context.Context, request BookingRequest) (*Booking, error)
+}
+
+// CallbackSlotsResponse lists the callback slots offered to a visitor.
+type CallbackSlotsResponse struct {
+	Slots []TimeSlot `json:"slots"`
+}
+
+// ScheduleCallbackRequest is a visitor's pick of a callback slot.
+type ScheduleCallbackRequest struct {
+	SessionID string    `json:"sessionId"`
+	Start     time.Time `json:"start"`
+	// Name and Email default to the session's identity.
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Notes string `json:"notes,omitempty"`
+}
+
+// ScheduleCallbackResponse is the response after booking a callback.
+type ScheduleCallbackResponse struct {
+	Booking *Booking `json:"booking"`
+}
+
+// GetCallbackSlots returns the free callback slots for a session, from now
+// to Config.CallbackWindow ahead.
+func (pp *PocketPing) GetCallbackSlots(ctx context.Context, sessionID string) (*CallbackSlotsResponse, error) {
+	if pp.config.CalendarProvider == nil {
+		return nil, ErrCalendarNotConfigured
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	pp.bookings.mu.Lock()
+	booked := append([]TimeSlot(nil), pp.bookings.current(time.Now())...)
+	pp.bookings.mu.Unlock()
+	slots, err := pp.callbackSlots(ctx, booked)
+	if err != nil {
+		return nil, err
+	}
+	return &CallbackSlotsResponse{Slots: slots}, nil
+}
+
+// OfferCallback pushes a callback_slots event with the free slots, so the
+// widget shows the slot picker. Typically called from an operator command
+// or when no operator is online.
+func (pp *PocketPing) OfferCallback(ctx context.Context, sessionID string) error {
+	response, err := pp.GetCallbackSlots(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	pp.BroadcastToSession(sessionID, WebSocketEvent{
+		Type: "callback_slots",
+		Data: response,
+	})
+	return nil
+}
+
+// HandleScheduleCallback books the slot the visitor picked. The slot must
+// still be free, and the session under Config.MaxCallbacksPerSession
+// upcoming callbacks. The booking is recorded in Session.Callbacks, the
+// visitor gets a confirmation message and every bridge serving the session
+// is told, with the meeting link.
+func (pp *PocketPing) HandleScheduleCallback(ctx context.Context, request ScheduleCallbackRequest) (*ScheduleCallbackResponse, error) {
+	if pp.config.CalendarProvider == nil {
+		return nil, ErrCalendarNotConfigured
+	}
+	session, booking, err := pp.bookCallback(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	when := formatCallbackTime(booking.Start, session)
+	caption := fmt.Sprintf("📅 Callback booked for %s", when)
+	if booking.MeetingURL != "" {
+		caption += ": " + booking.MeetingURL
+	}
+	pp.notifyBridgesNotice(ctx, session, caption)
+
+	content := fmt.Sprintf("Your callback is booked for %s.", when)
+	if booking.MeetingURL != "" {
+		content += " Join here: " + booking.MeetingURL
+	}
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: content, Sender: SenderOperator}); err != nil {
+		return nil, err
+	}
+	return &ScheduleCallbackResponse{Booking: booking}, nil
+}
+
+// bookCallback checks the session's cap and the slot, books it and records
+// it in the session, all under pp.bookings.mu: two requests for the same
+// slot, or from the same session, can't both pass the checks.
+func (pp *PocketPing) bookCallback(ctx context.Context, request ScheduleCallbackRequest) (*Session, *Booking, error) {
+	b := &pp.bookings
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	session, err := pp.storage.GetSession(ctx, request.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, ErrSessionNotFound
+	}
+	limit := pp.config.MaxCallbacksPerSession
+	if limit <= 0 {
+		limit = DefaultMaxCallbacksPerSession
+	}
+	now := time.Now()
+	upcoming := 0
+	for _, callback := range session.Callbacks {
+		if callback.End.After(now) {
+			upcoming++
+		}
+	}
+	if upcoming >= limit {
+		return nil, nil, ErrCallbackLimit
+	}
+
+	slots, err := pp.callbackSlots(ctx, b.current(now))
+	if err != nil {
+		return nil, nil, err
+	}
+	var slot *TimeSlot
+	for i := range slots {
+		if slots[i].Start.Equal(request.Start) {
+			slot = &slots[i]
+			break
+		}
+	}
+	if slot == nil {
+		return nil, nil, ErrSlotUnavailable
+	}
+
+	name, email := strings.TrimSpace(request.Name), strings.TrimSpace(request.Email)
+	if session.Identity != nil {
+		if name == "" {
+			name = session.Identity.Name
+		}
+		if email == "" {
+			email = session.Identity.Email
+		}
+	}
+	booking, err := pp.config.CalendarProvider.Book(ctx, BookingRequest{
+		Session: session,
+		Slot:    *slot,
+		Name:    name,
+		Email:   email,
+		Notes:   strings.TrimSpace(request.Notes),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	b.booked = append(b.booked, *slot)
+
+	session.Callbacks = append(session.Callbacks, *booking)
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, nil, err
+	}
+	return session, booking, nil
+}
+
+// callbackSlots returns the calendar's free slots in the callback window,
+// minus the booked ones it may still offer.
+func (pp *PocketPing) callbackSlots(ctx context.Context, booked []TimeSlot) ([]TimeSlot, error) {
+	window := pp.config.CallbackWindow
+	if window <= 0 {
+		window = DefaultCallbackWindow
+	}
+	now := time.Now()
+	slots, err := pp.config.CalendarProvider.Availability(ctx, now, now.Add(window))
+	if err != nil {
+		return nil, err
+	}
+	free := slots[:0:0]
+	for _, slot := range slots {
+		if !overlapsAny(slot, booked) {
+			free = append(free, slot)
+		}
+	}
+	return free, nil
+}
+
+// current forgets the booked slots that have ended and returns the others.
+// The caller holds mu.
+func (b *callbackBookings) current(now time.Time) []TimeSlot {
+	kept := b.booked[:0]
+	for _, slot := range b.booked {
+		if slot.End.After(now) {
+			kept = append(kept, slot)
+		}
+	}
+	b.booked = kept
+	return kept
+}
+
+// formatCallbackTime formats t in the visitor's time zone when known.
+func formatCallbackTime(t time.Time, session *Session) string {
+	if session.Metadata != nil && session.Metadata.Timezone != "" {
+		if loc, err := time.LoadLocation(session.Metadata.Timezone); err == nil {
+			t = t.In(loc)
+		}
+	}
+	return t.Format("Mon Jan 2, 15:04 MST")
+}
+
+// WorkingHours are the hours callbacks can be booked in, for the providers
+// that compute slots from busy times.
+type WorkingHours struct {
+	// Location is the time zone of the hours (default UTC).
+	Location *time.Location
+	// StartHour and EndHour bound each day, e.g. 9 and 17 (default 9-17).
+	StartHour, EndHour int
+	// Weekends allows slots on Saturday and Sunday.
+	Weekends bool
+}
+
+// freeSlots cuts [from, to) into slots of duration within the working hours
+// and drops those overlapping a busy period.
+func freeSlots(busy []TimeSlot, from, to time.Time, duration time.Duration, hours WorkingHours) []TimeSlot {
+	loc := hours.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	startHour, endHour := hours.StartHour, hours.EndHour
+	if startHour == 0 && endHour == 0 {
+		startHour, endHour = 9, 17
+	}
+
+	var slots []TimeSlot
+	local := from.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !hours.Weekends && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday) {
+			continue
+		}
+		dayEnd := time.Date(day.Year(), day.Month(), day.Day(), endHour, 0, 0, 0, loc)
+		for start := time.Date(day.Year(), day.Month(), day.Day(), startHour, 0, 0, 0, loc); !start.Add(duration).After(dayEnd); start = start.Add(duration) {
+			slot := TimeSlot{Start: start, End: start.Add(duration)}
+			if slot.Start.Before(from) || slot.End.After(to) || overlapsAny(slot, busy) {
+				continue
+			}
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+func overlapsAny(slot TimeSlot, busy []TimeSlot) bool {
+	for _, b := range busy {
+		if slot.Start.Before(b.End) && b.Start.Before(slot.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCallbackDuration is the length of a callback slot when a provider's
+// SlotDuration is zero.
+const DefaultCallbackDuration = 30 * time.Minute
+
+// ─────────────────────────────────────────────────────────────────
+// GoogleCalendarProvider
+// ─────────────────────────────────────────────────────────────────
+
+// GoogleCalendarProvider implements CalendarProvider with the Google Calendar
+// API: slots are the working hours minus the calendar's busy times, and
+// bookings are events with a Google Meet link and the visitor as attendee.
+type GoogleCalendarProvider struct {
+	// AccessToken returns an OAuth access token with the calendar scope
+	// (required), e.g. from an oauth2.TokenSource.
+	AccessToken func(ctx context.Context) (string, error)
+	// CalendarID is the calendar to book in (default "primary").
+	CalendarID string
+	// SlotDuration is the length of a callback (default DefaultCallbackDuration).
+	SlotDuration time.Duration
+	// Hours are the bookable hours (default 9-17 UTC on weekdays).
+	Hours WorkingHours
+	// Summary is the event title (default "Callback").
+	Summary string
+	// BaseURL is the API base URL (default "https://www.googleapis.com/calendar/v3").
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+func (p *GoogleCalendarProvider) calendarID() string {
+	if p.CalendarID != "" {
+		return p.CalendarID
+	}
+	return "primary"
+}
+
+func (p *GoogleCalendarProvider) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return "https://www.googleapis.com/calendar/v3"
+}
+
+func (p *GoogleCalendarProvider) header(ctx context.Context) (http.Header, error) {
+	token, err := p.AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	return header, nil
+}
+
+// Availability calls POST {baseURL}/freeBusy and returns the working-hour
+// slots that don't overlap a busy period.
+func (p *GoogleCalendarProvider) Availability(ctx context.Context, from, to time.Time) ([]TimeSlot, error) {
+	header, err := p.header(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{
+		"timeMin": from.UTC().Format(time.RFC3339),
+		"timeMax": to.UTC().Format(time.RFC3339),
+		"items":   []map[string]string{{"id": p.calendarID()}},
+	}
+	var result struct {
+		Calendars map[string]struct {
+			Busy []TimeSlot `json:"busy"`
+		} `json:"calendars"`
+	}
+	if err := doJSON(ctx, p.HTTPClient, "google", http.MethodPost, p.baseURL()+"/freeBusy", header, payload, &result); err != nil {
+		return nil, err
+	}
+	duration := p.SlotDuration
+	if duration <= 0 {
+		duration = DefaultCallbackDuration
+	}
+	return freeSlots(result.Calendars[p.calendarID()].Busy, from, to, duration, p.Hours), nil
+}
+
+// Book inserts an event with conference data, which makes Google create a
+// Meet link, and invites the visitor when their email is known.
+func (p *GoogleCalendarProvider) Book(ctx context.Context, request BookingRequest) (*Booking, error) {
+	header, err := p.header(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary := p.Summary
+	if summary == "" {
+		summary = "Callback"
+	}
+	if request.Name != "" {
+		summary += " with " + request.Name
+	}
+	event := map[string]interface{}{
+		"summary":     summary,
+		"description": fmt.Sprintf("PocketPing session %s\n\n%s", request.Session.ID, request.Notes),
+		"start":       map[string]string{"dateTime": request.Slot.Start.UTC().Format(time.RFC3339)},
+		"end":         map[string]string{"dateTime": request.Slot.End.UTC().Format(time.RFC3339)},
+		"conferenceData": map[string]interface{}{
+			"createRequest": map[string]interface{}{
+				"requestId":             request.Session.ID + "-" + strconv.FormatInt(request.Slot.Start.Unix(), 10),
+				"conferenceSolutionKey": map[string]string{"type": "hangoutsMeet"},
+			},
+		},
+	}
+	if request.Email != "" {
+		event["attendees"] = []map[string]string{{"email": request.Email, "displayName": request.Name}}
+	}
+
+	endpoint := fmt.Sprintf("%s/calendars/%s/events?conferenceDataVersion=1&sendUpdates=all", p.baseURL(), url.PathEscape(p.calendarID()))
+	var created struct {
+		ID          string `json:"id"`
+		HangoutLink string `json:"hangoutLink"`
+	}
+	if err := doJSON(ctx, p.HTTPClient, "google", http.MethodPost, endpoint, header, event, &created); err != nil {
+		return nil, err
+	}
+	return &Booking{Provider: "google", ID: created.ID, Start: request.Slot.Start, End: request.Slot.End, MeetingURL: created.HangoutLink}, nil
+}
+
+// ─────────────────────────────────────────────────────────────────
+// CalComProvider
+// ─────────────────────────────────────────────────────────────────
+
+// CalComProvider implements CalendarProvider with the Cal.com API v2, using
+// the slots and location of one event type.
+type CalComProvider struct {
+	// APIKey is a Cal.com API key (required).
+	APIKey string
+	// EventTypeID is the event type booked for callbacks (required).
+	EventTypeID int
+	// TimeZone is the attendee time zone when the visitor's is unknown
+	// (default "UTC").
+	TimeZone string
+	// BaseURL is the API base URL (default "https://api.cal.com/v2").
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+func (p *CalComProvider) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return "https://api.cal.com/v2"
+}
+
+func (p *CalComProvider) header(apiVersion string) http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.APIKey)
+	header.Set("cal-api-version", apiVersion)
+	return header
+}
+
+// Availability calls GET {baseURL}/slots for the event type.
+func (p *CalComProvider) Availability(ctx context.Context, from, to time.Time) ([]TimeSlot, error) {
+	query := url.Values{
+		"eventTypeId": {strconv.Itoa(p.EventTypeID)},
+		"start":       {from.UTC().Format(time.RFC3339)},
+		"end":         {to.UTC().Format(time.RFC3339)},
+		"format":      {"range"},
+	}
+	var result struct {
+		Data map[string][]TimeSlot `json:"data"`
+	}
+	if err := doJSON(ctx, p.HTTPClient, "calcom", http.MethodGet, p.baseURL()+"/slots?"+query.Encode(), p.header("2024-09-04"), nil, &result); err != nil {
+		return nil, err
+	}
+	var slots []TimeSlot
+	for _, day := range result.Data {
+		slots = append(slots, day...)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots, nil
+}
+
+// Book calls POST {baseURL}/bookings with the visitor as attendee.
+func (p *CalComProvider) Book(ctx context.Context, request BookingRequest) (*Booking, error) {
+	timeZone := p.TimeZone
+	if request.Session.Metadata != nil && request.Session.Metadata.Timezone != "" {
+		timeZone = request.Session.Metadata.Timezone
+	}
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	name := request.Name
+	if name == "" {
+		name = "Visitor"
+	}
+	payload := map[string]interface{}{
+		"start":       request.Slot.Start.UTC().Format(time.RFC3339),
+		"eventTypeId": p.EventTypeID,
+		"attendee":    map[string]string{"name": name, "email": request.Email, "timeZone": timeZone},
+		"metadata":    map[string]string{"pocketpingSessionId": request.Session.ID},
+	}
+	if request.Notes != "" {
+		payload["bookingFieldsResponses"] = map[string]string{"notes": request.Notes}
+	}
+	var result struct {
+		Data struct {
+			UID        string    `json:"uid"`
+			Start      time.Time `json:"start"`
+			End        time.Time `json:"end"`
+			MeetingURL string    `json:"meetingUrl"`
+		} `json:"data"`
+	}
+	if err := doJSON(ctx, p.HTTPClient, "calcom", http.MethodPost, p.baseURL()+"/bookings", p.header("2024-08-13"), payload, &result); err != nil {
+		return nil, err
+	}
+	booking := &Booking{Provider: "calcom", ID: result.Data.UID, Start: result.Data.Start, End: result.Data.End, MeetingURL: result.Data.MeetingURL}
+	if booking.Start.IsZero() {
+		booking.Start, booking.End = request.Slot.Start, request.Slot.End
+	}
+	return booking, nil
+}
+
+var (
+	_ CalendarProvider = (*GoogleCalendarProvider)(nil)
+	_ CalendarProvider = (*CalComProvider)(nil)
+)
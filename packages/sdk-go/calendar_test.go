@@ -0,0 +1,201 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeCalendar struct {
+	slots  []TimeSlot
+	booked []BookingRequest
+}
+
+func (f *fakeCalendar) Availability(ctx context.Context, from, to time.Time) ([]TimeSlot, error) {
+	return f.slots, nil
+}
+
+func (f *fakeCalendar) Book(ctx context.Context, request BookingRequest) (*Booking, error) {
+	f.booked = append(f.booked, request)
+	return &Booking{Provider: "fake", ID: "evt1", Start: request.Slot.Start, End: request.Slot.End, MeetingURL: "https://meet.example.com/abc"}, nil
+}
+
+func TestScheduleCallback(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	calendar := &fakeCalendar{slots: []TimeSlot{{Start: start, End: start.Add(30 * time.Minute)}}}
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{CalendarProvider: calendar, Bridges: []Bridge{bridge}})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	if err := pp.OfferCallback(ctx, session.ID); err != nil {
+		t.Fatalf("OfferCallback: %v", err)
+	}
+	if conn.events[0].Type != "callback_slots" || len(conn.events[0].Data.(*CallbackSlotsResponse).Slots) != 1 {
+		t.Errorf("offer event = %+v", conn.events[0])
+	}
+
+	if _, err := pp.HandleScheduleCallback(ctx, ScheduleCallbackRequest{SessionID: session.ID, Start: start.Add(time.Hour)}); !errors.Is(err, ErrSlotUnavailable) {
+		t.Errorf("unknown slot error = %v, want ErrSlotUnavailable", err)
+	}
+
+	response, err := pp.HandleScheduleCallback(ctx, ScheduleCallbackRequest{SessionID: session.ID, Start: start, Name: "Jane", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("HandleScheduleCallback: %v", err)
+	}
+	if response.Booking.ID != "evt1" || calendar.booked[0].Email != "jane@example.com" {
+		t.Errorf("booking = %+v, request = %+v", response.Booking, calendar.booked[0])
+	}
+
+	updated, _ := pp.GetSession(ctx, session.ID)
+	if len(updated.Callbacks) != 1 {
+		t.Errorf("session callbacks = %+v", updated.Callbacks)
+	}
+	confirmation := conn.events[len(conn.events)-1].Data.(*Message)
+	if !strings.Contains(confirmation.Content, "https://meet.example.com/abc") {
+		t.Errorf("confirmation = %q", confirmation.Content)
+	}
+	pp.dispatcher.wait()
+	if len(bridge.notices) != 1 || !strings.Contains(bridge.notices[0], "https://meet.example.com/abc") {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+}
+
+func TestScheduleCallbackLimitAndRace(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	slot := TimeSlot{Start: start, End: start.Add(30 * time.Minute)}
+	// The calendar keeps offering a slot once booked, like a lagging
+	// availability API.
+	calendar := &fakeCalendar{slots: []TimeSlot{slot, {Start: slot.End, End: slot.End.Add(30 * time.Minute)}}}
+	pp := New(Config{CalendarProvider: calendar, MaxCallbacksPerSession: 5})
+
+	// Two visitors race for the same slot: one gets it.
+	sessions := []*Session{newSession(ctx, t, pp), newSession(ctx, t, pp)}
+	errs := make(chan error, len(sessions))
+	for _, session := range sessions {
+		go func(sessionID string) {
+			_, err := pp.HandleScheduleCallback(ctx, ScheduleCallbackRequest{SessionID: sessionID, Start: start})
+			errs <- err
+		}(session.ID)
+	}
+	var unavailable int
+	for range sessions {
+		if err := <-errs; errors.Is(err, ErrSlotUnavailable) {
+			unavailable++
+		} else if err != nil {
+			t.Fatalf("HandleScheduleCallback: %v", err)
+		}
+	}
+	if unavailable != 1 || len(calendar.booked) != 1 {
+		t.Errorf("got %d unavailable and %d bookings, want 1 and 1", unavailable, len(calendar.booked))
+	}
+	slots, _ := pp.GetCallbackSlots(ctx, sessions[0].ID)
+	if len(slots.Slots) != 1 || slots.Slots[0].Start.Equal(start) {
+		t.Errorf("slots = %+v, want the booked slot left out", slots.Slots)
+	}
+
+	// The default cap is one upcoming callback per session.
+	pp.config.MaxCallbacksPerSession = 0
+	booker := sessions[0]
+	if len(calendar.booked) == 1 && calendar.booked[0].Session.ID != booker.ID {
+		booker = sessions[1]
+	}
+	if _, err := pp.HandleScheduleCallback(ctx, ScheduleCallbackRequest{SessionID: booker.ID, Start: slot.End}); !errors.Is(err, ErrCallbackLimit) {
+		t.Errorf("second booking error = %v, want ErrCallbackLimit", err)
+	}
+}
+
+func TestScheduleCallbackWithoutCalendar(t *testing.T) {
+	pp := New(Config{})
+	if _, err := pp.GetCallbackSlots(context.Background(), "s1"); !errors.Is(err, ErrCalendarNotConfigured) {
+		t.Errorf("error = %v, want ErrCalendarNotConfigured", err)
+	}
+}
+
+func TestFreeSlots(t *testing.T) {
+	// Friday 2025-01-17 08:00 UTC to Monday 2025-01-20 12:00 UTC.
+	from := time.Date(2025, 1, 17, 8, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC)
+	busy := []TimeSlot{{Start: time.Date(2025, 1, 17, 10, 15, 0, 0, time.UTC), End: time.Date(2025, 1, 17, 11, 0, 0, 0, time.UTC)}}
+
+	slots := freeSlots(busy, from, to, time.Hour, WorkingHours{StartHour: 9, EndHour: 12})
+	var got []string
+	for _, slot := range slots {
+		got = append(got, slot.Start.Format("Mon 15:04"))
+	}
+	want := "Fri 09:00,Fri 11:00,Mon 09:00,Mon 10:00,Mon 11:00"
+	if strings.Join(got, ",") != want {
+		t.Errorf("slots = %v, want %s", got, want)
+	}
+}
+
+func TestGoogleCalendarProvider(t *testing.T) {
+	var event map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29" {
+			t.Errorf("unexpected auth %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/freeBusy":
+			w.Write([]byte(`{"calendars":{"primary":{"busy":[{"start":"2025-01-17T09:00:00Z","end":"2025-01-17T09:30:00Z"}]}}}`))
+		case "/calendars/primary/events":
+			if r.URL.Query().Get("conferenceDataVersion") != "1" {
+				t.Errorf("expected conferenceDataVersion=1, got %s", r.URL.RawQuery)
+			}
+			json.NewDecoder(r.Body).Decode(&event)
+			w.Write([]byte(`{"id":"evt1","hangoutLink":"https://meet.google.com/abc-defg-hij"}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := &GoogleCalendarProvider{
+		AccessToken: func(context.Context) (string, error) { return "ya29", nil },
+		BaseURL:     server.URL,
+		Hours:       WorkingHours{StartHour: 9, EndHour: 10},
+	}
+	from := time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)
+	slots, err := provider.Availability(context.Background(), from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Availability: %v", err)
+	}
+	if len(slots) != 1 || slots[0].Start.Hour() != 9 || slots[0].Start.Minute() != 30 {
+		t.Errorf("slots = %+v", slots)
+	}
+
+	booking, err := provider.Book(context.Background(), BookingRequest{Session: &Session{ID: "s1"}, Slot: slots[0], Name: "Jane", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("Book: %v", err)
+	}
+	if booking.MeetingURL != "https://meet.google.com/abc-defg-hij" || event["summary"] != "Callback with Jane" {
+		t.Errorf("booking = %+v, event = %v", booking, event)
+	}
+}
+
+func TestCalComProviderAvailability(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("eventTypeId") != "42" || r.Header.Get("cal-api-version") == "" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"status":"success","data":{` +
+			`"2025-01-18":[{"start":"2025-01-18T09:00:00Z","end":"2025-01-18T09:30:00Z"}],` +
+			`"2025-01-17":[{"start":"2025-01-17T15:00:00Z","end":"2025-01-17T15:30:00Z"}]}}`))
+	}))
+	defer server.Close()
+
+	provider := &CalComProvider{APIKey: "cal_live", EventTypeID: 42, BaseURL: server.URL}
+	slots, err := provider.Availability(context.Background(), time.Now(), time.Now().Add(72*time.Hour))
+	if err != nil {
+		t.Fatalf("Availability: %v", err)
+	}
+	if len(slots) != 2 || slots[0].Start.Day() != 17 {
+		t.Errorf("slots = %+v", slots)
+	}
+}
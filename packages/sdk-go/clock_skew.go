@@ -0,0 +1,125 @@
+package pocketping
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultClockSkewThreshold is how far a platform timestamp may be from local
+// time before ClockSkewDetector treats it as skewed.
+const DefaultClockSkewThreshold = 30 * time.Second
+
+// ClockSkewStats describes the timestamps seen from one source.
+type ClockSkewStats struct {
+	// LastSkew is the platform time minus local time of the last timestamp.
+	LastSkew time.Duration `json:"lastSkew"`
+	// Observed counts the timestamps seen.
+	Observed int64 `json:"observed"`
+	// Skewed counts the timestamps beyond the threshold, replaced with local
+	// time.
+	Skewed int64 `json:"skewed"`
+}
+
+// ClockSkewDetector normalizes timestamps from platforms (Telegram edit
+// dates, Discord edited_timestamp, ...) against local time, so clocks that
+// disagree don't produce negative durations in analytics. It keeps the
+// platform time when it is within the threshold of local time, but never in
+// the future; beyond the threshold it uses local time, and logs when a source
+// starts drifting. Safe for concurrent use.
+type ClockSkewDetector struct {
+	threshold time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	sources map[string]*clockSkewSource
+}
+
+type clockSkewSource struct {
+	stats  ClockSkewStats
+	skewed bool
+}
+
+// NewClockSkewDetector returns a detector with the given threshold; zero or
+// negative means DefaultClockSkewThreshold.
+func NewClockSkewDetector(threshold time.Duration) *ClockSkewDetector {
+	if threshold <= 0 {
+		threshold = DefaultClockSkewThreshold
+	}
+	return &ClockSkewDetector{threshold: threshold, now: time.Now, sources: make(map[string]*clockSkewSource)}
+}
+
+// Threshold returns the skew threshold.
+func (d *ClockSkewDetector) Threshold() time.Duration {
+	return d.threshold
+}
+
+// Normalize returns the time to record for a timestamp reported by source,
+// received now. A zero timestamp means local time.
+func (d *ClockSkewDetector) Normalize(source string, platformTime time.Time) time.Time {
+	now := d.now()
+	if platformTime.IsZero() {
+		return now
+	}
+	skew := platformTime.Sub(now)
+
+	d.mu.Lock()
+	src, ok := d.sources[source]
+	if !ok {
+		src = &clockSkewSource{}
+		d.sources[source] = src
+	}
+	src.stats.Observed++
+	src.stats.LastSkew = skew
+	beyond := skew > d.threshold || skew < -d.threshold
+	if beyond {
+		src.stats.Skewed++
+	}
+	started, recovered := beyond && !src.skewed, !beyond && src.skewed
+	src.skewed = beyond
+	d.mu.Unlock()
+
+	switch {
+	case started:
+		log.Printf("[PocketPing] Clock skew: %s timestamps are %s off local time (threshold %s); using local time until they agree", source, skew.Round(time.Millisecond), d.threshold)
+	case recovered:
+		log.Printf("[PocketPing] Clock skew: %s timestamps agree with local time again", source)
+	}
+
+	if beyond || platformTime.After(now) {
+		return now
+	}
+	return platformTime
+}
+
+// platformTimeKey is the context key of the platform time of an edit or
+// deletion.
+type platformTimeKey struct{}
+
+// PlatformTime returns the time the bridge platform reported for the edit or
+// deletion whose WebhookConfig or DiscordGatewayConfig callback got ctx; the
+// callback's own time is normalized with their ClockSkew. The time is zero
+// when the platform reported none, and ok is false outside those callbacks.
+func PlatformTime(ctx context.Context) (platformTime time.Time, ok bool) {
+	platformTime, ok = ctx.Value(platformTimeKey{}).(time.Time)
+	return platformTime, ok
+}
+
+// normalizePlatformTime returns the time to pass to an edit or delete
+// callback for a time reported by source (zero if none), and ctx carrying
+// the reported one for PlatformTime.
+func normalizePlatformTime(ctx context.Context, clock *ClockSkewDetector, source string, reported time.Time) (context.Context, time.Time) {
+	return context.WithValue(ctx, platformTimeKey{}, reported), clock.Normalize(source, reported)
+}
+
+// Stats returns the skew statistics of each source seen so far.
+func (d *ClockSkewDetector) Stats() map[string]ClockSkewStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := make(map[string]ClockSkewStats, len(d.sources))
+	for name, src := range d.sources {
+		stats[name] = src.stats
+	}
+	return stats
+}
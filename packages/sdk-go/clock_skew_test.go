@@ -0,0 +1,198 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockSkewDetectorNormalize(t *testing.T) {
+	logs := captureLog(t)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	d := NewClockSkewDetector(30 * time.Second)
+	d.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		platform time.Time
+		want     time.Time
+	}{
+		{"zero uses local time", time.Time{}, now},
+		{"small lag kept", now.Add(-2 * time.Second), now.Add(-2 * time.Second)},
+		{"small lead clamped to now", now.Add(time.Second), now},
+		{"far ahead replaced", now.Add(5 * time.Minute), now},
+		{"far behind replaced", now.Add(-5 * time.Minute), now},
+	}
+	for _, tt := range tests {
+		if got := d.Normalize("telegram", tt.platform); !got.Equal(tt.want) {
+			t.Errorf("%s: Normalize = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	stats := d.Stats()["telegram"]
+	if stats.Observed != 4 || stats.Skewed != 2 || stats.LastSkew != -5*time.Minute {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	// Logged once when the skew started, not for every timestamp.
+	if n := strings.Count(logs.String(), "Clock skew: telegram timestamps are"); n != 1 {
+		t.Errorf("expected one skew log line, got %d:\n%s", n, logs.String())
+	}
+
+	d.Normalize("telegram", now)
+	if !strings.Contains(logs.String(), "agree with local time again") {
+		t.Errorf("expected a recovery log line:\n%s", logs.String())
+	}
+}
+
+// platformTimes records the times an edit or delete callback got.
+type platformTimes struct {
+	at, reported time.Time
+	ok           bool
+}
+
+func (p *platformTimes) record(ctx context.Context, at time.Time) {
+	p.at = at
+	p.reported, p.ok = PlatformTime(ctx)
+}
+
+func TestWebhookPlatformTimesNormalized(t *testing.T) {
+	captureLog(t)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	clock := NewClockSkewDetector(0)
+	clock.now = func() time.Time { return now }
+
+	var got platformTimes
+	wh := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		SlackBotToken:    "xoxb",
+		ClockSkew:        clock,
+		OnOperatorMessageEdit: func(ctx context.Context, sessionID, bridgeMessageID, content, sourceBridge string, editedAt time.Time) {
+			got.record(ctx, editedAt)
+		},
+		OnOperatorMessageDelete: func(ctx context.Context, sessionID, bridgeMessageID, sourceBridge string, deletedAt time.Time) {
+			got.record(ctx, deletedAt)
+		},
+	})
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		payload  string
+		reported time.Time
+		want     time.Time
+	}{
+		{"telegram edit from the future", wh.HandleTelegramWebhook(),
+			fmt.Sprintf(`{"edited_message":{"message_id":1,"message_thread_id":456,"text":"x","edit_date":%d}}`, now.Add(time.Hour).Unix()),
+			now.Add(time.Hour), now},
+		{"telegram deletion skewed behind", wh.HandleTelegramWebhook(),
+			fmt.Sprintf(`{"message_reaction":{"message_id":1,"message_thread_id":456,"new_reaction":[{"type":"emoji","emoji":"🗑"}],"date":%d}}`, now.Add(-10*time.Minute).Unix()),
+			now.Add(-10 * time.Minute), now},
+		{"telegram edit in time", wh.HandleTelegramWebhook(),
+			fmt.Sprintf(`{"edited_message":{"message_id":1,"message_thread_id":456,"text":"x","edit_date":%d}}`, now.Add(-3*time.Second).Unix()),
+			now.Add(-3 * time.Second), now.Add(-3 * time.Second)},
+		{"slack edit slightly ahead", wh.HandleSlackWebhook(),
+			fmt.Sprintf(`{"type":"event_callback","event":{"type":"message","subtype":"message_changed","ts":"%d.500000","message":{"ts":"1.1","thread_ts":"th1","text":"x"}}}`, now.Add(5*time.Second).Unix()),
+			now.Add(5*time.Second + 500*time.Millisecond), now},
+		{"slack deletion without a time", wh.HandleSlackWebhook(),
+			`{"type":"event_callback","event":{"type":"message","subtype":"message_deleted","deleted_ts":"1.1","previous_message":{"ts":"1.1","thread_ts":"th2"}}}`,
+			time.Time{}, now},
+	}
+	for _, tt := range tests {
+		got = platformTimes{}
+		if rec := postWebhook(tt.handler, tt.payload); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tt.name, rec.Code)
+		}
+		if !got.at.Equal(tt.want) || !got.ok || !got.reported.Equal(tt.reported) {
+			t.Errorf("%s: got %v (platform %v, %v), want %v (platform %v)", tt.name, got.at, got.reported, got.ok, tt.want, tt.reported)
+		}
+	}
+	if stats := clock.Stats(); stats["telegram"].Skewed != 2 || stats["slack"].Observed != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestGatewayPlatformTimesNormalized(t *testing.T) {
+	captureLog(t)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	clock := NewClockSkewDetector(0)
+	clock.now = func() time.Time { return now }
+
+	var got platformTimes
+	g := newTestGateway(DiscordGatewayConfig{
+		ClockSkew: clock,
+		OnOperatorMessageEdit: func(ctx context.Context, sessionID, bridgeMessageID, content string, editedAt time.Time) {
+			got.record(ctx, editedAt)
+		},
+		OnOperatorMessageDelete: func(ctx context.Context, sessionID, bridgeMessageID string, deletedAt time.Time) {
+			got.record(ctx, deletedAt)
+		},
+	})
+
+	future := now.Add(2 * time.Hour)
+	g.handleMessageUpdate(messageUpdatePayload{ID: "m1", ChannelID: "t1", Content: "x", EditedTimestamp: future.Format(time.RFC3339)})
+	if !got.at.Equal(now) || !got.reported.Equal(future) {
+		t.Errorf("edit got %v (platform %v), want %v (platform %v)", got.at, got.reported, now, future)
+	}
+
+	g.handleMessageDelete(messageDeletePayload{ID: "m1", ChannelID: "t1"})
+	if !got.at.Equal(now) || !got.ok || !got.reported.IsZero() {
+		t.Errorf("delete got %v (platform %v, %v)", got.at, got.reported, got.ok)
+	}
+	if stats := clock.Stats()["discord"]; stats.Skewed != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestOperatorEditStoresPlatformTime(t *testing.T) {
+	captureLog(t)
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	sent, err := pp.SendOperatorMessage(ctx, sessionID, "Helo", "telegram", "Ann", WithBridgeMessageID("42"))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+
+	// A Telegram clock an hour ahead, through the webhook into storage
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	wh := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		SessionResolver: func(ctx context.Context, container BridgeContainer) (string, bool) {
+			return sessionID, true
+		},
+		OnOperatorMessageEdit: func(ctx context.Context, sessionID, bridgeMessageID, content, sourceBridge string, editedAt time.Time) {
+			if _, err := pp.HandleOperatorEditMessage(ctx, OperatorEditMessageRequest{
+				SessionID: sessionID, SourceBridge: sourceBridge, BridgeMessageID: bridgeMessageID, Content: content, EditedAt: editedAt,
+			}); err != nil {
+				t.Errorf("HandleOperatorEditMessage: %v", err)
+			}
+		},
+	})
+	before := time.Now()
+	payload := fmt.Sprintf(`{"edited_message":{"message_id":42,"message_thread_id":456,"text":"Hello","edit_date":%d}}`, future.Unix())
+	postWebhook(wh.HandleTelegramWebhook(), payload)
+
+	stored, _ := pp.storage.GetMessage(ctx, sent.ID)
+	if stored.EditedAt == nil || stored.EditedAt.Before(before) || stored.EditedAt.After(time.Now()) {
+		t.Errorf("EditedAt = %v, want local time", stored.EditedAt)
+	}
+	if stored.PlatformEditedAt == nil || !stored.PlatformEditedAt.Equal(future) {
+		t.Errorf("PlatformEditedAt = %v, want %v", stored.PlatformEditedAt, future)
+	}
+
+	// Without a callback, the request's time is normalized with Config.ClockSkew
+	past := time.Now().Add(-time.Hour)
+	if _, err := pp.HandleOperatorDeleteMessage(ctx, OperatorDeleteMessageRequest{SessionID: sessionID, SourceBridge: "telegram", BridgeMessageID: "42", DeletedAt: past}); err != nil {
+		t.Fatalf("HandleOperatorDeleteMessage: %v", err)
+	}
+	stored, _ = pp.storage.GetMessage(ctx, sent.ID)
+	if stored.DeletedAt == nil || stored.DeletedAt.Before(sent.Timestamp) || stored.PlatformDeletedAt == nil || !stored.PlatformDeletedAt.Equal(past) {
+		t.Errorf("DeletedAt = %v, PlatformDeletedAt = %v", stored.DeletedAt, stored.PlatformDeletedAt)
+	}
+	if stats := pp.config.ClockSkew.Stats()["telegram"]; stats.Skewed != 1 {
+		t.Errorf("Config.ClockSkew stats = %+v", stats)
+	}
+}
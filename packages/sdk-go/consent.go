@@ -0,0 +1,66 @@
+package pocketping
+
+import (
+	"context"
+	"time"
+)
+
+// hasConsent reports whether session's metadata may be tracked: always,
+// unless Config.RequireConsent is set and the visitor hasn't consented.
+func (pp *PocketPing) hasConsent(session *Session) bool {
+	return !pp.config.RequireConsent || session.Consent
+}
+
+// consentlessMetadata keeps only what the chat needs from a visitor who
+// hasn't consented to tracking: their language, for replies, and time zone,
+// for scheduling.
+func consentlessMetadata(metadata *SessionMetadata) *SessionMetadata {
+	if metadata == nil || (metadata.Language == "" && metadata.Timezone == "") {
+		return nil
+	}
+	return &SessionMetadata{
+		Language: metadata.Language,
+		Timezone: metadata.Timezone,
+	}
+}
+
+// GrantConsent records that a session's visitor consented to tracking
+// (Config.RequireConsent), typically from your cookie banner. metadata, if
+// not nil, replaces the minimal metadata kept so far; the region is then
+// resolved again. The widget can do the same by reconnecting with
+// ConnectRequest.Consent.
+func (pp *PocketPing) GrantConsent(ctx context.Context, sessionID string, metadata *SessionMetadata) (*Session, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	pp.applyConsent(ctx, session, metadata)
+	session.LastActivity = time.Now()
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "session_update", Data: session})
+	pp.inbox.addSession(session)
+	return session, nil
+}
+
+// applyConsent marks session consented and tracks what was skipped until
+// now.
+func (pp *PocketPing) applyConsent(ctx context.Context, session *Session, metadata *SessionMetadata) {
+	if !session.Consent {
+		now := time.Now()
+		session.Consent = true
+		session.ConsentAt = &now
+	}
+	if metadata != nil {
+		session.Metadata = pp.minimizeMetadata(metadata)
+	}
+	if region := pp.resolveRegion(ctx, session); region != "" {
+		session.Region = region
+	}
+}
@@ -0,0 +1,130 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func consentConfig() Config {
+	return Config{
+		RequireConsent:  true,
+		CountryRegions:  map[string]string{"FR": "eu"},
+		DefaultRegion:   "us",
+		TrackedElements: []TrackedElement{{Selector: "#pricing", Name: "clicked_pricing"}},
+	}
+}
+
+var trackedMetadata = SessionMetadata{
+	URL:      "https://example.com/pricing",
+	IP:       "198.51.100.7",
+	Country:  "FR",
+	Language: "fr",
+	Timezone: "Europe/Paris",
+}
+
+func TestHandleConnectWithoutConsent(t *testing.T) {
+	ctx := context.Background()
+	pp := New(consentConfig())
+	metadata := trackedMetadata
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &metadata})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if len(resp.TrackedElements) != 0 {
+		t.Errorf("TrackedElements = %v, want none", resp.TrackedElements)
+	}
+
+	session, _ := pp.GetSession(ctx, resp.SessionID)
+	if want := (SessionMetadata{Language: "fr", Timezone: "Europe/Paris"}); session.Metadata == nil || *session.Metadata != want {
+		t.Errorf("metadata = %+v, want %+v", session.Metadata, want)
+	}
+	if session.Region != "us" || session.Consent {
+		t.Errorf("Region = %q, Consent = %v", session.Region, session.Consent)
+	}
+
+	// Consent from the widget on the next connect upgrades the session.
+	resp, err = pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &metadata, Consent: true})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if len(resp.TrackedElements) != 1 {
+		t.Errorf("TrackedElements = %v after consent", resp.TrackedElements)
+	}
+	session, _ = pp.GetSession(ctx, resp.SessionID)
+	if !session.Consent || session.ConsentAt == nil || session.Region != "eu" || session.Metadata.URL != metadata.URL {
+		t.Errorf("session after consent = %+v", session)
+	}
+}
+
+func TestHandleConnectWithConsent(t *testing.T) {
+	ctx := context.Background()
+	pp := New(consentConfig())
+	metadata := trackedMetadata
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &metadata, Consent: true})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	session, _ := pp.GetSession(ctx, resp.SessionID)
+	if !session.Consent || session.Region != "eu" || *session.Metadata != trackedMetadata {
+		t.Errorf("session = %+v", session)
+	}
+}
+
+func TestGrantConsent(t *testing.T) {
+	ctx := context.Background()
+	pp := New(consentConfig())
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	metadata := trackedMetadata
+	session, err := pp.GrantConsent(ctx, sessionID, &metadata)
+	if err != nil {
+		t.Fatalf("GrantConsent: %v", err)
+	}
+	if !session.Consent || session.Region != "eu" || session.Metadata.IP != "198.51.100.7" {
+		t.Errorf("session = %+v", session)
+	}
+	if _, err := pp.GrantConsent(ctx, "missing", nil); err != ErrSessionNotFound {
+		t.Errorf("missing session: err = %v", err)
+	}
+}
+
+func TestWebhookMetadataWithoutConsent(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []WebhookPayload
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload WebhookPayload
+		_ = json.Unmarshal(body, &payload)
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	ctx := context.Background()
+	config := consentConfig()
+	config.WebhookURL = webhook.URL
+	pp := New(config)
+	metadata := trackedMetadata
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &metadata})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if err := pp.HandleCustomEvent(ctx, resp.SessionID, CustomEvent{Name: "clicked"}); err != nil {
+		t.Fatalf("HandleCustomEvent: %v", err)
+	}
+	if err := pp.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 1 || payloads[0].Session.Metadata != nil {
+		t.Errorf("payloads = %+v, want one without metadata", payloads)
+	}
+}
@@ -0,0 +1,141 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrContactRequestNotFound is returned by HandleContactSubmit when the
+// message doesn't carry a contact request of the session.
+var ErrContactRequestNotFound = newError("contact_request_not_found", http.StatusNotFound, "contact request not found")
+
+// DefaultContactPrompt is the text of a contact request sent without one.
+const DefaultContactPrompt = "Could you share your phone number so we can call you back?"
+
+// ContactRequestStatus is the state of a ContactRequest.
+type ContactRequestStatus string
+
+// Contact request statuses.
+const (
+	ContactRequestPending   ContactRequestStatus = "pending"
+	ContactRequestCompleted ContactRequestStatus = "completed"
+)
+
+// ContactRequest asks the visitor for a phone number. It rides on an
+// operator message (Message.ContactRequest); the widget shows it as a phone
+// input and posts the answer to HandleContactSubmit.
+type ContactRequest struct {
+	ID          string               `json:"id"`
+	Status      ContactRequestStatus `json:"status"`
+	CreatedAt   time.Time            `json:"createdAt"`
+	RespondedAt *time.Time           `json:"respondedAt,omitempty"`
+}
+
+// ContactSubmitRequest is the visitor's answer to a contact request.
+type ContactSubmitRequest struct {
+	SessionID string `json:"sessionId"`
+	// MessageID is the operator message carrying the contact request.
+	MessageID string `json:"messageId"`
+	// Phone is the number as typed; it is normalized to E.164.
+	Phone string `json:"phone"`
+	// PhoneCountry is the ISO 3166-1 alpha-2 country code (FR, US, ...)
+	// picked in the widget, used for numbers typed without a calling code.
+	PhoneCountry string `json:"phoneCountry,omitempty"`
+}
+
+// ContactSubmitResponse is the response after submitting a phone number.
+type ContactSubmitResponse struct {
+	OK bool `json:"ok"`
+}
+
+// RequestContact sends the visitor an operator message asking for their
+// phone number. An empty prompt uses DefaultContactPrompt.
+func (pp *PocketPing) RequestContact(ctx context.Context, sessionID, operatorName, prompt string) (*Message, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		prompt = DefaultContactPrompt
+	}
+	request := ContactRequest{
+		ID:        pp.generateID(),
+		Status:    ContactRequestPending,
+		CreatedAt: time.Now(),
+	}
+	response, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: prompt, Sender: SenderOperator, ContactRequest: &request})
+	if err != nil {
+		return nil, err
+	}
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("📱 %s asked for a phone number", takeoverName(operatorName)))
+
+	return pp.storage.GetMessage(ctx, response.MessageID)
+}
+
+// HandleContactSubmit handles the visitor's answer to a contact request. It
+// validates the number, stores it as the session's UserPhone and
+// UserPhoneCountry, marks the request completed and notifies the widget,
+// the operator consoles and the bridges. Answering again replaces the
+// number.
+func (pp *PocketPing) HandleContactSubmit(ctx context.Context, request ContactSubmitRequest) (*ContactSubmitResponse, error) {
+	session, err := pp.storage.GetSession(ctx, request.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	message, err := pp.storage.GetMessage(ctx, request.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.SessionID != session.ID || message.ContactRequest == nil {
+		return nil, ErrContactRequestNotFound
+	}
+
+	phone, country, err := parsePhone(request.Phone, request.PhoneCountry)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.UserPhone = phone
+	session.UserPhoneCountry = country
+	session.LastActivity = now
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	message.ContactRequest.Status = ContactRequestCompleted
+	message.ContactRequest.RespondedAt = &now
+	if storageWithBridge, ok := pp.storage.(StorageWithBridgeIDs); ok {
+		err = storageWithBridge.UpdateMessage(ctx, message)
+	} else {
+		err = pp.storage.SaveMessage(ctx, message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pp.BroadcastToSession(session.ID, WebSocketEvent{
+		Type: "contact_request_updated",
+		Data: map[string]interface{}{
+			"messageId":      message.ID,
+			"contactRequest": message.ContactRequest,
+		},
+	})
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "identity_update", Data: session})
+	pp.inbox.addSession(session)
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("📱 Visitor shared their phone number: %s", phone))
+
+	return &ContactSubmitResponse{OK: true}, nil
+}
@@ -0,0 +1,64 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRequestContactAndSubmit(t *testing.T) {
+	ctx := context.Background()
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{Bridges: []Bridge{bridge}})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	message, err := pp.RequestContact(ctx, session.ID, "Bob", "")
+	if err != nil {
+		t.Fatalf("RequestContact: %v", err)
+	}
+	if message.Content != DefaultContactPrompt || message.ContactRequest == nil || message.ContactRequest.Status != ContactRequestPending {
+		t.Fatalf("message = %+v", message)
+	}
+
+	for _, bad := range []ContactSubmitRequest{
+		{SessionID: session.ID, MessageID: message.ID, Phone: "0612345678"},
+		{SessionID: session.ID, MessageID: message.ID, Phone: "+33 6 12 34 56 78", PhoneCountry: "France"},
+		{SessionID: session.ID, MessageID: message.ID, Phone: "+33 6 12 ab"},
+	} {
+		if _, err := pp.HandleContactSubmit(ctx, bad); !errors.Is(err, ErrInvalidPhone) {
+			t.Errorf("HandleContactSubmit(%+v) = %v, want ErrInvalidPhone", bad, err)
+		}
+	}
+	other := sendVisitorMessage(t, pp, session.ID, "hi")
+	if _, err := pp.HandleContactSubmit(ctx, ContactSubmitRequest{SessionID: session.ID, MessageID: other, Phone: "+33612345678"}); !errors.Is(err, ErrContactRequestNotFound) {
+		t.Errorf("answering a plain message = %v, want ErrContactRequestNotFound", err)
+	}
+
+	if _, err := pp.HandleContactSubmit(ctx, ContactSubmitRequest{SessionID: session.ID, MessageID: message.ID, Phone: "+33 (6) 12-34-56-78", PhoneCountry: "fr"}); err != nil {
+		t.Fatalf("HandleContactSubmit: %v", err)
+	}
+
+	updated, _ := pp.GetSession(ctx, session.ID)
+	if updated.UserPhone != "+33612345678" || updated.UserPhoneCountry != "FR" {
+		t.Errorf("session phone = %q %q", updated.UserPhone, updated.UserPhoneCountry)
+	}
+	stored, _ := pp.storage.GetMessage(ctx, message.ID)
+	if stored.ContactRequest.Status != ContactRequestCompleted || stored.ContactRequest.RespondedAt == nil {
+		t.Errorf("contact request = %+v", stored.ContactRequest)
+	}
+	if types := conn.types(); types[len(types)-1] != "contact_request_updated" {
+		t.Errorf("widget events = %v", conn.types())
+	}
+	waitFor(t, "bridge notices", func() bool {
+		bridge.mu.Lock()
+		defer bridge.mu.Unlock()
+		return len(bridge.notices) == 2
+	})
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	if bridge.notices[0] != "📱 Bob asked for a phone number" || bridge.notices[1] != "📱 Visitor shared their phone number: +33612345678" {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+}
@@ -0,0 +1,286 @@
+package pocketping
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Config.ContextProvider lookups.
+const (
+	DefaultContextCacheTTL  = 5 * time.Minute
+	DefaultContextCacheSize = 10000
+	DefaultContextTimeout   = 2 * time.Second
+)
+
+var (
+	// ErrContextProviderNotConfigured is returned by GetCustomerContext when
+	// Config.ContextProvider is nil.
+	ErrContextProviderNotConfigured = newError("not_configured", http.StatusNotFound, "no context provider configured")
+	// ErrNoIdentity is returned by GetCustomerContext for a session whose
+	// visitor hasn't identified.
+	ErrNoIdentity = newError("no_identity", http.StatusConflict, "session has no identity")
+)
+
+// ContextOrder is a recent order shown in CustomerContext.
+type ContextOrder struct {
+	ID     string    `json:"id"`
+	Date   time.Time `json:"date"`
+	Total  string    `json:"total"`
+	Status string    `json:"status,omitempty"`
+	URL    string    `json:"url,omitempty"`
+}
+
+// ContextField is a labelled value shown in CustomerContext.
+type ContextField struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// CustomerContext is what operators see about a visitor's account: plan,
+// lifetime value, recent orders and any other fields.
+type CustomerContext struct {
+	Plan          string         `json:"plan,omitempty"`
+	LifetimeValue string         `json:"lifetimeValue,omitempty"`
+	RecentOrders  []ContextOrder `json:"recentOrders,omitempty"`
+	Fields        []ContextField `json:"fields,omitempty"`
+	// URL links to the customer in the source system (admin, CRM).
+	URL string `json:"url,omitempty"`
+}
+
+// ContextProvider fetches a visitor's account context from an external
+// system (shop, billing, CRM) by their identity. Return nil, nil for an
+// unknown customer.
+type ContextProvider interface {
+	FetchContext(ctx context.Context, identity *UserIdentity) (*CustomerContext, error)
+}
+
+// ContextCommandCallback is called when an operator runs /context in a
+// session's topic or thread. Typically calls PocketPing.PostCustomerContext.
+type ContextCommandCallback func(ctx context.Context, sessionID, operatorName, sourceBridge string)
+
+type contextCacheEntry struct {
+	identityID string
+	context    *CustomerContext
+	expires    time.Time
+}
+
+// contextCache caches CustomerContext by identity ID, in least recently
+// used order.
+type contextCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// GetCustomerContext returns the CustomerContext of a session's visitor from
+// Config.ContextProvider, cached for Config.ContextCacheTTL per identity.
+// It returns nil, nil when the provider doesn't know the visitor.
+func (pp *PocketPing) GetCustomerContext(ctx context.Context, sessionID string) (*CustomerContext, error) {
+	if pp.config.ContextProvider == nil {
+		return nil, ErrContextProviderNotConfigured
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	if session.Identity == nil {
+		return nil, ErrNoIdentity
+	}
+	return pp.customerContext(ctx, session.Identity)
+}
+
+// PostCustomerContext posts the visitor's CustomerContext to every bridge
+// serving the session, for the /context command.
+func (pp *PocketPing) PostCustomerContext(ctx context.Context, sessionID string) error {
+	customer, err := pp.GetCustomerContext(ctx, sessionID)
+	if err != nil && !errors.Is(err, ErrNoIdentity) {
+		return err
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	caption := "📇 No customer context: the visitor hasn't identified"
+	switch {
+	case session.Identity != nil && customer == nil:
+		caption = "📇 No customer context for " + identityLabel(session.Identity)
+	case customer != nil:
+		caption = FormatCustomerContext(customer)
+	}
+	pp.notifyBridgesNotice(ctx, session, caption)
+	return nil
+}
+
+// customerContext fetches and caches the context of identity, within
+// Config.ContextTimeout.
+func (pp *PocketPing) customerContext(ctx context.Context, identity *UserIdentity) (*CustomerContext, error) {
+	if customer, ok := pp.cachedContext(identity.ID); ok {
+		return customer, nil
+	}
+
+	timeout := pp.config.ContextTimeout
+	if timeout <= 0 {
+		timeout = DefaultContextTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	customer, err := pp.config.ContextProvider.FetchContext(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	pp.cacheContext(identity.ID, customer)
+	return customer, nil
+}
+
+// cachedContext returns the cached context of an identity, if still fresh.
+func (pp *PocketPing) cachedContext(identityID string) (*CustomerContext, bool) {
+	c := &pp.contextCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[identityID]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*contextCacheEntry)
+	if !time.Now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, identityID)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.context, true
+}
+
+// cacheContext caches the context of an identity for Config.ContextCacheTTL,
+// evicting the least recently used entries over Config.ContextCacheSize.
+func (pp *PocketPing) cacheContext(identityID string, customer *CustomerContext) {
+	ttl := pp.config.ContextCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultContextCacheTTL
+	}
+	size := pp.config.ContextCacheSize
+	if size <= 0 {
+		size = DefaultContextCacheSize
+	}
+	entry := &contextCacheEntry{identityID: identityID, context: customer, expires: time.Now().Add(ttl)}
+
+	c := &pp.contextCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+	if element, ok := c.entries[identityID]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[identityID] = c.order.PushFront(entry)
+	for c.order.Len() > size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*contextCacheEntry).identityID)
+	}
+}
+
+// contextNotice returns a function giving the caption appended to a
+// session's bridge notifications, fetched once on first call. It is empty
+// without a provider, an identity or a known customer; lookup errors are
+// logged, not fatal to the notification.
+func (pp *PocketPing) contextNotice(ctx context.Context, session *Session) func() string {
+	if pp.config.ContextProvider == nil || session.Identity == nil {
+		return func() string { return "" }
+	}
+	identity := session.Identity
+	return sync.OnceValue(func() string {
+		customer, err := pp.customerContext(context.WithoutCancel(ctx), identity)
+		if err != nil {
+			log.Printf("[PocketPing] Context lookup for session %s failed: %v", session.ID, err)
+			return ""
+		}
+		if customer == nil {
+			return ""
+		}
+		return FormatCustomerContext(customer)
+	})
+}
+
+// notifyCustomerContext posts the caption from contextNotice (or another
+// lazy caption) to b, when there is one and b supports plain notifications.
+func (pp *PocketPing) notifyCustomerContext(ctx context.Context, b Bridge, session *Session, caption func() string) {
+	notifier, ok := b.(BridgeWithNotify)
+	if !ok {
+		return
+	}
+	text := caption()
+	if text == "" {
+		return
+	}
+	if err := notifier.Notify(ctx, session, text); err != nil {
+		log.Printf("[PocketPing] Bridge %s notification failed: %v", b.Name(), err)
+	}
+}
+
+// FormatCustomerContext renders a CustomerContext as a plain-text bridge
+// notice.
+func FormatCustomerContext(customer *CustomerContext) string {
+	lines := []string{"📇 Customer context"}
+	if customer.Plan != "" {
+		lines = append(lines, "Plan: "+customer.Plan)
+	}
+	if customer.LifetimeValue != "" {
+		lines = append(lines, "Lifetime value: "+customer.LifetimeValue)
+	}
+	for _, field := range customer.Fields {
+		lines = append(lines, field.Label+": "+field.Value)
+	}
+	if len(customer.RecentOrders) > 0 {
+		lines = append(lines, "Recent orders:")
+		for _, order := range customer.RecentOrders {
+			line := fmt.Sprintf("• %s, %s, %s", order.ID, order.Date.Format("2006-01-02"), order.Total)
+			if order.Status != "" {
+				line += " (" + order.Status + ")"
+			}
+			if order.URL != "" {
+				line += " " + order.URL
+			}
+			lines = append(lines, line)
+		}
+	}
+	if customer.URL != "" {
+		lines = append(lines, customer.URL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func identityLabel(identity *UserIdentity) string {
+	if identity.Email != "" {
+		return identity.Email
+	}
+	if identity.Name != "" {
+		return identity.Name
+	}
+	return identity.ID
+}
+
+// isContextCommand recognises /context (also as /context@bot).
+func isContextCommand(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/context"
+}
@@ -0,0 +1,154 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeContextProvider struct {
+	calls    atomic.Int32
+	customer *CustomerContext
+	delay    time.Duration
+}
+
+func (f *fakeContextProvider) FetchContext(ctx context.Context, identity *UserIdentity) (*CustomerContext, error) {
+	f.calls.Add(1)
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if identity.ID != "cus_1" {
+		return nil, nil
+	}
+	return f.customer, nil
+}
+
+func TestCustomerContextOnNewSession(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeContextProvider{customer: &CustomerContext{
+		Plan:          "Pro",
+		LifetimeValue: "$1,240",
+		RecentOrders:  []ContextOrder{{ID: "#1042", Date: time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC), Total: "$89.00", Status: "shipped"}},
+	}}
+	first := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "first"}}
+	second := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "second"}}
+	pp := New(Config{ContextProvider: provider, Bridges: []Bridge{first, second}})
+
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "visitor-1", Identity: &UserIdentity{ID: "cus_1"}})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	pp.dispatcher.wait()
+	for _, bridge := range []*notifyRecordingBridge{first, second} {
+		if len(bridge.notices) != 1 || !strings.Contains(bridge.notices[0], "Plan: Pro") ||
+			!strings.Contains(bridge.notices[0], "• #1042, 2025-01-17, $89.00 (shipped)") {
+			t.Errorf("%s notices = %q", bridge.Name(), bridge.notices)
+		}
+	}
+	if provider.calls.Load() != 1 {
+		t.Errorf("provider calls = %d, want 1 (shared across bridges)", provider.calls.Load())
+	}
+
+	// Re-identifying as the same visitor doesn't repost; the cache serves /context.
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: resp.SessionID, Identity: &UserIdentity{ID: "cus_1", Name: "Jane"}}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	if err := pp.PostCustomerContext(ctx, resp.SessionID); err != nil {
+		t.Fatalf("PostCustomerContext: %v", err)
+	}
+	pp.dispatcher.wait()
+	if len(first.notices) != 2 || provider.calls.Load() != 1 {
+		t.Errorf("notices = %q, provider calls = %d", first.notices, provider.calls.Load())
+	}
+}
+
+func TestCustomerContextOnIdentify(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeContextProvider{customer: &CustomerContext{Plan: "Starter"}}
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{ContextProvider: provider, Bridges: []Bridge{bridge}})
+	session := newSession(ctx, t, pp)
+
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: session.ID, Identity: &UserIdentity{ID: "cus_1"}}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	pp.dispatcher.wait()
+	if len(bridge.notices) != 1 || !strings.Contains(bridge.notices[0], "Plan: Starter") {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+}
+
+func TestCustomerContextTimeout(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeContextProvider{customer: &CustomerContext{Plan: "Pro"}, delay: time.Second}
+	pp := New(Config{ContextProvider: provider, ContextTimeout: 10 * time.Millisecond})
+	session := newSession(ctx, t, pp)
+	pp.HandleIdentify(ctx, IdentifyRequest{SessionID: session.ID, Identity: &UserIdentity{ID: "cus_1"}})
+
+	if _, err := pp.GetCustomerContext(ctx, session.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCustomerContextCacheBounded(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeContextProvider{customer: &CustomerContext{Plan: "Pro"}}
+	pp := New(Config{ContextProvider: provider, ContextCacheSize: 2})
+
+	for _, id := range []string{"cus_1", "cus_2", "cus_1", "cus_3"} {
+		if _, err := pp.customerContext(ctx, &UserIdentity{ID: id}); err != nil {
+			t.Fatalf("customerContext(%s): %v", id, err)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 3 {
+		t.Errorf("calls = %d, want 3 (cus_1 cached once)", calls)
+	}
+	if size := len(pp.contextCache.entries); size != 2 {
+		t.Errorf("cache size = %d, want 2", size)
+	}
+	if _, ok := pp.cachedContext("cus_2"); ok {
+		t.Error("expected the least recently used identity to be evicted")
+	}
+
+	// Expired entries are dropped when looked up
+	pp.contextCache.entries["cus_1"].Value.(*contextCacheEntry).expires = time.Now()
+	if _, ok := pp.cachedContext("cus_1"); ok || len(pp.contextCache.entries) != 1 {
+		t.Errorf("expected the expired entry to be dropped, have %d", len(pp.contextCache.entries))
+	}
+}
+
+func TestGetCustomerContextErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := New(Config{}).GetCustomerContext(ctx, "s1"); !errors.Is(err, ErrContextProviderNotConfigured) {
+		t.Errorf("error = %v, want ErrContextProviderNotConfigured", err)
+	}
+	pp := New(Config{ContextProvider: &fakeContextProvider{}})
+	session := newSession(ctx, t, pp)
+	if _, err := pp.GetCustomerContext(ctx, session.ID); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("error = %v, want ErrNoIdentity", err)
+	}
+}
+
+func TestWebhookHandler_ContextCommand(t *testing.T) {
+	var got string
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		OnContextCommand: func(ctx context.Context, sessionID, operatorName, sourceBridge string) {
+			got = sessionID + " " + operatorName + " " + sourceBridge
+		},
+	})
+	payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"/context@pp_bot"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+	if got != "456 Bob telegram" {
+		t.Errorf("callback got %q", got)
+	}
+}
@@ -0,0 +1,80 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+)
+
+// CountSessions returns the number of sessions matching filter.
+//
+// Storage adapters implementing StorageWithCounts answer directly; otherwise
+// sessions are listed and counted (requires StorageWithListSessions).
+func (pp *PocketPing) CountSessions(ctx context.Context, filter SessionCountFilter) (int, error) {
+	if counter, ok := pp.storage.(StorageWithCounts); ok {
+		return counter.CountSessions(ctx, filter)
+	}
+	lister, ok := pp.storage.(StorageWithListSessions)
+	if !ok {
+		return 0, ErrCountUnsupported
+	}
+
+	sessions, err := lister.ListSessions(ctx, filter.CreatedSince)
+	if err != nil {
+		return 0, fmt.Errorf("list sessions: %w", err)
+	}
+	count := 0
+	for _, session := range sessions {
+		if filter.Matches(session) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountMessages returns the number of messages matching filter.
+//
+// Storage adapters implementing StorageWithCounts answer directly; otherwise
+// messages are paged through and counted. Without a SessionID filter that
+// fallback requires StorageWithListSessions.
+func (pp *PocketPing) CountMessages(ctx context.Context, filter MessageCountFilter) (int, error) {
+	if counter, ok := pp.storage.(StorageWithCounts); ok {
+		return counter.CountMessages(ctx, filter)
+	}
+
+	sessionIDs := []string{filter.SessionID}
+	if filter.SessionID == "" {
+		lister, ok := pp.storage.(StorageWithListSessions)
+		if !ok {
+			return 0, ErrCountUnsupported
+		}
+		sessions, err := lister.ListSessions(ctx, nil)
+		if err != nil {
+			return 0, fmt.Errorf("list sessions: %w", err)
+		}
+		sessionIDs = sessionIDs[:0]
+		for _, session := range sessions {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+
+	count := 0
+	for _, sessionID := range sessionIDs {
+		after := ""
+		for {
+			page, err := pp.storage.GetMessages(ctx, sessionID, after, snapshotPageSize)
+			if err != nil {
+				return 0, fmt.Errorf("get messages for %s: %w", sessionID, err)
+			}
+			for i := range page {
+				if filter.Matches(&page[i]) {
+					count++
+				}
+			}
+			if len(page) < snapshotPageSize {
+				break
+			}
+			after = page[len(page)-1].ID
+		}
+	}
+	return count, nil
+}
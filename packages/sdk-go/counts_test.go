@@ -0,0 +1,108 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countlessStorage exposes only ListSessions on top of the base Storage, to
+// exercise the CountSessions/CountMessages fallback.
+type countlessStorage struct {
+	Storage
+	mem *MemoryStorage
+}
+
+func (s countlessStorage) ListSessions(ctx context.Context, since *time.Time) ([]*Session, error) {
+	return s.mem.ListSessions(ctx, since)
+}
+
+func seedCountStorage(t *testing.T) *MemoryStorage {
+	t.Helper()
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	now := time.Now()
+
+	old := createTestSession("sess-old", "v1", nil, nil)
+	old.CreatedAt = now.Add(-48 * time.Hour)
+	old.LastActivity = now.Add(-48 * time.Hour)
+	_ = m.CreateSession(ctx, old)
+	_ = m.CreateSession(ctx, createTestSession("sess-1", "v1", nil, nil))
+	_ = m.CreateSession(ctx, createTestSession("sess-2", "v2", nil, nil))
+
+	_ = m.SaveMessage(ctx, createTestMessage("m1", "sess-1", "hi"))
+	reply := createTestMessage("m2", "sess-1", "hello")
+	reply.Sender = SenderOperator
+	_ = m.SaveMessage(ctx, reply)
+	deleted := createTestMessage("m3", "sess-1", "oops")
+	deletedAt := now
+	deleted.DeletedAt = &deletedAt
+	_ = m.SaveMessage(ctx, deleted)
+	_ = m.SaveMessage(ctx, createTestMessage("m4", "sess-2", "hey"))
+	return m
+}
+
+func TestCountSessionsAndMessages(t *testing.T) {
+	ctx := context.Background()
+	mem := seedCountStorage(t)
+	since := time.Now().Add(-time.Hour)
+
+	for name, storage := range map[string]Storage{
+		"StorageWithCounts": mem,
+		"fallback":          countlessStorage{Storage: mem, mem: mem},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pp := mockPocketPing(storage)
+
+			sessionCases := []struct {
+				filter SessionCountFilter
+				want   int
+			}{
+				{SessionCountFilter{}, 3},
+				{SessionCountFilter{CreatedSince: &since}, 2},
+				{SessionCountFilter{CreatedUntil: &since}, 1},
+				{SessionCountFilter{ActiveSince: &since, VisitorID: "v1"}, 1},
+			}
+			for _, tc := range sessionCases {
+				got, err := pp.CountSessions(ctx, tc.filter)
+				if err != nil || got != tc.want {
+					t.Errorf("CountSessions(%+v) = %d, %v; want %d", tc.filter, got, err, tc.want)
+				}
+			}
+
+			messageCases := []struct {
+				filter MessageCountFilter
+				want   int
+			}{
+				{MessageCountFilter{}, 3},
+				{MessageCountFilter{IncludeDeleted: true}, 4},
+				{MessageCountFilter{SessionID: "sess-1"}, 2},
+				{MessageCountFilter{Sender: SenderVisitor}, 2},
+				{MessageCountFilter{Since: &since, SessionID: "sess-2"}, 1},
+			}
+			for _, tc := range messageCases {
+				got, err := pp.CountMessages(ctx, tc.filter)
+				if err != nil || got != tc.want {
+					t.Errorf("CountMessages(%+v) = %d, %v; want %d", tc.filter, got, err, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCountUnsupported(t *testing.T) {
+	ctx := context.Background()
+	pp := mockPocketPing(statsLessStorage{})
+
+	if _, err := pp.CountSessions(ctx, SessionCountFilter{}); !errors.Is(err, ErrCountUnsupported) {
+		t.Errorf("expected ErrCountUnsupported, got %v", err)
+	}
+	if _, err := pp.CountMessages(ctx, MessageCountFilter{}); !errors.Is(err, ErrCountUnsupported) {
+		t.Errorf("expected ErrCountUnsupported, got %v", err)
+	}
+	// A single session can still be paged through.
+	if n, err := pp.CountMessages(ctx, MessageCountFilter{SessionID: "sess-1"}); err != nil || n != 0 {
+		t.Errorf("expected 0, nil; got %d, %v", n, err)
+	}
+}
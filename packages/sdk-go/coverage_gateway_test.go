@@ -203,7 +203,7 @@ func TestGatewayHandleMessageUpdate(t *testing.T) {
 				gotSession, gotID, gotContent, gotEdited = sid, bid, c, e
 			},
 		})
-		ts := "2026-01-01T00:00:00Z"
+		ts := time.Now().Add(-2 * time.Second).UTC().Format(time.RFC3339)
 		g.handleMessageUpdate(messageUpdatePayload{ID: "m1", ChannelID: "t1", Content: "edited", EditedTimestamp: ts})
 		if gotSession != "t1" || gotID != "m1" || gotContent != "edited" {
 			t.Errorf("got session=%q id=%q content=%q", gotSession, gotID, gotContent)
@@ -0,0 +1,201 @@
+package pocketping
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeTTL is how long MemoryDeduper remembers a delivery.
+const DefaultDedupeTTL = 10 * time.Minute
+
+// Deduper suppresses duplicate bridge notifications. A message is delivered
+// to a bridge only the first time MarkDelivered returns true for its
+// (messageID, bridgeKey) pair. The key of a BridgeWithDestination is its
+// Destination, so bridges posting to the same chat get each message once;
+// other bridges are keyed by name and position in Config.Bridges.
+//
+// The default MemoryDeduper only sees one process. To dedupe across
+// processes (e.g. an SDK TelegramBridge and a bridge-server Telegram bridge
+// pointed at the same chat), use a RedisDeduper on both sides.
+type Deduper interface {
+	// MarkDelivered records the delivery and reports whether it is the first.
+	MarkDelivered(ctx context.Context, messageID, bridgeKey string) bool
+}
+
+// BridgeWithDestination is implemented by bridges that can name the chat or
+// channel they post to. The built-in bridges and the bridge-server's do.
+type BridgeWithDestination interface {
+	Bridge
+	// Destination returns BridgeDestination of the bridge's platform and
+	// chat, channel or webhook URL.
+	Destination() string
+}
+
+// BridgeDestination returns the dedupe key of a platform's chat, channel or
+// webhook URL. The target is hashed, so keys don't carry webhook secrets.
+func BridgeDestination(platform, target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return platform + ":" + hex.EncodeToString(sum[:8])
+}
+
+// bridgeDedupeKey returns the Deduper key of b, the i-th bridge.
+func bridgeDedupeKey(i int, b Bridge) string {
+	if d, ok := b.(BridgeWithDestination); ok {
+		if destination := d.Destination(); destination != "" {
+			return destination
+		}
+	}
+	return b.Name() + "#" + strconv.Itoa(i)
+}
+
+type dedupeKey struct {
+	messageID string
+	bridgeKey string
+}
+
+// MemoryDeduper is an in-memory Deduper whose entries expire after a TTL.
+type MemoryDeduper struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[dedupeKey]time.Time
+	sweepAt time.Time
+}
+
+// NewMemoryDeduper creates an in-memory deduper. A ttl <= 0 uses
+// DefaultDedupeTTL.
+func NewMemoryDeduper(ttl time.Duration) *MemoryDeduper {
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	return &MemoryDeduper{
+		ttl:     ttl,
+		entries: make(map[dedupeKey]time.Time),
+	}
+}
+
+// MarkDelivered implements Deduper.
+func (d *MemoryDeduper) MarkDelivered(ctx context.Context, messageID, bridgeKey string) bool {
+	if messageID == "" {
+		return true
+	}
+
+	now := time.Now()
+	key := dedupeKey{messageID: messageID, bridgeKey: bridgeKey}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Sweep expired entries at most once per TTL to keep the map bounded.
+	if now.After(d.sweepAt) {
+		for k, expires := range d.entries {
+			if now.After(expires) {
+				delete(d.entries, k)
+			}
+		}
+		d.sweepAt = now.Add(d.ttl)
+	}
+
+	if expires, ok := d.entries[key]; ok && now.Before(expires) {
+		return false
+	}
+	d.entries[key] = now.Add(d.ttl)
+	return true
+}
+
+// alreadyNotified reports whether a visitor message was notified to b, the
+// i-th bridge, before, per the storage's persisted cursor (see
+// StorageWithNotifyCursors): it is the last notified message, or older than
+// it.
+func (pp *PocketPing) alreadyNotified(ctx context.Context, message *Message, i int, b Bridge) bool {
+	cursors, ok := pp.storage.(StorageWithNotifyCursors)
+	if !ok {
+		return false
+	}
+	lastID, err := cursors.GetLastNotified(ctx, message.SessionID, bridgeDedupeKey(i, b))
+	if err != nil || lastID == "" {
+		return false
+	}
+	if lastID == message.ID {
+		return true
+	}
+	last, err := pp.storage.GetMessage(ctx, lastID)
+	return err == nil && last != nil && !message.Timestamp.After(last.Timestamp)
+}
+
+// recordNotified advances the persisted cursor of b, the i-th bridge, to
+// message.
+func (pp *PocketPing) recordNotified(ctx context.Context, message *Message, i int, b Bridge) {
+	cursors, ok := pp.storage.(StorageWithNotifyCursors)
+	if !ok {
+		return
+	}
+	if err := cursors.SetLastNotified(ctx, message.SessionID, bridgeDedupeKey(i, b), message.ID); err != nil {
+		log.Printf("[PocketPing] Failed to record notification of message %s to bridge %s: %v", message.ID, b.Name(), err)
+	}
+}
+
+// RedisDeduper is a Deduper over Redis, shared by every SDK instance and
+// bridge-server using the same server. Deliveries are remembered for the
+// TTL. When Redis can't be reached, messages are delivered.
+type RedisDeduper struct {
+	redisClient
+	ttl time.Duration
+}
+
+// NewRedisDeduper creates a deduper for the Redis server at redisURL
+// ("redis://[user:password@]host[:port]", or "rediss://" for TLS). A ttl
+// <= 0 uses DefaultDedupeTTL.
+func NewRedisDeduper(redisURL string, ttl time.Duration) (*RedisDeduper, error) {
+	endpoint, err := parseRedisURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	return &RedisDeduper{redisClient: redisClient{redisEndpoint: endpoint}, ttl: ttl}, nil
+}
+
+// MarkDelivered implements Deduper with SET NX.
+func (d *RedisDeduper) MarkDelivered(ctx context.Context, messageID, bridgeKey string) bool {
+	if messageID == "" {
+		return true
+	}
+	key := fmt.Sprintf("pocketping:dedupe:%s:%s", bridgeKey, messageID)
+	reply, err := d.do(ctx, "SET", key, "1", "NX", "PX", strconv.FormatInt(d.ttl.Milliseconds(), 10))
+	if err != nil {
+		log.Printf("[PocketPing] Redis dedupe of message %s failed, delivering: %v", messageID, err)
+		return true
+	}
+	// A null reply means the key was already set
+	bulk, isBulk := reply.([]byte)
+	return !isBulk || bulk != nil
+}
+
+// Ensure MemoryDeduper implements Deduper interface
+var _ Deduper = (*MemoryDeduper)(nil)
+
+// Ensure RedisDeduper implements Deduper interface
+var _ Deduper = (*RedisDeduper)(nil)
+
+// OverlappingBridges returns the destinations (see BridgeWithDestination)
+// more than one bridge posts to. Only one of those bridges gets each
+// message; usually the overlap is a misconfiguration.
+func OverlappingBridges(bridges []Bridge) []string {
+	counts := make(map[string]int, len(bridges))
+	var overlapping []string
+	for i, bridge := range bridges {
+		key := bridgeDedupeKey(i, bridge)
+		counts[key]++
+		if counts[key] == 2 {
+			overlapping = append(overlapping, key)
+		}
+	}
+	return overlapping
+}
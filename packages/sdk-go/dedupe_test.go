@@ -0,0 +1,192 @@
+package pocketping
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryDeduper(t *testing.T) {
+	ctx := context.Background()
+	d := NewMemoryDeduper(50 * time.Millisecond)
+
+	if !d.MarkDelivered(ctx, "msg-1", "telegram") {
+		t.Fatal("expected first delivery to pass")
+	}
+	if d.MarkDelivered(ctx, "msg-1", "telegram") {
+		t.Error("expected duplicate delivery to be suppressed")
+	}
+	if !d.MarkDelivered(ctx, "msg-1", "slack") {
+		t.Error("expected delivery to a different bridge to pass")
+	}
+	if !d.MarkDelivered(ctx, "", "telegram") || !d.MarkDelivered(ctx, "", "telegram") {
+		t.Error("expected messages without ID never to be suppressed")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !d.MarkDelivered(ctx, "msg-1", "telegram") {
+		t.Error("expected delivery to pass again after TTL")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.entries) != 1 {
+		t.Errorf("expected expired entries to be swept, got %d", len(d.entries))
+	}
+}
+
+type countingBridge struct {
+	BaseBridge
+	mu    sync.Mutex
+	count int
+}
+
+func (b *countingBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count++
+	return nil
+}
+
+// destinationBridge is a countingBridge posting to a destination.
+type destinationBridge struct {
+	countingBridge
+	destination string
+}
+
+func (b *destinationBridge) Destination() string {
+	return b.destination
+}
+
+func TestNotifyBridgesSuppressesDuplicates(t *testing.T) {
+	first := &destinationBridge{countingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}, BridgeDestination("telegram", "-100")}
+	second := &destinationBridge{countingBridge{BaseBridge: BaseBridge{BridgeName: "telegram-copy"}}, BridgeDestination("telegram", "-100")}
+	other := &destinationBridge{countingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}, BridgeDestination("telegram", "-200")}
+	unnamed := &countingBridge{BaseBridge: BaseBridge{BridgeName: "custom"}}
+	twin := &countingBridge{BaseBridge: BaseBridge{BridgeName: "custom"}}
+	pp := New(Config{Bridges: []Bridge{first, second, other, unnamed, twin}})
+	ctx := context.Background()
+
+	session := createTestSession("sess-1", "v1", nil, nil)
+	msg := createTestMessage("msg-1", "sess-1", "hello")
+	pp.notifyBridgesMessage(ctx, msg, session)
+	pp.notifyBridgesMessage(ctx, msg, session)
+	pp.dispatcher.wait()
+
+	if total := first.count + second.count; total != 1 {
+		t.Errorf("expected one delivery to chat -100, got %d", total)
+	}
+	if other.count != 1 {
+		t.Errorf("expected one delivery to chat -200, got %d", other.count)
+	}
+	if unnamed.count != 1 || twin.count != 1 {
+		t.Errorf("bridges sharing a name got %d and %d deliveries, want 1 each", unnamed.count, twin.count)
+	}
+}
+
+func TestOverlappingBridges(t *testing.T) {
+	bridges := []Bridge{
+		&destinationBridge{countingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}, "telegram:a"},
+		&BaseBridge{BridgeName: "slack"},
+		&BaseBridge{BridgeName: "slack"},
+		&destinationBridge{countingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}, "telegram:a"},
+		&destinationBridge{countingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}, "telegram:a"},
+	}
+	got := OverlappingBridges(bridges)
+	if len(got) != 1 || got[0] != "telegram:a" {
+		t.Errorf("expected [telegram:a], got %v", got)
+	}
+	if got := OverlappingBridges(bridges[:3]); len(got) != 0 {
+		t.Errorf("expected no overlap, got %v", got)
+	}
+}
+
+func TestBridgeDestination(t *testing.T) {
+	telegram, _ := NewTelegramBridge("token", "-100")
+	if telegram.Destination() != BridgeDestination("telegram", "-100") {
+		t.Errorf("Destination = %q", telegram.Destination())
+	}
+	hook := "https://hooks.slack.com/services/T0/B0/secret"
+	if key := BridgeDestination("slack", hook); !strings.HasPrefix(key, "slack:") || strings.Contains(key, "secret") {
+		t.Errorf("BridgeDestination = %q", key)
+	}
+	if BridgeDestination("slack", "C1") == BridgeDestination("slack", "C2") {
+		t.Error("different channels share a key")
+	}
+}
+
+func TestRedisDeduper(t *testing.T) {
+	ctx := context.Background()
+	addr := fakeRedis(t)
+	sdk, err := NewRedisDeduper("redis://"+addr, time.Minute)
+	if err != nil {
+		t.Fatalf("NewRedisDeduper: %v", err)
+	}
+	defer sdk.Close()
+	bridgeServer, _ := NewRedisDeduper("redis://"+addr, 0)
+	defer bridgeServer.Close()
+
+	key := BridgeDestination("telegram", "-100")
+	if !sdk.MarkDelivered(ctx, "msg-1", key) {
+		t.Fatal("expected first delivery to pass")
+	}
+	if bridgeServer.MarkDelivered(ctx, "msg-1", key) {
+		t.Error("expected the other process's duplicate to be suppressed")
+	}
+	if !bridgeServer.MarkDelivered(ctx, "msg-1", BridgeDestination("slack", "C1")) {
+		t.Error("expected delivery to another destination to pass")
+	}
+
+	down, _ := NewRedisDeduper("redis://127.0.0.1:1", 0)
+	if !down.MarkDelivered(ctx, "msg-1", key) {
+		t.Error("expected delivery when Redis is down")
+	}
+	if _, err := NewRedisDeduper("http://localhost", 0); err == nil {
+		t.Error("expected error for http scheme")
+	}
+}
+
+func TestNotifyCursorSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.log")
+	storage, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("NewPersistentMemoryStorage: %v", err)
+	}
+	bridge := NewMockBridge("telegram")
+	pp := New(Config{Storage: storage, Bridges: []Bridge{bridge}})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "first")
+	sendVisitorMessage(t, pp, session.ID, "second")
+	pp.dispatcher.wait()
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A new process with a fresh deduper replays the recent messages.
+	reopened, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	restarted := NewMockBridge("telegram")
+	pp = New(Config{Storage: reopened, Bridges: []Bridge{restarted}})
+	messages, err := reopened.GetMessages(ctx, session.ID, "", 10)
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("GetMessages = %d, %v", len(messages), err)
+	}
+	stored, _ := reopened.GetSession(ctx, session.ID)
+	for i := range messages {
+		pp.notifyBridgesMessage(ctx, &messages[i], stored)
+	}
+	sendVisitorMessage(t, pp, session.ID, "third")
+	pp.dispatcher.wait()
+
+	restarted.mu.Lock()
+	defer restarted.mu.Unlock()
+	if len(restarted.VisitorMsgCalls) != 1 || restarted.VisitorMsgCalls[0].Content != "third" {
+		t.Errorf("after restart, bridge got %d messages, want only the new one", len(restarted.VisitorMsgCalls))
+	}
+}
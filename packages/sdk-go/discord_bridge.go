@@ -20,6 +20,7 @@ type DiscordWebhookBridge struct {
 	AvatarURL  string
 
 	httpClient *http.Client
+	dryRun     bool
 	pp         *PocketPing
 }
 
@@ -47,6 +48,14 @@ func WithDiscordWebhookHTTPClient(client *http.Client) DiscordWebhookOption {
 	}
 }
 
+// WithDiscordWebhookDryRun logs every outgoing Discord payload instead of calling the Discord
+// API, for staging environments (see NewDryRunTransport).
+func WithDiscordWebhookDryRun() DiscordWebhookOption {
+	return func(d *DiscordWebhookBridge) {
+		d.dryRun = true
+	}
+}
+
 // NewDiscordWebhookBridge creates a new Discord webhook bridge.
 // Returns an error if configuration is invalid.
 func NewDiscordWebhookBridge(webhookURL string, opts ...DiscordWebhookOption) (*DiscordWebhookBridge, error) {
@@ -68,6 +77,9 @@ func NewDiscordWebhookBridge(webhookURL string, opts ...DiscordWebhookOption) (*
 	for _, opt := range opts {
 		opt(d)
 	}
+	if d.dryRun {
+		d.httpClient = dryRunClient(d.Name(), d.httpClient)
+	}
 
 	return d, nil
 }
@@ -81,9 +93,18 @@ func MustNewDiscordWebhookBridge(webhookURL string, opts ...DiscordWebhookOption
 	return d
 }
 
+// Destination returns the dedupe key of the bridge's webhook.
+func (d *DiscordWebhookBridge) Destination() string {
+	return BridgeDestination("discord", d.WebhookURL)
+}
+
 // Init initializes the Discord webhook bridge.
 func (d *DiscordWebhookBridge) Init(ctx context.Context, pp *PocketPing) error {
 	d.pp = pp
+	if pp != nil && pp.config.DryRun && !d.dryRun {
+		d.dryRun = true
+		d.httpClient = dryRunClient(d.Name(), d.httpClient)
+	}
 	return nil
 }
 
@@ -121,23 +142,20 @@ func (d *DiscordWebhookBridge) OnNewSession(ctx context.Context, session *Sessio
 	}
 
 	_, err := d.sendWebhookMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordWebhookBridge] OnNewSession error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnVisitorMessage sends a notification when a visitor sends a message.
 func (d *DiscordWebhookBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
 	visitorName := d.getVisitorName(session)
-	content := fmt.Sprintf("💬 %s:\n%s", visitorName, message.Content)
+	content := fmt.Sprintf("💬 %s:\n%s", visitorName, message.Content+locationText(message.Location))
 
 	// Note: Discord webhooks don't return message IDs in a way that allows editing
 	// For full edit/delete support, use DiscordBotBridge instead
 	var replyToMessageID string
-	if message.ReplyTo != "" && d.pp != nil {
+	if replyTarget(message) != "" && d.pp != nil {
 		if storage, ok := d.pp.GetStorage().(StorageWithBridgeIDs); ok {
-			bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, message.ReplyTo)
+			bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, replyTarget(message))
 			if err == nil && bridgeIDs != nil && bridgeIDs.DiscordMessageID != "" {
 				replyToMessageID = bridgeIDs.DiscordMessageID
 			}
@@ -146,8 +164,7 @@ func (d *DiscordWebhookBridge) OnVisitorMessage(ctx context.Context, message *Me
 
 	result, err := d.sendWebhookMessage(ctx, content, replyToMessageID)
 	if err != nil {
-		log.Printf("[DiscordWebhookBridge] OnVisitorMessage error: %v", err)
-		return nil
+		return err
 	}
 
 	if result != nil && result.DiscordMessageID != "" && d.pp != nil {
@@ -162,6 +179,10 @@ func (d *DiscordWebhookBridge) OnVisitorMessage(ctx context.Context, message *Me
 
 // OnOperatorMessage is called when an operator sends a message.
 func (d *DiscordWebhookBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return d.OnAIMessage(ctx, message, session)
+	}
+
 	if sourceBridge == d.Name() {
 		return nil
 	}
@@ -171,13 +192,24 @@ func (d *DiscordWebhookBridge) OnOperatorMessage(ctx context.Context, message *M
 		name = "Operator"
 	}
 
-	content := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content)
+	content := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
 
 	_, err := d.sendWebhookMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordWebhookBridge] OnOperatorMessage error: %v", err)
-	}
-	return nil
+	return err
+}
+
+// Notify posts a plain one-line notice.
+func (d *DiscordWebhookBridge) Notify(ctx context.Context, session *Session, message string) error {
+	_, err := d.sendWebhookMessage(ctx, message, "")
+	return err
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (d *DiscordWebhookBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	content := fmt.Sprintf("🤖 %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+
+	_, err := d.sendWebhookMessage(ctx, content, "")
+	return err
 }
 
 // OnTyping is called when visitor starts/stops typing.
@@ -204,10 +236,7 @@ func (d *DiscordWebhookBridge) OnCustomEvent(ctx context.Context, event CustomEv
 	}
 
 	_, err := d.sendWebhookMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordWebhookBridge] OnCustomEvent error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnIdentityUpdate is called when a user identifies themselves.
@@ -221,17 +250,14 @@ func (d *DiscordWebhookBridge) OnIdentityUpdate(ctx context.Context, session *Se
 		content += fmt.Sprintf("\n📛 Name: %s", session.Identity.Name)
 	}
 	if session.Identity.Email != "" {
-		content += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email)
+		content += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email+disposableEmailNote(session))
 	}
 	if session.UserPhone != "" {
 		content += fmt.Sprintf("\n📱 Phone: %s", session.UserPhone)
 	}
 
 	_, err := d.sendWebhookMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordWebhookBridge] OnIdentityUpdate error: %v", err)
-	}
-	return nil
+	return err
 }
 
 type discordWebhookPayload struct {
@@ -311,6 +337,15 @@ func (d *DiscordWebhookBridge) getVisitorName(session *Session) string {
 // Ensure DiscordWebhookBridge implements Bridge interface
 var _ Bridge = (*DiscordWebhookBridge)(nil)
 
+// Ensure DiscordWebhookBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*DiscordWebhookBridge)(nil)
+
+// Ensure DiscordWebhookBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*DiscordWebhookBridge)(nil)
+
+// Ensure DiscordWebhookBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*DiscordWebhookBridge)(nil)
+
 // DiscordBotBridge sends notifications to Discord using a bot token.
 // This supports full edit/delete functionality.
 type DiscordBotBridge struct {
@@ -319,6 +354,7 @@ type DiscordBotBridge struct {
 	ChannelID string
 
 	httpClient *http.Client
+	dryRun     bool
 	pp         *PocketPing
 }
 
@@ -332,6 +368,14 @@ func WithDiscordBotHTTPClient(client *http.Client) DiscordBotOption {
 	}
 }
 
+// WithDiscordBotDryRun logs every outgoing Discord payload instead of calling the Discord
+// API, for staging environments (see NewDryRunTransport).
+func WithDiscordBotDryRun() DiscordBotOption {
+	return func(d *DiscordBotBridge) {
+		d.dryRun = true
+	}
+}
+
 // NewDiscordBotBridge creates a new Discord bot bridge.
 func NewDiscordBotBridge(botToken, channelID string, opts ...DiscordBotOption) *DiscordBotBridge {
 	d := &DiscordBotBridge{
@@ -344,13 +388,25 @@ func NewDiscordBotBridge(botToken, channelID string, opts ...DiscordBotOption) *
 	for _, opt := range opts {
 		opt(d)
 	}
+	if d.dryRun {
+		d.httpClient = dryRunClient(d.Name(), d.httpClient)
+	}
 
 	return d
 }
 
+// Destination returns the dedupe key of the bridge's channel.
+func (d *DiscordBotBridge) Destination() string {
+	return BridgeDestination("discord", d.ChannelID)
+}
+
 // Init initializes the Discord bot bridge.
 func (d *DiscordBotBridge) Init(ctx context.Context, pp *PocketPing) error {
 	d.pp = pp
+	if pp != nil && pp.config.DryRun && !d.dryRun {
+		d.dryRun = true
+		d.httpClient = dryRunClient(d.Name(), d.httpClient)
+	}
 	return nil
 }
 
@@ -388,21 +444,18 @@ func (d *DiscordBotBridge) OnNewSession(ctx context.Context, session *Session) e
 	}
 
 	_, err := d.sendMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordBotBridge] OnNewSession error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnVisitorMessage sends a notification when a visitor sends a message.
 func (d *DiscordBotBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
 	visitorName := d.getVisitorName(session)
-	content := fmt.Sprintf("💬 %s:\n%s", visitorName, message.Content)
+	content := fmt.Sprintf("💬 %s:\n%s", visitorName, message.Content+locationText(message.Location))
 
 	var replyToMessageID string
-	if message.ReplyTo != "" && d.pp != nil {
+	if replyTarget(message) != "" && d.pp != nil {
 		if storage, ok := d.pp.GetStorage().(StorageWithBridgeIDs); ok {
-			bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, message.ReplyTo)
+			bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, replyTarget(message))
 			if err == nil && bridgeIDs != nil && bridgeIDs.DiscordMessageID != "" {
 				replyToMessageID = bridgeIDs.DiscordMessageID
 			}
@@ -411,8 +464,7 @@ func (d *DiscordBotBridge) OnVisitorMessage(ctx context.Context, message *Messag
 
 	result, err := d.sendMessage(ctx, content, replyToMessageID)
 	if err != nil {
-		log.Printf("[DiscordBotBridge] OnVisitorMessage error: %v", err)
-		return nil
+		return err
 	}
 
 	// Save bridge message ID for edit/delete support
@@ -429,6 +481,10 @@ func (d *DiscordBotBridge) OnVisitorMessage(ctx context.Context, message *Messag
 
 // OnOperatorMessage is called when an operator sends a message.
 func (d *DiscordBotBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return d.OnAIMessage(ctx, message, session)
+	}
+
 	if sourceBridge == d.Name() {
 		return nil
 	}
@@ -438,13 +494,24 @@ func (d *DiscordBotBridge) OnOperatorMessage(ctx context.Context, message *Messa
 		name = "Operator"
 	}
 
-	content := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content)
+	content := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
 
 	_, err := d.sendMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordBotBridge] OnOperatorMessage error: %v", err)
-	}
-	return nil
+	return err
+}
+
+// Notify posts a plain one-line notice.
+func (d *DiscordBotBridge) Notify(ctx context.Context, session *Session, message string) error {
+	_, err := d.sendMessage(ctx, message, "")
+	return err
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (d *DiscordBotBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	content := fmt.Sprintf("🤖 %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+
+	_, err := d.sendMessage(ctx, content, "")
+	return err
 }
 
 // OnTyping sends a typing indicator.
@@ -453,11 +520,7 @@ func (d *DiscordBotBridge) OnTyping(ctx context.Context, sessionID string, isTyp
 		return nil
 	}
 
-	err := d.triggerTyping(ctx)
-	if err != nil {
-		log.Printf("[DiscordBotBridge] OnTyping error: %v", err)
-	}
-	return nil
+	return d.triggerTyping(ctx)
 }
 
 // OnMessageRead is called when messages are marked as read.
@@ -478,10 +541,7 @@ func (d *DiscordBotBridge) OnCustomEvent(ctx context.Context, event CustomEvent,
 	}
 
 	_, err := d.sendMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordBotBridge] OnCustomEvent error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnIdentityUpdate is called when a user identifies themselves.
@@ -495,17 +555,14 @@ func (d *DiscordBotBridge) OnIdentityUpdate(ctx context.Context, session *Sessio
 		content += fmt.Sprintf("\n📛 Name: %s", session.Identity.Name)
 	}
 	if session.Identity.Email != "" {
-		content += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email)
+		content += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email+disposableEmailNote(session))
 	}
 	if session.UserPhone != "" {
 		content += fmt.Sprintf("\n📱 Phone: %s", session.UserPhone)
 	}
 
 	_, err := d.sendMessage(ctx, content, "")
-	if err != nil {
-		log.Printf("[DiscordBotBridge] OnIdentityUpdate error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnMessageEdit handles message edits.
@@ -526,8 +583,7 @@ func (d *DiscordBotBridge) OnMessageEdit(ctx context.Context, sessionID, message
 
 	err = d.editMessage(ctx, bridgeIDs.DiscordMessageID, content+" (edited)")
 	if err != nil {
-		log.Printf("[DiscordBotBridge] OnMessageEdit error: %v", err)
-		return nil, nil
+		return nil, err
 	}
 
 	return &BridgeMessageResult{
@@ -552,10 +608,7 @@ func (d *DiscordBotBridge) OnMessageDelete(ctx context.Context, sessionID, messa
 	}
 
 	err = d.deleteMessage(ctx, bridgeIDs.DiscordMessageID)
-	if err != nil {
-		log.Printf("[DiscordBotBridge] OnMessageDelete error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // Discord API helpers
@@ -572,7 +625,50 @@ type discordMessage struct {
 }
 
 func (d *DiscordBotBridge) sendMessage(ctx context.Context, content string, replyToMessageID string) (*BridgeMessageResult, error) {
-	apiURL := fmt.Sprintf("%s/channels/%s/messages", discordAPIBase, d.ChannelID)
+	return d.sendMessageTo(ctx, d.ChannelID, content, replyToMessageID)
+}
+
+// SendDirectMessage sends text to an operator in a DM with the bot, opening
+// the DM channel first. The operator must share a server with the bot.
+func (d *DiscordBotBridge) SendDirectMessage(ctx context.Context, userID, text string) error {
+	body, err := json.Marshal(map[string]string{"recipient_id": userID})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", discordAPIBase+"/users/@me/channels", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+d.BotToken)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("discord error: %s (status %d)", string(respBody), resp.StatusCode)
+	}
+
+	var channel struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &channel); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	_, err = d.sendMessageTo(ctx, channel.ID, text, "")
+	return err
+}
+
+// sendMessageTo posts a message to a channel.
+func (d *DiscordBotBridge) sendMessageTo(ctx context.Context, channelID, content string, replyToMessageID string) (*BridgeMessageResult, error) {
+	apiURL := fmt.Sprintf("%s/channels/%s/messages", discordAPIBase, channelID)
 
 	payload := discordMessagePayload{Content: content}
 	if replyToMessageID != "" {
@@ -699,5 +795,17 @@ func (d *DiscordBotBridge) getVisitorName(session *Session) string {
 // Ensure DiscordBotBridge implements Bridge interface
 var _ Bridge = (*DiscordBotBridge)(nil)
 
+// Ensure DiscordBotBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*DiscordBotBridge)(nil)
+
+// Ensure DiscordBotBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*DiscordBotBridge)(nil)
+
+// Ensure DiscordBotBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*DiscordBotBridge)(nil)
+
 // Ensure DiscordBotBridge implements BridgeWithEditDelete interface
 var _ BridgeWithEditDelete = (*DiscordBotBridge)(nil)
+
+// Ensure DiscordBotBridge implements BridgeWithDirectMessages interface
+var _ BridgeWithDirectMessages = (*DiscordBotBridge)(nil)
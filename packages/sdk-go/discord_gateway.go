@@ -40,6 +40,13 @@ type DiscordGatewayConfig struct {
 	OnOperatorMessageWithIDs func(ctx context.Context, sessionID, content, operatorName string, attachments []Attachment, replyToBridgeMessageID *int, bridgeMessageID string)
 	OnOperatorMessageEdit    func(ctx context.Context, sessionID, bridgeMessageID, content string, editedAt time.Time)
 	OnOperatorMessageDelete  func(ctx context.Context, sessionID, bridgeMessageID string, deletedAt time.Time)
+	// OnWhisper receives whispers ("// text" or /whisper text), which stay
+	// between operators
+	OnWhisper WhisperCallback
+	// ClockSkew normalizes the platform times passed to
+	// OnOperatorMessageEdit, whose context holds the original (see
+	// PlatformTime). Defaults to a detector with DefaultClockSkewThreshold.
+	ClockSkew *ClockSkewDetector
 }
 
 // DiscordGateway manages a persistent WebSocket connection to Discord Gateway
@@ -101,6 +108,7 @@ type messageCreatePayload struct {
 	Author          discordUser       `json:"author"`
 	Attachments     []discordAttachment `json:"attachments"`
 	MessageReference *messageReference `json:"message_reference,omitempty"`
+	Mentions        []discordUser     `json:"mentions,omitempty"`
 }
 
 type messageUpdatePayload struct {
@@ -109,6 +117,7 @@ type messageUpdatePayload struct {
 	Content        string       `json:"content,omitempty"`
 	EditedTimestamp string      `json:"edited_timestamp,omitempty"`
 	Author         *discordUser `json:"author,omitempty"`
+	Mentions       []discordUser `json:"mentions,omitempty"`
 }
 
 type messageDeletePayload struct {
@@ -118,9 +127,18 @@ type messageDeletePayload struct {
 }
 
 type discordUser struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Bot      bool   `json:"bot"`
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
+	Bot        bool   `json:"bot"`
+}
+
+// displayName is the user's display name, or their username.
+func (u discordUser) displayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
 }
 
 type discordAttachment struct {
@@ -139,6 +157,9 @@ type messageReference struct {
 
 // NewDiscordGateway creates a new Discord Gateway instance
 func NewDiscordGateway(config DiscordGatewayConfig) *DiscordGateway {
+	if config.ClockSkew == nil {
+		config.ClockSkew = NewClockSkewDetector(0)
+	}
 	return &DiscordGateway{
 		config:     config,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
@@ -330,6 +351,13 @@ func (g *DiscordGateway) handleMessage(msg messageCreatePayload) {
 	// We should check if the parent is our forum channel
 	// For now, we process all non-bot messages
 
+	if content, ok := parseWhisper(msg.Content); ok {
+		if content != "" && g.config.OnWhisper != nil {
+			g.config.OnWhisper(context.Background(), msg.ChannelID, msg.Author.Username, "discord", ResolveDiscordMentions(content, discordUserNames(msg.Mentions)))
+		}
+		return
+	}
+
 	if g.config.OnOperatorMessage == nil && g.config.OnOperatorMessageWithIDs == nil {
 		return
 	}
@@ -356,12 +384,14 @@ func (g *DiscordGateway) handleMessage(msg messageCreatePayload) {
 	// For Discord, we'll pass nil since the ID is a string snowflake, not int
 	// The backend will need to handle this differently
 
+	content := ResolveDiscordMentions(msg.Content, discordUserNames(msg.Mentions))
+
 	// Call the callback
 	if g.config.OnOperatorMessage != nil {
 		g.config.OnOperatorMessage(
 			context.Background(),
 			msg.ChannelID, // Thread/channel ID as session ID
-			msg.Content,
+			content,
 			msg.Author.Username,
 			attachments,
 			replyToBridgeMessageID,
@@ -371,7 +401,7 @@ func (g *DiscordGateway) handleMessage(msg messageCreatePayload) {
 		g.config.OnOperatorMessageWithIDs(
 			context.Background(),
 			msg.ChannelID,
-			msg.Content,
+			content,
 			msg.Author.Username,
 			attachments,
 			replyToBridgeMessageID,
@@ -393,14 +423,16 @@ func (g *DiscordGateway) handleMessageUpdate(msg messageUpdatePayload) {
 		return
 	}
 
-	editedAt := time.Now()
+	var reported time.Time
 	if msg.EditedTimestamp != "" {
 		if parsed, err := time.Parse(time.RFC3339, msg.EditedTimestamp); err == nil {
-			editedAt = parsed
+			reported = parsed
 		}
 	}
+	ctx, editedAt := normalizePlatformTime(context.Background(), g.config.ClockSkew, "discord", reported)
 
-	g.config.OnOperatorMessageEdit(context.Background(), msg.ChannelID, msg.ID, msg.Content, editedAt)
+	content := ResolveDiscordMentions(msg.Content, discordUserNames(msg.Mentions))
+	g.config.OnOperatorMessageEdit(ctx, msg.ChannelID, msg.ID, content, editedAt)
 }
 
 func (g *DiscordGateway) handleMessageDelete(msg messageDeletePayload) {
@@ -408,7 +440,9 @@ func (g *DiscordGateway) handleMessageDelete(msg messageDeletePayload) {
 		return
 	}
 
-	g.config.OnOperatorMessageDelete(context.Background(), msg.ChannelID, msg.ID, time.Now())
+	// Discord doesn't say when a message was deleted
+	ctx, deletedAt := normalizePlatformTime(context.Background(), g.config.ClockSkew, "discord", time.Time{})
+	g.config.OnOperatorMessageDelete(ctx, msg.ChannelID, msg.ID, deletedAt)
 }
 
 func (g *DiscordGateway) isAllowedBot(botID string) bool {
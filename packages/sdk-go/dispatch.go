@@ -0,0 +1,86 @@
+package pocketping
+
+import "sync"
+
+// dispatchKey identifies one ordered queue: a session on a single bridge.
+// Bridges are keyed by their index in PocketPing.bridges so two instances of
+// the same bridge type stay independent.
+type dispatchKey struct {
+	sessionID string
+	bridge    int
+}
+
+// bridgeDispatcher runs bridge notifications asynchronously while keeping
+// them in FIFO order per session and bridge. A slow bridge only delays its
+// own queue; other bridges and other sessions proceed in parallel.
+//
+// Each queue is drained by at most one goroutine, started on demand and
+// exiting once the queue is empty, so idle sessions cost nothing.
+type bridgeDispatcher struct {
+	mu     sync.Mutex
+	queues map[dispatchKey][]func()
+	wg     sync.WaitGroup
+}
+
+func newBridgeDispatcher() *bridgeDispatcher {
+	return &bridgeDispatcher{queues: make(map[dispatchKey][]func())}
+}
+
+// dispatch enqueues fn behind any pending work for the same key.
+func (d *bridgeDispatcher) dispatch(key dispatchKey, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, running := d.queues[key]
+	d.queues[key] = append(pending, fn)
+	if !running {
+		d.wg.Add(1)
+		go d.drain(key)
+	}
+}
+
+func (d *bridgeDispatcher) drain(key dispatchKey) {
+	defer d.wg.Done()
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		fn := queue[0]
+		d.queues[key] = queue[1:]
+		d.mu.Unlock()
+
+		fn()
+	}
+}
+
+// wait blocks until every queued notification has run.
+func (d *bridgeDispatcher) wait() {
+	d.wg.Wait()
+}
+
+// dispatchToBridges queues fn for every bridge serving region, ordered per
+// session.
+func (pp *PocketPing) dispatchToBridges(sessionID, region string, fn func(b Bridge)) {
+	pp.dispatchToBridgesAt(sessionID, region, func(i int, b Bridge) {
+		fn(b)
+	})
+}
+
+// dispatchToBridgesAt is dispatchToBridges passing each bridge's position in
+// pp.bridges too.
+func (pp *PocketPing) dispatchToBridgesAt(sessionID, region string, fn func(i int, b Bridge)) {
+	routed := routeBridges(pp.bridges, region)
+	for i, bridge := range pp.bridges {
+		if !routed[i] {
+			continue
+		}
+		i, b := i, bridge
+		pp.dispatcher.dispatch(dispatchKey{sessionID: sessionID, bridge: i}, func() {
+			fn(i, b)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderRecordingBridge records visitor messages per session, sleeping a
+// random amount to shake out reordering.
+type orderRecordingBridge struct {
+	BaseBridge
+	mu       sync.Mutex
+	received map[string][]string
+}
+
+func (b *orderRecordingBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.received[session.ID] = append(b.received[session.ID], message.ID)
+	return nil
+}
+
+func TestBridgeDispatchPreservesPerSessionOrder(t *testing.T) {
+	bridges := []*orderRecordingBridge{
+		{BaseBridge: BaseBridge{BridgeName: "a"}, received: make(map[string][]string)},
+		{BaseBridge: BaseBridge{BridgeName: "b"}, received: make(map[string][]string)},
+	}
+	pp := New(Config{Bridges: []Bridge{bridges[0], bridges[1]}})
+	ctx := context.Background()
+
+	const perSession = 50
+	sessions := []*Session{
+		createTestSession("sess-1", "v1", nil, nil),
+		createTestSession("sess-2", "v2", nil, nil),
+	}
+	for i := 0; i < perSession; i++ {
+		for _, session := range sessions {
+			msg := createTestMessage(fmt.Sprintf("%s-%03d", session.ID, i), session.ID, "hi")
+			pp.notifyBridgesMessage(ctx, msg, session)
+		}
+	}
+	pp.dispatcher.wait()
+
+	for _, bridge := range bridges {
+		for _, session := range sessions {
+			got := bridge.received[session.ID]
+			if len(got) != perSession {
+				t.Fatalf("bridge %s, %s: expected %d messages, got %d", bridge.Name(), session.ID, perSession, len(got))
+			}
+			for i, id := range got {
+				if want := fmt.Sprintf("%s-%03d", session.ID, i); id != want {
+					t.Fatalf("bridge %s, %s: position %d: expected %s, got %s", bridge.Name(), session.ID, i, want, id)
+				}
+			}
+		}
+	}
+
+	pp.dispatcher.mu.Lock()
+	defer pp.dispatcher.mu.Unlock()
+	if len(pp.dispatcher.queues) != 0 {
+		t.Errorf("expected drained queues to be removed, got %d", len(pp.dispatcher.queues))
+	}
+}
+
+func TestBridgeDispatchSlowBridgeDoesNotBlockOthers(t *testing.T) {
+	d := newBridgeDispatcher()
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	d.dispatch(dispatchKey{sessionID: "s", bridge: 0}, func() { <-release })
+	d.dispatch(dispatchKey{sessionID: "s", bridge: 1}, func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected bridge 1 to run while bridge 0 is blocked")
+	}
+	close(release)
+	d.wait()
+}
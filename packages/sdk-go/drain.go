@@ -0,0 +1,103 @@
+package pocketping
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ErrDraining is returned by HandleConnect for new visitors once Drain has
+// been called. Returning visitors still connect.
+var ErrDraining = newError("draining", http.StatusServiceUnavailable, "server is draining")
+
+// RestartEventType is the event Drain sends to connected widgets and
+// operator consoles: the server is going away, reconnect shortly (to another
+// node, behind a load balancer).
+const RestartEventType = "server_restart"
+
+// RestartEventData is the data of a RestartEventType event.
+type RestartEventData struct {
+	// ReconnectAfter is how long to wait before reconnecting, in ms.
+	ReconnectAfter int64 `json:"reconnectAfter"`
+}
+
+// drainReconnectDelay is the RestartEventData.ReconnectAfter Drain sends.
+const drainReconnectDelay = 2 * time.Second
+
+// Drain prepares for shutdown without cutting conversations mid-send: it
+// stops creating sessions (HandleConnect returns ErrDraining for new
+// visitors) and starting webhook deliveries, posts pending batches and digests to the bridges, waits for the
+// queued bridge notifications and webhooks, and tells connected widgets and
+// operator consoles to reconnect. It returns once nothing is in flight, or
+// ctx's error if ctx ends first. Call Stop afterwards.
+func (pp *PocketPing) Drain(ctx context.Context) error {
+	pp.webhooksMu.Lock()
+	if !pp.draining.Swap(true) {
+		log.Printf("[PocketPing] Draining")
+	}
+	pp.webhooksMu.Unlock()
+
+	pp.flushBatches(ctx)
+	pp.flushDigests(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		pp.dispatcher.wait()
+		pp.webhooks.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	pp.notifyRestart()
+	return err
+}
+
+// IsDraining reports whether Drain has been called, e.g. for a readiness
+// probe.
+func (pp *PocketPing) IsDraining() bool {
+	return pp.draining.Load()
+}
+
+// notifyRestart sends a RestartEventType event to every socket on this node.
+func (pp *PocketPing) notifyRestart() {
+	event := WebSocketEvent{
+		Type: RestartEventType,
+		Data: RestartEventData{ReconnectAfter: drainReconnectDelay.Milliseconds()},
+	}
+
+	pp.socketsMu.RLock()
+	sessionIDs := make([]string, 0, len(pp.sessionSockets))
+	for sessionID := range pp.sessionSockets {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	pp.socketsMu.RUnlock()
+
+	for _, sessionID := range sessionIDs {
+		pp.broadcastLocal(sessionID, event)
+	}
+	pp.broadcastToOperators("", event)
+}
+
+// goWebhook runs a webhook delivery, or another call out such as an
+// enrichment lookup, in the background; Drain waits for it. Once Drain has
+// been called, deliveries are skipped (and logged) instead.
+func (pp *PocketPing) goWebhook(deliver func()) {
+	pp.webhooksMu.Lock()
+	defer pp.webhooksMu.Unlock()
+	if pp.draining.Load() {
+		log.Printf("[PocketPing] Draining, webhook delivery skipped")
+		return
+	}
+	pp.webhooks.Add(1)
+	go func() {
+		defer pp.webhooks.Done()
+		deliver()
+	}()
+}
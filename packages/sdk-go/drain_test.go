@@ -0,0 +1,128 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+	var delivered atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		delivered.Add(1)
+	}))
+	defer webhook.Close()
+
+	bridge := NewMockBridge("slack")
+	pp := New(Config{
+		Bridges:            []Bridge{bridge},
+		WebhookURL:         webhook.URL,
+		MessageBatchWindow: time.Minute,
+	})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(sessionID, ws)
+	sendVisitorMessage(t, pp, sessionID, "still typing...")
+	if err := pp.HandleCustomEvent(ctx, sessionID, CustomEvent{Name: "clicked"}); err != nil {
+		t.Fatalf("HandleCustomEvent: %v", err)
+	}
+
+	if err := pp.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if !pp.IsDraining() {
+		t.Error("IsDraining = false after Drain")
+	}
+	bridge.mu.Lock()
+	mirrored := len(bridge.VisitorMsgCalls)
+	bridge.mu.Unlock()
+	if mirrored != 1 {
+		t.Errorf("bridge got %d messages, want the pending batch", mirrored)
+	}
+	if delivered.Load() != 1 {
+		t.Errorf("webhook deliveries = %d, want 1", delivered.Load())
+	}
+	if got := ws.types(); got[len(got)-1] != RestartEventType {
+		t.Errorf("socket events = %v, want %s last", got, RestartEventType)
+	}
+
+	if _, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v2"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("new visitor: err = %v, want ErrDraining", err)
+	}
+	if resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1"}); err != nil || resp.SessionID != sessionID {
+		t.Errorf("returning visitor: err = %v", err)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer webhook.Close()
+	defer close(release)
+
+	pp := New(Config{WebhookURL: webhook.URL})
+	sessionID := connectVisitor(context.Background(), t, pp, "v1")
+	if err := pp.HandleCustomEvent(context.Background(), sessionID, CustomEvent{Name: "clicked"}); err != nil {
+		t.Fatalf("HandleCustomEvent: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pp.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain: err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestDrainRefusesNewWebhooks(t *testing.T) {
+	captureLog(t)
+	pp := New(Config{})
+
+	// goWebhook racing with Drain either runs before Drain waits, or not at
+	// all (go test -race catches an Add concurrent with Wait)
+	var started, finished atomic.Int32
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			pp.goWebhook(func() {
+				started.Add(1)
+				time.Sleep(time.Millisecond)
+				finished.Add(1)
+			})
+		}
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := pp.Drain(ctx)
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if started.Load() != finished.Load() {
+		t.Errorf("Drain returned with %d of %d deliveries unfinished", started.Load()-finished.Load(), started.Load())
+	}
+
+	ran := false
+	pp.goWebhook(func() { ran = true })
+	pp.webhooks.Wait()
+	if ran {
+		t.Error("goWebhook ran a delivery after Drain")
+	}
+}
@@ -0,0 +1,81 @@
+package pocketping
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// dryRunBodyLimit bounds the payload bytes logged per dry-run request.
+const dryRunBodyLimit = 4096
+
+// dryRunTransport logs bridge API requests instead of sending them, and
+// answers with a fake success that the Telegram, Discord and Slack clients
+// all accept.
+type dryRunTransport struct {
+	bridge string
+	seq    atomic.Int64
+}
+
+// NewDryRunTransport returns an http.RoundTripper that logs each request of
+// the named bridge (method, redacted URL and payload) and answers with a fake
+// success instead of calling the platform API. Bridges use it in dry-run
+// mode; custom bridges can plug it into their own HTTP client.
+func NewDryRunTransport(bridgeName string) http.RoundTripper {
+	return &dryRunTransport{bridge: bridgeName}
+}
+
+func (d *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var payload []byte
+	if req.Body != nil {
+		var err error
+		payload, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("dry run: read body: %w", err)
+		}
+	}
+	// Telegram payloads are form-encoded; log them readable.
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if decoded, err := url.QueryUnescape(string(payload)); err == nil {
+			payload = []byte(decoded)
+		}
+	}
+	if len(payload) > dryRunBodyLimit {
+		payload = append(payload[:dryRunBodyLimit], "…"...)
+	}
+	log.Printf("[PocketPing] [DryRun] %s %s %s %s", d.bridge, req.Method, RedactURL(req.URL), payload)
+
+	id := d.seq.Add(1)
+	body := fmt.Sprintf(`{"ok":true,"result":{"message_id":%d,"message_thread_id":%d},"id":"%d","ts":"%d.%06d","channel":"dry-run"}`,
+		id, id, id, time.Now().Unix(), id)
+	// Slack incoming webhooks answer a plain "ok".
+	if req.URL.Host == "hooks.slack.com" {
+		body = "ok"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}, nil
+}
+
+// dryRunClient returns a copy of a bridge's HTTP client that only logs, for
+// bridges in dry-run mode. The client's timeout, redirect policy and cookie
+// jar are kept; only its transport is replaced, since it must not send.
+func dryRunClient(bridgeName string, client *http.Client) *http.Client {
+	dryRun := &http.Client{}
+	if client != nil {
+		*dryRun = *client
+	}
+	dryRun.Transport = NewDryRunTransport(bridgeName)
+	return dryRun
+}
@@ -0,0 +1,96 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLog redirects the standard logger for the test's duration.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestConfigDryRunSkipsPlatformAPIs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("platform API called in dry-run mode: %s", r.URL.Path)
+	}))
+	defer srv.Close()
+	logs := captureLog(t)
+
+	ctx := context.Background()
+	bridge := telegramBridgeTo(t, srv)
+	pp := New(Config{Bridges: []Bridge{bridge}, DryRun: true})
+	if err := pp.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "Hello from staging")
+	pp.dispatcher.wait()
+
+	out := logs.String()
+	if !strings.Contains(out, "[DryRun] telegram POST") || !strings.Contains(out, "Hello from staging") {
+		t.Errorf("dry-run log missing payload:\n%s", out)
+	}
+	if strings.Contains(out, "test-token") {
+		t.Errorf("dry-run log leaks the bot token:\n%s", out)
+	}
+}
+
+func TestBridgeDryRunOption(t *testing.T) {
+	logs := captureLog(t)
+
+	bridge, err := NewSlackBotBridge("xoxb-test", "C123", WithSlackBotDryRun())
+	if err != nil {
+		t.Fatalf("NewSlackBotBridge: %v", err)
+	}
+	result, err := bridge.postMessage(context.Background(), "hi")
+	if err != nil || result.SlackMessageTS == "" {
+		t.Fatalf("postMessage = %+v, %v; want a fake ts", result, err)
+	}
+	if !strings.Contains(logs.String(), `[DryRun] slack-bot POST slack.com/api/chat.postMessage {"channel":"C123"`) {
+		t.Errorf("unexpected log:\n%s", logs.String())
+	}
+}
+
+func TestDryRunSlackWebhookAnswersOK(t *testing.T) {
+	captureLog(t)
+
+	req, _ := http.NewRequest("POST", "https://hooks.slack.com/services/T000/B000/XXXX", strings.NewReader(`{"text":"hi"}`))
+	resp, err := NewDryRunTransport("slack").RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want ok like Slack incoming webhooks", body)
+	}
+}
+
+func TestDryRunKeepsHTTPClientSettings(t *testing.T) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	bridge, err := NewTelegramBridge("123456:ABC-token", "-100123", WithTelegramHTTPClient(client), WithTelegramDryRun())
+	if err != nil {
+		t.Fatalf("NewTelegramBridge: %v", err)
+	}
+	if bridge.httpClient == client || bridge.httpClient.Timeout != 5*time.Second {
+		t.Errorf("dry-run client = %+v, want a copy of the configured client", bridge.httpClient)
+	}
+	if _, ok := bridge.httpClient.Transport.(*dryRunTransport); !ok {
+		t.Errorf("transport = %T, want the dry-run transport", bridge.httpClient.Transport)
+	}
+	if client.Transport != nil {
+		t.Error("configured client modified")
+	}
+}
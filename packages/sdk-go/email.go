@@ -0,0 +1,169 @@
+package pocketping
+
+import (
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+var (
+	// ErrInvalidEmail is returned by HandleIdentify when the identity's
+	// email doesn't validate (see ValidateEmail).
+	ErrInvalidEmail = newError("invalid_email", http.StatusBadRequest, "invalid email address")
+	// ErrDisposableEmail is returned by HandleIdentify for a disposable
+	// email address under DisposableEmailReject.
+	ErrDisposableEmail = newError("disposable_email", http.StatusUnprocessableEntity, "disposable email addresses are not accepted")
+)
+
+// DisposableEmailPolicy decides what HandleIdentify does with disposable
+// email addresses.
+type DisposableEmailPolicy string
+
+const (
+	// DisposableEmailAllow doesn't check for disposable addresses. The
+	// default.
+	DisposableEmailAllow DisposableEmailPolicy = ""
+	// DisposableEmailFlag accepts them and sets Session.DisposableEmail,
+	// which the bridges show next to the email.
+	DisposableEmailFlag DisposableEmailPolicy = "flag"
+	// DisposableEmailReject refuses them with ErrDisposableEmail.
+	DisposableEmailReject DisposableEmailPolicy = "reject"
+)
+
+// disposableEmailDomains are well-known throwaway mailbox providers.
+var disposableEmailDomains = map[string]bool{
+	"10minutemail.com":       true,
+	"20minutemail.com":       true,
+	"33mail.com":             true,
+	"dispostable.com":        true,
+	"emailondeck.com":        true,
+	"fakeinbox.com":          true,
+	"getairmail.com":         true,
+	"getnada.com":            true,
+	"guerrillamail.com":      true,
+	"guerrillamail.net":      true,
+	"guerrillamail.org":      true,
+	"guerrillamailblock.com": true,
+	"maildrop.cc":            true,
+	"mailinator.com":         true,
+	"mailnesia.com":          true,
+	"mintemail.com":          true,
+	"mohmal.com":             true,
+	"mytemp.email":           true,
+	"sharklasers.com":        true,
+	"spamgourmet.com":        true,
+	"temp-mail.org":          true,
+	"tempmail.com":           true,
+	"tempmail.net":           true,
+	"tempmailo.com":          true,
+	"tempr.email":            true,
+	"throwawaymail.com":      true,
+	"trashmail.com":          true,
+	"yopmail.com":            true,
+	"yopmail.fr":             true,
+	"yopmail.net":            true,
+}
+
+// ValidateEmail checks that email is a plain address (no display name or
+// angle brackets) with a local part of at most 64 characters and a domain
+// of at least two valid DNS labels.
+func ValidateEmail(email string) error {
+	if email == "" || len(email) > 254 || strings.TrimSpace(email) != email {
+		return ErrInvalidEmail
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 1 || at > 64 {
+		return ErrInvalidEmail
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return ErrInvalidEmail
+	}
+	if !validEmailDomain(email[at+1:]) {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// validEmailDomain reports whether domain is a hostname with a TLD:
+// letters, digits and inner hyphens, 63 characters per label at most.
+func validEmailDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	return strings.TrimLeft(tld, "0123456789") != ""
+}
+
+// IsDisposableEmail reports whether email belongs to a well-known
+// disposable mailbox provider, subdomains included.
+func IsDisposableEmail(email string) bool {
+	return isDisposableDomain(emailDomain(email), nil)
+}
+
+// isDisposableEmail also checks Config.DisposableEmailDomains.
+func (pp *PocketPing) isDisposableEmail(email string) bool {
+	return isDisposableDomain(emailDomain(email), pp.config.DisposableEmailDomains)
+}
+
+func emailDomain(email string) string {
+	return strings.ToLower(email[strings.LastIndexByte(email, '@')+1:])
+}
+
+func isDisposableDomain(domain string, extra []string) bool {
+	for domain != "" {
+		if disposableEmailDomains[domain] {
+			return true
+		}
+		for _, d := range extra {
+			if strings.EqualFold(d, domain) {
+				return true
+			}
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// checkIdentityEmail validates the identity's email and applies
+// Config.DisposableEmailPolicy, returning whether to flag it.
+func (pp *PocketPing) checkIdentityEmail(email string) (disposable bool, err error) {
+	if email == "" {
+		return false, nil
+	}
+	if err := ValidateEmail(email); err != nil {
+		return false, err
+	}
+	switch pp.config.DisposableEmailPolicy {
+	case DisposableEmailFlag:
+		return pp.isDisposableEmail(email), nil
+	case DisposableEmailReject:
+		if pp.isDisposableEmail(email) {
+			return false, ErrDisposableEmail
+		}
+	}
+	return false, nil
+}
+
+// disposableEmailNote marks a flagged email in bridge notifications.
+func disposableEmailNote(session *Session) string {
+	if session.DisposableEmail {
+		return " ⚠️ disposable"
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	for _, email := range []string{"jane@example.com", "jane.doe+chat@mail.example.co.uk", "o'neil@example.io", "x@xn--80ak6aa92e.com"} {
+		if err := ValidateEmail(email); err != nil {
+			t.Errorf("ValidateEmail(%q) = %v", email, err)
+		}
+	}
+	for _, email := range []string{
+		"", "jane", "jane@", "@example.com", "jane@localhost", "jane@example", "jane@example.123",
+		"Jane <jane@example.com>", " jane@example.com", "jane@@example.com", "jane@-example.com",
+		"jane@example..com", "jane@exa_mple.com", strings.Repeat("a", 65) + "@example.com",
+	} {
+		if err := ValidateEmail(email); !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("ValidateEmail(%q) = %v, want ErrInvalidEmail", email, err)
+		}
+	}
+}
+
+func TestIsDisposableEmail(t *testing.T) {
+	if !IsDisposableEmail("x@Mailinator.com") || !IsDisposableEmail("x@eu.yopmail.com") {
+		t.Error("disposable domain not detected")
+	}
+	if IsDisposableEmail("x@example.com") || IsDisposableEmail("x@notmailinator.com") {
+		t.Error("regular domain detected as disposable")
+	}
+}
+
+func TestHandleIdentifyEmailPolicy(t *testing.T) {
+	ctx := context.Background()
+	identify := func(pp *PocketPing, sessionID, email string) error {
+		_, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: sessionID, Identity: &UserIdentity{ID: "u1", Email: email}})
+		return err
+	}
+
+	allow := New(Config{})
+	session := newSession(ctx, t, allow)
+	if err := identify(allow, session.ID, "not-an-email"); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("invalid email: %v", err)
+	}
+	if err := identify(allow, session.ID, "jane@mailinator.com"); err != nil {
+		t.Errorf("allow policy: %v", err)
+	}
+	if got, _ := allow.GetSession(ctx, session.ID); got.DisposableEmail {
+		t.Error("flagged without a policy")
+	}
+
+	reject := New(Config{DisposableEmailPolicy: DisposableEmailReject, DisposableEmailDomains: []string{"burner.test"}})
+	session = newSession(ctx, t, reject)
+	if err := identify(reject, session.ID, "jane@mail.burner.test"); !errors.Is(err, ErrDisposableEmail) {
+		t.Errorf("reject policy: %v", err)
+	}
+
+	flag := New(Config{DisposableEmailPolicy: DisposableEmailFlag})
+	session = newSession(ctx, t, flag)
+	if err := identify(flag, session.ID, "jane@yopmail.com"); err != nil {
+		t.Fatalf("flag policy: %v", err)
+	}
+	if got, _ := flag.GetSession(ctx, session.ID); !got.DisposableEmail {
+		t.Error("disposable email not flagged")
+	}
+	if err := identify(flag, session.ID, "jane@example.com"); err != nil {
+		t.Fatalf("flag policy: %v", err)
+	}
+	if got, _ := flag.GetSession(ctx, session.ID); got.DisposableEmail {
+		t.Error("flag kept after a regular email")
+	}
+}
+
+func TestTelegramBridgeIdentityDisposableEmail(t *testing.T) {
+	var mu sync.Mutex
+	var text string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		text = r.PostForm.Get("text")
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":7}}`))
+	}))
+	defer srv.Close()
+
+	session := &Session{ID: "s1", Identity: &UserIdentity{ID: "u1", Email: "jane@yopmail.com"}, DisposableEmail: true}
+	if err := telegramBridgeTo(t, srv).OnIdentityUpdate(context.Background(), session); err != nil {
+		t.Fatalf("OnIdentityUpdate: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(text, "📧 Email: jane@yopmail.com ⚠️ disposable") {
+		t.Errorf("text = %q", text)
+	}
+}
@@ -0,0 +1,258 @@
+package pocketping
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Config.EnrichmentProvider lookups.
+const (
+	DefaultEnrichmentCacheTTL  = 24 * time.Hour
+	DefaultEnrichmentCacheSize = 10000
+	DefaultEnrichmentTimeout   = 2 * time.Second
+)
+
+// enrichmentVisitorRateLimit is the most lookups per minute for one IP
+// address, or one session when its IP is unknown, so a visitor can't spend
+// Config.EnrichmentRateLimit identifying with made-up emails.
+const enrichmentVisitorRateLimit = 5
+
+// Identity.Extra keys set from an Enrichment. Values the visitor's
+// identify call already set are kept.
+const (
+	EnrichmentCompanyKey       = "company"
+	EnrichmentCompanyDomainKey = "companyDomain"
+	EnrichmentRoleKey          = "role"
+	EnrichmentAvatarKey        = "avatarUrl"
+	EnrichmentLocationKey      = "location"
+)
+
+// Enrichment is what an EnrichmentProvider knows about a visitor.
+type Enrichment struct {
+	Company string `json:"company,omitempty"`
+	// CompanyDomain is the company's website domain.
+	CompanyDomain string `json:"companyDomain,omitempty"`
+	// Role is the visitor's job title.
+	Role      string `json:"role,omitempty"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+	Location  string `json:"location,omitempty"`
+}
+
+// EnrichmentProvider looks up a visitor in an external data source
+// (Clearbit-style) by their email and its domain, when they identify. Return
+// nil, nil when the visitor is unknown.
+type EnrichmentProvider interface {
+	Enrich(ctx context.Context, email, domain string) (*Enrichment, error)
+}
+
+type enrichmentCacheEntry struct {
+	email      string
+	enrichment *Enrichment
+	expires    time.Time
+}
+
+// enrichmentCache caches Enrichment by email, unknown visitors included, in
+// least recently used order, and rate limits the lookups.
+type enrichmentCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	limit   rateLimiter
+}
+
+// enrichSession looks up an identified visitor with
+// Config.EnrichmentProvider in the background, and merges what it finds
+// into the session's Identity.Extra and posts it to the bridges. Lookups are
+// cached for Config.EnrichmentCacheTTL per email, bounded by
+// Config.EnrichmentTimeout, and limited per visitor and to
+// Config.EnrichmentRateLimit per minute overall. Failed and skipped lookups
+// are logged.
+func (pp *PocketPing) enrichSession(ctx context.Context, session *Session) {
+	if pp.config.EnrichmentProvider == nil || session.Identity == nil || session.Identity.Email == "" {
+		return
+	}
+	email := strings.ToLower(session.Identity.Email)
+	visitor := "session:" + session.ID
+	if session.Metadata != nil && session.Metadata.IP != "" {
+		visitor = "ip:" + session.Metadata.IP
+	}
+	sessionID := session.ID
+	ctx = context.WithoutCancel(ctx)
+	pp.goWebhook(func() {
+		enrichment := pp.lookupEnrichment(ctx, email, visitor)
+		if enrichment == nil {
+			return
+		}
+		session, err := pp.storage.GetSession(ctx, sessionID)
+		if err != nil || session == nil || session.Identity == nil || !strings.EqualFold(session.Identity.Email, email) {
+			return
+		}
+		identity := *session.Identity
+		identity.Extra = mergeEnrichment(identity.Extra, enrichment)
+		session.Identity = &identity
+		if err := pp.storage.UpdateSession(ctx, session); err != nil {
+			log.Printf("[PocketPing] Saving enrichment of session %s failed: %v", sessionID, err)
+			return
+		}
+		pp.notifyOperators(sessionID, WebSocketEvent{Type: "identity_update", Data: session})
+		if notice := FormatEnrichment(enrichment); notice != "" {
+			pp.notifyBridgesNotice(ctx, session, notice)
+		}
+	})
+}
+
+// lookupEnrichment returns the cached or looked up Enrichment of email, or
+// nil.
+func (pp *PocketPing) lookupEnrichment(ctx context.Context, email, visitor string) *Enrichment {
+	if enrichment, ok := pp.cachedEnrichment(email); ok {
+		return enrichment
+	}
+	if !pp.takeEnrichmentLookup(visitor) {
+		log.Printf("[PocketPing] Enrichment of %s skipped: over the rate limit", email)
+		return nil
+	}
+	timeout := pp.config.EnrichmentTimeout
+	if timeout <= 0 {
+		timeout = DefaultEnrichmentTimeout
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	enrichment, err := pp.config.EnrichmentProvider.Enrich(lookupCtx, email, emailDomain(email))
+	if err != nil {
+		log.Printf("[PocketPing] Enrichment of %s failed: %v", email, err)
+		return nil
+	}
+	pp.cacheEnrichment(email, enrichment)
+	return enrichment
+}
+
+// mergeEnrichment returns a copy of extra with the enrichment's values
+// added, keeping those already set.
+func mergeEnrichment(extra map[string]interface{}, enrichment *Enrichment) map[string]interface{} {
+	merged := make(map[string]interface{}, len(extra)+5)
+	for key, value := range extra {
+		merged[key] = value
+	}
+	for key, value := range map[string]string{
+		EnrichmentCompanyKey:       enrichment.Company,
+		EnrichmentCompanyDomainKey: enrichment.CompanyDomain,
+		EnrichmentRoleKey:          enrichment.Role,
+		EnrichmentAvatarKey:        enrichment.AvatarURL,
+		EnrichmentLocationKey:      enrichment.Location,
+	} {
+		if _, set := merged[key]; !set && value != "" {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// cachedEnrichment returns the cached lookup of email, if still fresh.
+func (pp *PocketPing) cachedEnrichment(email string) (*Enrichment, bool) {
+	c := &pp.enrichments
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[email]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*enrichmentCacheEntry)
+	if !time.Now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, email)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.enrichment, true
+}
+
+// cacheEnrichment caches the lookup of email for Config.EnrichmentCacheTTL,
+// evicting the least recently used entries over Config.EnrichmentCacheSize.
+func (pp *PocketPing) cacheEnrichment(email string, enrichment *Enrichment) {
+	ttl := pp.config.EnrichmentCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultEnrichmentCacheTTL
+	}
+	size := pp.config.EnrichmentCacheSize
+	if size <= 0 {
+		size = DefaultEnrichmentCacheSize
+	}
+	entry := &enrichmentCacheEntry{email: email, enrichment: enrichment, expires: time.Now().Add(ttl)}
+
+	c := &pp.enrichments
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+	if element, ok := c.entries[email]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[email] = c.order.PushFront(entry)
+	for c.order.Len() > size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*enrichmentCacheEntry).email)
+	}
+}
+
+// takeEnrichmentLookup reports whether a provider lookup for visitor fits
+// in enrichmentVisitorRateLimit and Config.EnrichmentRateLimit, and counts
+// it.
+func (pp *PocketPing) takeEnrichmentLookup(visitor string) bool {
+	now := time.Now()
+	l := &pp.enrichments.limit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*rateBucket)
+	}
+	l.sweep(&RateLimitConfig{VisitorPerMinute: enrichmentVisitorRateLimit, IPPerMinute: pp.config.EnrichmentRateLimit}, now)
+
+	perVisitor := l.refill(visitor, enrichmentVisitorRateLimit, 0, now)
+	if perVisitor.tokens < 1 {
+		return false
+	}
+	if perMinute := pp.config.EnrichmentRateLimit; perMinute > 0 {
+		global := l.refill("lookups", perMinute, 0, now)
+		if global.tokens < 1 {
+			return false
+		}
+		global.tokens--
+	}
+	perVisitor.tokens--
+	return true
+}
+
+// FormatEnrichment renders an Enrichment as a compact plain-text bridge
+// notice.
+func FormatEnrichment(enrichment *Enrichment) string {
+	lines := []string{"🔎 Enrichment"}
+	if enrichment.Company != "" {
+		company := enrichment.Company
+		if enrichment.CompanyDomain != "" {
+			company += " (" + enrichment.CompanyDomain + ")"
+		}
+		lines = append(lines, "Company: "+company)
+	}
+	if enrichment.Role != "" {
+		lines = append(lines, "Role: "+enrichment.Role)
+	}
+	if enrichment.Location != "" {
+		lines = append(lines, "Location: "+enrichment.Location)
+	}
+	if enrichment.AvatarURL != "" {
+		lines = append(lines, "Avatar: "+enrichment.AvatarURL)
+	}
+	if len(lines) == 1 {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
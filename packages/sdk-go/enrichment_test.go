@@ -0,0 +1,148 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// fakeEnrichmentProvider knows acme.com visitors and fails for broken.com.
+type fakeEnrichmentProvider struct {
+	mu      sync.Mutex
+	lookups []string
+}
+
+func (p *fakeEnrichmentProvider) Enrich(ctx context.Context, email, domain string) (*Enrichment, error) {
+	p.mu.Lock()
+	p.lookups = append(p.lookups, email)
+	p.mu.Unlock()
+	switch domain {
+	case "acme.com":
+		return &Enrichment{Company: "Acme Inc", CompanyDomain: "acme.com", Role: "CTO", AvatarURL: "https://img.example.com/jane.png"}, nil
+	case "broken.com":
+		return nil, errors.New("provider down")
+	}
+	return nil, nil
+}
+
+func (p *fakeEnrichmentProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.lookups)
+}
+
+// identify identifies a session and waits for its enrichment.
+func identify(ctx context.Context, t *testing.T, pp *PocketPing, sessionID string, identity *UserIdentity) *Session {
+	t.Helper()
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: sessionID, Identity: identity}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	pp.webhooks.Wait()
+	pp.dispatcher.wait()
+	session, _ := pp.GetSession(ctx, sessionID)
+	return session
+}
+
+func TestIdentifyEnrichesIdentity(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeEnrichmentProvider{}
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{Bridges: []Bridge{bridge}, EnrichmentProvider: provider})
+
+	session := identify(ctx, t, pp, newSession(ctx, t, pp).ID, &UserIdentity{ID: "u1", Email: "Jane@Acme.com", Extra: map[string]interface{}{"role": "Founder"}})
+	extra := session.Identity.Extra
+	if extra[EnrichmentCompanyKey] != "Acme Inc" || extra[EnrichmentAvatarKey] != "https://img.example.com/jane.png" {
+		t.Errorf("extra = %v", extra)
+	}
+	if extra[EnrichmentRoleKey] != "Founder" {
+		t.Errorf("role = %v, want the visitor's own", extra[EnrichmentRoleKey])
+	}
+	if _, ok := extra[EnrichmentLocationKey]; ok {
+		t.Errorf("empty location merged: %v", extra)
+	}
+
+	bridge.mu.Lock()
+	notices := append([]string(nil), bridge.notices...)
+	bridge.mu.Unlock()
+	want := "🔎 Enrichment\nCompany: Acme Inc (acme.com)\nRole: CTO\nAvatar: https://img.example.com/jane.png"
+	if len(notices) != 1 || notices[0] != want {
+		t.Errorf("notices = %q, want %q", notices, want)
+	}
+
+	// Another session of the same visitor is served from the cache
+	identify(ctx, t, pp, newSession(ctx, t, pp).ID, &UserIdentity{ID: "u1", Email: "jane@acme.com"})
+	if provider.count() != 1 {
+		t.Errorf("lookups = %v, want one", provider.lookups)
+	}
+}
+
+func TestIdentifyEnrichmentFailuresAndLimit(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeEnrichmentProvider{}
+	pp := New(Config{EnrichmentProvider: provider, EnrichmentRateLimit: 2})
+
+	// A failing provider doesn't fail identify
+	session := identify(ctx, t, pp, newSession(ctx, t, pp).ID, &UserIdentity{ID: "u1", Email: "bob@broken.com"})
+	if len(session.Identity.Extra) != 0 {
+		t.Errorf("extra = %v", session.Identity.Extra)
+	}
+	// Unknown visitors are looked up once, then cached
+	identify(ctx, t, pp, newSession(ctx, t, pp).ID, &UserIdentity{ID: "u2", Email: "sam@example.org"})
+	identify(ctx, t, pp, newSession(ctx, t, pp).ID, &UserIdentity{ID: "u2", Email: "sam@example.org"})
+	// Over the limit: not enriched
+	session = identify(ctx, t, pp, newSession(ctx, t, pp).ID, &UserIdentity{ID: "u3", Email: "jane@acme.com"})
+	if _, ok := session.Identity.Extra[EnrichmentCompanyKey]; ok {
+		t.Errorf("enriched over the rate limit: %v", session.Identity.Extra)
+	}
+	if provider.count() != 2 {
+		t.Errorf("lookups = %v", provider.lookups)
+	}
+}
+
+func TestEnrichmentVisitorLimit(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeEnrichmentProvider{}
+	pp := New(Config{EnrichmentProvider: provider})
+	sessionID := newSession(ctx, t, pp).ID
+	for i := 0; i < enrichmentVisitorRateLimit+2; i++ {
+		identify(ctx, t, pp, sessionID, &UserIdentity{ID: fmt.Sprintf("u%d", i), Email: fmt.Sprintf("user%d@example.org", i)})
+	}
+	if provider.count() != enrichmentVisitorRateLimit {
+		t.Errorf("lookups = %d, want %d", provider.count(), enrichmentVisitorRateLimit)
+	}
+	// Other visitors still get theirs
+	identify(ctx, t, pp, connectVisitor(ctx, t, pp, "visitor-2"), &UserIdentity{ID: "other", Email: "other@example.org"})
+	if provider.count() != enrichmentVisitorRateLimit+1 {
+		t.Errorf("lookups = %d, want another visitor's", provider.count())
+	}
+}
+
+func TestEnrichmentCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	pp := New(Config{EnrichmentCacheSize: 2})
+	pp.cacheEnrichment("a@example.com", nil)
+	pp.cacheEnrichment("b@example.com", nil)
+	pp.cachedEnrichment("a@example.com")
+	pp.cacheEnrichment("c@example.com", nil)
+	if _, ok := pp.cachedEnrichment("b@example.com"); ok {
+		t.Error("least recently used entry kept")
+	}
+	for _, email := range []string{"a@example.com", "c@example.com"} {
+		if _, ok := pp.cachedEnrichment(email); !ok {
+			t.Errorf("%s evicted", email)
+		}
+	}
+	if len(pp.enrichments.entries) != 2 {
+		t.Errorf("cache holds %d entries", len(pp.enrichments.entries))
+	}
+}
+
+func TestFormatEnrichment(t *testing.T) {
+	if got := FormatEnrichment(&Enrichment{}); got != "" {
+		t.Errorf("empty enrichment = %q", got)
+	}
+	if got, want := FormatEnrichment(&Enrichment{Company: "Acme Inc", Location: "Paris, France"}), "🔎 Enrichment\nCompany: Acme Inc\nLocation: Paris, France"; got != want {
+		t.Errorf("FormatEnrichment = %q, want %q", got, want)
+	}
+}
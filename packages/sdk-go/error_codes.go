@@ -0,0 +1,76 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Error is an SDK error clients can act on: Code is stable, HTTPStatus is
+// the status to answer with, and Message is safe to show to visitors.
+//
+// The errors returned by the Handle* functions are, or wrap, an *Error,
+// except for storage and network failures: AsError reports those as
+// ErrInternal. Compare with errors.Is, as with any sentinel error.
+type Error struct {
+	Code       string
+	HTTPStatus int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code string, status int, message string) *Error {
+	return &Error{Code: code, HTTPStatus: status, Message: message}
+}
+
+// Errors of the built-in HTTP handlers.
+var (
+	ErrBadRequest      = newError("bad_request", http.StatusBadRequest, "Bad request")
+	ErrInvalidJSON     = newError("invalid_json", http.StatusBadRequest, "Invalid JSON")
+	ErrPayloadTooLarge = newError("payload_too_large", http.StatusRequestEntityTooLarge, "Payload too large")
+	// ErrInternal stands for errors that aren't an *Error, whose message
+	// isn't shown to clients.
+	ErrInternal = newError("internal_error", http.StatusInternalServerError, "Internal error")
+
+	errInvalidWebhookEvent   = newError("invalid_event", http.StatusBadRequest, "Invalid event")
+	errTelegramNotConfigured = newError("not_configured", http.StatusNotFound, "Telegram not configured")
+	errSlackNotConfigured    = newError("not_configured", http.StatusNotFound, "Slack not configured")
+)
+
+// AsError returns the *Error err is or wraps, or ErrInternal.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return ErrInternal
+}
+
+// ErrorResponse is the JSON body WriteError answers with.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// WriteError answers with err's status and an ErrorResponse, for your own
+// handlers around HandleConnect, HandleMessage and the others:
+//
+//	resp, err := pp.HandleMessage(r.Context(), req)
+//	if err != nil {
+//	    pocketping.WriteError(w, err)
+//	    return
+//	}
+func WriteError(w http.ResponseWriter, err error) {
+	e := AsError(err)
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		w.Header().Set("Retry-After", rateLimited.retryAfterSeconds())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.HTTPStatus)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: e.Message, Code: e.Code})
+}
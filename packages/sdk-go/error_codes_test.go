@@ -0,0 +1,69 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAsError(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	_, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: strings.Repeat("x", MaxMessageContentLength+1), Sender: SenderVisitor})
+	if e := AsError(err); e != ErrContentTooLong || e.Code != "content_too_long" || e.HTTPStatus != http.StatusRequestEntityTooLarge {
+		t.Errorf("AsError(content too long) = %+v", e)
+	}
+	if !errors.Is(err, ErrContentTooLong) {
+		t.Error("errors.Is no longer matches the sentinel")
+	}
+
+	_, err = pp.HandleIdentify(ctx, IdentifyRequest{SessionID: sessionID, Identity: &UserIdentity{ID: "u1"}, Phone: "12"})
+	if e := AsError(err); e.Code != "invalid_phone" || e.HTTPStatus != http.StatusBadRequest {
+		t.Errorf("AsError(wrapped) = %+v", e)
+	}
+
+	if e := AsError(fmt.Errorf("query sessions: %w", errors.New("connection refused"))); e != ErrInternal {
+		t.Errorf("AsError(storage error) = %+v, want ErrInternal", e)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, fmt.Errorf("get session: %w", ErrSessionNotFound))
+
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if body != (ErrorResponse{Error: "session not found", Code: "session_not_found"}) {
+		t.Errorf("body = %+v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteError(rec, errors.New("pq: password authentication failed"))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("internal error leaked: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWebhookHandlerErrorCodes(t *testing.T) {
+	handler := NewWebhookHandler(WebhookConfig{TelegramBotToken: "token"})
+	rec := httptest.NewRecorder()
+	handler.HandleTelegramWebhook()(rec, httptest.NewRequest("POST", "/webhooks/telegram", strings.NewReader("{")))
+
+	var body ErrorResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusBadRequest || body.Code != "invalid_json" {
+		t.Errorf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
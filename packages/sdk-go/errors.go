@@ -55,6 +55,32 @@ For private channels: /invite @YourBotName first`,
 3. Select a channel → Copy Webhook URL
 
 Note: Webhooks are send-only. Use Bot mode for full features.`,
+	},
+	"teams": {
+		"webhook_url": `To get a Microsoft Teams webhook URL:
+
+1. In your channel, open ••• → Workflows
+2. Pick "Post to a channel when a webhook request is received"
+3. Finish the flow and copy the webhook URL
+
+Note: Webhooks are send-only. Use Bot mode for edit/delete sync.`,
+		"team_id": `To get your Microsoft Teams team ID:
+
+1. In Teams, open ••• next to the team → Get link to team
+2. Copy the groupId parameter of the link
+3. Set TEAMS_TEAM_ID in your environment`,
+		"channel_id": `To get your Microsoft Teams channel ID:
+
+1. In Teams, open ••• next to the channel → Get link to channel
+2. Copy the part after /channel/ (URL-decoded, like 19:...@thread.tacv2)
+3. Set TEAMS_CHANNEL_ID in your environment`,
+		"access_token": `To get Microsoft Graph access tokens:
+
+1. Register an app at https://entra.microsoft.com → App registrations
+2. Add the delegated permissions ChannelMessage.Send
+   and ChannelMessage.ReadWrite
+3. Sign in once with the account that posts notifications
+   and refresh its token (e.g. with golang.org/x/oauth2)`,
 	},
 	"telegram": {
 		"bot_token": `To create a Telegram Bot:
@@ -210,3 +236,29 @@ func ValidateSlackWebhookConfig(webhookURL string) error {
 	}
 	return nil
 }
+
+// ValidateTeamsWebhookConfig validates Microsoft Teams Webhook configuration.
+func ValidateTeamsWebhookConfig(webhookURL string) error {
+	if webhookURL == "" {
+		return NewSetupError("Teams", "webhook_url")
+	}
+	if !strings.HasPrefix(webhookURL, "https://") {
+		return NewSetupErrorWithGuide(
+			"Teams",
+			"valid webhook_url",
+			"Webhook URL must start with https://\n\n"+SetupGuides["teams"]["webhook_url"],
+		)
+	}
+	return nil
+}
+
+// ValidateTeamsBotConfig validates Microsoft Teams Bot configuration.
+func ValidateTeamsBotConfig(teamID, channelID string) error {
+	if teamID == "" {
+		return NewSetupError("Teams", "team_id")
+	}
+	if channelID == "" {
+		return NewSetupError("Teams", "channel_id")
+	}
+	return nil
+}
@@ -0,0 +1,598 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// StorageEventType names a state change recorded by EventSourcedStorage.
+type StorageEventType string
+
+const (
+	StorageEventSessionCreated  StorageEventType = "session_created"
+	StorageEventSessionUpdated  StorageEventType = "session_updated"
+	StorageEventIdentitySet     StorageEventType = "identity_set"
+	StorageEventSessionDeleted  StorageEventType = "session_deleted"
+	StorageEventMessageSent     StorageEventType = "message_sent"
+	StorageEventMessageUpdated  StorageEventType = "message_updated"
+	StorageEventMessageEdited   StorageEventType = "message_edited"
+	StorageEventMessageDeleted  StorageEventType = "message_deleted"
+	StorageEventBridgeIDsSaved  StorageEventType = "bridge_ids_saved"
+	StorageEventAttachmentSaved StorageEventType = "attachment_saved"
+	StorageEventNotifyCursorSet StorageEventType = "notify_cursor_set"
+)
+
+// StorageEvent is one state change in an event log. Events carry the full
+// new state of what they touch (session, message...), so projections are
+// rebuilt by applying them in order.
+type StorageEvent struct {
+	// Seq is the event's position in the log, from 1; set by Append.
+	Seq       int64            `json:"seq"`
+	Type      StorageEventType `json:"type"`
+	Time      time.Time        `json:"time"`
+	SessionID string           `json:"sessionId,omitempty"`
+
+	Session    *Session          `json:"session,omitempty"`
+	Message    *Message          `json:"message,omitempty"`
+	MessageID  string            `json:"messageId,omitempty"`
+	BridgeIDs  *BridgeMessageIds `json:"bridgeIds,omitempty"`
+	Attachment *Attachment       `json:"attachment,omitempty"`
+	Bridge     string            `json:"bridge,omitempty"`
+}
+
+// EventLog is an append-only store of StorageEvents.
+type EventLog interface {
+	// Append sets the event's Seq to the next position and stores it.
+	Append(ctx context.Context, event *StorageEvent) error
+
+	// Events calls fn with every event after the given Seq, in order,
+	// stopping at the first error.
+	Events(ctx context.Context, after int64, fn func(StorageEvent) error) error
+}
+
+// MemoryEventLog is an in-memory EventLog, for tests and debugging.
+type MemoryEventLog struct {
+	mu     sync.RWMutex
+	events []StorageEvent
+}
+
+// NewMemoryEventLog creates an empty in-memory event log.
+func NewMemoryEventLog() *MemoryEventLog {
+	return &MemoryEventLog{}
+}
+
+// Append implements EventLog. The event is copied, so later changes to the
+// session or message don't alter history.
+func (l *MemoryEventLog) Append(ctx context.Context, event *StorageEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Seq = int64(len(l.events)) + 1
+	stored, err := cloneStorageEvent(event)
+	if err != nil {
+		return err
+	}
+	l.events = append(l.events, stored)
+	return nil
+}
+
+// Events implements EventLog.
+func (l *MemoryEventLog) Events(ctx context.Context, after int64, fn func(StorageEvent) error) error {
+	l.mu.RLock()
+	var events []StorageEvent
+	if after < int64(len(l.events)) {
+		events = l.events[max(after, 0):]
+	}
+	l.mu.RUnlock()
+
+	for i := range events {
+		event, err := cloneStorageEvent(&events[i])
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneStorageEvent deep-copies an event through its JSON encoding.
+func cloneStorageEvent(event *StorageEvent) (StorageEvent, error) {
+	var clone StorageEvent
+	data, err := json.Marshal(event)
+	if err != nil {
+		return clone, fmt.Errorf("encode event: %w", err)
+	}
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return clone, fmt.Errorf("decode event: %w", err)
+	}
+	return clone, nil
+}
+
+// FileEventLog is an EventLog stored as JSON lines in a file. It is never
+// compacted: the file is the full history.
+type FileEventLog struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	lastSeq int64
+}
+
+// NewFileEventLog opens (or creates) the event log at path. Call Close when
+// done.
+func NewFileEventLog(path string) (*FileEventLog, error) {
+	l := &FileEventLog{path: path}
+	if err := l.Events(context.Background(), 0, func(event StorageEvent) error {
+		l.lastSeq = event.Seq
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// Append implements EventLog. Each event is synced to disk.
+func (l *FileEventLog) Append(ctx context.Context, event *StorageEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("event log is closed")
+	}
+	event.Seq = l.lastSeq + 1
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write event log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("sync event log: %w", err)
+	}
+	l.lastSeq = event.Seq
+	return nil
+}
+
+// Events implements EventLog. A truncated final line (from a crash
+// mid-write) is ignored.
+func (l *FileEventLog) Events(ctx context.Context, after int64, fn func(StorageEvent) error) error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open event log: %w", err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := r.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			var event StorageEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				return fmt.Errorf("event log line %d: %w", lineNo, err)
+			}
+			if event.Seq > after {
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("read event log: %w", readErr)
+		}
+	}
+}
+
+// Close closes the log file.
+func (l *FileEventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// EventSourcedStorage is a Storage that records every state change in an
+// EventLog and serves reads from an in-memory projection rebuilt from it.
+// The log doubles as an audit trail (see History) and lets you inspect the
+// state at any point (see StateAt) when debugging sync issues.
+//
+// Reads return copies, so changes only take effect through the write
+// methods, as with a database.
+type EventSourcedStorage struct {
+	// mu serializes writes so the log and the projection agree on order.
+	mu    sync.Mutex
+	log   EventLog
+	state *MemoryStorage
+}
+
+// NewEventSourcedStorage replays log into a fresh projection.
+func NewEventSourcedStorage(ctx context.Context, log EventLog) (*EventSourcedStorage, error) {
+	state, err := projectEvents(ctx, log, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &EventSourcedStorage{log: log, state: state}, nil
+}
+
+// projectEvents applies the log's events up to seq (all when seq <= 0) to a
+// new MemoryStorage.
+func projectEvents(ctx context.Context, log EventLog, seq int64) (*MemoryStorage, error) {
+	state := NewMemoryStorage()
+	errStop := errors.New("stop")
+	err := log.Events(ctx, 0, func(event StorageEvent) error {
+		if seq > 0 && event.Seq > seq {
+			return errStop
+		}
+		state.applyStorageEvent(&event)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, fmt.Errorf("replay event log: %w", err)
+	}
+	return state, nil
+}
+
+// applyStorageEvent applies an event to the projection.
+func (m *MemoryStorage) applyStorageEvent(event *StorageEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch event.Type {
+	case StorageEventSessionCreated:
+		m.applyCreateSession(event.Session)
+	case StorageEventSessionUpdated, StorageEventIdentitySet:
+		m.applyUpdateSession(event.Session)
+	case StorageEventSessionDeleted:
+		m.applyDeleteSession(event.SessionID)
+	case StorageEventMessageSent, StorageEventMessageUpdated, StorageEventMessageEdited, StorageEventMessageDeleted:
+		m.applySaveMessage(event.Message)
+	case StorageEventBridgeIDsSaved:
+		m.applyBridgeIDs(event.MessageID, *event.BridgeIDs)
+	case StorageEventAttachmentSaved:
+		m.applyPutAttachment(event.Attachment)
+	case StorageEventNotifyCursorSet:
+		m.applyLastNotified(event.SessionID, event.Bridge, event.MessageID)
+	}
+}
+
+// record appends an event and applies it. Callers hold s.mu.
+func (s *EventSourcedStorage) record(ctx context.Context, event StorageEvent) error {
+	event.Time = time.Now()
+	if err := s.log.Append(ctx, &event); err != nil {
+		return err
+	}
+	s.state.applyStorageEvent(&event)
+	return nil
+}
+
+// History returns a session's events, oldest first.
+func (s *EventSourcedStorage) History(ctx context.Context, sessionID string) ([]StorageEvent, error) {
+	var events []StorageEvent
+	err := s.log.Events(ctx, 0, func(event StorageEvent) error {
+		if event.SessionID == sessionID {
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// StateAt rebuilds the state as it was right after event seq.
+func (s *EventSourcedStorage) StateAt(ctx context.Context, seq int64) (*MemoryStorage, error) {
+	if seq <= 0 {
+		return NewMemoryStorage(), nil
+	}
+	return projectEvents(ctx, s.log, seq)
+}
+
+// CreateSession records a session_created event.
+func (s *EventSourcedStorage) CreateSession(ctx context.Context, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(ctx, StorageEvent{Type: StorageEventSessionCreated, SessionID: session.ID, Session: cloneSession(session)})
+}
+
+// GetSession retrieves a session by ID.
+func (s *EventSourcedStorage) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	session, err := s.state.GetSession(ctx, sessionID)
+	return cloneSession(session), err
+}
+
+// GetSessionByVisitorID retrieves the most recent session for a visitor.
+func (s *EventSourcedStorage) GetSessionByVisitorID(ctx context.Context, visitorID string) (*Session, error) {
+	session, err := s.state.GetSessionByVisitorID(ctx, visitorID)
+	return cloneSession(session), err
+}
+
+// UpdateSession records an identity_set event when the identity changed,
+// session_updated otherwise.
+func (s *EventSourcedStorage) UpdateSession(ctx context.Context, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	eventType := StorageEventSessionUpdated
+	if previous, _ := s.state.GetSession(ctx, session.ID); previous != nil && !reflect.DeepEqual(previous.Identity, session.Identity) {
+		eventType = StorageEventIdentitySet
+	}
+	return s.record(ctx, StorageEvent{Type: eventType, SessionID: session.ID, Session: cloneSession(session)})
+}
+
+// DeleteSession records a session_deleted event.
+func (s *EventSourcedStorage) DeleteSession(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(ctx, StorageEvent{Type: StorageEventSessionDeleted, SessionID: sessionID})
+}
+
+// SaveMessage records message_sent for a new message, or message_edited,
+// message_deleted or message_updated (e.g. read status) for an existing one.
+func (s *EventSourcedStorage) SaveMessage(ctx context.Context, message *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveMessageLocked(ctx, message)
+}
+
+func (s *EventSourcedStorage) saveMessageLocked(ctx context.Context, message *Message) error {
+	eventType := StorageEventMessageSent
+	if previous, _ := s.state.GetMessage(ctx, message.ID); previous != nil {
+		switch {
+		case message.DeletedAt != nil && previous.DeletedAt == nil:
+			eventType = StorageEventMessageDeleted
+		case message.EditedAt != nil && (previous.EditedAt == nil || !message.EditedAt.Equal(*previous.EditedAt)):
+			eventType = StorageEventMessageEdited
+		default:
+			eventType = StorageEventMessageUpdated
+		}
+	}
+	stored := *message
+	return s.record(ctx, StorageEvent{Type: eventType, SessionID: message.SessionID, MessageID: message.ID, Message: &stored})
+}
+
+// GetMessages retrieves messages for a session.
+func (s *EventSourcedStorage) GetMessages(ctx context.Context, sessionID string, after string, limit int) ([]Message, error) {
+	return s.state.GetMessages(ctx, sessionID, after, limit)
+}
+
+// GetMessage retrieves a message by ID.
+func (s *EventSourcedStorage) GetMessage(ctx context.Context, messageID string) (*Message, error) {
+	message, err := s.state.GetMessage(ctx, messageID)
+	if message == nil {
+		return nil, err
+	}
+	clone := *message
+	return &clone, err
+}
+
+// OnSessionDeleted registers a hook for sessions deleted from the
+// projection.
+func (s *EventSourcedStorage) OnSessionDeleted(hook func(sessionID string)) {
+	s.state.OnSessionDeleted(hook)
+}
+
+// CleanupOldSessions records a session_deleted event for each session
+// inactive since olderThan.
+func (s *EventSourcedStorage) CleanupOldSessions(ctx context.Context, olderThan time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.state.ListSessions(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, session := range sessions {
+		if !session.LastActivity.Before(olderThan) {
+			continue
+		}
+		if err := s.record(ctx, StorageEvent{Type: StorageEventSessionDeleted, SessionID: session.ID}); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// ListSessions returns sessions, optionally only those created at or after
+// since.
+func (s *EventSourcedStorage) ListSessions(ctx context.Context, since *time.Time) ([]*Session, error) {
+	sessions, err := s.state.ListSessions(ctx, since)
+	for i, session := range sessions {
+		sessions[i] = cloneSession(session)
+	}
+	return sessions, err
+}
+
+// ListInactiveSessions implements StorageWithInactiveSessions.
+func (s *EventSourcedStorage) ListInactiveSessions(ctx context.Context, before time.Time) ([]*Session, error) {
+	sessions, err := s.state.ListInactiveSessions(ctx, before)
+	for i, session := range sessions {
+		sessions[i] = cloneSession(session)
+	}
+	return sessions, err
+}
+
+// FindSessionsByIdentity implements StorageWithIdentityIndex.
+func (s *EventSourcedStorage) FindSessionsByIdentity(ctx context.Context, identityID string) ([]*Session, error) {
+	sessions, err := s.state.FindSessionsByIdentity(ctx, identityID)
+	for i, session := range sessions {
+		sessions[i] = cloneSession(session)
+	}
+	return sessions, err
+}
+
+// SearchMessages implements StorageWithSearch.
+func (s *EventSourcedStorage) SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return s.state.SearchMessages(ctx, query, limit)
+}
+
+// CountSessions implements StorageWithCounts.
+func (s *EventSourcedStorage) CountSessions(ctx context.Context, filter SessionCountFilter) (int, error) {
+	return s.state.CountSessions(ctx, filter)
+}
+
+// CountMessages implements StorageWithCounts.
+func (s *EventSourcedStorage) CountMessages(ctx context.Context, filter MessageCountFilter) (int, error) {
+	return s.state.CountMessages(ctx, filter)
+}
+
+// UpdateMessage records the change to an existing message, like SaveMessage.
+func (s *EventSourcedStorage) UpdateMessage(ctx context.Context, message *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, _ := s.state.GetMessage(ctx, message.ID); previous == nil {
+		return nil // Message doesn't exist
+	}
+	return s.saveMessageLocked(ctx, message)
+}
+
+// SaveBridgeMessageIDs records a bridge_ids_saved event, merged with the
+// IDs already saved.
+func (s *EventSourcedStorage) SaveBridgeMessageIDs(ctx context.Context, messageID string, bridgeIDs BridgeMessageIds) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	merged := bridgeIDs
+	if existing, _ := s.state.GetBridgeMessageIDs(ctx, messageID); existing != nil {
+		merged = *existing
+		if bridgeIDs.TelegramMessageID != 0 {
+			merged.TelegramMessageID = bridgeIDs.TelegramMessageID
+		}
+		if bridgeIDs.DiscordMessageID != "" {
+			merged.DiscordMessageID = bridgeIDs.DiscordMessageID
+		}
+		if bridgeIDs.SlackMessageTS != "" {
+			merged.SlackMessageTS = bridgeIDs.SlackMessageTS
+		}
+		if bridgeIDs.TeamsMessageID != "" {
+			merged.TeamsMessageID = bridgeIDs.TeamsMessageID
+		}
+	}
+	var sessionID string
+	if message, _ := s.state.GetMessage(ctx, messageID); message != nil {
+		sessionID = message.SessionID
+	}
+	return s.record(ctx, StorageEvent{Type: StorageEventBridgeIDsSaved, SessionID: sessionID, MessageID: messageID, BridgeIDs: &merged})
+}
+
+// GetBridgeMessageIDs retrieves platform-specific message IDs for a message.
+func (s *EventSourcedStorage) GetBridgeMessageIDs(ctx context.Context, messageID string) (*BridgeMessageIds, error) {
+	ids, err := s.state.GetBridgeMessageIDs(ctx, messageID)
+	if ids == nil {
+		return nil, err
+	}
+	clone := *ids
+	return &clone, err
+}
+
+// FindMessageByBridgeID implements StorageWithBridgeMessageIndex.
+func (s *EventSourcedStorage) FindMessageByBridgeID(ctx context.Context, sessionID, bridge, bridgeMessageID string) (string, error) {
+	return s.state.FindMessageByBridgeID(ctx, sessionID, bridge, bridgeMessageID)
+}
+
+// SaveAttachment records an attachment_saved event.
+func (s *EventSourcedStorage) SaveAttachment(ctx context.Context, attachment *Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(ctx, StorageEvent{Type: StorageEventAttachmentSaved, MessageID: attachment.MessageID, Attachment: attachment})
+}
+
+// GetAttachment retrieves an attachment by ID. Returns (nil, nil) if not found.
+func (s *EventSourcedStorage) GetAttachment(ctx context.Context, attachmentID string) (*Attachment, error) {
+	return s.state.GetAttachment(ctx, attachmentID)
+}
+
+// GetMessageAttachments returns all attachments linked to the given message ID.
+func (s *EventSourcedStorage) GetMessageAttachments(ctx context.Context, messageID string) ([]Attachment, error) {
+	return s.state.GetMessageAttachments(ctx, messageID)
+}
+
+// UpdateAttachment records an attachment_saved event.
+func (s *EventSourcedStorage) UpdateAttachment(ctx context.Context, attachment *Attachment) error {
+	return s.SaveAttachment(ctx, attachment)
+}
+
+// GetLastNotified implements StorageWithNotifyCursors.
+func (s *EventSourcedStorage) GetLastNotified(ctx context.Context, sessionID, bridgeName string) (string, error) {
+	return s.state.GetLastNotified(ctx, sessionID, bridgeName)
+}
+
+// SetLastNotified records a notify_cursor_set event.
+func (s *EventSourcedStorage) SetLastNotified(ctx context.Context, sessionID, bridgeName, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record(ctx, StorageEvent{Type: StorageEventNotifyCursorSet, SessionID: sessionID, Bridge: bridgeName, MessageID: messageID})
+}
+
+// AppendSessionEvent implements StorageWithEventReplay. Replay events are
+// short-lived, so they are buffered without being logged.
+func (s *EventSourcedStorage) AppendSessionEvent(ctx context.Context, sessionID string, event WebSocketEvent) (int64, error) {
+	return s.state.AppendSessionEvent(ctx, sessionID, event)
+}
+
+// GetSessionEvents implements StorageWithEventReplay.
+func (s *EventSourcedStorage) GetSessionEvents(ctx context.Context, sessionID string, afterSeq int64) ([]WebSocketEvent, error) {
+	return s.state.GetSessionEvents(ctx, sessionID, afterSeq)
+}
+
+// GetAckedSeq implements StorageWithAcks.
+func (s *EventSourcedStorage) GetAckedSeq(ctx context.Context, sessionID string) (int64, error) {
+	return s.state.GetAckedSeq(ctx, sessionID)
+}
+
+// SetAckedSeq implements StorageWithAcks. Like replay events, acks aren't
+// logged.
+func (s *EventSourcedStorage) SetAckedSeq(ctx context.Context, sessionID string, seq int64) error {
+	return s.state.SetAckedSeq(ctx, sessionID, seq)
+}
+
+// cloneSession returns a shallow copy of session, or nil.
+func cloneSession(session *Session) *Session {
+	if session == nil {
+		return nil
+	}
+	clone := *session
+	return &clone
+}
+
+var (
+	_ EventLog = (*MemoryEventLog)(nil)
+	_ EventLog = (*FileEventLog)(nil)
+
+	_ Storage                       = (*EventSourcedStorage)(nil)
+	_ StorageWithListSessions       = (*EventSourcedStorage)(nil)
+	_ StorageWithInactiveSessions   = (*EventSourcedStorage)(nil)
+	_ StorageWithSearch             = (*EventSourcedStorage)(nil)
+	_ StorageWithCounts             = (*EventSourcedStorage)(nil)
+	_ StorageWithBridgeIDs          = (*EventSourcedStorage)(nil)
+	_ StorageWithBridgeMessageIndex = (*EventSourcedStorage)(nil)
+	_ StorageWithAttachments        = (*EventSourcedStorage)(nil)
+	_ StorageWithNotifyCursors      = (*EventSourcedStorage)(nil)
+	_ StorageWithEventReplay        = (*EventSourcedStorage)(nil)
+	_ StorageWithDeletionHook       = (*EventSourcedStorage)(nil)
+)
@@ -0,0 +1,103 @@
+package pocketping
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEventSourcedStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.log")
+	log, err := NewFileEventLog(path)
+	if err != nil {
+		t.Fatalf("NewFileEventLog: %v", err)
+	}
+	storage, err := NewEventSourcedStorage(ctx, log)
+	if err != nil {
+		t.Fatalf("NewEventSourcedStorage: %v", err)
+	}
+	pp := New(Config{Storage: storage})
+
+	session := newSession(ctx, t, pp)
+	first := sendVisitorMessage(t, pp, session.ID, "Helo")
+	if _, err := pp.HandleEditMessage(ctx, EditMessageRequest{SessionID: session.ID, MessageID: first, Content: "Hello"}); err != nil {
+		t.Fatalf("HandleEditMessage: %v", err)
+	}
+	second := sendVisitorMessage(t, pp, session.ID, "oops")
+	if _, err := pp.HandleDeleteMessage(ctx, DeleteMessageRequest{SessionID: session.ID, MessageID: second}); err != nil {
+		t.Fatalf("HandleDeleteMessage: %v", err)
+	}
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: session.ID, Identity: &UserIdentity{ID: "u1"}}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+
+	history, err := storage.History(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	var types []string
+	for _, event := range history {
+		if event.Type != StorageEventSessionUpdated && event.Type != StorageEventMessageUpdated {
+			types = append(types, string(event.Type))
+		}
+	}
+	if got, want := strings.Join(types, ","), "session_created,message_sent,message_edited,message_sent,message_deleted,identity_set"; got != want {
+		t.Errorf("history = %s, want %s", got, want)
+	}
+
+	// Rebuild from the file, as after a restart.
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	reopened, err := NewFileEventLog(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	rebuilt, err := NewEventSourcedStorage(ctx, reopened)
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	got, _ := rebuilt.GetSession(ctx, session.ID)
+	if got == nil || got.Identity == nil || got.Identity.ID != "u1" {
+		t.Fatalf("rebuilt session = %+v", got)
+	}
+	message, _ := rebuilt.GetMessage(ctx, first)
+	if message == nil || message.Content != "Hello" || message.EditedAt == nil {
+		t.Errorf("rebuilt message = %+v", message)
+	}
+
+	// The state right after the first message, before the edit.
+	var sentSeq int64
+	for _, event := range history {
+		if event.Type == StorageEventMessageSent {
+			sentSeq = event.Seq
+			break
+		}
+	}
+	past, err := rebuilt.StateAt(ctx, sentSeq)
+	if err != nil {
+		t.Fatalf("StateAt: %v", err)
+	}
+	if message, _ := past.GetMessage(ctx, first); message == nil || message.Content != "Helo" {
+		t.Errorf("message at seq %d = %+v", sentSeq, message)
+	}
+}
+
+func TestEventSourcedStorageReadsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	storage, err := NewEventSourcedStorage(ctx, NewMemoryEventLog())
+	if err != nil {
+		t.Fatalf("NewEventSourcedStorage: %v", err)
+	}
+	if err := storage.CreateSession(ctx, &Session{ID: "s1", VisitorID: "v1"}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	session, _ := storage.GetSession(ctx, "s1")
+	session.VisitorID = "changed"
+	if again, _ := storage.GetSession(ctx, "s1"); again.VisitorID != "v1" {
+		t.Error("changing a read session altered the projection")
+	}
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Compatible reports whether local, a struct extending the shared struct
+// shared (e.g. Compatible(Session{}, pocketping.Session{})), encodes every
+// shared JSON field under the same name with a compatible type. Both sides
+// call it from their tests so a renamed field or a changed type fails the
+// build's test run rather than a deploy.
+func Compatible(shared, local interface{}) error {
+	var problems []string
+	compareStructs(reflect.TypeOf(shared), reflect.TypeOf(local), "", &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("events: %s is incompatible with %s: %s",
+			reflect.TypeOf(local), reflect.TypeOf(shared), strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// jsonFields returns a struct's encoded fields by JSON name, including
+// those of untagged embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embeddedName, embedded := range jsonFields(field.Type) {
+				if _, ok := fields[embeddedName]; !ok {
+					fields[embeddedName] = embedded
+				}
+			}
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func compareStructs(shared, local reflect.Type, path string, problems *[]string) {
+	localFields := jsonFields(local)
+	for name, field := range jsonFields(shared) {
+		other, ok := localFields[name]
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("missing %s%s", path, name))
+			continue
+		}
+		compareTypes(field.Type, other.Type, path+name, problems)
+	}
+}
+
+// compareTypes compares the JSON encoding of two field types. Pointers
+// encode like their targets, and structs are compared field by field unless
+// they encode themselves.
+func compareTypes(shared, local reflect.Type, path string, problems *[]string) {
+	for shared.Kind() == reflect.Pointer {
+		shared = shared.Elem()
+	}
+	for local.Kind() == reflect.Pointer {
+		local = local.Elem()
+	}
+	if shared == local {
+		return
+	}
+	if shared.Kind() != local.Kind() {
+		*problems = append(*problems, fmt.Sprintf("%s is %s, not %s", path, local, shared))
+		return
+	}
+	switch shared.Kind() {
+	case reflect.Slice, reflect.Array:
+		compareTypes(shared.Elem(), local.Elem(), path+"[]", problems)
+	case reflect.Map:
+		compareTypes(shared.Key(), local.Key(), path+"{}", problems)
+		compareTypes(shared.Elem(), local.Elem(), path+"{}", problems)
+	case reflect.Struct:
+		compareStructs(shared, local, path+".", problems)
+	}
+}
@@ -0,0 +1,311 @@
+// Package events defines the JSON shapes exchanged between PocketPing
+// backends, widgets and the bridge-server. The Go SDK and the bridge-server
+// both build on these types, so the wire format can't drift between them.
+//
+// Types both sides use unchanged (Sender, MessageStatus, UserIdentity,
+// SessionMetadata) are aliased by each side. Session, Message and Attachment
+// hold the fields every side agrees on; the SDK and the bridge-server extend
+// them with their own fields and convert with helpers, and Compatible checks
+// in tests that the extensions keep the shared fields intact.
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types sent by backends to the bridge-server's /api/events.
+const (
+	TypeNewSession            = "new_session"
+	TypeVisitorMessage        = "visitor_message"
+	TypeAITakeover            = "ai_takeover"
+	TypeOperatorStatus        = "operator_status"
+	TypeMessageRead           = "message_read"
+	TypeCustomEvent           = "custom_event"
+	TypeIdentityUpdate        = "identity_update"
+	TypeVisitorMessageEdited  = "visitor_message_edited"
+	TypeVisitorMessageDeleted = "visitor_message_deleted"
+	TypeCsatSubmitted         = "csat_submitted"
+	TypeAssignmentUpdate      = "assignment_update"
+	TypeCloseSession          = "close_session"
+)
+
+// Event types sent by the bridge-server back to backends.
+const (
+	TypeOperatorMessage        = "operator_message"
+	TypeOperatorMessageEdited  = "operator_message_edited"
+	TypeOperatorMessageDeleted = "operator_message_deleted"
+	TypeOperatorTyping         = "operator_typing"
+	TypeSessionClosed          = "session_closed"
+	TypeAssignmentChanged      = "assignment_changed"
+)
+
+// Sender represents who sent a message.
+type Sender string
+
+const (
+	SenderVisitor  Sender = "visitor"
+	SenderOperator Sender = "operator"
+	SenderAI       Sender = "ai"
+)
+
+// MessageStatus represents the delivery status of a message.
+type MessageStatus string
+
+const (
+	MessageStatusSending   MessageStatus = "sending"
+	MessageStatusSent      MessageStatus = "sent"
+	MessageStatusDelivered MessageStatus = "delivered"
+	MessageStatusRead      MessageStatus = "read"
+)
+
+// UserIdentity represents user identity data from PocketPing.identify().
+type UserIdentity struct {
+	// ID is the required unique user identifier.
+	ID string `json:"id"`
+	// Email is the user's email address.
+	Email string `json:"email,omitempty"`
+	// Name is the user's display name.
+	Name string `json:"name,omitempty"`
+	// Extra holds any custom fields (plan, company, etc.). They are
+	// encoded next to the base fields.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes the base fields and the custom fields in one object.
+func (u UserIdentity) MarshalJSON() ([]byte, error) {
+	base := map[string]interface{}{}
+	if u.ID != "" {
+		base["id"] = u.ID
+	}
+	if u.Email != "" {
+		base["email"] = u.Email
+	}
+	if u.Name != "" {
+		base["name"] = u.Name
+	}
+	for k, v := range u.Extra {
+		if k != "id" && k != "email" && k != "name" {
+			base[k] = v
+		}
+	}
+	return json.Marshal(base)
+}
+
+// UnmarshalJSON decodes the base fields, keeping the others in Extra.
+func (u *UserIdentity) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if id, ok := raw["id"].(string); ok {
+		u.ID = id
+	}
+	if email, ok := raw["email"].(string); ok {
+		u.Email = email
+	}
+	if name, ok := raw["name"].(string); ok {
+		u.Name = name
+	}
+
+	u.Extra = make(map[string]interface{})
+	for k, v := range raw {
+		if k != "id" && k != "email" && k != "name" {
+			u.Extra[k] = v
+		}
+	}
+	return nil
+}
+
+// SessionMetadata contains metadata about a visitor's session.
+type SessionMetadata struct {
+	// Page info
+	URL       string `json:"url,omitempty"`
+	Referrer  string `json:"referrer,omitempty"`
+	PageTitle string `json:"pageTitle,omitempty"`
+
+	// Client info
+	UserAgent        string `json:"userAgent,omitempty"`
+	Timezone         string `json:"timezone,omitempty"`
+	Language         string `json:"language,omitempty"`
+	ScreenResolution string `json:"screenResolution,omitempty"`
+
+	// Geo info (populated server-side from IP)
+	IP      string `json:"ip,omitempty"`
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+
+	// Device info (parsed from user agent)
+	DeviceType string `json:"deviceType,omitempty"` // "desktop", "mobile", "tablet"
+	Browser    string `json:"browser,omitempty"`
+	OS         string `json:"os,omitempty"`
+}
+
+// Session holds the session fields every side sends and reads.
+type Session struct {
+	ID             string           `json:"id"`
+	VisitorID      string           `json:"visitorId"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	LastActivity   time.Time        `json:"lastActivity"`
+	OperatorOnline bool             `json:"operatorOnline"`
+	AIActive       bool             `json:"aiActive"`
+	Metadata       *SessionMetadata `json:"metadata,omitempty"`
+	Identity       *UserIdentity    `json:"identity,omitempty"`
+	// UserPhone is the user's phone from pre-chat form (E.164 format: +33612345678).
+	UserPhone string `json:"userPhone,omitempty"`
+	// UserPhoneCountry is the user's phone country code (ISO: FR, US, etc.).
+	UserPhoneCountry string `json:"userPhoneCountry,omitempty"`
+}
+
+// Attachment holds the attachment fields every side sends and reads.
+type Attachment struct {
+	ID           string `json:"id"`
+	Filename     string `json:"filename"`
+	MimeType     string `json:"mimeType"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+	// Status is the upload status ("pending", "uploading", "ready", "failed").
+	Status string `json:"status"`
+	// UploadedFrom is "widget", "telegram", "discord", "slack" or "api".
+	UploadedFrom string `json:"uploadedFrom,omitempty"`
+	BridgeFileID string `json:"bridgeFileId,omitempty"`
+}
+
+// Message holds the message fields every side sends and reads.
+type Message struct {
+	ID        string                 `json:"id"`
+	SessionID string                 `json:"sessionId"`
+	Content   string                 `json:"content"`
+	Sender    Sender                 `json:"sender"`
+	Timestamp time.Time              `json:"timestamp"`
+	ReplyTo   string                 `json:"replyTo,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	// Attachments contains file attachments in this message.
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Read receipt fields
+	Status      MessageStatus `json:"status,omitempty"`
+	DeliveredAt *time.Time    `json:"deliveredAt,omitempty"`
+	ReadAt      *time.Time    `json:"readAt,omitempty"`
+
+	// Edit/delete fields
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// NewSessionEvent is sent when a new chat session starts.
+type NewSessionEvent struct {
+	Type    string   `json:"type"`
+	Session *Session `json:"session"`
+}
+
+// VisitorMessageEvent is sent when a visitor sends a message.
+type VisitorMessageEvent struct {
+	Type    string   `json:"type"`
+	Message *Message `json:"message"`
+	Session *Session `json:"session"`
+}
+
+// IdentityUpdateEvent is sent when a user's identity is updated.
+type IdentityUpdateEvent struct {
+	Type    string   `json:"type"`
+	Session *Session `json:"session"`
+}
+
+// MessageReadEvent is sent when messages are marked as delivered or read.
+type MessageReadEvent struct {
+	Type        string        `json:"type"`
+	SessionID   string        `json:"sessionId"`
+	MessageIDs  []string      `json:"messageIds"`
+	Status      MessageStatus `json:"status"`
+	ReadAt      *time.Time    `json:"readAt,omitempty"`
+	DeliveredAt *time.Time    `json:"deliveredAt,omitempty"`
+}
+
+// VisitorMessageEditedEvent is sent when a visitor edits a message.
+type VisitorMessageEditedEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	MessageID string    `json:"messageId"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"editedAt"`
+}
+
+// VisitorMessageDeletedEvent is sent when a visitor deletes a message.
+type VisitorMessageDeletedEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	MessageID string    `json:"messageId"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// OperatorMessageEvent is sent when an operator replies from a bridge.
+type OperatorMessageEvent struct {
+	Type         string       `json:"type"`
+	SessionID    string       `json:"sessionId"`
+	MessageID    string       `json:"messageId"`
+	Content      string       `json:"content"`
+	SourceBridge string       `json:"sourceBridge"`
+	OperatorName string       `json:"operatorName,omitempty"`
+	Attachments  []Attachment `json:"attachments,omitempty"`
+	// ReplyToBridgeMessageID is the Telegram message_id being replied to.
+	ReplyToBridgeMessageID *int `json:"replyToBridgeMessageId,omitempty"`
+}
+
+// OperatorMessageEditedEvent is sent when an operator edits a bridge message.
+type OperatorMessageEditedEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	MessageID string    `json:"messageId"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"editedAt"`
+}
+
+// OperatorMessageDeletedEvent is sent when an operator deletes a bridge
+// message.
+type OperatorMessageDeletedEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	MessageID string    `json:"messageId"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// OperatorTypingEvent is sent when an operator starts or stops typing.
+type OperatorTypingEvent struct {
+	Type         string `json:"type"`
+	SessionID    string `json:"sessionId"`
+	IsTyping     bool   `json:"isTyping"`
+	SourceBridge string `json:"sourceBridge"`
+}
+
+// Assignee identifies the operator a session is assigned to.
+type Assignee struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// SessionClosedEvent is sent when a session is closed from a bridge or the
+// bridge-server API.
+type SessionClosedEvent struct {
+	Type          string    `json:"type"`
+	SchemaVersion int       `json:"schemaVersion,omitempty"`
+	SessionID     string    `json:"sessionId"`
+	SourceBridge  string    `json:"sourceBridge"`
+	Assignee      *Assignee `json:"assignee,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	ClosedAt      string    `json:"closedAt,omitempty"` // ISO-8601
+}
+
+// AssignmentChangedEvent is sent when a session's assignee changes. Source
+// is "api" or the bridge an operator replied from.
+type AssignmentChangedEvent struct {
+	Type             string    `json:"type"`
+	SchemaVersion    int       `json:"schemaVersion"`
+	SessionID        string    `json:"sessionId"`
+	Assignee         *Assignee `json:"assignee"`
+	PreviousAssignee *Assignee `json:"previousAssignee"`
+	Source           string    `json:"source"`
+	ChangedAt        string    `json:"changedAt"` // ISO-8601
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserIdentityKeepsCustomFields(t *testing.T) {
+	var identity UserIdentity
+	if err := json.Unmarshal([]byte(`{"id":"u1","email":"a@example.com","plan":"pro"}`), &identity); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if identity.ID != "u1" || identity.Email != "a@example.com" || identity.Extra["plan"] != "pro" {
+		t.Fatalf("identity = %+v", identity)
+	}
+
+	data, err := json.Marshal(identity)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := string(data), `{"email":"a@example.com","id":"u1","plan":"pro"}`; got != want {
+		t.Errorf("encoded %s, want %s", got, want)
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	type extended struct {
+		Session
+		TelegramTopicID int64 `json:"telegramTopicId,omitempty"`
+	}
+	type pointers struct {
+		ID        *string   `json:"id"`
+		Filename  string    `json:"filename"`
+		MimeType  string    `json:"mimeType"`
+		Size      int64     `json:"size"`
+		URL       string    `json:"url"`
+		Thumbnail string    `json:"thumbnailUrl"`
+		Status    string    `json:"status"`
+		Uploaded  string    `json:"uploadedFrom"`
+		FileID    string    `json:"bridgeFileId"`
+		Extra     time.Time `json:"createdAt"`
+	}
+	type renamed struct {
+		ID        string    `json:"id"`
+		Session   string    `json:"session_id"`
+		Content   string    `json:"content"`
+		Sender    string    `json:"sender"`
+		Timestamp string    `json:"timestamp"`
+		Metadata  []string  `json:"metadata"`
+		EditedAt  time.Time `json:"editedAt"`
+	}
+
+	if err := Compatible(Attachment{}, pointers{}); err != nil {
+		t.Errorf("pointers: %v", err)
+	}
+	if err := Compatible(Session{}, Session{}); err != nil {
+		t.Errorf("identical: %v", err)
+	}
+	if err := Compatible(Session{}, extended{}); err != nil {
+		t.Errorf("embedded: %v", err)
+	}
+
+	err := Compatible(Message{}, renamed{})
+	if err == nil {
+		t.Fatal("renamed fields reported compatible")
+	}
+	for _, want := range []string{"missing sessionId", "timestamp is string", "metadata is []string", "missing attachments"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+}
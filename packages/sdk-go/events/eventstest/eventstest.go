@@ -0,0 +1,126 @@
+// Package eventstest holds the golden payloads of the contract between
+// PocketPing backends and the bridge-server. The SDK's tests record the
+// events it encodes and parse what the bridge-server sends back; the
+// bridge-server's tests do the opposite. Run a module's tests with
+// -update-contract to rewrite the payloads it produces.
+package eventstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Sides of the contract, named after the sender.
+const (
+	// Backend is the events backends post to the bridge-server's /api/events.
+	Backend = "backend"
+	// Bridge is the events the bridge-server posts to BACKEND_WEBHOOK_URL.
+	Bridge = "bridge"
+)
+
+// PlaceholderTime replaces every timestamp in the golden payloads, so they
+// don't change from run to run.
+const PlaceholderTime = "2024-01-02T03:04:05Z"
+
+var update = flag.Bool("update-contract", false, "rewrite the golden contract payloads")
+
+// Dir returns the directory holding a side's golden payloads.
+func Dir(side string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "testdata", "contract", side)
+}
+
+// Check compares a payload produced by side with its golden file, named
+// after the event type, or rewrites the file with -update-contract.
+func Check(t testing.TB, side, eventType string, payload []byte) {
+	t.Helper()
+	got, err := Normalize(payload)
+	if err != nil {
+		t.Fatalf("%s %s: %v", side, eventType, err)
+	}
+	path := filepath.Join(Dir(side), eventType+".json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s %s has no golden payload (run with -update-contract to record it): %v", side, eventType, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s %s payload changed; if intended, run with -update-contract and check the other module still parses it.\ngot:\n%s\nwant:\n%s", side, eventType, got, want)
+	}
+}
+
+// Payloads returns a side's golden payloads by event type.
+func Payloads(t testing.TB, side string) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(Dir(side), "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no golden %s payloads in %s", side, Dir(side))
+	}
+	payloads := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		payloads[strings.TrimSuffix(filepath.Base(path), ".json")] = data
+	}
+	return payloads
+}
+
+// Types returns the sorted keys of payloads.
+func Types(payloads map[string][]byte) []string {
+	types := make([]string, 0, len(payloads))
+	for eventType := range payloads {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Normalize indents a payload with sorted keys and replaces RFC 3339
+// timestamps with PlaceholderTime. Other values, including timestamps that
+// changed format, are kept.
+func Normalize(payload []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(scrubTimes(value), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func scrubTimes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = scrubTimes(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubTimes(item)
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return PlaceholderTime
+		}
+	}
+	return value
+}
@@ -0,0 +1,117 @@
+package pocketping
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
+	"github.com/Ruwad-io/pocketping/sdk-go/events/eventstest"
+)
+
+// contractFixtures returns a session and a message with every shared field
+// set.
+func contractFixtures() (*Session, *Message) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	session := &Session{
+		ID:             "sess-1",
+		VisitorID:      "visitor-1",
+		CreatedAt:      at,
+		LastActivity:   at,
+		OperatorOnline: true,
+		AIActive:       true,
+		Metadata: &SessionMetadata{
+			URL: "https://example.com/pricing", Referrer: "https://google.com", PageTitle: "Pricing",
+			UserAgent: "Mozilla/5.0", Timezone: "Europe/Paris", Language: "fr-FR", ScreenResolution: "1920x1080",
+			IP: "203.0.113.7", Country: "France", City: "Paris", DeviceType: "desktop", Browser: "Firefox", OS: "Linux",
+		},
+		Identity:         &UserIdentity{ID: "user-1", Email: "jane@example.com", Name: "Jane", Extra: map[string]interface{}{"plan": "pro"}},
+		UserPhone:        "+33612345678",
+		UserPhoneCountry: "FR",
+		// SDK-only fields aren't sent.
+		HumanTakeover: true,
+		Region:        "eu",
+	}
+	message := &Message{
+		ID:        "msg-1",
+		SessionID: "sess-1",
+		Content:   "Hello",
+		Sender:    SenderVisitor,
+		Timestamp: at,
+		ReplyTo:   "msg-0",
+		Metadata:  map[string]interface{}{"source": "widget"},
+		Attachments: []Attachment{{
+			ID: "att-1", Filename: "invoice.pdf", MimeType: "application/pdf", Size: 1024,
+			URL: "https://files.example.com/invoice.pdf", ThumbnailURL: "https://files.example.com/invoice.png",
+			Status: AttachmentStatusReady, UploadedFrom: UploadSourceWidget, CreatedAt: at,
+		}},
+		Status:   MessageStatusSent,
+		ThreadID: "thread-1",
+	}
+	return session, message
+}
+
+// backendCustomEvent is the custom_event payload, whose event keeps the
+// SDK's CustomEvent shape.
+type backendCustomEvent struct {
+	Type    string          `json:"type"`
+	Event   CustomEvent     `json:"event"`
+	Session *events.Session `json:"session"`
+}
+
+func TestContractBackendPayloads(t *testing.T) {
+	session, message := contractFixtures()
+	at := message.Timestamp
+
+	for eventType, event := range map[string]interface{}{
+		events.TypeNewSession:     events.NewSessionEvent{Type: events.TypeNewSession, Session: SessionToEvent(session)},
+		events.TypeVisitorMessage: events.VisitorMessageEvent{Type: events.TypeVisitorMessage, Message: MessageToEvent(message), Session: SessionToEvent(session)},
+		events.TypeMessageRead: events.MessageReadEvent{
+			Type: events.TypeMessageRead, SessionID: session.ID, MessageIDs: []string{"msg-2", "msg-3"}, Status: MessageStatusRead, ReadAt: &at,
+		},
+		events.TypeCustomEvent: backendCustomEvent{
+			Type:    events.TypeCustomEvent,
+			Event:   CustomEvent{Name: "clicked_pricing", Data: map[string]interface{}{"plan": "pro"}, Timestamp: at, SessionID: session.ID},
+			Session: SessionToEvent(session),
+		},
+		events.TypeIdentityUpdate: events.IdentityUpdateEvent{Type: events.TypeIdentityUpdate, Session: SessionToEvent(session)},
+		events.TypeVisitorMessageEdited: events.VisitorMessageEditedEvent{
+			Type: events.TypeVisitorMessageEdited, SessionID: session.ID, MessageID: message.ID, Content: "Hello again", EditedAt: at,
+		},
+		events.TypeVisitorMessageDeleted: events.VisitorMessageDeletedEvent{
+			Type: events.TypeVisitorMessageDeleted, SessionID: session.ID, MessageID: message.ID, DeletedAt: at,
+		},
+	} {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", eventType, err)
+		}
+		eventstest.Check(t, eventstest.Backend, eventType, payload)
+	}
+}
+
+// bridgeServerEventTypes are the events the bridge-server sends, with the
+// SDK's type for each.
+var bridgeServerEventTypes = map[string]func() interface{}{
+	events.TypeOperatorMessage:        func() interface{} { return &events.OperatorMessageEvent{} },
+	events.TypeOperatorMessageEdited:  func() interface{} { return &events.OperatorMessageEditedEvent{} },
+	events.TypeOperatorMessageDeleted: func() interface{} { return &events.OperatorMessageDeletedEvent{} },
+	events.TypeSessionClosed:          func() interface{} { return &events.SessionClosedEvent{} },
+	events.TypeAssignmentChanged:      func() interface{} { return &events.AssignmentChangedEvent{} },
+}
+
+func TestContractBridgePayloadsParse(t *testing.T) {
+	for eventType, payload := range eventstest.Payloads(t, eventstest.Bridge) {
+		newEvent, ok := bridgeServerEventTypes[eventType]
+		if !ok {
+			t.Errorf("bridge-server sends %s, which the SDK doesn't know", eventType)
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(newEvent()); err != nil {
+			t.Errorf("%s doesn't parse into the SDK's type: %v", eventType, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package pocketping
+
+import "github.com/Ruwad-io/pocketping/sdk-go/events"
+
+// SessionToEvent returns the shared fields of a session, as sent to the
+// bridge-server. It returns nil for a nil session.
+func SessionToEvent(session *Session) *events.Session {
+	if session == nil {
+		return nil
+	}
+	return &events.Session{
+		ID:               session.ID,
+		VisitorID:        session.VisitorID,
+		CreatedAt:        session.CreatedAt,
+		LastActivity:     session.LastActivity,
+		OperatorOnline:   session.OperatorOnline,
+		AIActive:         session.AIActive,
+		Metadata:         session.Metadata,
+		Identity:         session.Identity,
+		UserPhone:        session.UserPhone,
+		UserPhoneCountry: session.UserPhoneCountry,
+	}
+}
+
+// SessionFromEvent returns a session holding the shared fields. It returns
+// nil for a nil session.
+func SessionFromEvent(session *events.Session) *Session {
+	if session == nil {
+		return nil
+	}
+	return &Session{
+		ID:               session.ID,
+		VisitorID:        session.VisitorID,
+		CreatedAt:        session.CreatedAt,
+		LastActivity:     session.LastActivity,
+		OperatorOnline:   session.OperatorOnline,
+		AIActive:         session.AIActive,
+		Metadata:         session.Metadata,
+		Identity:         session.Identity,
+		UserPhone:        session.UserPhone,
+		UserPhoneCountry: session.UserPhoneCountry,
+	}
+}
+
+// MessageToEvent returns the shared fields of a message. It returns nil for
+// a nil message.
+func MessageToEvent(message *Message) *events.Message {
+	if message == nil {
+		return nil
+	}
+	shared := &events.Message{
+		ID:          message.ID,
+		SessionID:   message.SessionID,
+		Content:     message.Content,
+		Sender:      message.Sender,
+		Timestamp:   message.Timestamp,
+		ReplyTo:     message.ReplyTo,
+		Metadata:    message.Metadata,
+		Status:      message.Status,
+		DeliveredAt: message.DeliveredAt,
+		ReadAt:      message.ReadAt,
+		EditedAt:    message.EditedAt,
+		DeletedAt:   message.DeletedAt,
+	}
+	for _, attachment := range message.Attachments {
+		shared.Attachments = append(shared.Attachments, AttachmentToEvent(attachment))
+	}
+	return shared
+}
+
+// MessageFromEvent returns a message holding the shared fields. It returns
+// nil for a nil message.
+func MessageFromEvent(message *events.Message) *Message {
+	if message == nil {
+		return nil
+	}
+	local := &Message{
+		ID:          message.ID,
+		SessionID:   message.SessionID,
+		Content:     message.Content,
+		Sender:      message.Sender,
+		Timestamp:   message.Timestamp,
+		ReplyTo:     message.ReplyTo,
+		Metadata:    message.Metadata,
+		Status:      message.Status,
+		DeliveredAt: message.DeliveredAt,
+		ReadAt:      message.ReadAt,
+		EditedAt:    message.EditedAt,
+		DeletedAt:   message.DeletedAt,
+	}
+	for _, attachment := range message.Attachments {
+		local.Attachments = append(local.Attachments, AttachmentFromEvent(attachment))
+	}
+	return local
+}
+
+// AttachmentToEvent returns the shared fields of an attachment. Data isn't
+// part of them.
+func AttachmentToEvent(attachment Attachment) events.Attachment {
+	return events.Attachment{
+		ID:           attachment.ID,
+		Filename:     attachment.Filename,
+		MimeType:     attachment.MimeType,
+		Size:         attachment.Size,
+		URL:          attachment.URL,
+		ThumbnailURL: attachment.ThumbnailURL,
+		Status:       string(attachment.Status),
+		UploadedFrom: string(attachment.UploadedFrom),
+		BridgeFileID: attachment.BridgeFileID,
+	}
+}
+
+// AttachmentFromEvent returns an attachment holding the shared fields.
+func AttachmentFromEvent(attachment events.Attachment) Attachment {
+	return Attachment{
+		ID:           attachment.ID,
+		Filename:     attachment.Filename,
+		MimeType:     attachment.MimeType,
+		Size:         attachment.Size,
+		URL:          attachment.URL,
+		ThumbnailURL: attachment.ThumbnailURL,
+		Status:       AttachmentStatus(attachment.Status),
+		UploadedFrom: UploadSource(attachment.UploadedFrom),
+		BridgeFileID: attachment.BridgeFileID,
+	}
+}
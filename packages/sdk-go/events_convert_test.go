@@ -0,0 +1,64 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
+)
+
+func TestSharedEventTypesCompatible(t *testing.T) {
+	for _, pair := range []struct{ shared, local interface{} }{
+		{events.Session{}, Session{}},
+		{events.Message{}, Message{}},
+		{events.Attachment{}, Attachment{}},
+	} {
+		if err := events.Compatible(pair.shared, pair.local); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEventConversionRoundTrip(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	session := &Session{
+		ID:        "s1",
+		VisitorID: "v1",
+		CreatedAt: now,
+		Metadata:  &SessionMetadata{URL: "https://example.com"},
+		Identity:  &UserIdentity{ID: "u1", Extra: map[string]interface{}{"plan": "pro"}},
+		UserPhone: "+33612345678",
+	}
+	message := &Message{
+		ID:          "m1",
+		SessionID:   "s1",
+		Content:     "hello",
+		Sender:      SenderOperator,
+		Timestamp:   now,
+		Metadata:    map[string]interface{}{"source": "api"},
+		Attachments: []Attachment{{ID: "a1", Filename: "f.pdf", Status: AttachmentStatusReady, UploadedFrom: UploadSourceSlack}},
+		Status:      MessageStatusSent,
+	}
+
+	if got := SessionFromEvent(SessionToEvent(session)); !reflect.DeepEqual(got, session) {
+		t.Errorf("session round trip = %+v", got)
+	}
+	if got := MessageFromEvent(MessageToEvent(message)); !reflect.DeepEqual(got, message) {
+		t.Errorf("message round trip = %+v", got)
+	}
+	if SessionToEvent(nil) != nil || MessageToEvent(nil) != nil {
+		t.Error("nil not converted to nil")
+	}
+
+	// The shared encoding is the local encoding without the local fields.
+	local, _ := json.Marshal(message)
+	shared, _ := json.Marshal(MessageToEvent(message))
+	var fromLocal, fromShared events.Message
+	_ = json.Unmarshal(local, &fromLocal)
+	_ = json.Unmarshal(shared, &fromShared)
+	if !reflect.DeepEqual(fromLocal, fromShared) {
+		t.Errorf("decoded %+v, want %+v", fromLocal, fromShared)
+	}
+}
@@ -0,0 +1,224 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrExperimentResultsUnsupported is returned by ExperimentResults when the
+// storage adapter does not implement StorageWithListSessions.
+var ErrExperimentResultsUnsupported = errors.New("ExperimentResults requires Storage to implement StorageWithListSessions")
+
+// Experiment is an A/B experiment. Sessions are assigned a variant
+// deterministically from a hash of the visitor ID, so a returning visitor
+// keeps their variant. Custom events listed in ConversionEvents count as a
+// conversion for the session's variant.
+type Experiment struct {
+	// Name identifies the experiment. Renaming it reassigns every visitor.
+	Name string
+	// Variants to split sessions between.
+	Variants []ExperimentVariant
+	// ConversionEvents are the custom event names that count as a conversion.
+	ConversionEvents []string
+}
+
+// ExperimentVariant is one arm of an Experiment.
+type ExperimentVariant struct {
+	// Name identifies the variant (e.g. "control", "friendly-welcome").
+	Name string
+	// Weight is the variant's relative share of sessions (default 1).
+	Weight int
+	// WelcomeMessage, when set, replaces Config.WelcomeMessage for sessions in
+	// this variant.
+	WelcomeMessage string
+}
+
+// ExperimentAssignment is a session's variant in one experiment.
+type ExperimentAssignment struct {
+	Variant    string    `json:"variant"`
+	AssignedAt time.Time `json:"assignedAt"`
+	// ConvertedAt is set by the first conversion event.
+	ConvertedAt     *time.Time `json:"convertedAt,omitempty"`
+	ConversionEvent string     `json:"conversionEvent,omitempty"`
+}
+
+// ExperimentResult reports exposures and conversions per variant.
+type ExperimentResult struct {
+	Experiment string          `json:"experiment"`
+	Variants   []VariantResult `json:"variants"`
+}
+
+// VariantResult is the outcome of one variant.
+type VariantResult struct {
+	Variant string `json:"variant"`
+	// Sessions is the number of sessions assigned to the variant.
+	Sessions int `json:"sessions"`
+	// Conversions is the number of those sessions that converted.
+	Conversions int `json:"conversions"`
+	// ConversionRate is Conversions / Sessions (0 without sessions).
+	ConversionRate float64 `json:"conversionRate"`
+}
+
+// pickVariant deterministically assigns visitorID to a variant of exp.
+func pickVariant(exp *Experiment, visitorID string) *ExperimentVariant {
+	total := 0
+	for i := range exp.Variants {
+		total += variantWeight(&exp.Variants[i])
+	}
+	if total == 0 {
+		return nil
+	}
+
+	bucket := int(stableBucket(exp.Name, visitorID, uint32(total)))
+	for i := range exp.Variants {
+		bucket -= variantWeight(&exp.Variants[i])
+		if bucket < 0 {
+			return &exp.Variants[i]
+		}
+	}
+	return nil
+}
+
+func variantWeight(v *ExperimentVariant) int {
+	if v.Weight == 0 {
+		return 1
+	}
+	if v.Weight < 0 {
+		return 0
+	}
+	return v.Weight
+}
+
+// assignExperiments assigns the session to every configured experiment it
+// isn't in yet. Reports whether anything changed.
+func (pp *PocketPing) assignExperiments(session *Session) bool {
+	changed := false
+	for i := range pp.config.Experiments {
+		exp := &pp.config.Experiments[i]
+		if _, ok := session.Experiments[exp.Name]; ok {
+			continue
+		}
+		variant := pickVariant(exp, session.VisitorID)
+		if variant == nil {
+			continue
+		}
+		if session.Experiments == nil {
+			session.Experiments = make(map[string]*ExperimentAssignment)
+		}
+		session.Experiments[exp.Name] = &ExperimentAssignment{
+			Variant:    variant.Name,
+			AssignedAt: time.Now(),
+		}
+		changed = true
+	}
+	return changed
+}
+
+// experimentVariants returns the session's variant per experiment.
+func experimentVariants(session *Session) map[string]string {
+	if len(session.Experiments) == 0 {
+		return nil
+	}
+	variants := make(map[string]string, len(session.Experiments))
+	for name, assignment := range session.Experiments {
+		variants[name] = assignment.Variant
+	}
+	return variants
+}
+
+// welcomeMessage returns the welcome message for a session: the first
+// experiment variant (in config order) that overrides it, or the default.
+func (pp *PocketPing) welcomeMessage(session *Session) string {
+	for i := range pp.config.Experiments {
+		exp := &pp.config.Experiments[i]
+		assignment, ok := session.Experiments[exp.Name]
+		if !ok {
+			continue
+		}
+		for j := range exp.Variants {
+			v := &exp.Variants[j]
+			if v.Name == assignment.Variant && v.WelcomeMessage != "" {
+				return v.WelcomeMessage
+			}
+		}
+	}
+	return pp.config.WelcomeMessage
+}
+
+// recordExperimentConversion marks the session as converted in every
+// experiment listing event as a conversion event. Only the first conversion
+// per experiment counts.
+func (pp *PocketPing) recordExperimentConversion(ctx context.Context, session *Session, event CustomEvent) error {
+	changed := false
+	for i := range pp.config.Experiments {
+		exp := &pp.config.Experiments[i]
+		assignment, ok := session.Experiments[exp.Name]
+		if !ok || assignment.ConvertedAt != nil {
+			continue
+		}
+		for _, name := range exp.ConversionEvents {
+			if name == event.Name {
+				now := time.Now()
+				assignment.ConvertedAt = &now
+				assignment.ConversionEvent = event.Name
+				changed = true
+				break
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return pp.storage.UpdateSession(ctx, session)
+}
+
+// ExperimentResults counts sessions and conversions per variant for every
+// configured experiment, from the stored sessions. Requires the storage
+// adapter to implement StorageWithListSessions.
+func (pp *PocketPing) ExperimentResults(ctx context.Context) ([]ExperimentResult, error) {
+	lister, ok := pp.storage.(StorageWithListSessions)
+	if !ok {
+		return nil, ErrExperimentResultsUnsupported
+	}
+	sessions, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	results := make([]ExperimentResult, 0, len(pp.config.Experiments))
+	for i := range pp.config.Experiments {
+		exp := &pp.config.Experiments[i]
+		byVariant := make(map[string]*VariantResult, len(exp.Variants))
+		result := ExperimentResult{Experiment: exp.Name, Variants: make([]VariantResult, len(exp.Variants))}
+		for j := range exp.Variants {
+			result.Variants[j].Variant = exp.Variants[j].Name
+			byVariant[exp.Variants[j].Name] = &result.Variants[j]
+		}
+
+		for _, session := range sessions {
+			assignment, ok := session.Experiments[exp.Name]
+			if !ok {
+				continue
+			}
+			vr, ok := byVariant[assignment.Variant]
+			if !ok {
+				// Variant removed from config since assignment
+				continue
+			}
+			vr.Sessions++
+			if assignment.ConvertedAt != nil {
+				vr.Conversions++
+			}
+		}
+
+		for j := range result.Variants {
+			if vr := &result.Variants[j]; vr.Sessions > 0 {
+				vr.ConversionRate = float64(vr.Conversions) / float64(vr.Sessions)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
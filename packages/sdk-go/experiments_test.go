@@ -0,0 +1,136 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func welcomeExperiment() Experiment {
+	return Experiment{
+		Name: "welcome-copy",
+		Variants: []ExperimentVariant{
+			{Name: "control"},
+			{Name: "friendly", WelcomeMessage: "Hey there 👋 Ask us anything!"},
+		},
+		ConversionEvents: []string{"signup"},
+	}
+}
+
+func TestExperimentAssignmentIsDeterministic(t *testing.T) {
+	exp := welcomeExperiment()
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		visitor := fmt.Sprintf("visitor-%d", i)
+		first := pickVariant(&exp, visitor)
+		if again := pickVariant(&exp, visitor); again != first {
+			t.Fatalf("visitor %s: variant changed between calls", visitor)
+		}
+		counts[first.Name]++
+	}
+	if counts["control"] < 400 || counts["friendly"] < 400 {
+		t.Errorf("expected a roughly even split, got %v", counts)
+	}
+
+	weighted := Experiment{Name: "weighted", Variants: []ExperimentVariant{{Name: "a", Weight: 9}, {Name: "b", Weight: 1}, {Name: "off", Weight: -1}}}
+	counts = map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[pickVariant(&weighted, fmt.Sprintf("visitor-%d", i)).Name]++
+	}
+	if counts["a"] < 850 || counts["off"] != 0 {
+		t.Errorf("expected ~90%% in a and none in off, got %v", counts)
+	}
+}
+
+func TestHandleConnectExperiments(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{
+		Storage:        NewMemoryStorage(),
+		WelcomeMessage: "Hello! How can I help?",
+		Experiments:    []Experiment{welcomeExperiment()},
+	})
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		visitor := fmt.Sprintf("visitor-%d", i)
+		resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: visitor})
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		variant := resp.Experiments["welcome-copy"]
+		seen[variant] = true
+
+		want := "Hello! How can I help?"
+		if variant == "friendly" {
+			want = "Hey there 👋 Ask us anything!"
+		}
+		if resp.WelcomeMessage != want {
+			t.Errorf("variant %s: expected welcome %q, got %q", variant, want, resp.WelcomeMessage)
+		}
+
+		// Reconnecting keeps the variant.
+		again, _ := pp.HandleConnect(ctx, ConnectRequest{VisitorID: visitor, SessionID: resp.SessionID})
+		if again.Experiments["welcome-copy"] != variant {
+			t.Errorf("visitor %s: variant changed on reconnect", visitor)
+		}
+	}
+	if !seen["control"] || !seen["friendly"] {
+		t.Errorf("expected both variants among 20 visitors, got %v", seen)
+	}
+}
+
+func TestExperimentConversionsAndResults(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{
+		Storage:     NewMemoryStorage(),
+		Experiments: []Experiment{welcomeExperiment()},
+	})
+
+	variants := map[string]string{}
+	for i := 0; i < 10; i++ {
+		resp, _ := pp.HandleConnect(ctx, ConnectRequest{VisitorID: fmt.Sprintf("visitor-%d", i)})
+		variants[resp.SessionID] = resp.Experiments["welcome-copy"]
+	}
+
+	converted := map[string]int{}
+	n := 0
+	for sessionID, variant := range variants {
+		if n%2 == 0 {
+			_ = pp.TriggerEvent(ctx, sessionID, "signup", nil)
+			_ = pp.TriggerEvent(ctx, sessionID, "signup", nil) // counted once
+			converted[variant]++
+		} else {
+			_ = pp.TriggerEvent(ctx, sessionID, "clicked_pricing", nil)
+		}
+		n++
+	}
+
+	results, err := pp.ExperimentResults(ctx)
+	if err != nil {
+		t.Fatalf("ExperimentResults: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Variants) != 2 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	total := 0
+	for _, vr := range results[0].Variants {
+		total += vr.Sessions
+		if vr.Conversions != converted[vr.Variant] {
+			t.Errorf("variant %s: expected %d conversions, got %d", vr.Variant, converted[vr.Variant], vr.Conversions)
+		}
+		if vr.Sessions > 0 && vr.ConversionRate != float64(vr.Conversions)/float64(vr.Sessions) {
+			t.Errorf("variant %s: wrong conversion rate %v", vr.Variant, vr.ConversionRate)
+		}
+	}
+	if total != 10 {
+		t.Errorf("expected 10 sessions across variants, got %d", total)
+	}
+}
+
+func TestExperimentResultsUnsupported(t *testing.T) {
+	pp := New(Config{Storage: statsLessStorage{}, Experiments: []Experiment{welcomeExperiment()}})
+	if _, err := pp.ExperimentResults(context.Background()); !errors.Is(err, ErrExperimentResultsUnsupported) {
+		t.Errorf("expected ErrExperimentResultsUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ExportFormat is the file format written by ExportConversations.
+type ExportFormat string
+
+const (
+	// ExportCSV writes one CSV table with a row per session and per message
+	// (see ExportCSVHeader).
+	ExportCSV ExportFormat = "csv"
+	// ExportNDJSON writes one SnapshotRecord per line, session records
+	// followed by their messages.
+	ExportNDJSON ExportFormat = "ndjson"
+)
+
+// ContentType is the HTTP Content-Type of the format.
+func (f ExportFormat) ContentType() string {
+	if f == ExportNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ExportCSVHeader is the header row of CSV exports. The type column is
+// "session" or "message"; timestamp is the session's creation time or the
+// message's time. Message columns are empty on session rows, and visitor
+// columns on message rows.
+var ExportCSVHeader = []string{
+	"type", "session_id", "visitor_id", "email", "name", "region",
+	"message_id", "sender", "content", "thread_id", "reply_to", "attachments",
+	"timestamp", "edited_at", "deleted_at",
+}
+
+var (
+	// ErrExportUnsupported is returned by ExportConversations when the
+	// storage doesn't implement StorageWithListSessions.
+	ErrExportUnsupported = newError("not_configured", http.StatusNotFound, "export requires a storage that can list sessions")
+	// ErrInvalidExportFormat is returned for a format other than csv or
+	// ndjson.
+	ErrInvalidExportFormat = newError("invalid_export_format", http.StatusBadRequest, "export format must be csv or ndjson")
+)
+
+// ExportOptions selects what ExportConversations writes.
+type ExportOptions struct {
+	// Format defaults to ExportCSV.
+	Format ExportFormat
+	// From and To bound the export (inclusive / exclusive); zero values
+	// don't bound it. Sessions active in the range are exported with their
+	// messages sent in the range.
+	From time.Time
+	To   time.Time
+}
+
+// ParseExportQuery reads ExportOptions from the query parameters format,
+// from and to (RFC 3339), for export endpoints.
+func ParseExportQuery(query url.Values) (ExportOptions, error) {
+	opts := ExportOptions{Format: ExportFormat(query.Get("format"))}
+	if v := query.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return opts, ErrBadRequest
+		}
+		opts.From = t
+	}
+	if v := query.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return opts, ErrBadRequest
+		}
+		opts.To = t
+	}
+	if opts.Format == "" {
+		opts.Format = ExportCSV
+	}
+	if opts.Format != ExportCSV && opts.Format != ExportNDJSON {
+		return opts, ErrInvalidExportFormat
+	}
+	return opts, nil
+}
+
+// IncludesSession reports whether the session was active in the range:
+// created before To and last active at or after From.
+func (o ExportOptions) IncludesSession(session *Session) bool {
+	if !o.To.IsZero() && !session.CreatedAt.Before(o.To) {
+		return false
+	}
+	lastActive := session.LastActivity
+	if lastActive.Before(session.CreatedAt) {
+		lastActive = session.CreatedAt
+	}
+	return o.From.IsZero() || !lastActive.Before(o.From)
+}
+
+// IncludesMessage reports whether the message was sent in the range.
+func (o ExportOptions) IncludesMessage(message *Message) bool {
+	if !o.From.IsZero() && message.Timestamp.Before(o.From) {
+		return false
+	}
+	return o.To.IsZero() || message.Timestamp.Before(o.To)
+}
+
+// ExportStats counts the records written by ExportConversations.
+type ExportStats struct {
+	Sessions int `json:"sessions"`
+	Messages int `json:"messages"`
+}
+
+// ExportWriter writes sessions and messages in an ExportFormat. Records are
+// buffered until Flush.
+type ExportWriter struct {
+	bw  *bufio.Writer
+	csv *csv.Writer
+	enc *json.Encoder
+}
+
+// NewExportWriter returns an ExportWriter for format, writing the CSV
+// header right away.
+func NewExportWriter(w io.Writer, format ExportFormat) (*ExportWriter, error) {
+	e := &ExportWriter{bw: bufio.NewWriter(w)}
+	switch format {
+	case ExportCSV:
+		e.csv = csv.NewWriter(e.bw)
+		if err := e.csv.Write(ExportCSVHeader); err != nil {
+			return nil, err
+		}
+	case ExportNDJSON:
+		e.enc = json.NewEncoder(e.bw)
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+	return e, nil
+}
+
+// WriteSession writes a session record.
+func (e *ExportWriter) WriteSession(session *Session) error {
+	if e.enc != nil {
+		return e.enc.Encode(SnapshotRecord{Type: SnapshotRecordSession, Session: session})
+	}
+	var email, name string
+	if session.Identity != nil {
+		email, name = session.Identity.Email, session.Identity.Name
+	}
+	return e.csv.Write([]string{
+		SnapshotRecordSession, session.ID, session.VisitorID, email, name, session.Region,
+		"", "", "", "", "", "",
+		formatExportTime(&session.CreatedAt), "", "",
+	})
+}
+
+// WriteMessage writes a message record.
+func (e *ExportWriter) WriteMessage(message *Message) error {
+	if e.enc != nil {
+		return e.enc.Encode(SnapshotRecord{Type: SnapshotRecordMessage, Message: message})
+	}
+	return e.csv.Write([]string{
+		SnapshotRecordMessage, message.SessionID, "", "", "", "",
+		message.ID, string(message.Sender), message.Content, message.ThreadID, message.ReplyTo,
+		strconv.Itoa(len(message.Attachments)),
+		formatExportTime(&message.Timestamp), formatExportTime(message.EditedAt), formatExportTime(message.DeletedAt),
+	})
+}
+
+// Flush writes the buffered records to the underlying writer.
+func (e *ExportWriter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.bw.Flush()
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ExportConversations writes the sessions and messages in the range of opts
+// to w, oldest session first, for reporting and warehouse ingestion. Each
+// session is followed by its messages and flushed to w before the next one
+// is read, so large exports stream instead of piling up in memory.
+func (pp *PocketPing) ExportConversations(ctx context.Context, w io.Writer, opts ExportOptions) (*ExportStats, error) {
+	if opts.Format == "" {
+		opts.Format = ExportCSV
+	}
+	lister, ok := pp.storage.(StorageWithListSessions)
+	if !ok {
+		return nil, ErrExportUnsupported
+	}
+	ew, err := NewExportWriter(w, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+
+	stats := &ExportStats{}
+	for _, session := range sessions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !opts.IncludesSession(session) {
+			continue
+		}
+		if err := ew.WriteSession(session); err != nil {
+			return nil, err
+		}
+		stats.Sessions++
+
+		after := ""
+		for {
+			page, err := pp.storage.GetMessages(ctx, session.ID, after, snapshotPageSize)
+			if err != nil {
+				return nil, fmt.Errorf("get messages for %s: %w", session.ID, err)
+			}
+			for i := range page {
+				if !opts.IncludesMessage(&page[i]) {
+					continue
+				}
+				if err := ew.WriteMessage(&page[i]); err != nil {
+					return nil, err
+				}
+				stats.Messages++
+			}
+			if len(page) < snapshotPageSize {
+				break
+			}
+			after = page[len(page)-1].ID
+		}
+
+		if err := ew.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ew.Flush(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// exportFilename is the attachment filename for an export response.
+func exportFilename(opts ExportOptions) string {
+	name := "pocketping-export"
+	if !opts.From.IsZero() {
+		name += "-" + opts.From.UTC().Format("20060102")
+	}
+	return name + "." + string(opts.Format)
+}
+
+// HandleExport returns an export endpoint: a GET with the query parameters
+// of ParseExportQuery, answered with the file as an attachment. It has no
+// authentication of its own; mount it behind your admin auth.
+func (pp *PocketPing) HandleExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			WriteError(w, ErrMethodNotAllowed)
+			return
+		}
+		opts, err := ParseExportQuery(r.URL.Query())
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if _, ok := pp.storage.(StorageWithListSessions); !ok {
+			WriteError(w, ErrExportUnsupported)
+			return
+		}
+
+		w.Header().Set("Content-Type", opts.Format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="`+exportFilename(opts)+`"`)
+		if _, err := pp.ExportConversations(r.Context(), w, opts); err != nil {
+			// The status line is already sent; the truncated body is all
+			// the client gets.
+			log.Printf("[PocketPing] Export failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func seedExportStorage(t *testing.T) (*MemoryStorage, time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	day := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+
+	old := createTestSession("old", "v0", nil, nil)
+	old.CreatedAt, old.LastActivity = day.Add(-48*time.Hour), day.Add(-47*time.Hour)
+	_ = storage.CreateSession(ctx, old)
+	_ = storage.SaveMessage(ctx, &Message{ID: "m-old", SessionID: "old", Content: "old", Sender: SenderVisitor, Timestamp: old.CreatedAt})
+
+	s1 := createTestSession("s1", "v1", &UserIdentity{ID: "u1", Email: "ann@example.com", Name: "Ann"}, nil)
+	s1.CreatedAt, s1.LastActivity = day.Add(-time.Hour), day.Add(2*time.Hour)
+	_ = storage.CreateSession(ctx, s1)
+	_ = storage.SaveMessage(ctx, &Message{ID: "m-1", SessionID: "s1", Content: "before", Sender: SenderVisitor, Timestamp: day.Add(-time.Hour)})
+	_ = storage.SaveMessage(ctx, &Message{ID: "m-2", SessionID: "s1", Content: "Hi, \"quoted\"\nline", Sender: SenderVisitor, Timestamp: day.Add(time.Hour)})
+	_ = storage.SaveMessage(ctx, &Message{ID: "m-3", SessionID: "s1", Content: "Hello", Sender: SenderOperator, Timestamp: day.Add(2 * time.Hour)})
+	return storage, day
+}
+
+func TestExportConversationsCSV(t *testing.T) {
+	storage, day := seedExportStorage(t)
+	pp := New(Config{Storage: storage})
+
+	var buf bytes.Buffer
+	stats, err := pp.ExportConversations(context.Background(), &buf, ExportOptions{From: day, To: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("ExportConversations: %v", err)
+	}
+	if *stats != (ExportStats{Sessions: 1, Messages: 2}) {
+		t.Errorf("stats = %+v", *stats)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != strings.Join(ExportCSVHeader, ",") {
+		t.Fatalf("rows = %q", rows)
+	}
+	if rows[1][0] != "session" || rows[1][1] != "s1" || rows[1][3] != "ann@example.com" {
+		t.Errorf("session row = %q", rows[1])
+	}
+	if rows[2][0] != "message" || rows[2][6] != "m-2" || rows[2][8] != "Hi, \"quoted\"\nline" || rows[2][12] != "2026-05-04T01:00:00Z" {
+		t.Errorf("message row = %q", rows[2])
+	}
+	if rows[3][6] != "m-3" || rows[3][7] != "operator" {
+		t.Errorf("message row = %q", rows[3])
+	}
+}
+
+func TestExportConversationsNDJSON(t *testing.T) {
+	storage, _ := seedExportStorage(t)
+	pp := New(Config{Storage: storage})
+
+	var buf bytes.Buffer
+	stats, err := pp.ExportConversations(context.Background(), &buf, ExportOptions{Format: ExportNDJSON})
+	if err != nil {
+		t.Fatalf("ExportConversations: %v", err)
+	}
+	if *stats != (ExportStats{Sessions: 2, Messages: 4}) {
+		t.Errorf("stats = %+v", *stats)
+	}
+
+	var types []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec SnapshotRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		types = append(types, rec.Type)
+	}
+	if got := strings.Join(types, ","); got != "session,message,session,message,message,message" {
+		t.Errorf("records = %s", got)
+	}
+}
+
+func TestExportConversationsUnsupported(t *testing.T) {
+	pp := New(Config{Storage: statsLessStorage{}})
+	if _, err := pp.ExportConversations(context.Background(), &bytes.Buffer{}, ExportOptions{}); !errors.Is(err, ErrExportUnsupported) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	storage, _ := seedExportStorage(t)
+	handler := New(Config{Storage: storage}).HandleExport()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/export?"+query, nil))
+		return rec
+	}
+
+	rec := get("format=ndjson&from=2026-05-04T00:00:00Z")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "pocketping-export-20260504.ndjson") {
+		t.Errorf("Content-Disposition = %q", rec.Header().Get("Content-Disposition"))
+	}
+	if lines := strings.Count(rec.Body.String(), "\n"); lines != 3 {
+		t.Errorf("lines = %d, body %s", lines, rec.Body)
+	}
+
+	if rec := get("format=xlsx"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad format: status = %d", rec.Code)
+	}
+	if rec := get("from=yesterday"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad from: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,365 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultFaultTimeout is how long an injected timeout hangs when
+// FaultConfig.Timeout is zero and the call's context has no deadline.
+const DefaultFaultTimeout = 30 * time.Second
+
+var (
+	// ErrInjectedFault is returned by FaultInjector for a call it failed.
+	ErrInjectedFault = errors.New("injected bridge fault")
+	// ErrInjectedTimeout is returned by FaultInjector for a call it hung. It
+	// wraps context.DeadlineExceeded.
+	ErrInjectedTimeout = fmt.Errorf("injected bridge timeout: %w", context.DeadlineExceeded)
+)
+
+// FaultConfig sets the faults a FaultInjector injects. Rates are
+// probabilities from 0 to 1, drawn per call.
+type FaultConfig struct {
+	// ErrorRate fails calls with ErrInjectedFault, without reaching the
+	// bridge.
+	ErrorRate float64
+	// TimeoutRate hangs calls until their context is done or Timeout
+	// elapses, then fails them with ErrInjectedTimeout.
+	TimeoutRate float64
+	// Timeout bounds injected hangs (default DefaultFaultTimeout).
+	Timeout time.Duration
+	// Latency delays every call, plus up to LatencyJitter at random.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// Methods limits faults to these Bridge methods, e.g.
+	// "OnVisitorMessage". Empty means every method but Init and Destroy.
+	Methods []string
+	// Seed makes the faults reproducible. Zero seeds from the clock.
+	Seed int64
+}
+
+// FaultStats counts the calls a FaultInjector targeted (see
+// FaultConfig.Methods) and the faults it injected.
+type FaultStats struct {
+	Calls    int64 `json:"calls"`
+	Errors   int64 `json:"errors"`
+	Timeouts int64 `json:"timeouts"`
+}
+
+// FaultInjector wraps a bridge and injects errors, latency and timeouts
+// into its calls, to check retries, circuit breakers and queues against a
+// simulated platform outage in staging. It keeps the wrapped bridge's name,
+// so duplicate suppression and echo filtering are unchanged.
+//
+// FaultInjector implements BridgeWithEditDelete, BridgeWithNotify,
+// BridgeWithAIMessage, BridgeWithAttachments, BridgeWithMentions,
+// BridgeWithDirectMessages and BridgeWithDestination; those calls are
+// no-ops when the wrapped bridge doesn't support them, except direct
+// messages, which fail.
+type FaultInjector struct {
+	bridge Bridge
+
+	mu     sync.Mutex
+	config FaultConfig
+	rng    *rand.Rand
+	stats  FaultStats
+}
+
+// NewFaultInjector wraps bridge with the faults of config.
+func NewFaultInjector(bridge Bridge, config FaultConfig) *FaultInjector {
+	f := &FaultInjector{bridge: bridge}
+	f.SetConfig(config)
+	log.Printf("[PocketPing] Fault injection enabled on bridge %s: %.0f%% errors, %.0f%% timeouts, %s latency",
+		bridge.Name(), config.ErrorRate*100, config.TimeoutRate*100, config.Latency)
+	return f
+}
+
+// SetConfig replaces the faults, e.g. to start or end a simulated outage.
+// The random source is reseeded when config.Seed is set.
+func (f *FaultInjector) SetConfig(config FaultConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+	if f.rng == nil || config.Seed != 0 {
+		seed := config.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		f.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// Stats returns the calls targeted and the faults injected so far.
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// Unwrap returns the wrapped bridge.
+func (f *FaultInjector) Unwrap() Bridge {
+	return f.bridge
+}
+
+// inject applies the faults for a call of method. A non-nil error means
+// the call must fail without reaching the bridge.
+func (f *FaultInjector) inject(ctx context.Context, method string) error {
+	f.mu.Lock()
+	config := f.config
+	if !faultTargets(config.Methods, method) {
+		f.mu.Unlock()
+		return nil
+	}
+	f.stats.Calls++
+	delay := config.Latency
+	if config.LatencyJitter > 0 {
+		delay += time.Duration(f.rng.Int63n(int64(config.LatencyJitter)))
+	}
+	roll := f.rng.Float64()
+	timeout := roll < config.TimeoutRate
+	fail := !timeout && roll < config.TimeoutRate+config.ErrorRate
+	if timeout {
+		f.stats.Timeouts++
+	} else if fail {
+		f.stats.Errors++
+	}
+	f.mu.Unlock()
+
+	if timeout {
+		hang := config.Timeout
+		if hang <= 0 {
+			hang = DefaultFaultTimeout
+		}
+		sleepCtx(ctx, hang)
+		return ErrInjectedTimeout
+	}
+	if delay > 0 && !sleepCtx(ctx, delay) {
+		return ctx.Err()
+	}
+	if fail {
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+func faultTargets(methods []string, method string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepCtx waits for d, or until ctx is done; it reports whether d elapsed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Name returns the wrapped bridge's name.
+func (f *FaultInjector) Name() string {
+	return f.bridge.Name()
+}
+
+// Regions returns the wrapped bridge's regions.
+func (f *FaultInjector) Regions() []string {
+	if rb, ok := f.bridge.(BridgeWithRegions); ok {
+		return rb.Regions()
+	}
+	return nil
+}
+
+// Init initializes the wrapped bridge, without faults.
+func (f *FaultInjector) Init(ctx context.Context, pp *PocketPing) error {
+	return f.bridge.Init(ctx, pp)
+}
+
+// OnNewSession forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnNewSession(ctx context.Context, session *Session) error {
+	if err := f.inject(ctx, "OnNewSession"); err != nil {
+		return err
+	}
+	return f.bridge.OnNewSession(ctx, session)
+}
+
+// OnVisitorMessage forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	if err := f.inject(ctx, "OnVisitorMessage"); err != nil {
+		return err
+	}
+	return f.bridge.OnVisitorMessage(ctx, message, session)
+}
+
+// OnOperatorMessage forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if err := f.inject(ctx, "OnOperatorMessage"); err != nil {
+		return err
+	}
+	return f.bridge.OnOperatorMessage(ctx, message, session, sourceBridge, operatorName)
+}
+
+// OnAIMessage forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	if err := f.inject(ctx, "OnAIMessage"); err != nil {
+		return err
+	}
+	return deliverAIMessage(ctx, f.bridge, message, session)
+}
+
+// Notify forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) Notify(ctx context.Context, session *Session, message string) error {
+	notifier, ok := f.bridge.(BridgeWithNotify)
+	if !ok {
+		return nil
+	}
+	if err := f.inject(ctx, "Notify"); err != nil {
+		return err
+	}
+	return notifier.Notify(ctx, session, message)
+}
+
+// OnVisitorAttachments forwards to the wrapped bridge unless a fault is
+// injected.
+func (f *FaultInjector) OnVisitorAttachments(ctx context.Context, message *Message, session *Session, attachments []Attachment) error {
+	uploader, ok := f.bridge.(BridgeWithAttachments)
+	if !ok {
+		return nil
+	}
+	if err := f.inject(ctx, "OnVisitorAttachments"); err != nil {
+		return err
+	}
+	return uploader.OnVisitorAttachments(ctx, message, session, attachments)
+}
+
+// Mention returns the wrapped bridge's mention syntax, without faults.
+func (f *FaultInjector) Mention(mention OperatorMention) string {
+	if mentioner, ok := f.bridge.(BridgeWithMentions); ok {
+		return mentioner.Mention(mention)
+	}
+	return ""
+}
+
+// SendDirectMessage forwards to the wrapped bridge unless a fault is
+// injected.
+func (f *FaultInjector) SendDirectMessage(ctx context.Context, userID, text string) error {
+	messenger, ok := f.bridge.(BridgeWithDirectMessages)
+	if !ok {
+		return fmt.Errorf("bridge %s doesn't support direct messages", f.bridge.Name())
+	}
+	if err := f.inject(ctx, "SendDirectMessage"); err != nil {
+		return err
+	}
+	return messenger.SendDirectMessage(ctx, userID, text)
+}
+
+// Destination returns the wrapped bridge's destination, so duplicate
+// suppression is unchanged.
+func (f *FaultInjector) Destination() string {
+	if d, ok := f.bridge.(BridgeWithDestination); ok {
+		return d.Destination()
+	}
+	return ""
+}
+
+// OnTyping forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
+	if err := f.inject(ctx, "OnTyping"); err != nil {
+		return err
+	}
+	return f.bridge.OnTyping(ctx, sessionID, isTyping)
+}
+
+// OnMessageRead forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status MessageStatus) error {
+	if err := f.inject(ctx, "OnMessageRead"); err != nil {
+		return err
+	}
+	return f.bridge.OnMessageRead(ctx, sessionID, messageIDs, status)
+}
+
+// OnCustomEvent forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnCustomEvent(ctx context.Context, event CustomEvent, session *Session) error {
+	if err := f.inject(ctx, "OnCustomEvent"); err != nil {
+		return err
+	}
+	return f.bridge.OnCustomEvent(ctx, event, session)
+}
+
+// OnIdentityUpdate forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnIdentityUpdate(ctx context.Context, session *Session) error {
+	if err := f.inject(ctx, "OnIdentityUpdate"); err != nil {
+		return err
+	}
+	return f.bridge.OnIdentityUpdate(ctx, session)
+}
+
+// OnMessageEdit forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnMessageEdit(ctx context.Context, sessionID, messageID, content string, editedAt time.Time) (*BridgeMessageResult, error) {
+	editor, ok := f.bridge.(BridgeWithEditDelete)
+	if !ok {
+		return nil, nil
+	}
+	if err := f.inject(ctx, "OnMessageEdit"); err != nil {
+		return nil, err
+	}
+	return editor.OnMessageEdit(ctx, sessionID, messageID, content, editedAt)
+}
+
+// OnMessageDelete forwards to the wrapped bridge unless a fault is injected.
+func (f *FaultInjector) OnMessageDelete(ctx context.Context, sessionID, messageID string, deletedAt time.Time) error {
+	editor, ok := f.bridge.(BridgeWithEditDelete)
+	if !ok {
+		return nil
+	}
+	if err := f.inject(ctx, "OnMessageDelete"); err != nil {
+		return err
+	}
+	return editor.OnMessageDelete(ctx, sessionID, messageID, deletedAt)
+}
+
+// Destroy cleans up the wrapped bridge, without faults.
+func (f *FaultInjector) Destroy(ctx context.Context) error {
+	return f.bridge.Destroy(ctx)
+}
+
+// Ensure FaultInjector implements Bridge interface
+var _ Bridge = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithEditDelete interface
+var _ BridgeWithEditDelete = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithRegions interface
+var _ BridgeWithRegions = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithAttachments interface
+var _ BridgeWithAttachments = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithMentions interface
+var _ BridgeWithMentions = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithDirectMessages interface
+var _ BridgeWithDirectMessages = (*FaultInjector)(nil)
+
+// Ensure FaultInjector implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*FaultInjector)(nil)
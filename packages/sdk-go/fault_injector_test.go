@@ -0,0 +1,156 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFaultInjectorErrorRate(t *testing.T) {
+	ctx := context.Background()
+	inner := newRecordingBridge("slack")
+	f := NewFaultInjector(inner, FaultConfig{ErrorRate: 0.3, Seed: 42})
+	if f.Name() != "slack" {
+		t.Errorf("Name = %q", f.Name())
+	}
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if err := f.OnVisitorMessage(ctx, &Message{ID: "m"}, &Session{ID: "s"}); err != nil {
+			if !errors.Is(err, ErrInjectedFault) {
+				t.Fatalf("err = %v", err)
+			}
+			failed++
+		}
+	}
+	if failed < 250 || failed > 350 {
+		t.Errorf("failed %d of 1000 calls at a 30%% error rate", failed)
+	}
+	stats := f.Stats()
+	if stats.Calls != 1000 || stats.Errors != int64(failed) || len(inner.messages) != 1000-failed {
+		t.Errorf("stats = %+v, delivered %d", stats, len(inner.messages))
+	}
+
+	// Ending the outage lets every call through
+	f.SetConfig(FaultConfig{})
+	if err := f.OnVisitorMessage(ctx, &Message{ID: "m"}, &Session{ID: "s"}); err != nil {
+		t.Errorf("err after the outage = %v", err)
+	}
+}
+
+func TestFaultInjectorTimeout(t *testing.T) {
+	f := NewFaultInjector(newRecordingBridge("slack"), FaultConfig{TimeoutRate: 1, Timeout: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := f.OnNewSession(ctx, &Session{ID: "s"})
+	if !errors.Is(err, ErrInjectedTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("hung %s past the context deadline", elapsed)
+	}
+	if f.Stats().Timeouts != 1 {
+		t.Errorf("stats = %+v", f.Stats())
+	}
+}
+
+func TestFaultInjectorLatencyAndMethods(t *testing.T) {
+	ctx := context.Background()
+	inner := newRecordingBridge("slack")
+	f := NewFaultInjector(inner, FaultConfig{ErrorRate: 1, Latency: 30 * time.Millisecond, Methods: []string{"OnVisitorMessage"}})
+
+	// Methods outside the list are untouched
+	start := time.Now()
+	if err := f.OnOperatorMessage(ctx, &Message{ID: "op"}, &Session{ID: "s"}, "telegram", "Op"); err != nil {
+		t.Fatalf("OnOperatorMessage: %v", err)
+	}
+	if time.Since(start) >= 30*time.Millisecond || len(inner.operator) != 1 {
+		t.Error("expected OnOperatorMessage without faults")
+	}
+
+	start = time.Now()
+	if err := f.OnVisitorMessage(ctx, &Message{ID: "m"}, &Session{ID: "s"}); !errors.Is(err, ErrInjectedFault) {
+		t.Fatalf("err = %v", err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Error("expected the injected latency")
+	}
+}
+
+func TestFaultInjectorOptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+
+	// A bridge without edit/delete or notify support is not called for them
+	plain := NewFaultInjector(newRecordingBridge("plain"), FaultConfig{ErrorRate: 1})
+	if _, err := plain.OnMessageEdit(ctx, "s", "m", "x", time.Now()); err != nil {
+		t.Errorf("OnMessageEdit = %v", err)
+	}
+	if err := plain.Notify(ctx, &Session{ID: "s"}, "hi"); err != nil {
+		t.Errorf("Notify = %v", err)
+	}
+
+	spy := &editDeleteSpyBridge{BaseBridge: BaseBridge{BridgeName: "spy"}, editCh: make(chan struct{}, 1), deleteCh: make(chan struct{}, 1)}
+	f := NewFaultInjector(spy, FaultConfig{})
+	if _, err := f.OnMessageEdit(ctx, "s", "m", "x", time.Now()); err != nil {
+		t.Fatalf("OnMessageEdit: %v", err)
+	}
+	if err := f.OnMessageDelete(ctx, "s", "m", time.Now()); err != nil {
+		t.Fatalf("OnMessageDelete: %v", err)
+	}
+	if len(spy.editCh) != 1 || len(spy.deleteCh) != 1 {
+		t.Errorf("edits = %d, deletes = %d", len(spy.editCh), len(spy.deleteCh))
+	}
+
+	if err := plain.SendDirectMessage(ctx, "42", "hi"); err == nil {
+		t.Error("SendDirectMessage succeeded on a bridge without direct messages")
+	}
+	if plain.Mention(OperatorMention{UserID: "42"}) != "" || plain.Destination() != "" {
+		t.Error("expected no mention syntax or destination from a plain bridge")
+	}
+
+	dm := &dmRecordingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}
+	if err := NewFaultInjector(dm, FaultConfig{}).SendDirectMessage(ctx, "42", "hi"); err != nil || len(dm.sent()) != 1 {
+		t.Errorf("SendDirectMessage = %v, sent %q", err, dm.sent())
+	}
+	if err := NewFaultInjector(dm, FaultConfig{ErrorRate: 1}).SendDirectMessage(ctx, "42", "hi"); !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("SendDirectMessage = %v, want ErrInjectedFault", err)
+	}
+	uploader := &uploadRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}
+	err := NewFaultInjector(uploader, FaultConfig{}).OnVisitorAttachments(ctx, &Message{}, &Session{ID: "s"}, []Attachment{{ID: "a1"}})
+	if err != nil || len(uploader.files) != 1 {
+		t.Errorf("OnVisitorAttachments = %v, files %d", err, len(uploader.files))
+	}
+	mentioner := NewFaultInjector(&mentionRecordingBridge{notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}}, FaultConfig{ErrorRate: 1})
+	if got := mentioner.Mention(OperatorMention{UserID: "U42"}); got != "<@U42>" {
+		t.Errorf("Mention = %q", got)
+	}
+	destination := BridgeDestination("telegram", "-100")
+	if got := NewFaultInjector(&destinationBridge{destination: destination}, FaultConfig{}).Destination(); got != destination {
+		t.Errorf("Destination = %q, want %q", got, destination)
+	}
+
+	regional := NewFaultInjector(&BaseBridge{BridgeName: "eu", BridgeRegions: []string{"eu"}}, FaultConfig{})
+	if got := regional.Regions(); len(got) != 1 || got[0] != "eu" {
+		t.Errorf("Regions = %v", got)
+	}
+}
+
+func TestFaultInjectorInPipeline(t *testing.T) {
+	ctx := context.Background()
+	inner := newRecordingBridge("slack")
+	f := NewFaultInjector(inner, FaultConfig{ErrorRate: 1})
+	pp := New(Config{Bridges: []Bridge{f}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	// The outage fails the notification, not the visitor's request
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "Hi", Sender: SenderVisitor}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	pp.dispatcher.wait()
+	if len(inner.messages) != 0 || f.Stats().Errors == 0 {
+		t.Errorf("delivered = %d, stats = %+v", len(inner.messages), f.Stats())
+	}
+}
@@ -0,0 +1,71 @@
+package pocketping
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+)
+
+// Well-known feature flags understood by the widget.
+const (
+	FlagAttachments = "attachments"
+)
+
+// FlagResolver returns the feature flags for a session, e.g. enabling
+// attachments for paid plans or a rollout percentage per tenant. Returned
+// flags are applied over Config.FeatureFlags; flags it omits keep their
+// default. projectID is Session.ProjectID, the ConnectRequest.ProjectID
+// the session connected with.
+//
+// On error the defaults are used, so a flag service outage doesn't break the
+// widget.
+type FlagResolver func(ctx context.Context, session *Session, projectID string) (map[string]bool, error)
+
+// FeatureFlags returns the resolved flags for a session, for server-side
+// checks that must agree with what the widget was told: the resolver's
+// flags merged over the defaults. Nil when no flags are configured.
+func (pp *PocketPing) FeatureFlags(ctx context.Context, session *Session) map[string]bool {
+	var resolved map[string]bool
+	if pp.config.FlagResolver != nil {
+		flags, err := pp.config.FlagResolver(ctx, session, session.ProjectID)
+		if err != nil {
+			log.Printf("[PocketPing] Feature flag resolution failed for session %s, using defaults: %v", session.ID, err)
+		} else {
+			resolved = flags
+		}
+	}
+
+	if len(pp.config.FeatureFlags) == 0 && len(resolved) == 0 {
+		return nil
+	}
+	flags := make(map[string]bool, len(pp.config.FeatureFlags)+len(resolved))
+	for name, enabled := range pp.config.FeatureFlags {
+		flags[name] = enabled
+	}
+	for name, enabled := range resolved {
+		flags[name] = enabled
+	}
+	return flags
+}
+
+// InRollout reports whether key (a visitor or session ID) falls within the
+// first percent of a stable per-flag bucketing, for gradual rollouts from a
+// FlagResolver. The same key always gets the same answer for a flag.
+func InRollout(flag, key string, percent int) bool {
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+	return int(stableBucket(flag, key, 100)) < percent
+}
+
+// stableBucket hashes (namespace, key) into [0, n).
+func stableBucket(namespace, key string, n uint32) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return h.Sum32() % n
+}
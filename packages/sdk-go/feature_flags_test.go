@@ -0,0 +1,146 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func paidPlanResolver(ctx context.Context, session *Session, projectID string) (map[string]bool, error) {
+	if session.Identity != nil && session.Identity.Extra["plan"] == "paid" {
+		return map[string]bool{FlagAttachments: true}, nil
+	}
+	return nil, nil
+}
+
+func TestHandleConnectFeatureFlags(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{
+		Storage:      NewMemoryStorage(),
+		FeatureFlags: map[string]bool{FlagAttachments: false, "csat": true},
+		FlagResolver: paidPlanResolver,
+	})
+
+	free, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v-free"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if free.FeatureFlags[FlagAttachments] || !free.FeatureFlags["csat"] {
+		t.Errorf("unexpected free flags: %v", free.FeatureFlags)
+	}
+
+	paid, err := pp.HandleConnect(ctx, ConnectRequest{
+		VisitorID: "v-paid",
+		Identity:  &UserIdentity{ID: "u1", Extra: map[string]interface{}{"plan": "paid"}},
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if !paid.FeatureFlags[FlagAttachments] || !paid.FeatureFlags["csat"] {
+		t.Errorf("unexpected paid flags: %v", paid.FeatureFlags)
+	}
+}
+
+func TestHandleConnectFeatureFlagResolverError(t *testing.T) {
+	pp := New(Config{
+		Storage:      NewMemoryStorage(),
+		FeatureFlags: map[string]bool{"csat": true},
+		FlagResolver: func(ctx context.Context, session *Session, projectID string) (map[string]bool, error) {
+			return nil, errors.New("flag service down")
+		},
+	})
+
+	resp, err := pp.HandleConnect(context.Background(), ConnectRequest{VisitorID: "v1"})
+	if err != nil {
+		t.Fatalf("resolver errors must not fail connect: %v", err)
+	}
+	if !resp.FeatureFlags["csat"] {
+		t.Errorf("expected defaults on resolver error, got %v", resp.FeatureFlags)
+	}
+}
+
+func TestFeatureFlagsPassesProjectID(t *testing.T) {
+	ctx := context.Background()
+	var projects []string
+	pp := New(Config{
+		Storage: NewMemoryStorage(),
+		FlagResolver: func(ctx context.Context, session *Session, projectID string) (map[string]bool, error) {
+			projects = append(projects, projectID)
+			return map[string]bool{FlagAttachments: projectID == "proj-paid"}, nil
+		},
+	})
+
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", ProjectID: "proj-paid"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	session, _ := pp.storage.GetSession(ctx, resp.SessionID)
+	if session.ProjectID != "proj-paid" {
+		t.Fatalf("expected the session to keep its project ID, got %q", session.ProjectID)
+	}
+	if !pp.FeatureFlags(ctx, session)[FlagAttachments] {
+		t.Error("expected server-side flags to resolve for the session's project")
+	}
+	for _, projectID := range projects {
+		if projectID != "proj-paid" {
+			t.Errorf("expected the resolver to get proj-paid, got %q", projectID)
+		}
+	}
+}
+
+func TestHandleConnectNoFeatureFlags(t *testing.T) {
+	pp := New(Config{Storage: NewMemoryStorage()})
+	resp, _ := pp.HandleConnect(context.Background(), ConnectRequest{VisitorID: "v1"})
+	if resp.FeatureFlags != nil {
+		t.Errorf("expected no flags, got %v", resp.FeatureFlags)
+	}
+}
+
+func TestUploadRespectsAttachmentsFlag(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	pp := New(Config{
+		Storage:      storage,
+		FeatureFlags: map[string]bool{FlagAttachments: false},
+		FlagResolver: paidPlanResolver,
+	})
+
+	free := createTestSession("sess-free", "v1", nil, nil)
+	paid := createTestSession("sess-paid", "v2", &UserIdentity{ID: "u2", Extra: map[string]interface{}{"plan": "paid"}}, nil)
+	_ = storage.CreateSession(ctx, free)
+	_ = storage.CreateSession(ctx, paid)
+
+	upload := UploadRequest{Filename: "photo.jpg", MimeType: "image/jpeg", Size: 1024}
+
+	upload.SessionID = free.ID
+	if _, err := pp.HandleUploadRequest(ctx, upload); !errors.Is(err, ErrAttachmentsDisabled) {
+		t.Errorf("expected ErrAttachmentsDisabled, got %v", err)
+	}
+
+	upload.SessionID = paid.ID
+	if _, err := pp.HandleUploadRequest(ctx, upload); err != nil {
+		t.Errorf("expected paid upload to succeed, got %v", err)
+	}
+}
+
+func TestInRollout(t *testing.T) {
+	if InRollout("new-ui", "v1", 0) || !InRollout("new-ui", "v1", 100) {
+		t.Error("0% and 100% rollouts must be exact")
+	}
+
+	in := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("visitor-%d", i)
+		got := InRollout("new-ui", key, 30)
+		if got != InRollout("new-ui", key, 30) {
+			t.Fatal("rollout must be stable for a key")
+		}
+		if got {
+			in++
+		}
+	}
+	if in < 230 || in > 370 {
+		t.Errorf("expected ~30%% in rollout, got %d/1000", in)
+	}
+}
@@ -0,0 +1,205 @@
+package pocketping
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Fixture is one recorded bridge API exchange. URLs and bodies are sanitized
+// before they are stored, so fixture files can be committed.
+type Fixture struct {
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Request  string `json:"request,omitempty"`
+	Status   int    `json:"status"`
+	Response string `json:"response"`
+}
+
+// fixtureSecretKeys are JSON fields whose values are masked in fixtures.
+var fixtureSecretKeys = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"bot_token":     true,
+	"client_secret": true,
+	"secret":        true,
+}
+
+// RecordingTransport passes bridge API requests to the real platform and
+// saves each exchange to a fixture file, for later use with ReplayTransport.
+type RecordingTransport struct {
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	fixtures []Fixture
+}
+
+// NewRecordingTransport returns a transport that sends requests through next
+// (http.DefaultTransport when nil) and rewrites the fixture file at path after
+// every exchange. Plug it into a bridge with its HTTP client option, e.g.
+// WithTelegramHTTPClient.
+func NewRecordingTransport(path string, next http.RoundTripper) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RecordingTransport{path: path, next: next}
+}
+
+func (r *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fixture := Fixture{Method: req.Method, URL: RedactURL(req.URL)}
+	if req.Body != nil {
+		payload, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("record: read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		fixture.Request = string(sanitizeFixtureBody(payload))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("record: read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	fixture.Status = resp.StatusCode
+	fixture.Response = string(sanitizeFixtureBody(body))
+
+	r.mu.Lock()
+	r.fixtures = append(r.fixtures, fixture)
+	err = writeFixtures(r.path, r.fixtures)
+	r.mu.Unlock()
+	if err != nil {
+		log.Printf("[PocketPing] Failed to write fixtures: %v", err)
+	}
+	return resp, nil
+}
+
+// Fixtures returns the exchanges recorded so far.
+func (r *RecordingTransport) Fixtures() []Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Fixture(nil), r.fixtures...)
+}
+
+// LoadFixtures reads a fixture file written by RecordingTransport.
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// ReplayTransport answers bridge API requests from recorded fixtures without
+// touching the network. Each fixture is used once, in recorded order per
+// method and URL; a request without a remaining fixture fails.
+type ReplayTransport struct {
+	mu       sync.Mutex
+	fixtures []Fixture
+	used     []bool
+}
+
+// NewReplayTransport returns a transport that replays fixtures.
+func NewReplayTransport(fixtures []Fixture) *ReplayTransport {
+	return &ReplayTransport{fixtures: fixtures, used: make([]bool, len(fixtures))}
+}
+
+func (r *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	url := RedactURL(req.URL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, fixture := range r.fixtures {
+		if r.used[i] || fixture.Method != req.Method || fixture.URL != url {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			StatusCode: fixture.Status,
+			Status:     fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(bytes.NewBufferString(fixture.Response)),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("replay: no fixture for %s %s", req.Method, url)
+}
+
+// Remaining returns how many fixtures have not been replayed yet.
+func (r *ReplayTransport) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+func writeFixtures(path string, fixtures []Fixture) error {
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// sanitizeFixtureBody masks credential fields in a JSON body. Other bodies are
+// returned unchanged.
+func sanitizeFixtureBody(body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	if !maskSecrets(value) {
+		return body
+	}
+	sanitized, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return sanitized
+}
+
+// maskSecrets replaces secret values in place and reports whether any were
+// found.
+func maskSecrets(value interface{}) bool {
+	masked := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := field.(string); ok && fixtureSecretKeys[key] {
+				v[key] = "***"
+				masked = true
+				continue
+			}
+			masked = maskSecrets(field) || masked
+		}
+	case []interface{}:
+		for _, item := range v {
+			masked = maskSecrets(item) || masked
+		}
+	}
+	return masked
+}
@@ -0,0 +1,68 @@
+package pocketping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplayFixtures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"result":{"message_id":42,"token":"leaked-secret"}}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "telegram.json")
+	recorder := NewRecordingTransport(path, &testTransport{baseURL: srv.URL, token: "test-token"})
+	bridge := MustNewTelegramBridge("test-token", "-1001234", WithTelegramHTTPClient(&http.Client{Transport: recorder}))
+	if _, err := bridge.sendMessage(context.Background(), "Hello", nil); err != nil {
+		t.Fatalf("sendMessage while recording: %v", err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	if len(fixtures) != 1 || fixtures[0].URL != "api.telegram.org/***/sendMessage" || fixtures[0].Status != http.StatusOK {
+		t.Fatalf("unexpected fixtures: %+v", fixtures)
+	}
+	if strings.Contains(fixtures[0].Response, "leaked-secret") || !strings.Contains(fixtures[0].Request, "Hello") {
+		t.Errorf("fixture not sanitized or missing the payload: %+v", fixtures[0])
+	}
+
+	srv.Close()
+	replay := NewReplayTransport(fixtures)
+	bridge = MustNewTelegramBridge("other-token", "-1001234", WithTelegramHTTPClient(&http.Client{Transport: replay}))
+	result, err := bridge.sendMessage(context.Background(), "Hello", nil)
+	if err != nil || result.TelegramMessageID != 42 {
+		t.Fatalf("replayed sendMessage = %+v, %v; want message 42", result, err)
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("expected every fixture to be used, %d left", replay.Remaining())
+	}
+	if _, err := bridge.sendMessage(context.Background(), "Again", nil); err == nil {
+		t.Error("expected an error once fixtures are exhausted")
+	}
+}
+
+func TestRecordingRedactsWebhookURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slack.json")
+	recorder := NewRecordingTransport(path, NewDryRunTransport("slack"))
+	captureLog(t)
+
+	req, _ := http.NewRequest("POST", "https://hooks.slack.com/services/T000/B000/short", strings.NewReader(`{"text":"hi"}`))
+	if _, err := recorder.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	if len(fixtures) != 1 || fixtures[0].URL != "hooks.slack.com/***" {
+		t.Errorf("fixtures = %+v, want the webhook URL redacted", fixtures)
+	}
+}
@@ -0,0 +1,393 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Errors of forms.
+var (
+	// ErrFormNotFound is returned by SendNamedForm for a name missing from
+	// Config.Forms, and by HandleFormSubmit when the message doesn't carry a
+	// form of the session.
+	ErrFormNotFound = newError("form_not_found", http.StatusNotFound, "form not found")
+	// ErrInvalidForm is wrapped by the *FormValidationError HandleFormSubmit
+	// returns for answers that don't fit the form, and returned by SendForm
+	// for a form without fields.
+	ErrInvalidForm = newError("invalid_form", http.StatusBadRequest, "Some answers are missing or invalid")
+	// ErrFormAlreadySubmitted is returned by HandleFormSubmit for a form
+	// already answered.
+	ErrFormAlreadySubmitted = newError("form_already_submitted", http.StatusConflict, "form already submitted")
+)
+
+// FormFieldType is the input a FormField is shown as.
+type FormFieldType string
+
+// Form field types.
+const (
+	// FormFieldText is a single line of text.
+	FormFieldText FormFieldType = "text"
+	// FormFieldTextarea is free text over several lines.
+	FormFieldTextarea FormFieldType = "textarea"
+	// FormFieldEmail is an email address, checked with ValidateEmail.
+	FormFieldEmail FormFieldType = "email"
+	// FormFieldNumber is a number between Min and Max, when set.
+	FormFieldNumber FormFieldType = "number"
+	// FormFieldSelect is one of Options.
+	FormFieldSelect FormFieldType = "select"
+	// FormFieldRating is a whole number from 1 to Max (default 5).
+	FormFieldRating FormFieldType = "rating"
+	// FormFieldCheckbox is "true" or "false".
+	FormFieldCheckbox FormFieldType = "checkbox"
+)
+
+// DefaultFormRatingMax is the top of a rating field without Max.
+const DefaultFormRatingMax = 5
+
+// FormField is a question of a form.
+type FormField struct {
+	// Name keys the answer in FormSubmitRequest.Answers.
+	Name string `json:"name"`
+	// Label is the question shown to the visitor. Defaults to Name.
+	Label string `json:"label,omitempty"`
+	// Type defaults to FormFieldText.
+	Type     FormFieldType `json:"type,omitempty"`
+	Required bool          `json:"required,omitempty"`
+	// Options are the choices of a select field.
+	Options []string `json:"options,omitempty"`
+	// Min and Max bound number fields; Max is the top of a rating field.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// MaxLength caps text, textarea and email answers, in characters.
+	// Text and textarea answers are always capped at
+	// MaxMessageContentLength.
+	MaxLength int `json:"maxLength,omitempty"`
+}
+
+// FormDefinition is a form operators can send, e.g. a feedback survey.
+type FormDefinition struct {
+	Title  string      `json:"title"`
+	Fields []FormField `json:"fields"`
+}
+
+// FormStatus is the state of a Form.
+type FormStatus string
+
+// Form statuses.
+const (
+	FormPending   FormStatus = "pending"
+	FormCompleted FormStatus = "completed"
+)
+
+// Form is a form sent to the visitor. It rides on an operator message
+// (Message.Form); the widget renders its fields and posts the answers to
+// HandleFormSubmit.
+type Form struct {
+	ID string `json:"id"`
+	// Name is the Config.Forms key the form was sent by, if any.
+	Name string `json:"name,omitempty"`
+	FormDefinition
+	Status      FormStatus `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+	// Answers are the visitor's answers, by field name, once completed.
+	Answers map[string]string `json:"answers,omitempty"`
+}
+
+// FormSubmitRequest is the visitor's answers to a form.
+type FormSubmitRequest struct {
+	SessionID string `json:"sessionId"`
+	// MessageID is the operator message carrying the form.
+	MessageID string `json:"messageId"`
+	// Answers are keyed by field name. Answers to unknown fields are
+	// dropped.
+	Answers map[string]string `json:"answers"`
+}
+
+// FormSubmitResponse is the response after submitting a form.
+type FormSubmitResponse struct {
+	OK bool `json:"ok"`
+}
+
+// FormValidationError is returned by HandleFormSubmit for answers that
+// don't fit the form. It wraps ErrInvalidForm.
+type FormValidationError struct {
+	// Fields maps each rejected field name to the reason.
+	Fields map[string]string
+}
+
+func (e *FormValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reasons := make([]string, len(names))
+	for i, name := range names {
+		reasons[i] = name + ": " + e.Fields[name]
+	}
+	return fmt.Sprintf("%s (%s)", ErrInvalidForm.Message, strings.Join(reasons, ", "))
+}
+
+func (e *FormValidationError) Unwrap() error {
+	return ErrInvalidForm
+}
+
+// FormCommandCallback is called when an operator runs /form <name> in a
+// session's topic or thread. Typically calls PocketPing.SendNamedForm.
+type FormCommandCallback func(ctx context.Context, sessionID, operatorName, sourceBridge, name string)
+
+// SendForm sends the visitor an operator message carrying form. The answers
+// come back through HandleFormSubmit.
+func (pp *PocketPing) SendForm(ctx context.Context, sessionID, operatorName string, form FormDefinition) (*Message, error) {
+	return pp.sendForm(ctx, sessionID, operatorName, "", form)
+}
+
+// SendNamedForm sends the form of Config.Forms called name, as SendForm.
+func (pp *PocketPing) SendNamedForm(ctx context.Context, sessionID, operatorName, name string) (*Message, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	form, ok := pp.config.Forms[name]
+	if !ok {
+		return nil, ErrFormNotFound
+	}
+	return pp.sendForm(ctx, sessionID, operatorName, name, form)
+}
+
+func (pp *PocketPing) sendForm(ctx context.Context, sessionID, operatorName, name string, definition FormDefinition) (*Message, error) {
+	if len(definition.Fields) == 0 {
+		return nil, ErrInvalidForm
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	form := Form{
+		ID:             pp.generateID(),
+		Name:           name,
+		FormDefinition: definition,
+		Status:         FormPending,
+		CreatedAt:      time.Now(),
+	}
+	form.Fields = append([]FormField(nil), definition.Fields...)
+	// HandleMessage doesn't forward operator messages to the bridges, so
+	// the notice below is the form's only post there
+	response, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: formTitle(&form), Sender: SenderOperator, Form: &form})
+	if err != nil {
+		return nil, err
+	}
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("📋 %s sent the form %q", takeoverName(operatorName), formTitle(&form)))
+
+	return pp.storage.GetMessage(ctx, response.MessageID)
+}
+
+// HandleFormSubmit handles the visitor's answers to a form. It validates
+// them against the form's fields, stores them on the message, marks the
+// form completed and notifies the widget. The answers are then triggered
+// as a "form_submitted" custom event (data: formId, name, messageId and
+// answers) and posted on the bridges as a summary.
+func (pp *PocketPing) HandleFormSubmit(ctx context.Context, request FormSubmitRequest) (*FormSubmitResponse, error) {
+	session, err := pp.storage.GetSession(ctx, request.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	message, err := pp.storage.GetMessage(ctx, request.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.SessionID != session.ID || message.Form == nil {
+		return nil, ErrFormNotFound
+	}
+	if message.Form.Status == FormCompleted {
+		return nil, ErrFormAlreadySubmitted
+	}
+
+	answers, err := validateFormAnswers(message.Form.Fields, request.Answers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Storage may hand out its own message, so the form is only changed
+	// on a copy
+	form := *message.Form
+	now := time.Now()
+	form.Status = FormCompleted
+	form.RespondedAt = &now
+	form.Answers = answers
+	updated := *message
+	updated.Form = &form
+	if err := pp.updateStoredMessage(ctx, &updated); err != nil {
+		return nil, err
+	}
+
+	pp.BroadcastToSession(session.ID, WebSocketEvent{
+		Type: "form_updated",
+		Data: map[string]interface{}{
+			"messageId": message.ID,
+			"form":      &form,
+		},
+	})
+
+	data := map[string]interface{}{
+		"formId":    form.ID,
+		"messageId": message.ID,
+		"answers":   answers,
+	}
+	if form.Name != "" {
+		data["name"] = form.Name
+	}
+	pp.TriggerEvent(ctx, session.ID, "form_submitted", data)
+	pp.notifyBridgesNotice(ctx, session, formSummary(&form))
+
+	return &FormSubmitResponse{OK: true}, nil
+}
+
+// validateFormAnswers checks answers against fields and returns them
+// trimmed, without unknown or empty ones.
+func validateFormAnswers(fields []FormField, answers map[string]string) (map[string]string, error) {
+	valid := make(map[string]string)
+	invalid := make(map[string]string)
+	for _, field := range fields {
+		answer := strings.TrimSpace(answers[field.Name])
+		if answer == "" {
+			if field.Required {
+				invalid[field.Name] = "required"
+			}
+			continue
+		}
+		if reason := checkFormAnswer(&field, answer); reason != "" {
+			invalid[field.Name] = reason
+			continue
+		}
+		valid[field.Name] = answer
+	}
+	if len(invalid) > 0 {
+		return nil, &FormValidationError{Fields: invalid}
+	}
+	return valid, nil
+}
+
+// checkFormAnswer returns why answer doesn't fit field, or "".
+func checkFormAnswer(field *FormField, answer string) string {
+	switch field.Type {
+	case FormFieldText, FormFieldTextarea, "":
+		if field.Type != FormFieldTextarea && strings.ContainsAny(answer, "\r\n") {
+			return "must be a single line"
+		}
+		if ValidateContent(answer) != nil {
+			return "too long"
+		}
+	case FormFieldEmail:
+		if ValidateEmail(answer) != nil {
+			return "invalid email"
+		}
+	case FormFieldNumber:
+		n, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return "not a number"
+		}
+		if field.Min != nil && n < *field.Min {
+			return fmt.Sprintf("must be at least %g", *field.Min)
+		}
+		if field.Max != nil && n > *field.Max {
+			return fmt.Sprintf("must be at most %g", *field.Max)
+		}
+	case FormFieldSelect:
+		for _, option := range field.Options {
+			if answer == option {
+				return ""
+			}
+		}
+		return "not one of the options"
+	case FormFieldRating:
+		top := DefaultFormRatingMax
+		if field.Max != nil {
+			top = int(*field.Max)
+		}
+		n, err := strconv.Atoi(answer)
+		if err != nil || n < 1 || n > top {
+			return fmt.Sprintf("must be from 1 to %d", top)
+		}
+	case FormFieldCheckbox:
+		if answer != "true" && answer != "false" {
+			return "must be true or false"
+		}
+	default:
+		return "unknown field type"
+	}
+	if field.MaxLength > 0 && utf8.RuneCountInString(answer) > field.MaxLength {
+		return fmt.Sprintf("longer than %d characters", field.MaxLength)
+	}
+	return ""
+}
+
+// formTitle returns the form's title, or its name.
+func formTitle(form *Form) string {
+	if form.Title != "" {
+		return form.Title
+	}
+	if form.Name != "" {
+		return form.Name
+	}
+	return "form"
+}
+
+// formSummary formats a completed form for the bridges, one answer per
+// line in field order.
+func formSummary(form *Form) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📋 Visitor answered %q", formTitle(form))
+	for _, field := range form.Fields {
+		answer, ok := form.Answers[field.Name]
+		if !ok {
+			continue
+		}
+		label := field.Label
+		if label == "" {
+			label = field.Name
+		}
+		switch field.Type {
+		case FormFieldRating:
+			top := DefaultFormRatingMax
+			if field.Max != nil {
+				top = int(*field.Max)
+			}
+			answer += "/" + strconv.Itoa(top)
+		case FormFieldCheckbox:
+			if answer == "true" {
+				answer = "yes"
+			} else {
+				answer = "no"
+			}
+		}
+		fmt.Fprintf(&b, "\n• %s: %s", label, answer)
+	}
+	return b.String()
+}
+
+// parseFormCommand recognises /form <name> (also as /form@bot) and returns
+// the name, empty when missing.
+func parseFormCommand(text string) (name string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	if command != "/form" {
+		return "", false
+	}
+	return strings.Join(fields[1:], " "), true
+}
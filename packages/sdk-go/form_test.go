@@ -0,0 +1,191 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// formBridge records notices and operator messages.
+type formBridge struct {
+	notifyRecordingBridge
+	operator int
+}
+
+func (b *formBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge, operatorName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.operator++
+	return nil
+}
+
+func feedbackForm() FormDefinition {
+	return FormDefinition{
+		Title: "Feedback",
+		Fields: []FormField{
+			{Name: "rating", Label: "How did we do?", Type: FormFieldRating, Required: true},
+			{Name: "topic", Type: FormFieldSelect, Options: []string{"billing", "shipping"}},
+			{Name: "email", Type: FormFieldEmail},
+			{Name: "subscribe", Label: "Keep me posted", Type: FormFieldCheckbox},
+			{Name: "comment", Type: FormFieldTextarea, MaxLength: 20},
+		},
+	}
+}
+
+func TestSendFormAndSubmit(t *testing.T) {
+	ctx := context.Background()
+	bridge := &formBridge{notifyRecordingBridge: notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}}
+	pp := New(Config{Bridges: []Bridge{bridge}, Forms: map[string]FormDefinition{"feedback": feedbackForm()}})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+	var submitted []CustomEvent
+	pp.OnEvent("form_submitted", func(event CustomEvent, session *Session) {
+		submitted = append(submitted, event)
+	})
+
+	if _, err := pp.SendNamedForm(ctx, session.ID, "Bob", "survey"); !errors.Is(err, ErrFormNotFound) {
+		t.Errorf("unknown form = %v, want ErrFormNotFound", err)
+	}
+	message, err := pp.SendNamedForm(ctx, session.ID, "Bob", " Feedback ")
+	if err != nil {
+		t.Fatalf("SendNamedForm: %v", err)
+	}
+	if message.Content != "Feedback" || message.Form == nil || message.Form.Name != "feedback" || message.Form.Status != FormPending || len(message.Form.Fields) != 5 {
+		t.Fatalf("message = %+v", message)
+	}
+
+	_, err = pp.HandleFormSubmit(ctx, FormSubmitRequest{SessionID: session.ID, MessageID: message.ID, Answers: map[string]string{
+		"topic":     "returns",
+		"email":     "not an email",
+		"subscribe": "maybe",
+		"comment":   "far too long for this field",
+	}})
+	var invalid *FormValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidForm) {
+		t.Fatalf("invalid answers = %v", err)
+	}
+	for _, name := range []string{"rating", "topic", "email", "subscribe", "comment"} {
+		if invalid.Fields[name] == "" {
+			t.Errorf("%s not rejected: %v", name, invalid.Fields)
+		}
+	}
+
+	other := sendVisitorMessage(t, pp, session.ID, "hi")
+	if _, err := pp.HandleFormSubmit(ctx, FormSubmitRequest{SessionID: session.ID, MessageID: other}); !errors.Is(err, ErrFormNotFound) {
+		t.Errorf("answering a plain message = %v, want ErrFormNotFound", err)
+	}
+
+	answers := map[string]string{"rating": "4", "topic": "billing", "subscribe": "true", "comment": " Quick! ", "extra": "x"}
+	if _, err := pp.HandleFormSubmit(ctx, FormSubmitRequest{SessionID: session.ID, MessageID: message.ID, Answers: answers}); err != nil {
+		t.Fatalf("HandleFormSubmit: %v", err)
+	}
+	if _, err := pp.HandleFormSubmit(ctx, FormSubmitRequest{SessionID: session.ID, MessageID: message.ID, Answers: answers}); !errors.Is(err, ErrFormAlreadySubmitted) {
+		t.Errorf("second submit = %v, want ErrFormAlreadySubmitted", err)
+	}
+
+	stored, _ := pp.storage.GetMessage(ctx, message.ID)
+	want := map[string]string{"rating": "4", "topic": "billing", "subscribe": "true", "comment": "Quick!"}
+	if stored.Form.Status != FormCompleted || stored.Form.RespondedAt == nil || len(stored.Form.Answers) != len(want) {
+		t.Fatalf("form = %+v", stored.Form)
+	}
+	for name, answer := range want {
+		if stored.Form.Answers[name] != answer {
+			t.Errorf("answer %s = %q, want %q", name, stored.Form.Answers[name], answer)
+		}
+	}
+	if len(submitted) != 1 || submitted[0].Data["name"] != "feedback" || submitted[0].Data["messageId"] != message.ID {
+		t.Errorf("form_submitted events = %+v", submitted)
+	}
+	found := false
+	for _, eventType := range conn.types() {
+		found = found || eventType == "form_updated"
+	}
+	if !found {
+		t.Errorf("widget events = %v", conn.types())
+	}
+
+	waitFor(t, "bridge notices", func() bool {
+		bridge.mu.Lock()
+		defer bridge.mu.Unlock()
+		return len(bridge.notices) == 2
+	})
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	summary := "📋 Visitor answered \"Feedback\"\n• How did we do?: 4/5\n• topic: billing\n• Keep me posted: yes\n• comment: Quick!"
+	if bridge.notices[0] != "📋 Bob sent the form \"Feedback\"" || bridge.notices[1] != summary {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+	if bridge.operator != 0 {
+		t.Errorf("form posted %d more times as an operator message", bridge.operator)
+	}
+}
+
+func TestSendFormWithoutFields(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	session := newSession(ctx, t, pp)
+	if _, err := pp.SendForm(ctx, session.ID, "Bob", FormDefinition{Title: "Empty"}); !errors.Is(err, ErrInvalidForm) {
+		t.Errorf("SendForm = %v, want ErrInvalidForm", err)
+	}
+}
+
+func TestCheckFormAnswer(t *testing.T) {
+	min, max := 1.0, 10.0
+	tests := []struct {
+		field  FormField
+		answer string
+		ok     bool
+	}{
+		{FormField{Type: FormFieldNumber, Min: &min, Max: &max}, "2.5", true},
+		{FormField{Type: FormFieldNumber, Min: &min, Max: &max}, "11", false},
+		{FormField{Type: FormFieldNumber}, "ten", false},
+		{FormField{Type: FormFieldRating, Max: &max}, "10", true},
+		{FormField{Type: FormFieldRating}, "6", false},
+		{FormField{Type: FormFieldRating}, "0", false},
+		{FormField{}, "one line", true},
+		{FormField{}, "two\nlines", false},
+		{FormField{Type: FormFieldTextarea}, "two\nlines", true},
+		{FormField{Type: FormFieldTextarea}, strings.Repeat("a", MaxMessageContentLength+1), false},
+		{FormField{Type: FormFieldEmail}, "jane@example.com", true},
+		{FormField{Type: "date"}, "2026-01-01", false},
+	}
+	for _, tt := range tests {
+		if reason := checkFormAnswer(&tt.field, tt.answer); (reason == "") != tt.ok {
+			t.Errorf("checkFormAnswer(%+v, %q) = %q", tt.field, tt.answer, reason)
+		}
+	}
+}
+
+func TestWebhookHandler_FormCommand(t *testing.T) {
+	type call struct{ sessionID, operatorName, source, name string }
+	var calls []call
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		OnFormCommand: func(ctx context.Context, sessionID, operatorName, sourceBridge, name string) {
+			calls = append(calls, call{sessionID, operatorName, sourceBridge, name})
+		},
+	})
+
+	payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"/form@pocketping_bot feedback"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	payload = []byte(`{"type":2,"channel_id":"T9","member":{"user":{"username":"bob"}},"data":{"name":"form","options":[{"name":"name","value":"nps"}]}}`)
+	handler.HandleDiscordWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/discord", bytes.NewReader(payload)))
+
+	want := []call{
+		{"456", "Bob", "telegram", "feedback"},
+		{"T9", "bob", "discord", "nps"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package pocketping
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ErrSessionEnded is returned by HandleMessage for visitor messages to a
+// session that was ended for a fresh start (see StartFreshSession).
+var ErrSessionEnded = newError("session_ended", http.StatusGone, "session has ended")
+
+// StartFreshSession hands a shared device (a kiosk, a family computer) over
+// to a new visitor: visitorID's current session is ended and a new session
+// is started under a new visitor ID, returned in ConnectResponse.VisitorID.
+// The widget does the same with ConnectRequest.FreshSession.
+//
+// Ended sessions aren't resumed and refuse visitor messages with
+// ErrSessionEnded. Their sockets get a session_ended event.
+func (pp *PocketPing) StartFreshSession(ctx context.Context, visitorID string) (*ConnectResponse, error) {
+	return pp.HandleConnect(ctx, ConnectRequest{VisitorID: visitorID, FreshSession: true})
+}
+
+// endVisitorSessions ends the sessions a fresh-start connect would have
+// resumed: the requested one, if it's the visitor's, and the visitor's
+// latest.
+func (pp *PocketPing) endVisitorSessions(ctx context.Context, request ConnectRequest) error {
+	ended := make(map[string]bool)
+	end := func(session *Session) error {
+		if session == nil || session.VisitorID != request.VisitorID || session.EndedAt != nil || ended[session.ID] {
+			return nil
+		}
+		ended[session.ID] = true
+		return pp.endSession(ctx, session, "🔚 Session ended: the device was handed over to a new visitor")
+	}
+
+	if sessionID := request.SessionID; sessionID != "" || request.AffinityToken != "" {
+		if sessionID == "" {
+			sessionID = pp.resumeSessionID(request)
+		}
+		if sessionID != "" {
+			session, err := pp.storage.GetSession(ctx, sessionID)
+			if err != nil {
+				return err
+			}
+			if err := end(session); err != nil {
+				return err
+			}
+		}
+	}
+	if request.VisitorID == "" {
+		return nil
+	}
+	session, err := pp.storage.GetSessionByVisitorID(ctx, request.VisitorID)
+	if err != nil {
+		return err
+	}
+	return end(session)
+}
+
+// endSession marks session ended and tells its widgets, and the bridges
+// with notice unless it is empty.
+func (pp *PocketPing) endSession(ctx context.Context, session *Session, notice string) error {
+	now := time.Now()
+	session.EndedAt = &now
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return err
+	}
+
+	pp.BroadcastToSession(session.ID, WebSocketEvent{
+		Type: "session_ended",
+		Data: map[string]interface{}{
+			"endedAt": now.Format(time.RFC3339),
+		},
+	})
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "session_update", Data: session})
+	pp.leaveWaitQueue(session.ID, nil)
+	if notice != "" {
+		pp.notifyBridgesNotice(ctx, session, notice)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStartFreshSession(t *testing.T) {
+	ctx := context.Background()
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{Bridges: []Bridge{bridge}})
+	previous := connectVisitor(ctx, t, pp, "kiosk-1")
+	sendVisitorMessage(t, pp, previous, "my account number is 1234")
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(previous, ws)
+
+	resp, err := pp.StartFreshSession(ctx, "kiosk-1")
+	if err != nil {
+		t.Fatalf("StartFreshSession: %v", err)
+	}
+	if resp.SessionID == previous || resp.VisitorID == "kiosk-1" || resp.VisitorID == "" {
+		t.Errorf("resp = %+v, want a new session and visitor", resp)
+	}
+	if len(resp.Messages) != 0 {
+		t.Errorf("fresh session has %d messages", len(resp.Messages))
+	}
+
+	ended, _ := pp.GetSession(ctx, previous)
+	if ended.EndedAt == nil {
+		t.Error("previous session not ended")
+	}
+	if types := ws.types(); len(types) == 0 || types[len(types)-1] != "session_ended" {
+		t.Errorf("events = %v", types)
+	}
+	pp.dispatcher.wait()
+	bridge.mu.Lock()
+	if len(bridge.notices) != 1 {
+		t.Errorf("notices = %v", bridge.notices)
+	}
+	bridge.mu.Unlock()
+
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: previous, Content: "still there?", Sender: SenderVisitor}); !errors.Is(err, ErrSessionEnded) {
+		t.Errorf("message to ended session: err = %v, want ErrSessionEnded", err)
+	}
+
+	// The previous visitor ID gets a new session rather than the ended one.
+	if again := connectVisitor(ctx, t, pp, "kiosk-1"); again == previous {
+		t.Error("ended session resumed")
+	}
+}
+
+func TestHandleConnectFreshSession(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	previous := connectVisitor(ctx, t, pp, "kiosk-1")
+
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{
+		VisitorID:    "kiosk-1",
+		SessionID:    previous,
+		Identity:     &UserIdentity{ID: "previous-user"},
+		Metadata:     &SessionMetadata{URL: "https://example.com/lobby"},
+		FreshSession: true,
+	})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	session, _ := pp.GetSession(ctx, resp.SessionID)
+	if session.Identity != nil {
+		t.Error("identity carried over to the fresh session")
+	}
+	if session.Metadata == nil || session.Metadata.URL != "https://example.com/lobby" {
+		t.Errorf("metadata = %+v", session.Metadata)
+	}
+	if ended, _ := pp.GetSession(ctx, previous); ended.EndedAt == nil {
+		t.Error("previous session not ended")
+	}
+
+	// Another visitor's session isn't ended by naming it.
+	other := connectVisitor(ctx, t, pp, "v2")
+	if _, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "kiosk-2", SessionID: other, FreshSession: true}); err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if s, _ := pp.GetSession(ctx, other); s.EndedAt != nil {
+		t.Error("another visitor's session was ended")
+	}
+}
@@ -0,0 +1,235 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+)
+
+// StorageGauges are the sizes of a storage's in-memory maps.
+type StorageGauges struct {
+	Sessions         int `json:"sessions"`
+	Messages         int `json:"messages"`
+	BridgeMessageIDs int `json:"bridgeMessageIds"`
+	Attachments      int `json:"attachments"`
+	NotifyCursors    int `json:"notifyCursors"`
+	ReplayEvents     int `json:"replayEvents"`
+	OperatorTokens   int `json:"operatorTokens"`
+	// RoutedSessions is the number of sessions a RoutingStorage remembers
+	// the backend of.
+	RoutedSessions int `json:"routedSessions,omitempty"`
+}
+
+// StorageWithGauges extends Storage with the sizes of what it holds in
+// memory, reported by PocketPing.Gauges.
+type StorageWithGauges interface {
+	Storage
+
+	// StorageGauges returns the current sizes.
+	StorageGauges() StorageGauges
+}
+
+// Gauges are the sizes of PocketPing's internal state. Sampled over a soak
+// test, a gauge that only ever grows (e.g. EventHandlers after visitors
+// leave) points at a leak.
+type Gauges struct {
+	// Storage is nil when the storage doesn't implement StorageWithGauges.
+	Storage *StorageGauges `json:"storage,omitempty"`
+
+	// Sockets are the visitor sockets on this node, across SocketSessions
+	// sessions.
+	Sockets         int `json:"sockets"`
+	SocketSessions  int `json:"socketSessions"`
+	OperatorSockets int `json:"operatorSockets"`
+
+	// EventHandlers counts OnEvent handlers; EventHandlersByName splits
+	// them by event name.
+	EventHandlers       int            `json:"eventHandlers"`
+	EventHandlersByName map[string]int `json:"eventHandlersByName"`
+
+	// QueuedNotifications are bridge notifications waiting or running, in
+	// NotificationQueues per-session queues.
+	QueuedNotifications int `json:"queuedNotifications"`
+	NotificationQueues  int `json:"notificationQueues"`
+	// RetryingNotifications are waiting to be retried (Config.BridgeRetry).
+	RetryingNotifications int `json:"retryingNotifications"`
+
+	OfflineEvents    int `json:"offlineEvents"`
+	OperatorActivity int `json:"operatorActivity"`
+	ContextCache     int `json:"contextCache"`
+	PendingDigests   int `json:"pendingDigests"`
+	PendingBatches   int `json:"pendingBatches"`
+	InboxEntries     int `json:"inboxEntries"`
+	RateLimitBuckets int `json:"rateLimitBuckets"`
+	QueuedVisitors   int `json:"queuedVisitors"`
+
+	Goroutines int    `json:"goroutines"`
+	HeapAlloc  uint64 `json:"heapAlloc"`
+}
+
+// Gauges samples the sizes of the internal state.
+func (pp *PocketPing) Gauges() Gauges {
+	var g Gauges
+	if store, ok := pp.storage.(StorageWithGauges); ok {
+		sg := store.StorageGauges()
+		g.Storage = &sg
+	}
+
+	pp.socketsMu.RLock()
+	g.SocketSessions = len(pp.sessionSockets)
+	for _, conns := range pp.sessionSockets {
+		g.Sockets += len(conns)
+	}
+	g.OperatorSockets = len(pp.operatorSockets)
+	pp.socketsMu.RUnlock()
+
+	pp.handlersMu.RLock()
+	g.EventHandlersByName = make(map[string]int, len(pp.eventHandlers))
+	for name, handlers := range pp.eventHandlers {
+		g.EventHandlersByName[name] = len(handlers)
+		g.EventHandlers += len(handlers)
+	}
+	pp.handlersMu.RUnlock()
+
+	g.NotificationQueues, g.QueuedNotifications = pp.dispatcher.pending()
+	g.RetryingNotifications = int(pp.retrying.Load())
+
+	pp.offline.mu.Lock()
+	for _, events := range pp.offline.pending {
+		g.OfflineEvents += len(events)
+	}
+	pp.offline.mu.Unlock()
+
+	pp.operatorActivityMu.RLock()
+	g.OperatorActivity = len(pp.operatorActivity)
+	pp.operatorActivityMu.RUnlock()
+
+	pp.contextCache.mu.Lock()
+	g.ContextCache = len(pp.contextCache.entries)
+	pp.contextCache.mu.Unlock()
+
+	pp.digests.mu.Lock()
+	g.PendingDigests = len(pp.digests.pending)
+	pp.digests.mu.Unlock()
+
+	pp.batches.mu.Lock()
+	g.PendingBatches = len(pp.batches.pending)
+	pp.batches.mu.Unlock()
+
+	pp.rateLimits.mu.Lock()
+	g.RateLimitBuckets = len(pp.rateLimits.buckets)
+	pp.rateLimits.mu.Unlock()
+
+	pp.waitQueue.mu.Lock()
+	g.QueuedVisitors = len(pp.waitQueue.waiting)
+	pp.waitQueue.mu.Unlock()
+
+	if pp.inbox != nil {
+		pp.inbox.mu.RLock()
+		g.InboxEntries = len(pp.inbox.entries)
+		pp.inbox.mu.RUnlock()
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	g.Goroutines = runtime.NumGoroutine()
+	g.HeapAlloc = mem.HeapAlloc
+	return g
+}
+
+// HandleDebugGauges returns a debugging handler dumping Gauges as JSON, for
+// soak tests to poll. Event handler names are listed sorted by count, then
+// name, under "topEventHandlers". Mount it behind your admin
+// authentication.
+func (pp *PocketPing) HandleDebugGauges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g := pp.Gauges()
+		type handlerCount struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		}
+		top := make([]handlerCount, 0, len(g.EventHandlersByName))
+		for name, count := range g.EventHandlersByName {
+			top = append(top, handlerCount{Name: name, Count: count})
+		}
+		sort.Slice(top, func(i, j int) bool {
+			if top[i].Count != top[j].Count {
+				return top[i].Count > top[j].Count
+			}
+			return top[i].Name < top[j].Name
+		})
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			Gauges
+			TopEventHandlers []handlerCount `json:"topEventHandlers"`
+		}{g, top})
+	}
+}
+
+// pending returns the number of queues and of notifications in them. Each
+// queue has a notification running, taken off the queue while it runs.
+func (d *bridgeDispatcher) pending() (queues, notifications int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, queue := range d.queues {
+		notifications += len(queue) + 1
+	}
+	return len(d.queues), notifications
+}
+
+// StorageGauges implements StorageWithGauges.
+func (m *MemoryStorage) StorageGauges() StorageGauges {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	g := StorageGauges{
+		Sessions:         len(m.sessions),
+		Messages:         len(m.messageByID),
+		BridgeMessageIDs: len(m.bridgeMessageIDs),
+		Attachments:      len(m.attachments),
+		OperatorTokens:   len(m.operatorTokens),
+	}
+	for _, cursors := range m.lastNotified {
+		g.NotifyCursors += len(cursors)
+	}
+	for _, events := range m.replay {
+		g.ReplayEvents += len(events)
+	}
+	return g
+}
+
+// StorageGauges implements StorageWithGauges, with the projection's sizes.
+func (s *EventSourcedStorage) StorageGauges() StorageGauges {
+	return s.state.StorageGauges()
+}
+
+// StorageGauges implements StorageWithGauges, summing the backends that
+// implement it.
+func (r *RoutingStorage) StorageGauges() StorageGauges {
+	var g StorageGauges
+	for _, backend := range r.backends {
+		store, ok := backend.(StorageWithGauges)
+		if !ok {
+			continue
+		}
+		b := store.StorageGauges()
+		g.Sessions += b.Sessions
+		g.Messages += b.Messages
+		g.BridgeMessageIDs += b.BridgeMessageIDs
+		g.Attachments += b.Attachments
+		g.NotifyCursors += b.NotifyCursors
+		g.ReplayEvents += b.ReplayEvents
+		g.OperatorTokens += b.OperatorTokens
+	}
+	r.mu.RLock()
+	g.RoutedSessions = len(r.sessions)
+	r.mu.RUnlock()
+	return g
+}
+
+var (
+	_ StorageWithGauges = (*MemoryStorage)(nil)
+	_ StorageWithGauges = (*EventSourcedStorage)(nil)
+	_ StorageWithGauges = (*RoutingStorage)(nil)
+)
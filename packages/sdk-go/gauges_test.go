@@ -0,0 +1,79 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGauges(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{InboxReadModel: true})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	sendVisitorMessage(t, pp, sessionID, "Hi")
+	pp.RegisterWebSocket(sessionID, &mockWSConn{})
+	pp.RegisterWebSocket(sessionID, &mockWSConn{})
+
+	off := pp.OnEvent("clicked", func(event CustomEvent, session *Session) {})
+	pp.OnEvent("clicked", func(event CustomEvent, session *Session) {})
+
+	g := pp.Gauges()
+	if g.Storage == nil || g.Storage.Sessions != 1 || g.Storage.Messages != 1 {
+		t.Errorf("storage = %+v", g.Storage)
+	}
+	if g.Sockets != 2 || g.SocketSessions != 1 {
+		t.Errorf("sockets = %d in %d sessions", g.Sockets, g.SocketSessions)
+	}
+	if g.EventHandlers != 2 || g.EventHandlersByName["clicked"] != 2 {
+		t.Errorf("handlers = %d, %v", g.EventHandlers, g.EventHandlersByName)
+	}
+	if g.InboxEntries != 1 || g.Goroutines == 0 {
+		t.Errorf("gauges = %+v", g)
+	}
+
+	off()
+	if g := pp.Gauges(); g.EventHandlers != 1 {
+		t.Errorf("handlers after unsubscribing = %d", g.EventHandlers)
+	}
+}
+
+func TestGaugesStorageWithoutGauges(t *testing.T) {
+	// Embedding only Storage hides MemoryStorage's gauges
+	pp := New(Config{Storage: struct{ Storage }{NewMemoryStorage()}})
+	if g := pp.Gauges(); g.Storage != nil {
+		t.Errorf("storage = %+v", g.Storage)
+	}
+
+	routing := NewRoutingStorage(NewMemoryStorage(), map[string]Storage{"eu": NewMemoryStorage()})
+	if err := routing.CreateSession(context.Background(), &Session{ID: "s1", Region: "eu"}); err != nil {
+		t.Fatal(err)
+	}
+	if g := routing.StorageGauges(); g.Sessions != 1 || g.RoutedSessions != 1 {
+		t.Errorf("routing = %+v", g)
+	}
+}
+
+func TestHandleDebugGauges(t *testing.T) {
+	pp := New(Config{})
+	pp.OnEvent("a", func(event CustomEvent, session *Session) {})
+	pp.OnEvent("b", func(event CustomEvent, session *Session) {})
+	pp.OnEvent("b", func(event CustomEvent, session *Session) {})
+
+	rec := httptest.NewRecorder()
+	pp.HandleDebugGauges()(rec, httptest.NewRequest("GET", "/debug/gauges", nil))
+	var body struct {
+		EventHandlers    int `json:"eventHandlers"`
+		TopEventHandlers []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"topEventHandlers"`
+		Storage *StorageGauges `json:"storage"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body = %s (%v)", rec.Body, err)
+	}
+	if body.EventHandlers != 3 || len(body.TopEventHandlers) != 2 || body.TopEventHandlers[0].Name != "b" || body.Storage == nil {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
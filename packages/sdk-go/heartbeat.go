@@ -0,0 +1,178 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// heartbeatMissedPings is how many pings in a row a socket may leave
+// unanswered before it is reaped.
+const heartbeatMissedPings = 2
+
+// PingableConn is a WebSocketConn the heartbeat can ping (see
+// Config.HeartbeatInterval). EncodedConn implements it with WebSocket ping
+// frames; browsers answer those without any widget code.
+type PingableConn interface {
+	WebSocketConn
+	// Ping sends a ping that must be written before deadline.
+	Ping(deadline time.Time) error
+	// LastPong returns when the last pong (or the connection) was seen.
+	LastPong() time.Time
+}
+
+// ConnectionHealth describes one socket of a session.
+type ConnectionHealth struct {
+	// Pingable is false for sockets the heartbeat can't check.
+	Pingable bool `json:"pingable"`
+	// LastPong is when the socket last answered a ping.
+	LastPong *time.Time `json:"lastPong,omitempty"`
+}
+
+// pingable returns the PingableConn behind conn, looking through send
+// queues.
+func pingable(conn WebSocketConn) (PingableConn, bool) {
+	if q, ok := conn.(*sendQueue); ok {
+		conn = q.conn
+	}
+	p, ok := conn.(PingableConn)
+	return p, ok
+}
+
+// startHeartbeat pings every socket each Config.HeartbeatInterval until Stop.
+func (pp *PocketPing) startHeartbeat() {
+	interval := pp.config.HeartbeatInterval
+	if interval <= 0 || pp.heartbeatStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	pp.heartbeatStop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				pp.heartbeat()
+			}
+		}
+	}()
+}
+
+// stopHeartbeat ends the heartbeat started by Start.
+func (pp *PocketPing) stopHeartbeat() {
+	if pp.heartbeatStop != nil {
+		close(pp.heartbeatStop)
+		pp.heartbeatStop = nil
+	}
+}
+
+// heartbeat reaps the sockets that missed too many pings and pings the rest.
+func (pp *PocketPing) heartbeat() {
+	interval := pp.config.HeartbeatInterval
+	now := time.Now()
+
+	type socket struct {
+		sessionID string
+		conn      WebSocketConn
+	}
+	var sockets []socket
+	pp.socketsMu.RLock()
+	for sessionID, conns := range pp.sessionSockets {
+		for conn := range conns {
+			sockets = append(sockets, socket{sessionID, conn})
+		}
+	}
+	ops := make([]*OperatorConn, 0, len(pp.operatorSockets))
+	for op := range pp.operatorSockets {
+		ops = append(ops, op)
+	}
+	pp.socketsMu.RUnlock()
+
+	alive := func(conn WebSocketConn) bool {
+		p, ok := pingable(conn)
+		if !ok {
+			return true
+		}
+		if now.Sub(p.LastPong()) > heartbeatMissedPings*interval {
+			return false
+		}
+		return p.Ping(now.Add(interval)) == nil
+	}
+	for _, s := range sockets {
+		if !alive(s.conn) {
+			log.Printf("[PocketPing] Reaping stale WebSocket of session %s", s.sessionID)
+			pp.UnregisterWebSocket(s.sessionID, s.conn)
+			_ = s.conn.Close()
+		}
+	}
+	for _, op := range ops {
+		if !alive(op.conn) {
+			log.Printf("[PocketPing] Reaping stale operator console of %s", op.Name)
+			pp.DisconnectOperator(op)
+			_ = op.conn.Close()
+		}
+	}
+}
+
+// ConnectionCounts returns the number of sockets of each session with at
+// least one, on this node.
+func (pp *PocketPing) ConnectionCounts() map[string]int {
+	pp.socketsMu.RLock()
+	defer pp.socketsMu.RUnlock()
+
+	counts := make(map[string]int, len(pp.sessionSockets))
+	for sessionID, conns := range pp.sessionSockets {
+		counts[sessionID] = len(conns)
+	}
+	return counts
+}
+
+// SessionConnections returns the health of a session's sockets on this
+// node, most recently answering first.
+func (pp *PocketPing) SessionConnections(sessionID string) []ConnectionHealth {
+	pp.socketsMu.RLock()
+	conns := make([]WebSocketConn, 0, len(pp.sessionSockets[sessionID]))
+	for conn := range pp.sessionSockets[sessionID] {
+		conns = append(conns, conn)
+	}
+	pp.socketsMu.RUnlock()
+
+	health := make([]ConnectionHealth, 0, len(conns))
+	for _, conn := range conns {
+		h := ConnectionHealth{}
+		if p, ok := pingable(conn); ok {
+			lastPong := p.LastPong()
+			h.Pingable, h.LastPong = true, &lastPong
+		}
+		health = append(health, h)
+	}
+	sort.SliceStable(health, func(i, j int) bool {
+		return health[j].LastPong == nil || (health[i].LastPong != nil && health[i].LastPong.After(*health[j].LastPong))
+	})
+	return health
+}
+
+// HandleConnections returns a debugging handler listing this node's socket
+// counts per session, or a session's sockets with ?sessionId=, to check
+// "visitor not receiving messages" reports. Mount it behind your admin
+// authentication.
+func (pp *PocketPing) HandleConnections() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		if sessionID := r.URL.Query().Get("sessionId"); sessionID != "" {
+			body = map[string]interface{}{
+				"sessionId":   sessionID,
+				"connections": pp.SessionConnections(sessionID),
+			}
+		} else {
+			body = map[string]interface{}{"sessions": pp.ConnectionCounts()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
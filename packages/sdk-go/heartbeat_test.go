@@ -0,0 +1,112 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pingConn is a PingableConn that answers pings unless it is dead.
+type pingConn struct {
+	mockWSConn
+	dead     bool
+	lastPong time.Time
+	pings    int
+	closed   bool
+	pingMu   sync.Mutex
+}
+
+func (c *pingConn) Ping(deadline time.Time) error {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	c.pings++
+	if !c.dead {
+		c.lastPong = time.Now()
+	}
+	return nil
+}
+
+func (c *pingConn) LastPong() time.Time {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	return c.lastPong
+}
+
+func (c *pingConn) Close() error {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func TestHeartbeatReapsStaleSockets(t *testing.T) {
+	pp := New(Config{HeartbeatInterval: time.Second})
+	healthy := &pingConn{lastPong: time.Now()}
+	stale := &pingConn{dead: true, lastPong: time.Now().Add(-time.Minute)}
+	plain := &mockWSConn{}
+	pp.RegisterWebSocket("s1", healthy)
+	pp.RegisterWebSocket("s1", stale)
+	pp.RegisterWebSocket("s2", plain)
+
+	pp.heartbeat()
+
+	if !stale.closed || stale.pings != 0 {
+		t.Errorf("stale socket closed = %v, pinged %d times", stale.closed, stale.pings)
+	}
+	if healthy.closed || healthy.pings != 1 {
+		t.Errorf("healthy socket closed = %v, pinged %d times", healthy.closed, healthy.pings)
+	}
+	counts := pp.ConnectionCounts()
+	if counts["s1"] != 1 || counts["s2"] != 1 {
+		t.Errorf("ConnectionCounts = %v", counts)
+	}
+	health := pp.SessionConnections("s1")
+	if len(health) != 1 || !health[0].Pingable || health[0].LastPong == nil {
+		t.Errorf("SessionConnections = %+v", health)
+	}
+	if health := pp.SessionConnections("s2"); len(health) != 1 || health[0].Pingable {
+		t.Errorf("plain socket health = %+v", health)
+	}
+}
+
+func TestHeartbeatRunsFromStart(t *testing.T) {
+	pp := New(Config{HeartbeatInterval: 10 * time.Millisecond})
+	conn := &pingConn{lastPong: time.Now()}
+	pp.RegisterWebSocket("s1", conn)
+	if err := pp.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, "pings", func() bool {
+		conn.pingMu.Lock()
+		defer conn.pingMu.Unlock()
+		return conn.pings >= 2
+	})
+	_ = pp.Stop(context.Background())
+}
+
+func TestHandleConnections(t *testing.T) {
+	pp := New(Config{})
+	pp.RegisterWebSocket("s1", &mockWSConn{})
+	pp.RegisterWebSocket("s1", &mockWSConn{})
+
+	rec := httptest.NewRecorder()
+	pp.HandleConnections()(rec, httptest.NewRequest("GET", "/debug/connections", nil))
+	var all struct {
+		Sessions map[string]int `json:"sessions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil || all.Sessions["s1"] != 2 {
+		t.Errorf("body = %s (%v)", rec.Body, err)
+	}
+
+	rec = httptest.NewRecorder()
+	pp.HandleConnections()(rec, httptest.NewRequest("GET", "/debug/connections?sessionId=s1", nil))
+	var one struct {
+		Connections []ConnectionHealth `json:"connections"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &one); err != nil || len(one.Connections) != 2 {
+		t.Errorf("body = %s (%v)", rec.Body, err)
+	}
+}
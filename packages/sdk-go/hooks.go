@@ -0,0 +1,31 @@
+package pocketping
+
+import (
+	"context"
+	"net/http"
+)
+
+// BeforeSessionCreateHook runs before a new session is stored (see
+// Config.BeforeSessionCreate). It may change the session, e.g. to enrich its
+// metadata or identity, or return an error to refuse the connection.
+type BeforeSessionCreateHook func(ctx context.Context, session *Session, request ConnectRequest) error
+
+// BeforeMessageSaveHook runs before a message is stored (see
+// Config.BeforeMessageSave). It may change the message, e.g. its Content or
+// Metadata, or return an error to refuse it. Edits have EditedAt set.
+type BeforeMessageSaveHook func(ctx context.Context, message *Message, session *Session) error
+
+// Reject returns an error for a Before hook to refuse the operation with.
+// Unlike other errors, which WriteError reports as ErrInternal, its message
+// is shown to the visitor, with a 403 status and the "rejected" code.
+func Reject(message string) *Error {
+	return newError("rejected", http.StatusForbidden, message)
+}
+
+// beforeMessageSave runs Config.BeforeMessageSave on message.
+func (pp *PocketPing) beforeMessageSave(ctx context.Context, message *Message, session *Session) error {
+	if pp.config.BeforeMessageSave == nil {
+		return nil
+	}
+	return pp.config.BeforeMessageSave(ctx, message, session)
+}
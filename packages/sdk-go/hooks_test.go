@@ -0,0 +1,122 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBeforeSessionCreate(t *testing.T) {
+	ctx := context.Background()
+	created := 0
+	pp := New(Config{
+		BeforeSessionCreate: func(ctx context.Context, session *Session, request ConnectRequest) error {
+			if request.ProjectID == "banned" {
+				return Reject("This chat is unavailable")
+			}
+			session.Metadata = &SessionMetadata{Language: "fr"}
+			return nil
+		},
+		OnNewSession: func(session *Session) { created++ },
+	})
+
+	_, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", ProjectID: "banned"})
+	if e := AsError(err); e.Code != "rejected" || e.HTTPStatus != 403 || e.Message != "This chat is unavailable" {
+		t.Fatalf("err = %v", err)
+	}
+	if session, _ := pp.storage.GetSessionByVisitorID(ctx, "v1"); session != nil || created != 0 {
+		t.Errorf("rejected session stored: %+v, created = %d", session, created)
+	}
+
+	sessionID := connectVisitor(ctx, t, pp, "v2")
+	session, _ := pp.storage.GetSession(ctx, sessionID)
+	if session.Metadata == nil || session.Metadata.Language != "fr" || created != 1 {
+		t.Errorf("session = %+v, created = %d", session, created)
+	}
+}
+
+func TestBeforeMessageSave(t *testing.T) {
+	ctx := context.Background()
+	bridge := newRecordingBridge("slack")
+	errSpam := errors.New("spam")
+	pp := New(Config{
+		Bridges: []Bridge{bridge},
+		BeforeMessageSave: func(ctx context.Context, message *Message, session *Session) error {
+			if strings.Contains(message.Content, "casino") {
+				return errSpam
+			}
+			message.Content = strings.ReplaceAll(message.Content, "darn", "d***")
+			if message.EditedAt == nil {
+				message.Metadata = map[string]interface{}{"checked": true}
+			}
+			return nil
+		},
+	})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "cheap casino", Sender: SenderVisitor}); !errors.Is(err, errSpam) {
+		t.Fatalf("err = %v", err)
+	}
+	messageID := sendVisitorMessage(t, pp, sessionID, "darn it")
+	pp.dispatcher.wait()
+	messages, _ := pp.storage.GetMessages(ctx, sessionID, "", 10)
+	if len(messages) != 1 || messages[0].Content != "d*** it" || messages[0].Metadata["checked"] != true {
+		t.Fatalf("messages = %+v", messages)
+	}
+	if len(bridge.messages) != 1 || bridge.messages[0].Content != "d*** it" {
+		t.Errorf("bridge got %+v", bridge.messages)
+	}
+
+	resp, err := pp.HandleEditMessage(ctx, EditMessageRequest{SessionID: sessionID, MessageID: messageID, Content: "darn again"})
+	if err != nil || resp.Message.Content != "d*** again" {
+		t.Fatalf("edit = %+v, %v", resp, err)
+	}
+	if _, err := pp.HandleEditMessage(ctx, EditMessageRequest{SessionID: sessionID, MessageID: messageID, Content: "casino"}); !errors.Is(err, errSpam) {
+		t.Errorf("edit err = %v", err)
+	}
+	if message, _ := pp.storage.GetMessage(ctx, messageID); message.Content != "d*** again" {
+		t.Errorf("stored content = %q", message.Content)
+	}
+}
+
+func TestBeforeMessageSaveAIAndOperatorEdits(t *testing.T) {
+	ctx := context.Background()
+	errSpam := errors.New("spam")
+	fake := &fakeAIProvider{reply: "casino bonus"}
+	pp := New(Config{
+		AIProvider:      fake,
+		AITakeoverDelay: -1,
+		BeforeMessageSave: func(ctx context.Context, message *Message, session *Session) error {
+			if strings.Contains(message.Content, "casino") {
+				return errSpam
+			}
+			message.Content = strings.ReplaceAll(message.Content, "darn", "d***")
+			return nil
+		},
+	})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	// The refused AI reply isn't stored
+	sendVisitorMessage(t, pp, sessionID, "anyone?")
+	if ai := aiMessages(ctx, t, pp, sessionID); fake.callCount() != 1 || len(ai) != 0 {
+		t.Fatalf("provider calls = %d, AI messages = %+v", fake.callCount(), ai)
+	}
+
+	sent, err := pp.SendOperatorMessage(ctx, sessionID, "Hi", "slack", "Ann", WithBridgeMessageID("1712.0001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := func(content string) (*EditMessageResponse, error) {
+		return pp.HandleOperatorEditMessage(ctx, OperatorEditMessageRequest{SessionID: sessionID, SourceBridge: "slack", BridgeMessageID: "1712.0001", Content: content})
+	}
+	if resp, err := edit("darn, hi"); err != nil || resp.Message.Content != "d***, hi" {
+		t.Fatalf("edit = %+v, %v", resp, err)
+	}
+	if _, err := edit("casino"); !errors.Is(err, errSpam) {
+		t.Errorf("edit err = %v", err)
+	}
+	if message, _ := pp.storage.GetMessage(ctx, sent.ID); message.Content != "d***, hi" {
+		t.Errorf("stored content = %q", message.Content)
+	}
+}
@@ -0,0 +1,187 @@
+package pocketping
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ErrIdentityConflict is returned by HandleIdentify under
+// IdentityConflictReject when another visitor already identified with the
+// same Identity.ID.
+var ErrIdentityConflict = newError("identity_conflict", http.StatusConflict, "identity is already used by another visitor")
+
+// IdentityConflictPolicy decides what HandleIdentify does when a visitor
+// identifies with an Identity.ID another visitor's session already has.
+// Conflicts are found with StorageWithIdentityIndex; storage without it
+// isn't checked.
+type IdentityConflictPolicy string
+
+const (
+	// IdentityConflictAllow doesn't check: both sessions keep the
+	// identity. The default.
+	IdentityConflictAllow IdentityConflictPolicy = ""
+	// IdentityConflictReject refuses the second visitor with
+	// ErrIdentityConflict.
+	IdentityConflictReject IdentityConflictPolicy = "reject"
+	// IdentityConflictMerge moves the second visitor's messages into the
+	// user's most recent session and deletes theirs. IdentifyResponse.SessionID
+	// tells the widget which session to continue in. Only identities
+	// verified with Config.IdentitySecret are merged: the others are
+	// handled as IdentityConflictFork, since anyone can claim an
+	// Identity.ID.
+	IdentityConflictMerge IdentityConflictPolicy = "merge"
+	// IdentityConflictFork keeps both sessions, recording the user's most
+	// recent session as the new one's Session.ForkedFrom.
+	IdentityConflictFork IdentityConflictPolicy = "fork"
+)
+
+// findIdentityConflict returns the most recently active session of another
+// visitor with the identity, or nil.
+func (pp *PocketPing) findIdentityConflict(ctx context.Context, session *Session, identityID string) (*Session, error) {
+	index, ok := pp.storage.(StorageWithIdentityIndex)
+	if !ok {
+		return nil, nil
+	}
+	sessions, err := index.FindSessionsByIdentity(ctx, identityID)
+	if err != nil {
+		return nil, fmt.Errorf("find sessions by identity: %w", err)
+	}
+
+	var conflict *Session
+	for _, other := range sessions {
+		if other.ID == session.ID || other.VisitorID == session.VisitorID || other.Identity == nil || other.Identity.ID != identityID {
+			continue
+		}
+		if conflict == nil || other.LastActivity.After(conflict.LastActivity) {
+			conflict = other
+		}
+	}
+	return conflict, nil
+}
+
+// resolveIdentityConflict applies Config.IdentityConflictPolicy to a visitor
+// identifying as identityID and returns the session to identify: session,
+// or the session it was merged into. verified says whether the identity's
+// user hash checked out.
+func (pp *PocketPing) resolveIdentityConflict(ctx context.Context, session *Session, identityID string, verified bool) (*Session, error) {
+	policy := pp.config.IdentityConflictPolicy
+	if policy == IdentityConflictAllow {
+		return session, nil
+	}
+	existing, err := pp.findIdentityConflict(ctx, session, identityID)
+	if err != nil || existing == nil {
+		return session, err
+	}
+	if policy == IdentityConflictMerge && !verified {
+		log.Printf("[PocketPing] Identity %s of session %s is unverified: forking instead of merging", identityID, session.ID)
+		policy = IdentityConflictFork
+	}
+
+	log.Printf("[PocketPing] Identity %s of session %s is already used by session %s (%s)", identityID, session.ID, existing.ID, policy)
+	if pp.config.WebhookURL != "" {
+		pp.goWebhook(func() { pp.forwardIdentityConflictToWebhook(ctx, identityID, session, existing, policy) })
+	}
+
+	switch policy {
+	case IdentityConflictReject:
+		return nil, ErrIdentityConflict
+	case IdentityConflictMerge:
+		if err := pp.mergeSession(ctx, session, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	case IdentityConflictFork:
+		session.ForkedFrom = existing.ID
+	}
+	return session, nil
+}
+
+// verifyIdentityHash reports whether userHash is the hex HMAC-SHA256 of
+// identityID under Config.IdentitySecret. Without a secret nothing verifies.
+func (pp *PocketPing) verifyIdentityHash(identityID, userHash string) bool {
+	if pp.config.IdentitySecret == "" || userHash == "" {
+		return false
+	}
+	got, err := hex.DecodeString(userHash)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, IdentityHash(pp.config.IdentitySecret, identityID))
+}
+
+// IdentityHash returns the HMAC-SHA256 of identityID under secret. Servers
+// sign their user IDs with it and hand the hex encoding to the widget,
+// which sends it along as IdentifyRequest.UserHash.
+func IdentityHash(secret, identityID string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(identityID))
+	return h.Sum(nil)
+}
+
+// mergeSession copies from's messages into into, under new IDs, and deletes
+// from. The widgets on from are told to switch with a session_merged event.
+func (pp *PocketPing) mergeSession(ctx context.Context, from, into *Session) error {
+	ids := make(map[string]string)
+	after := ""
+	for {
+		page, err := pp.storage.GetMessages(ctx, from.ID, after, snapshotPageSize)
+		if err != nil {
+			return fmt.Errorf("get messages for %s: %w", from.ID, err)
+		}
+		page = pp.hydrateAttachments(ctx, page)
+		for _, message := range page {
+			moved := message
+			moved.ID = pp.generateID()
+			moved.SessionID = into.ID
+			ids[message.ID] = moved.ID
+			if id, ok := ids[moved.ReplyTo]; ok {
+				moved.ReplyTo = id
+			}
+			if id, ok := ids[moved.ThreadID]; ok {
+				moved.ThreadID = id
+			}
+			if err := pp.storage.SaveMessage(ctx, &moved); err != nil {
+				return err
+			}
+			pp.inbox.recordMessage(&moved)
+		}
+		if len(page) < snapshotPageSize {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+
+	if from.LastActivity.After(into.LastActivity) {
+		into.LastActivity = from.LastActivity
+	}
+	pp.BroadcastToSession(from.ID, WebSocketEvent{
+		Type: "session_merged",
+		Data: map[string]interface{}{"sessionId": into.ID},
+	})
+	if err := pp.storage.DeleteSession(ctx, from.ID); err != nil {
+		return err
+	}
+	if pp.inbox != nil {
+		pp.inbox.Remove(from.ID)
+	}
+	return nil
+}
+
+// forwardIdentityConflictToWebhook fires an identity_conflict webhook.
+func (pp *PocketPing) forwardIdentityConflictToWebhook(ctx context.Context, identityID string, session, existing *Session, policy IdentityConflictPolicy) {
+	pp.postWebhookEvent(ctx, "identity_conflict", map[string]interface{}{
+		"identityId":        identityID,
+		"sessionId":         session.ID,
+		"visitorId":         session.VisitorID,
+		"existingSessionId": existing.ID,
+		"existingVisitorId": existing.VisitorID,
+		"policy":            string(policy),
+		"detectedAt":        time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,202 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// identifyAs identifies a new visitor's session as user u1.
+func identifyAs(ctx context.Context, t *testing.T, pp *PocketPing, visitorID string) (string, *IdentifyResponse, error) {
+	t.Helper()
+	sessionID := connectVisitor(ctx, t, pp, visitorID)
+	resp, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: sessionID, Identity: &UserIdentity{ID: "u1"}})
+	return sessionID, resp, err
+}
+
+func TestIdentityConflictAllow(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	identifyAs(ctx, t, pp, "v1")
+	if _, _, err := identifyAs(ctx, t, pp, "v2"); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+}
+
+func TestIdentityConflictReject(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{IdentityConflictPolicy: IdentityConflictReject})
+	if _, _, err := identifyAs(ctx, t, pp, "v1"); err != nil {
+		t.Fatalf("first visitor: %v", err)
+	}
+	sessionID, _, err := identifyAs(ctx, t, pp, "v2")
+	if !errors.Is(err, ErrIdentityConflict) {
+		t.Fatalf("second visitor: err = %v, want ErrIdentityConflict", err)
+	}
+	if got, _ := pp.GetSession(ctx, sessionID); got.Identity != nil {
+		t.Error("rejected session was identified")
+	}
+
+	// The same visitor identifying again isn't a conflict.
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: connectVisitor(ctx, t, pp, "v1"), Identity: &UserIdentity{ID: "u1"}}); err != nil {
+		t.Errorf("same visitor: %v", err)
+	}
+}
+
+func TestIdentityConflictFork(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{IdentityConflictPolicy: IdentityConflictFork})
+	first, _, _ := identifyAs(ctx, t, pp, "v1")
+	second, resp, err := identifyAs(ctx, t, pp, "v2")
+	if err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	if resp.SessionID != "" {
+		t.Errorf("SessionID = %q, want empty", resp.SessionID)
+	}
+	got, _ := pp.GetSession(ctx, second)
+	if got.ForkedFrom != first || got.Identity == nil || got.Identity.ID != "u1" {
+		t.Errorf("forked session = %+v", got)
+	}
+}
+
+func TestIdentityConflictMerge(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{IdentityConflictPolicy: IdentityConflictMerge, IdentitySecret: "s3cret"})
+	first, _, _ := identifyAs(ctx, t, pp, "v1")
+	sendVisitorMessage(t, pp, first, "hello from the laptop")
+
+	second := connectVisitor(ctx, t, pp, "v2")
+	question := sendVisitorMessage(t, pp, second, "hello from the phone")
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: second, Content: "follow-up", Sender: SenderVisitor, ReplyTo: question}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(second, ws)
+
+	userHash := hex.EncodeToString(IdentityHash("s3cret", "u1"))
+	resp, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: second, Identity: &UserIdentity{ID: "u1"}, UserHash: userHash})
+	if err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	if resp.SessionID != first {
+		t.Errorf("SessionID = %q, want %q", resp.SessionID, first)
+	}
+	if got, _ := pp.GetSession(ctx, second); got != nil {
+		t.Error("merged session still exists")
+	}
+
+	messages, _ := pp.storage.GetMessages(ctx, first, "", 100)
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(messages))
+	}
+	if messages[2].ReplyTo != messages[1].ID || messages[1].ID == question {
+		t.Errorf("reply not remapped: %+v", messages[2])
+	}
+	for _, m := range messages {
+		if m.SessionID != first {
+			t.Errorf("message %s in session %s", m.ID, m.SessionID)
+		}
+	}
+
+	ws.mu.Lock()
+	last := ws.events[len(ws.events)-1]
+	ws.mu.Unlock()
+	if last.Type != "session_merged" || last.Data.(map[string]interface{})["sessionId"] != first {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestIdentityConflictMergeUnverified(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{IdentityConflictPolicy: IdentityConflictMerge, IdentitySecret: "s3cret"})
+	first, _, _ := identifyAs(ctx, t, pp, "v1")
+
+	for _, userHash := range []string{"", "zz", hex.EncodeToString(IdentityHash("wrong", "u1"))} {
+		second := connectVisitor(ctx, t, pp, "v2-"+userHash)
+		resp, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: second, Identity: &UserIdentity{ID: "u1"}, UserHash: userHash})
+		if err != nil {
+			t.Fatalf("HandleIdentify(%q): %v", userHash, err)
+		}
+		if resp.SessionID != "" {
+			t.Errorf("hash %q: merged into %s", userHash, resp.SessionID)
+		}
+		got, _ := pp.GetSession(ctx, second)
+		if got == nil || got.ForkedFrom == "" {
+			t.Errorf("hash %q: session = %+v, want forked", userHash, got)
+		}
+	}
+	if got, _ := pp.GetSession(ctx, first); got == nil {
+		t.Error("first session deleted")
+	}
+}
+
+func TestMemoryStorageIdentityIndex(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	a := &Session{ID: "a", VisitorID: "v1", Identity: &UserIdentity{ID: "u1"}}
+	b := &Session{ID: "b", VisitorID: "v2"}
+	_ = m.CreateSession(ctx, a)
+	_ = m.CreateSession(ctx, b)
+
+	b.Identity = &UserIdentity{ID: "u1"}
+	_ = m.UpdateSession(ctx, b)
+	if got, _ := m.FindSessionsByIdentity(ctx, "u1"); len(got) != 2 {
+		t.Fatalf("u1 sessions = %d, want 2", len(got))
+	}
+
+	a.Identity = &UserIdentity{ID: "u2"}
+	_ = m.UpdateSession(ctx, a)
+	_ = m.DeleteSession(ctx, "b")
+	if got, _ := m.FindSessionsByIdentity(ctx, "u1"); len(got) != 0 {
+		t.Errorf("u1 sessions = %d, want 0", len(got))
+	}
+	if got, _ := m.FindSessionsByIdentity(ctx, "u2"); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("u2 sessions = %+v", got)
+	}
+	if len(m.identities) != 1 || len(m.sessionIdentity) != 1 {
+		t.Errorf("index not cleaned up: %v %v", m.identities, m.sessionIdentity)
+	}
+}
+
+func TestIdentityConflictWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event map[string]interface{}
+		_ = json.Unmarshal(body, &event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	ctx := context.Background()
+	pp := New(Config{WebhookURL: webhook.URL, IdentityConflictPolicy: IdentityConflictReject})
+	first, _, _ := identifyAs(ctx, t, pp, "v1")
+	second, _, _ := identifyAs(ctx, t, pp, "v2")
+	if err := pp.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, event := range events {
+		if event["type"] != "identity_conflict" {
+			continue
+		}
+		data := event["data"].(map[string]interface{})
+		if data["sessionId"] != second || data["existingSessionId"] != first || data["visitorId"] != "v2" || data["policy"] != "reject" {
+			t.Errorf("data = %v", data)
+		}
+		return
+	}
+	t.Errorf("no identity_conflict webhook in %v", events)
+}
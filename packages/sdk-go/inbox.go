@@ -0,0 +1,349 @@
+package pocketping
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxInboxPreview is the longest InboxEntry.LastMessage gets, in runes.
+const maxInboxPreview = 120
+
+// InboxEntry is a session's row in the operator inbox.
+type InboxEntry struct {
+	SessionID string `json:"sessionId"`
+	VisitorID string `json:"visitorId"`
+	// Visitor is the identified visitor's email, name or ID.
+	Visitor string `json:"visitor,omitempty"`
+	// Operator is the last operator who replied; empty while unanswered.
+	Operator string `json:"operator,omitempty"`
+	// Unread counts the visitor messages since the last operator reply or
+	// Inbox.MarkRead.
+	Unread       int       `json:"unread"`
+	LastMessage  string    `json:"lastMessage,omitempty"`
+	LastActivity time.Time `json:"lastActivity"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// InboxQuery selects inbox entries.
+type InboxQuery struct {
+	// Operator only lists the sessions this operator last replied in.
+	Operator string
+	// Unassigned only lists sessions no operator has replied in.
+	Unassigned bool
+	// UnreadOnly only lists sessions with unread visitor messages.
+	UnreadOnly bool
+	// Before only lists sessions last active before this time, for paging.
+	Before time.Time
+	// Limit caps the results. Defaults to 50.
+	Limit int
+}
+
+// Inbox is a read model of sessions for operator inboxes, kept up to date as
+// messages come in, so listing the most recent conversations costs the page
+// size rather than a scan of every session. Enable it with
+// Config.InboxReadModel.
+type Inbox struct {
+	mu      sync.RWMutex
+	entries map[string]*inboxItem
+	// recent holds every session, most recently active first; byOperator
+	// holds each operator's sessions ("" for unassigned) in the same order.
+	recent     *list.List
+	byOperator map[string]*list.List
+	unread     map[string]int // operator -> unread visitor messages
+}
+
+type inboxItem struct {
+	entry InboxEntry
+	all   *list.Element
+	own   *list.Element
+}
+
+func newInbox() *Inbox {
+	return &Inbox{
+		entries:    make(map[string]*inboxItem),
+		recent:     list.New(),
+		byOperator: make(map[string]*list.List),
+		unread:     make(map[string]int),
+	}
+}
+
+// List returns the entries matching query, most recently active first.
+func (in *Inbox) List(query InboxQuery) []InboxEntry {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+
+	sessions := in.recent
+	switch {
+	case query.Operator != "":
+		sessions = in.byOperator[query.Operator]
+	case query.Unassigned:
+		sessions = in.byOperator[""]
+	}
+	if sessions == nil {
+		return []InboxEntry{}
+	}
+
+	entries := []InboxEntry{}
+	for e := sessions.Front(); e != nil && len(entries) < limit; e = e.Next() {
+		entry := e.Value.(*inboxItem).entry
+		if !query.Before.IsZero() && !entry.LastActivity.Before(query.Before) {
+			continue
+		}
+		if query.UnreadOnly && entry.Unread == 0 {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Get returns a session's entry.
+func (in *Inbox) Get(sessionID string) (InboxEntry, bool) {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+
+	item, ok := in.entries[sessionID]
+	if !ok {
+		return InboxEntry{}, false
+	}
+	return item.entry, true
+}
+
+// Unread returns the unread visitor messages across an operator's sessions
+// ("" for unassigned sessions).
+func (in *Inbox) Unread(operator string) int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return in.unread[operator]
+}
+
+// Len returns the number of sessions in the inbox.
+func (in *Inbox) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.entries)
+}
+
+// MarkRead clears a session's unread count, e.g. when an operator opens it.
+func (in *Inbox) MarkRead(sessionID string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if item, ok := in.entries[sessionID]; ok {
+		in.setUnread(item, 0)
+	}
+}
+
+// Remove drops a session from the inbox.
+func (in *Inbox) Remove(sessionID string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	item, ok := in.entries[sessionID]
+	if !ok {
+		return
+	}
+	in.setUnread(item, 0)
+	in.recent.Remove(item.all)
+	in.detach(item)
+	delete(in.entries, sessionID)
+}
+
+// addSession adds (or refreshes) a session's entry. Nil-safe, like the other
+// updates, so callers needn't check whether the read model is enabled.
+func (in *Inbox) addSession(session *Session) {
+	if in == nil {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	item := in.item(session.ID)
+	item.entry.VisitorID = session.VisitorID
+	item.entry.CreatedAt = session.CreatedAt
+	if session.Identity != nil {
+		item.entry.Visitor = identityLabel(session.Identity)
+	}
+	if session.LastActivity.After(item.entry.LastActivity) {
+		in.touch(item, session.LastActivity)
+	}
+}
+
+// recordMessage updates a session's entry for a new message: visitor
+// messages are unread until an operator replies.
+func (in *Inbox) recordMessage(message *Message) {
+	if in == nil {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	item := in.item(message.SessionID)
+	if preview := inboxPreview(message); preview != "" {
+		item.entry.LastMessage = preview
+	}
+	switch message.Sender {
+	case SenderVisitor:
+		in.setUnread(item, item.entry.Unread+1)
+	case SenderOperator:
+		in.setUnread(item, 0)
+	}
+	in.touch(item, message.Timestamp)
+}
+
+// assign records operator as the one handling a session.
+func (in *Inbox) assign(sessionID, operator string) {
+	if in == nil || operator == "" {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	item := in.item(sessionID)
+	if item.entry.Operator == operator {
+		return
+	}
+	unread := item.entry.Unread
+	in.setUnread(item, 0)
+	in.detach(item)
+	item.entry.Operator = operator
+	in.attach(item)
+	in.setUnread(item, unread)
+}
+
+// item returns a session's item, creating it. Callers hold in.mu.
+func (in *Inbox) item(sessionID string) *inboxItem {
+	if item, ok := in.entries[sessionID]; ok {
+		return item
+	}
+	item := &inboxItem{entry: InboxEntry{SessionID: sessionID}}
+	item.all = in.recent.PushBack(item)
+	in.attach(item)
+	in.entries[sessionID] = item
+	return item
+}
+
+// touch moves an item to its place by last activity. Activity is almost
+// always "now", so this is normally a move to the front. Callers hold in.mu.
+func (in *Inbox) touch(item *inboxItem, at time.Time) {
+	if at.Before(item.entry.LastActivity) {
+		return
+	}
+	item.entry.LastActivity = at
+	reposition(in.recent, item.all, at)
+	reposition(in.byOperator[item.entry.Operator], item.own, at)
+}
+
+// reposition moves e to the front of l, or just behind the newer items.
+func reposition(l *list.List, e *list.Element, at time.Time) {
+	mark := l.Front()
+	for mark != nil && (mark == e || mark.Value.(*inboxItem).entry.LastActivity.After(at)) {
+		mark = mark.Next()
+	}
+	switch {
+	case mark == nil:
+		l.MoveToBack(e)
+	case mark != e:
+		l.MoveBefore(e, mark)
+	}
+}
+
+// attach adds an item to its operator's list. Callers hold in.mu.
+func (in *Inbox) attach(item *inboxItem) {
+	sessions := in.byOperator[item.entry.Operator]
+	if sessions == nil {
+		sessions = list.New()
+		in.byOperator[item.entry.Operator] = sessions
+	}
+	item.own = sessions.PushBack(item)
+	reposition(sessions, item.own, item.entry.LastActivity)
+}
+
+// detach removes an item from its operator's list. Callers hold in.mu.
+func (in *Inbox) detach(item *inboxItem) {
+	sessions := in.byOperator[item.entry.Operator]
+	sessions.Remove(item.own)
+	if sessions.Len() == 0 {
+		delete(in.byOperator, item.entry.Operator)
+	}
+}
+
+// setUnread updates a session's unread count and its operator's total.
+// Callers hold in.mu.
+func (in *Inbox) setUnread(item *inboxItem, unread int) {
+	in.unread[item.entry.Operator] += unread - item.entry.Unread
+	if in.unread[item.entry.Operator] == 0 {
+		delete(in.unread, item.entry.Operator)
+	}
+	item.entry.Unread = unread
+}
+
+// inboxPreview is a message's one-line preview.
+func inboxPreview(message *Message) string {
+	preview := strings.Join(strings.Fields(message.Content), " ")
+	if runes := []rune(preview); len(runes) > maxInboxPreview {
+		preview = string(runes[:maxInboxPreview-1]) + "…"
+	}
+	if preview == "" && len(message.Attachments) > 0 {
+		preview = "📎 " + message.Attachments[0].Filename
+	}
+	return preview
+}
+
+// Inbox returns the operator inbox read model, or nil unless
+// Config.InboxReadModel is set.
+func (pp *PocketPing) Inbox() *Inbox {
+	return pp.inbox
+}
+
+// RebuildInbox fills the inbox read model from storage, e.g. at startup. It
+// scans every session once (the storage must implement
+// StorageWithListSessions). Operators aren't stored with messages, so
+// rebuilt sessions are unassigned until the next operator reply.
+func (pp *PocketPing) RebuildInbox(ctx context.Context) error {
+	if pp.inbox == nil {
+		return nil
+	}
+	lister, ok := pp.storage.(StorageWithListSessions)
+	if !ok {
+		return ErrListSessionsUnsupported
+	}
+	sessions, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return err
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivity.Before(sessions[j].LastActivity)
+	})
+
+	rebuilt := newInbox()
+	for _, session := range sessions {
+		messages, err := pp.allMessages(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		rebuilt.addSession(session)
+		for i := range messages {
+			if messages[i].DeletedAt == nil {
+				rebuilt.recordMessage(&messages[i])
+			}
+		}
+	}
+
+	pp.inbox.mu.Lock()
+	defer pp.inbox.mu.Unlock()
+	pp.inbox.entries = rebuilt.entries
+	pp.inbox.recent = rebuilt.recent
+	pp.inbox.byOperator = rebuilt.byOperator
+	pp.inbox.unread = rebuilt.unread
+	return nil
+}
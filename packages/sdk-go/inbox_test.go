@@ -0,0 +1,123 @@
+package pocketping
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func connectVisitor(ctx context.Context, t *testing.T, pp *PocketPing, visitorID string) string {
+	t.Helper()
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: visitorID})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	return resp.SessionID
+}
+
+func TestInbox(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{InboxReadModel: true})
+	first := connectVisitor(ctx, t, pp, "v1")
+	second := connectVisitor(ctx, t, pp, "v2")
+
+	sendVisitorMessage(t, pp, first, "Hi")
+	sendVisitorMessage(t, pp, second, "Hello")
+	sendVisitorMessage(t, pp, second, "anyone?")
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: second, Identity: &UserIdentity{ID: "u1", Email: "ann@example.com"}}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+
+	inbox := pp.Inbox()
+	entries := inbox.List(InboxQuery{})
+	if len(entries) != 2 || entries[0].SessionID != second || entries[0].Unread != 2 || entries[0].Visitor != "ann@example.com" || entries[0].LastMessage != "anyone?" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if inbox.Unread("") != 3 {
+		t.Errorf("unassigned unread = %d, want 3", inbox.Unread(""))
+	}
+
+	if _, err := pp.SendOperatorMessage(ctx, first, "Hi, how can I help?", "slack", "Bob"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	mine := inbox.List(InboxQuery{Operator: "Bob"})
+	if len(mine) != 1 || mine[0].SessionID != first || mine[0].Unread != 0 {
+		t.Fatalf("Bob's inbox = %+v", mine)
+	}
+	if unassigned := inbox.List(InboxQuery{Unassigned: true}); len(unassigned) != 1 || unassigned[0].SessionID != second {
+		t.Errorf("unassigned = %+v", unassigned)
+	}
+	if unread := inbox.List(InboxQuery{UnreadOnly: true}); len(unread) != 1 || unread[0].SessionID != second {
+		t.Errorf("unread = %+v", unread)
+	}
+
+	sendVisitorMessage(t, pp, first, "My order is late")
+	if entries := inbox.List(InboxQuery{Limit: 1}); entries[0].SessionID != first {
+		t.Errorf("most recent = %s, want %s", entries[0].SessionID, first)
+	}
+	if inbox.Unread("Bob") != 1 {
+		t.Errorf("Bob's unread = %d, want 1", inbox.Unread("Bob"))
+	}
+	inbox.MarkRead(first)
+	if inbox.Unread("Bob") != 0 {
+		t.Errorf("Bob's unread after MarkRead = %d", inbox.Unread("Bob"))
+	}
+
+	page := inbox.List(InboxQuery{Before: inbox.List(InboxQuery{Limit: 1})[0].LastActivity})
+	if len(page) != 1 || page[0].SessionID != second {
+		t.Errorf("next page = %+v", page)
+	}
+}
+
+func TestRebuildInbox(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	pp := New(Config{Storage: storage})
+	older := connectVisitor(ctx, t, pp, "v1")
+	sendVisitorMessage(t, pp, older, "first")
+	time.Sleep(time.Millisecond)
+	newer := connectVisitor(ctx, t, pp, "v2")
+	sendVisitorMessage(t, pp, newer, "second")
+	if _, err := pp.SendOperatorMessage(ctx, newer, "reply", "slack", "Bob"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+
+	restarted := New(Config{Storage: storage, InboxReadModel: true})
+	if err := restarted.RebuildInbox(ctx); err != nil {
+		t.Fatalf("RebuildInbox: %v", err)
+	}
+	entries := restarted.Inbox().List(InboxQuery{})
+	if len(entries) != 2 || entries[0].SessionID != newer || entries[0].Unread != 0 || entries[1].Unread != 1 {
+		t.Errorf("rebuilt entries = %+v", entries)
+	}
+}
+
+func TestInboxDropsDeletedSessions(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage(WithMaxSessions(2))
+	pp := New(Config{Storage: storage, InboxReadModel: true})
+	deleted := connectVisitor(ctx, t, pp, "v1")
+	old := connectVisitor(ctx, t, pp, "v2")
+	if err := storage.DeleteSession(ctx, deleted); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, ok := pp.Inbox().Get(deleted); ok {
+		t.Error("deleted session still in the inbox")
+	}
+
+	// Over WithMaxSessions, the least recently active session is evicted
+	time.Sleep(time.Millisecond)
+	kept := connectVisitor(ctx, t, pp, "v3")
+	time.Sleep(time.Millisecond)
+	connectVisitor(ctx, t, pp, "v4")
+	if _, ok := pp.Inbox().Get(old); ok {
+		t.Error("evicted session still in the inbox")
+	}
+
+	if _, err := storage.CleanupOldSessions(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("CleanupOldSessions: %v", err)
+	}
+	if _, ok := pp.Inbox().Get(kept); ok || pp.Inbox().Len() != 0 {
+		t.Errorf("inbox after cleanup has %d entries", pp.Inbox().Len())
+	}
+}
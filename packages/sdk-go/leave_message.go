@@ -0,0 +1,137 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLeaveMessagePrompt is the welcome message of leave-a-message
+// sessions when Config.LeaveMessagePrompt is empty.
+const DefaultLeaveMessagePrompt = "We're away right now. Leave us a message and we'll get back to you."
+
+// DefaultLeaveMessageDigestDelay is how long visitor messages are collected
+// into a digest in leave-a-message mode.
+const DefaultLeaveMessageDigestDelay = 5 * time.Minute
+
+// maxDigestLine is the longest a message gets in a digest, in runes.
+const maxDigestLine = 200
+
+// leaveMessageActive reports whether sessions connecting now get
+// leave-a-message mode: it's enabled, nobody is online and the AI is off.
+func (pp *PocketPing) leaveMessageActive() bool {
+	return pp.config.LeaveMessageMode && !pp.operatorOnline && pp.aiProvider == nil
+}
+
+// leaveMessagePrompt returns the welcome message of leave-a-message sessions.
+func (pp *PocketPing) leaveMessagePrompt() string {
+	if pp.config.LeaveMessagePrompt != "" {
+		return pp.config.LeaveMessagePrompt
+	}
+	return DefaultLeaveMessagePrompt
+}
+
+// messageDigest collects a leave-a-message session's visitor messages after
+// the first one of the digest window.
+type messageDigest struct {
+	messages []*Message
+	timer    *time.Timer
+}
+
+// messageDigests holds the open digests by session ID.
+type messageDigests struct {
+	mu      sync.Mutex
+	pending map[string]*messageDigest
+}
+
+// holdForDigest reports whether a visitor message of a leave-a-message
+// session goes into a digest instead of being mirrored. The first message of
+// each digest window is mirrored as usual; the window closes after
+// Config.LeaveMessageDigestDelay, when the rest is posted as one notice.
+// Sessions are back to normal as soon as an operator is online.
+func (pp *PocketPing) holdForDigest(ctx context.Context, message *Message, session *Session) bool {
+	if !session.LeaveMessage || pp.operatorOnline {
+		return false
+	}
+
+	delay := pp.config.LeaveMessageDigestDelay
+	if delay <= 0 {
+		delay = DefaultLeaveMessageDigestDelay
+	}
+
+	pp.digests.mu.Lock()
+	defer pp.digests.mu.Unlock()
+	if pp.digests.pending == nil {
+		pp.digests.pending = make(map[string]*messageDigest)
+	}
+	digest, ok := pp.digests.pending[session.ID]
+	if !ok {
+		flushCtx := context.WithoutCancel(ctx)
+		pp.digests.pending[session.ID] = &messageDigest{
+			timer: time.AfterFunc(delay, func() { pp.flushDigest(flushCtx, session.ID) }),
+		}
+		return false
+	}
+	digest.messages = append(digest.messages, message)
+	return true
+}
+
+// flushDigest closes a session's digest window, posting its messages.
+func (pp *PocketPing) flushDigest(ctx context.Context, sessionID string) {
+	pp.digests.mu.Lock()
+	digest, ok := pp.digests.pending[sessionID]
+	delete(pp.digests.pending, sessionID)
+	pp.digests.mu.Unlock()
+	if !ok {
+		return
+	}
+	digest.timer.Stop()
+	if len(digest.messages) == 0 {
+		return
+	}
+
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil || session == nil {
+		log.Printf("[PocketPing] Dropped digest of %d messages for session %s: session not found", len(digest.messages), sessionID)
+		return
+	}
+	pp.notifyBridgesNotice(ctx, session, formatDigest(digest.messages))
+}
+
+// flushDigests posts every open digest, e.g. when an operator comes online.
+func (pp *PocketPing) flushDigests(ctx context.Context) {
+	pp.digests.mu.Lock()
+	sessionIDs := make([]string, 0, len(pp.digests.pending))
+	for sessionID := range pp.digests.pending {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	pp.digests.mu.Unlock()
+
+	for _, sessionID := range sessionIDs {
+		pp.flushDigest(ctx, sessionID)
+	}
+}
+
+// formatDigest renders held messages as one notice.
+func formatDigest(messages []*Message) string {
+	var b strings.Builder
+	if len(messages) == 1 {
+		b.WriteString("📨 1 more message:")
+	} else {
+		fmt.Fprintf(&b, "📨 %d more messages:", len(messages))
+	}
+	for _, message := range messages {
+		line := strings.Join(strings.Fields(message.Content), " ")
+		if runes := []rune(line); len(runes) > maxDigestLine {
+			line = string(runes[:maxDigestLine-1]) + "…"
+		}
+		if line == "" && len(message.Attachments) > 0 {
+			line = fmt.Sprintf("(%d attachments)", len(message.Attachments))
+		}
+		b.WriteString("\n• " + line)
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package pocketping
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type digestRecordingBridge struct {
+	notifyRecordingBridge
+	messages []*Message
+}
+
+func (b *digestRecordingBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, message)
+	return nil
+}
+
+func TestLeaveMessageMode(t *testing.T) {
+	ctx := context.Background()
+	bridge := &digestRecordingBridge{notifyRecordingBridge: notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}}
+	pp := New(Config{Bridges: []Bridge{bridge}, LeaveMessageMode: true})
+
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1"})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if !resp.LeaveMessage || resp.WelcomeMessage != DefaultLeaveMessagePrompt {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	for _, text := range []string{"Hi, my order is late", "order #42", "  please   call me  "} {
+		sendVisitorMessage(t, pp, resp.SessionID, text)
+	}
+	pp.dispatcher.wait()
+	if len(bridge.messages) != 1 || bridge.messages[0].Content != "Hi, my order is late" {
+		t.Fatalf("mirrored %d messages, want only the first", len(bridge.messages))
+	}
+	if len(bridge.notices) != 0 {
+		t.Fatalf("digest posted early: %q", bridge.notices)
+	}
+
+	pp.SetOperatorOnline(true)
+	pp.dispatcher.wait()
+	if want := "📨 2 more messages:\n• order #42\n• please call me"; strings.Join(bridge.notices, "|") != want {
+		t.Errorf("notices = %q, want %q", bridge.notices, want)
+	}
+
+	sendVisitorMessage(t, pp, resp.SessionID, "Thanks!")
+	pp.dispatcher.wait()
+	if len(bridge.messages) != 2 {
+		t.Errorf("message after the operator came online was not mirrored")
+	}
+}
+
+func TestLeaveMessageModeNeedsEveryoneAway(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{LeaveMessageMode: true, AIProvider: &fakeAIProvider{}})
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1"})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if resp.LeaveMessage {
+		t.Error("leave-a-message mode with the AI enabled")
+	}
+}
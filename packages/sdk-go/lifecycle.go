@@ -0,0 +1,254 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLifecycleCheckInterval is how often SessionLifecycle looks for
+// inactive sessions by default.
+const DefaultLifecycleCheckInterval = time.Minute
+
+// ErrLifecycleUnsupported is returned by Start and CloseInactiveSessions
+// when Config.SessionLifecycle is set but the storage adapter implements
+// neither StorageWithInactiveSessions nor StorageWithListSessions.
+var ErrLifecycleUnsupported = errors.New("session lifecycle requires Storage to implement StorageWithInactiveSessions or StorageWithListSessions")
+
+// SessionLifecycle closes sessions after a period of inactivity (see
+// Config.SessionLifecycle). A closed session is ended like one handed over
+// with StartFreshSession, except that the bridges only get the
+// "session_closed" custom event, which also goes to the event handlers and
+// webhook. The session is then archived.
+type SessionLifecycle struct {
+	// InactivityTimeout closes sessions whose last activity is older.
+	// Zero closes none; CloseSession still works.
+	InactivityTimeout time.Duration
+	// CheckInterval is how often sessions are checked. Defaults to
+	// DefaultLifecycleCheckInterval.
+	CheckInterval time.Duration
+	// Archiver, when set, receives every closed session with its messages.
+	Archiver Archiver
+}
+
+// SessionArchive is a closed session with its messages.
+type SessionArchive struct {
+	Session  *Session  `json:"session"`
+	Messages []Message `json:"messages"`
+	// Reason is "inactive", or the reason passed to CloseSession.
+	Reason   string    `json:"reason"`
+	ClosedAt time.Time `json:"closedAt"`
+}
+
+// Archiver exports closed sessions, e.g. to cold storage.
+type Archiver interface {
+	Archive(ctx context.Context, archive *SessionArchive) error
+}
+
+// ArchiverFunc is a function used as an Archiver.
+type ArchiverFunc func(ctx context.Context, archive *SessionArchive) error
+
+// Archive calls f.
+func (f ArchiverFunc) Archive(ctx context.Context, archive *SessionArchive) error {
+	return f(ctx, archive)
+}
+
+// JSONArchiver stores each archive as a JSON document named
+// "<session ID>.json" through Put, e.g. in an S3 bucket.
+type JSONArchiver struct {
+	Put func(ctx context.Context, name string, body []byte) error
+}
+
+// NewDirArchiver returns a JSONArchiver writing into dir.
+func NewDirArchiver(dir string) *JSONArchiver {
+	return &JSONArchiver{Put: func(ctx context.Context, name string, body []byte) error {
+		return os.WriteFile(filepath.Join(dir, name), body, 0o600)
+	}}
+}
+
+// Archive encodes archive and stores it.
+func (a *JSONArchiver) Archive(ctx context.Context, archive *SessionArchive) error {
+	body, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	return a.Put(ctx, archive.Session.ID+".json", body)
+}
+
+var (
+	_ Archiver = ArchiverFunc(nil)
+	_ Archiver = (*JSONArchiver)(nil)
+)
+
+// startLifecycle closes inactive sessions each CheckInterval until Stop.
+func (pp *PocketPing) startLifecycle() error {
+	lifecycle := pp.config.SessionLifecycle
+	if lifecycle == nil || lifecycle.InactivityTimeout <= 0 || pp.lifecycleStop != nil {
+		return nil
+	}
+	if !canListInactive(pp.storage) {
+		return ErrLifecycleUnsupported
+	}
+	interval := lifecycle.CheckInterval
+	if interval <= 0 {
+		interval = DefaultLifecycleCheckInterval
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	pp.lifecycleStop, pp.lifecycleDone = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := pp.CloseInactiveSessions(context.Background()); err != nil {
+					log.Printf("[PocketPing] Closing inactive sessions failed: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// stopLifecycle ends the checks started by Start, waiting for a running
+// one to finish.
+func (pp *PocketPing) stopLifecycle() {
+	if pp.lifecycleStop == nil {
+		return
+	}
+	close(pp.lifecycleStop)
+	<-pp.lifecycleDone
+	pp.lifecycleStop, pp.lifecycleDone = nil, nil
+}
+
+// CloseInactiveSessions closes the open sessions inactive for longer than
+// SessionLifecycle.InactivityTimeout and returns how many it closed. Start
+// runs it every CheckInterval; call it yourself to close them on your own
+// schedule.
+func (pp *PocketPing) CloseInactiveSessions(ctx context.Context) (int, error) {
+	lifecycle := pp.config.SessionLifecycle
+	if lifecycle == nil || lifecycle.InactivityTimeout <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-lifecycle.InactivityTimeout)
+	sessions, err := listInactiveSessions(ctx, pp.storage, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, session := range sessions {
+		if session.EndedAt != nil || !session.LastActivity.Before(cutoff) {
+			continue
+		}
+		if err := pp.closeSession(ctx, session, "inactive"); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// canListInactive reports whether storage can find inactive sessions.
+func canListInactive(storage Storage) bool {
+	switch storage.(type) {
+	case StorageWithInactiveSessions, StorageWithListSessions:
+		return true
+	}
+	return false
+}
+
+// listInactiveSessions returns the open sessions of storage last active
+// before cutoff, from StorageWithInactiveSessions, or by listing every
+// session.
+func listInactiveSessions(ctx context.Context, storage Storage, cutoff time.Time) ([]*Session, error) {
+	if store, ok := storage.(StorageWithInactiveSessions); ok {
+		return store.ListInactiveSessions(ctx, cutoff)
+	}
+	lister, ok := storage.(StorageWithListSessions)
+	if !ok {
+		return nil, ErrLifecycleUnsupported
+	}
+	sessions, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	inactive := sessions[:0]
+	for _, session := range sessions {
+		if session.EndedAt == nil && session.LastActivity.Before(cutoff) {
+			inactive = append(inactive, session)
+		}
+	}
+	return inactive, nil
+}
+
+// CloseSession closes a session now, as SessionLifecycle does inactive ones:
+// the session is ended, a "session_closed" event is emitted with reason,
+// and the session is archived when SessionLifecycle.Archiver is set.
+func (pp *PocketPing) CloseSession(ctx context.Context, sessionID, reason string) error {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrSessionNotFound
+	}
+	if session.EndedAt != nil {
+		return ErrSessionEnded
+	}
+	return pp.closeSession(ctx, session, reason)
+}
+
+// closeSession ends session, emits session_closed and archives it. The
+// event is the bridges' only notification; watchers get a line.
+func (pp *PocketPing) closeSession(ctx context.Context, session *Session, reason string) error {
+	lastActivity := session.LastActivity
+	if err := pp.endSession(ctx, session, ""); err != nil {
+		return err
+	}
+	closedAt := *session.EndedAt
+	pp.notifyWatchers(ctx, session, "🔚 Session closed ("+reason+")", "", "")
+
+	err := pp.TriggerEvent(ctx, session.ID, "session_closed", map[string]interface{}{
+		"reason":       reason,
+		"closedAt":     closedAt.Format(time.RFC3339),
+		"lastActivity": lastActivity.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	if lifecycle := pp.config.SessionLifecycle; lifecycle != nil && lifecycle.Archiver != nil {
+		if err := pp.archiveSession(ctx, lifecycle.Archiver, session, reason, closedAt); err != nil {
+			// The session is closed either way; archiving is best effort
+			log.Printf("[PocketPing] Archiving session %s failed: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// archiveSession hands session and all its messages to archiver.
+func (pp *PocketPing) archiveSession(ctx context.Context, archiver Archiver, session *Session, reason string, closedAt time.Time) error {
+	archive := &SessionArchive{Session: session, Messages: []Message{}, Reason: reason, ClosedAt: closedAt}
+	after := ""
+	for {
+		page, err := pp.storage.GetMessages(ctx, session.ID, after, snapshotPageSize)
+		if err != nil {
+			return fmt.Errorf("get messages: %w", err)
+		}
+		archive.Messages = append(archive.Messages, page...)
+		if len(page) < snapshotPageSize {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+	return archiver.Archive(ctx, archive)
+}
@@ -0,0 +1,180 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// idleSession makes a session look inactive for d.
+func idleSession(ctx context.Context, t *testing.T, pp *PocketPing, sessionID string, d time.Duration) {
+	t.Helper()
+	session, _ := pp.storage.GetSession(ctx, sessionID)
+	session.LastActivity = time.Now().Add(-d)
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		t.Fatalf("UpdateSession: %v", err)
+	}
+}
+
+func TestCloseInactiveSessions(t *testing.T) {
+	ctx := context.Background()
+	bridge := NewMockBridge("slack")
+	notifier := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}
+	var archives []*SessionArchive
+	pp := New(Config{
+		Bridges: []Bridge{bridge, notifier},
+		SessionLifecycle: &SessionLifecycle{
+			InactivityTimeout: 30 * time.Minute,
+			Archiver: ArchiverFunc(func(ctx context.Context, archive *SessionArchive) error {
+				archives = append(archives, archive)
+				return nil
+			}),
+		},
+	})
+	idle := connectVisitor(ctx, t, pp, "v1")
+	active := connectVisitor(ctx, t, pp, "v2")
+	sendVisitorMessage(t, pp, idle, "anyone there?")
+	idleSession(ctx, t, pp, idle, time.Hour)
+
+	closed, err := pp.CloseInactiveSessions(ctx)
+	if err != nil || closed != 1 {
+		t.Fatalf("closed = %d, err = %v", closed, err)
+	}
+	pp.dispatcher.wait()
+
+	if session, _ := pp.storage.GetSession(ctx, idle); session.EndedAt == nil {
+		t.Error("idle session not ended")
+	}
+	if session, _ := pp.storage.GetSession(ctx, active); session.EndedAt != nil {
+		t.Error("active session ended")
+	}
+	if len(bridge.EventCalls) != 1 || bridge.EventCalls[0].Name != "session_closed" || bridge.EventCalls[0].Data["reason"] != "inactive" {
+		t.Errorf("bridge events = %+v", bridge.EventCalls)
+	}
+	// The event is the bridges' only notification
+	if len(notifier.notices) != 0 {
+		t.Errorf("notices = %v", notifier.notices)
+	}
+	if len(archives) != 1 || archives[0].Session.ID != idle || len(archives[0].Messages) != 1 || archives[0].Reason != "inactive" {
+		t.Errorf("archives = %+v", archives)
+	}
+
+	// Closed sessions stay closed
+	if closed, _ := pp.CloseInactiveSessions(ctx); closed != 0 {
+		t.Errorf("closed again = %d", closed)
+	}
+	if err := pp.CloseSession(ctx, idle, "manual"); !errors.Is(err, ErrSessionEnded) {
+		t.Errorf("CloseSession err = %v", err)
+	}
+}
+
+func TestSessionLifecycleStart(t *testing.T) {
+	ctx := context.Background()
+	closed := make(chan string, 1)
+	pp := New(Config{SessionLifecycle: &SessionLifecycle{InactivityTimeout: time.Minute, CheckInterval: 5 * time.Millisecond}})
+	pp.OnEvent("session_closed", func(event CustomEvent, session *Session) {
+		closed <- session.ID
+	})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	idleSession(ctx, t, pp, sessionID, 2*time.Minute)
+
+	if err := pp.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer pp.Stop(ctx)
+	select {
+	case id := <-closed:
+		if id != sessionID {
+			t.Errorf("closed %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session not closed")
+	}
+
+	unsupported := New(Config{Storage: struct{ Storage }{NewMemoryStorage()}, SessionLifecycle: &SessionLifecycle{InactivityTimeout: time.Minute}})
+	if err := unsupported.Start(ctx); !errors.Is(err, ErrLifecycleUnsupported) {
+		t.Errorf("Start err = %v", err)
+	}
+}
+
+func TestCloseInactiveSessionsListsAll(t *testing.T) {
+	ctx := context.Background()
+	// Storage without StorageWithInactiveSessions has every session listed
+	storage := struct{ StorageWithListSessions }{NewMemoryStorage()}
+	pp := New(Config{Storage: storage, SessionLifecycle: &SessionLifecycle{InactivityTimeout: time.Minute}})
+	idle := connectVisitor(ctx, t, pp, "v1")
+	connectVisitor(ctx, t, pp, "v2")
+	idleSession(ctx, t, pp, idle, time.Hour)
+
+	if closed, err := pp.CloseInactiveSessions(ctx); err != nil || closed != 1 {
+		t.Fatalf("closed = %d, err = %v", closed, err)
+	}
+	if session, _ := pp.storage.GetSession(ctx, idle); session.EndedAt == nil {
+		t.Error("idle session not ended")
+	}
+}
+
+func TestSessionLifecycleStopWaits(t *testing.T) {
+	ctx := context.Background()
+	archiving, release := make(chan struct{}), make(chan struct{})
+	archived := false
+	pp := New(Config{SessionLifecycle: &SessionLifecycle{
+		InactivityTimeout: time.Minute,
+		CheckInterval:     5 * time.Millisecond,
+		Archiver: ArchiverFunc(func(ctx context.Context, archive *SessionArchive) error {
+			close(archiving)
+			<-release
+			archived = true
+			return nil
+		}),
+	}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	idleSession(ctx, t, pp, sessionID, 2*time.Minute)
+	if err := pp.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	<-archiving
+
+	stopped := make(chan struct{})
+	go func() {
+		pp.Stop(ctx)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned during a check")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	if !archived {
+		t.Error("check not finished")
+	}
+}
+
+func TestDirArchiver(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	pp := New(Config{SessionLifecycle: &SessionLifecycle{Archiver: NewDirArchiver(dir)}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	sendVisitorMessage(t, pp, sessionID, "bye")
+
+	if err := pp.CloseSession(ctx, sessionID, "resolved"); err != nil {
+		t.Fatalf("CloseSession: %v", err)
+	}
+	body, err := os.ReadFile(filepath.Join(dir, sessionID+".json"))
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	var archive SessionArchive
+	if err := json.Unmarshal(body, &archive); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if archive.Session.ID != sessionID || archive.Reason != "resolved" || len(archive.Messages) != 1 || archive.Messages[0].Content != "bye" {
+		t.Errorf("archive = %+v", archive)
+	}
+}
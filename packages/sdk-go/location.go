@@ -0,0 +1,42 @@
+package pocketping
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Location is a point shared in a message: the widget's "share my location"
+// action, or a location an operator sends from Telegram.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Label is an optional place name or address.
+	Label string `json:"label,omitempty"`
+}
+
+// Valid reports whether the coordinates are in range.
+func (l Location) Valid() bool {
+	return l.Latitude >= -90 && l.Latitude <= 90 && l.Longitude >= -180 && l.Longitude <= 180
+}
+
+// MapURL returns a link to the location on OpenStreetMap, which the bridges
+// unfurl into a map preview.
+func (l Location) MapURL() string {
+	lat := strconv.FormatFloat(l.Latitude, 'f', -1, 64)
+	lon := strconv.FormatFloat(l.Longitude, 'f', -1, 64)
+	query := url.Values{"mlat": {lat}, "mlon": {lon}}
+	return fmt.Sprintf("https://www.openstreetmap.org/?%s#map=16/%s/%s", query.Encode(), lat, lon)
+}
+
+// locationText renders a location for bridges that only post text, or ""
+// when there is none.
+func locationText(location *Location) string {
+	if location == nil {
+		return ""
+	}
+	if location.Label != "" {
+		return fmt.Sprintf("\n📍 %s\n%s", location.Label, location.MapURL())
+	}
+	return "\n📍 " + location.MapURL()
+}
@@ -0,0 +1,91 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLocationMapURL(t *testing.T) {
+	location := Location{Latitude: 48.8584, Longitude: 2.2945}
+	want := "https://www.openstreetmap.org/?mlat=48.8584&mlon=2.2945#map=16/48.8584/2.2945"
+	if got := location.MapURL(); got != want {
+		t.Errorf("MapURL = %q, want %q", got, want)
+	}
+	if got := locationText(&Location{Latitude: 1, Longitude: 2, Label: "Office"}); !strings.HasPrefix(got, "\n📍 Office\nhttps://") {
+		t.Errorf("locationText = %q", got)
+	}
+	if locationText(nil) != "" {
+		t.Error("locationText(nil) not empty")
+	}
+}
+
+func TestHandleMessageLocation(t *testing.T) {
+	pp := New(Config{})
+	ctx := context.Background()
+	sessionID := newSessionFixture(t, pp)
+
+	_, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Sender: SenderVisitor, Location: &Location{Latitude: 91}})
+	if !errors.Is(err, ErrInvalidLocation) {
+		t.Errorf("err = %v, want ErrInvalidLocation", err)
+	}
+
+	resp, err := pp.HandleMessage(ctx, SendMessageRequest{
+		SessionID: sessionID,
+		Sender:    SenderVisitor,
+		Location:  &Location{Latitude: 48.8584, Longitude: 2.2945, Label: "Eiffel Tower"},
+	})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	stored, _ := pp.storage.GetMessage(ctx, resp.MessageID)
+	if stored == nil || stored.Location == nil || stored.Location.Label != "Eiffel Tower" {
+		t.Errorf("stored message = %+v", stored)
+	}
+}
+
+func TestTelegramBridgeVisitorLocation(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		calls = append(calls, r.URL.Path+" "+r.PostForm.Get("text")+r.PostForm.Get("latitude")+","+r.PostForm.Get("reply_to_message_id"))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":7}}`))
+	}))
+	defer srv.Close()
+	b := telegramBridgeTo(t, srv)
+
+	message := &Message{ID: "m1", Sender: SenderVisitor, Location: &Location{Latitude: 48.8584, Longitude: 2.2945}}
+	if err := b.OnVisitorMessage(context.Background(), message, sampleSession()); err != nil {
+		t.Fatalf("OnVisitorMessage: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 || !strings.Contains(calls[0], "openstreetmap.org") || calls[1] != "/sendLocation 48.8584,7" {
+		t.Errorf("calls = %q", calls)
+	}
+}
+
+func TestTelegramWebhookLocation(t *testing.T) {
+	var gotContent string
+	wh := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "tok",
+		OnOperatorMessage: func(ctx context.Context, sid, c, on, sb string, a []Attachment, r *int) {
+			gotContent = c
+		},
+	})
+	rec := postWebhook(wh.HandleTelegramWebhook(), `{"message":{"message_id":42,"message_thread_id":7,"location":{"latitude":48.8584,"longitude":2.2945},"from":{"id":1,"first_name":"Alice"}}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if want := "📍 https://www.openstreetmap.org/?mlat=48.8584&mlon=2.2945#map=16/48.8584/2.2945"; gotContent != want {
+		t.Errorf("content = %q, want %q", gotContent, want)
+	}
+}
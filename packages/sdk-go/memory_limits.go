@@ -0,0 +1,211 @@
+package pocketping
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// EvictionReason says why MemoryStorage released data.
+type EvictionReason string
+
+const (
+	// EvictionMaxSessions: the session was the least recently active when
+	// the session cap was exceeded.
+	EvictionMaxSessions EvictionReason = "max_sessions"
+	// EvictionIdleTTL: the session had no activity for longer than the idle TTL.
+	EvictionIdleTTL EvictionReason = "idle_ttl"
+	// EvictionMaxMessages: the session's oldest messages exceeded the
+	// per-session cap. The session itself is kept.
+	EvictionMaxMessages EvictionReason = "max_messages"
+)
+
+// Eviction describes data released by MemoryStorage. For session evictions
+// Messages holds all of the session's messages; for EvictionMaxMessages it
+// holds only the dropped ones.
+type Eviction struct {
+	Reason   EvictionReason
+	Session  *Session
+	Messages []Message
+}
+
+// EvictionHandler is called after data is evicted, outside the storage lock,
+// so it may archive it elsewhere (or call back into the storage).
+type EvictionHandler func(ctx context.Context, eviction Eviction)
+
+// MemoryStorageOption configures NewMemoryStorage.
+type MemoryStorageOption func(*MemoryStorage)
+
+// memoryLimits are the optional bounds on a MemoryStorage.
+type memoryLimits struct {
+	maxSessions int
+	maxMessages int
+	idleTTL     time.Duration
+	onEvict     EvictionHandler
+	lastSweep   time.Time
+}
+
+// maxIdleSweepInterval bounds how often writes scan for idle sessions.
+const maxIdleSweepInterval = time.Minute
+
+// WithMaxSessions caps the number of sessions. When exceeded, the least
+// recently active sessions are evicted with their messages.
+func WithMaxSessions(n int) MemoryStorageOption {
+	return func(m *MemoryStorage) {
+		m.limits.maxSessions = n
+	}
+}
+
+// WithMaxMessagesPerSession caps the messages kept per session. When
+// exceeded, the oldest messages are dropped.
+func WithMaxMessagesPerSession(n int) MemoryStorageOption {
+	return func(m *MemoryStorage) {
+		m.limits.maxMessages = n
+	}
+}
+
+// WithIdleTTL evicts sessions whose LastActivity is older than ttl. Idle
+// sessions are swept during writes (at most once a minute) and by EvictIdle.
+func WithIdleTTL(ttl time.Duration) MemoryStorageOption {
+	return func(m *MemoryStorage) {
+		m.limits.idleTTL = ttl
+	}
+}
+
+// WithOnEvict registers a callback for evicted data, e.g. for archival.
+func WithOnEvict(handler EvictionHandler) MemoryStorageOption {
+	return func(m *MemoryStorage) {
+		m.limits.onEvict = handler
+	}
+}
+
+// EvictIdle evicts sessions idle for longer than the configured idle TTL and
+// returns how many were evicted. No-op without WithIdleTTL.
+func (m *MemoryStorage) EvictIdle(ctx context.Context) (int, error) {
+	if m.limits.idleTTL <= 0 {
+		return 0, nil
+	}
+
+	m.mu.Lock()
+	m.limits.lastSweep = time.Time{}
+	evicted, err := m.enforceSessionLimitsLocked(time.Now())
+	m.mu.Unlock()
+
+	m.notifyEvicted(ctx, evicted)
+	return len(evicted), err
+}
+
+// enforceSessionLimitsLocked evicts idle sessions (if a sweep is due) and
+// then the least recently active sessions above the cap. Callers hold m.mu.
+func (m *MemoryStorage) enforceSessionLimitsLocked(now time.Time) ([]Eviction, error) {
+	var victims []string
+	reasons := map[string]EvictionReason{}
+
+	if ttl := m.limits.idleTTL; ttl > 0 {
+		every := ttl
+		if every > maxIdleSweepInterval {
+			every = maxIdleSweepInterval
+		}
+		if now.Sub(m.limits.lastSweep) >= every {
+			m.limits.lastSweep = now
+			cutoff := now.Add(-ttl)
+			for id, session := range m.sessions {
+				if session.LastActivity.Before(cutoff) {
+					victims = append(victims, id)
+					reasons[id] = EvictionIdleTTL
+				}
+			}
+		}
+	}
+
+	if max := m.limits.maxSessions; max > 0 && len(m.sessions)-len(victims) > max {
+		remaining := make([]*Session, 0, len(m.sessions))
+		for id, session := range m.sessions {
+			if _, ok := reasons[id]; !ok {
+				remaining = append(remaining, session)
+			}
+		}
+		sort.Slice(remaining, func(i, j int) bool {
+			return remaining[i].LastActivity.Before(remaining[j].LastActivity)
+		})
+		for _, session := range remaining[:len(remaining)-max] {
+			victims = append(victims, session.ID)
+			reasons[session.ID] = EvictionMaxSessions
+		}
+	}
+
+	if len(victims) == 0 {
+		return nil, nil
+	}
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpDeleteSessions, IDs: victims}); err != nil {
+		return nil, err
+	}
+
+	evicted := make([]Eviction, 0, len(victims))
+	for _, id := range victims {
+		evicted = append(evicted, Eviction{
+			Reason:   reasons[id],
+			Session:  m.sessions[id],
+			Messages: append([]Message(nil), m.messages[id]...),
+		})
+		m.applyDeleteSession(id)
+	}
+	return evicted, nil
+}
+
+// enforceMessageLimitLocked drops the oldest messages of a session above the
+// per-session cap. Callers hold m.mu.
+func (m *MemoryStorage) enforceMessageLimitLocked(sessionID string) ([]Eviction, error) {
+	max := m.limits.maxMessages
+	msgs := m.messages[sessionID]
+	if max <= 0 || len(msgs) <= max {
+		return nil, nil
+	}
+
+	dropped := append([]Message(nil), msgs[:len(msgs)-max]...)
+	ids := make([]string, len(dropped))
+	for i, msg := range dropped {
+		ids[i] = msg.ID
+	}
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpTrimMessages, SessionID: sessionID, IDs: ids}); err != nil {
+		return nil, err
+	}
+	m.applyTrimMessages(sessionID, ids)
+
+	return []Eviction{{
+		Reason:   EvictionMaxMessages,
+		Session:  m.sessions[sessionID],
+		Messages: dropped,
+	}}, nil
+}
+
+// applyTrimMessages removes the given messages from a session.
+func (m *MemoryStorage) applyTrimMessages(sessionID string, ids []string) {
+	drop := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		drop[id] = struct{}{}
+	}
+
+	msgs := m.messages[sessionID]
+	kept := make([]Message, 0, len(msgs))
+	var removed []Message
+	for _, msg := range msgs {
+		if _, ok := drop[msg.ID]; ok {
+			removed = append(removed, msg)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	m.messages[sessionID] = kept
+	m.forgetMessages(removed)
+}
+
+// notifyEvicted runs the eviction handler. Called without m.mu held.
+func (m *MemoryStorage) notifyEvicted(ctx context.Context, evicted []Eviction) {
+	if m.limits.onEvict == nil {
+		return
+	}
+	for _, eviction := range evicted {
+		m.limits.onEvict(ctx, eviction)
+	}
+}
@@ -0,0 +1,153 @@
+package pocketping
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorageMaxSessions(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var evicted []Eviction
+	m := NewMemoryStorage(
+		WithMaxSessions(2),
+		WithOnEvict(func(ctx context.Context, e Eviction) {
+			mu.Lock()
+			evicted = append(evicted, e)
+			mu.Unlock()
+		}),
+	)
+
+	now := time.Now()
+	for i, id := range []string{"sess-1", "sess-2", "sess-3"} {
+		s := createTestSession(id, "v", nil, nil)
+		s.LastActivity = now.Add(time.Duration(i) * time.Second)
+		if id == "sess-1" {
+			// Most recently active despite being created first
+			s.LastActivity = now.Add(time.Hour)
+		}
+		if err := m.CreateSession(ctx, s); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+		if id == "sess-2" {
+			_ = m.SaveMessage(ctx, createTestMessage("m1", "sess-2", "hello"))
+		}
+	}
+
+	if s, _ := m.GetSession(ctx, "sess-2"); s != nil {
+		t.Error("expected least recently active session to be evicted")
+	}
+	for _, id := range []string{"sess-1", "sess-3"} {
+		if s, _ := m.GetSession(ctx, id); s == nil {
+			t.Errorf("expected %s to be kept", id)
+		}
+	}
+	if msg, _ := m.GetMessage(ctx, "m1"); msg != nil {
+		t.Error("expected evicted session's messages to be released")
+	}
+
+	if len(evicted) != 1 {
+		t.Fatalf("expected 1 eviction, got %d", len(evicted))
+	}
+	e := evicted[0]
+	if e.Reason != EvictionMaxSessions || e.Session.ID != "sess-2" || len(e.Messages) != 1 {
+		t.Errorf("unexpected eviction: %+v", e)
+	}
+}
+
+func TestMemoryStorageMaxMessagesPerSession(t *testing.T) {
+	ctx := context.Background()
+	var evicted []Eviction
+	m := NewMemoryStorage(
+		WithMaxMessagesPerSession(2),
+		WithOnEvict(func(ctx context.Context, e Eviction) { evicted = append(evicted, e) }),
+	)
+	_ = m.CreateSession(ctx, createTestSession("sess-1", "v", nil, nil))
+	for _, id := range []string{"m1", "m2", "m3"} {
+		if err := m.SaveMessage(ctx, createTestMessage(id, "sess-1", id)); err != nil {
+			t.Fatalf("save %s: %v", id, err)
+		}
+	}
+
+	msgs, _ := m.GetMessages(ctx, "sess-1", "", 10)
+	if len(msgs) != 2 || msgs[0].ID != "m2" || msgs[1].ID != "m3" {
+		t.Errorf("expected [m2 m3], got %+v", msgs)
+	}
+	if msg, _ := m.GetMessage(ctx, "m1"); msg != nil {
+		t.Error("expected trimmed message to be released")
+	}
+	if len(evicted) != 1 || evicted[0].Reason != EvictionMaxMessages || evicted[0].Messages[0].ID != "m1" {
+		t.Errorf("unexpected evictions: %+v", evicted)
+	}
+	if s, _ := m.GetSession(ctx, "sess-1"); s == nil {
+		t.Error("trimming messages must keep the session")
+	}
+}
+
+func TestMemoryStorageIdleTTL(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage(WithIdleTTL(time.Hour))
+
+	idle := createTestSession("idle", "v", nil, nil)
+	idle.LastActivity = time.Now().Add(-2 * time.Hour)
+	_ = m.CreateSession(ctx, idle)
+	_ = m.CreateSession(ctx, createTestSession("active", "v", nil, nil))
+
+	// The first write already swept the idle session.
+	if s, _ := m.GetSession(ctx, "idle"); s != nil {
+		t.Error("expected idle session to be evicted on write")
+	}
+
+	stale := createTestSession("stale", "v", nil, nil)
+	_ = m.CreateSession(ctx, stale)
+	stale.LastActivity = time.Now().Add(-2 * time.Hour)
+	_ = m.UpdateSession(ctx, stale)
+
+	n, err := m.EvictIdle(ctx)
+	if err != nil {
+		t.Fatalf("EvictIdle: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 eviction, got %d", n)
+	}
+	if s, _ := m.GetSession(ctx, "active"); s == nil {
+		t.Error("active session must be kept")
+	}
+}
+
+func TestMemoryStorageEvictIdleWithoutTTL(t *testing.T) {
+	m := NewMemoryStorage()
+	n, err := m.EvictIdle(context.Background())
+	if n != 0 || err != nil {
+		t.Errorf("expected no-op, got %d, %v", n, err)
+	}
+}
+
+func TestPersistentMemoryStorageLimitsReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pocketping.log")
+
+	m, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0),
+		WithMemoryStorageOptions(WithMaxMessagesPerSession(1)))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = m.CreateSession(ctx, createTestSession("sess-1", "v", nil, nil))
+	_ = m.SaveMessage(ctx, createTestMessage("m1", "sess-1", "first"))
+	_ = m.SaveMessage(ctx, createTestMessage("m2", "sess-1", "second"))
+	// No Close: the trim must be replayed from the log.
+
+	reopened, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	msgs, _ := reopened.GetMessages(ctx, "sess-1", "", 10)
+	if len(msgs) != 1 || msgs[0].ID != "m2" {
+		t.Errorf("expected [m2] after replay, got %+v", msgs)
+	}
+}
@@ -0,0 +1,438 @@
+package pocketping
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCompactionInterval is how often a persistent MemoryStorage rewrites
+// its log down to the current state.
+const DefaultCompactionInterval = 10 * time.Minute
+
+// Log operations recorded by a persistent MemoryStorage.
+const (
+	memoryOpCreateSession  = "create_session"
+	memoryOpUpdateSession  = "update_session"
+	memoryOpDeleteSessions = "delete_sessions"
+	memoryOpSaveMessage    = "save_message"
+	memoryOpUpdateMessage  = "update_message"
+	memoryOpBridgeIDs      = "bridge_ids"
+	memoryOpPutAttachment  = "put_attachment"
+	memoryOpTrimMessages   = "trim_messages"
+	memoryOpLastNotified   = "last_notified"
+	memoryOpOperatorToken  = "operator_token"
+	memoryOpTouchToken     = "touch_operator_token"
+	memoryOpSaveNote       = "save_note"
+	memoryOpSaveWatch      = "save_watch"
+	memoryOpDeleteWatch    = "delete_watch"
+)
+
+// memoryLogEntry is one line of the append-only log.
+type memoryLogEntry struct {
+	Op         string            `json:"op"`
+	Session    *Session          `json:"session,omitempty"`
+	SessionID  string            `json:"sessionId,omitempty"`
+	IDs        []string          `json:"ids,omitempty"`
+	Message    *Message          `json:"message,omitempty"`
+	MessageID  string            `json:"messageId,omitempty"`
+	BridgeIDs  *BridgeMessageIds `json:"bridgeIds,omitempty"`
+	Attachment *Attachment       `json:"attachment,omitempty"`
+	Bridge     string            `json:"bridge,omitempty"`
+	// OperatorToken is set for operator_token entries.
+	OperatorToken *OperatorToken `json:"operatorToken,omitempty"`
+	// UsedAt is set for touch_operator_token entries.
+	UsedAt *time.Time `json:"usedAt,omitempty"`
+	// Note is set for save_note entries.
+	Note *Note `json:"note,omitempty"`
+	// Watch is set for save_watch and delete_watch entries.
+	Watch *Watch `json:"watch,omitempty"`
+}
+
+// memoryLog is the append-only log backing a persistent MemoryStorage.
+// Writes happen under MemoryStorage.mu.
+type memoryLog struct {
+	path       string
+	file       *os.File
+	w          *bufio.Writer
+	syncWrites bool
+	closed     bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// PersistenceOption configures NewPersistentMemoryStorage.
+type PersistenceOption func(*persistenceConfig)
+
+type persistenceConfig struct {
+	compactionInterval time.Duration
+	syncWrites         bool
+	storageOptions     []MemoryStorageOption
+}
+
+// WithCompactionInterval sets how often the log is compacted.
+// Zero or negative disables periodic compaction (Compact can still be called).
+func WithCompactionInterval(interval time.Duration) PersistenceOption {
+	return func(c *persistenceConfig) {
+		c.compactionInterval = interval
+	}
+}
+
+// WithSyncWrites fsyncs the log after every write. Safer against power loss,
+// but much slower; by default writes are flushed to the OS only.
+func WithSyncWrites(enabled bool) PersistenceOption {
+	return func(c *persistenceConfig) {
+		c.syncWrites = enabled
+	}
+}
+
+// WithMemoryStorageOptions applies MemoryStorage options (limits, eviction
+// callback) to the persistent store.
+func WithMemoryStorageOptions(opts ...MemoryStorageOption) PersistenceOption {
+	return func(c *persistenceConfig) {
+		c.storageOptions = append(c.storageOptions, opts...)
+	}
+}
+
+// NewPersistentMemoryStorage creates a MemoryStorage whose writes are
+// recorded in an append-only JSON log at path. Existing state is replayed
+// from the log at startup, and the log is periodically compacted to the
+// current state. Call Close to stop compaction and release the file.
+//
+// This gives small deployments durability without running a database. All
+// data still lives in memory.
+func NewPersistentMemoryStorage(path string, opts ...PersistenceOption) (*MemoryStorage, error) {
+	cfg := persistenceConfig{compactionInterval: DefaultCompactionInterval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m := NewMemoryStorage(cfg.storageOptions...)
+	if err := m.replayLog(path); err != nil {
+		return nil, err
+	}
+
+	m.wal = &memoryLog{
+		path:       path,
+		syncWrites: cfg.syncWrites,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	// Apply limits to the replayed state, then start from a compacted log
+	// so replay time stays proportional to state.
+	m.mu.Lock()
+	evicted, err := m.enforceSessionLimitsLocked(time.Now())
+	if err == nil {
+		err = m.compactLocked()
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	m.notifyEvicted(context.Background(), evicted)
+
+	if cfg.compactionInterval > 0 {
+		go m.compactLoop(cfg.compactionInterval)
+	} else {
+		close(m.wal.done)
+	}
+	return m, nil
+}
+
+// replayLog applies every entry of the log at path. A truncated final line
+// (crash mid-write) is ignored.
+func (m *MemoryStorage) replayLog(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open storage log: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var entry memoryLogEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				if readErr == io.EOF {
+					log.Printf("[MemoryStorage] Ignoring truncated last log entry (line %d)", lineNo)
+					return nil
+				}
+				return fmt.Errorf("storage log line %d: %w", lineNo, err)
+			}
+			if err := m.applyLogEntry(&entry); err != nil {
+				return fmt.Errorf("storage log line %d: %w", lineNo, err)
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("read storage log: %w", readErr)
+		}
+	}
+}
+
+func (m *MemoryStorage) applyLogEntry(entry *memoryLogEntry) error {
+	switch entry.Op {
+	case memoryOpCreateSession:
+		if entry.Session == nil {
+			return fmt.Errorf("%s without session", entry.Op)
+		}
+		m.applyCreateSession(entry.Session)
+	case memoryOpUpdateSession:
+		if entry.Session == nil {
+			return fmt.Errorf("%s without session", entry.Op)
+		}
+		m.applyUpdateSession(entry.Session)
+	case memoryOpDeleteSessions:
+		for _, id := range entry.IDs {
+			m.applyDeleteSession(id)
+		}
+	case memoryOpSaveMessage:
+		if entry.Message == nil {
+			return fmt.Errorf("%s without message", entry.Op)
+		}
+		m.applySaveMessage(entry.Message)
+	case memoryOpUpdateMessage:
+		if entry.Message == nil {
+			return fmt.Errorf("%s without message", entry.Op)
+		}
+		m.applyUpdateMessage(entry.Message)
+	case memoryOpBridgeIDs:
+		if entry.BridgeIDs == nil {
+			return fmt.Errorf("%s without bridge IDs", entry.Op)
+		}
+		m.applyBridgeIDs(entry.MessageID, *entry.BridgeIDs)
+	case memoryOpPutAttachment:
+		if entry.Attachment == nil {
+			return fmt.Errorf("%s without attachment", entry.Op)
+		}
+		m.applyPutAttachment(entry.Attachment)
+	case memoryOpTrimMessages:
+		m.applyTrimMessages(entry.SessionID, entry.IDs)
+	case memoryOpOperatorToken:
+		if entry.OperatorToken == nil {
+			return fmt.Errorf("%s without operator token", entry.Op)
+		}
+		m.applyOperatorToken(entry.OperatorToken)
+	case memoryOpTouchToken:
+		if len(entry.IDs) != 1 || entry.UsedAt == nil {
+			return fmt.Errorf("%s without token ID or time", entry.Op)
+		}
+		m.applyTouchOperatorToken(entry.IDs[0], *entry.UsedAt)
+	case memoryOpLastNotified:
+		m.applyLastNotified(entry.SessionID, entry.Bridge, entry.MessageID)
+	case memoryOpSaveNote:
+		if entry.Note == nil {
+			return fmt.Errorf("%s without note", entry.Op)
+		}
+		m.applySaveNote(entry.Note)
+	case memoryOpSaveWatch, memoryOpDeleteWatch:
+		if entry.Watch == nil {
+			return fmt.Errorf("%s without watch", entry.Op)
+		}
+		if entry.Op == memoryOpSaveWatch {
+			m.applySaveWatch(entry.Watch)
+		} else {
+			m.applyDeleteWatch(entry.Watch)
+		}
+	default:
+		return fmt.Errorf("unknown log op %q", entry.Op)
+	}
+	return nil
+}
+
+// logOp appends entry to the log. No-op for a purely in-memory store.
+// Callers hold m.mu.
+func (m *MemoryStorage) logOp(entry *memoryLogEntry) error {
+	if m.wal == nil || m.wal.w == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode log entry: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := m.wal.w.Write(data); err != nil {
+		return fmt.Errorf("write storage log: %w", err)
+	}
+	if err := m.wal.w.Flush(); err != nil {
+		return fmt.Errorf("write storage log: %w", err)
+	}
+	if m.wal.syncWrites {
+		if err := m.wal.file.Sync(); err != nil {
+			return fmt.Errorf("sync storage log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Compact rewrites the log so it only contains the current state.
+// No-op for a purely in-memory store.
+func (m *MemoryStorage) Compact() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.compactLocked()
+}
+
+// compactLocked writes the current state to a temporary file and atomically
+// replaces the log with it. Callers hold m.mu.
+func (m *MemoryStorage) compactLocked() error {
+	if m.wal == nil || m.wal.closed {
+		return nil
+	}
+
+	tmpPath := m.wal.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create compacted log: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	writeErr := func() error {
+		for _, session := range m.sessions {
+			if err := enc.Encode(&memoryLogEntry{Op: memoryOpCreateSession, Session: session}); err != nil {
+				return err
+			}
+		}
+		for _, msgs := range m.messages {
+			for i := range msgs {
+				if err := enc.Encode(&memoryLogEntry{Op: memoryOpSaveMessage, Message: &msgs[i]}); err != nil {
+					return err
+				}
+			}
+		}
+		for messageID, ids := range m.bridgeMessageIDs {
+			if err := enc.Encode(&memoryLogEntry{Op: memoryOpBridgeIDs, MessageID: messageID, BridgeIDs: ids}); err != nil {
+				return err
+			}
+		}
+		for _, att := range m.attachments {
+			if err := enc.Encode(&memoryLogEntry{Op: memoryOpPutAttachment, Attachment: att}); err != nil {
+				return err
+			}
+		}
+		for sessionID, cursors := range m.lastNotified {
+			for bridge, messageID := range cursors {
+				if err := enc.Encode(&memoryLogEntry{Op: memoryOpLastNotified, SessionID: sessionID, Bridge: bridge, MessageID: messageID}); err != nil {
+					return err
+				}
+			}
+		}
+		for _, token := range m.operatorTokens {
+			if err := enc.Encode(&memoryLogEntry{Op: memoryOpOperatorToken, OperatorToken: token}); err != nil {
+				return err
+			}
+		}
+		for _, notes := range m.notes {
+			for i := range notes {
+				if err := enc.Encode(&memoryLogEntry{Op: memoryOpSaveNote, Note: &notes[i]}); err != nil {
+					return err
+				}
+			}
+		}
+		for _, watches := range m.watches {
+			for _, watch := range watches {
+				if err := enc.Encode(&memoryLogEntry{Op: memoryOpSaveWatch, Watch: &watch}); err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return tmp.Sync()
+	}()
+	if closeErr := tmp.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write compacted log: %w", writeErr)
+	}
+
+	if m.wal.file != nil {
+		m.wal.file.Close()
+		m.wal.file, m.wal.w = nil, nil
+	}
+	if err := os.Rename(tmpPath, m.wal.path); err != nil {
+		return fmt.Errorf("replace storage log: %w", err)
+	}
+	syncDir(filepath.Dir(m.wal.path))
+
+	file, err := os.OpenFile(m.wal.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("reopen storage log: %w", err)
+	}
+	m.wal.file = file
+	m.wal.w = bufio.NewWriter(file)
+	return nil
+}
+
+// syncDir fsyncs a directory so a rename is durable. Best effort.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+func (m *MemoryStorage) compactLoop(interval time.Duration) {
+	defer close(m.wal.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.wal.stop:
+			return
+		case <-ticker.C:
+			if err := m.Compact(); err != nil {
+				log.Printf("[MemoryStorage] Compaction error: %v", err)
+			}
+		}
+	}
+}
+
+// Close stops periodic compaction, compacts one last time and closes the
+// log. Further writes are kept in memory only. No-op for a purely in-memory
+// store.
+func (m *MemoryStorage) Close() error {
+	if m.wal == nil {
+		return nil
+	}
+	m.wal.stopOnce.Do(func() { close(m.wal.stop) })
+	<-m.wal.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.wal.closed {
+		return nil
+	}
+	err := m.compactLocked()
+	if m.wal.file != nil {
+		if closeErr := m.wal.file.Close(); err == nil {
+			err = closeErr
+		}
+		m.wal.file, m.wal.w = nil, nil
+	}
+	m.wal.closed = true
+	return err
+}
@@ -0,0 +1,162 @@
+package pocketping
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPersistentMemoryStorageReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pocketping.log")
+
+	m, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = m.CreateSession(ctx, createTestSession("sess-1", "v1", nil, nil))
+	_ = m.CreateSession(ctx, createTestSession("sess-2", "v2", nil, nil))
+	_ = m.SaveMessage(ctx, &Message{ID: "m1", SessionID: "sess-1", Content: "first", Sender: SenderVisitor, Timestamp: time.Now()})
+	_ = m.SaveMessage(ctx, &Message{ID: "m2", SessionID: "sess-1", Content: "second", Sender: SenderOperator, Timestamp: time.Now()})
+	_ = m.UpdateMessage(ctx, &Message{ID: "m1", SessionID: "sess-1", Content: "first (edited)", Sender: SenderVisitor, Timestamp: time.Now()})
+	_ = m.SaveBridgeMessageIDs(ctx, "m1", BridgeMessageIds{TelegramMessageID: 7})
+	_ = m.SaveBridgeMessageIDs(ctx, "m1", BridgeMessageIds{SlackMessageTS: "1.2"})
+	_ = m.SaveAttachment(ctx, &Attachment{ID: "att-1", MessageID: "m2", Filename: "a.pdf"})
+	_ = m.DeleteSession(ctx, "sess-2")
+
+	session, _ := m.GetSession(ctx, "sess-1")
+	session.Identity = &UserIdentity{ID: "u1", Name: "Alice"}
+	_ = m.UpdateSession(ctx, session)
+
+	// Simulate a crash: no Close, plus a torn final write
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = f.WriteString(`{"op":"save_message","message":{"id":"m3"`)
+	f.Close()
+
+	reopened, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if s, _ := reopened.GetSession(ctx, "sess-2"); s != nil {
+		t.Error("expected deleted session to stay deleted")
+	}
+	s1, _ := reopened.GetSession(ctx, "sess-1")
+	if s1 == nil || s1.Identity == nil || s1.Identity.Name != "Alice" {
+		t.Fatalf("expected sess-1 with updated identity, got %+v", s1)
+	}
+	msgs, _ := reopened.GetMessages(ctx, "sess-1", "", 10)
+	if len(msgs) != 2 || msgs[0].Content != "first (edited)" || msgs[1].ID != "m2" {
+		t.Errorf("unexpected messages %+v", msgs)
+	}
+	if m3, _ := reopened.GetMessage(ctx, "m3"); m3 != nil {
+		t.Error("expected torn entry to be ignored")
+	}
+	ids, _ := reopened.GetBridgeMessageIDs(ctx, "m1")
+	if ids == nil || ids.TelegramMessageID != 7 || ids.SlackMessageTS != "1.2" {
+		t.Errorf("expected merged bridge IDs, got %+v", ids)
+	}
+	if id, _ := reopened.FindMessageByBridgeID(ctx, "sess-1", "slack-bot", "1.2"); id != "m1" {
+		t.Errorf("expected the bridge ID index to be rebuilt, got %q", id)
+	}
+	if att, _ := reopened.GetAttachment(ctx, "att-1"); att == nil || att.Filename != "a.pdf" {
+		t.Errorf("expected attachment, got %+v", att)
+	}
+}
+
+func TestPersistentMemoryStorageCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pocketping.log")
+
+	m, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0), WithSyncWrites(true))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	session := createTestSession("sess-1", "v1", nil, nil)
+	_ = m.CreateSession(ctx, session)
+	for i := 0; i < 20; i++ {
+		_ = m.UpdateSession(ctx, session)
+	}
+
+	before, _ := os.ReadFile(path)
+	if n := strings.Count(string(before), "\n"); n != 21 {
+		t.Fatalf("expected 21 log lines before compaction, got %d", n)
+	}
+
+	if err := m.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	after, _ := os.ReadFile(path)
+	if n := strings.Count(string(after), "\n"); n != 1 {
+		t.Errorf("expected 1 log line after compaction, got %d", n)
+	}
+
+	// Writes after compaction land in the new log
+	_ = m.SaveMessage(ctx, &Message{ID: "m1", SessionID: "sess-1", Content: "hi", Sender: SenderVisitor})
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	reopened, err := NewPersistentMemoryStorage(path, WithCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if msg, _ := reopened.GetMessage(ctx, "m1"); msg == nil {
+		t.Error("expected message written after compaction to survive")
+	}
+}
+
+func TestPersistentMemoryStoragePeriodicCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pocketping.log")
+
+	m, err := NewPersistentMemoryStorage(path, WithCompactionInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer m.Close()
+
+	session := createTestSession("sess-1", "v1", nil, nil)
+	_ = m.CreateSession(ctx, session)
+	_ = m.UpdateSession(ctx, session)
+	_ = m.UpdateSession(ctx, session)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.RLock()
+		data, _ := os.ReadFile(path)
+		m.mu.RUnlock()
+		if strings.Count(string(data), "\n") == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("expected periodic compaction to shrink the log")
+}
+
+func TestPersistentMemoryStorageCorruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pocketping.log")
+	_ = os.WriteFile(path, []byte("not json\n{\"op\":\"create_session\"}\n"), 0o600)
+
+	if _, err := NewPersistentMemoryStorage(path); err == nil {
+		t.Error("expected error for a corrupt log")
+	}
+}
+
+func TestMemoryStorageCloseWithoutPersistence(t *testing.T) {
+	m := NewMemoryStorage()
+	if err := m.Compact(); err != nil {
+		t.Errorf("Compact: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// OperatorMention is an operator, or a group of operators, to @-mention on
+// one bridge. Set one of UserID, GroupID and Username.
+type OperatorMention struct {
+	// Bridge is the bridge name ("telegram", "discord", "slack").
+	Bridge string
+	// UserID is the operator's platform user ID: a Slack member ID
+	// (U…), a Discord user ID, or a numeric Telegram user ID.
+	UserID string
+	// Name is the operator's display name, used where the platform shows
+	// a label (Telegram).
+	Name string
+	// GroupID is a Slack user group ID (S…) or a Discord role ID, to ping
+	// a whole team.
+	GroupID string
+	// Username is a Telegram @username, for operators whose user ID you
+	// don't have. Telegram has no groups: list each username.
+	Username string
+}
+
+// empty reports whether the mention names no one.
+func (m OperatorMention) empty() bool {
+	return m.UserID == "" && m.GroupID == "" && m.Username == ""
+}
+
+// MentionRouter picks the operators to @-mention in the bridge channels
+// when a session starts or the visitor writes, e.g. the session's assignee.
+// Mentions are posted as a notice after the notification, on bridges that
+// implement BridgeWithMentions and BridgeWithNotify.
+type MentionRouter func(ctx context.Context, session *Session) []OperatorMention
+
+// BridgeWithMentions is implemented by bridges that can @-mention an
+// operator.
+type BridgeWithMentions interface {
+	Bridge
+	// Mention returns the platform syntax that pings the operator, or ""
+	// for mentions the platform doesn't support.
+	Mention(mention OperatorMention) string
+}
+
+// Mention returns a Telegram text mention; it pings the user without them
+// needing a username. It needs the HTML parse mode. Usernames are
+// mentioned as "@username"; groups aren't supported.
+func (t *TelegramBridge) Mention(mention OperatorMention) string {
+	if mention.Username != "" {
+		username := "@" + strings.TrimPrefix(mention.Username, "@")
+		if t.ParseMode == "HTML" {
+			return html.EscapeString(username)
+		}
+		return username
+	}
+	if mention.UserID == "" {
+		return ""
+	}
+	name := mention.Name
+	if name == "" {
+		name = mention.UserID
+	}
+	if t.ParseMode != "HTML" {
+		return "@" + name
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%s">%s</a>`, html.EscapeString(mention.UserID), html.EscapeString(name))
+}
+
+// Mention returns a Discord user or role mention.
+func (d *DiscordWebhookBridge) Mention(mention OperatorMention) string {
+	return discordMentionSyntax(mention)
+}
+
+// Mention returns a Discord user or role mention.
+func (d *DiscordBotBridge) Mention(mention OperatorMention) string {
+	return discordMentionSyntax(mention)
+}
+
+// Mention returns a Slack user or user group mention.
+func (s *SlackWebhookBridge) Mention(mention OperatorMention) string {
+	return slackMentionSyntax(mention)
+}
+
+// Mention returns a Slack user or user group mention.
+func (s *SlackBotBridge) Mention(mention OperatorMention) string {
+	return slackMentionSyntax(mention)
+}
+
+func discordMentionSyntax(mention OperatorMention) string {
+	switch {
+	case mention.GroupID != "":
+		return "<@&" + mention.GroupID + ">"
+	case mention.UserID != "":
+		return "<@" + mention.UserID + ">"
+	}
+	return ""
+}
+
+func slackMentionSyntax(mention OperatorMention) string {
+	switch {
+	case mention.GroupID != "":
+		return "<!subteam^" + mention.GroupID + ">"
+	case mention.UserID != "":
+		return "<@" + mention.UserID + ">"
+	}
+	return ""
+}
+
+var (
+	_ BridgeWithMentions = (*TelegramBridge)(nil)
+	_ BridgeWithMentions = (*DiscordWebhookBridge)(nil)
+	_ BridgeWithMentions = (*DiscordBotBridge)(nil)
+	_ BridgeWithMentions = (*SlackWebhookBridge)(nil)
+	_ BridgeWithMentions = (*SlackBotBridge)(nil)
+)
+
+// operatorMentions returns a function giving the session's
+// Config.MentionRouter result followed by the Config.TagMentions of its
+// tags, computed once on first call.
+func (pp *PocketPing) operatorMentions(ctx context.Context, session *Session) func() []OperatorMention {
+	if pp.config.MentionRouter == nil && len(pp.config.TagMentions) == 0 {
+		return func() []OperatorMention { return nil }
+	}
+	return sync.OnceValue(func() []OperatorMention {
+		var mentions []OperatorMention
+		if pp.config.MentionRouter != nil {
+			mentions = pp.config.MentionRouter(ctx, session)
+		}
+		for _, tag := range session.Tags {
+			for configured, tagMentions := range pp.config.TagMentions {
+				if normalizeTag(configured) == tag {
+					mentions = append(mentions, tagMentions...)
+				}
+			}
+		}
+		return mentions
+	})
+}
+
+// notifyMentions pings the operators routed to b, if any.
+func (pp *PocketPing) notifyMentions(ctx context.Context, b Bridge, session *Session, mentions func() []OperatorMention) {
+	mentioner, ok := b.(BridgeWithMentions)
+	if !ok {
+		return
+	}
+	notifier, ok := b.(BridgeWithNotify)
+	if !ok {
+		return
+	}
+	var pings []string
+	seen := make(map[string]bool)
+	for _, mention := range mentions() {
+		if mention.Bridge != b.Name() || mention.empty() {
+			continue
+		}
+		// A router and a tag may name the same operator
+		if ping := mentioner.Mention(mention); ping != "" && !seen[ping] {
+			seen[ping] = true
+			pings = append(pings, ping)
+		}
+	}
+	if len(pings) == 0 {
+		return
+	}
+	if err := notifier.Notify(ctx, session, "🔔 "+strings.Join(pings, " ")); err != nil {
+		log.Printf("[PocketPing] Bridge %s mention failed: %v", b.Name(), err)
+	}
+}
+
+// slackMention matches Slack's <@U123>, <#C123|name> and <!here> tokens,
+// with their optional |label.
+var slackMention = regexp.MustCompile(`<([@#!])([^<>|]+)(?:\|([^<>]*))?>`)
+
+// ResolveSlackMentions replaces Slack mention tokens in text with readable
+// names, so operator messages reach the visitor as "@Alice" rather than
+// "<@U024BE7LH>". userName looks up a member's display name (nil or an
+// empty result keeps the ID). Links are left as they are.
+func ResolveSlackMentions(text string, userName func(userID string) string) string {
+	return slackMention.ReplaceAllStringFunc(text, func(token string) string {
+		parts := slackMention.FindStringSubmatch(token)
+		kind, id, label := parts[1], parts[2], parts[3]
+		switch kind {
+		case "@":
+			if label != "" {
+				return "@" + strings.TrimPrefix(label, "@")
+			}
+			if userName != nil {
+				if name := userName(id); name != "" {
+					return "@" + name
+				}
+			}
+			return "@" + id
+		case "#":
+			if label != "" {
+				return "#" + label
+			}
+			return "#" + id
+		default:
+			if label != "" {
+				return label
+			}
+			if command, _, _ := strings.Cut(id, "^"); command == "here" || command == "channel" || command == "everyone" {
+				return "@" + command
+			}
+			return token
+		}
+	})
+}
+
+// discordMention matches Discord's <@123> and <@!123> user mentions.
+var discordMention = regexp.MustCompile(`<@!?(\d+)>`)
+
+// ResolveDiscordMentions replaces Discord user mentions in content with
+// "@name", using names by user ID (from the message's mentions or the
+// interaction's resolved users). Unknown users are left as they are.
+func ResolveDiscordMentions(content string, names map[string]string) string {
+	return discordMention.ReplaceAllStringFunc(content, func(token string) string {
+		if name := names[discordMention.FindStringSubmatch(token)[1]]; name != "" {
+			return "@" + name
+		}
+		return token
+	})
+}
+
+// resolveSlackText resolves mentions with users.info, looking each member up
+// once.
+func (wh *WebhookHandler) resolveSlackText(text string) string {
+	if !strings.Contains(text, "<") {
+		return text
+	}
+	names := make(map[string]string)
+	return ResolveSlackMentions(text, func(userID string) string {
+		if wh.config.SlackBotToken == "" {
+			return ""
+		}
+		if name, ok := names[userID]; ok {
+			return name
+		}
+		name, err := wh.getSlackUserName(userID)
+		if err != nil {
+			log.Printf("[SlackWebhook] Failed to resolve mention %s: %v", userID, err)
+		}
+		names[userID] = name
+		return name
+	})
+}
+
+// discordUserNames maps Discord user IDs to display names.
+func discordUserNames(users []discordUser) map[string]string {
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.displayName()
+	}
+	return names
+}
@@ -0,0 +1,177 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveSlackMentions(t *testing.T) {
+	names := map[string]string{"U1": "Alice"}
+	tests := []struct{ in, want string }{
+		{"Thanks <@U1>!", "Thanks @Alice!"},
+		{"cc <@U2|bob>", "cc @bob"},
+		{"ask <@U3>", "ask @U3"},
+		{"see <#C1|billing> <!here>", "see #billing @here"},
+		{"<!subteam^S1|@support> <!date^1392734382^{date}|Feb 18>", "@support Feb 18"},
+		{"docs: <https://example.com|here>", "docs: <https://example.com|here>"},
+	}
+	for _, tt := range tests {
+		if got := ResolveSlackMentions(tt.in, func(id string) string { return names[id] }); got != tt.want {
+			t.Errorf("ResolveSlackMentions(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDiscordMentions(t *testing.T) {
+	got := ResolveDiscordMentions("hi <@1> and <@!2>, not <@3> or <@&4>", map[string]string{"1": "Alice", "2": "Bob"})
+	if want := "hi @Alice and @Bob, not <@3> or <@&4>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSlackWebhookResolvesMentions(t *testing.T) {
+	var lookups int
+	userSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		_, _ = w.Write([]byte(`{"ok":true,"user":{"real_name":"Alice Martin","name":"alice"}}`))
+	}))
+	defer userSrv.Close()
+
+	var gotContent string
+	wh := NewWebhookHandler(WebhookConfig{
+		SlackBotToken: "xoxb",
+		OnOperatorMessage: func(ctx context.Context, sid, c, on, sb string, a []Attachment, r *int) {
+			gotContent = c
+		},
+	})
+	wh.httpClient = &http.Client{Transport: &webhookTestTransport{slackURL: userSrv.URL}}
+
+	payload := `{"type":"event_callback","event":{"type":"message","thread_ts":"111.222","ts":"333.444","user":"U9","text":"<@U1> will follow up, <@U1> knows billing"}}`
+	postWebhook(wh.HandleSlackWebhook(), payload)
+	if gotContent != "@Alice Martin will follow up, @Alice Martin knows billing" {
+		t.Errorf("content = %q", gotContent)
+	}
+	if lookups != 2 { // the author, then U1 once
+		t.Errorf("users.info lookups = %d, want 2", lookups)
+	}
+}
+
+func TestDiscordResolvesMentions(t *testing.T) {
+	var gotContent string
+	g := newTestGateway(DiscordGatewayConfig{
+		OnOperatorMessage: func(ctx context.Context, sid, c, on string, a []Attachment, r *int) { gotContent = c },
+	})
+	g.handleMessage(messageCreatePayload{
+		ChannelID: "thread1",
+		Content:   "<@42> can help",
+		Author:    discordUser{ID: "u1", Username: "eve"},
+		Mentions:  []discordUser{{ID: "42", Username: "alice", GlobalName: "Alice"}},
+	})
+	if gotContent != "@Alice can help" {
+		t.Errorf("gateway content = %q", gotContent)
+	}
+
+	handler := NewWebhookHandler(WebhookConfig{
+		OnOperatorMessage: func(ctx context.Context, sid, c, on, sb string, a []Attachment, r *int) { gotContent = c },
+	})
+	payload := `{"type":2,"channel_id":"T9","data":{"name":"reply","options":[{"name":"message","value":"ask <@42>"}],"resolved":{"users":{"42":{"id":"42","username":"alice"}}}}}`
+	postWebhook(handler.HandleDiscordWebhook(), payload)
+	if gotContent != "ask @alice" {
+		t.Errorf("interaction content = %q", gotContent)
+	}
+}
+
+type mentionRecordingBridge struct {
+	notifyRecordingBridge
+}
+
+func (b *mentionRecordingBridge) Mention(mention OperatorMention) string {
+	return slackMentionSyntax(mention)
+}
+
+func TestMentionRouter(t *testing.T) {
+	ctx := context.Background()
+	slack := &mentionRecordingBridge{notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}}
+	plain := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "plain"}}
+	pp := New(Config{
+		Bridges: []Bridge{slack, plain},
+		MentionRouter: func(ctx context.Context, session *Session) []OperatorMention {
+			return []OperatorMention{{Bridge: "slack", UserID: "U1"}, {Bridge: "telegram", UserID: "7"}}
+		},
+	})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "Hello?")
+	pp.dispatcher.wait()
+
+	if strings.Join(slack.notices, "|") != "🔔 <@U1>|🔔 <@U1>" { // new session, then the message
+		t.Errorf("slack notices = %q", slack.notices)
+	}
+	if len(plain.notices) != 0 {
+		t.Errorf("bridge without mentions got %q", plain.notices)
+	}
+}
+
+func TestTelegramMention(t *testing.T) {
+	bridge := MustNewTelegramBridge("123:abc", "-100")
+	if got := bridge.Mention(OperatorMention{UserID: "42", Name: "Ann & Co"}); got != `<a href="tg://user?id=42">Ann &amp; Co</a>` {
+		t.Errorf("mention = %q", got)
+	}
+}
+
+func TestTagMentions(t *testing.T) {
+	ctx := context.Background()
+	slack := &mentionRecordingBridge{notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}}
+	pp := New(Config{
+		Bridges: []Bridge{slack},
+		MentionRouter: func(ctx context.Context, session *Session) []OperatorMention {
+			return []OperatorMention{{Bridge: "slack", UserID: "U1"}}
+		},
+		TagMentions: map[string][]OperatorMention{
+			"Billing": {{Bridge: "slack", GroupID: "S9"}, {Bridge: "slack", UserID: "U1"}},
+			"sales":   {{Bridge: "slack", GroupID: "S7"}},
+		},
+	})
+	session := newSession(ctx, t, pp)
+	pp.dispatcher.wait()
+	if _, err := pp.TagSession(ctx, session.ID, " billing ", "vip", "billing"); err != nil {
+		t.Fatalf("TagSession: %v", err)
+	}
+	sendVisitorMessage(t, pp, session.ID, "Refund please")
+	pp.dispatcher.wait()
+
+	// The router's U1 is only pinged once
+	if strings.Join(slack.notices, "|") != "🔔 <@U1>|🔔 <@U1> <!subteam^S9>" {
+		t.Errorf("slack notices = %q", slack.notices)
+	}
+
+	tagged, err := pp.UntagSession(ctx, session.ID, "BILLING")
+	if err != nil || strings.Join(tagged.Tags, ",") != "vip" || tagged.HasTag("billing") || !tagged.HasTag("VIP") {
+		t.Errorf("tags = %v, err = %v", tagged.Tags, err)
+	}
+	if _, err := pp.TagSession(ctx, "missing", "billing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestGroupMentionSyntax(t *testing.T) {
+	telegram := MustNewTelegramBridge("123:abc", "-100")
+	for _, tt := range []struct {
+		bridge  BridgeWithMentions
+		mention OperatorMention
+		want    string
+	}{
+		{&SlackBotBridge{}, OperatorMention{GroupID: "S1"}, "<!subteam^S1>"},
+		{&DiscordBotBridge{}, OperatorMention{GroupID: "55"}, "<@&55>"},
+		{&DiscordWebhookBridge{}, OperatorMention{UserID: "56"}, "<@56>"},
+		{telegram, OperatorMention{Username: "@billing_ann"}, "@billing_ann"},
+		{telegram, OperatorMention{GroupID: "S1"}, ""},
+	} {
+		if got := tt.bridge.Mention(tt.mention); got != tt.want {
+			t.Errorf("%T.Mention(%+v) = %q, want %q", tt.bridge, tt.mention, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package pocketping
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxBatchMessages is the most visitor messages batched into one bridge
+// notification; a full batch is sent right away.
+const MaxBatchMessages = 10
+
+// messageBatch is a session's visitor messages waiting to be mirrored.
+type messageBatch struct {
+	messages []*Message
+	timer    *time.Timer
+}
+
+// messageBatches holds the open batches by session ID.
+type messageBatches struct {
+	mu      sync.Mutex
+	pending map[string]*messageBatch
+}
+
+// batchVisitorMessage queues a visitor message for the bridges when
+// Config.MessageBatchWindow is set, reporting whether it did. The batch is
+// sent once no message has arrived for the window, or when it holds
+// MaxBatchMessages.
+func (pp *PocketPing) batchVisitorMessage(ctx context.Context, message *Message, session *Session) bool {
+	window := pp.config.MessageBatchWindow
+	if window <= 0 {
+		return false
+	}
+
+	pp.batches.mu.Lock()
+	if pp.batches.pending == nil {
+		pp.batches.pending = make(map[string]*messageBatch)
+	}
+	batch, ok := pp.batches.pending[session.ID]
+	if !ok {
+		flushCtx := context.WithoutCancel(ctx)
+		batch = &messageBatch{}
+		batch.timer = time.AfterFunc(window, func() { pp.flushBatch(flushCtx, session.ID) })
+		pp.batches.pending[session.ID] = batch
+	} else {
+		batch.timer.Reset(window)
+	}
+	batch.messages = append(batch.messages, message)
+	full := len(batch.messages) >= MaxBatchMessages
+	pp.batches.mu.Unlock()
+
+	if full {
+		pp.flushBatch(ctx, session.ID)
+	}
+	return true
+}
+
+// flushBatch mirrors a session's batched messages as one notification.
+func (pp *PocketPing) flushBatch(ctx context.Context, sessionID string) {
+	pp.batches.mu.Lock()
+	batch, ok := pp.batches.pending[sessionID]
+	delete(pp.batches.pending, sessionID)
+	pp.batches.mu.Unlock()
+	if !ok {
+		return
+	}
+	batch.timer.Stop()
+
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil || session == nil {
+		return
+	}
+	merged := mergeMessages(batch.messages)
+	mergedIDs := make([]string, 0, len(batch.messages)-1)
+	for _, message := range batch.messages[1:] {
+		mergedIDs = append(mergedIDs, message.ID)
+	}
+	pp.notifyBridgesMessage(ctx, merged, session, mergedIDs...)
+}
+
+// shareBridgeMessageIDs saves the bridge message IDs of a batch's merged
+// notification for the other messages merged into it, so edits and
+// deletions of any of them reach the bridges.
+func (pp *PocketPing) shareBridgeMessageIDs(ctx context.Context, messageID string, mergedIDs []string) {
+	store, ok := pp.storage.(StorageWithBridgeIDs)
+	if !ok || len(mergedIDs) == 0 {
+		return
+	}
+	ids, err := store.GetBridgeMessageIDs(ctx, messageID)
+	if err != nil || ids == nil {
+		return
+	}
+	for _, id := range mergedIDs {
+		if err := store.SaveBridgeMessageIDs(ctx, id, *ids); err != nil {
+			log.Printf("[PocketPing] Saving bridge IDs of batched message %s failed: %v", id, err)
+		}
+	}
+}
+
+// flushBatches mirrors every open batch.
+func (pp *PocketPing) flushBatches(ctx context.Context) {
+	pp.batches.mu.Lock()
+	sessionIDs := make([]string, 0, len(pp.batches.pending))
+	for sessionID := range pp.batches.pending {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	pp.batches.mu.Unlock()
+
+	for _, sessionID := range sessionIDs {
+		pp.flushBatch(ctx, sessionID)
+	}
+}
+
+// mergeMessages combines batched messages into the first one: contents are
+// joined by line breaks and attachments concatenated. The merged message
+// keeps the first message's ID, so bridges map replies and edits to it; the
+// others share its bridge message IDs.
+func mergeMessages(messages []*Message) *Message {
+	if len(messages) == 1 {
+		return messages[0]
+	}
+	merged := *messages[0]
+	contents := make([]string, 0, len(messages))
+	merged.Attachments = nil
+	for _, message := range messages {
+		if message.Content != "" {
+			contents = append(contents, message.Content)
+		}
+		merged.Attachments = append(merged.Attachments, message.Attachments...)
+	}
+	merged.Content = strings.Join(contents, "\n")
+	return &merged
+}
@@ -0,0 +1,97 @@
+package pocketping
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMessageBatching(t *testing.T) {
+	ctx := context.Background()
+	bridge := NewMockBridge("telegram")
+	pp := New(Config{Bridges: []Bridge{bridge}, MessageBatchWindow: time.Hour})
+	session := newSession(ctx, t, pp)
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, ws)
+
+	for _, text := range []string{"hi", "I have a question", "about billing"} {
+		sendVisitorMessage(t, pp, session.ID, text)
+	}
+	pp.dispatcher.wait()
+	if ws.count() != 3 {
+		t.Errorf("widget got %d events, want every message", ws.count())
+	}
+	bridge.mu.Lock()
+	pending := len(bridge.VisitorMsgCalls)
+	bridge.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("bridge notified %d times before the window closed", pending)
+	}
+
+	if err := pp.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	pp.dispatcher.wait()
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	if len(bridge.VisitorMsgCalls) != 1 || bridge.VisitorMsgCalls[0].Content != "hi\nI have a question\nabout billing" {
+		t.Fatalf("bridge messages = %+v", bridge.VisitorMsgCalls)
+	}
+}
+
+func TestMessageBatchWindowCloses(t *testing.T) {
+	ctx := context.Background()
+	bridge := NewMockBridge("telegram")
+	pp := New(Config{Bridges: []Bridge{bridge}, MessageBatchWindow: 20 * time.Millisecond})
+	session := newSession(ctx, t, pp)
+
+	for i := 0; i < MaxBatchMessages+1; i++ {
+		sendVisitorMessage(t, pp, session.ID, "spam")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		bridge.mu.Lock()
+		calls := len(bridge.VisitorMsgCalls)
+		bridge.mu.Unlock()
+		if calls == 2 { // a full batch, then the leftover one
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bridge notified %d times, want 2", calls)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// idSavingBridge saves a Telegram message ID for each visitor message, like
+// the Telegram bridge.
+type idSavingBridge struct {
+	*MockBridge
+	pp *PocketPing
+}
+
+func (b *idSavingBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	return b.pp.storage.(StorageWithBridgeIDs).SaveBridgeMessageIDs(ctx, message.ID, BridgeMessageIds{TelegramMessageID: 42})
+}
+
+func TestMessageBatchSharesBridgeIDs(t *testing.T) {
+	ctx := context.Background()
+	bridge := &idSavingBridge{MockBridge: NewMockBridge("telegram")}
+	pp := New(Config{Bridges: []Bridge{bridge}, MessageBatchWindow: time.Hour})
+	bridge.pp = pp
+	session := newSession(ctx, t, pp)
+
+	var ids []string
+	for _, text := range []string{"hi", "about billing"} {
+		ids = append(ids, sendVisitorMessage(t, pp, session.ID, text))
+	}
+	pp.flushBatches(ctx)
+	pp.dispatcher.wait()
+
+	store := pp.storage.(StorageWithBridgeIDs)
+	first, _ := store.GetBridgeMessageIDs(ctx, ids[0])
+	second, _ := store.GetBridgeMessageIDs(ctx, ids[1])
+	if first == nil || second == nil || second.TelegramMessageID != 42 {
+		t.Errorf("bridge IDs = %+v and %+v, want the batch's notification for both", first, second)
+	}
+}
@@ -0,0 +1,98 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+)
+
+// errNoMessageResult is returned when middleware returns neither a result
+// nor an error.
+var errNoMessageResult = errors.New("message middleware returned no result")
+
+// MessageOp says which call a MessageRequest comes from.
+type MessageOp string
+
+const (
+	// MessageOpSend is HandleMessage (and the calls built on it:
+	// HandleVisitorMessage, SendOperatorMessage...).
+	MessageOpSend MessageOp = "send"
+	// MessageOpEdit is HandleEditMessage.
+	MessageOpEdit MessageOp = "edit"
+)
+
+// MessageRequest is a message going through the middleware. Exactly one of
+// Send and Edit is set, per Op; middleware may change them before calling
+// next.
+type MessageRequest struct {
+	Op   MessageOp
+	Send *SendMessageRequest
+	Edit *EditMessageRequest
+}
+
+// SessionID returns the session the message is for.
+func (r *MessageRequest) SessionID() string {
+	if r.Edit != nil {
+		return r.Edit.SessionID
+	}
+	return r.Send.SessionID
+}
+
+// Sender returns who sends the message. Edits are always the visitor's.
+func (r *MessageRequest) Sender() Sender {
+	if r.Edit != nil {
+		return SenderVisitor
+	}
+	return r.Send.Sender
+}
+
+// Content returns the message content.
+func (r *MessageRequest) Content() string {
+	if r.Edit != nil {
+		return r.Edit.Content
+	}
+	return r.Send.Content
+}
+
+// SetContent replaces the message content, e.g. to redact it.
+func (r *MessageRequest) SetContent(content string) {
+	if r.Edit != nil {
+		r.Edit.Content = content
+		return
+	}
+	r.Send.Content = content
+}
+
+// MessageResult is the outcome of a MessageRequest: Send or Edit, per Op.
+type MessageResult struct {
+	Send *SendMessageResponse
+	Edit *EditMessageResponse
+}
+
+// MessageHandlerFunc handles a message, as the rest of the chain.
+type MessageHandlerFunc func(ctx context.Context, req *MessageRequest) (*MessageResult, error)
+
+// MessageMiddleware wraps message handling. It may change the request,
+// return an error instead of calling next, or look at the result.
+type MessageMiddleware func(next MessageHandlerFunc) MessageHandlerFunc
+
+// Use appends middleware to the chain HandleMessage and HandleEditMessage
+// run through. The first middleware added is the outermost. Middleware sees
+// every message sent through HandleMessage, including the operator messages
+// the SDK sends itself, so check req.Sender() where that matters.
+func (pp *PocketPing) Use(middleware ...MessageMiddleware) {
+	pp.middlewareMu.Lock()
+	defer pp.middlewareMu.Unlock()
+	pp.middleware = append(pp.middleware, middleware...)
+}
+
+// runMessage runs req through the middleware chain, ending with handle.
+func (pp *PocketPing) runMessage(ctx context.Context, req *MessageRequest, handle MessageHandlerFunc) (*MessageResult, error) {
+	pp.middlewareMu.RLock()
+	chain := pp.middleware
+	pp.middlewareMu.RUnlock()
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		handle = chain[i](handle)
+	}
+	return handle(ctx, req)
+}
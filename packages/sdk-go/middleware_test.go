@@ -0,0 +1,95 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrderAndRedaction(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	var order []string
+	trace := func(name string) MessageMiddleware {
+		return func(next MessageHandlerFunc) MessageHandlerFunc {
+			return func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+				order = append(order, name+":"+string(req.Op))
+				return next(ctx, req)
+			}
+		}
+	}
+	redact := func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+			req.SetContent(strings.ReplaceAll(req.Content(), "4242", "****"))
+			return next(ctx, req)
+		}
+	}
+	pp.Use(trace("outer"), redact)
+	pp.Use(trace("inner"))
+
+	messageID := sendVisitorMessage(t, pp, sessionID, "card 4242")
+	msg, _ := pp.GetStorage().GetMessage(ctx, messageID)
+	if msg == nil || msg.Content != "card ****" {
+		t.Fatalf("message = %+v", msg)
+	}
+
+	resp, err := pp.HandleEditMessage(ctx, EditMessageRequest{SessionID: sessionID, MessageID: messageID, Content: "card 4242 again"})
+	if err != nil {
+		t.Fatalf("HandleEditMessage: %v", err)
+	}
+	if resp.Message.Content != "card **** again" {
+		t.Errorf("edited content = %q", resp.Message.Content)
+	}
+
+	want := []string{"outer:send", "inner:send", "outer:edit", "inner:edit"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("order = %v", order)
+	}
+}
+
+func TestMiddlewareRejects(t *testing.T) {
+	ctx := context.Background()
+	bridge := newRecordingBridge("slack")
+	pp := New(Config{Bridges: []Bridge{bridge}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	errBlocked := errors.New("blocked")
+	pp.Use(func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+			if req.Sender() == SenderVisitor && strings.Contains(req.Content(), "spam") {
+				return nil, errBlocked
+			}
+			return next(ctx, req)
+		}
+	})
+
+	_, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "buy spam", Sender: SenderVisitor})
+	if !errors.Is(err, errBlocked) {
+		t.Fatalf("err = %v", err)
+	}
+	// Operators aren't filtered by this middleware
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "no spam here", Sender: SenderOperator}); err != nil {
+		t.Fatalf("operator message: %v", err)
+	}
+	pp.dispatcher.wait()
+	if len(bridge.messages) != 0 {
+		t.Errorf("bridge got %d messages", len(bridge.messages))
+	}
+}
+
+func TestMiddlewareWithoutResult(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	pp.Use(func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+			return nil, nil
+		}
+	})
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "Hi", Sender: SenderVisitor}); err == nil {
+		t.Error("expected an error")
+	}
+}
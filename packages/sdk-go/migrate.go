@@ -0,0 +1,163 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrMigrationMismatch is returned by Migrate when verification finds
+// differences between source and destination. The report lists them.
+var ErrMigrationMismatch = errors.New("migration verification found mismatches")
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// OnProgress is called after each session (with its messages) is copied.
+	OnProgress func(MigrateProgress)
+
+	// SkipVerify disables the post-copy consistency check.
+	SkipVerify bool
+}
+
+// MigrateProgress reports how far a migration has got.
+type MigrateProgress struct {
+	SnapshotStats
+	// TotalSessions is the number of sessions in the source.
+	TotalSessions int `json:"totalSessions"`
+	// SessionID is the session that was just copied.
+	SessionID string `json:"sessionId"`
+}
+
+// MigrateMismatch describes one difference found during verification.
+type MigrateMismatch struct {
+	SessionID string `json:"sessionId"`
+	Reason    string `json:"reason"`
+}
+
+// MigrateReport is the result of Migrate.
+type MigrateReport struct {
+	// Copied counts the records written to the destination.
+	Copied SnapshotStats `json:"copied"`
+	// Verified is true when the consistency check ran.
+	Verified bool `json:"verified"`
+	// Mismatches lists differences found by the consistency check.
+	Mismatches []MigrateMismatch `json:"mismatches,omitempty"`
+}
+
+// Migrate copies all sessions, messages, bridge message IDs and attachment
+// metadata from src to dst, then verifies that every session exists in dst
+// with the same message count and last message.
+//
+// src is only read from, so it can be a read-only instance still serving
+// traffic from a snapshot of its data. Writes to dst are idempotent: an
+// interrupted migration can simply be re-run. src must implement
+// StorageWithListSessions.
+func Migrate(ctx context.Context, src, dst Storage, opts MigrateOptions) (*MigrateReport, error) {
+	lister, ok := src.(StorageWithListSessions)
+	if !ok {
+		return nil, ErrSnapshotUnsupported
+	}
+	sessions, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	total := len(sessions)
+
+	report := &MigrateReport{}
+	currentSession := ""
+	reportProgress := func() {
+		if opts.OnProgress != nil && currentSession != "" {
+			opts.OnProgress(MigrateProgress{
+				SnapshotStats: report.Copied,
+				TotalSessions: total,
+				SessionID:     currentSession,
+			})
+		}
+	}
+
+	_, err = walkStorage(ctx, src, func(rec *SnapshotRecord) error {
+		if rec.Type == SnapshotRecordSession {
+			// A new session starts: the previous one is complete.
+			reportProgress()
+			currentSession = rec.Session.ID
+
+			// Don't share pointers between the two storages.
+			session := *rec.Session
+			rec.Session = &session
+		}
+		return restoreRecord(ctx, dst, rec, &report.Copied)
+	})
+	if err != nil {
+		return report, err
+	}
+	reportProgress()
+
+	if opts.SkipVerify {
+		return report, nil
+	}
+
+	report.Mismatches, err = verifyMigration(ctx, src, dst, sessions)
+	if err != nil {
+		return report, err
+	}
+	report.Verified = true
+	if len(report.Mismatches) > 0 {
+		return report, ErrMigrationMismatch
+	}
+	return report, nil
+}
+
+// verifyMigration compares each source session with its copy in dst.
+func verifyMigration(ctx context.Context, src, dst Storage, sessions []*Session) ([]MigrateMismatch, error) {
+	var mismatches []MigrateMismatch
+	for _, session := range sessions {
+		copied, err := dst.GetSession(ctx, session.ID)
+		if err != nil {
+			return nil, fmt.Errorf("verify session %s: %w", session.ID, err)
+		}
+		if copied == nil {
+			mismatches = append(mismatches, MigrateMismatch{SessionID: session.ID, Reason: "session missing in destination"})
+			continue
+		}
+
+		srcCount, srcLast, err := messageSummary(ctx, src, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		dstCount, dstLast, err := messageSummary(ctx, dst, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		if srcCount != dstCount {
+			mismatches = append(mismatches, MigrateMismatch{
+				SessionID: session.ID,
+				Reason:    fmt.Sprintf("message count differs: source %d, destination %d", srcCount, dstCount),
+			})
+		} else if srcLast != dstLast {
+			mismatches = append(mismatches, MigrateMismatch{
+				SessionID: session.ID,
+				Reason:    fmt.Sprintf("last message differs: source %s, destination %s", srcLast, dstLast),
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// messageSummary returns the message count and last message ID of a session.
+func messageSummary(ctx context.Context, storage Storage, sessionID string) (int, string, error) {
+	count, last, after := 0, "", ""
+	for {
+		page, err := storage.GetMessages(ctx, sessionID, after, snapshotPageSize)
+		if err != nil {
+			return 0, "", fmt.Errorf("verify messages for %s: %w", sessionID, err)
+		}
+		count += len(page)
+		if len(page) > 0 {
+			last = page[len(page)-1].ID
+		}
+		if len(page) < snapshotPageSize {
+			return count, last, nil
+		}
+		after = last
+	}
+}
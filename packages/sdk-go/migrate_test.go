@@ -0,0 +1,81 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryStorage()
+	seedSnapshotStorage(t, src)
+	dst := NewMemoryStorage()
+
+	var progress []MigrateProgress
+	report, err := Migrate(ctx, src, dst, MigrateOptions{
+		OnProgress: func(p MigrateProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	want := SnapshotStats{Sessions: 2, Messages: snapshotPageSize + 4, BridgeIDs: 1, Attachments: 1}
+	if report.Copied != want {
+		t.Errorf("expected copied %+v, got %+v", want, report.Copied)
+	}
+	if !report.Verified || len(report.Mismatches) != 0 {
+		t.Errorf("expected clean verification, got %+v", report)
+	}
+
+	if len(progress) != 2 {
+		t.Fatalf("expected 2 progress reports, got %d", len(progress))
+	}
+	if progress[0].TotalSessions != 2 || progress[0].Sessions != 1 || progress[1].Sessions != 2 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	if progress[1].Messages != want.Messages {
+		t.Errorf("expected final progress to include all messages, got %d", progress[1].Messages)
+	}
+
+	// Sessions are copied, not shared
+	copied, _ := dst.GetSession(ctx, "sess-1")
+	original, _ := src.GetSession(ctx, "sess-1")
+	if copied == original {
+		t.Error("expected destination to hold its own session copy")
+	}
+}
+
+// lossyStorage drops every message, to exercise verification.
+type lossyStorage struct {
+	*MemoryStorage
+}
+
+func (l lossyStorage) SaveMessage(ctx context.Context, message *Message) error { return nil }
+
+func TestMigrateReportsMismatches(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryStorage()
+	_ = src.CreateSession(ctx, createTestSession("sess-1", "v1", nil, nil))
+	_ = src.SaveMessage(ctx, &Message{ID: "m1", SessionID: "sess-1", Content: "hi", Sender: SenderVisitor, Timestamp: time.Now()})
+
+	report, err := Migrate(ctx, src, lossyStorage{NewMemoryStorage()}, MigrateOptions{})
+	if !errors.Is(err, ErrMigrationMismatch) {
+		t.Fatalf("expected ErrMigrationMismatch, got %v", err)
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].SessionID != "sess-1" {
+		t.Errorf("unexpected mismatches %+v", report.Mismatches)
+	}
+
+	report, err = Migrate(ctx, src, lossyStorage{NewMemoryStorage()}, MigrateOptions{SkipVerify: true})
+	if err != nil || report.Verified {
+		t.Errorf("expected unverified success with SkipVerify, got %+v, %v", report, err)
+	}
+}
+
+func TestMigrateRequiresListSessions(t *testing.T) {
+	if _, err := Migrate(context.Background(), statsLessStorage{}, NewMemoryStorage(), MigrateOptions{}); !errors.Is(err, ErrSnapshotUnsupported) {
+		t.Errorf("expected ErrSnapshotUnsupported, got %v", err)
+	}
+}
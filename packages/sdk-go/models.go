@@ -2,27 +2,28 @@
 package pocketping
 
 import (
-	"encoding/json"
 	"time"
+
+	"github.com/Ruwad-io/pocketping/sdk-go/events"
 )
 
 // Sender represents who sent a message.
-type Sender string
+type Sender = events.Sender
 
 const (
-	SenderVisitor  Sender = "visitor"
-	SenderOperator Sender = "operator"
-	SenderAI       Sender = "ai"
+	SenderVisitor  = events.SenderVisitor
+	SenderOperator = events.SenderOperator
+	SenderAI       = events.SenderAI
 )
 
 // MessageStatus represents the delivery status of a message.
-type MessageStatus string
+type MessageStatus = events.MessageStatus
 
 const (
-	MessageStatusSending   MessageStatus = "sending"
-	MessageStatusSent      MessageStatus = "sent"
-	MessageStatusDelivered MessageStatus = "delivered"
-	MessageStatusRead      MessageStatus = "read"
+	MessageStatusSending   = events.MessageStatusSending
+	MessageStatusSent      = events.MessageStatusSent
+	MessageStatusDelivered = events.MessageStatusDelivered
+	MessageStatusRead      = events.MessageStatusRead
 )
 
 // AttachmentStatus represents the upload status of an attachment.
@@ -129,93 +130,11 @@ const (
 )
 
 // UserIdentity represents user identity data from PocketPing.identify().
-type UserIdentity struct {
-	// ID is the required unique user identifier.
-	ID string `json:"id"`
-	// Email is the user's email address.
-	Email string `json:"email,omitempty"`
-	// Name is the user's display name.
-	Name string `json:"name,omitempty"`
-	// Extra holds any custom fields (plan, company, etc.).
-	Extra map[string]interface{} `json:"-"`
-}
-
-// MarshalJSON implements custom JSON marshaling for UserIdentity.
-func (u UserIdentity) MarshalJSON() ([]byte, error) {
-	base := map[string]interface{}{}
-
-	// Add base fields
-	if u.ID != "" {
-		base["id"] = u.ID
-	}
-	if u.Email != "" {
-		base["email"] = u.Email
-	}
-	if u.Name != "" {
-		base["name"] = u.Name
-	}
-
-	// Add extra fields
-	for k, v := range u.Extra {
-		if k != "id" && k != "email" && k != "name" {
-			base[k] = v
-		}
-	}
-
-	return json.Marshal(base)
-}
-
-// UnmarshalJSON implements custom JSON unmarshaling for UserIdentity.
-func (u *UserIdentity) UnmarshalJSON(data []byte) error {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-
-	if id, ok := raw["id"].(string); ok {
-		u.ID = id
-	}
-	if email, ok := raw["email"].(string); ok {
-		u.Email = email
-	}
-	if name, ok := raw["name"].(string); ok {
-		u.Name = name
-	}
-
-	// Store extra fields
-	u.Extra = make(map[string]interface{})
-	for k, v := range raw {
-		if k != "id" && k != "email" && k != "name" {
-			u.Extra[k] = v
-		}
-	}
-
-	return nil
-}
+// Custom fields are kept in Extra.
+type UserIdentity = events.UserIdentity
 
 // SessionMetadata contains metadata about a visitor's session.
-type SessionMetadata struct {
-	// Page info
-	URL       string `json:"url,omitempty"`
-	Referrer  string `json:"referrer,omitempty"`
-	PageTitle string `json:"pageTitle,omitempty"`
-
-	// Client info
-	UserAgent        string `json:"userAgent,omitempty"`
-	Timezone         string `json:"timezone,omitempty"`
-	Language         string `json:"language,omitempty"`
-	ScreenResolution string `json:"screenResolution,omitempty"`
-
-	// Geo info (populated server-side from IP)
-	IP      string `json:"ip,omitempty"`
-	Country string `json:"country,omitempty"`
-	City    string `json:"city,omitempty"`
-
-	// Device info (parsed from user agent)
-	DeviceType string `json:"deviceType,omitempty"` // "desktop", "mobile", "tablet"
-	Browser    string `json:"browser,omitempty"`
-	OS         string `json:"os,omitempty"`
-}
+type SessionMetadata = events.SessionMetadata
 
 // Session represents a chat session with a visitor.
 type Session struct {
@@ -227,12 +146,61 @@ type Session struct {
 	AIActive       bool             `json:"aiActive"`
 	Metadata       *SessionMetadata `json:"metadata,omitempty"`
 	Identity       *UserIdentity    `json:"identity,omitempty"`
+	// ProjectID is the ConnectRequest.ProjectID of the session's latest
+	// connect, passed to Config.FlagResolver.
+	ProjectID string `json:"projectId,omitempty"`
 	// UserPhone is the user's phone from pre-chat form (E.164 format: +33612345678).
 	UserPhone string `json:"userPhone,omitempty"`
 	// UserPhoneCountry is the user's phone country code (ISO: FR, US, etc.).
 	UserPhoneCountry string `json:"userPhoneCountry,omitempty"`
+	// DisposableEmail is set when the identity's email is from a disposable
+	// mailbox provider and Config.DisposableEmailPolicy is
+	// DisposableEmailFlag.
+	DisposableEmail bool `json:"disposableEmail,omitempty"`
 	// Csat holds the post-conversation CSAT rating state.
 	Csat *SessionCsat `json:"csat,omitempty"`
+	// WelcomeFlow is the session's progress through its welcome flow, if
+	// one targeted the page it started on.
+	WelcomeFlow *WelcomeFlowState `json:"welcomeFlow,omitempty"`
+	// Workflow is the session's progress through the workflow last started
+	// with StartWorkflow.
+	Workflow *WorkflowState `json:"workflow,omitempty"`
+	// HumanTakeover is set while an operator has taken the session over from
+	// the AI (see PocketPing.TakeOver); the AI fallback stays silent.
+	HumanTakeover bool `json:"humanTakeover,omitempty"`
+	// Region is the region the session is routed to (e.g. "eu"), set from
+	// Config.RegionResolver. Empty when unknown.
+	Region string `json:"region,omitempty"`
+	// Experiments holds the session's A/B experiment assignments, by
+	// experiment name.
+	Experiments map[string]*ExperimentAssignment `json:"experiments,omitempty"`
+	// Tickets are the tickets created from the session (see
+	// PocketPing.CreateTicket), oldest first.
+	Tickets []Ticket `json:"tickets,omitempty"`
+	// Callbacks are the callbacks the visitor booked (see
+	// PocketPing.HandleScheduleCallback), oldest first.
+	Callbacks []Booking `json:"callbacks,omitempty"`
+	// Risk is the session's latest at-risk assessment (see
+	// PocketPing.EvaluateRisk), when at-risk flagging is enabled.
+	Risk *SessionRisk `json:"risk,omitempty"`
+	// LeaveMessage is set when the session connected while nobody could
+	// answer (see Config.LeaveMessageMode).
+	LeaveMessage bool `json:"leaveMessage,omitempty"`
+	// ForkedFrom is the session of another visitor that had the session's
+	// identity first, under IdentityConflictFork.
+	ForkedFrom string `json:"forkedFrom,omitempty"`
+	// EndedAt is when the session was ended for a fresh start on a shared
+	// device (see StartFreshSession).
+	EndedAt *time.Time `json:"endedAt,omitempty"`
+	// Consent is set once the visitor consented to tracking
+	// (Config.RequireConsent), at ConsentAt.
+	Consent   bool       `json:"consent,omitempty"`
+	ConsentAt *time.Time `json:"consentAt,omitempty"`
+	// FirstResponseAt is when an operator first replied.
+	FirstResponseAt *time.Time `json:"firstResponseAt,omitempty"`
+	// Tags are the session's routing tags (see PocketPing.TagSession),
+	// lowercase.
+	Tags []string `json:"tags,omitempty"`
 }
 
 // SessionCsat is the CSAT rating state stored on a session.
@@ -258,8 +226,22 @@ type Message struct {
 	Timestamp time.Time              `json:"timestamp"`
 	ReplyTo   string                 `json:"replyTo,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	// ThreadID is the ID of the message that started the sub-conversation
+	// this message belongs to. Empty for the main conversation.
+	ThreadID string `json:"threadId,omitempty"`
 	// Attachments contains file attachments in this message.
 	Attachments []Attachment `json:"attachments,omitempty"`
+	// QuickReplies are suggestion chips shown under an operator or AI message.
+	QuickReplies []QuickReply `json:"quickReplies,omitempty"`
+	// Payment is the payment request an operator message carries.
+	Payment *PaymentRequest `json:"payment,omitempty"`
+	// Location is the location the message shares.
+	Location *Location `json:"location,omitempty"`
+	// ContactRequest is the phone number request an operator message
+	// carries.
+	ContactRequest *ContactRequest `json:"contactRequest,omitempty"`
+	// Form is the form an operator message carries.
+	Form *Form `json:"form,omitempty"`
 
 	// Read receipt fields
 	Status      MessageStatus `json:"status,omitempty"`
@@ -269,6 +251,84 @@ type Message struct {
 	// Edit/delete fields
 	EditedAt  *time.Time `json:"editedAt,omitempty"`
 	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// PlatformEditedAt and PlatformDeletedAt are the times the bridge
+	// platform reported for an edit or deletion made there; EditedAt and
+	// DeletedAt are normalized to local time (see ClockSkewDetector).
+	PlatformEditedAt  *time.Time `json:"platformEditedAt,omitempty"`
+	PlatformDeletedAt *time.Time `json:"platformDeletedAt,omitempty"`
+}
+
+// SessionCountFilter selects the sessions counted by CountSessions.
+// Zero fields don't filter.
+type SessionCountFilter struct {
+	// CreatedSince/CreatedUntil bound CreatedAt (inclusive / exclusive).
+	CreatedSince *time.Time `json:"createdSince,omitempty"`
+	CreatedUntil *time.Time `json:"createdUntil,omitempty"`
+	// ActiveSince only counts sessions with LastActivity at or after it.
+	ActiveSince *time.Time `json:"activeSince,omitempty"`
+	// VisitorID only counts the sessions of one visitor.
+	VisitorID string `json:"visitorId,omitempty"`
+}
+
+// Matches reports whether session passes the filter.
+func (f SessionCountFilter) Matches(session *Session) bool {
+	if f.CreatedSince != nil && session.CreatedAt.Before(*f.CreatedSince) {
+		return false
+	}
+	if f.CreatedUntil != nil && !session.CreatedAt.Before(*f.CreatedUntil) {
+		return false
+	}
+	if f.ActiveSince != nil && session.LastActivity.Before(*f.ActiveSince) {
+		return false
+	}
+	if f.VisitorID != "" && session.VisitorID != f.VisitorID {
+		return false
+	}
+	return true
+}
+
+// MessageCountFilter selects the messages counted by CountMessages.
+// Zero fields don't filter. Deleted messages are excluded unless
+// IncludeDeleted is set.
+type MessageCountFilter struct {
+	// SessionID only counts the messages of one session.
+	SessionID string `json:"sessionId,omitempty"`
+	// Sender only counts messages from one sender.
+	Sender Sender `json:"sender,omitempty"`
+	// Since/Until bound Timestamp (inclusive / exclusive).
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	// IncludeDeleted also counts soft-deleted messages.
+	IncludeDeleted bool `json:"includeDeleted,omitempty"`
+}
+
+// Matches reports whether message passes the filter.
+func (f MessageCountFilter) Matches(message *Message) bool {
+	if f.SessionID != "" && message.SessionID != f.SessionID {
+		return false
+	}
+	if f.Sender != "" && message.Sender != f.Sender {
+		return false
+	}
+	if f.Since != nil && message.Timestamp.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !message.Timestamp.Before(*f.Until) {
+		return false
+	}
+	if !f.IncludeDeleted && message.DeletedAt != nil {
+		return false
+	}
+	return true
+}
+
+// SearchResult is a session matched by SearchMessages.
+type SearchResult struct {
+	// Session is the matching session.
+	Session *Session `json:"session"`
+	// Message is the most recent matching message, or nil when the session
+	// matched on visitor identity only.
+	Message *Message `json:"message,omitempty"`
 }
 
 // TrackedElement represents a tracked element configuration for SaaS auto-tracking.
@@ -297,6 +357,24 @@ type ConnectRequest struct {
 	SessionID string           `json:"sessionId,omitempty"`
 	Metadata  *SessionMetadata `json:"metadata,omitempty"`
 	Identity  *UserIdentity    `json:"identity,omitempty"`
+	// ProjectID selects per-project widget settings (Config.ProjectWidgetSettings).
+	ProjectID string `json:"projectId,omitempty"`
+	// AffinityToken is the token from the previous ConnectResponse. It
+	// resumes the session on any node.
+	AffinityToken string `json:"affinityToken,omitempty"`
+	// LastEventSeq is the Seq of the last event the widget received; the
+	// events after it are returned in ConnectResponse.MissedEvents.
+	LastEventSeq int64 `json:"lastEventSeq,omitempty"`
+	// FreshSession ends the visitor's session and starts a new one under a
+	// new visitor ID instead of resuming (see StartFreshSession).
+	FreshSession bool `json:"freshSession,omitempty"`
+	// Consent is set once the visitor consented to tracking. Under
+	// Config.RequireConsent, metadata is minimal until then.
+	Consent bool `json:"consent,omitempty"`
+	// ClientIP is the visitor's IP address, set by the server from the
+	// HTTP request (e.g. with GetClientIP), never by the widget. It
+	// replaces Metadata.IP and is used by Config.RateLimit.
+	ClientIP string `json:"-"`
 }
 
 // ConnectResponse is the response after connecting.
@@ -307,6 +385,32 @@ type ConnectResponse struct {
 	WelcomeMessage  string           `json:"welcomeMessage,omitempty"`
 	Messages        []Message        `json:"messages"`
 	TrackedElements []TrackedElement `json:"trackedElements,omitempty"`
+	// ServerConfig is the widget appearance/behavior set on the server; it
+	// takes precedence over the widget's init options.
+	ServerConfig *WidgetSettings `json:"serverConfig,omitempty"`
+	// FeatureFlags are the capabilities enabled for this session.
+	FeatureFlags map[string]bool `json:"featureFlags,omitempty"`
+	// Experiments maps experiment names to the session's variant.
+	Experiments map[string]string `json:"experiments,omitempty"`
+	// WelcomeFlow holds the welcome steps to show, when a WelcomeFlow
+	// targets the session. WelcomeMessage is empty then.
+	WelcomeFlow *WelcomeFlowPrompt `json:"welcomeFlow,omitempty"`
+	// LeaveMessage is set when nobody can answer right now: the widget
+	// should present a "leave a message" form. WelcomeMessage holds the
+	// prompt then.
+	LeaveMessage bool `json:"leaveMessage,omitempty"`
+	// AffinityToken routes the session back to Node when the widget
+	// reconnects (see Config.AffinitySecret).
+	AffinityToken string `json:"affinityToken,omitempty"`
+	// Node is the node that served the connection.
+	Node string `json:"node,omitempty"`
+	// MissedEvents are the session's events after
+	// ConnectRequest.LastEventSeq, or after the last acknowledged one if
+	// earlier.
+	MissedEvents []WebSocketEvent `json:"missedEvents,omitempty"`
+	// Queue is the session's place in line while it waits for a first
+	// operator reply (see Config.WaitQueue).
+	Queue *QueueStatus `json:"queue,omitempty"`
 }
 
 // SendMessageRequest is the request to send a message.
@@ -315,16 +419,40 @@ type SendMessageRequest struct {
 	Content   string `json:"content"`
 	Sender    Sender `json:"sender"`
 	ReplyTo   string `json:"replyTo,omitempty"`
+	// ThreadID posts the message in a thread. Any message of the session can
+	// start one: its ID becomes the thread ID. The ID of a message already in
+	// a thread means that thread.
+	ThreadID string `json:"threadId,omitempty"`
 	// AttachmentIDs contains IDs of attachments to include with the message.
 	AttachmentIDs []string `json:"attachmentIds,omitempty"`
-	// Attachments contains inline attachments (for operator messages from bridges).
+	// Attachments contains inline attachments (for operator messages from
+	// bridges). They get new IDs. Ignored on visitor and AI messages.
 	Attachments []Attachment `json:"attachments,omitempty"`
+	// QuickReplies are suggestion chips for the visitor. Ignored on visitor
+	// messages.
+	QuickReplies []QuickReply `json:"quickReplies,omitempty"`
+	// Payment is a payment request to attach (see RequestPayment). Ignored
+	// on visitor messages.
+	Payment *PaymentRequest `json:"payment,omitempty"`
+	// Location is a shared location, e.g. from the widget's "share my
+	// location" action. Content may be empty.
+	Location *Location `json:"location,omitempty"`
+	// ContactRequest asks the visitor for a phone number (see
+	// RequestContact). Ignored on visitor messages.
+	ContactRequest *ContactRequest `json:"contactRequest,omitempty"`
+	// ClientIP is the visitor's IP address, set by the server from the
+	// HTTP request, for Config.RateLimit. Defaults to the session's
+	// Metadata.IP.
+	ClientIP string `json:"-"`
+	// Form is a form to attach (see SendForm). Ignored on visitor messages.
+	Form *Form `json:"form,omitempty"`
 }
 
 // SendMessageResponse is the response after sending a message.
 type SendMessageResponse struct {
 	MessageID string    `json:"messageId"`
 	Timestamp time.Time `json:"timestamp"`
+	ThreadID  string    `json:"threadId,omitempty"`
 }
 
 // GetMessagesRequest is the request to get messages.
@@ -332,6 +460,8 @@ type GetMessagesRequest struct {
 	SessionID string `json:"sessionId"`
 	After     string `json:"after,omitempty"`
 	Limit     int    `json:"limit,omitempty"`
+	// ThreadID only returns the thread's root message and its replies.
+	ThreadID string `json:"threadId,omitempty"`
 }
 
 // GetMessagesResponse is the response containing messages.
@@ -345,6 +475,8 @@ type TypingRequest struct {
 	SessionID string `json:"sessionId"`
 	Sender    Sender `json:"sender"`
 	IsTyping  bool   `json:"isTyping"`
+	// ThreadID is the thread being typed in, if any.
+	ThreadID string `json:"threadId,omitempty"`
 }
 
 // ReadRequest is the request to mark messages as read/delivered.
@@ -394,17 +526,32 @@ type BridgeMessageIds struct {
 	DiscordMessageID string `json:"discordMessageId,omitempty"`
 	// SlackMessageTS is the Slack message timestamp.
 	SlackMessageTS string `json:"slackMessageTs,omitempty"`
+	// TeamsMessageID is the Microsoft Graph chat message ID.
+	TeamsMessageID string `json:"teamsMessageId,omitempty"`
 }
 
 // IdentifyRequest is the request to identify a user.
 type IdentifyRequest struct {
 	SessionID string        `json:"sessionId"`
 	Identity  *UserIdentity `json:"identity"`
+	// Phone is an optional phone number, e.g. from a pre-chat form. It is
+	// normalized to E.164 and stored as Session.UserPhone.
+	Phone string `json:"phone,omitempty"`
+	// PhoneCountry is the ISO 3166-1 alpha-2 country code used for numbers
+	// typed without a calling code.
+	PhoneCountry string `json:"phoneCountry,omitempty"`
+	// UserHash is the hex HMAC-SHA256 of Identity.ID under
+	// Config.IdentitySecret, computed by your server (see IdentityHash).
+	// IdentityConflictMerge only merges verified identities.
+	UserHash string `json:"userHash,omitempty"`
 }
 
 // IdentifyResponse is the response after identifying a user.
 type IdentifyResponse struct {
 	OK bool `json:"ok"`
+	// SessionID is set when the session was merged into another one
+	// (IdentityConflictMerge): the widget continues in that session.
+	SessionID string `json:"sessionId,omitempty"`
 }
 
 // CsatRequest is a visitor-submitted CSAT rating (POST /csat).
@@ -442,10 +589,28 @@ type CustomEvent struct {
 	SessionID string `json:"sessionId,omitempty"`
 }
 
+// MessageChunk is the payload of a "message_chunk" WebSocket event: a piece
+// of an AI reply that is still being generated. Chunks of one reply share
+// MessageID; the widget appends Delta in Seq order. The "message" event with
+// the same ID commits the full reply. Aborted means generation failed and the
+// partial reply should be discarded.
+type MessageChunk struct {
+	MessageID string `json:"messageId"`
+	SessionID string `json:"sessionId"`
+	Sender    Sender `json:"sender"`
+	Seq       int    `json:"seq"`
+	Delta     string `json:"delta,omitempty"`
+	Aborted   bool   `json:"aborted,omitempty"`
+}
+
 // WebSocketEvent represents a WebSocket event structure.
 type WebSocketEvent struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
+	// Seq numbers the session's events when event replay is enabled (see
+	// Config.AffinitySecret). Widgets pass the last one they saw when
+	// reconnecting.
+	Seq int64 `json:"seq,omitempty"`
 }
 
 // VersionCheckResult is the result of checking widget version.
@@ -486,6 +651,16 @@ type WebhookSession struct {
 // CustomEventHandler is a function that handles custom events.
 type CustomEventHandler func(event CustomEvent, session *Session)
 
+// EventSubscription identifies a handler subscribed with SubscribeEvent,
+// for Unsubscribe.
+type EventSubscription uint64
+
+// eventHandlerEntry is a subscribed event handler.
+type eventHandlerEntry struct {
+	id      EventSubscription
+	handler CustomEventHandler
+}
+
 // MessageHandler is a function that handles messages.
 type MessageHandler func(message *Message, session *Session)
 
@@ -0,0 +1,278 @@
+package pocketping
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// errMsgPackTruncated is returned when MessagePack data ends mid-value.
+var errMsgPackTruncated = errors.New("msgpack: unexpected end of data")
+
+// errMsgPackTooDeep is returned when arrays and maps nest deeper than
+// maxMsgPackDepth.
+var errMsgPackTooDeep = errors.New("msgpack: nesting too deep")
+
+// maxMsgPackDepth bounds the nesting of decoded arrays and maps, so a small
+// frame can't exhaust the stack.
+const maxMsgPackDepth = 64
+
+// MarshalMsgPack encodes v as MessagePack. Values are first encoded as JSON,
+// so json tags and MarshalJSON methods apply and the result decodes to the
+// same structure as the JSON encoding.
+func MarshalMsgPack(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encodeMsgPack(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalMsgPack decodes MessagePack data into v the way json.Unmarshal
+// would decode the equivalent JSON.
+func UnmarshalMsgPack(data []byte, v interface{}) error {
+	value, rest, err := decodeMsgPack(data, 0)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("msgpack: %d bytes after value", len(rest))
+	}
+	converted, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(converted, v)
+}
+
+// encodeMsgPack writes a value decoded from JSON.
+func encodeMsgPack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			encodeMsgPackInt(buf, n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		encodeMsgPackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		encodeMsgPackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMsgPack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		encodeMsgPackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_ = encodeMsgPack(buf, key)
+			if err := encodeMsgPack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", value)
+	}
+	return nil
+}
+
+func encodeMsgPackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= 127, n >= -32 && n < 0:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		_ = binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		_ = binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// encodeMsgPackHeader writes a string, array or map header: the fix format
+// below fixLimit, then the 8 (if any), 16 or 32-bit length format.
+func encodeMsgPackHeader(buf *bytes.Buffer, n int, fix byte, fixLimit int, f8, f16, f32 byte) {
+	switch {
+	case n < fixLimit:
+		buf.WriteByte(fix | byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(f8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(f16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(f32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// decodeMsgPack decodes one value into JSON-compatible types and returns the
+// remaining data. Binary values decode as strings; extension types aren't
+// supported. depth is the number of enclosing arrays and maps.
+func decodeMsgPack(data []byte, depth int) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, errMsgPackTruncated
+	}
+	b, data := data[0], data[1:]
+	switch {
+	case b <= 0x7f:
+		return int64(b), data, nil
+	case b >= 0xe0:
+		return int64(int8(b)), data, nil
+	case b&0xe0 == 0xa0:
+		return decodeMsgPackString(data, int(b&0x1f))
+	case b&0xf0 == 0x90:
+		return decodeMsgPackArray(data, int(b&0x0f), depth)
+	case b&0xf0 == 0x80:
+		return decodeMsgPackMap(data, int(b&0x0f), depth)
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, data, nil
+	case 0xc2:
+		return false, data, nil
+	case 0xc3:
+		return true, data, nil
+	case 0xca:
+		bits, rest, err := msgPackUint(data, 4)
+		return float64(math.Float32frombits(uint32(bits))), rest, err
+	case 0xcb:
+		bits, rest, err := msgPackUint(data, 8)
+		return math.Float64frombits(bits), rest, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, rest, err := msgPackUint(data, 1<<(b-0xcc))
+		if n > math.MaxInt64 {
+			return float64(n), rest, err
+		}
+		return int64(n), rest, err
+	case 0xd0:
+		n, rest, err := msgPackUint(data, 1)
+		return int64(int8(n)), rest, err
+	case 0xd1:
+		n, rest, err := msgPackUint(data, 2)
+		return int64(int16(n)), rest, err
+	case 0xd2:
+		n, rest, err := msgPackUint(data, 4)
+		return int64(int32(n)), rest, err
+	case 0xd3:
+		n, rest, err := msgPackUint(data, 8)
+		return int64(n), rest, err
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		size := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xc4: 1, 0xc5: 2, 0xc6: 4}[b]
+		n, rest, err := msgPackUint(data, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgPackString(rest, int(n))
+	case 0xdc, 0xdd:
+		n, rest, err := msgPackUint(data, 2<<(b-0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgPackArray(rest, int(n), depth)
+	case 0xde, 0xdf:
+		n, rest, err := msgPackUint(data, 2<<(b-0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgPackMap(rest, int(n), depth)
+	}
+	return nil, nil, fmt.Errorf("msgpack: unsupported format 0x%02x", b)
+}
+
+func msgPackUint(data []byte, size int) (uint64, []byte, error) {
+	if len(data) < size {
+		return 0, nil, errMsgPackTruncated
+	}
+	var n uint64
+	for _, b := range data[:size] {
+		n = n<<8 | uint64(b)
+	}
+	return n, data[size:], nil
+}
+
+func decodeMsgPackString(data []byte, n int) (interface{}, []byte, error) {
+	if len(data) < n {
+		return nil, nil, errMsgPackTruncated
+	}
+	return string(data[:n]), data[n:], nil
+}
+
+func decodeMsgPackArray(data []byte, n, depth int) (interface{}, []byte, error) {
+	if depth >= maxMsgPackDepth {
+		return nil, nil, errMsgPackTooDeep
+	}
+	items := make([]interface{}, 0, min(n, len(data)))
+	for i := 0; i < n; i++ {
+		item, rest, err := decodeMsgPack(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+		data = rest
+	}
+	return items, data, nil
+}
+
+func decodeMsgPackMap(data []byte, n, depth int) (interface{}, []byte, error) {
+	if depth >= maxMsgPackDepth {
+		return nil, nil, errMsgPackTooDeep
+	}
+	fields := make(map[string]interface{}, min(n, len(data)))
+	for i := 0; i < n; i++ {
+		key, rest, err := decodeMsgPack(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("msgpack: map key of type %T", key)
+		}
+		if fields[name], data, err = decodeMsgPack(rest, depth+1); err != nil {
+			return nil, nil, err
+		}
+	}
+	return fields, data, nil
+}
@@ -0,0 +1,90 @@
+package pocketping
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalMsgPackEncoding(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{5, []byte{0x05}},
+		{-3, []byte{0xfd}},
+		{200, []byte{0xd1, 0x00, 0xc8}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"hi", []byte{0xa2, 'h', 'i'}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+	}
+	for _, tt := range tests {
+		got, err := MarshalMsgPack(tt.value)
+		if err != nil {
+			t.Fatalf("MarshalMsgPack(%v): %v", tt.value, err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("MarshalMsgPack(%v) = % x, want % x", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	sent := WebSocketEvent{
+		Type: "message",
+		Seq:  math.MaxInt32 + 1,
+		Data: &Message{
+			ID:        "m1",
+			SessionID: "s1",
+			Content:   strings.Repeat("é", 300),
+			Sender:    SenderOperator,
+			Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Metadata:  map[string]interface{}{"score": -1.25, "tags": []interface{}{"a", nil}},
+		},
+	}
+	data, err := MarshalMsgPack(sent)
+	if err != nil {
+		t.Fatalf("MarshalMsgPack: %v", err)
+	}
+
+	var got struct {
+		Type string  `json:"type"`
+		Seq  int64   `json:"seq"`
+		Data Message `json:"data"`
+	}
+	if err := UnmarshalMsgPack(data, &got); err != nil {
+		t.Fatalf("UnmarshalMsgPack: %v", err)
+	}
+	want := *sent.Data.(*Message)
+	if got.Type != "message" || got.Seq != sent.Seq || !reflect.DeepEqual(got.Data, want) {
+		t.Errorf("round trip = %+v, want %+v", got, sent)
+	}
+
+	if err := UnmarshalMsgPack(data[:len(data)-1], &got); !errors.Is(err, errMsgPackTruncated) {
+		t.Errorf("truncated: err = %v", err)
+	}
+}
+
+func TestUnmarshalMsgPackDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		data := bytes.Repeat([]byte{0x91}, depth)
+		return append(data, 0xc0)
+	}
+	var v interface{}
+	if err := UnmarshalMsgPack(nested(maxMsgPackDepth), &v); err != nil {
+		t.Errorf("depth %d: %v", maxMsgPackDepth, err)
+	}
+	if err := UnmarshalMsgPack(nested(100000), &v); !errors.Is(err, errMsgPackTooDeep) {
+		t.Errorf("deep arrays: err = %v, want errMsgPackTooDeep", err)
+	}
+	if err := UnmarshalMsgPack(append(bytes.Repeat([]byte{0x81, 0xa1, 'k'}, 100), 0xc0), &v); !errors.Is(err, errMsgPackTooDeep) {
+		t.Errorf("deep maps: err = %v, want errMsgPackTooDeep", err)
+	}
+}
@@ -0,0 +1,103 @@
+package pocketping
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultOfflineQueueMaxAge is how long queued events are kept when
+// Config.OfflineQueueMaxAge is zero.
+const DefaultOfflineQueueMaxAge = 2 * time.Minute
+
+// offlineEvent is a broadcast waiting for the session's next socket.
+type offlineEvent struct {
+	event    WebSocketEvent
+	queuedAt time.Time
+}
+
+// offlineQueues holds the events of sessions without sockets, by session ID.
+type offlineQueues struct {
+	mu        sync.Mutex
+	pending   map[string][]offlineEvent
+	lastSweep time.Time
+}
+
+// offlineMaxAge returns how long queued events are kept.
+func (pp *PocketPing) offlineMaxAge() time.Duration {
+	if pp.config.OfflineQueueMaxAge > 0 {
+		return pp.config.OfflineQueueMaxAge
+	}
+	return DefaultOfflineQueueMaxAge
+}
+
+// queueOffline keeps an event for a session with no sockets on this node,
+// when Config.OfflineQueueSize is set. The oldest events are dropped once
+// the queue is full.
+func (pp *PocketPing) queueOffline(sessionID string, event WebSocketEvent) {
+	if pp.config.OfflineQueueSize <= 0 || transientEvents[event.Type] {
+		return
+	}
+	q := &pp.offline
+	q.mu.Lock()
+
+	// A socket registered meanwhile gets the event right away. It is sent
+	// after unlocking, as writes can block.
+	pp.socketsMu.RLock()
+	connected := len(pp.sessionSockets[sessionID]) > 0
+	pp.socketsMu.RUnlock()
+	if connected {
+		q.mu.Unlock()
+		pp.broadcastLocal(sessionID, event)
+		return
+	}
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	maxAge := pp.offlineMaxAge()
+	if q.pending == nil {
+		q.pending = make(map[string][]offlineEvent)
+	}
+	if now.Sub(q.lastSweep) > maxAge {
+		for id, events := range q.pending {
+			if now.Sub(events[len(events)-1].queuedAt) > maxAge {
+				delete(q.pending, id)
+			}
+		}
+		q.lastSweep = now
+	}
+
+	events := append(q.pending[sessionID], offlineEvent{event: event, queuedAt: now})
+	if len(events) > pp.config.OfflineQueueSize {
+		events = append([]offlineEvent(nil), events[len(events)-pp.config.OfflineQueueSize:]...)
+	}
+	q.pending[sessionID] = events
+}
+
+// takeOffline removes a session's queued events and returns those younger
+// than the max age.
+func (pp *PocketPing) takeOffline(sessionID string) []WebSocketEvent {
+	q := &pp.offline
+	q.mu.Lock()
+	queued := q.pending[sessionID]
+	delete(q.pending, sessionID)
+	q.mu.Unlock()
+
+	events := make([]WebSocketEvent, 0, len(queued))
+	maxAge := pp.offlineMaxAge()
+	for _, queued := range queued {
+		if time.Since(queued.queuedAt) <= maxAge {
+			events = append(events, queued.event)
+		}
+	}
+	return events
+}
+
+// flushOffline sends a session's queued events to a newly registered socket.
+func (pp *PocketPing) flushOffline(sessionID string, conn, writer WebSocketConn) {
+	for _, event := range pp.takeOffline(sessionID) {
+		if err := writer.WriteJSON(event); err != nil {
+			pp.UnregisterWebSocket(sessionID, conn)
+			return
+		}
+	}
+}
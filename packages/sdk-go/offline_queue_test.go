@@ -0,0 +1,83 @@
+package pocketping
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	pp := New(Config{OfflineQueueSize: 2})
+	for _, kind := range []string{"first", "typing", "second", "third"} {
+		pp.BroadcastToSession("s1", WebSocketEvent{Type: kind})
+	}
+
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket("s1", ws)
+	if got := ws.types(); len(got) != 2 || got[0] != "second" || got[1] != "third" {
+		t.Errorf("flushed events = %v, want [second third]", got)
+	}
+
+	// Flushed once; later events go straight to the socket.
+	again := &mockWSConn{}
+	pp.RegisterWebSocket("s1", again)
+	pp.BroadcastToSession("s1", WebSocketEvent{Type: "live"})
+	if got := again.types(); len(got) != 1 || got[0] != "live" {
+		t.Errorf("second socket events = %v, want [live]", got)
+	}
+}
+
+func TestOfflineQueueMaxAge(t *testing.T) {
+	pp := New(Config{OfflineQueueSize: 10, OfflineQueueMaxAge: 10 * time.Millisecond})
+	pp.BroadcastToSession("s1", WebSocketEvent{Type: "stale"})
+	time.Sleep(30 * time.Millisecond)
+	pp.BroadcastToSession("s1", WebSocketEvent{Type: "fresh"})
+
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket("s1", ws)
+	if got := ws.types(); len(got) != 1 || got[0] != "fresh" {
+		t.Errorf("flushed events = %v, want [fresh]", got)
+	}
+}
+
+func TestOfflineQueueDisabledByDefault(t *testing.T) {
+	pp := New(Config{})
+	pp.BroadcastToSession("s1", WebSocketEvent{Type: "lost"})
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket("s1", ws)
+	if ws.count() != 0 {
+		t.Errorf("got %v without OfflineQueueSize", ws.types())
+	}
+}
+
+// stalledWSConn blocks writes until released.
+type stalledWSConn struct {
+	release chan struct{}
+}
+
+func (s *stalledWSConn) WriteJSON(v interface{}) error {
+	<-s.release
+	return nil
+}
+
+func (s *stalledWSConn) Close() error { return nil }
+
+func TestOfflineQueueDoesNotBlockOnSlowSocket(t *testing.T) {
+	pp := New(Config{OfflineQueueSize: 10})
+	stalled := &stalledWSConn{release: make(chan struct{})}
+	defer close(stalled.release)
+	pp.RegisterWebSocket("s1", stalled)
+
+	go pp.queueOffline("s1", WebSocketEvent{Type: "slow"})
+	time.Sleep(20 * time.Millisecond) // let it block on the write
+
+	done := make(chan struct{})
+	go func() {
+		pp.queueOffline("s2", WebSocketEvent{Type: "queued"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queueing for another session blocked behind a slow socket")
+	}
+}
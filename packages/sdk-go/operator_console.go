@@ -0,0 +1,213 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// OperatorConsoleSource is the source bridge of replies sent from an
+// operator console, so every bridge mirrors them.
+const OperatorConsoleSource = "console"
+
+var (
+	// ErrOperatorConsoleNotConfigured is returned by AuthenticateOperator
+	// when Config.OperatorAuthenticator is not set.
+	ErrOperatorConsoleNotConfigured = newError("not_configured", http.StatusNotFound, "operator console is not configured")
+	// ErrOperatorUnauthorized is returned by AuthenticateOperator for a
+	// missing or rejected token.
+	ErrOperatorUnauthorized = newError("unauthorized", http.StatusUnauthorized, "invalid operator token")
+)
+
+// OperatorAuthenticator checks an operator console token and returns the
+// operator's display name. Return ErrOperatorUnauthorized (or any error) to
+// reject the connection.
+type OperatorAuthenticator func(ctx context.Context, token string) (operatorName string, err error)
+
+// OperatorEvent is a session event as sent to operator consoles: the
+// visitor-side WebSocketEvent plus the session it belongs to.
+type OperatorEvent struct {
+	Type      string      `json:"type"`
+	SessionID string      `json:"sessionId"`
+	Data      interface{} `json:"data"`
+}
+
+// OperatorCommand is a frame sent by an operator console.
+//
+//	{"type": "reply", "sessionId": "...", "content": "Hi!"}
+//	{"type": "typing", "sessionId": "...", "isTyping": true}
+//	{"type": "read", "sessionId": "...", "messageIds": ["..."]}
+//	{"type": "messages", "sessionId": "...", "after": "...", "limit": 50}
+//	{"type": "undelivered", "sessionId": "..."}
+type OperatorCommand struct {
+	Type       string   `json:"type"`
+	SessionID  string   `json:"sessionId"`
+	Content    string   `json:"content,omitempty"`
+	ThreadID   string   `json:"threadId,omitempty"`
+	IsTyping   bool     `json:"isTyping,omitempty"`
+	MessageIDs []string `json:"messageIds,omitempty"`
+	After      string   `json:"after,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+}
+
+// OperatorConn is an authenticated operator console connection. It receives
+// the events of every session and can reply directly, so a team can work
+// without Telegram, Discord or Slack.
+type OperatorConn struct {
+	// Name is the operator's display name, from the authenticator.
+	Name string
+
+	pp   *PocketPing
+	conn WebSocketConn
+	// writeMu serializes writes: broadcasts and command answers come from
+	// different goroutines.
+	writeMu sync.Mutex
+}
+
+// AuthenticateOperator checks the token of an operator console's upgrade
+// request, sent as "Authorization: Bearer <token>", with
+// Config.OperatorAuthenticator, and returns the operator's name. Call it
+// before upgrading, so a rejected console gets a plain 401 and tokens stay
+// out of URLs and access logs.
+func (pp *PocketPing) AuthenticateOperator(r *http.Request) (string, error) {
+	if pp.config.OperatorAuthenticator == nil {
+		return "", ErrOperatorConsoleNotConfigured
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", ErrOperatorUnauthorized
+	}
+	name, err := pp.config.OperatorAuthenticator(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrOperatorUnauthorized) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %v", ErrOperatorUnauthorized, err)
+	}
+	return name, nil
+}
+
+// ConnectOperator registers the WebSocket of an operator console
+// authenticated with AuthenticateOperator. While at least one console is
+// connected the operator is shown as online. Call DisconnectOperator when
+// the socket closes.
+func (pp *PocketPing) ConnectOperator(operatorName string, conn WebSocketConn) *OperatorConn {
+	op := &OperatorConn{Name: operatorName, pp: pp, conn: pp.newSendQueue(conn)}
+	pp.socketsMu.Lock()
+	pp.operatorSockets[op] = struct{}{}
+	first := len(pp.operatorSockets) == 1
+	pp.socketsMu.Unlock()
+
+	if first {
+		pp.SetOperatorOnline(true)
+	}
+	return op
+}
+
+// DisconnectOperator unregisters an operator console. The operator goes
+// offline when the last console disconnects.
+func (pp *PocketPing) DisconnectOperator(op *OperatorConn) {
+	pp.socketsMu.Lock()
+	_, ok := pp.operatorSockets[op]
+	delete(pp.operatorSockets, op)
+	last := ok && len(pp.operatorSockets) == 0
+	pp.socketsMu.Unlock()
+	stopSendQueue(op.conn)
+
+	if last {
+		pp.SetOperatorOnline(false)
+	}
+}
+
+// notifyOperators sends an event meant for operator consoles only, on every
+// replica.
+func (pp *PocketPing) notifyOperators(sessionID string, event WebSocketEvent) {
+	pp.broadcastToOperators(sessionID, event)
+	pp.publishBroadcast(sessionID, event, true)
+}
+
+// broadcastToOperators sends a session event to this process's operator
+// consoles.
+func (pp *PocketPing) broadcastToOperators(sessionID string, event WebSocketEvent) {
+	pp.socketsMu.RLock()
+	if len(pp.operatorSockets) == 0 {
+		pp.socketsMu.RUnlock()
+		return
+	}
+	ops := make([]*OperatorConn, 0, len(pp.operatorSockets))
+	for op := range pp.operatorSockets {
+		ops = append(ops, op)
+	}
+	pp.socketsMu.RUnlock()
+
+	operatorEvent := OperatorEvent{Type: event.Type, SessionID: sessionID, Data: event.Data}
+	for _, op := range ops {
+		if err := op.writeJSON(operatorEvent); err != nil {
+			pp.DisconnectOperator(op)
+		}
+	}
+}
+
+// HandleCommand runs a frame received from the operator's console. Replies
+// are sent to the visitor and mirrored to the bridges; "messages" and
+// "undelivered" are answered with an event of the same type on this
+// connection only.
+func (op *OperatorConn) HandleCommand(ctx context.Context, raw []byte) error {
+	var command OperatorCommand
+	if err := json.Unmarshal(raw, &command); err != nil {
+		return fmt.Errorf("invalid operator command: %w", err)
+	}
+	if command.SessionID == "" {
+		return ErrSessionNotFound
+	}
+
+	switch command.Type {
+	case "reply":
+		_, err := op.pp.SendOperatorMessage(ctx, command.SessionID, command.Content, OperatorConsoleSource, op.Name, WithThread(command.ThreadID))
+		return err
+	case "typing":
+		return op.pp.HandleTyping(ctx, TypingRequest{
+			SessionID: command.SessionID,
+			Sender:    SenderOperator,
+			IsTyping:  command.IsTyping,
+			ThreadID:  command.ThreadID,
+		})
+	case "read":
+		_, err := op.pp.HandleRead(ctx, ReadRequest{
+			SessionID:  command.SessionID,
+			MessageIDs: command.MessageIDs,
+			Status:     MessageStatusRead,
+		})
+		return err
+	case "messages":
+		response, err := op.pp.HandleGetMessages(ctx, GetMessagesRequest{
+			SessionID: command.SessionID,
+			After:     command.After,
+			Limit:     command.Limit,
+			ThreadID:  command.ThreadID,
+		})
+		if err != nil {
+			return err
+		}
+		return op.writeJSON(OperatorEvent{Type: "messages", SessionID: command.SessionID, Data: response})
+	case "undelivered":
+		messages, err := op.pp.UndeliveredMessages(ctx, command.SessionID)
+		if err != nil {
+			return err
+		}
+		return op.writeJSON(OperatorEvent{Type: "undelivered", SessionID: command.SessionID, Data: messages})
+	default:
+		return fmt.Errorf("unknown operator command %q", command.Type)
+	}
+}
+
+// writeJSON writes an event to the console's socket, one write at a time.
+func (op *OperatorConn) writeJSON(v interface{}) error {
+	op.writeMu.Lock()
+	defer op.writeMu.Unlock()
+	return op.conn.WriteJSON(v)
+}
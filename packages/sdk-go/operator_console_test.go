@@ -0,0 +1,162 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type operatorWSConn struct {
+	mu     sync.Mutex
+	events []OperatorEvent
+}
+
+func (c *operatorWSConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := v.(OperatorEvent); ok {
+		c.events = append(c.events, e)
+	}
+	return nil
+}
+
+func (c *operatorWSConn) Close() error { return nil }
+
+func (c *operatorWSConn) types() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	types := make([]string, len(c.events))
+	for i, e := range c.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+// operatorUpgrade returns an operator console upgrade request with the
+// Authorization header set, if not empty.
+func operatorUpgrade(authorization string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/operator", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+func TestOperatorConsole(t *testing.T) {
+	ctx := context.Background()
+	bridge := NewMockBridge("slack")
+	pp := New(Config{
+		Bridges: []Bridge{bridge},
+		OperatorAuthenticator: func(ctx context.Context, token string) (string, error) {
+			if token != "secret" {
+				return "", errors.New("unknown token")
+			}
+			return "Ann", nil
+		},
+	})
+
+	for _, header := range []string{"", "secret", "Bearer wrong"} {
+		if _, err := pp.AuthenticateOperator(operatorUpgrade(header)); !errors.Is(err, ErrOperatorUnauthorized) {
+			t.Fatalf("Authorization %q: err = %v", header, err)
+		}
+	}
+	name, err := pp.AuthenticateOperator(operatorUpgrade("Bearer secret"))
+	if err != nil || name != "Ann" {
+		t.Fatalf("AuthenticateOperator = %q, %v", name, err)
+	}
+	conn := &operatorWSConn{}
+	op := pp.ConnectOperator(name, conn)
+	if !pp.IsOperatorOnline() {
+		t.Error("operator not online while a console is connected")
+	}
+
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "Hello?")
+	if err := op.HandleCommand(ctx, []byte(`{"type":"reply","sessionId":"`+session.ID+`","content":"Hi, Ann here"}`)); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if err := op.HandleCommand(ctx, []byte(`{"type":"messages","sessionId":"`+session.ID+`"}`)); err != nil {
+		t.Fatalf("messages: %v", err)
+	}
+	pp.dispatcher.wait()
+
+	if got := strings.Join(conn.types(), ","); got != "new_session,message,message,messages" {
+		t.Fatalf("events = %s", got)
+	}
+	conn.mu.Lock()
+	history := conn.events[3].Data.(*GetMessagesResponse)
+	if conn.events[1].SessionID != session.ID || len(history.Messages) != 2 || history.Messages[1].Sender != SenderOperator {
+		t.Errorf("unexpected events %+v", conn.events)
+	}
+	conn.mu.Unlock()
+
+	bridge.mu.Lock()
+	if len(bridge.OperatorMsgCalls) != 1 {
+		t.Errorf("reply mirrored %d times, want 1", len(bridge.OperatorMsgCalls))
+	}
+	bridge.mu.Unlock()
+
+	if err := op.HandleCommand(ctx, []byte(`{"type":"wave","sessionId":"`+session.ID+`"}`)); err == nil {
+		t.Error("unknown command accepted")
+	}
+	pp.DisconnectOperator(op)
+	if pp.IsOperatorOnline() {
+		t.Error("operator still online after the last console disconnected")
+	}
+}
+
+func TestOperatorConsoleNotConfigured(t *testing.T) {
+	pp := New(Config{})
+	if _, err := pp.AuthenticateOperator(operatorUpgrade("Bearer secret")); !errors.Is(err, ErrOperatorConsoleNotConfigured) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// overlapConn records whether two writes ever ran at once, which
+// *websocket.Conn doesn't allow.
+type overlapConn struct {
+	writing atomic.Int32
+	overlap atomic.Bool
+}
+
+func (c *overlapConn) WriteJSON(v interface{}) error {
+	if c.writing.Add(1) > 1 {
+		c.overlap.Store(true)
+	}
+	time.Sleep(100 * time.Microsecond)
+	c.writing.Add(-1)
+	return nil
+}
+
+func (c *overlapConn) Close() error { return nil }
+
+func TestOperatorConsoleSerializesWrites(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	conn := &overlapConn{}
+	op := pp.ConnectOperator("Ann", conn)
+	session := newSession(ctx, t, pp)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pp.broadcastToOperators(session.ID, WebSocketEvent{Type: "typing"})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = op.HandleCommand(ctx, []byte(`{"type":"undelivered","sessionId":"`+session.ID+`"}`))
+		}()
+	}
+	wg.Wait()
+	if conn.overlap.Load() {
+		t.Error("writes to the console socket overlapped")
+	}
+}
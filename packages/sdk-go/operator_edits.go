@@ -0,0 +1,286 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OperatorEditMessageRequest is an operator edit made on a bridge, e.g. from
+// WebhookConfig.OnOperatorMessageEdit.
+type OperatorEditMessageRequest struct {
+	SessionID string `json:"sessionId"`
+	// SourceBridge is the platform the edit was made on: "telegram",
+	// "discord", "slack" or "teams".
+	SourceBridge string `json:"sourceBridge"`
+	// BridgeMessageID is the message's ID on SourceBridge, recorded with
+	// WithBridgeMessageID when the message was sent.
+	BridgeMessageID string `json:"bridgeMessageId"`
+	Content         string `json:"content"`
+	// EditedAt is the platform's time of the edit, normalized with
+	// Config.ClockSkew. Defaults to now.
+	EditedAt time.Time `json:"editedAt,omitempty"`
+}
+
+// OperatorDeleteMessageRequest is an operator deletion made on a bridge,
+// e.g. from WebhookConfig.OnOperatorMessageDelete.
+type OperatorDeleteMessageRequest struct {
+	SessionID       string `json:"sessionId"`
+	SourceBridge    string `json:"sourceBridge"`
+	BridgeMessageID string `json:"bridgeMessageId"`
+	// DeletedAt is the platform's time of the deletion, normalized like
+	// EditedAt. Defaults to now.
+	DeletedAt time.Time `json:"deletedAt,omitempty"`
+}
+
+// WithBridgeMessageID records the ID an operator message has on the bridge
+// it was sent from (SendOperatorMessage's sourceBridge), so that edits and
+// deletions made there later find it. Needs StorageWithBridgeIDs.
+func WithBridgeMessageID(bridgeMessageID string) OperatorMessageOption {
+	return func(o *operatorMessageOptions) {
+		o.bridgeMessageID = bridgeMessageID
+	}
+}
+
+// HandleOperatorEditMessage applies an operator's edit made on a bridge: the
+// stored message is updated, the other bridges edit their copy, and the
+// widget gets a message_edited event.
+func (pp *PocketPing) HandleOperatorEditMessage(ctx context.Context, request OperatorEditMessageRequest) (*EditMessageResponse, error) {
+	if strings.TrimSpace(request.Content) == "" {
+		return nil, ErrNoContent
+	}
+	if err := ValidateContent(request.Content); err != nil {
+		return nil, err
+	}
+	session, err := pp.storage.GetSession(ctx, request.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	message, err := pp.findBridgeMessage(ctx, request.SessionID, request.SourceBridge, request.BridgeMessageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+
+	editedAt, platformEditedAt := pp.operatorEditTime(ctx, request.SourceBridge, request.EditedAt, message.Timestamp)
+	message.Content = request.Content
+	message.EditedAt = &editedAt
+	message.PlatformEditedAt = platformEditedAt
+	if err := pp.beforeMessageSave(ctx, message, session); err != nil {
+		return nil, err
+	}
+	if err := pp.updateStoredMessage(ctx, message); err != nil {
+		return nil, err
+	}
+
+	pp.syncEditToBridges(ctx, request.SessionID, message.ID, message.Content, editedAt, request.SourceBridge)
+	pp.BroadcastToSession(request.SessionID, WebSocketEvent{
+		Type: "message_edited",
+		Data: map[string]interface{}{
+			"messageId": message.ID,
+			"content":   message.Content,
+			"editedAt":  editedAt.Format(time.RFC3339),
+		},
+	})
+
+	response := &EditMessageResponse{}
+	response.Message.ID = message.ID
+	response.Message.Content = message.Content
+	response.Message.EditedAt = editedAt
+	return response, nil
+}
+
+// HandleOperatorDeleteMessage applies an operator's deletion made on a
+// bridge: the stored message is soft-deleted, the other bridges delete their
+// copy, and the widget gets a message_deleted event.
+func (pp *PocketPing) HandleOperatorDeleteMessage(ctx context.Context, request OperatorDeleteMessageRequest) (*DeleteMessageResponse, error) {
+	message, err := pp.findBridgeMessage(ctx, request.SessionID, request.SourceBridge, request.BridgeMessageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return &DeleteMessageResponse{Deleted: true}, nil
+	}
+
+	deletedAt, platformDeletedAt := pp.operatorEditTime(ctx, request.SourceBridge, request.DeletedAt, message.Timestamp)
+	// Sync before the soft delete, as bridges look up their IDs
+	pp.syncDeleteToBridges(ctx, request.SessionID, message.ID, deletedAt, request.SourceBridge)
+	message.DeletedAt = &deletedAt
+	message.PlatformDeletedAt = platformDeletedAt
+	if err := pp.updateStoredMessage(ctx, message); err != nil {
+		return nil, err
+	}
+
+	pp.BroadcastToSession(request.SessionID, WebSocketEvent{
+		Type: "message_deleted",
+		Data: map[string]interface{}{
+			"messageId": message.ID,
+			"deletedAt": deletedAt.Format(time.RFC3339),
+		},
+	})
+	return &DeleteMessageResponse{Deleted: true}, nil
+}
+
+// operatorEditTime returns the time to record for an edit or deletion made
+// on source of a message sent at sentAt, never before it, and the time the
+// platform reported, if any. Times from the webhook and Gateway callbacks
+// are already normalized, with the platform's in ctx (see PlatformTime);
+// others are normalized with Config.ClockSkew. A zero at means now.
+func (pp *PocketPing) operatorEditTime(ctx context.Context, source string, at, sentAt time.Time) (time.Time, *time.Time) {
+	reported, normalized := PlatformTime(ctx)
+	if !normalized {
+		reported, at = at, pp.config.ClockSkew.Normalize(source, at)
+	} else if at.IsZero() {
+		at = time.Now()
+	}
+	if at.Before(sentAt) {
+		at = sentAt
+	}
+	if reported.IsZero() {
+		return at, nil
+	}
+	return at, &reported
+}
+
+// findBridgeMessage returns a copy of the operator message of sessionID
+// whose ID on sourceBridge is bridgeMessageID.
+func (pp *PocketPing) findBridgeMessage(ctx context.Context, sessionID, sourceBridge, bridgeMessageID string) (*Message, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	store, ok := pp.storage.(StorageWithBridgeIDs)
+	if !ok || bridgeMessageID == "" {
+		return nil, ErrMessageNotFound
+	}
+
+	if index, ok := store.(StorageWithBridgeMessageIndex); ok {
+		messageID, err := index.FindMessageByBridgeID(ctx, sessionID, sourceBridge, bridgeMessageID)
+		if !errors.Is(err, ErrStorageNotSupported) {
+			if err != nil {
+				return nil, err
+			}
+			if messageID == "" {
+				return nil, ErrMessageNotFound
+			}
+			stored, err := pp.storage.GetMessage(ctx, messageID)
+			if err != nil {
+				return nil, err
+			}
+			if stored == nil || stored.SessionID != sessionID || stored.Sender != SenderOperator {
+				return nil, ErrMessageNotFound
+			}
+			message := *stored
+			return &message, nil
+		}
+	}
+
+	// Without the index, the session's operator messages are scanned
+	after := ""
+	for {
+		page, err := pp.storage.GetMessages(ctx, sessionID, after, snapshotPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			if page[i].Sender != SenderOperator {
+				continue
+			}
+			ids, err := store.GetBridgeMessageIDs(ctx, page[i].ID)
+			if err != nil {
+				return nil, err
+			}
+			if ids != nil && bridgeMessageIDOf(ids, sourceBridge) == bridgeMessageID {
+				message := page[i]
+				return &message, nil
+			}
+		}
+		if len(page) < snapshotPageSize {
+			return nil, ErrMessageNotFound
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// saveBridgeMessageID records a message's ID on sourceBridge. With
+// StorageWithBridgeMessageIndex, this also indexes it for findBridgeMessage.
+func (pp *PocketPing) saveBridgeMessageID(ctx context.Context, messageID, sourceBridge, bridgeMessageID string) error {
+	store, ok := pp.storage.(StorageWithBridgeIDs)
+	if !ok || bridgeMessageID == "" {
+		return nil
+	}
+	ids, ok, err := bridgeMessageIDsOn(sourceBridge, bridgeMessageID)
+	if err != nil || !ok {
+		return err
+	}
+	return store.SaveBridgeMessageIDs(ctx, messageID, ids)
+}
+
+// bridgeMessageIDsOn returns BridgeMessageIds holding bridgeMessageID as
+// the ID on sourceBridge, or false for a bridge without message IDs.
+func bridgeMessageIDsOn(sourceBridge, bridgeMessageID string) (BridgeMessageIds, bool, error) {
+	var ids BridgeMessageIds
+	switch bridgePlatform(sourceBridge) {
+	case "telegram":
+		id, err := strconv.ParseInt(bridgeMessageID, 10, 64)
+		if err != nil {
+			return ids, false, err
+		}
+		ids.TelegramMessageID = id
+	case "discord":
+		ids.DiscordMessageID = bridgeMessageID
+	case "slack":
+		ids.SlackMessageTS = bridgeMessageID
+	case "teams":
+		ids.TeamsMessageID = bridgeMessageID
+	default:
+		return ids, false, nil
+	}
+	return ids, true, nil
+}
+
+// bridgeMessageIDOf returns the ID in ids for sourceBridge.
+func bridgeMessageIDOf(ids *BridgeMessageIds, sourceBridge string) string {
+	switch bridgePlatform(sourceBridge) {
+	case "telegram":
+		if ids.TelegramMessageID != 0 {
+			return strconv.FormatInt(ids.TelegramMessageID, 10)
+		}
+	case "discord":
+		return ids.DiscordMessageID
+	case "slack":
+		return ids.SlackMessageTS
+	case "teams":
+		return ids.TeamsMessageID
+	}
+	return ""
+}
+
+// bridgePlatform returns the platform of a bridge or source bridge name:
+// "slack-bot" and "slack" are both "slack".
+func bridgePlatform(name string) string {
+	for _, platform := range []string{"telegram", "discord", "slack", "teams"} {
+		if strings.HasPrefix(name, platform) {
+			return platform
+		}
+	}
+	return name
+}
+
+// updateStoredMessage saves an edited or deleted message.
+func (pp *PocketPing) updateStoredMessage(ctx context.Context, message *Message) error {
+	if store, ok := pp.storage.(StorageWithBridgeIDs); ok {
+		return store.UpdateMessage(ctx, message)
+	}
+	return pp.storage.SaveMessage(ctx, message)
+}
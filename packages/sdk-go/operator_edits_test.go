@@ -0,0 +1,128 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newEditDeleteSpy(name string) *editDeleteSpyBridge {
+	return &editDeleteSpyBridge{BaseBridge: BaseBridge{BridgeName: name}, editCh: make(chan struct{}, 8), deleteCh: make(chan struct{}, 8)}
+}
+
+func TestOperatorEditAndDelete(t *testing.T) {
+	ctx := context.Background()
+	slack := newEditDeleteSpy("slack-bot")
+	telegram := newEditDeleteSpy("telegram")
+	pp := New(Config{Bridges: []Bridge{slack, telegram}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	ws := &mockWSConn{}
+	pp.RegisterWebSocket(sessionID, ws)
+
+	sent, err := pp.SendOperatorMessage(ctx, sessionID, "Helo", "slack", "Ann", WithBridgeMessageID("1712.0001"))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+
+	editedAt := time.Now()
+	resp, err := pp.HandleOperatorEditMessage(ctx, OperatorEditMessageRequest{
+		SessionID: sessionID, SourceBridge: "slack", BridgeMessageID: "1712.0001", Content: "Hello", EditedAt: editedAt,
+	})
+	if err != nil || resp.Message.ID != sent.ID || resp.Message.Content != "Hello" {
+		t.Fatalf("edit = %+v, %v", resp, err)
+	}
+	if _, err := pp.HandleOperatorDeleteMessage(ctx, OperatorDeleteMessageRequest{SessionID: sessionID, SourceBridge: "slack", BridgeMessageID: "1712.0001"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	pp.dispatcher.wait()
+
+	stored, _ := pp.storage.GetMessage(ctx, sent.ID)
+	if stored.Content != "Hello" || stored.EditedAt == nil || !stored.EditedAt.Equal(editedAt) || stored.DeletedAt == nil ||
+		stored.PlatformEditedAt == nil || !stored.PlatformEditedAt.Equal(editedAt) || stored.PlatformDeletedAt != nil {
+		t.Errorf("stored = %+v", stored)
+	}
+	// The edit was made on Slack: only the other bridges sync it
+	if len(slack.editCh) != 0 || len(slack.deleteCh) != 0 || len(telegram.editCh) != 1 || len(telegram.deleteCh) != 1 {
+		t.Errorf("slack edits/deletes = %d/%d, telegram = %d/%d", len(slack.editCh), len(slack.deleteCh), len(telegram.editCh), len(telegram.deleteCh))
+	}
+	var types []string
+	for _, event := range ws.events {
+		types = append(types, event.Type)
+	}
+	if len(types) < 2 || types[len(types)-2] != "message_edited" || types[len(types)-1] != "message_deleted" {
+		t.Errorf("widget events = %v", types)
+	}
+
+	if _, err := pp.HandleOperatorEditMessage(ctx, OperatorEditMessageRequest{SessionID: sessionID, SourceBridge: "slack", BridgeMessageID: "1712.0001", Content: "Again"}); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("edit deleted err = %v", err)
+	}
+}
+
+func TestOperatorEditUnknownMessage(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	if _, err := pp.SendOperatorMessage(ctx, sessionID, "Hi", "telegram", "Ann", WithBridgeMessageID("42")); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+
+	for _, request := range []OperatorEditMessageRequest{
+		{SessionID: sessionID, SourceBridge: "telegram", BridgeMessageID: "43", Content: "x"},
+		{SessionID: sessionID, SourceBridge: "discord", BridgeMessageID: "42", Content: "x"},
+	} {
+		if _, err := pp.HandleOperatorEditMessage(ctx, request); !errors.Is(err, ErrMessageNotFound) {
+			t.Errorf("%+v: err = %v", request, err)
+		}
+	}
+	if _, err := pp.HandleOperatorEditMessage(ctx, OperatorEditMessageRequest{SessionID: sessionID, SourceBridge: "telegram", BridgeMessageID: "42", Content: "Hi!"}); err != nil {
+		t.Errorf("telegram edit: %v", err)
+	}
+	if _, err := pp.HandleOperatorDeleteMessage(ctx, OperatorDeleteMessageRequest{SessionID: "missing", SourceBridge: "telegram", BridgeMessageID: "42"}); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("delete err = %v", err)
+	}
+}
+
+func TestMemoryStorageBridgeMessageIndex(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	for _, sessionID := range []string{"a", "b"} {
+		_ = m.CreateSession(ctx, createTestSession(sessionID, "v-"+sessionID, nil, nil))
+		_ = m.SaveMessage(ctx, &Message{ID: "m-" + sessionID, SessionID: sessionID, Sender: SenderOperator})
+		// Telegram message IDs repeat across chats
+		_ = m.SaveBridgeMessageIDs(ctx, "m-"+sessionID, BridgeMessageIds{TelegramMessageID: 7})
+	}
+	if id, _ := m.FindMessageByBridgeID(ctx, "b", "telegram", "7"); id != "m-b" {
+		t.Errorf("session b = %q", id)
+	}
+
+	_ = m.SaveBridgeMessageIDs(ctx, "m-a", BridgeMessageIds{TelegramMessageID: 8, SlackMessageTS: "1.2"})
+	if id, _ := m.FindMessageByBridgeID(ctx, "a", "telegram", "7"); id != "" {
+		t.Errorf("replaced ID still found: %q", id)
+	}
+	if id, _ := m.FindMessageByBridgeID(ctx, "a", "slack", "1.2"); id != "m-a" {
+		t.Errorf("slack = %q", id)
+	}
+
+	_ = m.DeleteSession(ctx, "a")
+	if id, _ := m.FindMessageByBridgeID(ctx, "a", "telegram", "8"); id != "" || len(m.bridgeIndex) != 1 {
+		t.Errorf("deleted session: %q, index = %v", id, m.bridgeIndex)
+	}
+}
+
+// unindexedStorage hides StorageWithBridgeMessageIndex.
+type unindexedStorage struct{ StorageWithBridgeIDs }
+
+func TestOperatorEditWithoutBridgeIndex(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{Storage: unindexedStorage{NewMemoryStorage()}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	sent, err := pp.SendOperatorMessage(ctx, sessionID, "Helo", "discord", "Ann", WithBridgeMessageID("d1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := pp.HandleOperatorEditMessage(ctx, OperatorEditMessageRequest{SessionID: sessionID, SourceBridge: "discord", BridgeMessageID: "d1", Content: "Hello"})
+	if err != nil || resp.Message.ID != sent.ID {
+		t.Errorf("edit = %+v, %v", resp, err)
+	}
+}
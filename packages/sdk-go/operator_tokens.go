@@ -0,0 +1,263 @@
+package pocketping
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OperatorTokenSource is the source bridge of operator messages sent with an
+// operator API token, so every bridge mirrors them.
+const OperatorTokenSource = "api"
+
+var (
+	// ErrOperatorTokensUnsupported is returned by the operator token
+	// functions when the storage doesn't implement StorageWithOperatorTokens.
+	ErrOperatorTokensUnsupported = newError("not_configured", http.StatusNotFound, "operator tokens are not supported by the storage")
+	// ErrOperatorTokenNotFound is returned by RevokeOperatorToken for an
+	// unknown token ID.
+	ErrOperatorTokenNotFound = newError("not_found", http.StatusNotFound, "operator token not found")
+	// ErrMethodNotAllowed is returned by the built-in handlers for requests
+	// with the wrong method.
+	ErrMethodNotAllowed = newError("method_not_allowed", http.StatusMethodNotAllowed, "Method not allowed")
+)
+
+// OperatorToken is an operator API token. Only the SHA-256 hash of its
+// secret is stored; the secret is returned once, by IssueOperatorToken.
+type OperatorToken struct {
+	ID string `json:"id"`
+	// Operator is the display name messages sent with the token are
+	// attributed to.
+	Operator   string     `json:"operator"`
+	Label      string     `json:"label,omitempty"`
+	Hash       string     `json:"hash"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// OperatorAuditAction is what an OperatorAuditRecord records.
+type OperatorAuditAction string
+
+const (
+	// OperatorTokenIssued: a token was issued.
+	OperatorTokenIssued OperatorAuditAction = "token_issued"
+	// OperatorTokenRevoked: a token was revoked.
+	OperatorTokenRevoked OperatorAuditAction = "token_revoked"
+	// OperatorMessageSent: a message was sent with a token.
+	OperatorMessageSent OperatorAuditAction = "message_sent"
+)
+
+// OperatorAuditRecord is the audit record of an operator token use, passed
+// to Config.OnOperatorAudit.
+type OperatorAuditRecord struct {
+	Action    OperatorAuditAction `json:"action"`
+	TokenID   string              `json:"tokenId"`
+	Operator  string              `json:"operator"`
+	SessionID string              `json:"sessionId,omitempty"`
+	MessageID string              `json:"messageId,omitempty"`
+	At        time.Time           `json:"at"`
+}
+
+// OperatorAuditor receives the audit record of every operator token use.
+type OperatorAuditor func(ctx context.Context, record OperatorAuditRecord)
+
+// WithOperatorToken sends the message as the operator owning an API token
+// secret, which replaces the operatorName argument. SendOperatorMessage
+// fails with ErrOperatorUnauthorized if the token is unknown or revoked.
+func WithOperatorToken(secret string) OperatorMessageOption {
+	return func(o *operatorMessageOptions) {
+		o.operatorToken = secret
+	}
+}
+
+// hashOperatorToken is the stored form of a token secret.
+func hashOperatorToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (pp *PocketPing) operatorTokenStorage() (StorageWithOperatorTokens, error) {
+	tokens, ok := pp.storage.(StorageWithOperatorTokens)
+	if !ok {
+		return nil, ErrOperatorTokensUnsupported
+	}
+	return tokens, nil
+}
+
+// IssueOperatorToken creates an API token for operator, for scripts and
+// integrations sending operator messages. The secret is only returned here:
+// hand it over, it can't be read back.
+func (pp *PocketPing) IssueOperatorToken(ctx context.Context, operator, label string) (string, *OperatorToken, error) {
+	tokens, err := pp.operatorTokenStorage()
+	if err != nil {
+		return "", nil, err
+	}
+	if operator == "" {
+		return "", nil, ErrBadRequest
+	}
+
+	secret := "ppo_" + randomHex(24)
+	token := &OperatorToken{
+		ID:        "opt_" + randomHex(8),
+		Operator:  operator,
+		Label:     label,
+		Hash:      hashOperatorToken(secret),
+		CreatedAt: time.Now(),
+	}
+	if err := tokens.SaveOperatorToken(ctx, token); err != nil {
+		return "", nil, err
+	}
+	pp.auditOperator(ctx, OperatorAuditRecord{Action: OperatorTokenIssued, TokenID: token.ID, Operator: operator})
+	return secret, token, nil
+}
+
+// RevokeOperatorToken revokes a token by ID. Revoking twice is a no-op.
+func (pp *PocketPing) RevokeOperatorToken(ctx context.Context, tokenID string) error {
+	tokens, err := pp.operatorTokenStorage()
+	if err != nil {
+		return err
+	}
+	all, err := tokens.ListOperatorTokens(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range all {
+		token := &all[i]
+		if token.ID != tokenID {
+			continue
+		}
+		if token.RevokedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		token.RevokedAt = &now
+		if err := tokens.SaveOperatorToken(ctx, token); err != nil {
+			return err
+		}
+		pp.auditOperator(ctx, OperatorAuditRecord{Action: OperatorTokenRevoked, TokenID: token.ID, Operator: token.Operator})
+		return nil
+	}
+	return ErrOperatorTokenNotFound
+}
+
+// ListOperatorTokens returns every operator token, revoked ones included.
+func (pp *PocketPing) ListOperatorTokens(ctx context.Context) ([]OperatorToken, error) {
+	tokens, err := pp.operatorTokenStorage()
+	if err != nil {
+		return nil, err
+	}
+	return tokens.ListOperatorTokens(ctx)
+}
+
+// ResolveOperatorToken returns the token for a secret, or
+// ErrOperatorUnauthorized if it's unknown or revoked, and records its use.
+func (pp *PocketPing) ResolveOperatorToken(ctx context.Context, secret string) (*OperatorToken, error) {
+	tokens, err := pp.operatorTokenStorage()
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, ErrOperatorUnauthorized
+	}
+	token, err := tokens.GetOperatorToken(ctx, hashOperatorToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.RevokedAt != nil {
+		return nil, ErrOperatorUnauthorized
+	}
+
+	now := time.Now()
+	token.LastUsedAt = &now
+	if err := tokens.TouchOperatorToken(ctx, token.ID, now); err != nil {
+		log.Printf("[PocketPing] Failed to record use of operator token %s: %v", token.ID, err)
+	}
+	return token, nil
+}
+
+// operatorSender resolves who SendOperatorMessage sends as: the token's
+// operator, if one is given. Without one, sends from the API are refused
+// when Config.RequireOperatorTokens is set.
+func (pp *PocketPing) operatorSender(ctx context.Context, options operatorMessageOptions, sourceBridge, operatorName string) (*OperatorToken, string, error) {
+	if options.operatorToken != "" {
+		token, err := pp.ResolveOperatorToken(ctx, options.operatorToken)
+		if err != nil {
+			return nil, "", err
+		}
+		return token, token.Operator, nil
+	}
+	if pp.config.RequireOperatorTokens && (sourceBridge == "" || sourceBridge == OperatorTokenSource) {
+		return nil, "", ErrOperatorUnauthorized
+	}
+	return nil, operatorName, nil
+}
+
+func (pp *PocketPing) auditOperator(ctx context.Context, record OperatorAuditRecord) {
+	record.At = time.Now()
+	if pp.config.OnOperatorAudit != nil {
+		pp.config.OnOperatorAudit(ctx, record)
+	}
+}
+
+// OperatorMessageRequest is the body of HandleOperatorMessages.
+type OperatorMessageRequest struct {
+	SessionID     string   `json:"sessionId"`
+	Content       string   `json:"content"`
+	ThreadID      string   `json:"threadId,omitempty"`
+	AttachmentIDs []string `json:"attachmentIds,omitempty"`
+}
+
+// HandleOperatorMessages returns the REST endpoint for operator messages:
+// a POST with an OperatorMessageRequest body and an operator API token as
+// "Authorization: Bearer ppo_...". It answers with the sent Message.
+func (pp *PocketPing) HandleOperatorMessages() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			WriteError(w, ErrMethodNotAllowed)
+			return
+		}
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			WriteError(w, ErrOperatorUnauthorized)
+			return
+		}
+
+		var request OperatorMessageRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+			WriteError(w, ErrInvalidJSON)
+			return
+		}
+		if request.SessionID == "" {
+			WriteError(w, ErrBadRequest)
+			return
+		}
+
+		opts := []OperatorMessageOption{WithOperatorToken(secret)}
+		if request.ThreadID != "" {
+			opts = append(opts, WithThread(request.ThreadID))
+		}
+		if len(request.AttachmentIDs) > 0 {
+			opts = append(opts, WithAttachmentIDs(request.AttachmentIDs...))
+		}
+		message, err := pp.SendOperatorMessage(r.Context(), request.SessionID, request.Content, OperatorTokenSource, "", opts...)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(message)
+	}
+}
@@ -0,0 +1,195 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOperatorTokens(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var audit []OperatorAuditRecord
+	pp := New(Config{
+		InboxReadModel:        true,
+		RequireOperatorTokens: true,
+		OnOperatorAudit: func(ctx context.Context, record OperatorAuditRecord) {
+			mu.Lock()
+			audit = append(audit, record)
+			mu.Unlock()
+		},
+	})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	if _, err := pp.SendOperatorMessage(ctx, sessionID, "Hi", "", "Ann"); !errors.Is(err, ErrOperatorUnauthorized) {
+		t.Fatalf("send without token: err = %v", err)
+	}
+	if _, err := pp.SendOperatorMessage(ctx, sessionID, "Hi", OperatorConsoleSource, "Ann"); err != nil {
+		t.Fatalf("console send: %v", err)
+	}
+
+	secret, token, err := pp.IssueOperatorToken(ctx, "Bob", "zapier")
+	if err != nil {
+		t.Fatalf("IssueOperatorToken: %v", err)
+	}
+	if !strings.HasPrefix(secret, "ppo_") || token.Hash == secret {
+		t.Errorf("secret = %q, hash = %q", secret, token.Hash)
+	}
+
+	if _, err := pp.SendOperatorMessage(ctx, sessionID, "Hi", "", "", WithOperatorToken("ppo_wrong")); !errors.Is(err, ErrOperatorUnauthorized) {
+		t.Fatalf("send with bad token: err = %v", err)
+	}
+	message, err := pp.SendOperatorMessage(ctx, sessionID, "Hello from Bob", "", "Mallory", WithOperatorToken(secret))
+	if err != nil {
+		t.Fatalf("send with token: %v", err)
+	}
+	if entry, _ := pp.Inbox().Get(sessionID); entry.Operator != "Bob" {
+		t.Errorf("assignee = %q, want Bob", entry.Operator)
+	}
+
+	tokens, err := pp.ListOperatorTokens(ctx)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("ListOperatorTokens = %+v, %v", tokens, err)
+	}
+
+	if err := pp.RevokeOperatorToken(ctx, token.ID); err != nil {
+		t.Fatalf("RevokeOperatorToken: %v", err)
+	}
+	if _, err := pp.SendOperatorMessage(ctx, sessionID, "Hi", "", "", WithOperatorToken(secret)); !errors.Is(err, ErrOperatorUnauthorized) {
+		t.Fatalf("send with revoked token: err = %v", err)
+	}
+	if err := pp.RevokeOperatorToken(ctx, "opt_missing"); !errors.Is(err, ErrOperatorTokenNotFound) {
+		t.Errorf("revoke unknown: err = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []OperatorAuditAction{OperatorTokenIssued, OperatorMessageSent, OperatorTokenRevoked}
+	if len(audit) != len(want) {
+		t.Fatalf("audit = %+v", audit)
+	}
+	for i, record := range audit {
+		if record.Action != want[i] || record.TokenID != token.ID || record.Operator != "Bob" {
+			t.Errorf("audit[%d] = %+v", i, record)
+		}
+	}
+	if audit[1].MessageID != message.ID || audit[1].SessionID != sessionID {
+		t.Errorf("message audit = %+v", audit[1])
+	}
+}
+
+func TestOperatorTokensUnsupported(t *testing.T) {
+	pp := New(Config{Storage: statsLessStorage{}})
+	if _, _, err := pp.IssueOperatorToken(context.Background(), "Ann", ""); !errors.Is(err, ErrOperatorTokensUnsupported) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestOperatorTokensPersisted(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pocketping.log")
+	storage, err := NewPersistentMemoryStorage(path)
+	if err != nil {
+		t.Fatalf("NewPersistentMemoryStorage: %v", err)
+	}
+	pp := New(Config{Storage: storage})
+	secret, _, err := pp.IssueOperatorToken(ctx, "Ann", "")
+	if err != nil {
+		t.Fatalf("IssueOperatorToken: %v", err)
+	}
+	if _, err := pp.ResolveOperatorToken(ctx, secret); err != nil {
+		t.Fatalf("ResolveOperatorToken: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := NewPersistentMemoryStorage(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	tokens, _ := reopened.ListOperatorTokens(ctx)
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Errorf("reopened tokens = %+v", tokens)
+	}
+	token, err := New(Config{Storage: reopened}).ResolveOperatorToken(ctx, secret)
+	if err != nil || token.Operator != "Ann" {
+		t.Fatalf("ResolveOperatorToken = %+v, %v", token, err)
+	}
+}
+
+func TestTouchOperatorTokenKeepsRevocation(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	secret, token, err := pp.IssueOperatorToken(ctx, "Ann", "")
+	if err != nil {
+		t.Fatalf("IssueOperatorToken: %v", err)
+	}
+	// A use racing the revocation: the token was loaded before it.
+	if err := pp.RevokeOperatorToken(ctx, token.ID); err != nil {
+		t.Fatalf("RevokeOperatorToken: %v", err)
+	}
+	storage := pp.storage.(StorageWithOperatorTokens)
+	if err := storage.TouchOperatorToken(ctx, token.ID, time.Now()); err != nil {
+		t.Fatalf("TouchOperatorToken: %v", err)
+	}
+	if _, err := pp.ResolveOperatorToken(ctx, secret); !errors.Is(err, ErrOperatorUnauthorized) {
+		t.Errorf("revoked token resolved after use: err = %v", err)
+	}
+}
+
+func TestHandleOperatorMessages(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	secret, _, err := pp.IssueOperatorToken(ctx, "Ann", "")
+	if err != nil {
+		t.Fatalf("IssueOperatorToken: %v", err)
+	}
+	handler := pp.HandleOperatorMessages()
+
+	post := func(auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/operator/messages", strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	body := `{"sessionId":"` + sessionID + `","content":"Shipped!"}`
+	if rec := post("", body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d", rec.Code)
+	}
+	if rec := post("Bearer ppo_wrong", body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad token: status = %d", rec.Code)
+	}
+	if rec := post("Bearer "+secret, "{"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad JSON: status = %d", rec.Code)
+	}
+
+	rec := post("Bearer "+secret, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var message Message
+	if err := json.NewDecoder(rec.Body).Decode(&message); err != nil || message.Content != "Shipped!" || message.Sender != SenderOperator {
+		t.Fatalf("message = %+v, %v", message, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/operator/messages", nil)
+	get := httptest.NewRecorder()
+	handler(get, req)
+	if get.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d", get.Code)
+	}
+}
@@ -0,0 +1,277 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPaymentsNotConfigured is returned by RequestPayment when
+	// Config.PaymentProvider is nil.
+	ErrPaymentsNotConfigured = newError("not_configured", http.StatusNotFound, "no payment provider configured")
+	// ErrInvalidAmount is returned for a zero, negative or malformed amount.
+	ErrInvalidAmount = newError("invalid_amount", http.StatusBadRequest, "invalid payment amount")
+)
+
+// PaymentStatus is the state of a PaymentRequest.
+type PaymentStatus string
+
+// Payment statuses.
+const (
+	PaymentStatusPending PaymentStatus = "pending"
+	PaymentStatusPaid    PaymentStatus = "paid"
+	PaymentStatusExpired PaymentStatus = "expired"
+)
+
+// PaymentRequest asks the visitor to pay. It rides on an operator message
+// (Message.Payment); the widget shows it as a pay button.
+type PaymentRequest struct {
+	ID string `json:"id"`
+	// Amount is in the currency's minor unit (cents for USD).
+	Amount int64 `json:"amount"`
+	// Currency is the ISO 4217 code, upper case.
+	Currency    string        `json:"currency"`
+	Description string        `json:"description"`
+	URL         string        `json:"url"`
+	Status      PaymentStatus `json:"status"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	PaidAt      *time.Time    `json:"paidAt,omitempty"`
+	// ProviderID is the provider's ID, e.g. the Stripe Checkout Session ID.
+	ProviderID string `json:"providerId,omitempty"`
+	// ReceiptURL links to the provider's receipt, once paid.
+	ReceiptURL string `json:"receiptUrl,omitempty"`
+}
+
+// PaymentEvent is a payment update parsed from a provider webhook.
+type PaymentEvent struct {
+	SessionID  string
+	PaymentID  string
+	Status     PaymentStatus
+	ReceiptURL string
+}
+
+// PaymentProvider creates payment links and parses the provider's webhooks,
+// e.g. Stripe Checkout. CreatePaymentLink returns the URL the visitor pays
+// at and the provider's ID for it; it must carry session.ID and request.ID
+// through to the webhook. ParseWebhook verifies the request and returns nil
+// for events that aren't payment updates.
+type PaymentProvider interface {
+	CreatePaymentLink(ctx context.Context, session *Session, request PaymentRequest) (url, providerID string, err error)
+	ParseWebhook(r *http.Request) (*PaymentEvent, error)
+}
+
+// PaymentCommandCallback is called when an operator runs
+// /charge <amount> <currency> [description] in a session's topic or thread.
+// amount is in the currency's minor unit. Typically calls
+// PocketPing.RequestPayment.
+type PaymentCommandCallback func(ctx context.Context, sessionID, operatorName, sourceBridge string, amount int64, currency, description string)
+
+// RequestPayment creates a payment link with Config.PaymentProvider and
+// sends it to the visitor as an operator message carrying the
+// PaymentRequest. amount is in the currency's minor unit.
+func (pp *PocketPing) RequestPayment(ctx context.Context, sessionID, operatorName string, amount int64, currency, description string) (*Message, error) {
+	if pp.config.PaymentProvider == nil {
+		return nil, ErrPaymentsNotConfigured
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	payment := PaymentRequest{
+		ID:          pp.generateID(),
+		Amount:      amount,
+		Currency:    strings.ToUpper(currency),
+		Description: strings.TrimSpace(description),
+		Status:      PaymentStatusPending,
+		CreatedAt:   time.Now(),
+	}
+	payment.URL, payment.ProviderID, err = pp.config.PaymentProvider.CreatePaymentLink(ctx, session, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("💳 Payment request: %s", FormatAmount(payment.Amount, payment.Currency))
+	if payment.Description != "" {
+		content += " for " + payment.Description
+	}
+	content += "\n" + payment.URL
+	response, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: content, Sender: SenderOperator, Payment: &payment})
+	if err != nil {
+		return nil, err
+	}
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("💳 %s requested %s", takeoverName(operatorName), FormatAmount(payment.Amount, payment.Currency)))
+
+	return pp.storage.GetMessage(ctx, response.MessageID)
+}
+
+// HandlePaymentWebhook returns the handler for the payment provider's
+// webhook. A completed payment marks the request paid and posts a receipt
+// into the conversation; an expired one is marked expired. Updates that fail
+// to apply are answered with a 500, so the provider retries them.
+func (pp *PocketPing) HandlePaymentWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if pp.config.PaymentProvider == nil {
+			WriteError(w, ErrPaymentsNotConfigured)
+			return
+		}
+		event, err := pp.config.PaymentProvider.ParseWebhook(r)
+		if err != nil {
+			log.Printf("[PocketPing] Payment webhook rejected: %v", err)
+			WriteError(w, errInvalidWebhookEvent)
+			return
+		}
+		if event != nil {
+			if err := pp.applyPaymentEvent(r.Context(), event); err != nil {
+				// A 5xx makes the provider deliver the update again.
+				log.Printf("[PocketPing] Payment %s update failed: %v", event.PaymentID, err)
+				WriteError(w, ErrInternal)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// applyPaymentEvent updates the message carrying the payment. Repeated
+// deliveries of the same update are ignored.
+func (pp *PocketPing) applyPaymentEvent(ctx context.Context, event *PaymentEvent) error {
+	messages, err := pp.allMessages(ctx, event.SessionID)
+	if err != nil {
+		return err
+	}
+	var message *Message
+	for i := range messages {
+		if messages[i].Payment != nil && messages[i].Payment.ID == event.PaymentID {
+			message = &messages[i]
+			break
+		}
+	}
+	if message == nil {
+		return ErrMessageNotFound
+	}
+	payment := message.Payment
+	if payment.Status == event.Status || payment.Status == PaymentStatusPaid {
+		return nil
+	}
+
+	payment.Status = event.Status
+	if event.Status == PaymentStatusPaid {
+		now := time.Now()
+		payment.PaidAt = &now
+		payment.ReceiptURL = event.ReceiptURL
+	}
+	if storageWithBridge, ok := pp.storage.(StorageWithBridgeIDs); ok {
+		err = storageWithBridge.UpdateMessage(ctx, message)
+	} else {
+		err = pp.storage.SaveMessage(ctx, message)
+	}
+	if err != nil {
+		return err
+	}
+
+	pp.BroadcastToSession(event.SessionID, WebSocketEvent{
+		Type: "payment_updated",
+		Data: map[string]interface{}{
+			"messageId": message.ID,
+			"payment":   payment,
+		},
+	})
+	if event.Status != PaymentStatusPaid {
+		return nil
+	}
+
+	receipt := fmt.Sprintf("✅ Payment received: %s", FormatAmount(payment.Amount, payment.Currency))
+	if payment.Description != "" {
+		receipt += " for " + payment.Description
+	}
+	if payment.ReceiptURL != "" {
+		receipt += "\nReceipt: " + payment.ReceiptURL
+	}
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: event.SessionID, Content: receipt, Sender: SenderOperator, ReplyTo: message.ID}); err != nil {
+		return err
+	}
+	if session, err := pp.storage.GetSession(ctx, event.SessionID); err == nil && session != nil {
+		pp.notifyBridgesNotice(ctx, session, receipt)
+	}
+	return nil
+}
+
+// currencyExponents are the ISO 4217 minor-unit digits of the currencies
+// that don't have two.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "JPY": 0, "KMF": 0, "KRW": 0, "MGA": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// currencyExponent returns the minor-unit digits of currency.
+func currencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// ParseAmount converts a decimal amount ("49", "49.90", "12.500 KWD") to
+// the currency's minor unit. Amounts that don't fit in an int64 are
+// invalid.
+func ParseAmount(amount, currency string) (int64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil || math.IsNaN(value) || value <= 0 || math.IsInf(value, 0) {
+		return 0, ErrInvalidAmount
+	}
+	minor := math.Round(value * math.Pow10(currencyExponent(currency)))
+	// float64(math.MaxInt64) rounds up to 2^63, which already overflows
+	if minor >= math.MaxInt64 {
+		return 0, ErrInvalidAmount
+	}
+	return int64(minor), nil
+}
+
+// FormatAmount formats an amount in minor units, e.g. "49.90 USD" or
+// "12.500 KWD".
+func FormatAmount(amount int64, currency string) string {
+	currency = strings.ToUpper(currency)
+	exponent := currencyExponent(currency)
+	if exponent == 0 {
+		return fmt.Sprintf("%d %s", amount, currency)
+	}
+	unit := int64(math.Pow10(exponent))
+	return fmt.Sprintf("%d.%0*d %s", amount/unit, exponent, amount%unit, currency)
+}
+
+// parseChargeCommand recognises /charge <amount> <currency> [description]
+// (also as /charge@bot). The description may be quoted.
+func parseChargeCommand(text string) (amount int64, currency, description string, ok bool, err error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0, "", "", false, nil
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	if command != "/charge" {
+		return 0, "", "", false, nil
+	}
+	if len(fields) < 3 || len(fields[2]) != 3 {
+		return 0, "", "", true, fmt.Errorf("usage: /charge <amount> <currency> [description]")
+	}
+	currency = strings.ToUpper(fields[2])
+	amount, err = ParseAmount(fields[1], currency)
+	if err != nil {
+		return 0, "", "", true, err
+	}
+	description = strings.Trim(strings.Join(fields[3:], " "), `"“”`)
+	return amount, currency, description, true, nil
+}
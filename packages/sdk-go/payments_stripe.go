@@ -0,0 +1,172 @@
+package pocketping
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stripeSignatureTolerance is how old a Stripe webhook signature may be.
+const stripeSignatureTolerance = 5 * time.Minute
+
+// StripePaymentProvider implements PaymentProvider with Stripe Checkout:
+// each payment request is a Checkout Session, and the
+// checkout.session.completed / checkout.session.expired webhooks update it.
+type StripePaymentProvider struct {
+	// SecretKey is the Stripe secret key (required).
+	SecretKey string
+	// WebhookSecret is the endpoint's signing secret, whsec_... (required).
+	WebhookSecret string
+	// SuccessURL is where the visitor lands after paying (required by Stripe).
+	SuccessURL string
+	// CancelURL is where the visitor lands when going back (optional).
+	CancelURL string
+	// BaseURL is the API base URL (default "https://api.stripe.com/v1").
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+func (p *StripePaymentProvider) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return "https://api.stripe.com/v1"
+}
+
+// CreatePaymentLink creates a Checkout Session (POST {baseURL}/checkout/sessions)
+// with the session and payment IDs in its metadata.
+func (p *StripePaymentProvider) CreatePaymentLink(ctx context.Context, session *Session, request PaymentRequest) (string, string, error) {
+	description := request.Description
+	if description == "" {
+		description = "Payment"
+	}
+	form := url.Values{
+		"mode":                                                 {"payment"},
+		"success_url":                                          {p.SuccessURL},
+		"line_items[0][quantity]":                              {"1"},
+		"line_items[0][price_data][currency]":                  {strings.ToLower(request.Currency)},
+		"line_items[0][price_data][unit_amount]":               {strconv.FormatInt(request.Amount, 10)},
+		"line_items[0][price_data][product_data][name]":        {description},
+		"metadata[pocketping_session_id]":                      {session.ID},
+		"metadata[pocketping_payment_id]":                      {request.ID},
+		"payment_intent_data[metadata][pocketping_session_id]": {session.ID},
+		"client_reference_id":                                  {request.ID},
+	}
+	if p.CancelURL != "" {
+		form.Set("cancel_url", p.CancelURL)
+	}
+	if session.Identity != nil && session.Identity.Email != "" {
+		form.Set("customer_email", session.Identity.Email)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL()+"/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+p.SecretKey)
+	req.Header.Set("Idempotency-Key", request.ID)
+
+	resp, err := httpClientOr(p.HTTPClient).Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("stripe: unexpected status %d", resp.StatusCode)
+	}
+	var checkout struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&checkout); err != nil {
+		return "", "", err
+	}
+	return checkout.URL, checkout.ID, nil
+}
+
+// ParseWebhook verifies the Stripe-Signature header and returns the update
+// for checkout.session.completed (when paid) and checkout.session.expired.
+func (p *StripePaymentProvider) ParseWebhook(r *http.Request) (*PaymentEvent, error) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, DefaultWebhookMaxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.verifySignature(payload, r.Header.Get("Stripe-Signature")); err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				PaymentStatus string            `json:"payment_status"`
+				Metadata      map[string]string `json:"metadata"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	object := event.Data.Object
+	update := &PaymentEvent{SessionID: object.Metadata["pocketping_session_id"], PaymentID: object.Metadata["pocketping_payment_id"]}
+	if update.SessionID == "" || update.PaymentID == "" {
+		return nil, nil
+	}
+	switch {
+	case event.Type == "checkout.session.completed" && object.PaymentStatus == "paid",
+		event.Type == "checkout.session.async_payment_succeeded":
+		update.Status = PaymentStatusPaid
+	case event.Type == "checkout.session.expired":
+		update.Status = PaymentStatusExpired
+	default:
+		return nil, nil
+	}
+	return update, nil
+}
+
+// verifySignature checks a "t=...,v1=..." Stripe signature header.
+func (p *StripePaymentProvider) verifySignature(payload []byte, header string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return errors.New("stripe: malformed signature header")
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return errors.New("stripe: signature timestamp outside tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.WebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		if given, err := hex.DecodeString(signature); err == nil && hmac.Equal(given, expected) {
+			return nil
+		}
+	}
+	return errors.New("stripe: signature mismatch")
+}
+
+var _ PaymentProvider = (*StripePaymentProvider)(nil)
@@ -0,0 +1,228 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakePaymentProvider struct {
+	event *PaymentEvent
+}
+
+func (f *fakePaymentProvider) CreatePaymentLink(ctx context.Context, session *Session, request PaymentRequest) (string, string, error) {
+	return "https://pay.example.com/" + request.ID, "cs_1", nil
+}
+
+func (f *fakePaymentProvider) ParseWebhook(r *http.Request) (*PaymentEvent, error) {
+	return f.event, nil
+}
+
+func TestRequestPaymentAndReceipt(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakePaymentProvider{}
+	pp := New(Config{PaymentProvider: provider})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	message, err := pp.RequestPayment(ctx, session.ID, "Bob", 4900, "usd", "Consulting")
+	if err != nil {
+		t.Fatalf("RequestPayment: %v", err)
+	}
+	payment := message.Payment
+	if payment == nil || payment.Currency != "USD" || payment.Status != PaymentStatusPending || payment.ProviderID != "cs_1" {
+		t.Fatalf("payment = %+v", payment)
+	}
+	if !strings.Contains(message.Content, "49.00 USD for Consulting") || !strings.Contains(message.Content, payment.URL) {
+		t.Errorf("content = %q", message.Content)
+	}
+
+	provider.event = &PaymentEvent{SessionID: session.ID, PaymentID: payment.ID, Status: PaymentStatusPaid}
+	for i := 0; i < 2; i++ { // the second delivery is a no-op
+		rec := httptest.NewRecorder()
+		pp.HandlePaymentWebhook()(rec, httptest.NewRequest("POST", "/payments/webhook", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("webhook status = %d", rec.Code)
+		}
+	}
+
+	updated, _ := pp.storage.GetMessage(ctx, message.ID)
+	if updated.Payment.Status != PaymentStatusPaid || updated.Payment.PaidAt == nil {
+		t.Errorf("payment after webhook = %+v", updated.Payment)
+	}
+	var receipts int
+	for _, event := range conn.events {
+		if msg, ok := event.Data.(*Message); ok && strings.HasPrefix(msg.Content, "✅ Payment received: 49.00 USD") {
+			receipts++
+			if msg.ReplyTo != message.ID {
+				t.Errorf("receipt replyTo = %q, want %q", msg.ReplyTo, message.ID)
+			}
+		}
+	}
+	if receipts != 1 {
+		t.Errorf("receipts = %d, want 1", receipts)
+	}
+}
+
+func TestRequestPaymentValidation(t *testing.T) {
+	ctx := context.Background()
+	if _, err := New(Config{}).RequestPayment(ctx, "s1", "Bob", 100, "USD", ""); !errors.Is(err, ErrPaymentsNotConfigured) {
+		t.Errorf("error = %v, want ErrPaymentsNotConfigured", err)
+	}
+	pp := New(Config{PaymentProvider: &fakePaymentProvider{}})
+	if _, err := pp.RequestPayment(ctx, "s1", "Bob", 0, "USD", ""); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+func TestParseChargeCommand(t *testing.T) {
+	tests := []struct {
+		text        string
+		amount      int64
+		currency    string
+		description string
+		ok, err     bool
+	}{
+		{`/charge 49 USD "Consulting"`, 4900, "USD", "Consulting", true, false},
+		{`/charge@pp_bot 19.99 eur`, 1999, "EUR", "", true, false},
+		{`/charge 5000 JPY Setup fee`, 5000, "JPY", "Setup fee", true, false},
+		{`/charge 12.5 KWD`, 12500, "KWD", "", true, false},
+		{`/charge 49`, 0, "", "", true, true},
+		{`/charge abc USD`, 0, "", "", true, true},
+		{`/ticket`, 0, "", "", false, false},
+	}
+	for _, tt := range tests {
+		amount, currency, description, ok, err := parseChargeCommand(tt.text)
+		if ok != tt.ok || (err != nil) != tt.err || amount != tt.amount || currency != tt.currency || description != tt.description {
+			t.Errorf("parseChargeCommand(%q) = %d %q %q %v %v", tt.text, amount, currency, description, ok, err)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+	}{
+		{"49", "USD", 4900},
+		{" 49.90 ", "usd", 4990},
+		{"5000", "JPY", 5000},
+		{"12.5", "KWD", 12500},
+		{"9e16", "USD", 9000000000000000000},
+	}
+	for _, tt := range tests {
+		if got, err := ParseAmount(tt.amount, tt.currency); err != nil || got != tt.want {
+			t.Errorf("ParseAmount(%q, %s) = %d, %v, want %d", tt.amount, tt.currency, got, err, tt.want)
+		}
+	}
+
+	for _, amount := range []string{"", "abc", "0", "-5", "NaN", "nan", "Inf", "-Inf", "1e30", "92233720368547758.08"} {
+		if got, err := ParseAmount(amount, "USD"); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("ParseAmount(%q) = %d, %v, want ErrInvalidAmount", amount, got, err)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{4990, "usd", "49.90 USD"},
+		{5000, "JPY", "5000 JPY"},
+		{12500, "KWD", "12.500 KWD"},
+		{1005, "BHD", "1.005 BHD"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("FormatAmount(%d, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentWebhookFailureRetries(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakePaymentProvider{}
+	pp := New(Config{PaymentProvider: provider})
+	session := newSession(ctx, t, pp)
+
+	provider.event = &PaymentEvent{SessionID: session.ID, PaymentID: "unknown", Status: PaymentStatusPaid}
+	rec := httptest.NewRecorder()
+	pp.HandlePaymentWebhook()(rec, httptest.NewRequest("POST", "/payments/webhook", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("webhook status = %d, want 500 so the provider retries", rec.Code)
+	}
+}
+
+func TestWebhookHandler_ChargeCommand(t *testing.T) {
+	var got string
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		OnChargeCommand: func(ctx context.Context, sessionID, operatorName, sourceBridge string, amount int64, currency, description string) {
+			got = fmt.Sprintf("%s %s %s %d %s %s", sessionID, operatorName, sourceBridge, amount, currency, description)
+		},
+	})
+	payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"/charge 49 USD \"Consulting\""}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+	if got != "456 Bob telegram 4900 USD Consulting" {
+		t.Errorf("callback got %q", got)
+	}
+}
+
+func signStripe(payload []byte, secret string, at time.Time) string {
+	timestamp := fmt.Sprintf("%d", at.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestStripePaymentProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/checkout/sessions" || r.Form.Get("line_items[0][price_data][unit_amount]") != "4900" ||
+			r.Form.Get("metadata[pocketping_payment_id]") != "pay1" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Form)
+		}
+		w.Write([]byte(`{"id":"cs_test_1","url":"https://checkout.stripe.com/c/pay/cs_test_1"}`))
+	}))
+	defer server.Close()
+
+	provider := &StripePaymentProvider{SecretKey: "sk_test", WebhookSecret: "whsec_test", SuccessURL: "https://example.com/thanks", BaseURL: server.URL}
+	link, id, err := provider.CreatePaymentLink(context.Background(), &Session{ID: "s1"}, PaymentRequest{ID: "pay1", Amount: 4900, Currency: "USD"})
+	if err != nil || id != "cs_test_1" || !strings.HasPrefix(link, "https://checkout.stripe.com/") {
+		t.Fatalf("CreatePaymentLink = %q, %q, %v", link, id, err)
+	}
+
+	payload := []byte(`{"type":"checkout.session.completed","data":{"object":{"payment_status":"paid","metadata":{"pocketping_session_id":"s1","pocketping_payment_id":"pay1"}}}}`)
+	req := httptest.NewRequest("POST", "/payments/webhook", bytes.NewReader(payload))
+	req.Header.Set("Stripe-Signature", signStripe(payload, "whsec_test", time.Now()))
+	event, err := provider.ParseWebhook(req)
+	if err != nil || event == nil || event.Status != PaymentStatusPaid || event.PaymentID != "pay1" {
+		t.Errorf("ParseWebhook = %+v, %v", event, err)
+	}
+
+	for name, signature := range map[string]string{
+		"wrong secret": signStripe(payload, "whsec_other", time.Now()),
+		"stale":        signStripe(payload, "whsec_test", time.Now().Add(-time.Hour)),
+		"missing":      "",
+	} {
+		req := httptest.NewRequest("POST", "/payments/webhook", bytes.NewReader(payload))
+		req.Header.Set("Stripe-Signature", signature)
+		if _, err := provider.ParseWebhook(req); err == nil {
+			t.Errorf("%s: expected a signature error", name)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package pocketping
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Ruwad-io/pocketping/sdk-go/phonenumber"
+)
+
+// ErrInvalidPhone is returned when a phone number given to HandleIdentify or
+// HandleContactSubmit doesn't validate. It wraps the phonenumber error
+// saying why, e.g. "invalid phone number: phone number is too short".
+var ErrInvalidPhone = newError("invalid_phone", http.StatusBadRequest, "invalid phone number")
+
+// parsePhone normalizes a visitor's phone number to E.164 and returns it
+// with its country, inferred from the calling code. country is the
+// visitor's pick, used for numbers typed without a calling code.
+func parsePhone(phone, country string) (string, string, error) {
+	number, err := phonenumber.Parse(phone, country)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrInvalidPhone, err)
+	}
+	return number.E164, number.Country, nil
+}
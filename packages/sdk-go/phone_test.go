@@ -0,0 +1,39 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Ruwad-io/pocketping/sdk-go/phonenumber"
+)
+
+func TestHandleIdentifyPhone(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	session := newSession(ctx, t, pp)
+
+	_, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: session.ID, Identity: &UserIdentity{ID: "u1"}, Phone: "06 12"})
+	if !errors.Is(err, ErrInvalidPhone) || !errors.Is(err, phonenumber.ErrMissingCountry) {
+		t.Errorf("err = %v, want ErrInvalidPhone wrapping ErrMissingCountry", err)
+	}
+	if got, _ := pp.GetSession(ctx, session.ID); got.Identity != nil {
+		t.Error("identity stored despite the invalid phone")
+	}
+
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: session.ID, Identity: &UserIdentity{ID: "u1"}, Phone: "06 12 34 56 78", PhoneCountry: "FR"}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	got, _ := pp.GetSession(ctx, session.ID)
+	if got.UserPhone != "+33612345678" || got.UserPhoneCountry != "FR" {
+		t.Errorf("phone = %q %q", got.UserPhone, got.UserPhoneCountry)
+	}
+
+	// Identifying again without a phone keeps it.
+	if _, err := pp.HandleIdentify(ctx, IdentifyRequest{SessionID: session.ID, Identity: &UserIdentity{ID: "u1", Name: "Jane"}}); err != nil {
+		t.Fatalf("HandleIdentify: %v", err)
+	}
+	if got, _ := pp.GetSession(ctx, session.ID); got.UserPhone != "+33612345678" {
+		t.Errorf("phone = %q after identifying without one", got.UserPhone)
+	}
+}
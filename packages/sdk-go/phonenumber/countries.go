@@ -0,0 +1,90 @@
+package phonenumber
+
+// country is a numbering plan: calling code, trunk prefix dialled before
+// national numbers (dropped in E.164), and the national significant number's
+// length range.
+type country struct {
+	iso      string
+	code     string
+	trunk    string
+	min, max int
+}
+
+// plans lists the known numbering plans. Where several countries share a
+// calling code, the first one listed is the main one.
+var plans = []country{
+	{"US", "1", "1", 10, 10},
+	{"CA", "1", "1", 10, 10},
+	{"RU", "7", "8", 10, 10},
+	{"KZ", "7", "8", 10, 10},
+	{"EG", "20", "0", 9, 10},
+	{"ZA", "27", "0", 9, 9},
+	{"GR", "30", "", 10, 10},
+	{"NL", "31", "0", 9, 9},
+	{"BE", "32", "0", 8, 9},
+	{"FR", "33", "0", 9, 9},
+	{"ES", "34", "", 9, 9},
+	{"HU", "36", "06", 8, 9},
+	{"IT", "39", "", 6, 11},
+	{"RO", "40", "0", 9, 9},
+	{"CH", "41", "0", 9, 9},
+	{"AT", "43", "0", 4, 13},
+	{"GB", "44", "0", 9, 10},
+	{"DK", "45", "", 8, 8},
+	{"SE", "46", "0", 7, 9},
+	{"NO", "47", "", 8, 8},
+	{"PL", "48", "", 9, 9},
+	{"DE", "49", "0", 6, 13},
+	{"PE", "51", "0", 8, 9},
+	{"MX", "52", "", 10, 10},
+	{"AR", "54", "0", 10, 11},
+	{"BR", "55", "0", 10, 11},
+	{"CL", "56", "", 9, 9},
+	{"CO", "57", "", 10, 10},
+	{"MY", "60", "0", 8, 10},
+	{"AU", "61", "0", 9, 9},
+	{"ID", "62", "0", 8, 12},
+	{"PH", "63", "0", 8, 10},
+	{"NZ", "64", "0", 8, 10},
+	{"SG", "65", "", 8, 8},
+	{"TH", "66", "0", 8, 9},
+	{"JP", "81", "0", 9, 10},
+	{"KR", "82", "0", 8, 10},
+	{"VN", "84", "0", 9, 10},
+	{"CN", "86", "0", 9, 11},
+	{"TR", "90", "0", 10, 10},
+	{"IN", "91", "0", 10, 10},
+	{"PK", "92", "0", 9, 10},
+	{"MA", "212", "0", 9, 9},
+	{"DZ", "213", "0", 8, 9},
+	{"TN", "216", "", 8, 8},
+	{"NG", "234", "0", 8, 10},
+	{"KE", "254", "0", 9, 9},
+	{"PT", "351", "", 9, 9},
+	{"LU", "352", "", 4, 11},
+	{"IE", "353", "0", 7, 9},
+	{"FI", "358", "0", 5, 12},
+	{"UA", "380", "0", 9, 9},
+	{"CZ", "420", "", 9, 9},
+	{"HK", "852", "", 8, 8},
+	{"TW", "886", "0", 8, 9},
+	{"LB", "961", "0", 7, 8},
+	{"SA", "966", "0", 9, 9},
+	{"AE", "971", "0", 8, 9},
+	{"IL", "972", "0", 8, 9},
+	{"QA", "974", "", 8, 8},
+}
+
+var (
+	// countries indexes plans by ISO code.
+	countries = make(map[string]country, len(plans))
+	// callingCodes lists the countries of each calling code, main first.
+	callingCodes = make(map[string][]string)
+)
+
+func init() {
+	for _, c := range plans {
+		countries[c.iso] = c
+		callingCodes[c.code] = append(callingCodes[c.code], c.iso)
+	}
+}
@@ -0,0 +1,176 @@
+// Package phonenumber validates phone numbers and normalizes them to E.164
+// (+33612345678), the format PocketPing stores in Session.UserPhone.
+//
+// Numbers written in international form (+33 6 12 34 56 78, 0033 6 ...)
+// carry their country; national numbers (06 12 34 56 78) need a default
+// country, whose trunk prefix is dropped. The country is inferred from the
+// calling code. Lengths are checked per country for the countries in the
+// package's table, and against E.164's limits for the others.
+package phonenumber
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors returned by Parse. They are written to be shown to the visitor.
+var (
+	ErrEmpty             = errors.New("phone number is empty")
+	ErrInvalidCharacters = errors.New("phone number contains invalid characters")
+	ErrMissingCountry    = errors.New("phone number needs a country code, e.g. +33")
+	ErrUnknownCountry    = errors.New("unknown country")
+	ErrTooShort          = errors.New("phone number is too short")
+	ErrTooLong           = errors.New("phone number is too long")
+)
+
+// E.164 allows at most 15 digits, calling code included. Shorter than 8 is
+// not a real subscriber number anywhere.
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+// Number is a parsed phone number.
+type Number struct {
+	// E164 is the normalized number, e.g. "+33612345678".
+	E164 string
+	// CallingCode is the country calling code without "+", e.g. "33".
+	// Empty when the calling code isn't in the package's table.
+	CallingCode string
+	// National is the national significant number, e.g. "612345678".
+	National string
+	// Country is the ISO 3166-1 alpha-2 code, e.g. "FR". Calling codes
+	// shared by several countries resolve to the default country when it
+	// shares the code, else to the main one (US for +1, RU for +7).
+	Country string
+}
+
+// Parse validates number and normalizes it. defaultCountry (ISO 3166-1
+// alpha-2, any case) is used for numbers without a calling code and may be
+// empty.
+func Parse(number, defaultCountry string) (Number, error) {
+	digits, international, err := clean(number)
+	if err != nil {
+		return Number{}, err
+	}
+
+	var fallback *country
+	if defaultCountry != "" {
+		c, ok := countries[strings.ToUpper(strings.TrimSpace(defaultCountry))]
+		if !ok {
+			return Number{}, ErrUnknownCountry
+		}
+		fallback = &c
+	}
+
+	if !international {
+		if fallback == nil {
+			return Number{}, ErrMissingCountry
+		}
+		national := digits
+		if fallback.trunk != "" {
+			national = strings.TrimPrefix(national, fallback.trunk)
+		}
+		return build(*fallback, national)
+	}
+
+	for n := 1; n <= 3 && n < len(digits); n++ {
+		code := digits[:n]
+		candidates, ok := callingCodes[code]
+		if !ok {
+			continue
+		}
+		c := countries[candidates[0]]
+		if fallback != nil && fallback.code == code {
+			c = *fallback
+		}
+		return build(c, digits[n:])
+	}
+
+	// Unknown calling code: only E.164's limits apply.
+	if err := checkLength(len(digits), minDigits, maxDigits); err != nil {
+		return Number{}, err
+	}
+	return Number{E164: "+" + digits, National: digits}, nil
+}
+
+// Normalize returns number in E.164 format.
+func Normalize(number, defaultCountry string) (string, error) {
+	parsed, err := Parse(number, defaultCountry)
+	if err != nil {
+		return "", err
+	}
+	return parsed.E164, nil
+}
+
+// Valid reports whether number parses.
+func Valid(number, defaultCountry string) bool {
+	_, err := Parse(number, defaultCountry)
+	return err == nil
+}
+
+// CallingCode returns the calling code of an ISO 3166-1 alpha-2 country,
+// e.g. "33" for "FR".
+func CallingCode(isoCountry string) (string, bool) {
+	c, ok := countries[strings.ToUpper(strings.TrimSpace(isoCountry))]
+	return c.code, ok
+}
+
+// clean strips formatting characters and the international prefix ("+" or
+// "00"), returning the digits and whether the number had the prefix.
+func clean(number string) (digits string, international bool, err error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return "", false, ErrEmpty
+	}
+
+	var b strings.Builder
+	for i, r := range number {
+		switch {
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		case r == '+' && i == 0:
+			international = true
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			return "", false, ErrInvalidCharacters
+		}
+	}
+	digits = b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		digits, international = digits[2:], true
+	}
+	if digits == "" {
+		return "", false, ErrEmpty
+	}
+	return digits, international, nil
+}
+
+// build checks the national number's length for c and assembles the Number.
+func build(c country, national string) (Number, error) {
+	if national == "" {
+		return Number{}, ErrTooShort
+	}
+	if err := checkLength(len(national), c.min, c.max); err != nil {
+		return Number{}, err
+	}
+	if err := checkLength(len(c.code)+len(national), minDigits, maxDigits); err != nil {
+		return Number{}, err
+	}
+	return Number{
+		E164:        "+" + c.code + national,
+		CallingCode: c.code,
+		National:    national,
+		Country:     c.iso,
+	}, nil
+}
+
+func checkLength(n, min, max int) error {
+	switch {
+	case n < min:
+		return ErrTooShort
+	case n > max:
+		return ErrTooLong
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package phonenumber
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		number, country string
+		want            Number
+	}{
+		{"+33 6 12 34 56 78", "", Number{"+33612345678", "33", "612345678", "FR"}},
+		{"0033 (6) 12-34-56-78", "", Number{"+33612345678", "33", "612345678", "FR"}},
+		{"06 12 34 56 78", "fr", Number{"+33612345678", "33", "612345678", "FR"}},
+		{"+44 7911 123456", "FR", Number{"+447911123456", "44", "7911123456", "GB"}},
+		{"(415) 555-0100", "US", Number{"+14155550100", "1", "4155550100", "US"}},
+		{"1 415 555 0100", "US", Number{"+14155550100", "1", "4155550100", "US"}},
+		{"+1 604 555 0100", "CA", Number{"+16045550100", "1", "6045550100", "CA"}},
+		{"+1 604 555 0100", "", Number{"+16045550100", "1", "6045550100", "US"}},
+		{"8 912 345 67 89", "RU", Number{"+79123456789", "7", "9123456789", "RU"}},
+		{"06 20 123 4567", "HU", Number{"+36201234567", "36", "201234567", "HU"}},
+		{"02 1234 5678", "IT", Number{"+390212345678", "39", "0212345678", "IT"}},
+		{"+355 69 123 4567", "", Number{E164: "+355691234567", National: "355691234567"}},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.number, tc.country)
+		if err != nil {
+			t.Errorf("Parse(%q, %q): %v", tc.number, tc.country, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Parse(%q, %q) = %+v, want %+v", tc.number, tc.country, got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		number, country string
+		want            error
+	}{
+		{"  ", "", ErrEmpty},
+		{"+", "", ErrEmpty},
+		{"+33 6 12 ab", "", ErrInvalidCharacters},
+		{"6+12345678", "", ErrInvalidCharacters},
+		{"06 12 34 56 78", "", ErrMissingCountry},
+		{"06 12 34 56 78", "XX", ErrUnknownCountry},
+		{"+33 6 12 34", "", ErrTooShort},
+		{"+33 6 12 34 56 78 90", "", ErrTooLong},
+		{"+355 12", "", ErrTooShort},
+		{"+355 1234 5678 9012 34", "", ErrTooLong},
+	}
+	for _, tc := range cases {
+		if _, err := Parse(tc.number, tc.country); !errors.Is(err, tc.want) {
+			t.Errorf("Parse(%q, %q) = %v, want %v", tc.number, tc.country, err, tc.want)
+		}
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	if got, err := Normalize("06 12 34 56 78", "FR"); err != nil || got != "+33612345678" {
+		t.Errorf("Normalize = %q, %v", got, err)
+	}
+	if !Valid("+33612345678", "") || Valid("12", "FR") {
+		t.Error("Valid gave the wrong answer")
+	}
+	if code, ok := CallingCode("gb"); !ok || code != "44" {
+		t.Errorf("CallingCode(gb) = %q, %v", code, ok)
+	}
+	if _, ok := CallingCode("XX"); ok {
+		t.Error("CallingCode(XX) found")
+	}
+}
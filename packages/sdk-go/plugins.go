@@ -0,0 +1,157 @@
+package pocketping
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Plugin bundles the pieces of an integration (a CRM, an AI provider, an
+// analytics tool...) so it can ship as one module. Embed BasePlugin and
+// override what the plugin provides.
+type Plugin interface {
+	// Name returns the unique name of the plugin. Its routes are served
+	// under it (see HandlePlugins).
+	Name() string
+
+	// Bridges returns the bridges the plugin notifies.
+	Bridges() []Bridge
+
+	// Middleware returns message middleware, added as with Use.
+	Middleware() []MessageMiddleware
+
+	// EventHandlers returns custom event handlers by event name, subscribed
+	// as with OnEvent.
+	EventHandlers() map[string]CustomEventHandler
+
+	// Routes returns HTTP handlers by path, e.g. "/webhook" for a CRM's
+	// callbacks.
+	Routes() map[string]http.Handler
+}
+
+// BasePlugin provides empty defaults for the Plugin methods.
+type BasePlugin struct {
+	PluginName string
+}
+
+// Name returns the plugin name.
+func (p *BasePlugin) Name() string {
+	return p.PluginName
+}
+
+// Bridges returns no bridges by default.
+func (p *BasePlugin) Bridges() []Bridge {
+	return nil
+}
+
+// Middleware returns no middleware by default.
+func (p *BasePlugin) Middleware() []MessageMiddleware {
+	return nil
+}
+
+// EventHandlers returns no event handlers by default.
+func (p *BasePlugin) EventHandlers() map[string]CustomEventHandler {
+	return nil
+}
+
+// Routes returns no routes by default.
+func (p *BasePlugin) Routes() map[string]http.Handler {
+	return nil
+}
+
+var _ Plugin = (*BasePlugin)(nil)
+
+// PluginFactory builds a plugin for one PocketPing.
+type PluginFactory func() Plugin
+
+type registeredPlugin struct {
+	name    string
+	factory PluginFactory
+}
+
+// registeredPlugins holds the plugin factories registered with
+// RegisterPlugin.
+var registeredPlugins struct {
+	mu      sync.Mutex
+	plugins []registeredPlugin
+}
+
+// RegisterPlugin makes a plugin part of every PocketPing created with New
+// from then on, usually from the init function of the plugin's package.
+// New calls factory for a fresh plugin, so instances don't share bridges:
+//
+//	func init() {
+//		pocketping.RegisterPlugin("hubspot", func() pocketping.Plugin {
+//			return &hubspot.Plugin{APIKey: os.Getenv("HUBSPOT_API_KEY")}
+//		})
+//	}
+//
+// Plugins for a single PocketPing go in Config.Plugins instead. It panics
+// if a plugin of the same name is already registered.
+func RegisterPlugin(name string, factory PluginFactory) {
+	registeredPlugins.mu.Lock()
+	defer registeredPlugins.mu.Unlock()
+	for _, p := range registeredPlugins.plugins {
+		if p.name == name {
+			panic(fmt.Sprintf("pocketping: plugin %q registered twice", name))
+		}
+	}
+	registeredPlugins.plugins = append(registeredPlugins.plugins, registeredPlugin{name: name, factory: factory})
+}
+
+// installPlugins adds the registered plugins and Config.Plugins to pp, in
+// that order; a plugin in Config.Plugins replaces a registered one of the
+// same name, which isn't built. Their bridges are initialized by Start, like
+// Config.Bridges.
+func (pp *PocketPing) installPlugins() {
+	registeredPlugins.mu.Lock()
+	registered := append([]registeredPlugin(nil), registeredPlugins.plugins...)
+	registeredPlugins.mu.Unlock()
+
+	configured := make(map[string]Plugin, len(pp.config.Plugins))
+	for _, plugin := range pp.config.Plugins {
+		configured[plugin.Name()] = plugin
+	}
+	var plugins []Plugin
+	for _, r := range registered {
+		if plugin, ok := configured[r.name]; ok {
+			plugins = append(plugins, plugin)
+			delete(configured, r.name)
+			continue
+		}
+		plugins = append(plugins, r.factory())
+	}
+	for _, plugin := range pp.config.Plugins {
+		if _, ok := configured[plugin.Name()]; ok {
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	pp.pluginRoutes = http.NewServeMux()
+	for _, plugin := range plugins {
+		pp.plugins = append(pp.plugins, plugin.Name())
+		pp.bridges = append(pp.bridges, plugin.Bridges()...)
+		pp.Use(plugin.Middleware()...)
+		for eventName, handler := range plugin.EventHandlers() {
+			pp.OnEvent(eventName, handler)
+		}
+		for path, handler := range plugin.Routes() {
+			pp.pluginRoutes.Handle("/"+plugin.Name()+"/"+strings.TrimPrefix(path, "/"), handler)
+		}
+	}
+}
+
+// Plugins returns the names of the plugins installed in pp.
+func (pp *PocketPing) Plugins() []string {
+	return append([]string(nil), pp.plugins...)
+}
+
+// HandlePlugins serves the plugins' routes, each under its plugin name:
+// route "/webhook" of plugin "hubspot" is "/hubspot/webhook". Mount it
+// with the prefix stripped:
+//
+//	mux.Handle("/pocketping/plugins/", http.StripPrefix("/pocketping/plugins", pp.HandlePlugins()))
+func (pp *PocketPing) HandlePlugins() http.Handler {
+	return pp.pluginRoutes
+}
@@ -0,0 +1,104 @@
+package pocketping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// crmPlugin bundles one of each plugin piece.
+type crmPlugin struct {
+	BasePlugin
+	bridge *recordingBridge
+	events []string
+}
+
+func (p *crmPlugin) Bridges() []Bridge {
+	return []Bridge{p.bridge}
+}
+
+func (p *crmPlugin) Middleware() []MessageMiddleware {
+	return []MessageMiddleware{func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+			req.SetContent(strings.ToUpper(req.Content()))
+			return next(ctx, req)
+		}
+	}}
+}
+
+func (p *crmPlugin) EventHandlers() map[string]CustomEventHandler {
+	return map[string]CustomEventHandler{"signup": func(event CustomEvent, session *Session) {
+		p.events = append(p.events, event.Name)
+	}}
+}
+
+func (p *crmPlugin) Routes() map[string]http.Handler {
+	return map[string]http.Handler{"/webhook": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})}
+}
+
+func newCRMPlugin(name string) *crmPlugin {
+	return &crmPlugin{BasePlugin: BasePlugin{PluginName: name}, bridge: newRecordingBridge(name)}
+}
+
+func TestPluginInstall(t *testing.T) {
+	ctx := context.Background()
+	plugin := newCRMPlugin("crm")
+	pp := New(Config{Plugins: []Plugin{plugin}})
+	if err := pp.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	sendVisitorMessage(t, pp, sessionID, "hello")
+	pp.dispatcher.wait()
+	if len(plugin.bridge.messages) != 1 || plugin.bridge.messages[0].Content != "HELLO" {
+		t.Errorf("bridge got %+v", plugin.bridge.messages)
+	}
+
+	pp.HandleCustomEvent(ctx, sessionID, CustomEvent{Name: "signup"})
+	if len(plugin.events) != 1 {
+		t.Errorf("events = %v", plugin.events)
+	}
+
+	rec := httptest.NewRecorder()
+	pp.HandlePlugins().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/crm/webhook", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("route status = %d", rec.Code)
+	}
+	if names := pp.Plugins(); len(names) != 1 || names[0] != "crm" {
+		t.Errorf("plugins = %v", names)
+	}
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	RegisterPlugin("crm", func() Plugin { return newCRMPlugin("crm") })
+	t.Cleanup(func() { registeredPlugins.plugins = nil })
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a plugin twice didn't panic")
+		}
+	}()
+
+	pp := New(Config{})
+	if names := pp.Plugins(); len(names) != 1 || len(pp.bridges) != 1 {
+		t.Errorf("plugins = %v, bridges = %d", names, len(pp.bridges))
+	}
+	// Each instance gets its own plugin
+	if other := New(Config{}); other.bridges[0] == pp.bridges[0] {
+		t.Error("instances share the registered plugin's bridge")
+	}
+
+	// Config.Plugins replaces a registered plugin of the same name
+	override := newCRMPlugin("crm")
+	pp = New(Config{Plugins: []Plugin{override, newCRMPlugin("analytics")}})
+	if names := pp.Plugins(); len(names) != 2 || pp.bridges[0] != Bridge(override.bridge) {
+		t.Errorf("plugins = %v", names)
+	}
+
+	RegisterPlugin("crm", func() Plugin { return newCRMPlugin("crm") })
+}
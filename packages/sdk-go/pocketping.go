@@ -11,30 +11,55 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Common errors
+// Common errors. Those a client can cause are *Error values, with a code
+// and HTTP status (see AsError).
 var (
-	ErrSessionNotFound    = errors.New("session not found")
-	ErrIdentityIDRequired = errors.New("identity.id is required")
-	ErrContentTooLong     = errors.New("message content exceeds maximum length")
-	ErrMessageNotFound    = errors.New("message not found")
-	ErrUnauthorized       = errors.New("unauthorized: can only edit/delete own messages")
-	ErrMessageDeleted     = errors.New("cannot edit deleted message")
-	ErrNoContent          = errors.New("content cannot be empty")
-	ErrInvalidMimeType    = errors.New("invalid mime type")
-	ErrFileTooLarge       = errors.New("file too large")
-	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrSessionNotFound    = newError("session_not_found", http.StatusNotFound, "session not found")
+	ErrIdentityIDRequired = newError("identity_id_required", http.StatusBadRequest, "identity.id is required")
+	ErrContentTooLong     = newError("content_too_long", http.StatusRequestEntityTooLarge, "message content exceeds maximum length")
+	ErrMessageNotFound    = newError("message_not_found", http.StatusNotFound, "message not found")
+	ErrUnauthorized       = newError("forbidden", http.StatusForbidden, "unauthorized: can only edit/delete own messages")
+	ErrMessageDeleted     = newError("message_deleted", http.StatusConflict, "cannot edit deleted message")
+	ErrNoContent          = newError("no_content", http.StatusBadRequest, "content cannot be empty")
+	ErrInvalidMimeType    = newError("invalid_mime_type", http.StatusUnsupportedMediaType, "invalid mime type")
+	ErrFileTooLarge       = newError("file_too_large", http.StatusRequestEntityTooLarge, "file too large")
+	ErrAttachmentNotFound = newError("attachment_not_found", http.StatusNotFound, "attachment not found")
+	// ErrAttachmentURLRequired is returned by SendOperatorMessage when an
+	// attachment passed to WithAttachments has neither a URL nor Data.
+	ErrAttachmentURLRequired = newError("attachment_url_required", http.StatusBadRequest, "attachment URL is required")
+	// ErrInvalidLocation is returned by HandleMessage when a shared
+	// location's coordinates are out of range.
+	ErrInvalidLocation = newError("invalid_location", http.StatusBadRequest, "invalid location")
+	// ErrTicketCreatorNotConfigured is returned by CreateTicket when
+	// Config.TicketCreator is nil.
+	ErrTicketCreatorNotConfigured = newError("not_configured", http.StatusNotFound, "no ticket creator configured")
+	// ErrThreadNotFound is returned when a message names a thread whose root
+	// message isn't in the session.
+	ErrThreadNotFound = newError("thread_not_found", http.StatusNotFound, "thread not found")
+	// ErrAttachmentsDisabled is returned by HandleUploadRequest when the
+	// session's FlagAttachments feature flag is off.
+	ErrAttachmentsDisabled = newError("attachments_disabled", http.StatusForbidden, "attachments are disabled for this session")
 	// ErrInvalidCsatScore is returned when a CSAT score is not an integer 1-5.
-	ErrInvalidCsatScore = errors.New("CSAT score must be an integer 1-5")
+	ErrInvalidCsatScore = newError("invalid_csat_score", http.StatusBadRequest, "CSAT score must be an integer 1-5")
 	// ErrListSessionsUnsupported is returned by GetStats when the storage adapter
 	// does not implement StorageWithListSessions.
 	ErrListSessionsUnsupported = errors.New(
 		"GetStats requires Storage to implement listSessions (ListSessions). " +
 			"The bundled MemoryStorage implements it; add it to your custom storage adapter to use stats.")
+	// ErrSearchUnsupported is returned by SearchMessages when the storage adapter
+	// does not implement StorageWithSearch.
+	ErrSearchUnsupported = errors.New("SearchMessages requires Storage to implement StorageWithSearch")
+	// ErrCountUnsupported is returned by CountSessions/CountMessages when the
+	// storage adapter implements neither StorageWithCounts nor
+	// StorageWithListSessions.
+	ErrCountUnsupported = errors.New("counting requires Storage to implement StorageWithCounts or StorageWithListSessions")
 )
 
 // Config holds the configuration for PocketPing.
@@ -45,15 +70,52 @@ type Config struct {
 	// Notification bridges (Telegram, Discord, etc.)
 	Bridges []Bridge
 
+	// DryRun puts every built-in bridge in dry-run mode: outgoing payloads are
+	// formatted and logged, but the platform APIs are never called. Applied
+	// when Start initializes the bridges; they can also be put in dry-run mode
+	// one by one (e.g. WithTelegramDryRun).
+	DryRun bool
+
+	// ClockSkew normalizes the platform times of operator edits and
+	// deletions handled without a WebhookConfig or DiscordGatewayConfig
+	// callback, which normalize their own. Defaults to a detector with
+	// DefaultClockSkewThreshold; pass the same one to them to see every
+	// source in its Stats.
+	ClockSkew *ClockSkewDetector
+
 	// Welcome message shown to new visitors
 	WelcomeMessage string
 
+	// WelcomeFlows are multi-step welcome sequences with quick replies,
+	// targeted by page. The first flow matching a new session's page is used
+	// instead of WelcomeMessage; see WelcomeFlow.
+	WelcomeFlows []WelcomeFlow
+
+	// Workflows are conversation templates (collect email, ask topic,
+	// route...) run on a session with StartWorkflow; see Workflow.
+	Workflows []Workflow
+
 	// Callback when a new session is created
 	OnNewSession SessionHandler
 
 	// Callback when a message is received
 	OnMessage MessageHandler
 
+	// Plugins are installed by New, after those registered with
+	// RegisterPlugin; see Plugin.
+	Plugins []Plugin
+
+	// BeforeSessionCreate, when set, runs before a new session is stored and
+	// may change it; an error refuses the connection, and HandleConnect
+	// returns it. OnNewSession only runs once the session exists.
+	BeforeSessionCreate BeforeSessionCreateHook
+
+	// BeforeMessageSave, when set, runs before a sent or edited message, an
+	// AI reply or an operator's bridge edit is stored and may change it; an
+	// error refuses the message, and HandleMessage, HandleEditMessage or
+	// HandleOperatorEditMessage returns it.
+	BeforeMessageSave BeforeMessageSaveHook
+
 	// Callback when a custom event is received from widget
 	OnEvent CustomEventHandler
 
@@ -87,6 +149,24 @@ type Config struct {
 	// TrackedElements to return in connect response
 	TrackedElements []TrackedElement
 
+	// WidgetSettings is sent to the widget as ConnectResponse.ServerConfig so
+	// its appearance can change without a frontend deploy.
+	WidgetSettings *WidgetSettings
+
+	// ProjectWidgetSettings overrides WidgetSettings per ConnectRequest.ProjectID.
+	// Only the fields set in the override replace the defaults.
+	ProjectWidgetSettings map[string]*WidgetSettings
+
+	// FeatureFlags are the default flags sent in ConnectResponse.FeatureFlags.
+	FeatureFlags map[string]bool
+
+	// FlagResolver resolves flags per session (plan, tenant, rollout
+	// percentage). Its result is applied over FeatureFlags.
+	FlagResolver FlagResolver
+
+	// Experiments are A/B experiments sessions are assigned to; see Experiment.
+	Experiments []Experiment
+
 	// IpFilter configuration for IP filtering
 	IpFilter *IpFilterConfig
 
@@ -105,6 +185,12 @@ type Config struct {
 	// Defaults to DefaultUploadBaseURL when empty.
 	UploadBaseURL string
 
+	// AttachmentStore, when set, keeps the files visitors upload with
+	// HandleUploadAttachment, and hands them to the bridges implementing
+	// BridgeWithAttachments. It also keeps the files operators send from
+	// the bridges, served to the widget by HandleAttachmentFile.
+	AttachmentStore AttachmentStore
+
 	// AIProvider, when set, enables the AI fallback: an automatic AI reply is
 	// generated for visitor messages when no operator is online and the
 	// takeover delay has elapsed.
@@ -118,6 +204,285 @@ type Config struct {
 	// when no operator is online. Defaults to DefaultAITakeoverDelay (300s).
 	// A value <= 0 means the AI takes over immediately.
 	AITakeoverDelay int
+
+	// DisableAIMirroring stops AI replies from being posted to bridges. They
+	// are still saved and sent to the visitor.
+	DisableAIMirroring bool
+
+	// AIActions are the structured actions the AI may request when the
+	// provider implements ActionAIProvider; see AIAction.
+	AIActions []AIAction
+
+	// OnAIAction receives an audit record for every AI action call, executed
+	// or not.
+	OnAIAction AIActionAuditor
+
+	// Deduper suppresses duplicate message notifications per bridge, keyed
+	// on message ID + bridge destination (see BridgeWithDestination).
+	// Defaults to an in-memory deduper with DefaultDedupeTTL; use a
+	// RedisDeduper to dedupe across processes and with a bridge-server.
+	Deduper Deduper
+
+	// BridgeRetry, when set, retries failed bridge notifications with
+	// exponential backoff, and hands those that still fail to its
+	// OnDeadLetter. Nil tries each notification once.
+	BridgeRetry *RetryPolicy
+
+	// RateLimit, when set, limits visitor messages per session and per IP
+	// address; HandleMessage refuses those over it with a *RateLimitError.
+	RateLimit *RateLimitConfig
+
+	// RegionResolver sets Session.Region when a session is created (or first
+	// seen without one), e.g. from a GeoIP lookup. Defaults to
+	// DefaultRegionResolver.
+	RegionResolver RegionResolver
+
+	// CountryRegions maps ISO country codes (Metadata.Country) to regions for
+	// DefaultRegionResolver, e.g. {"FR": "eu", "US": "us"}.
+	CountryRegions map[string]string
+
+	// DefaultRegion is used by DefaultRegionResolver when the country is
+	// unknown or unmapped.
+	DefaultRegion string
+
+	// TicketCreator, when set, enables CreateTicket (and the /ticket operator
+	// command): the transcript is handed off to a ticketing system.
+	TicketCreator TicketCreator
+
+	// Forms are the forms operators can send by name, with SendNamedForm or
+	// the /form <name> operator command. Names are lower case.
+	Forms map[string]FormDefinition
+
+	// CalendarProvider, when set, enables callback scheduling: the visitor
+	// picks a slot from its availability (see OfferCallback).
+	CalendarProvider CalendarProvider
+
+	// CallbackWindow is how far ahead callback slots are offered.
+	// Defaults to DefaultCallbackWindow (7 days).
+	CallbackWindow time.Duration
+
+	// MaxCallbacksPerSession caps the upcoming callbacks a session can
+	// book. Defaults to DefaultMaxCallbacksPerSession (1).
+	MaxCallbacksPerSession int
+
+	// PaymentProvider, when set, enables payment requests (see
+	// RequestPayment and HandlePaymentWebhook).
+	PaymentProvider PaymentProvider
+
+	// ContextProvider, when set, fetches identified visitors' account context
+	// (plan, recent orders...) for new-session notifications and the
+	// /context operator command.
+	ContextProvider ContextProvider
+
+	// ContextCacheTTL is how long a visitor's context is cached.
+	// Defaults to DefaultContextCacheTTL (5 minutes).
+	ContextCacheTTL time.Duration
+
+	// ContextCacheSize bounds the contexts cached, evicting the least
+	// recently used. Defaults to DefaultContextCacheSize.
+	ContextCacheSize int
+
+	// ContextTimeout bounds each ContextProvider lookup.
+	// Defaults to DefaultContextTimeout (2 seconds).
+	ContextTimeout time.Duration
+
+	// EnrichmentProvider, when set, looks up visitors by email in the
+	// background when they identify: the company, role, avatar and location
+	// it finds are merged into Identity.Extra and posted to the bridges.
+	EnrichmentProvider EnrichmentProvider
+
+	// EnrichmentCacheTTL is how long a lookup is cached per email.
+	// Defaults to DefaultEnrichmentCacheTTL (24 hours).
+	EnrichmentCacheTTL time.Duration
+
+	// EnrichmentCacheSize bounds the lookups cached, evicting the least
+	// recently used. Defaults to DefaultEnrichmentCacheSize.
+	EnrichmentCacheSize int
+
+	// EnrichmentTimeout bounds each EnrichmentProvider lookup.
+	// Defaults to DefaultEnrichmentTimeout (2 seconds).
+	EnrichmentTimeout time.Duration
+
+	// EnrichmentRateLimit is the most EnrichmentProvider lookups per
+	// minute overall; cache hits don't count. Visitors over it aren't
+	// enriched. Zero means no overall limit; each visitor IP is limited to
+	// a few lookups a minute regardless.
+	EnrichmentRateLimit int
+
+	// AtRiskThreshold, when above zero, enables at-risk flagging: sessions
+	// are scored after each visitor message, and bridges are notified when
+	// the score (0..1) reaches it. See EvaluateRisk.
+	AtRiskThreshold float64
+
+	// RiskScorer scores sessions for at-risk flagging.
+	// Defaults to DefaultRiskScorer.
+	RiskScorer RiskScorer
+
+	// Callback when a session is flagged at risk
+	OnAtRisk AtRiskHandler
+
+	// MentionRouter picks operators to @-mention in the bridge channels on
+	// new sessions and visitor messages (e.g. the assignee).
+	MentionRouter MentionRouter
+
+	// TagMentions maps routing tags (see TagSession) to the operators or
+	// groups to @-mention for sessions with that tag, e.g. "billing" to the
+	// billing team's Slack user group.
+	TagMentions map[string][]OperatorMention
+
+	// OperatorAuthenticator, when set, enables operator consoles: WebSockets
+	// that authenticate as an operator, receive every session's events and
+	// reply directly (see ConnectOperator).
+	OperatorAuthenticator OperatorAuthenticator
+
+	// LeaveMessageMode, when set, puts sessions that connect while no
+	// operator is online and the AI is disabled in leave-a-message mode: the
+	// widget shows LeaveMessagePrompt, and only the first visitor message is
+	// mirrored to the bridges, followed by a digest of the next ones.
+	LeaveMessageMode bool
+
+	// LeaveMessagePrompt is the welcome message in leave-a-message mode.
+	// Defaults to DefaultLeaveMessagePrompt.
+	LeaveMessagePrompt string
+
+	// LeaveMessageDigestDelay is how long messages are collected before the
+	// digest is posted. Defaults to DefaultLeaveMessageDigestDelay (5 minutes).
+	LeaveMessageDigestDelay time.Duration
+
+	// MessageBatchWindow, when set, batches each session's visitor messages
+	// into one bridge notification: they are mirrored once the visitor has
+	// been quiet for the window. The widget still gets every message at once.
+	MessageBatchWindow time.Duration
+
+	// InboxReadModel, when set, maintains the operator inbox read model
+	// (see PocketPing.Inbox) as sessions and messages come in.
+	InboxReadModel bool
+
+	// Broadcaster, when set, carries WebSocket events between app replicas,
+	// so BroadcastToSession reaches sockets connected to any of them. Use
+	// the same channel on every replica.
+	Broadcaster Broadcaster
+
+	// AffinitySecret, when set, signs the affinity token in each
+	// ConnectResponse, and turns on event replay with ReplayStore, or the
+	// storage if it implements StorageWithEventReplay. Use the same secret
+	// on every node.
+	AffinitySecret string
+
+	// ReplayStore, when set, buffers session events for replay instead of
+	// the storage, e.g. a RedisReplayStore shared by nodes whose storage
+	// can't. See AffinitySecret.
+	ReplayStore ReplayStore
+
+	// NodeID names this node in affinity tokens. Defaults to a random ID.
+	NodeID string
+
+	// SendQueueSize, when set, gives each WebSocket (visitor or operator
+	// console) a send queue of that many events, written by its own
+	// goroutine, so a slow client can't hold up broadcasts to others.
+	SendQueueSize int
+
+	// WriteTimeout is the write deadline for queued sends, on sockets with
+	// SetWriteDeadline. Defaults to DefaultWriteTimeout.
+	WriteTimeout time.Duration
+
+	// SlowClientPolicy says what to do when a send queue is full. Defaults
+	// to SlowClientDisconnect.
+	SlowClientPolicy SlowClientPolicy
+
+	// WebSocketCompression makes WebSocketUpgrader negotiate
+	// permessage-deflate.
+	WebSocketCompression bool
+
+	// AckTimeout is how long an operator message may go unacknowledged by
+	// the widget before UndeliveredMessages reports it. Defaults to
+	// DefaultAckTimeout.
+	AckTimeout time.Duration
+
+	// OfflineQueueSize, when set, keeps up to that many events per session
+	// while the session has no sockets on this node (e.g. during a tab
+	// switch or reconnect), and sends them to the next socket registered.
+	OfflineQueueSize int
+
+	// OfflineQueueMaxAge is how long queued events are kept. Defaults to
+	// DefaultOfflineQueueMaxAge.
+	OfflineQueueMaxAge time.Duration
+
+	// HeartbeatInterval, when set, pings every socket implementing
+	// PingableConn (see NewWebSocketConn) at that interval from Start, and
+	// reaps those that miss two pings in a row.
+	HeartbeatInterval time.Duration
+
+	// WaitQueue, when set, tells visitors waiting for a first operator reply
+	// their place in line and estimated wait, in ConnectResponse.Queue and
+	// "queue_update" WebSocket events.
+	WaitQueue *WaitQueueConfig
+
+	// SessionLifecycle, when set, closes sessions after a period of
+	// inactivity from Start and archives them; see SessionLifecycle.
+	SessionLifecycle *SessionLifecycle
+
+	// DisposableEmailPolicy says what HandleIdentify does with disposable
+	// email addresses. Defaults to DisposableEmailAllow.
+	DisposableEmailPolicy DisposableEmailPolicy
+
+	// DisposableEmailDomains are checked along with the built-in list of
+	// disposable email domains.
+	DisposableEmailDomains []string
+
+	// IdentityConflictPolicy says what HandleIdentify does when another
+	// visitor already identified with the same Identity.ID. Defaults to
+	// IdentityConflictAllow.
+	IdentityConflictPolicy IdentityConflictPolicy
+
+	// IdentitySecret, when set, verifies IdentifyRequest.UserHash. Keep it
+	// server-side: whoever has it can sign any Identity.ID.
+	IdentitySecret string
+
+	// Privacy minimizes the visitor metadata that is stored and sent to
+	// webhooks (IP truncation, user agent dropping). Nil keeps it all.
+	Privacy *PrivacyConfig
+
+	// RequireConsent gates tracking on the visitor's consent
+	// (ConnectRequest.Consent, GrantConsent). Until then, sessions keep only
+	// their language and time zone, skip region resolution (GeoIP) and
+	// element tracking, and webhooks get no metadata.
+	RequireConsent bool
+
+	// RequireOperatorTokens refuses SendOperatorMessage calls from the API
+	// (source bridge "" or OperatorTokenSource) without WithOperatorToken.
+	// Replies from bridges and operator consoles are authenticated there.
+	RequireOperatorTokens bool
+
+	// OnOperatorAudit receives an audit record when an operator token is
+	// issued, revoked or used to send a message.
+	OnOperatorAudit OperatorAuditor
+
+	// WarehouseStore, when set, enables the warehouse sink: new sessions,
+	// messages and custom events are written to it as NDJSON batches, for
+	// analytics pipelines (see FlushWarehouse).
+	WarehouseStore ObjectStore
+
+	// WarehousePrefix is prepended to the keys of batches and of the
+	// checkpoint (e.g. "pocketping/").
+	WarehousePrefix string
+
+	// WarehouseInterval is how often a batch is written.
+	// Defaults to DefaultWarehouseInterval (15 minutes).
+	WarehouseInterval time.Duration
+
+	// AnalyticsSink, when set, receives session, message and custom event
+	// rows in batches, for near-real-time dashboards (see ClickHouseSink and
+	// BigQuerySink).
+	AnalyticsSink AnalyticsSink
+
+	// AnalyticsBufferSize is how many events are batched before a write.
+	// Defaults to DefaultAnalyticsBufferSize (500).
+	AnalyticsBufferSize int
+
+	// AnalyticsFlushInterval is the longest an event waits for its batch.
+	// Defaults to DefaultAnalyticsFlushInterval (5 seconds).
+	AnalyticsFlushInterval time.Duration
 }
 
 // PocketPing is the main struct for handling chat sessions.
@@ -142,13 +507,88 @@ type PocketPing struct {
 	operatorActivityMu sync.RWMutex
 	operatorActivity   map[string]time.Time
 
-	// WebSocket connections (sessionID -> set of connections)
+	// WebSocket connections (sessionID -> connection -> its writer, a
+	// sendQueue when Config.SendQueueSize is set)
 	socketsMu      sync.RWMutex
-	sessionSockets map[string]map[WebSocketConn]struct{}
+	sessionSockets map[string]map[WebSocketConn]WebSocketConn
+	// Operator console connections (see ConnectOperator)
+	operatorSockets map[*OperatorConn]struct{}
+
+	// Message middleware (see Use)
+	middlewareMu sync.RWMutex
+	middleware   []MessageMiddleware
 
-	// Custom event handlers
+	// Installed plugins and their routes (see HandlePlugins)
+	plugins      []string
+	pluginRoutes *http.ServeMux
+
+	// Custom event handlers by event name
 	handlersMu    sync.RWMutex
-	eventHandlers map[string][]CustomEventHandler
+	eventHandlers map[string][]eventHandlerEntry
+	lastHandlerID EventSubscription
+
+	// Ordered, asynchronous bridge notifications
+	dispatcher *bridgeDispatcher
+	deduper    Deduper
+	// retrying counts notifications waiting to be retried (Config.BridgeRetry)
+	retrying atomic.Int64
+
+	// ContextProvider results by identity ID
+	contextCache contextCache
+
+	// EnrichmentProvider results by email
+	enrichments enrichmentCache
+
+	// Callback bookings under way and booked
+	bookings callbackBookings
+
+	// Leave-a-message digests by session ID
+	digests messageDigests
+
+	// Events for sessions without sockets (Config.OfflineQueueSize)
+	offline offlineQueues
+
+	// Canceled by Stop, ending retries in progress (Config.BridgeRetry)
+	stopped context.Context
+	stop    context.CancelFunc
+
+	// Closed by Stop to end the heartbeat (Config.HeartbeatInterval)
+	heartbeatStop chan struct{}
+
+	// Closed by Stop to end the inactivity checks (Config.SessionLifecycle),
+	// and by their goroutine once it has returned
+	lifecycleStop chan struct{}
+	lifecycleDone chan struct{}
+
+	// Visitor message rate limits (Config.RateLimit)
+	rateLimits rateLimiter
+
+	// Sessions awaiting a first operator reply (Config.WaitQueue), swept
+	// until waitQueueStop is closed
+	waitQueue     waitQueue
+	waitQueueStop chan struct{}
+
+	// Visitor messages waiting for Config.MessageBatchWindow, by session ID
+	batches messageBatches
+
+	// Operator inbox read model (nil unless Config.InboxReadModel)
+	inbox *Inbox
+
+	// Warehouse sink (nil unless Config.WarehouseStore)
+	warehouse *warehouseSink
+
+	// Analytics events waiting for Config.AnalyticsSink (nil unless set)
+	analytics *analyticsBuffer
+
+	// replicaID tells this process's broadcasts apart (see Broadcaster)
+	replicaID string
+
+	// Set by Drain; webhooks tracks webhook deliveries in flight.
+	// webhooksMu orders goWebhook's Add with Drain setting draining, so no
+	// delivery starts once Drain may be waiting
+	draining   atomic.Bool
+	webhooks   sync.WaitGroup
+	webhooksMu sync.Mutex
 
 	// HTTP client for webhooks
 	httpClient *http.Client
@@ -202,12 +642,18 @@ func New(config Config) *PocketPing {
 		aiTakeoverDelay = DefaultAITakeoverDelay
 	}
 
+	deduper := config.Deduper
+	if deduper == nil {
+		deduper = NewMemoryDeduper(DefaultDedupeTTL)
+	}
+
 	pp := &PocketPing{
 		config:            config,
 		storage:           storage,
-		bridges:           config.Bridges,
-		sessionSockets:    make(map[string]map[WebSocketConn]struct{}),
-		eventHandlers:     make(map[string][]CustomEventHandler),
+		bridges:           append([]Bridge(nil), config.Bridges...),
+		sessionSockets:    make(map[string]map[WebSocketConn]WebSocketConn),
+		operatorSockets:   make(map[*OperatorConn]struct{}),
+		eventHandlers:     make(map[string][]eventHandlerEntry),
 		maxAttachmentSize: maxAttachmentSize,
 		allowedMimeTypes:  allowedMimeTypes,
 		uploadBaseURL:     uploadBaseURL,
@@ -215,26 +661,72 @@ func New(config Config) *PocketPing {
 		aiSystemPrompt:    aiSystemPrompt,
 		aiTakeoverDelay:   aiTakeoverDelay,
 		operatorActivity:  make(map[string]time.Time),
+		dispatcher:        newBridgeDispatcher(),
+		deduper:           deduper,
+		replicaID:         newReplicaID(),
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
 	}
+	pp.stopped, pp.stop = context.WithCancel(context.Background())
+	if pp.config.ClockSkew == nil {
+		pp.config.ClockSkew = NewClockSkewDetector(0)
+	}
+
+	if config.InboxReadModel {
+		pp.inbox = newInbox()
+		if hooked, ok := storage.(StorageWithDeletionHook); ok {
+			hooked.OnSessionDeleted(pp.inbox.Remove)
+		}
+	}
+	if config.WarehouseStore != nil {
+		pp.warehouse = &warehouseSink{}
+	}
+	if config.AnalyticsSink != nil {
+		pp.analytics = &analyticsBuffer{full: make(chan struct{}, 1)}
+	}
+	pp.installPlugins()
 
 	return pp
 }
 
 // Start initializes PocketPing and all bridges.
 func (pp *PocketPing) Start(ctx context.Context) error {
+	for _, destination := range OverlappingBridges(pp.bridges) {
+		log.Printf("[PocketPing] Warning: several bridges post to %s; duplicate notifications are suppressed, so only one of them receives each message", destination)
+	}
+
 	for _, bridge := range pp.bridges {
 		if err := bridge.Init(ctx, pp); err != nil {
 			return fmt.Errorf("failed to init bridge %s: %w", bridge.Name(), err)
 		}
 	}
+	if err := pp.startBroadcaster(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to broadcasts: %w", err)
+	}
+	pp.startHeartbeat()
+	pp.startWaitQueue()
+	if err := pp.startLifecycle(); err != nil {
+		return err
+	}
+	pp.startWarehouse()
+	pp.startAnalytics()
 	return nil
 }
 
 // Stop gracefully shuts down PocketPing.
 func (pp *PocketPing) Stop(ctx context.Context) error {
+	pp.flushBatches(ctx)
+	pp.flushDigests(ctx)
+	pp.stop()
+	pp.stopHeartbeat()
+	pp.stopWaitQueue()
+	pp.stopLifecycle()
+	pp.stopWarehouse(ctx)
+	pp.stopAnalytics(ctx)
+	if pp.config.Broadcaster != nil {
+		_ = pp.config.Broadcaster.Close()
+	}
 	for _, bridge := range pp.bridges {
 		if err := bridge.Destroy(ctx); err != nil {
 			// Log but continue
@@ -255,6 +747,37 @@ func (pp *PocketPing) generateID() string {
 func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest) (*ConnectResponse, error) {
 	var session *Session
 
+	// A shared device hands over to a new visitor: nothing is resumed
+	if request.FreshSession {
+		if err := pp.endVisitorSessions(ctx, request); err != nil {
+			return nil, err
+		}
+		request = ConnectRequest{
+			VisitorID: pp.generateID(),
+			Metadata:  request.Metadata,
+			ProjectID: request.ProjectID,
+			ClientIP:  request.ClientIP,
+		}
+	}
+
+	// The server's view of the visitor's IP wins over the widget's
+	if request.ClientIP != "" {
+		metadata := SessionMetadata{}
+		if request.Metadata != nil {
+			metadata = *request.Metadata
+		}
+		metadata.IP = request.ClientIP
+		request.Metadata = &metadata
+	}
+
+	// Privacy settings apply before anything is stored
+	request.Metadata = pp.minimizeMetadata(request.Metadata)
+
+	// An affinity token resumes its session, whichever node issued it
+	if request.SessionID == "" {
+		request.SessionID = pp.resumeSessionID(request)
+	}
+
 	// Try to resume existing session by sessionID
 	if request.SessionID != "" {
 		s, err := pp.storage.GetSession(ctx, request.SessionID)
@@ -273,7 +796,29 @@ func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest)
 		session = s
 	}
 
+	// Ended sessions aren't resumed
+	if session != nil && session.EndedAt != nil {
+		session = nil
+	}
+
+	// Until the visitor consents, only what the chat needs is kept
+	if pp.config.RequireConsent && !request.Consent && (session == nil || !session.Consent) {
+		request.Metadata = consentlessMetadata(request.Metadata)
+	}
+
 	// Create new session if needed
+	if session == nil && pp.draining.Load() {
+		return nil, ErrDraining
+	}
+	if session == nil {
+		clientIP := request.ClientIP
+		if clientIP == "" && request.Metadata != nil {
+			clientIP = request.Metadata.IP
+		}
+		if err := pp.checkConnectRateLimit(clientIP); err != nil {
+			return nil, err
+		}
+	}
 	if session == nil {
 		session = &Session{
 			ID:             pp.generateID(),
@@ -284,6 +829,25 @@ func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest)
 			AIActive:       false,
 			Metadata:       request.Metadata,
 			Identity:       request.Identity,
+			ProjectID:      request.ProjectID,
+			LeaveMessage:   pp.leaveMessageActive(),
+		}
+		if request.Consent {
+			session.Consent = true
+			session.ConsentAt = &session.CreatedAt
+		}
+		if pp.hasConsent(session) {
+			session.Region = pp.resolveRegion(ctx, session)
+		} else {
+			session.Region = pp.config.DefaultRegion
+		}
+		pp.assignExperiments(session)
+		pp.startWelcomeFlow(session)
+
+		if pp.config.BeforeSessionCreate != nil {
+			if err := pp.config.BeforeSessionCreate(ctx, session, request); err != nil {
+				return nil, err
+			}
 		}
 
 		if err := pp.storage.CreateSession(ctx, session); err != nil {
@@ -292,6 +856,9 @@ func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest)
 
 		// Notify bridges about new session
 		pp.notifyBridgesNewSession(ctx, session)
+		pp.notifyOperators(session.ID, WebSocketEvent{Type: "new_session", Data: session})
+		pp.inbox.addSession(session)
+		pp.trackSessionStarted(session)
 
 		// Callback
 		if pp.config.OnNewSession != nil {
@@ -304,7 +871,7 @@ func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest)
 		if request.Metadata != nil {
 			if session.Metadata != nil {
 				// Preserve server-side fields
-				if session.Metadata.IP != "" {
+				if session.Metadata.IP != "" && request.ClientIP == "" {
 					request.Metadata.IP = session.Metadata.IP
 				}
 				if session.Metadata.Country != "" {
@@ -318,17 +885,48 @@ func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest)
 			needsUpdate = true
 		}
 
+		// Consent given from the widget since the last connect
+		if request.Consent && !session.Consent {
+			pp.applyConsent(ctx, session, nil)
+			needsUpdate = true
+		}
+
 		// Update identity if provided
 		if request.Identity != nil {
 			session.Identity = request.Identity
 			needsUpdate = true
 		}
 
+		// The widget moved to another project's pages
+		if request.ProjectID != "" && request.ProjectID != session.ProjectID {
+			session.ProjectID = request.ProjectID
+			needsUpdate = true
+		}
+
+		// Experiments added since the session was created
+		if pp.assignExperiments(session) {
+			needsUpdate = true
+		}
+
+		if leaveMessage := pp.leaveMessageActive(); session.LeaveMessage != leaveMessage {
+			session.LeaveMessage = leaveMessage
+			needsUpdate = true
+		}
+
+		// Sessions created before regions were configured
+		if session.Region == "" && pp.hasConsent(session) {
+			if region := pp.resolveRegion(ctx, session); region != "" {
+				session.Region = region
+				needsUpdate = true
+			}
+		}
+
 		if needsUpdate {
 			session.LastActivity = time.Now()
 			if err := pp.storage.UpdateSession(ctx, session); err != nil {
 				return nil, err
 			}
+			pp.inbox.addSession(session)
 		}
 	}
 
@@ -339,18 +937,78 @@ func (pp *PocketPing) HandleConnect(ctx context.Context, request ConnectRequest)
 	}
 	messages = pp.hydrateAttachments(ctx, messages)
 
+	flags := pp.FeatureFlags(ctx, session)
+
+	var missed []WebSocketEvent
+	if request.LastEventSeq > 0 {
+		if missed, err = pp.MissedEvents(ctx, session.ID, pp.resendFrom(ctx, session.ID, request.LastEventSeq)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Element tracking waits for consent too
+	var trackedElements []TrackedElement
+	if pp.hasConsent(session) {
+		trackedElements = pp.config.TrackedElements
+	}
+
+	welcomeMessage := pp.welcomeMessage(session)
+	welcomeFlow := pp.welcomePrompt(session)
+	if session.WelcomeFlow != nil {
+		welcomeMessage = ""
+	} else if session.LeaveMessage {
+		welcomeMessage = pp.leaveMessagePrompt()
+	}
+
+	var queue *QueueStatus
+	if status, ok := pp.QueueStatus(session.ID); ok {
+		queue = &status
+	}
+
 	return &ConnectResponse{
 		SessionID:       session.ID,
 		VisitorID:       session.VisitorID,
 		OperatorOnline:  pp.operatorOnline,
-		WelcomeMessage:  pp.config.WelcomeMessage,
+		WelcomeMessage:  welcomeMessage,
 		Messages:        messages,
-		TrackedElements: pp.config.TrackedElements,
+		TrackedElements: trackedElements,
+		ServerConfig:    pp.widgetSettings(request.ProjectID),
+		FeatureFlags:    flags,
+		Experiments:     experimentVariants(session),
+		WelcomeFlow:     welcomeFlow,
+		LeaveMessage:    session.LeaveMessage,
+		AffinityToken:   pp.issueAffinityToken(session),
+		Node:            pp.NodeID(),
+		MissedEvents:    missed,
+		Queue:           queue,
 	}, nil
 }
 
-// HandleMessage handles a message from visitor or operator.
+// HandleMessage handles a message from visitor or operator. It trusts
+// request.Sender, so pass widget requests through HandleVisitorMessage.
+// The message goes through the middleware added with Use first.
 func (pp *PocketPing) HandleMessage(ctx context.Context, request SendMessageRequest) (*SendMessageResponse, error) {
+	result, err := pp.runMessage(ctx, &MessageRequest{Op: MessageOpSend, Send: &request}, func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+		response, err := pp.handleMessage(ctx, *req.Send)
+		if err != nil {
+			return nil, err
+		}
+		return &MessageResult{Send: response}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || result.Send == nil {
+		return nil, errNoMessageResult
+	}
+	return result.Send, nil
+}
+
+func (pp *PocketPing) handleMessage(ctx context.Context, request SendMessageRequest) (*SendMessageResponse, error) {
+	if !validSender(request.Sender) {
+		return nil, ErrInvalidSender
+	}
+
 	// Validate content length
 	if err := ValidateContent(request.Content); err != nil {
 		return nil, err
@@ -363,6 +1021,23 @@ func (pp *PocketPing) HandleMessage(ctx context.Context, request SendMessageRequ
 	if session == nil {
 		return nil, ErrSessionNotFound
 	}
+	if session.EndedAt != nil && request.Sender == SenderVisitor {
+		return nil, ErrSessionEnded
+	}
+	if request.Sender == SenderVisitor {
+		if err := pp.checkRateLimit(session, request.ClientIP); err != nil {
+			return nil, err
+		}
+	}
+
+	if request.Location != nil && !request.Location.Valid() {
+		return nil, ErrInvalidLocation
+	}
+
+	threadID, err := pp.resolveThread(ctx, request.SessionID, request.ThreadID)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	message := &Message{
@@ -372,12 +1047,28 @@ func (pp *PocketPing) HandleMessage(ctx context.Context, request SendMessageRequ
 		Sender:    request.Sender,
 		Timestamp: now,
 		ReplyTo:   request.ReplyTo,
+		ThreadID:  threadID,
+		Location:  request.Location,
 		Status:    MessageStatusSent,
 	}
 
+	if request.Sender == SenderVisitor {
+		pp.annotateQuickReply(ctx, message)
+	} else {
+		message.QuickReplies = normalizeQuickReplies(request.QuickReplies)
+		message.Payment = request.Payment
+		message.ContactRequest = request.ContactRequest
+		message.Form = request.Form
+	}
+
 	// Inline attachments (e.g. operator messages from bridges) take precedence.
-	if len(request.Attachments) > 0 {
-		message.Attachments = request.Attachments
+	// Only operators send them; visitors upload first.
+	if len(request.Attachments) > 0 && request.Sender == SenderOperator {
+		inline, err := pp.saveInlineAttachments(ctx, message.ID, request.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		message.Attachments = inline
 	}
 
 	// Link any referenced attachments to this message BEFORE persisting and
@@ -392,12 +1083,19 @@ func (pp *PocketPing) HandleMessage(ctx context.Context, request SendMessageRequ
 		}
 	}
 
+	if err := pp.beforeMessageSave(ctx, message, session); err != nil {
+		return nil, err
+	}
 	if err := pp.storage.SaveMessage(ctx, message); err != nil {
 		return nil, err
 	}
+	pp.inbox.recordMessage(message)
+	pp.trackMessageSent(message, session)
 
 	// Update session activity
 	session.LastActivity = now
+	pp.trackWaitQueue(message, session)
+	atRisk := pp.observeRisk(session, message)
 
 	// Track operator activity for AI takeover detection. If an operator
 	// responds, disable AI for this session.
@@ -414,7 +1112,12 @@ func (pp *PocketPing) HandleMessage(ctx context.Context, request SendMessageRequ
 
 	// Notify bridges (only for visitor messages)
 	if request.Sender == SenderVisitor {
-		pp.notifyBridgesMessage(ctx, message, session)
+		if !pp.holdForDigest(ctx, message, session) && !pp.batchVisitorMessage(ctx, message, session) {
+			pp.notifyBridgesMessage(ctx, message, session)
+		}
+	}
+	if atRisk {
+		pp.notifyAtRisk(ctx, session)
 	}
 
 	// Broadcast to WebSocket clients
@@ -431,14 +1134,16 @@ func (pp *PocketPing) HandleMessage(ctx context.Context, request SendMessageRequ
 	// AI fallback: for visitor messages, after persisting + linking attachments
 	// + notifying bridges, possibly generate an AI reply when the operator is
 	// offline and the takeover delay has elapsed. AI errors are swallowed so
-	// message handling never fails.
-	if request.Sender == SenderVisitor {
+	// message handling never fails. Answers to a workflow step get the
+	// workflow's next prompt instead.
+	if request.Sender == SenderVisitor && !pp.advanceWorkflow(ctx, session.ID, message) {
 		pp.maybeAIRespond(ctx, session)
 	}
 
 	return &SendMessageResponse{
 		MessageID: message.ID,
 		Timestamp: now,
+		ThreadID:  threadID,
 	}, nil
 }
 
@@ -452,7 +1157,13 @@ func (pp *PocketPing) HandleGetMessages(ctx context.Context, request GetMessages
 		limit = 100
 	}
 
-	messages, err := pp.storage.GetMessages(ctx, request.SessionID, request.After, limit+1)
+	var messages []Message
+	var err error
+	if request.ThreadID != "" {
+		messages, err = pp.threadMessages(ctx, request.SessionID, request.ThreadID, request.After, limit+1)
+	} else {
+		messages, err = pp.storage.GetMessages(ctx, request.SessionID, request.After, limit+1)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -471,13 +1182,17 @@ func (pp *PocketPing) HandleGetMessages(ctx context.Context, request GetMessages
 
 // HandleTyping handles typing indicator.
 func (pp *PocketPing) HandleTyping(ctx context.Context, request TypingRequest) error {
+	data := map[string]interface{}{
+		"sessionId": request.SessionID,
+		"sender":    request.Sender,
+		"isTyping":  request.IsTyping,
+	}
+	if request.ThreadID != "" {
+		data["threadId"] = request.ThreadID
+	}
 	pp.BroadcastToSession(request.SessionID, WebSocketEvent{
 		Type: "typing",
-		Data: map[string]interface{}{
-			"sessionId": request.SessionID,
-			"sender":    request.Sender,
-			"isTyping":  request.IsTyping,
-		},
+		Data: data,
 	})
 	return nil
 }
@@ -491,8 +1206,26 @@ func (pp *PocketPing) HandlePresence(ctx context.Context) *PresenceResponse {
 	}
 }
 
-// HandleEditMessage handles editing a visitor's message.
+// HandleEditMessage handles editing a visitor's message. The edit goes
+// through the middleware added with Use first.
 func (pp *PocketPing) HandleEditMessage(ctx context.Context, request EditMessageRequest) (*EditMessageResponse, error) {
+	result, err := pp.runMessage(ctx, &MessageRequest{Op: MessageOpEdit, Edit: &request}, func(ctx context.Context, req *MessageRequest) (*MessageResult, error) {
+		response, err := pp.handleEditMessage(ctx, *req.Edit)
+		if err != nil {
+			return nil, err
+		}
+		return &MessageResult{Edit: response}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || result.Edit == nil {
+		return nil, errNoMessageResult
+	}
+	return result.Edit, nil
+}
+
+func (pp *PocketPing) handleEditMessage(ctx context.Context, request EditMessageRequest) (*EditMessageResponse, error) {
 	if strings.TrimSpace(request.Content) == "" {
 		return nil, ErrNoContent
 	}
@@ -533,9 +1266,16 @@ func (pp *PocketPing) HandleEditMessage(ctx context.Context, request EditMessage
 		return nil, ErrMessageDeleted
 	}
 
+	// Edit a copy: storage may hand out the message it holds, which must
+	// stay as is if BeforeMessageSave refuses the edit
+	edited := *message
+	message = &edited
 	now := time.Now()
 	message.Content = request.Content
 	message.EditedAt = &now
+	if err := pp.beforeMessageSave(ctx, message, session); err != nil {
+		return nil, err
+	}
 
 	// Try to use StorageWithBridgeIDs if available
 	if storageWithBridge, ok := pp.storage.(StorageWithBridgeIDs); ok {
@@ -549,14 +1289,14 @@ func (pp *PocketPing) HandleEditMessage(ctx context.Context, request EditMessage
 	}
 
 	// Sync edit to bridges
-	pp.syncEditToBridges(ctx, request.SessionID, request.MessageID, request.Content, now)
+	pp.syncEditToBridges(ctx, request.SessionID, request.MessageID, message.Content, now, "")
 
 	// Broadcast to WebSocket
 	pp.BroadcastToSession(request.SessionID, WebSocketEvent{
 		Type: "message_edited",
 		Data: map[string]interface{}{
 			"messageId": request.MessageID,
-			"content":   request.Content,
+			"content":   message.Content,
 			"editedAt":  now.Format(time.RFC3339),
 		},
 	})
@@ -604,7 +1344,7 @@ func (pp *PocketPing) HandleDeleteMessage(ctx context.Context, request DeleteMes
 
 	// Sync delete to bridges BEFORE soft delete (we need bridge IDs)
 	now := time.Now()
-	pp.syncDeleteToBridges(ctx, request.SessionID, request.MessageID, now)
+	pp.syncDeleteToBridges(ctx, request.SessionID, request.MessageID, now, "")
 
 	// Soft delete the message
 	message.DeletedAt = &now
@@ -716,8 +1456,32 @@ func (pp *PocketPing) HandleIdentify(ctx context.Context, request IdentifyReques
 		return nil, ErrSessionNotFound
 	}
 
+	disposableEmail, err := pp.checkIdentityEmail(request.Identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var phone, phoneCountry string
+	if request.Phone != "" {
+		if phone, phoneCountry, err = parsePhone(request.Phone, request.PhoneCountry); err != nil {
+			return nil, err
+		}
+	}
+
+	requested := session.ID
+	verified := pp.verifyIdentityHash(request.Identity.ID, request.UserHash)
+	if session, err = pp.resolveIdentityConflict(ctx, session, request.Identity.ID, verified); err != nil {
+		return nil, err
+	}
+
 	// Update session with identity
+	newIdentity := session.Identity == nil || session.Identity.ID != request.Identity.ID
 	session.Identity = request.Identity
+	session.DisposableEmail = disposableEmail
+	if phone != "" {
+		session.UserPhone = phone
+		session.UserPhoneCountry = phoneCountry
+	}
 	session.LastActivity = time.Now()
 
 	if err := pp.storage.UpdateSession(ctx, session); err != nil {
@@ -725,7 +1489,12 @@ func (pp *PocketPing) HandleIdentify(ctx context.Context, request IdentifyReques
 	}
 
 	// Notify bridges about identity update
-	pp.notifyBridgesIdentity(ctx, session)
+	pp.notifyBridgesIdentity(ctx, session, newIdentity)
+	if newIdentity && !disposableEmail {
+		pp.enrichSession(ctx, session)
+	}
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "identity_update", Data: session})
+	pp.inbox.addSession(session)
 
 	// Callback
 	if pp.config.OnIdentify != nil {
@@ -734,10 +1503,14 @@ func (pp *PocketPing) HandleIdentify(ctx context.Context, request IdentifyReques
 
 	// Forward identity event to webhook
 	if pp.config.WebhookURL != "" {
-		go pp.forwardIdentityToWebhook(ctx, session)
+		pp.goWebhook(func() { pp.forwardIdentityToWebhook(ctx, session) })
 	}
 
-	return &IdentifyResponse{OK: true}, nil
+	response := &IdentifyResponse{OK: true}
+	if session.ID != requested {
+		response.SessionID = session.ID
+	}
+	return response, nil
 }
 
 // GetSession retrieves a session by ID.
@@ -822,7 +1595,7 @@ func (pp *PocketPing) HandleCsat(ctx context.Context, request CsatRequest) (*Csa
 	pp.notifyBridgesCsat(ctx, session, score, comment)
 
 	if pp.config.WebhookURL != "" {
-		go pp.forwardCsatToWebhook(ctx, session, score, comment)
+		pp.goWebhook(func() { pp.forwardCsatToWebhook(ctx, session, score, comment) })
 	}
 
 	if pp.config.OnCsat != nil {
@@ -852,7 +1625,11 @@ func (pp *PocketPing) notifyBridgesCsat(ctx context.Context, session *Session, s
 	if comment != "" {
 		caption += fmt.Sprintf(" — %q", comment)
 	}
-	for _, bridge := range pp.bridges {
+	routed := routeBridges(pp.bridges, session.Region)
+	for i, bridge := range pp.bridges {
+		if !routed[i] {
+			continue
+		}
 		if notifier, ok := bridge.(BridgeWithNotify); ok {
 			if err := notifier.Notify(ctx, session, caption); err != nil {
 				log.Printf("[PocketPing] Bridge %s CSAT notification failed: %v", bridge.Name(), err)
@@ -878,14 +1655,20 @@ func (pp *PocketPing) forwardCsatToWebhook(ctx context.Context, session *Session
 		commentValue = comment
 	}
 
+	pp.postWebhookEvent(ctx, "csat_submitted", map[string]interface{}{
+		"sessionId":   session.ID,
+		"score":       score,
+		"comment":     commentValue,
+		"respondedAt": respondedAt.Format(time.RFC3339),
+	})
+}
+
+// postWebhookEvent posts a {type, data, sentAt} event to Config.WebhookURL,
+// HMAC-signed like other webhooks.
+func (pp *PocketPing) postWebhookEvent(ctx context.Context, eventType string, data map[string]interface{}) {
 	payload := map[string]interface{}{
-		"type": "csat_submitted",
-		"data": map[string]interface{}{
-			"sessionId":   session.ID,
-			"score":       score,
-			"comment":     commentValue,
-			"respondedAt": respondedAt.Format(time.RFC3339),
-		},
+		"type":   eventType,
+		"data":   data,
 		"sentAt": time.Now().Format(time.RFC3339),
 	}
 
@@ -960,28 +1743,84 @@ func (pp *PocketPing) GetStats(ctx context.Context, opts *GetStatsOptions) (*Sdk
 	return &stats, nil
 }
 
+// SearchMessages finds sessions by visitor email/name or message content.
+// Requires the storage adapter to implement StorageWithSearch. A limit <= 0
+// defaults to 20.
+func (pp *PocketPing) SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	searcher, ok := pp.storage.(StorageWithSearch)
+	if !ok {
+		return nil, ErrSearchUnsupported
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	return searcher.SearchMessages(ctx, query, limit)
+}
+
 // GetStorage returns the storage adapter.
 func (pp *PocketPing) GetStorage() Storage {
 	return pp.storage
 }
 
-// SendOperatorMessage sends a message as the operator.
-func (pp *PocketPing) SendOperatorMessage(ctx context.Context, sessionID, content string, sourceBridge, operatorName string) (*Message, error) {
+// SendOperatorMessage sends a message as the operator. Use WithQuickReplies to
+// attach suggestion chips, WithThread to reply within a thread and
+// WithAttachments or WithAttachmentIDs to send files. WithOperatorToken
+// sends as the operator owning an API token.
+func (pp *PocketPing) SendOperatorMessage(ctx context.Context, sessionID, content string, sourceBridge, operatorName string, opts ...OperatorMessageOption) (*Message, error) {
+	var options operatorMessageOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	token, operatorName, err := pp.operatorSender(ctx, options, sourceBridge, operatorName)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := pp.prepareOperatorAttachments(options.attachments, sourceBridge)
+	if err != nil {
+		return nil, err
+	}
+
 	response, err := pp.HandleMessage(ctx, SendMessageRequest{
-		SessionID: sessionID,
-		Content:   content,
-		Sender:    SenderOperator,
+		SessionID:     sessionID,
+		Content:       content,
+		Sender:        SenderOperator,
+		ThreadID:      options.threadID,
+		QuickReplies:  options.quickReplies,
+		Attachments:   attachments,
+		AttachmentIDs: options.attachmentIDs,
 	})
 	if err != nil {
 		return nil, err
 	}
+	pp.inbox.assign(sessionID, operatorName)
+	if err := pp.saveBridgeMessageID(ctx, response.MessageID, sourceBridge, options.bridgeMessageID); err != nil {
+		log.Printf("[PocketPing] Recording %s message ID %s failed: %v", sourceBridge, options.bridgeMessageID, err)
+	}
+	if token != nil {
+		pp.auditOperator(ctx, OperatorAuditRecord{
+			Action:    OperatorMessageSent,
+			TokenID:   token.ID,
+			Operator:  operatorName,
+			SessionID: sessionID,
+			MessageID: response.MessageID,
+		})
+	}
 
 	message := &Message{
-		ID:        response.MessageID,
-		SessionID: sessionID,
-		Content:   content,
-		Sender:    SenderOperator,
-		Timestamp: response.Timestamp,
+		ID:           response.MessageID,
+		SessionID:    sessionID,
+		Content:      content,
+		Sender:       SenderOperator,
+		Timestamp:    response.Timestamp,
+		ThreadID:     response.ThreadID,
+		QuickReplies: normalizeQuickReplies(options.quickReplies),
+	}
+	if len(attachments) > 0 || len(options.attachmentIDs) > 0 {
+		if stored, err := pp.storage.GetMessage(ctx, message.ID); err == nil && stored != nil {
+			message.Attachments = pp.hydrateAttachments(ctx, []Message{*stored})[0].Attachments
+		}
 	}
 
 	// Notify bridges for cross-bridge sync
@@ -996,6 +1835,9 @@ func (pp *PocketPing) SendOperatorMessage(ctx context.Context, sessionID, conten
 // SetOperatorOnline sets operator online/offline status.
 func (pp *PocketPing) SetOperatorOnline(online bool) {
 	pp.operatorOnline = online
+	if online {
+		pp.flushDigests(context.Background())
+	}
 
 	// Broadcast to all sessions
 	pp.socketsMu.RLock()
@@ -1018,33 +1860,88 @@ func (pp *PocketPing) IsOperatorOnline() bool {
 	return pp.operatorOnline
 }
 
-// OnEvent subscribes to a custom event.
+// OnEvent subscribes to a custom event ("*" for all events).
 // Returns an unsubscribe function.
 func (pp *PocketPing) OnEvent(eventName string, handler CustomEventHandler) func() {
+	sub := pp.SubscribeEvent(eventName, handler)
+	return func() {
+		pp.Unsubscribe(sub)
+	}
+}
+
+// SubscribeEvent is OnEvent returning a subscription, for Unsubscribe.
+func (pp *PocketPing) SubscribeEvent(eventName string, handler CustomEventHandler) EventSubscription {
 	pp.handlersMu.Lock()
-	pp.eventHandlers[eventName] = append(pp.eventHandlers[eventName], handler)
-	pp.handlersMu.Unlock()
+	defer pp.handlersMu.Unlock()
 
-	return func() {
-		pp.OffEvent(eventName, handler)
+	pp.lastHandlerID++
+	id := pp.lastHandlerID
+	pp.eventHandlers[eventName] = append(pp.eventHandlers[eventName], eventHandlerEntry{id: id, handler: handler})
+	return id
+}
+
+// Unsubscribe removes the handler SubscribeEvent returned sub for. It reports
+// whether the handler was still subscribed.
+func (pp *PocketPing) Unsubscribe(sub EventSubscription) bool {
+	pp.handlersMu.Lock()
+	defer pp.handlersMu.Unlock()
+
+	for eventName, handlers := range pp.eventHandlers {
+		for i, h := range handlers {
+			if h.id == sub {
+				pp.removeHandlerLocked(eventName, i)
+				return true
+			}
+		}
 	}
+	return false
 }
 
-// OffEvent unsubscribes from a custom event.
+// OffEvent unsubscribes the first handler of eventName with the same code
+// pointer as handler.
+//
+// Deprecated: closures created by the same expression share a code pointer,
+// as do method values of one method, so this can remove another handler.
+// Use the function OnEvent returns, or Unsubscribe.
 func (pp *PocketPing) OffEvent(eventName string, handler CustomEventHandler) {
 	pp.handlersMu.Lock()
 	defer pp.handlersMu.Unlock()
 
-	handlers := pp.eventHandlers[eventName]
-	for i, h := range handlers {
-		// Compare function pointers - this is a limitation in Go
-		if fmt.Sprintf("%p", h) == fmt.Sprintf("%p", handler) {
-			pp.eventHandlers[eventName] = append(handlers[:i], handlers[i+1:]...)
-			break
+	ptr := reflect.ValueOf(handler).Pointer()
+	for i, h := range pp.eventHandlers[eventName] {
+		if reflect.ValueOf(h.handler).Pointer() == ptr {
+			pp.removeHandlerLocked(eventName, i)
+			return
 		}
 	}
 }
 
+// removeHandlerLocked removes handler i of eventName, dropping the name
+// once it has no handlers left.
+func (pp *PocketPing) removeHandlerLocked(eventName string, i int) {
+	handlers := pp.eventHandlers[eventName]
+	if len(handlers) == 1 {
+		delete(pp.eventHandlers, eventName)
+		return
+	}
+	// Copy, so a dispatch iterating the old slice is unaffected
+	remaining := make([]eventHandlerEntry, 0, len(handlers)-1)
+	remaining = append(remaining, handlers[:i]...)
+	pp.eventHandlers[eventName] = append(remaining, handlers[i+1:]...)
+}
+
+// handlersFor returns the handlers of eventName.
+func (pp *PocketPing) handlersFor(eventName string) []CustomEventHandler {
+	pp.handlersMu.RLock()
+	defer pp.handlersMu.RUnlock()
+
+	handlers := make([]CustomEventHandler, len(pp.eventHandlers[eventName]))
+	for i, h := range pp.eventHandlers[eventName] {
+		handlers[i] = h.handler
+	}
+	return handlers
+}
+
 // EmitEvent sends a custom event to a specific session.
 func (pp *PocketPing) EmitEvent(sessionID, eventName string, data map[string]interface{}) {
 	event := CustomEvent{
@@ -1086,11 +1983,16 @@ func (pp *PocketPing) HandleCustomEvent(ctx context.Context, sessionID string, e
 
 	event.SessionID = sessionID
 
+	if err := pp.recordExperimentConversion(ctx, session, event); err != nil {
+		return err
+	}
+	if err := pp.recordWelcomeChoice(ctx, session, event); err != nil {
+		return err
+	}
+
 	// Call specific event handlers
-	pp.handlersMu.RLock()
-	handlers := append([]CustomEventHandler{}, pp.eventHandlers[event.Name]...)
-	wildcardHandlers := append([]CustomEventHandler{}, pp.eventHandlers["*"]...)
-	pp.handlersMu.RUnlock()
+	handlers := pp.handlersFor(event.Name)
+	wildcardHandlers := pp.handlersFor("*")
 
 	for _, handler := range handlers {
 		handler(event, session)
@@ -1104,13 +2006,15 @@ func (pp *PocketPing) HandleCustomEvent(ctx context.Context, sessionID string, e
 	if pp.config.OnEvent != nil {
 		pp.config.OnEvent(event, session)
 	}
+	pp.recordWarehouseEvent(event)
+	pp.trackCustomEvent(event, session)
 
 	// Notify bridges
 	pp.notifyBridgesEvent(ctx, event, session)
 
 	// Forward to webhook
 	if pp.config.WebhookURL != "" {
-		go pp.forwardToWebhook(ctx, event, session)
+		pp.goWebhook(func() { pp.forwardToWebhook(ctx, event, session) })
 	}
 
 	return nil
@@ -1128,48 +2032,83 @@ func (pp *PocketPing) TriggerEvent(ctx context.Context, sessionID, eventName str
 }
 
 // RegisterWebSocket registers a WebSocket connection for a session.
+// Events queued while the session had no sockets (Config.OfflineQueueSize)
+// are sent to it first.
 func (pp *PocketPing) RegisterWebSocket(sessionID string, conn WebSocketConn) {
+	writer := pp.registerWebSocket(sessionID, conn)
+	pp.waitQueueDisconnected(sessionID, true)
+	pp.flushOffline(sessionID, conn, writer)
+}
+
+// registerWebSocket registers conn and returns the writer to send through.
+func (pp *PocketPing) registerWebSocket(sessionID string, conn WebSocketConn) WebSocketConn {
 	pp.socketsMu.Lock()
 	defer pp.socketsMu.Unlock()
 
 	if pp.sessionSockets[sessionID] == nil {
-		pp.sessionSockets[sessionID] = make(map[WebSocketConn]struct{})
+		pp.sessionSockets[sessionID] = make(map[WebSocketConn]WebSocketConn)
 	}
-	pp.sessionSockets[sessionID][conn] = struct{}{}
+	writer, ok := pp.sessionSockets[sessionID][conn]
+	if !ok {
+		writer = pp.newSendQueue(conn)
+		pp.sessionSockets[sessionID][conn] = writer
+	}
+	return writer
 }
 
 // UnregisterWebSocket unregisters a WebSocket connection.
 func (pp *PocketPing) UnregisterWebSocket(sessionID string, conn WebSocketConn) {
 	pp.socketsMu.Lock()
-	defer pp.socketsMu.Unlock()
-
+	last := false
 	if sockets, ok := pp.sessionSockets[sessionID]; ok {
+		if writer, ok := sockets[conn]; ok {
+			stopSendQueue(writer)
+		}
 		delete(sockets, conn)
 		if len(sockets) == 0 {
 			delete(pp.sessionSockets, sessionID)
+			last = true
 		}
 	}
+	pp.socketsMu.Unlock()
+
+	if last {
+		pp.waitQueueDisconnected(sessionID, false)
+	}
 }
 
-// BroadcastToSession broadcasts an event to all WebSocket connections for a session.
+// BroadcastToSession broadcasts an event to all WebSocket connections for a
+// session, and to operator consoles. With Config.Broadcaster, sockets
+// connected to other replicas get it too.
 func (pp *PocketPing) BroadcastToSession(sessionID string, event WebSocketEvent) {
+	event = pp.recordEvent(sessionID, event)
+	pp.broadcastToOperators(sessionID, event)
+	if pp.broadcastLocal(sessionID, event) == 0 {
+		pp.queueOffline(sessionID, event)
+	}
+	pp.publishBroadcast(sessionID, event, false)
+}
+
+// broadcastLocal sends an event to this process's sockets for a session and
+// returns how many got it.
+func (pp *PocketPing) broadcastLocal(sessionID string, event WebSocketEvent) int {
 	pp.socketsMu.RLock()
 	sockets := pp.sessionSockets[sessionID]
 	if sockets == nil {
 		pp.socketsMu.RUnlock()
-		return
+		return 0
 	}
 
 	// Copy to avoid holding lock during write
-	conns := make([]WebSocketConn, 0, len(sockets))
-	for conn := range sockets {
-		conns = append(conns, conn)
+	writers := make(map[WebSocketConn]WebSocketConn, len(sockets))
+	for conn, writer := range sockets {
+		writers[conn] = writer
 	}
 	pp.socketsMu.RUnlock()
 
 	deadConns := []WebSocketConn{}
-	for _, conn := range conns {
-		if err := conn.WriteJSON(event); err != nil {
+	for conn, writer := range writers {
+		if err := writer.WriteJSON(event); err != nil {
 			deadConns = append(deadConns, conn)
 		}
 	}
@@ -1178,6 +2117,7 @@ func (pp *PocketPing) BroadcastToSession(sessionID string, event WebSocketEvent)
 	for _, conn := range deadConns {
 		pp.UnregisterWebSocket(sessionID, conn)
 	}
+	return len(writers) - len(deadConns)
 }
 
 // AddBridge adds a bridge dynamically.
@@ -1252,7 +2192,7 @@ func (pp *PocketPing) aiTakeoverDue(sessionID string) bool {
 // the takeover delay is due. Any error from the provider is logged and
 // swallowed so message handling never fails.
 func (pp *PocketPing) maybeAIRespond(ctx context.Context, session *Session) {
-	if pp.aiProvider == nil {
+	if pp.aiProvider == nil || session.HumanTakeover {
 		return
 	}
 	if pp.IsOperatorOnline() {
@@ -1276,28 +2216,36 @@ func (pp *PocketPing) maybeAIRespond(ctx context.Context, session *Session) {
 		return
 	}
 
-	reply, err := pp.aiProvider.GenerateResponse(ctx, messages, pp.aiSystemPrompt)
+	messageID := pp.generateID()
+	result, err := pp.generateAIReply(ctx, session, messageID, messages)
 	if err != nil {
 		log.Printf("[PocketPing] AI fallback: provider error for %s: %v", session.ID, err)
 		return
 	}
-	if reply == "" {
+	if result.Reply == "" {
 		return
 	}
 
 	now := time.Now()
 	aiMessage := &Message{
-		ID:        pp.generateID(),
-		SessionID: session.ID,
-		Content:   reply,
-		Sender:    SenderAI,
-		Timestamp: now,
-		Status:    MessageStatusSent,
+		ID:           messageID,
+		SessionID:    session.ID,
+		Content:      result.Reply,
+		Sender:       SenderAI,
+		Timestamp:    now,
+		Status:       MessageStatusSent,
+		QuickReplies: normalizeQuickReplies(result.QuickReplies),
+	}
+	if err := pp.beforeMessageSave(ctx, aiMessage, session); err != nil {
+		log.Printf("[PocketPing] AI fallback: reply for %s refused: %v", session.ID, err)
+		return
 	}
 	if err := pp.storage.SaveMessage(ctx, aiMessage); err != nil {
 		log.Printf("[PocketPing] AI fallback: failed to save AI message for %s: %v", session.ID, err)
 		return
 	}
+	pp.trackMessageSent(aiMessage, session)
+	pp.observeAIRisk(ctx, session, aiMessage)
 
 	// Broadcast to WebSocket clients.
 	pp.BroadcastToSession(session.ID, WebSocketEvent{
@@ -1305,78 +2253,204 @@ func (pp *PocketPing) maybeAIRespond(ctx context.Context, session *Session) {
 		Data: aiMessage,
 	})
 
-	// Notify bridges via the operator-message path so it shows in Telegram/etc.
-	pp.notifyBridgesOperatorMessage(ctx, aiMessage, session, "ai", "AI")
+	if !pp.config.DisableAIMirroring {
+		pp.notifyBridgesAIMessage(ctx, aiMessage, session)
+	}
+}
+
+// generateAIReply asks the AI provider for a reply. With AIActions, action
+// providers run the requested actions first (see generateAIActions).
+// Streaming providers have each delta broadcast as a "message_chunk" event for
+// messageID; a failed or empty stream is closed with an aborted chunk.
+func (pp *PocketPing) generateAIReply(ctx context.Context, session *Session, messageID string, messages []Message) (AIResult, error) {
+	if result, ok, err := pp.generateAIActions(ctx, session, messages); ok {
+		return result, err
+	}
+
+	sessionID := session.ID
+	streamer, ok := pp.aiProvider.(StreamingAIProvider)
+	if !ok {
+		reply, err := pp.aiProvider.GenerateResponse(ctx, messages, pp.aiSystemPrompt)
+		return AIResult{Reply: reply}, err
+	}
+
+	seq := 0
+	reply, err := streamer.StreamResponse(ctx, messages, pp.aiSystemPrompt, func(delta string) {
+		pp.BroadcastToSession(sessionID, WebSocketEvent{
+			Type: "message_chunk",
+			Data: MessageChunk{MessageID: messageID, SessionID: sessionID, Sender: SenderAI, Seq: seq, Delta: delta},
+		})
+		seq++
+	})
+	if (err != nil || reply == "") && seq > 0 {
+		pp.BroadcastToSession(sessionID, WebSocketEvent{
+			Type: "message_chunk",
+			Data: MessageChunk{MessageID: messageID, SessionID: sessionID, Sender: SenderAI, Seq: seq, Aborted: true},
+		})
+	}
+	return AIResult{Reply: reply}, err
 }
 
 // Bridge notification helpers
+//
+// Notifications run asynchronously but in conversation order: each session
+// has a FIFO queue per bridge (see bridgeDispatcher).
 
 func (pp *PocketPing) notifyBridgesNewSession(ctx context.Context, session *Session) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			_ = b.OnNewSession(ctx, session)
-		}(bridge)
-	}
+	customerContext := pp.contextNotice(ctx, session)
+	mentions := pp.operatorMentions(ctx, session)
+	pp.dispatchToBridges(session.ID, session.Region, func(b Bridge) {
+		_ = pp.deliver(ctx, b, "OnNewSession", session.ID, "", func(ctx context.Context) error {
+			return b.OnNewSession(ctx, session)
+		})
+		pp.notifyCustomerContext(ctx, b, session, customerContext)
+		pp.notifyMentions(ctx, b, session, mentions)
+	})
 }
 
-func (pp *PocketPing) notifyBridgesMessage(ctx context.Context, message *Message, session *Session) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			_ = b.OnVisitorMessage(ctx, message, session)
-		}(bridge)
-	}
+// notifyBridgesMessage mirrors a visitor message to the bridges. mergedIDs
+// are the other messages of a batch merged into message; they get its
+// bridge message IDs once delivered (see mergeMessages).
+func (pp *PocketPing) notifyBridgesMessage(ctx context.Context, message *Message, session *Session, mergedIDs ...string) {
+	pp.notifyWatchers(ctx, session, "Visitor: "+message.Content+attachmentsText(message.Attachments), "", "")
+	mentions := pp.operatorMentions(ctx, session)
+	pp.dispatchToBridgesAt(session.ID, session.Region, func(i int, b Bridge) {
+		if !pp.markDelivered(ctx, message.ID, i, b) {
+			return
+		}
+		if pp.alreadyNotified(ctx, message, i, b) {
+			log.Printf("[PocketPing] Skipped replay of message %s to bridge %s", message.ID, b.Name())
+			return
+		}
+		err := pp.deliver(ctx, b, "OnVisitorMessage", session.ID, message.ID, func(ctx context.Context) error {
+			return b.OnVisitorMessage(ctx, message, session)
+		})
+		if err == nil {
+			pp.recordNotified(ctx, message, i, b)
+			pp.shareBridgeMessageIDs(ctx, message.ID, mergedIDs)
+		}
+		if uploader, ok := b.(BridgeWithAttachments); ok && err == nil && len(message.Attachments) > 0 {
+			if files := pp.attachmentFiles(ctx, message); len(files) > 0 {
+				_ = pp.deliver(ctx, b, "OnVisitorAttachments", session.ID, message.ID, func(ctx context.Context) error {
+					return uploader.OnVisitorAttachments(ctx, message, session, files)
+				})
+			}
+		}
+		pp.notifyMentions(ctx, b, session, mentions)
+	})
 }
 
 func (pp *PocketPing) notifyBridgesOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge, operatorName string) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			_ = b.OnOperatorMessage(ctx, message, session, sourceBridge, operatorName)
-		}(bridge)
+	pp.notifyWatchers(ctx, session, takeoverName(operatorName)+": "+message.Content+attachmentsText(message.Attachments), sourceBridge, operatorName)
+	pp.dispatchToBridgesAt(session.ID, session.Region, func(i int, b Bridge) {
+		if !pp.markDelivered(ctx, message.ID, i, b) {
+			return
+		}
+		_ = pp.deliver(ctx, b, "OnOperatorMessage", session.ID, message.ID, func(ctx context.Context) error {
+			return b.OnOperatorMessage(ctx, message, session, sourceBridge, operatorName)
+		})
+	})
+}
+
+func (pp *PocketPing) notifyBridgesAIMessage(ctx context.Context, message *Message, session *Session) {
+	pp.notifyWatchers(ctx, session, "AI: "+message.Content, "", "")
+	pp.dispatchToBridgesAt(session.ID, session.Region, func(i int, b Bridge) {
+		if !pp.markDelivered(ctx, message.ID, i, b) {
+			return
+		}
+		_ = pp.deliver(ctx, b, "OnAIMessage", session.ID, message.ID, func(ctx context.Context) error {
+			return deliverAIMessage(ctx, b, message, session)
+		})
+	})
+}
+
+// notifyBridgesNotice posts a one-line notice about a session through the
+// bridges' plain-notification channel (BridgeWithNotify).
+func (pp *PocketPing) notifyBridgesNotice(ctx context.Context, session *Session, caption string) {
+	pp.notifyWatchers(ctx, session, caption, "", "")
+	pp.dispatchToBridges(session.ID, session.Region, func(b Bridge) {
+		notifier, ok := b.(BridgeWithNotify)
+		if !ok {
+			return
+		}
+		err := pp.deliver(ctx, b, "Notify", session.ID, "", func(ctx context.Context) error {
+			return notifier.Notify(ctx, session, caption)
+		})
+		if err != nil {
+			log.Printf("[PocketPing] Bridge %s notification failed: %v", b.Name(), err)
+		}
+	})
+}
+
+// markDelivered reports whether message should be delivered to b, the i-th
+// bridge, logging suppressed duplicates.
+func (pp *PocketPing) markDelivered(ctx context.Context, messageID string, i int, b Bridge) bool {
+	if pp.deduper.MarkDelivered(ctx, messageID, bridgeDedupeKey(i, b)) {
+		return true
 	}
+	log.Printf("[PocketPing] Suppressed duplicate notification of message %s to bridge %s", messageID, b.Name())
+	return false
 }
 
 func (pp *PocketPing) notifyBridgesRead(ctx context.Context, sessionID string, messageIDs []string, status MessageStatus) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			_ = b.OnMessageRead(ctx, sessionID, messageIDs, status)
-		}(bridge)
-	}
+	pp.dispatchToBridges(sessionID, pp.sessionRegion(ctx, sessionID), func(b Bridge) {
+		_ = pp.deliver(ctx, b, "OnMessageRead", sessionID, "", func(ctx context.Context) error {
+			return b.OnMessageRead(ctx, sessionID, messageIDs, status)
+		})
+	})
 }
 
 func (pp *PocketPing) notifyBridgesEvent(ctx context.Context, event CustomEvent, session *Session) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			_ = b.OnCustomEvent(ctx, event, session)
-		}(bridge)
-	}
+	pp.dispatchToBridges(session.ID, session.Region, func(b Bridge) {
+		_ = pp.deliver(ctx, b, "OnCustomEvent", session.ID, "", func(ctx context.Context) error {
+			return b.OnCustomEvent(ctx, event, session)
+		})
+	})
 }
 
-func (pp *PocketPing) notifyBridgesIdentity(ctx context.Context, session *Session) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			_ = b.OnIdentityUpdate(ctx, session)
-		}(bridge)
+// notifyBridgesIdentity also posts the visitor's customer context when
+// newIdentity is set, i.e. the session wasn't identified as them before.
+func (pp *PocketPing) notifyBridgesIdentity(ctx context.Context, session *Session, newIdentity bool) {
+	customerContext := func() string { return "" }
+	if newIdentity {
+		customerContext = pp.contextNotice(ctx, session)
 	}
+	pp.dispatchToBridges(session.ID, session.Region, func(b Bridge) {
+		_ = pp.deliver(ctx, b, "OnIdentityUpdate", session.ID, "", func(ctx context.Context) error {
+			return b.OnIdentityUpdate(ctx, session)
+		})
+		pp.notifyCustomerContext(ctx, b, session, customerContext)
+	})
 }
 
-func (pp *PocketPing) syncEditToBridges(ctx context.Context, sessionID, messageID, content string, editedAt time.Time) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			if bridgeWithEdit, ok := b.(BridgeWithEditDelete); ok {
-				_, _ = bridgeWithEdit.OnMessageEdit(ctx, sessionID, messageID, content, editedAt)
-			}
-		}(bridge)
-	}
+// syncEditToBridges passes an edit on to the bridges, except those of
+// sourceBridge's platform, where it was made (empty for the widget).
+func (pp *PocketPing) syncEditToBridges(ctx context.Context, sessionID, messageID, content string, editedAt time.Time, sourceBridge string) {
+	pp.dispatchToBridges(sessionID, pp.sessionRegion(ctx, sessionID), func(b Bridge) {
+		if sourceBridge != "" && bridgePlatform(b.Name()) == bridgePlatform(sourceBridge) {
+			return
+		}
+		if bridgeWithEdit, ok := b.(BridgeWithEditDelete); ok {
+			_ = pp.deliver(ctx, b, "OnMessageEdit", sessionID, messageID, func(ctx context.Context) error {
+				_, err := bridgeWithEdit.OnMessageEdit(ctx, sessionID, messageID, content, editedAt)
+				return err
+			})
+		}
+	})
 }
 
-func (pp *PocketPing) syncDeleteToBridges(ctx context.Context, sessionID, messageID string, deletedAt time.Time) {
-	for _, bridge := range pp.bridges {
-		go func(b Bridge) {
-			if bridgeWithDelete, ok := b.(BridgeWithEditDelete); ok {
-				_ = bridgeWithDelete.OnMessageDelete(ctx, sessionID, messageID, deletedAt)
-			}
-		}(bridge)
-	}
+// syncDeleteToBridges is syncEditToBridges for deletions.
+func (pp *PocketPing) syncDeleteToBridges(ctx context.Context, sessionID, messageID string, deletedAt time.Time, sourceBridge string) {
+	pp.dispatchToBridges(sessionID, pp.sessionRegion(ctx, sessionID), func(b Bridge) {
+		if sourceBridge != "" && bridgePlatform(b.Name()) == bridgePlatform(sourceBridge) {
+			return
+		}
+		if bridgeWithDelete, ok := b.(BridgeWithEditDelete); ok {
+			_ = pp.deliver(ctx, b, "OnMessageDelete", sessionID, messageID, func(ctx context.Context) error {
+				return bridgeWithDelete.OnMessageDelete(ctx, sessionID, messageID, deletedAt)
+			})
+		}
+	})
 }
 
 // Webhook forwarding
@@ -1391,7 +2465,7 @@ func (pp *PocketPing) forwardToWebhook(ctx context.Context, event CustomEvent, s
 		Session: WebhookSession{
 			ID:        session.ID,
 			VisitorID: session.VisitorID,
-			Metadata:  session.Metadata,
+			Metadata:  pp.webhookMetadata(session),
 			Identity:  session.Identity,
 		},
 		SentAt: time.Now(),
@@ -669,6 +669,59 @@ func TestOnEventWildcard(t *testing.T) {
 	}
 }
 
+type eventCounter struct{ calls int }
+
+func (c *eventCounter) handle(event CustomEvent, session *Session) { c.calls++ }
+
+func TestUnsubscribeIdenticalHandlers(t *testing.T) {
+	pp := New(Config{})
+
+	// Closures from one expression and method values of one method share a
+	// code pointer; subscriptions still tell them apart
+	var subs []EventSubscription
+	for i := 0; i < 2; i++ {
+		subs = append(subs, pp.SubscribeEvent("e", func(event CustomEvent, session *Session) {}))
+	}
+	first, second := &eventCounter{}, &eventCounter{}
+	pp.OnEvent("m", first.handle)
+	secondSub := pp.SubscribeEvent("m", second.handle)
+
+	if !pp.Unsubscribe(subs[1]) || !pp.Unsubscribe(secondSub) {
+		t.Fatal("expected Unsubscribe to remove the handlers")
+	}
+	if pp.Unsubscribe(secondSub) {
+		t.Error("expected a second Unsubscribe to report false")
+	}
+	if got := len(pp.handlersFor("e")); got != 1 {
+		t.Errorf("closures left = %d", got)
+	}
+	for _, handler := range pp.handlersFor("m") {
+		handler(CustomEvent{}, nil)
+	}
+	if first.calls != 1 || second.calls != 0 {
+		t.Errorf("first = %d, second = %d calls", first.calls, second.calls)
+	}
+
+	// The last handler of a name drops the name
+	pp.Unsubscribe(subs[0])
+	pp.handlersMu.RLock()
+	_, ok := pp.eventHandlers["e"]
+	pp.handlersMu.RUnlock()
+	if ok {
+		t.Error("expected the event name to be dropped")
+	}
+}
+
+func TestOffEventDeprecated(t *testing.T) {
+	pp := New(Config{})
+	handler := func(event CustomEvent, session *Session) {}
+	pp.OnEvent("e", handler)
+	pp.OffEvent("e", handler)
+	if got := len(pp.handlersFor("e")); got != 0 {
+		t.Errorf("handlers left = %d", got)
+	}
+}
+
 func TestWebSocketBroadcast(t *testing.T) {
 	pp := New(Config{})
 
@@ -1148,3 +1201,10 @@ func TestWebSocketEventJSON(t *testing.T) {
 		t.Errorf("expected type=message, got %v", result["type"])
 	}
 }
+
+func TestSearchMessagesRequiresSearchableStorage(t *testing.T) {
+	pp := New(Config{Storage: statsLessStorage{}})
+	if _, err := pp.SearchMessages(context.Background(), "x", 0); err != ErrSearchUnsupported {
+		t.Errorf("expected ErrSearchUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,560 @@
+package pocketping
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePostgres is a database/sql driver recording statements and answering
+// queries from a script, standing in for a PostgreSQL server.
+type fakePostgres struct {
+	mu    sync.Mutex
+	execs []fakePostgresCall
+	// respond answers a query with columns and rows; nil rows is no rows.
+	respond func(query string, args []driver.Value) ([]string, [][]driver.Value)
+}
+
+type fakePostgresCall struct {
+	query string
+	args  []driver.Value
+}
+
+var (
+	fakePostgresMu  sync.Mutex
+	fakePostgresDBs = map[string]*fakePostgres{}
+)
+
+func init() {
+	sql.Register("fakepostgres", fakePostgresDriver{})
+}
+
+// openFakePostgres returns a fake server reachable at the returned DSN.
+func openFakePostgres(t *testing.T) (*fakePostgres, string) {
+	fake := &fakePostgres{}
+	fakePostgresMu.Lock()
+	fakePostgresDBs[t.Name()] = fake
+	fakePostgresMu.Unlock()
+	return fake, t.Name()
+}
+
+// statements returns the executed statements containing substr.
+func (f *fakePostgres) statements(substr string) []fakePostgresCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []fakePostgresCall
+	for _, c := range f.execs {
+		if strings.Contains(c.query, substr) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+type fakePostgresDriver struct{}
+
+func (fakePostgresDriver) Open(dsn string) (driver.Conn, error) {
+	fakePostgresMu.Lock()
+	defer fakePostgresMu.Unlock()
+	fake, ok := fakePostgresDBs[dsn]
+	if !ok {
+		return nil, errors.New("unknown fake database")
+	}
+	return &fakePostgresConn{fake: fake}, nil
+}
+
+type fakePostgresConn struct{ fake *fakePostgres }
+
+func (c *fakePostgresConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakePostgresStmt{fake: c.fake, query: query}, nil
+}
+func (c *fakePostgresConn) Close() error              { return nil }
+func (c *fakePostgresConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakePostgresConn) Commit() error             { return nil }
+func (c *fakePostgresConn) Rollback() error           { return nil }
+
+type fakePostgresStmt struct {
+	fake  *fakePostgres
+	query string
+}
+
+func (s *fakePostgresStmt) Close() error  { return nil }
+func (s *fakePostgresStmt) NumInput() int { return -1 }
+
+func (s *fakePostgresStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.fake.mu.Lock()
+	defer s.fake.mu.Unlock()
+	s.fake.execs = append(s.fake.execs, fakePostgresCall{query: s.query, args: args})
+	return driver.RowsAffected(2), nil
+}
+
+func (s *fakePostgresStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.fake.mu.Lock()
+	respond := s.fake.respond
+	s.fake.mu.Unlock()
+	rows := &fakePostgresRows{columns: []string{"data"}}
+	if respond != nil {
+		rows.columns, rows.rows = respond(s.query, args)
+	}
+	return rows, nil
+}
+
+type fakePostgresRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakePostgresRows) Columns() []string { return r.columns }
+func (r *fakePostgresRows) Close() error      { return nil }
+func (r *fakePostgresRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// schemaVersion answers the schema version query with version.
+func schemaVersion(version int64) func(string, []driver.Value) ([]string, [][]driver.Value) {
+	return func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "MAX(version)") {
+			return []string{"version"}, [][]driver.Value{{version}}
+		}
+		return []string{"data"}, nil
+	}
+}
+
+func TestPostgresStorageMigrates(t *testing.T) {
+	ctx := context.Background()
+	fake, dsn := openFakePostgres(t)
+	fake.respond = schemaVersion(0)
+
+	s, err := NewPostgresStorage(ctx, PostgresConfig{DSN: dsn, DriverName: "fakepostgres", MaxOpenConns: 3})
+	if err != nil {
+		t.Fatalf("NewPostgresStorage: %v", err)
+	}
+	defer s.Close()
+
+	if len(fake.statements("pg_advisory_xact_lock")) != 1 {
+		t.Error("expected migrations under an advisory lock")
+	}
+	if len(fake.statements("CREATE TABLE pocketping_sessions")) != 1 || len(fake.statements("CREATE INDEX pocketping_messages_session_idx")) != 1 {
+		t.Error("expected the tables and indices to be created")
+	}
+	recorded := fake.statements("INSERT INTO pocketping_schema_migrations")
+	if len(recorded) != len(postgresMigrations) || recorded[len(recorded)-1].args[0] != int64(len(postgresMigrations)) {
+		t.Errorf("recorded migrations = %v", recorded)
+	}
+	if got := s.DB().Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("MaxOpenConnections = %d", got)
+	}
+
+	// An up-to-date schema is left alone
+	fake.respond = schemaVersion(int64(len(postgresMigrations)))
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(fake.statements("CREATE TABLE pocketping_sessions")) != 1 {
+		t.Error("expected no migration to be applied twice")
+	}
+
+	// A newer schema is refused
+	fake.respond = schemaVersion(int64(len(postgresMigrations) + 1))
+	if err := s.Migrate(ctx); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestPostgresStorageConfig(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewPostgresStorage(ctx, PostgresConfig{}); err == nil {
+		t.Error("expected an error without a DSN")
+	}
+
+	fake, dsn := openFakePostgres(t)
+	db, err := sql.Open("fakepostgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s, err := NewPostgresStorageFromDB(ctx, db, PostgresConfig{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("NewPostgresStorageFromDB: %v", err)
+	}
+	if len(fake.statements("")) != 0 {
+		t.Error("expected SkipMigrations to leave the schema alone")
+	}
+	if got := db.Stats().MaxOpenConnections; got != DefaultPostgresMaxOpenConns {
+		t.Errorf("MaxOpenConnections = %d", got)
+	}
+
+	// Close leaves a pool it didn't open
+	s.Close()
+	if err := db.PingContext(ctx); err != nil {
+		t.Errorf("Ping after Close: %v", err)
+	}
+}
+
+func TestPostgresStorageSessionsAndMessages(t *testing.T) {
+	ctx := context.Background()
+	fake, dsn := openFakePostgres(t)
+	s, err := NewPostgresStorage(ctx, PostgresConfig{DSN: dsn, DriverName: "fakepostgres", SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("NewPostgresStorage: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	session := &Session{ID: "s1", VisitorID: "v1", CreatedAt: now, LastActivity: now}
+	if err := s.CreateSession(ctx, session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	saved := fake.statements("INSERT INTO pocketping_sessions")
+	if len(saved) != 1 || saved[0].args[0] != "s1" || saved[0].args[1] != "v1" {
+		t.Fatalf("saved = %v", saved)
+	}
+
+	// Reads decode the stored JSON; a missing row is nil
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "FROM pocketping_sessions WHERE id") && args[0] == "s1":
+			return []string{"data"}, [][]driver.Value{{[]byte(saved[0].args[4].(string))}}
+		case strings.Contains(query, "FROM pocketping_messages\n\t\tWHERE session_id"):
+			return []string{"data"}, [][]driver.Value{
+				{[]byte(`{"id":"m1","sessionId":"s1","content":"Hi"}`)},
+				{[]byte(`{"id":"m2","sessionId":"s1","content":"Hello"}`)},
+			}
+		case strings.Contains(query, "SELECT bridge_ids") && args[0] == "m1":
+			return []string{"bridge_ids"}, [][]driver.Value{{[]byte(`{"telegramMessageId":7,"slackMessageTs":"1.2"}`)}}
+		case strings.Contains(query, "SELECT bridge_ids"):
+			return []string{"bridge_ids"}, [][]driver.Value{{nil}}
+		case strings.Contains(query, "bridge_ids @>") && args[0] == `{"telegramMessageId":7}` && args[1] == "s1":
+			return []string{"id"}, [][]driver.Value{{"m1"}}
+		case strings.Contains(query, "last_activity < $1 AND NOT data ? 'endedAt'") && args[0] == now:
+			return []string{"data"}, [][]driver.Value{{[]byte(saved[0].args[4].(string))}}
+		case strings.Contains(query, "RETURNING id"):
+			return []string{"id"}, [][]driver.Value{{"s1"}, {"s2"}}
+		}
+		return []string{"data"}, nil
+	}
+	got, err := s.GetSession(ctx, "s1")
+	if err != nil || got == nil || got.VisitorID != "v1" || !got.CreatedAt.Equal(now) {
+		t.Fatalf("GetSession = %+v, %v", got, err)
+	}
+	if got, err := s.GetSession(ctx, "missing"); got != nil || err != nil {
+		t.Errorf("GetSession(missing) = %+v, %v", got, err)
+	}
+	if got, err := s.GetMessage(ctx, "missing"); got != nil || err != nil {
+		t.Errorf("GetMessage(missing) = %+v, %v", got, err)
+	}
+
+	messages, err := s.GetMessages(ctx, "s1", "", 0)
+	if err != nil || len(messages) != 2 || messages[1].Content != "Hello" {
+		t.Fatalf("GetMessages = %+v, %v", messages, err)
+	}
+
+	// Bridge IDs are merged in the database
+	if err := s.SaveBridgeMessageIDs(ctx, "m1", BridgeMessageIds{DiscordMessageID: "d1"}); err != nil {
+		t.Fatalf("SaveBridgeMessageIDs: %v", err)
+	}
+	merge := fake.statements("SET bridge_ids")
+	if len(merge) != 1 || merge[0].args[1] != `{"discordMessageId":"d1"}` {
+		t.Errorf("merge = %v", merge)
+	}
+	ids, err := s.GetBridgeMessageIDs(ctx, "m1")
+	if err != nil || ids == nil || ids.TelegramMessageID != 7 || ids.SlackMessageTS != "1.2" {
+		t.Errorf("GetBridgeMessageIDs = %+v, %v", ids, err)
+	}
+	if ids, err := s.GetBridgeMessageIDs(ctx, "m2"); ids != nil || err != nil {
+		t.Errorf("GetBridgeMessageIDs(unset) = %+v, %v", ids, err)
+	}
+	if id, err := s.FindMessageByBridgeID(ctx, "s1", "telegram", "7"); id != "m1" || err != nil {
+		t.Errorf("FindMessageByBridgeID = %q, %v", id, err)
+	}
+	if id, err := s.FindMessageByBridgeID(ctx, "s1", "slack", "9.9"); id != "" || err != nil {
+		t.Errorf("FindMessageByBridgeID(unknown) = %q, %v", id, err)
+	}
+
+	// Only the open sessions inactive since the cutoff are read
+	if inactive, err := s.ListInactiveSessions(ctx, now); err != nil || len(inactive) != 1 || inactive[0].ID != "s1" {
+		t.Errorf("ListInactiveSessions = %+v, %v", inactive, err)
+	}
+
+	// Cleanup drops the sessions' messages with them
+	var removed []string
+	s.OnSessionDeleted(func(sessionID string) { removed = append(removed, sessionID) })
+	deleted, err := s.CleanupOldSessions(ctx, now)
+	if err != nil || deleted != 2 || len(removed) != 2 {
+		t.Errorf("CleanupOldSessions = %d, %v; hooks got %v", deleted, err, removed)
+	}
+	if len(fake.statements("DELETE FROM pocketping_messages WHERE session_id IN")) != 1 {
+		t.Error("expected the old sessions' messages to be deleted")
+	}
+}
+
+func TestPostgresStorageCountsAndCursors(t *testing.T) {
+	ctx := context.Background()
+	fake, dsn := openFakePostgres(t)
+	s, err := NewPostgresStorage(ctx, PostgresConfig{DSN: dsn, DriverName: "fakepostgres", SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("NewPostgresStorage: %v", err)
+	}
+	defer s.Close()
+
+	var counts []fakePostgresCall
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "COUNT(*)"):
+			counts = append(counts, fakePostgresCall{query: query, args: args})
+			return []string{"count"}, [][]driver.Value{{int64(3)}}
+		case strings.Contains(query, "FROM pocketping_notify_cursors") && args[0] == "s1" && args[1] == "telegram":
+			return []string{"message_id"}, [][]driver.Value{{"m7"}}
+		}
+		return []string{"data"}, nil
+	}
+
+	// Counts are answered by the database, not by listing
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if n, err := s.CountSessions(ctx, SessionCountFilter{CreatedSince: &since, VisitorID: "v1"}); n != 3 || err != nil {
+		t.Errorf("CountSessions = %d, %v", n, err)
+	}
+	if n, err := s.CountMessages(ctx, MessageCountFilter{SessionID: "s1", Sender: SenderVisitor}); n != 3 || err != nil {
+		t.Errorf("CountMessages = %d, %v", n, err)
+	}
+	if _, err := s.CountMessages(ctx, MessageCountFilter{IncludeDeleted: true}); err != nil {
+		t.Errorf("CountMessages(all): %v", err)
+	}
+	want := []fakePostgresCall{
+		{`SELECT COUNT(*) FROM pocketping_sessions WHERE created_at >= $1 AND visitor_id = $2`, []driver.Value{since, "v1"}},
+		{`SELECT COUNT(*) FROM pocketping_messages WHERE session_id = $1 AND data->>'sender' = $2 AND NOT data ? 'deletedAt'`, []driver.Value{"s1", "visitor"}},
+		{`SELECT COUNT(*) FROM pocketping_messages`, nil},
+	}
+	if len(counts) != len(want) {
+		t.Fatalf("count queries = %v", counts)
+	}
+	for i := range want {
+		if counts[i].query != want[i].query || fmt.Sprint(counts[i].args) != fmt.Sprint(want[i].args) {
+			t.Errorf("count query %d = %q %v, want %q %v", i, counts[i].query, counts[i].args, want[i].query, want[i].args)
+		}
+	}
+
+	// Notify cursors are upserted per session and bridge
+	if err := s.SetLastNotified(ctx, "s1", "telegram", "m8"); err != nil {
+		t.Fatalf("SetLastNotified: %v", err)
+	}
+	set := fake.statements("INSERT INTO pocketping_notify_cursors")
+	if len(set) != 1 || set[0].args[2] != "m8" || !strings.Contains(set[0].query, "ON CONFLICT (session_id, bridge_key)") {
+		t.Errorf("set = %v", set)
+	}
+	if id, err := s.GetLastNotified(ctx, "s1", "telegram"); id != "m7" || err != nil {
+		t.Errorf("GetLastNotified = %q, %v", id, err)
+	}
+	if id, err := s.GetLastNotified(ctx, "s1", "slack"); id != "" || err != nil {
+		t.Errorf("GetLastNotified(unset) = %q, %v", id, err)
+	}
+
+	// Deleting a session drops its cursors
+	if err := s.DeleteSession(ctx, "s1"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if len(fake.statements("DELETE FROM pocketping_notify_cursors WHERE session_id = $1")) != 1 {
+		t.Error("expected the session's notify cursors to be deleted")
+	}
+}
+
+// TestPostgresStorageIntegration runs PostgresStorage against a real server
+// at POCKETPING_TEST_POSTGRES_URL, with the database/sql driver named by
+// POCKETPING_TEST_POSTGRES_DRIVER (default DefaultPostgresDriver), which the
+// test binary must import. It is skipped otherwise.
+func TestPostgresStorageIntegration(t *testing.T) {
+	dsn := os.Getenv("POCKETPING_TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("POCKETPING_TEST_POSTGRES_URL not set")
+	}
+	driverName := os.Getenv("POCKETPING_TEST_POSTGRES_DRIVER")
+	if driverName == "" {
+		driverName = DefaultPostgresDriver
+	}
+	registered := false
+	for _, name := range sql.Drivers() {
+		registered = registered || name == driverName
+	}
+	if !registered {
+		t.Skipf("database/sql driver %q isn't imported by the test binary", driverName)
+	}
+
+	ctx := context.Background()
+	s, err := NewPostgresStorage(ctx, PostgresConfig{DSN: dsn, DriverName: driverName})
+	if err != nil {
+		t.Fatalf("NewPostgresStorage: %v", err)
+	}
+	defer s.Close()
+	// Migrating again is a no-op
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	prefix := fmt.Sprintf("it-%d-", time.Now().UnixNano())
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	session := &Session{
+		ID:           prefix + "s1",
+		VisitorID:    prefix + "v1",
+		CreatedAt:    now,
+		LastActivity: now,
+		Identity:     &UserIdentity{ID: prefix + "user"},
+	}
+	if err := s.CreateSession(ctx, session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	defer s.DeleteSession(ctx, session.ID)
+
+	got, err := s.GetSession(ctx, session.ID)
+	if err != nil || got == nil || got.VisitorID != session.VisitorID || !got.CreatedAt.Equal(now) {
+		t.Fatalf("GetSession = %+v, %v", got, err)
+	}
+	if got, err := s.GetSessionByVisitorID(ctx, session.VisitorID); err != nil || got == nil || got.ID != session.ID {
+		t.Errorf("GetSessionByVisitorID = %+v, %v", got, err)
+	}
+	if got, err := s.GetSession(ctx, prefix+"missing"); got != nil || err != nil {
+		t.Errorf("GetSession(missing) = %+v, %v", got, err)
+	}
+	session.AIActive = true
+	if err := s.UpdateSession(ctx, session); err != nil {
+		t.Fatalf("UpdateSession: %v", err)
+	}
+	if got, _ := s.GetSession(ctx, session.ID); got == nil || !got.AIActive {
+		t.Errorf("updated session = %+v", got)
+	}
+	if sessions, err := s.FindSessionsByIdentity(ctx, prefix+"user"); err != nil || len(sessions) != 1 {
+		t.Errorf("FindSessionsByIdentity = %d sessions, %v", len(sessions), err)
+	}
+	since := now.Add(-time.Second)
+	if sessions, err := s.ListSessions(ctx, &since); err != nil || len(sessions) == 0 {
+		t.Errorf("ListSessions = %d sessions, %v", len(sessions), err)
+	}
+
+	// Messages are paged in save order
+	for i, content := range []string{"Hi", "Hello", "Bye"} {
+		message := &Message{
+			ID:        fmt.Sprintf("%sm%d", prefix, i+1),
+			SessionID: session.ID,
+			Content:   content,
+			Sender:    SenderVisitor,
+			Timestamp: now,
+		}
+		if err := s.SaveMessage(ctx, message); err != nil {
+			t.Fatalf("SaveMessage: %v", err)
+		}
+	}
+	messages, err := s.GetMessages(ctx, session.ID, prefix+"m1", 1)
+	if err != nil || len(messages) != 1 || messages[0].Content != "Hello" {
+		t.Errorf("GetMessages after m1 = %+v, %v", messages, err)
+	}
+	message, err := s.GetMessage(ctx, prefix+"m3")
+	if err != nil || message == nil || message.Content != "Bye" {
+		t.Fatalf("GetMessage = %+v, %v", message, err)
+	}
+	message.Content = "Goodbye"
+	if err := s.UpdateMessage(ctx, message); err != nil {
+		t.Fatalf("UpdateMessage: %v", err)
+	}
+	if got, _ := s.GetMessage(ctx, prefix+"m3"); got == nil || got.Content != "Goodbye" {
+		t.Errorf("updated message = %+v", got)
+	}
+
+	// Counts skip deleted messages unless asked
+	deletedAt := now
+	deleted := &Message{ID: prefix + "m9", SessionID: session.ID, Content: "Gone", Sender: SenderOperator, Timestamp: now, DeletedAt: &deletedAt}
+	if err := s.SaveMessage(ctx, deleted); err != nil {
+		t.Fatalf("SaveMessage: %v", err)
+	}
+	if n, err := s.CountMessages(ctx, MessageCountFilter{SessionID: session.ID}); n != 3 || err != nil {
+		t.Errorf("CountMessages = %d, %v", n, err)
+	}
+	if n, err := s.CountMessages(ctx, MessageCountFilter{SessionID: session.ID, IncludeDeleted: true}); n != 4 || err != nil {
+		t.Errorf("CountMessages(deleted) = %d, %v", n, err)
+	}
+	if n, err := s.CountSessions(ctx, SessionCountFilter{VisitorID: session.VisitorID, ActiveSince: &since}); n != 1 || err != nil {
+		t.Errorf("CountSessions = %d, %v", n, err)
+	}
+
+	// Notify cursors are replaced per bridge
+	for _, id := range []string{prefix + "m1", prefix + "m2"} {
+		if err := s.SetLastNotified(ctx, session.ID, "telegram", id); err != nil {
+			t.Fatalf("SetLastNotified: %v", err)
+		}
+	}
+	if id, err := s.GetLastNotified(ctx, session.ID, "telegram"); id != prefix+"m2" || err != nil {
+		t.Errorf("GetLastNotified = %q, %v", id, err)
+	}
+	if id, err := s.GetLastNotified(ctx, session.ID, "slack"); id != "" || err != nil {
+		t.Errorf("GetLastNotified(unset) = %q, %v", id, err)
+	}
+
+	// Bridge IDs are merged and indexed
+	for _, ids := range []BridgeMessageIds{{TelegramMessageID: 7}, {SlackMessageTS: "1.2"}} {
+		if err := s.SaveBridgeMessageIDs(ctx, prefix+"m1", ids); err != nil {
+			t.Fatalf("SaveBridgeMessageIDs: %v", err)
+		}
+	}
+	ids, err := s.GetBridgeMessageIDs(ctx, prefix+"m1")
+	if err != nil || ids == nil || ids.TelegramMessageID != 7 || ids.SlackMessageTS != "1.2" {
+		t.Errorf("GetBridgeMessageIDs = %+v, %v", ids, err)
+	}
+	if ids, err := s.GetBridgeMessageIDs(ctx, prefix+"m2"); ids != nil || err != nil {
+		t.Errorf("GetBridgeMessageIDs(unset) = %+v, %v", ids, err)
+	}
+	if id, err := s.FindMessageByBridgeID(ctx, session.ID, "slack", "1.2"); id != prefix+"m1" || err != nil {
+		t.Errorf("FindMessageByBridgeID = %q, %v", id, err)
+	}
+
+	// Cleanup drops old sessions with their messages, and runs the hooks
+	old := &Session{ID: prefix + "s2", VisitorID: prefix + "v2", CreatedAt: now, LastActivity: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := s.CreateSession(ctx, old); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.SaveMessage(ctx, &Message{ID: prefix + "m4", SessionID: old.ID, Content: "Old", Sender: SenderVisitor, Timestamp: now}); err != nil {
+		t.Fatalf("SaveMessage: %v", err)
+	}
+	cutoff := time.Date(2001, 1, 2, 0, 0, 0, 0, time.UTC)
+	inactive, err := s.ListInactiveSessions(ctx, cutoff)
+	if err != nil || !containsSession(inactive, old.ID) || containsSession(inactive, session.ID) {
+		t.Errorf("ListInactiveSessions = %d sessions, %v", len(inactive), err)
+	}
+	removed := map[string]bool{}
+	s.OnSessionDeleted(func(sessionID string) { removed[sessionID] = true })
+	if deleted, err := s.CleanupOldSessions(ctx, cutoff); err != nil || deleted < 1 || !removed[old.ID] {
+		t.Errorf("CleanupOldSessions = %d, %v; hooks got %v", deleted, err, removed)
+	}
+	if got, err := s.GetMessage(ctx, prefix+"m4"); got != nil || err != nil {
+		t.Errorf("old session's message = %+v, %v", got, err)
+	}
+
+	if err := s.DeleteSession(ctx, session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if got, _ := s.GetSession(ctx, session.ID); got != nil || !removed[session.ID] {
+		t.Errorf("deleted session = %+v, hooks got %v", got, removed)
+	}
+	if got, _ := s.GetMessage(ctx, prefix+"m1"); got != nil {
+		t.Errorf("deleted session's message = %+v", got)
+	}
+}
+
+func containsSession(sessions []*Session, sessionID string) bool {
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package pocketping
+
+import "net"
+
+// PrivacyConfig minimizes the visitor data PocketPing stores and forwards.
+// Device, browser, OS and country are kept, so analytics still work.
+type PrivacyConfig struct {
+	// TruncateIP stores visitor IPs as their network: /24 for IPv4, /64
+	// for IPv6 (see TruncateIP).
+	TruncateIP bool
+
+	// DropUserAgent parses the user agent into DeviceType, Browser and OS,
+	// then drops it.
+	DropUserAgent bool
+
+	// MinimalWebhookMetadata sends webhooks only the session's country,
+	// language, device type, browser and OS instead of its full metadata.
+	MinimalWebhookMetadata bool
+}
+
+// TruncateIP zeroes the host part of an IP address: the last octet of an
+// IPv4 address (/24), the last 64 bits of an IPv6 address (/64). Strings
+// that aren't IPs are returned as is.
+func TruncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String()
+}
+
+// minimizeMetadata applies Config.Privacy to metadata from a visitor. It
+// returns a copy, leaving the caller's metadata alone.
+func (pp *PocketPing) minimizeMetadata(metadata *SessionMetadata) *SessionMetadata {
+	privacy := pp.config.Privacy
+	if metadata == nil || privacy == nil {
+		return metadata
+	}
+	minimized := *metadata
+	if privacy.TruncateIP && minimized.IP != "" {
+		minimized.IP = TruncateIP(minimized.IP)
+	}
+	if privacy.DropUserAgent && minimized.UserAgent != "" {
+		deviceType, browser, os := ParseUserAgent(minimized.UserAgent)
+		if minimized.DeviceType == "" {
+			minimized.DeviceType = deviceType
+		}
+		if minimized.Browser == "" {
+			minimized.Browser = browser
+		}
+		if minimized.OS == "" {
+			minimized.OS = os
+		}
+		minimized.UserAgent = ""
+	}
+	return &minimized
+}
+
+// webhookMetadata is the session metadata sent to webhooks: none without
+// consent (Config.RequireConsent).
+func (pp *PocketPing) webhookMetadata(session *Session) *SessionMetadata {
+	if !pp.hasConsent(session) {
+		return nil
+	}
+	metadata := session.Metadata
+	if metadata == nil || pp.config.Privacy == nil || !pp.config.Privacy.MinimalWebhookMetadata {
+		return metadata
+	}
+	return &SessionMetadata{
+		Country:    metadata.Country,
+		Language:   metadata.Language,
+		DeviceType: metadata.DeviceType,
+		Browser:    metadata.Browser,
+		OS:         metadata.OS,
+	}
+}
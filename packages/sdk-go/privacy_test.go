@@ -0,0 +1,89 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTruncateIP(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.42":                    "203.0.113.0",
+		"::ffff:203.0.113.42":             "203.0.113.0",
+		"2001:db8:85a3:1:8a2e:370:7334:1": "2001:db8:85a3:1::",
+		"not-an-ip":                       "not-an-ip",
+		"":                                "",
+	}
+	for ip, want := range cases {
+		if got := TruncateIP(ip); got != want {
+			t.Errorf("TruncateIP(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestHandleConnectPrivacy(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{Privacy: &PrivacyConfig{TruncateIP: true, DropUserAgent: true}})
+	metadata := &SessionMetadata{
+		IP:        "198.51.100.7",
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Version/17.0 Mobile/15E148 Safari/604.1",
+		URL:       "https://example.com/pricing",
+	}
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: metadata})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+
+	session, _ := pp.GetSession(ctx, resp.SessionID)
+	got := session.Metadata
+	if got.IP != "198.51.100.0" || got.UserAgent != "" {
+		t.Errorf("IP = %q, UserAgent = %q", got.IP, got.UserAgent)
+	}
+	if got.DeviceType != "mobile" || got.Browser != "Safari" || got.OS != "iOS" || got.URL != "https://example.com/pricing" {
+		t.Errorf("metadata = %+v", got)
+	}
+	if metadata.IP != "198.51.100.7" || metadata.UserAgent == "" {
+		t.Error("caller's metadata was modified")
+	}
+}
+
+func TestWebhookMinimalMetadata(t *testing.T) {
+	var mu sync.Mutex
+	var payload WebhookPayload
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		_ = json.Unmarshal(body, &payload)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	ctx := context.Background()
+	pp := New(Config{WebhookURL: webhook.URL, Privacy: &PrivacyConfig{MinimalWebhookMetadata: true}})
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &SessionMetadata{
+		IP:      "198.51.100.7",
+		URL:     "https://example.com/account?token=secret",
+		Country: "FR",
+		Browser: "Firefox",
+	}})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	if err := pp.HandleCustomEvent(ctx, resp.SessionID, CustomEvent{Name: "clicked"}); err != nil {
+		t.Fatalf("HandleCustomEvent: %v", err)
+	}
+	if err := pp.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := SessionMetadata{Country: "FR", Browser: "Firefox"}
+	if got := payload.Session.Metadata; got == nil || *got != want {
+		t.Errorf("webhook metadata = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QuickReply is a tappable suggestion shown under a message.
+type QuickReply struct {
+	// Label is the text on the button.
+	Label string `json:"label"`
+	// Value is sent back when chosen. Defaults to Label.
+	Value string `json:"value,omitempty"`
+}
+
+// QuickReplyMetadataKey is the Message.Metadata key holding the chosen
+// QuickReply.Value on a visitor message that answers a message's quick
+// replies (see HandleMessage).
+const QuickReplyMetadataKey = "quickReply"
+
+// OperatorMessageOption configures SendOperatorMessage.
+type OperatorMessageOption func(*operatorMessageOptions)
+
+type operatorMessageOptions struct {
+	quickReplies  []QuickReply
+	threadID      string
+	attachments   []Attachment
+	attachmentIDs []string
+	operatorToken string
+	// bridgeMessageID is the message's ID on the source bridge
+	bridgeMessageID string
+}
+
+// WithQuickReplies attaches suggestion chips to an operator message. The
+// widget shows them under the message; the visitor's pick comes back as a
+// visitor message replying to it.
+func WithQuickReplies(replies ...QuickReply) OperatorMessageOption {
+	return func(o *operatorMessageOptions) {
+		o.quickReplies = append(o.quickReplies, replies...)
+	}
+}
+
+// normalizeQuickReplies returns a copy of replies with empty values set to
+// the label, or nil when there are none.
+func normalizeQuickReplies(replies []QuickReply) []QuickReply {
+	if len(replies) == 0 {
+		return nil
+	}
+	out := make([]QuickReply, len(replies))
+	for i, reply := range replies {
+		if reply.Value == "" {
+			reply.Value = reply.Label
+		}
+		out[i] = reply
+	}
+	return out
+}
+
+// matchQuickReply returns the quick reply whose label or value is content.
+func matchQuickReply(replies []QuickReply, content string) (QuickReply, bool) {
+	content = strings.TrimSpace(content)
+	for _, reply := range replies {
+		if strings.EqualFold(content, reply.Label) || content == reply.Value {
+			return reply, true
+		}
+	}
+	return QuickReply{}, false
+}
+
+// annotateQuickReply tags a visitor message that answers the quick replies
+// of the message it replies to with the chosen value under
+// QuickReplyMetadataKey.
+func (pp *PocketPing) annotateQuickReply(ctx context.Context, message *Message) {
+	if message.ReplyTo == "" {
+		return
+	}
+	original, err := pp.storage.GetMessage(ctx, message.ReplyTo)
+	if err != nil || original == nil || original.SessionID != message.SessionID {
+		return
+	}
+	reply, ok := matchQuickReply(original.QuickReplies, message.Content)
+	if !ok {
+		return
+	}
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]interface{})
+	}
+	message.Metadata[QuickReplyMetadataKey] = reply.Value
+}
+
+// quickRepliesText renders quick replies as numbered options for bridges
+// that have no buttons, or "" when there are none.
+func quickRepliesText(replies []QuickReply) string {
+	if len(replies) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	for i, reply := range replies {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, reply.Label)
+	}
+	return b.String()
+}
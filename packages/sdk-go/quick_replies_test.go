@@ -0,0 +1,84 @@
+package pocketping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendOperatorMessageWithQuickReplies(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	msg, err := pp.SendOperatorMessage(ctx, session.ID, "Which plan?", "", "Bob",
+		WithQuickReplies(QuickReply{Label: "Free"}, QuickReply{Label: "Pro", Value: "pro"}))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if len(msg.QuickReplies) != 2 || msg.QuickReplies[0].Value != "Free" {
+		t.Errorf("quick replies = %+v", msg.QuickReplies)
+	}
+	broadcast := conn.events[0].Data.(*Message)
+	if len(broadcast.QuickReplies) != 2 {
+		t.Errorf("broadcast quick replies = %+v", broadcast.QuickReplies)
+	}
+
+	// The visitor's pick comes back as a reply and is tagged with its value.
+	resp, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "pro", Sender: SenderVisitor, ReplyTo: msg.ID})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	answer, _ := pp.storage.GetMessage(ctx, resp.MessageID)
+	if answer.Metadata[QuickReplyMetadataKey] != "pro" {
+		t.Errorf("metadata = %v, want quickReply=pro", answer.Metadata)
+	}
+
+	// Free text is not tagged, and visitors can't attach quick replies.
+	resp, _ = pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "not sure", Sender: SenderVisitor, ReplyTo: msg.ID,
+		QuickReplies: []QuickReply{{Label: "x"}}})
+	other, _ := pp.storage.GetMessage(ctx, resp.MessageID)
+	if other.Metadata != nil || other.QuickReplies != nil {
+		t.Errorf("free text message = %+v", other)
+	}
+}
+
+func TestAIReplyQuickReplies(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeActionProvider{result: AIResult{Reply: "Need more help?", QuickReplies: []QuickReply{{Label: "Yes"}, {Label: "No"}}}}
+	pp := New(Config{
+		AIProvider:      provider,
+		AITakeoverDelay: -1,
+		AIActions:       []AIAction{{Name: "noop", Handler: func(context.Context, *Session, map[string]interface{}) (string, error) { return "", nil }}},
+	})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "hi")
+
+	ai := aiMessages(ctx, t, pp, session.ID)
+	if len(ai) != 1 || len(ai[0].QuickReplies) != 2 || ai[0].QuickReplies[1].Value != "No" {
+		t.Errorf("AI messages = %+v", ai)
+	}
+}
+
+func TestTelegramRendersQuickReplies(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		texts = append(texts, r.PostForm.Get("text"))
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	bridge := telegramBridgeTo(t, srv)
+	msg := &Message{ID: "m1", SessionID: "sess-1", Content: "Which plan?", Sender: SenderOperator,
+		QuickReplies: []QuickReply{{Label: "Free"}, {Label: "Pro"}}}
+	_ = bridge.OnOperatorMessage(context.Background(), msg, sampleSession(), "", "Bob")
+
+	if len(texts) != 1 || !strings.HasSuffix(texts[0], "Which plan?\n\n1. Free\n2. Pro") {
+		t.Errorf("texts = %q", texts)
+	}
+}
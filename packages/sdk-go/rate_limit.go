@@ -0,0 +1,186 @@
+package pocketping
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is wrapped by the *RateLimitError HandleMessage and
+// HandleConnect return for a visitor over Config.RateLimit.
+var ErrRateLimited = newError("rate_limited", http.StatusTooManyRequests, "Too many messages, please slow down")
+
+// RateLimitError is returned for a visitor message or session over
+// Config.RateLimit.
+// It wraps ErrRateLimited, and WriteError answers it with a Retry-After
+// header.
+type RateLimitError struct {
+	// RetryAfter is how long until the visitor may send again.
+	RetryAfter time.Duration
+	// Scope is what hit the limit: "visitor" or "ip" for messages, "connect"
+	// for new sessions.
+	Scope string
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s (retry after %s)", ErrRateLimited.Message, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// retryAfterSeconds returns RetryAfter rounded up to whole seconds, for the
+// Retry-After header.
+func (e *RateLimitError) retryAfterSeconds() string {
+	return strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// RateLimitConfig limits visitor messages and new sessions, so a malicious
+// visitor can't flood bridges and webhooks. Limits refill continuously: 6
+// per minute is one message every 10 seconds, after a burst of Burst.
+//
+// The IP limits use the ClientIP the server sets on ConnectRequest and
+// SendMessageRequest, or else the session's Metadata.IP.
+type RateLimitConfig struct {
+	// VisitorPerMinute is the most messages a visitor ID may send per
+	// minute, across all its sessions. Zero means no visitor limit.
+	VisitorPerMinute int
+	// IPPerMinute is the most messages all visitors from one IP address may
+	// send per minute. Zero means no IP limit.
+	IPPerMinute int
+	// ConnectPerMinute is the most sessions one IP address may start per
+	// minute. HandleConnect refuses the others; returning visitors still
+	// connect. Zero means no connect limit.
+	ConnectPerMinute int
+	// Burst is how many messages may be sent, or sessions started, at once.
+	// Defaults to the per-minute limit.
+	Burst int
+}
+
+// rateBucket is a token bucket.
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter holds the token buckets of Config.RateLimit by key.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+// refill returns key's bucket, refilled at perMinute up to burst.
+func (l *rateLimiter) refill(key string, perMinute, burst int, now time.Time) *rateBucket {
+	if burst <= 0 {
+		burst = perMinute
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Minutes()*float64(perMinute))
+	b.last = now
+	return b
+}
+
+// wait returns how long until b holds a token, refilled at perMinute.
+func (b *rateBucket) wait(perMinute int) time.Duration {
+	return time.Duration((1 - b.tokens) / float64(perMinute) * float64(time.Minute))
+}
+
+// sweep drops the buckets that are full again, at most once a minute.
+func (l *rateLimiter) sweep(config *RateLimitConfig, now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	// An empty bucket is full again after burst / perMinute minutes, and
+	// then no different from a new one
+	idle := time.Minute
+	for _, perMinute := range []int{config.VisitorPerMinute, config.IPPerMinute, config.ConnectPerMinute} {
+		if perMinute > 0 && config.Burst > perMinute {
+			if d := time.Duration(float64(time.Minute) * float64(config.Burst) / float64(perMinute)); d > idle {
+				idle = d
+			}
+		}
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// checkRateLimit takes a visitor message of session, sent from clientIP
+// (empty if unknown), off Config.RateLimit, returning a *RateLimitError when
+// it is over.
+func (pp *PocketPing) checkRateLimit(session *Session, clientIP string) error {
+	config := pp.config.RateLimit
+	if config == nil {
+		return nil
+	}
+	if clientIP == "" && session.Metadata != nil {
+		clientIP = session.Metadata.IP
+	}
+	now := time.Now()
+	l := pp.lockRateLimits(config, now)
+	defer l.mu.Unlock()
+
+	// Both limits must have room before either is charged
+	var buckets []*rateBucket
+	if config.VisitorPerMinute > 0 {
+		b := l.refill("visitor:"+session.VisitorID, config.VisitorPerMinute, config.Burst, now)
+		if b.tokens < 1 {
+			return &RateLimitError{RetryAfter: b.wait(config.VisitorPerMinute), Scope: "visitor"}
+		}
+		buckets = append(buckets, b)
+	}
+	if config.IPPerMinute > 0 && clientIP != "" {
+		b := l.refill("ip:"+clientIP, config.IPPerMinute, config.Burst, now)
+		if b.tokens < 1 {
+			return &RateLimitError{RetryAfter: b.wait(config.IPPerMinute), Scope: "ip"}
+		}
+		buckets = append(buckets, b)
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return nil
+}
+
+// checkConnectRateLimit takes a new session from clientIP off
+// Config.RateLimit, returning a *RateLimitError when it is over.
+func (pp *PocketPing) checkConnectRateLimit(clientIP string) error {
+	config := pp.config.RateLimit
+	if config == nil || config.ConnectPerMinute <= 0 || clientIP == "" {
+		return nil
+	}
+	now := time.Now()
+	l := pp.lockRateLimits(config, now)
+	defer l.mu.Unlock()
+
+	b := l.refill("connect:"+clientIP, config.ConnectPerMinute, config.Burst, now)
+	if b.tokens < 1 {
+		return &RateLimitError{RetryAfter: b.wait(config.ConnectPerMinute), Scope: "connect"}
+	}
+	b.tokens--
+	return nil
+}
+
+// lockRateLimits locks the Config.RateLimit buckets and sweeps them. The
+// caller unlocks.
+func (pp *PocketPing) lockRateLimits(config *RateLimitConfig, now time.Time) *rateLimiter {
+	l := &pp.rateLimits
+	l.mu.Lock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*rateBucket)
+	}
+	l.sweep(config, now)
+	return l
+}
@@ -0,0 +1,163 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitVisitor(t *testing.T) {
+	ctx := context.Background()
+	bridge := newRecordingBridge("slack")
+	pp := New(Config{Bridges: []Bridge{bridge}, RateLimit: &RateLimitConfig{VisitorPerMinute: 6, Burst: 2}})
+	first := connectVisitor(ctx, t, pp, "v1")
+	second := connectVisitor(ctx, t, pp, "v2")
+
+	sendVisitorMessage(t, pp, first, "one")
+	sendVisitorMessage(t, pp, first, "two")
+	_, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: first, Content: "three", Sender: SenderVisitor})
+	var rateLimited *RateLimitError
+	if !errors.As(err, &rateLimited) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v", err)
+	}
+	// 6 per minute refills a message every 10 seconds
+	if rateLimited.Scope != "visitor" || rateLimited.RetryAfter <= 9*time.Second || rateLimited.RetryAfter > 10*time.Second {
+		t.Errorf("rate limit = %+v", rateLimited)
+	}
+	if AsError(err).HTTPStatus != 429 {
+		t.Errorf("status = %d", AsError(err).HTTPStatus)
+	}
+
+	// Other sessions and operators aren't limited
+	sendVisitorMessage(t, pp, second, "hello")
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: first, Content: "reply", Sender: SenderOperator}); err != nil {
+		t.Errorf("operator message: %v", err)
+	}
+	pp.dispatcher.wait()
+	if len(bridge.messages) != 3 {
+		t.Errorf("bridge got %d messages", len(bridge.messages))
+	}
+
+	// A new session of the same visitor shares the limit
+	if err := pp.CloseSession(ctx, first, "test"); err != nil {
+		t.Fatal(err)
+	}
+	again := connectVisitor(ctx, t, pp, "v1")
+	if again == first {
+		t.Fatal("expected a new session")
+	}
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: again, Content: "four", Sender: SenderVisitor}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("new session: err = %v", err)
+	}
+}
+
+func TestRateLimitIP(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{RateLimit: &RateLimitConfig{VisitorPerMinute: 10, IPPerMinute: 2}})
+	var sessions []string
+	for _, visitor := range []string{"v1", "v2", "v3"} {
+		resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: visitor, Metadata: &SessionMetadata{IP: "203.0.113.7"}})
+		if err != nil {
+			t.Fatalf("HandleConnect: %v", err)
+		}
+		sessions = append(sessions, resp.SessionID)
+	}
+
+	sendVisitorMessage(t, pp, sessions[0], "one")
+	sendVisitorMessage(t, pp, sessions[1], "two")
+	_, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessions[2], Content: "three", Sender: SenderVisitor})
+	var rateLimited *RateLimitError
+	if !errors.As(err, &rateLimited) || rateLimited.Scope != "ip" {
+		t.Fatalf("err = %v", err)
+	}
+
+	// The refused message didn't use up the visitor's own limit
+	pp.rateLimits.mu.Lock()
+	tokens := pp.rateLimits.buckets["visitor:v3"].tokens
+	pp.rateLimits.mu.Unlock()
+	if tokens != 10 {
+		t.Errorf("session tokens = %v", tokens)
+	}
+	if g := pp.Gauges(); g.RateLimitBuckets != 4 {
+		t.Errorf("buckets = %d", g.RateLimitBuckets)
+	}
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{RateLimit: &RateLimitConfig{IPPerMinute: 1}})
+	// The widget claims another IP on each connect; the server's wins
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &SessionMetadata{IP: "10.0.0.1"}, ClientIP: "203.0.113.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _ := pp.storage.GetSession(ctx, resp.SessionID)
+	if session.Metadata.IP != "203.0.113.7" {
+		t.Errorf("metadata IP = %q", session.Metadata.IP)
+	}
+
+	send := func(sessionID, clientIP string) error {
+		_, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "hi", Sender: SenderVisitor, ClientIP: clientIP})
+		return err
+	}
+	if err := send(resp.SessionID, "198.51.100.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(resp.SessionID, "198.51.100.1"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("same client IP: err = %v", err)
+	}
+	// Without a client IP, the session's applies
+	if err := send(resp.SessionID, ""); err != nil {
+		t.Errorf("session IP: err = %v", err)
+	}
+}
+
+func TestRateLimitConnect(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{RateLimit: &RateLimitConfig{ConnectPerMinute: 2}})
+	connect := func(visitorID, clientIP string) (*ConnectResponse, error) {
+		return pp.HandleConnect(ctx, ConnectRequest{VisitorID: visitorID, ClientIP: clientIP})
+	}
+	first, err := connect("v1", "203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connect("v2", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = connect("v3", "203.0.113.7")
+	var rateLimited *RateLimitError
+	if !errors.As(err, &rateLimited) || rateLimited.Scope != "connect" {
+		t.Fatalf("err = %v", err)
+	}
+
+	// Returning visitors and other IPs still connect
+	if resp, err := connect("v1", "203.0.113.7"); err != nil || resp.SessionID != first.SessionID {
+		t.Errorf("returning visitor: %v", err)
+	}
+	if _, err := connect("v3", "198.51.100.1"); err != nil {
+		t.Errorf("other IP: %v", err)
+	}
+}
+
+func TestRateLimitSweep(t *testing.T) {
+	l := &rateLimiter{buckets: make(map[string]*rateBucket)}
+	config := &RateLimitConfig{VisitorPerMinute: 6}
+	now := time.Now()
+	l.refill("visitor:old", 6, 0, now.Add(-2*time.Minute))
+	l.refill("visitor:new", 6, 0, now)
+	l.sweep(config, now)
+	if _, ok := l.buckets["visitor:old"]; ok || len(l.buckets) != 1 {
+		t.Errorf("buckets = %v", l.buckets)
+	}
+}
+
+func TestWriteErrorRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, &RateLimitError{RetryAfter: 1500 * time.Millisecond, Scope: "visitor"})
+	if rec.Code != 429 || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
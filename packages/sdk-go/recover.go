@@ -0,0 +1,44 @@
+package pocketping
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// recoveredPanics counts handler panics turned into 500s.
+var recoveredPanics atomic.Int64
+
+// RecoveredPanics returns how many handler panics RecoverHandler and the
+// webhook handlers have recovered since the process started. Export it as a
+// metric to spot crashes that no longer take the server down.
+func RecoveredPanics() int64 {
+	return recoveredPanics.Load()
+}
+
+// RecoverHandler wraps next so that a panic is logged with its stack trace
+// and answered with a 500, instead of killing the request goroutine (or, in
+// handlers without net/http's own recovery, the process).
+func RecoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer recoverHTTP(w, r.Method+" "+r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverHTTP recovers a panic in an HTTP handler, logs it and writes a 500.
+// Use it with defer. http.ErrAbortHandler is re-raised so net/http can abort
+// the response as intended.
+func recoverHTTP(w http.ResponseWriter, what string) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	if rec == http.ErrAbortHandler {
+		panic(rec)
+	}
+	recoveredPanics.Add(1)
+	log.Printf("[PocketPing] Panic handling %s: %v\n%s", what, rec, debug.Stack())
+	WriteError(w, ErrInternal)
+}
@@ -0,0 +1,42 @@
+package pocketping
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverHandler(t *testing.T) {
+	logs := captureLog(t)
+	before := RecoveredPanics()
+
+	handler := RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var session *Session
+		_ = session.ID // nil dereference
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/boom", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if got := RecoveredPanics() - before; got != 1 {
+		t.Errorf("RecoveredPanics grew by %d, want 1", got)
+	}
+	if out := logs.String(); !strings.Contains(out, "Panic handling GET /boom") || !strings.Contains(out, "goroutine") {
+		t.Errorf("expected the panic and stack trace in the log:\n%s", out)
+	}
+}
+
+func TestRecoverHandlerReraisesAbort(t *testing.T) {
+	handler := RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if recover() != http.ErrAbortHandler {
+			t.Error("expected http.ErrAbortHandler to propagate")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
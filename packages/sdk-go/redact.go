@@ -0,0 +1,44 @@
+package pocketping
+
+import (
+	"net/url"
+	"strings"
+)
+
+// webhookURLPrefixes are the incoming webhook URLs (host and path prefix)
+// whose whole path and query are the credential.
+var webhookURLPrefixes = []string{
+	"hooks.slack.com/",
+	"discord.com/api/webhooks/",
+	"discordapp.com/api/webhooks/",
+	"outlook.office.com/webhook",
+}
+
+// webhookHostSuffixes are the hosts of Teams incoming webhooks and
+// workflows, whose whole path and query are the credential.
+var webhookHostSuffixes = []string{".webhook.office.com", ".logic.azure.com"}
+
+// RedactURL returns the host and path of a bridge API URL with credentials
+// masked, for logs and fixtures: incoming webhook URLs keep only their host
+// and fixed prefix, and Telegram bot tokens and other token-like path
+// segments are replaced. The query is dropped.
+func RedactURL(u *url.URL) string {
+	hostPath := u.Host + u.Path
+	for _, prefix := range webhookURLPrefixes {
+		if strings.HasPrefix(hostPath, prefix) {
+			return prefix + "***"
+		}
+	}
+	for _, suffix := range webhookHostSuffixes {
+		if strings.HasSuffix(u.Host, suffix) {
+			return u.Host + "/***"
+		}
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if len(segment) >= 30 || (strings.HasPrefix(segment, "bot") && len(segment) > 3) {
+			segments[i] = "***"
+		}
+	}
+	return u.Host + strings.Join(segments, "/")
+}
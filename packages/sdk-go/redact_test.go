@@ -0,0 +1,26 @@
+package pocketping
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://api.telegram.org/bot123456:ABC-token/sendMessage", "api.telegram.org/***/sendMessage"},
+		{"https://hooks.slack.com/services/T000/B000/short", "hooks.slack.com/***"},
+		{"https://discord.com/api/webhooks/123/tok?wait=true", "discord.com/api/webhooks/***"},
+		{"https://contoso.webhook.office.com/webhookb2/abc/IncomingWebhook/def", "contoso.webhook.office.com/***"},
+		{"https://prod-01.westus.logic.azure.com/workflows/abc/triggers/manual/paths/invoke?sig=secret", "prod-01.westus.logic.azure.com/***"},
+		{"https://slack.com/api/chat.postMessage", "slack.com/api/chat.postMessage"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := RedactURL(u); got != tt.want {
+			t.Errorf("RedactURL(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pocketping
+
+import (
+	"context"
+	"strings"
+)
+
+// RegionResolver returns the region of a session (e.g. "eu"), or "" when
+// unknown. It's called before the session is stored, so it may use the
+// request metadata (IP, country) to do a GeoIP lookup.
+type RegionResolver func(ctx context.Context, pp *PocketPing, session *Session) string
+
+// DefaultRegionResolver maps Metadata.Country through Config.CountryRegions,
+// falling back to Config.DefaultRegion.
+func DefaultRegionResolver(ctx context.Context, pp *PocketPing, session *Session) string {
+	if session.Metadata != nil && session.Metadata.Country != "" {
+		if region, ok := pp.config.CountryRegions[strings.ToUpper(session.Metadata.Country)]; ok {
+			return region
+		}
+	}
+	return pp.config.DefaultRegion
+}
+
+// resolveRegion runs the configured RegionResolver.
+func (pp *PocketPing) resolveRegion(ctx context.Context, session *Session) string {
+	resolver := pp.config.RegionResolver
+	if resolver == nil {
+		resolver = DefaultRegionResolver
+	}
+	return resolver(ctx, pp, session)
+}
+
+// sessionRegion looks up the region of a session for notifications that only
+// carry its ID. Storage is only read when some bridge is regional.
+func (pp *PocketPing) sessionRegion(ctx context.Context, sessionID string) string {
+	if !hasRegionalBridges(pp.bridges) {
+		return ""
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil || session == nil {
+		return ""
+	}
+	return session.Region
+}
+
+// bridgeRegions returns the regions a bridge serves; nil means all.
+func bridgeRegions(b Bridge) []string {
+	if regional, ok := b.(BridgeWithRegions); ok {
+		return regional.Regions()
+	}
+	return nil
+}
+
+func hasRegionalBridges(bridges []Bridge) bool {
+	for _, b := range bridges {
+		if len(bridgeRegions(b)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// routeBridges reports, per bridge index, whether a session in region should
+// be notified on it. Bridges without regions always are; regional bridges
+// only for their regions. If no regional bridge serves region (including an
+// unknown region) and there is no global bridge either, every bridge is
+// notified so the conversation isn't lost.
+func routeBridges(bridges []Bridge, region string) []bool {
+	routed := make([]bool, len(bridges))
+	any := false
+	for i, b := range bridges {
+		regions := bridgeRegions(b)
+		if len(regions) == 0 {
+			routed[i] = true
+			any = true
+			continue
+		}
+		for _, r := range regions {
+			if region != "" && strings.EqualFold(r, region) {
+				routed[i] = true
+				any = true
+				break
+			}
+		}
+	}
+	if !any {
+		for i := range routed {
+			routed[i] = true
+		}
+	}
+	return routed
+}
@@ -0,0 +1,107 @@
+package pocketping
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHandleConnectResolvesRegion(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{
+		Storage:        NewMemoryStorage(),
+		CountryRegions: map[string]string{"FR": "eu", "US": "us"},
+		DefaultRegion:  "us",
+	})
+
+	cases := map[string]string{"fr": "eu", "US": "us", "JP": "us", "": "us"}
+	for country, want := range cases {
+		resp, err := pp.HandleConnect(ctx, ConnectRequest{
+			VisitorID: "visitor-" + country,
+			Metadata:  &SessionMetadata{Country: country},
+		})
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		session, _ := pp.GetStorage().GetSession(ctx, resp.SessionID)
+		if session.Region != want {
+			t.Errorf("country %q: expected region %q, got %q", country, want, session.Region)
+		}
+	}
+}
+
+func TestHandleConnectCustomRegionResolver(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	_ = storage.CreateSession(ctx, createTestSession("sess-1", "visitor-1", nil, nil))
+
+	pp := New(Config{
+		Storage: storage,
+		RegionResolver: func(ctx context.Context, pp *PocketPing, session *Session) string {
+			return "apac"
+		},
+	})
+
+	// Existing sessions without a region get one on their next connect.
+	if _, err := pp.HandleConnect(ctx, ConnectRequest{SessionID: "sess-1", VisitorID: "visitor-1"}); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	session, _ := storage.GetSession(ctx, "sess-1")
+	if session.Region != "apac" {
+		t.Errorf("expected region apac, got %q", session.Region)
+	}
+}
+
+func TestRegionalBridgeRouting(t *testing.T) {
+	ctx := context.Background()
+	newBridge := func(name string, regions ...string) *orderRecordingBridge {
+		return &orderRecordingBridge{
+			BaseBridge: BaseBridge{BridgeName: name, BridgeRegions: regions},
+			received:   make(map[string][]string),
+		}
+	}
+	eu, us, global := newBridge("slack-eu", "eu"), newBridge("slack-us", "US"), newBridge("telegram")
+	pp := New(Config{Bridges: []Bridge{eu, us, global}})
+
+	euSession := createTestSession("sess-eu", "v1", nil, nil)
+	euSession.Region = "eu"
+	usSession := createTestSession("sess-us", "v2", nil, nil)
+	usSession.Region = "us"
+	unknown := createTestSession("sess-unknown", "v3", nil, nil)
+
+	for _, session := range []*Session{euSession, usSession, unknown} {
+		pp.notifyBridgesMessage(ctx, createTestMessage("m-"+session.ID, session.ID, "hi"), session)
+	}
+	pp.dispatcher.wait()
+
+	expect := func(b *orderRecordingBridge, sessions ...string) {
+		t.Helper()
+		if len(b.received) != len(sessions) {
+			t.Errorf("bridge %s: expected %v, got %v", b.Name(), sessions, b.received)
+		}
+		for _, id := range sessions {
+			if len(b.received[id]) != 1 {
+				t.Errorf("bridge %s: expected a message for %s", b.Name(), id)
+			}
+		}
+	}
+	expect(eu, "sess-eu")
+	expect(us, "sess-us")
+	expect(global, "sess-eu", "sess-us", "sess-unknown")
+}
+
+func TestRouteBridgesFallsBackToAll(t *testing.T) {
+	bridges := []Bridge{
+		&BaseBridge{BridgeName: "eu", BridgeRegions: []string{"eu"}},
+		&BaseBridge{BridgeName: "us", BridgeRegions: []string{"us"}},
+	}
+
+	for _, region := range []string{"", "apac"} {
+		routed := routeBridges(bridges, region)
+		if !routed[0] || !routed[1] {
+			t.Errorf("region %q: expected fallback to every bridge, got %v", region, routed)
+		}
+	}
+	if routed := routeBridges(bridges, "eu"); !routed[0] || routed[1] {
+		t.Errorf("region eu: expected only the EU bridge, got %v", routed)
+	}
+}
@@ -0,0 +1,99 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// RedisReplayStore is a ReplayStore over Redis, for nodes whose storage
+// doesn't implement StorageWithEventReplay or isn't shared. Each session
+// keeps its last MaxReplayEvents events, for DefaultAffinityTokenTTL after
+// the latest.
+type RedisReplayStore struct {
+	redisClient
+}
+
+// NewRedisReplayStore creates a replay store for the Redis server at
+// redisURL ("redis://[user:password@]host[:port]", or "rediss://" for TLS).
+func NewRedisReplayStore(redisURL string) (*RedisReplayStore, error) {
+	endpoint, err := parseRedisURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisReplayStore{redisClient: redisClient{redisEndpoint: endpoint}}, nil
+}
+
+// AppendSessionEvent implements ReplayStore.
+func (s *RedisReplayStore) AppendSessionEvent(ctx context.Context, sessionID string, event WebSocketEvent) (int64, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return 0, err
+	}
+	seqKey, listKey := redisReplayKeys(sessionID)
+	reply, err := s.do(ctx, "INCR", seqKey)
+	if err != nil {
+		return 0, err
+	}
+	seq, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCR reply %v", reply)
+	}
+	event.Seq = seq
+	event.Data = json.RawMessage(data)
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl := strconv.FormatInt(DefaultAffinityTokenTTL.Milliseconds(), 10)
+	for _, cmd := range [][]string{
+		{"RPUSH", listKey, string(encoded)},
+		{"LTRIM", listKey, strconv.Itoa(-MaxReplayEvents), "-1"},
+		{"PEXPIRE", listKey, ttl},
+		{"PEXPIRE", seqKey, ttl},
+	} {
+		if _, err := s.do(ctx, cmd...); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+// GetSessionEvents implements ReplayStore.
+func (s *RedisReplayStore) GetSessionEvents(ctx context.Context, sessionID string, afterSeq int64) ([]WebSocketEvent, error) {
+	_, listKey := redisReplayKeys(sessionID)
+	reply, err := s.do(ctx, "LRANGE", listKey, "0", "-1")
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]interface{})
+
+	events := []WebSocketEvent{}
+	for _, item := range items {
+		data, _ := item.([]byte)
+		var event struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+			Seq  int64           `json:"seq"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil || event.Seq <= afterSeq {
+			continue
+		}
+		events = append(events, WebSocketEvent{Type: event.Type, Data: event.Data, Seq: event.Seq})
+	}
+	// Concurrent appends may have pushed out of order
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, nil
+}
+
+// redisReplayKeys returns the keys of a session's sequence counter and
+// event list.
+func redisReplayKeys(sessionID string) (seqKey, listKey string) {
+	return "pocketping:replay:" + sessionID + ":seq", "pocketping:replay:" + sessionID
+}
+
+// Ensure RedisReplayStore implements ReplayStore interface
+var _ ReplayStore = (*RedisReplayStore)(nil)
@@ -0,0 +1,168 @@
+package pocketping
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Defaults for RetryPolicy.
+const (
+	DefaultRetryMaxAttempts    = 5
+	DefaultRetryInitialBackoff = time.Second
+	DefaultRetryMaxBackoff     = time.Minute
+)
+
+// RetryPolicy retries failed bridge notifications with exponential backoff
+// (see Config.BridgeRetry). Retries happen in the notification's queue, so
+// later notifications of the same session to the same bridge wait, and
+// stay in order; other sessions and bridges aren't held up.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt. Defaults to
+	// DefaultRetryMaxAttempts.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; it doubles after
+	// each one, up to MaxBackoff. Default DefaultRetryInitialBackoff and
+	// DefaultRetryMaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter shortens each wait by a random fraction of up to Jitter (0..1),
+	// so bridges recovering from an outage aren't hit all at once.
+	Jitter float64
+	// Retryable reports whether an error is worth retrying. Defaults to
+	// every error.
+	Retryable func(err error) bool
+	// OnRetry is called before each retry, e.g. to count them.
+	OnRetry RetryHandler
+	// OnDeadLetter receives the notifications that failed for good.
+	OnDeadLetter DeadLetterHandler
+}
+
+// RetryHandler is called before a bridge notification is retried. attempt
+// is the attempt that failed with err (1 for the first).
+type RetryHandler func(ctx context.Context, bridge, operation string, attempt int, err error)
+
+// DeadLetter is a bridge notification given up on.
+type DeadLetter struct {
+	Bridge string `json:"bridge"`
+	// Operation is the bridge method, e.g. "OnVisitorMessage".
+	Operation string `json:"operation"`
+	SessionID string `json:"sessionId"`
+	// MessageID is empty for notifications not about a message.
+	MessageID string    `json:"messageId,omitempty"`
+	Attempts  int       `json:"attempts"`
+	Err       error     `json:"-"`
+	FailedAt  time.Time `json:"failedAt"`
+}
+
+// DeadLetterHandler is called with a notification given up on.
+type DeadLetterHandler func(ctx context.Context, letter DeadLetter)
+
+// backoff returns the wait before retry n (1 for the first retry).
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	wait := p.InitialBackoff
+	if wait <= 0 {
+		wait = DefaultRetryInitialBackoff
+	}
+	maxWait := p.MaxBackoff
+	if maxWait <= 0 {
+		maxWait = DefaultRetryMaxBackoff
+	}
+	for i := 1; i < n && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	if p.Jitter > 0 {
+		wait -= time.Duration(rand.Float64() * p.Jitter * float64(wait))
+	}
+	return wait
+}
+
+// deliver runs a bridge notification, retrying it per Config.BridgeRetry
+// until it succeeds or Stop is called. A notification that fails for good
+// goes to RetryPolicy.OnDeadLetter.
+// op names the bridge method; messageID may be empty.
+func (pp *PocketPing) deliver(ctx context.Context, b Bridge, op, sessionID, messageID string, fn func(ctx context.Context) error) error {
+	// The request that queued the notification may be over by the time its
+	// queue runs it, but Stop ends the attempts
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	defer context.AfterFunc(pp.stopped, cancel)()
+
+	return pp.config.BridgeRetry.run(ctx, b.Name(), op, sessionID, messageID, fn, pp.waitRetry)
+}
+
+// Do runs a bridge notification like PocketPing does, for callers that
+// notify bridges themselves: fn is retried with the policy's backoff until
+// it succeeds or ctx ends, and a notification that fails for good goes to
+// OnDeadLetter. With a nil policy fn runs once.
+func (p *RetryPolicy) Do(ctx context.Context, bridge, operation, sessionID, messageID string, fn func(ctx context.Context) error) error {
+	return p.run(ctx, bridge, operation, sessionID, messageID, fn, waitRetry)
+}
+
+// run is the retry loop of deliver and Do; wait sleeps before a retry.
+func (p *RetryPolicy) run(ctx context.Context, bridge, op, sessionID, messageID string, fn func(ctx context.Context) error, wait func(ctx context.Context, d time.Duration) bool) error {
+	err := fn(ctx)
+	if err == nil || p == nil {
+		return err
+	}
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+	attempts := 1
+	for ; attempts < maxAttempts; attempts++ {
+		if p.Retryable != nil && !p.Retryable(err) {
+			break
+		}
+		backoff := p.backoff(attempts)
+		log.Printf("[PocketPing] Bridge %s %s failed (attempt %d/%d), retrying in %s: %v", bridge, op, attempts, maxAttempts, backoff, err)
+		if !wait(ctx, backoff) {
+			break
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(ctx, bridge, op, attempts, err)
+		}
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+	}
+
+	log.Printf("[PocketPing] Bridge %s %s gave up after %d attempts: %v", bridge, op, attempts, err)
+	if p.OnDeadLetter != nil {
+		p.OnDeadLetter(context.WithoutCancel(ctx), DeadLetter{
+			Bridge:    bridge,
+			Operation: op,
+			SessionID: sessionID,
+			MessageID: messageID,
+			Attempts:  attempts,
+			Err:       err,
+			FailedAt:  time.Now(),
+		})
+	}
+	return err
+}
+
+// waitRetry waits before a retry, counted in Gauges, reporting false if ctx
+// ended first.
+func (pp *PocketPing) waitRetry(ctx context.Context, wait time.Duration) bool {
+	pp.retrying.Add(1)
+	defer pp.retrying.Add(-1)
+	return waitRetry(ctx, wait)
+}
+
+// waitRetry waits before a retry, reporting false if ctx ended first.
+func waitRetry(ctx context.Context, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,196 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyBridge fails its first failures visitor messages.
+type flakyBridge struct {
+	BaseBridge
+	mu        sync.Mutex
+	failures  int
+	attempts  int
+	delivered []string
+}
+
+func (b *flakyBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts++
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("502 bad gateway")
+	}
+	b.delivered = append(b.delivered, message.Content)
+	return nil
+}
+
+func fastRetry() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func TestBridgeRetryRecovers(t *testing.T) {
+	ctx := context.Background()
+	bridge := &flakyBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}, failures: 2}
+	pp := New(Config{Bridges: []Bridge{bridge}, BridgeRetry: fastRetry()})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	sendVisitorMessage(t, pp, sessionID, "first")
+	sendVisitorMessage(t, pp, sessionID, "second")
+	pp.dispatcher.wait()
+
+	// The retried message still goes out before the next one
+	if bridge.attempts != 4 || len(bridge.delivered) != 2 || bridge.delivered[0] != "first" {
+		t.Errorf("attempts = %d, delivered = %v", bridge.attempts, bridge.delivered)
+	}
+	if g := pp.Gauges(); g.RetryingNotifications != 0 {
+		t.Errorf("retrying = %d", g.RetryingNotifications)
+	}
+}
+
+func TestBridgeRetryDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	bridge := &flakyBridge{BaseBridge: BaseBridge{BridgeName: "slack"}, failures: 10}
+	var letters []DeadLetter
+	policy := fastRetry()
+	policy.OnDeadLetter = func(ctx context.Context, letter DeadLetter) {
+		letters = append(letters, letter)
+	}
+	pp := New(Config{Bridges: []Bridge{bridge}, BridgeRetry: policy})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	messageID := sendVisitorMessage(t, pp, sessionID, "Hi")
+	pp.dispatcher.wait()
+
+	if bridge.attempts != 3 || len(letters) != 1 {
+		t.Fatalf("attempts = %d, dead letters = %d", bridge.attempts, len(letters))
+	}
+	l := letters[0]
+	if l.Bridge != "slack" || l.Operation != "OnVisitorMessage" || l.SessionID != sessionID || l.MessageID != messageID || l.Attempts != 3 || l.Err == nil {
+		t.Errorf("dead letter = %+v", l)
+	}
+}
+
+func TestBridgeRetryNotRetryable(t *testing.T) {
+	ctx := context.Background()
+	bridge := &flakyBridge{BaseBridge: BaseBridge{BridgeName: "slack"}, failures: 10}
+	dead := 0
+	policy := fastRetry()
+	policy.Retryable = func(err error) bool { return false }
+	policy.OnDeadLetter = func(ctx context.Context, letter DeadLetter) { dead++ }
+	pp := New(Config{Bridges: []Bridge{bridge}, BridgeRetry: policy})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	sendVisitorMessage(t, pp, sessionID, "Hi")
+	pp.dispatcher.wait()
+	if bridge.attempts != 1 || dead != 1 {
+		t.Errorf("attempts = %d, dead letters = %d", bridge.attempts, dead)
+	}
+}
+
+func TestBridgeRetryDisabled(t *testing.T) {
+	ctx := context.Background()
+	bridge := &flakyBridge{BaseBridge: BaseBridge{BridgeName: "slack"}, failures: 1}
+	pp := New(Config{Bridges: []Bridge{bridge}})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	sendVisitorMessage(t, pp, sessionID, "Hi")
+	pp.dispatcher.wait()
+	if bridge.attempts != 1 || len(bridge.delivered) != 0 {
+		t.Errorf("attempts = %d, delivered = %v", bridge.attempts, bridge.delivered)
+	}
+}
+
+func TestDeliverOutlivesRequest(t *testing.T) {
+	pp := New(Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Queued notifications may first run after their request ended
+	err := pp.deliver(ctx, &flakyBridge{}, "OnVisitorMessage", "s1", "", func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Errorf("deliver = %v, want the request's cancellation ignored", err)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var retries []int
+	policy := fastRetry()
+	policy.OnRetry = func(ctx context.Context, bridge, operation string, attempt int, err error) {
+		if bridge != "discord" || operation != "OnNewSession" || err == nil {
+			t.Errorf("OnRetry(%q, %q, %d, %v)", bridge, operation, attempt, err)
+		}
+		retries = append(retries, attempt)
+	}
+
+	calls := 0
+	err := policy.Do(context.Background(), "discord", "OnNewSession", "s1", "", func(ctx context.Context) error {
+		if calls++; calls < 3 {
+			return errors.New("429 too many requests")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || len(retries) != 2 || retries[0] != 1 || retries[1] != 2 {
+		t.Errorf("err = %v, calls = %d, retries = %v", err, calls, retries)
+	}
+
+	// A nil policy runs once
+	calls = 0
+	var nilPolicy *RetryPolicy
+	if err := nilPolicy.Do(context.Background(), "discord", "OnNewSession", "s1", "", func(ctx context.Context) error {
+		calls++
+		return errors.New("down")
+	}); err == nil || calls != 1 {
+		t.Errorf("nil policy: err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+		if got := p.backoff(n); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", n, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < time.Second || got > 2*time.Second {
+			t.Fatalf("jittered backoff = %s", got)
+		}
+	}
+
+	if got := (&RetryPolicy{}).backoff(1); got != DefaultRetryInitialBackoff {
+		t.Errorf("default backoff = %s", got)
+	}
+}
+
+func TestBridgeRetryStop(t *testing.T) {
+	ctx := context.Background()
+	bridge := &flakyBridge{BaseBridge: BaseBridge{BridgeName: "slack"}, failures: 10}
+	dead := make(chan DeadLetter, 1)
+	policy := &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+	policy.OnDeadLetter = func(ctx context.Context, letter DeadLetter) { dead <- letter }
+	pp := New(Config{Bridges: []Bridge{bridge}, BridgeRetry: policy})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	sendVisitorMessage(t, pp, sessionID, "Hi")
+	waitFor(t, "the retry wait", func() bool { return pp.Gauges().RetryingNotifications == 1 })
+	if err := pp.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case letter := <-dead:
+		if letter.Attempts != 1 || letter.SessionID != sessionID {
+			t.Errorf("dead letter = %+v", letter)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't end the retry wait")
+	}
+}
@@ -0,0 +1,328 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Defaults for at-risk scoring.
+const (
+	// DefaultRiskBurstWindow is the window RiskSignals.BurstMessages counts
+	// visitor messages over.
+	DefaultRiskBurstWindow = time.Minute
+	// riskSentimentMessages is how many recent visitor messages the sentiment
+	// is averaged over.
+	riskSentimentMessages = 5
+)
+
+// RiskSignals are the inputs to a RiskScorer, computed from a session's
+// messages.
+type RiskSignals struct {
+	// VisitorMessages is the number of visitor messages in the session.
+	VisitorMessages int
+	// BurstMessages is the number of visitor messages within
+	// DefaultRiskBurstWindow of the latest one.
+	BurstMessages int
+	// Sentiment is the average sentiment of the recent visitor messages,
+	// from -1 (negative) to 1 (positive). See Sentiment.
+	Sentiment float64
+	// CurrentWait is how long the visitor has been waiting for a reply,
+	// zero when the last message isn't theirs.
+	CurrentWait time.Duration
+	// LongestWait is the longest the visitor waited for a reply so far,
+	// including CurrentWait.
+	LongestWait time.Duration
+}
+
+// RiskScorer maps RiskSignals to an at-risk score from 0 (fine) to 1
+// (likely to end badly). DefaultRiskScorer is used when Config.RiskScorer
+// is nil.
+type RiskScorer func(signals RiskSignals) float64
+
+// SessionRisk is a session's latest at-risk assessment.
+type SessionRisk struct {
+	Score   float64     `json:"score"`
+	Signals RiskSignals `json:"signals"`
+	// Flagged is set while Score is at or above Config.AtRiskThreshold;
+	// bridges are notified when it becomes set.
+	Flagged     bool       `json:"flagged"`
+	FlaggedAt   *time.Time `json:"flaggedAt,omitempty"`
+	EvaluatedAt time.Time  `json:"evaluatedAt"`
+
+	// The running state each new message is folded into, so scoring it
+	// doesn't read the session's history: when the visitor's unanswered
+	// messages began, the times of their messages within
+	// DefaultRiskBurstWindow of the latest, and the sentiments of their
+	// last few messages.
+	WaitingSince          *time.Time  `json:"waitingSince,omitempty"`
+	RecentVisitorMessages []time.Time `json:"recentVisitorMessages,omitempty"`
+	RecentSentiments      []float64   `json:"recentSentiments,omitempty"`
+}
+
+// AtRiskHandler is called when a session is flagged at risk.
+type AtRiskHandler func(session *Session, risk *SessionRisk)
+
+// DefaultRiskScorer weighs negative sentiment most, then waiting time, then
+// bursts of visitor messages.
+func DefaultRiskScorer(signals RiskSignals) float64 {
+	negative := math.Max(0, -signals.Sentiment)
+	wait := math.Max(
+		math.Min(signals.CurrentWait.Minutes()/10, 1),
+		math.Min(signals.LongestWait.Minutes()/20, 1),
+	)
+	burst := math.Min(math.Max(float64(signals.BurstMessages-2), 0)/3, 1)
+	return math.Min(0.5*negative+0.3*wait+0.2*burst, 1)
+}
+
+// EvaluateRisk scores a session with Config.RiskScorer and stores the result
+// in Session.Risk. When the score reaches Config.AtRiskThreshold, bridges
+// are notified once, until it falls below again. Messages are scored as
+// they come; call this periodically to also catch visitors who are left
+// waiting. A session without an assessment yet is scored from its history.
+func (pp *PocketPing) EvaluateRisk(ctx context.Context, sessionID string) (*SessionRisk, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	var risk SessionRisk
+	if session.Risk != nil {
+		risk = *session.Risk
+	} else {
+		messages, err := pp.allMessages(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range messages {
+			risk.observe(&messages[i])
+		}
+	}
+	flagged := pp.scoreRisk(session, risk, time.Now())
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	if flagged {
+		pp.notifyAtRisk(ctx, session)
+	}
+	return session.Risk, nil
+}
+
+// observeRisk folds a new message into the session's at-risk state and
+// rescores it, when at-risk flagging is enabled, without reading or saving
+// anything: the caller saves the session, then calls notifyAtRisk if the
+// session became flagged, which is reported. A session's state starts with
+// its first message after AtRiskThreshold is set.
+func (pp *PocketPing) observeRisk(session *Session, message *Message) bool {
+	if pp.config.AtRiskThreshold <= 0 {
+		return false
+	}
+	var risk SessionRisk
+	if session.Risk != nil {
+		risk = *session.Risk
+	}
+	risk.observe(message)
+	return pp.scoreRisk(session, risk, message.Timestamp)
+}
+
+// observeAIRisk ends the visitor's wait with an AI reply. The AI fallback
+// doesn't otherwise save the session, so this reloads and saves it, and only
+// when the visitor was waiting.
+func (pp *PocketPing) observeAIRisk(ctx context.Context, session *Session, message *Message) {
+	if pp.config.AtRiskThreshold <= 0 || session.Risk == nil || session.Risk.WaitingSince == nil {
+		return
+	}
+	session, err := pp.storage.GetSession(ctx, session.ID)
+	if err != nil || session == nil {
+		return
+	}
+	flagged := pp.observeRisk(session, message)
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		log.Printf("[PocketPing] Risk evaluation for session %s failed: %v", session.ID, err)
+		return
+	}
+	if flagged {
+		pp.notifyAtRisk(ctx, session)
+	}
+}
+
+// scoreRisk scores risk at now with Config.RiskScorer, flags it against
+// Config.AtRiskThreshold and stores it in session.Risk. It reports whether
+// the session became flagged.
+func (pp *PocketPing) scoreRisk(session *Session, risk SessionRisk, now time.Time) bool {
+	risk.Signals = risk.signalsAt(now)
+	scorer := pp.config.RiskScorer
+	if scorer == nil {
+		scorer = DefaultRiskScorer
+	}
+	risk.Score = scorer(risk.Signals)
+	risk.EvaluatedAt = now
+
+	wasFlagged := risk.Flagged
+	risk.Flagged, risk.FlaggedAt = false, nil
+	threshold := pp.config.AtRiskThreshold
+	if threshold > 0 && risk.Score >= threshold {
+		risk.Flagged = true
+		if wasFlagged && session.Risk != nil && session.Risk.FlaggedAt != nil {
+			risk.FlaggedAt = session.Risk.FlaggedAt
+		} else {
+			risk.FlaggedAt = &now
+		}
+	}
+	session.Risk = &risk
+	return risk.Flagged && !wasFlagged
+}
+
+// notifyAtRisk tells the bridges and OnAtRisk that a session was flagged.
+func (pp *PocketPing) notifyAtRisk(ctx context.Context, session *Session) {
+	pp.notifyBridgesNotice(ctx, session, formatRiskNotice(session.Risk))
+	if pp.config.OnAtRisk != nil {
+		pp.config.OnAtRisk(session, session.Risk)
+	}
+}
+
+// observe folds a message into the running state: the visitor's message
+// count, recent messages and sentiments, and their waits for a reply.
+// Deleted messages are ignored. The slices are copied, not appended to in
+// place, so a copy of a stored SessionRisk can be observed.
+func (r *SessionRisk) observe(msg *Message) {
+	if msg.DeletedAt != nil {
+		return
+	}
+	if msg.Sender != SenderVisitor {
+		if r.WaitingSince != nil {
+			if wait := msg.Timestamp.Sub(*r.WaitingSince); wait > r.Signals.LongestWait {
+				r.Signals.LongestWait = wait
+			}
+			r.WaitingSince = nil
+		}
+		return
+	}
+
+	r.Signals.VisitorMessages++
+	recent := make([]time.Time, 0, len(r.RecentVisitorMessages)+1)
+	for _, at := range r.RecentVisitorMessages {
+		if msg.Timestamp.Sub(at) <= DefaultRiskBurstWindow {
+			recent = append(recent, at)
+		}
+	}
+	r.RecentVisitorMessages = append(recent, msg.Timestamp)
+	sentiments := append(append([]float64(nil), r.RecentSentiments...), Sentiment(msg.Content))
+	if len(sentiments) > riskSentimentMessages {
+		sentiments = sentiments[len(sentiments)-riskSentimentMessages:]
+	}
+	r.RecentSentiments = sentiments
+	if r.WaitingSince == nil {
+		at := msg.Timestamp
+		r.WaitingSince = &at
+	}
+}
+
+// signalsAt derives the RiskSignals of the running state at now.
+func (r *SessionRisk) signalsAt(now time.Time) RiskSignals {
+	signals := r.Signals
+	signals.BurstMessages = len(r.RecentVisitorMessages)
+	signals.Sentiment = 0
+	for _, s := range r.RecentSentiments {
+		signals.Sentiment += s / float64(len(r.RecentSentiments))
+	}
+	signals.CurrentWait = 0
+	if r.WaitingSince != nil {
+		signals.CurrentWait = now.Sub(*r.WaitingSince)
+		if signals.CurrentWait > signals.LongestWait {
+			signals.LongestWait = signals.CurrentWait
+		}
+	}
+	return signals
+}
+
+// computeRiskSignals derives RiskSignals from a session's messages, oldest
+// first. Deleted messages are ignored.
+func computeRiskSignals(messages []Message, now time.Time) RiskSignals {
+	var risk SessionRisk
+	for i := range messages {
+		risk.observe(&messages[i])
+	}
+	return risk.signalsAt(now)
+}
+
+func formatRiskNotice(risk *SessionRisk) string {
+	var reasons []string
+	if risk.Signals.Sentiment < 0 {
+		reasons = append(reasons, fmt.Sprintf("negative tone (%.1f)", risk.Signals.Sentiment))
+	}
+	if risk.Signals.CurrentWait >= time.Minute {
+		reasons = append(reasons, "waiting "+risk.Signals.CurrentWait.Round(time.Minute).String())
+	}
+	if risk.Signals.BurstMessages > 2 {
+		reasons = append(reasons, fmt.Sprintf("%d messages in a minute", risk.Signals.BurstMessages))
+	}
+	notice := fmt.Sprintf("⚠️ Conversation at risk (score %.2f)", risk.Score)
+	if len(reasons) > 0 {
+		notice += ": " + strings.Join(reasons, ", ")
+	}
+	return notice
+}
+
+// Sentiment word lists, lower case.
+var (
+	negativeWords = map[string]bool{
+		"angry": true, "annoyed": true, "annoying": true, "awful": true, "bad": true, "broken": true,
+		"cancel": true, "complaint": true, "disappointed": true, "disappointing": true, "frustrated": true,
+		"frustrating": true, "hate": true, "horrible": true, "joke": true, "lawyer": true, "nobody": true,
+		"pathetic": true, "refund": true, "ridiculous": true, "rubbish": true, "scam": true, "slow": true,
+		"terrible": true, "unacceptable": true, "upset": true, "useless": true, "waiting": true,
+		"waste": true, "worst": true, "wrong": true,
+	}
+	positiveWords = map[string]bool{
+		"appreciate": true, "awesome": true, "excellent": true, "fantastic": true, "good": true,
+		"great": true, "happy": true, "helpful": true, "love": true, "perfect": true, "resolved": true,
+		"thank": true, "thanks": true, "wonderful": true, "works": true,
+	}
+	negations = map[string]bool{"not": true, "no": true, "never": true, "don't": true, "doesn't": true, "isn't": true, "didn't": true}
+)
+
+// Sentiment is a small lexicon-based sentiment estimate of text, from -1
+// (negative) to 1 (positive); 0 when nothing stands out. A negation flips
+// the next word, and shouting (all-caps words, "!!") counts as negative.
+func Sentiment(text string) float64 {
+	var positive, negative float64
+	negated := false
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		lower := strings.ToLower(word)
+		if negations[lower] {
+			negated = true
+			continue
+		}
+		isPositive, isNegative := positiveWords[lower], negativeWords[lower]
+		if negated {
+			isPositive, isNegative = isNegative, isPositive
+		}
+		if isPositive {
+			positive++
+		}
+		if isNegative {
+			negative++
+		}
+		if len([]rune(word)) > 2 && word == strings.ToUpper(word) {
+			negative += 0.5
+		}
+		negated = false
+	}
+	if strings.Contains(text, "!!") {
+		negative++
+	}
+	if positive+negative == 0 {
+		return 0
+	}
+	return (positive - negative) / (positive + negative)
+}
@@ -0,0 +1,159 @@
+package pocketping
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSentiment(t *testing.T) {
+	tests := []struct {
+		text string
+		want func(float64) bool
+	}{
+		{"Thanks, that's great!", func(s float64) bool { return s == 1 }},
+		{"This is terrible and useless", func(s float64) bool { return s == -1 }},
+		{"Not good at all", func(s float64) bool { return s == -1 }},
+		{"Where is my order?", func(s float64) bool { return s == 0 }},
+		{"WHY IS NOBODY ANSWERING!!", func(s float64) bool { return s == -1 }},
+		{"Good product but shipping was slow", func(s float64) bool { return s == 0 }},
+	}
+	for _, tt := range tests {
+		if got := Sentiment(tt.text); !tt.want(got) {
+			t.Errorf("Sentiment(%q) = %v", tt.text, got)
+		}
+	}
+}
+
+func TestComputeRiskSignals(t *testing.T) {
+	start := time.Date(2025, 1, 17, 10, 0, 0, 0, time.UTC)
+	messages := []Message{
+		{Sender: SenderVisitor, Content: "Hi", Timestamp: start},
+		{Sender: SenderOperator, Content: "Hello!", Timestamp: start.Add(15 * time.Minute)},
+		{Sender: SenderVisitor, Content: "This is terrible", Timestamp: start.Add(20 * time.Minute)},
+		{Sender: SenderVisitor, Content: "deleted", Timestamp: start.Add(20 * time.Minute), DeletedAt: &start},
+		{Sender: SenderVisitor, Content: "Hello?", Timestamp: start.Add(20*time.Minute + 30*time.Second)},
+	}
+	signals := computeRiskSignals(messages, start.Add(25*time.Minute))
+	if signals.VisitorMessages != 3 || signals.BurstMessages != 2 {
+		t.Errorf("counts = %+v", signals)
+	}
+	if signals.CurrentWait != 5*time.Minute || signals.LongestWait != 15*time.Minute {
+		t.Errorf("waits = %v, %v", signals.CurrentWait, signals.LongestWait)
+	}
+	if signals.Sentiment >= 0 {
+		t.Errorf("sentiment = %v, want negative", signals.Sentiment)
+	}
+}
+
+func TestAtRiskFlagging(t *testing.T) {
+	ctx := context.Background()
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	var flagged []*SessionRisk
+	pp := New(Config{
+		Bridges:         []Bridge{bridge},
+		AtRiskThreshold: 0.4,
+		OnAtRisk:        func(session *Session, risk *SessionRisk) { flagged = append(flagged, risk) },
+	})
+	session := newSession(ctx, t, pp)
+
+	sendVisitorMessage(t, pp, session.ID, "Hi, where is my order?")
+	if updated, _ := pp.GetSession(ctx, session.ID); updated.Risk == nil || updated.Risk.Flagged {
+		t.Fatalf("risk after a neutral message = %+v", updated.Risk)
+	}
+
+	sendVisitorMessage(t, pp, session.ID, "This is ridiculous, terrible service")
+	sendVisitorMessage(t, pp, session.ID, "Useless!!")
+	pp.dispatcher.wait()
+	if len(flagged) != 1 || !flagged[0].Flagged {
+		t.Fatalf("flagged = %+v, want one", flagged)
+	}
+	var notices []string
+	for _, notice := range bridge.notices {
+		if strings.HasPrefix(notice, "⚠️ Conversation at risk") {
+			notices = append(notices, notice)
+		}
+	}
+	if len(notices) != 1 || !strings.Contains(notices[0], "negative tone") {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+}
+
+func TestEvaluateRiskCustomScorer(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{
+		AtRiskThreshold: 0.9,
+		RiskScorer:      func(signals RiskSignals) float64 { return float64(signals.VisitorMessages) / 2 },
+	})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "one")
+	sendVisitorMessage(t, pp, session.ID, "two")
+
+	risk, err := pp.EvaluateRisk(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("EvaluateRisk: %v", err)
+	}
+	if risk.Score != 1 || !risk.Flagged || risk.FlaggedAt == nil {
+		t.Errorf("risk = %+v", risk)
+	}
+}
+
+// historyReadingStorage counts GetMessages calls.
+type historyReadingStorage struct {
+	*MemoryStorage
+	reads atomic.Int32
+}
+
+func (s *historyReadingStorage) GetMessages(ctx context.Context, sessionID, after string, limit int) ([]Message, error) {
+	s.reads.Add(1)
+	return s.MemoryStorage.GetMessages(ctx, sessionID, after, limit)
+}
+
+func TestAtRiskScoredIncrementally(t *testing.T) {
+	ctx := context.Background()
+	storage := &historyReadingStorage{MemoryStorage: NewMemoryStorage()}
+	pp := New(Config{Storage: storage, AtRiskThreshold: 0.9})
+	session := newSession(ctx, t, pp)
+	storage.reads.Store(0)
+
+	sendVisitorMessage(t, pp, session.ID, "This is terrible")
+	sendVisitorMessage(t, pp, session.ID, "Hello?")
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "Sorry, looking now", Sender: SenderOperator}); err != nil {
+		t.Fatalf("operator reply: %v", err)
+	}
+	if reads := storage.reads.Load(); reads != 0 {
+		t.Errorf("scoring read the history %d times", reads)
+	}
+
+	updated, _ := pp.GetSession(ctx, session.ID)
+	risk := updated.Risk
+	if risk == nil || risk.Signals.VisitorMessages != 2 || risk.Signals.BurstMessages != 2 {
+		t.Fatalf("risk = %+v", risk)
+	}
+	if risk.WaitingSince != nil || risk.Signals.CurrentWait != 0 {
+		t.Errorf("the operator reply must end the wait: %+v", risk)
+	}
+	if risk.Signals.Sentiment >= 0 {
+		t.Errorf("sentiment = %v, want negative", risk.Signals.Sentiment)
+	}
+}
+
+func TestEvaluateRiskSeedsFromHistory(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	session := newSession(ctx, t, pp)
+	sendVisitorMessage(t, pp, session.ID, "one")
+	sendVisitorMessage(t, pp, session.ID, "two")
+
+	// Enabled after the messages: EvaluateRisk reads them once.
+	pp.config.AtRiskThreshold = 0.9
+	risk, err := pp.EvaluateRisk(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("EvaluateRisk: %v", err)
+	}
+	if risk.Signals.VisitorMessages != 2 || risk.WaitingSince == nil {
+		t.Errorf("risk = %+v", risk)
+	}
+}
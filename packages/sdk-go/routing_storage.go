@@ -0,0 +1,361 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrStorageNotSupported is returned by RoutingStorage when one of its
+// backends doesn't implement an optional storage interface.
+var ErrStorageNotSupported = errors.New("storage backend does not support this operation")
+
+// RoutingStorage keeps each session, with its messages, in the backend of
+// its region (Session.Region, set by Config.RegionResolver), for data
+// residency: EU sessions in an EU database, US sessions in a US one.
+// Sessions without a region, or with one that has no backend, go to the
+// default backend.
+//
+// A session stays in the backend it was created in, even if its region
+// changes later. Lookups by session ID are remembered; lookups by visitor or
+// message ID ask every backend.
+//
+// It implements StorageWithListSessions, StorageWithBridgeIDs,
+// StorageWithBridgeMessageIndex and StorageWithNotifyCursors; those return
+// ErrStorageNotSupported when a backend doesn't.
+type RoutingStorage struct {
+	fallback Storage
+	regions  map[string]Storage
+	// backends lists fallback then the regions, in name order.
+	backends []Storage
+
+	mu       sync.RWMutex
+	sessions map[string]Storage
+}
+
+// NewRoutingStorage routes sessions to regions' backends, and the others to
+// fallback.
+func NewRoutingStorage(fallback Storage, regions map[string]Storage) *RoutingStorage {
+	names := make([]string, 0, len(regions))
+	for name := range regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	backends := []Storage{fallback}
+	for _, name := range names {
+		backends = append(backends, regions[name])
+	}
+	return &RoutingStorage{
+		fallback: fallback,
+		regions:  regions,
+		backends: backends,
+		sessions: make(map[string]Storage),
+	}
+}
+
+// Backend returns the backend for a region.
+func (s *RoutingStorage) Backend(region string) Storage {
+	if backend, ok := s.regions[region]; ok {
+		return backend
+	}
+	return s.fallback
+}
+
+// remember records which backend holds a session.
+func (s *RoutingStorage) remember(sessionID string, backend Storage) {
+	s.mu.Lock()
+	s.sessions[sessionID] = backend
+	s.mu.Unlock()
+}
+
+// sessionBackend finds the backend holding a session, or nil.
+func (s *RoutingStorage) sessionBackend(ctx context.Context, sessionID string) (Storage, *Session, error) {
+	s.mu.RLock()
+	backend := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if backend != nil {
+		session, err := backend.GetSession(ctx, sessionID)
+		if err != nil || session != nil {
+			return backend, session, err
+		}
+	}
+
+	for _, backend := range s.backends {
+		session, err := backend.GetSession(ctx, sessionID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if session != nil {
+			s.remember(sessionID, backend)
+			return backend, session, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// CreateSession stores the session in its region's backend.
+func (s *RoutingStorage) CreateSession(ctx context.Context, session *Session) error {
+	backend := s.Backend(session.Region)
+	if err := backend.CreateSession(ctx, session); err != nil {
+		return err
+	}
+	s.remember(session.ID, backend)
+	return nil
+}
+
+// GetSession retrieves a session from whichever backend holds it.
+func (s *RoutingStorage) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	_, session, err := s.sessionBackend(ctx, sessionID)
+	return session, err
+}
+
+// GetSessionByVisitorID returns the visitor's most recent session across
+// backends.
+func (s *RoutingStorage) GetSessionByVisitorID(ctx context.Context, visitorID string) (*Session, error) {
+	var latest *Session
+	for _, backend := range s.backends {
+		session, err := backend.GetSessionByVisitorID(ctx, visitorID)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil && (latest == nil || session.LastActivity.After(latest.LastActivity)) {
+			s.remember(session.ID, backend)
+			latest = session
+		}
+	}
+	return latest, nil
+}
+
+// UpdateSession updates the session in the backend holding it.
+func (s *RoutingStorage) UpdateSession(ctx context.Context, session *Session) error {
+	backend, _, err := s.sessionBackend(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		backend = s.Backend(session.Region)
+	}
+	return backend.UpdateSession(ctx, session)
+}
+
+// DeleteSession deletes the session from the backend holding it.
+func (s *RoutingStorage) DeleteSession(ctx context.Context, sessionID string) error {
+	backend, _, err := s.sessionBackend(ctx, sessionID)
+	if err != nil || backend == nil {
+		return err
+	}
+	if err := backend.DeleteSession(ctx, sessionID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.sessions, sessionID)
+	s.mu.Unlock()
+	return nil
+}
+
+// messageBackend returns the backend for a message's session: the one
+// holding it, or the fallback.
+func (s *RoutingStorage) messageBackend(ctx context.Context, sessionID string) (Storage, error) {
+	backend, _, err := s.sessionBackend(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return s.fallback, nil
+	}
+	return backend, nil
+}
+
+// SaveMessage stores the message next to its session.
+func (s *RoutingStorage) SaveMessage(ctx context.Context, message *Message) error {
+	backend, err := s.messageBackend(ctx, message.SessionID)
+	if err != nil {
+		return err
+	}
+	return backend.SaveMessage(ctx, message)
+}
+
+// GetMessages returns a session's messages from the backend holding it.
+func (s *RoutingStorage) GetMessages(ctx context.Context, sessionID string, after string, limit int) ([]Message, error) {
+	backend, err := s.messageBackend(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetMessages(ctx, sessionID, after, limit)
+}
+
+// GetMessage looks for the message in every backend.
+func (s *RoutingStorage) GetMessage(ctx context.Context, messageID string) (*Message, error) {
+	_, message, err := s.findMessage(ctx, messageID)
+	return message, err
+}
+
+// findMessage returns a message and the backend holding it.
+func (s *RoutingStorage) findMessage(ctx context.Context, messageID string) (Storage, *Message, error) {
+	for _, backend := range s.backends {
+		message, err := backend.GetMessage(ctx, messageID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if message != nil {
+			return backend, message, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// CleanupOldSessions cleans up every backend.
+func (s *RoutingStorage) CleanupOldSessions(ctx context.Context, olderThan time.Time) (int, error) {
+	total := 0
+	for _, backend := range s.backends {
+		deleted, err := backend.CleanupOldSessions(ctx, olderThan)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// OnSessionDeleted registers hook with every backend that supports it.
+func (s *RoutingStorage) OnSessionDeleted(hook func(sessionID string)) {
+	for _, backend := range s.backends {
+		if hooked, ok := backend.(StorageWithDeletionHook); ok {
+			hooked.OnSessionDeleted(hook)
+		}
+	}
+}
+
+// ListSessions lists the sessions of every backend.
+func (s *RoutingStorage) ListSessions(ctx context.Context, since *time.Time) ([]*Session, error) {
+	var all []*Session
+	for _, backend := range s.backends {
+		lister, ok := backend.(StorageWithListSessions)
+		if !ok {
+			return nil, ErrStorageNotSupported
+		}
+		sessions, err := lister.ListSessions(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, sessions...)
+	}
+	return all, nil
+}
+
+// ListInactiveSessions looks up the inactive sessions of every backend,
+// listing all the sessions of those that can't look them up.
+func (s *RoutingStorage) ListInactiveSessions(ctx context.Context, before time.Time) ([]*Session, error) {
+	var all []*Session
+	for _, backend := range s.backends {
+		sessions, err := listInactiveSessions(ctx, backend, before)
+		if errors.Is(err, ErrLifecycleUnsupported) {
+			return nil, ErrStorageNotSupported
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, sessions...)
+	}
+	return all, nil
+}
+
+// UpdateMessage updates the message in the backend holding it.
+func (s *RoutingStorage) UpdateMessage(ctx context.Context, message *Message) error {
+	backend, err := s.messageBackend(ctx, message.SessionID)
+	if err != nil {
+		return err
+	}
+	withIDs, ok := backend.(StorageWithBridgeIDs)
+	if !ok {
+		return ErrStorageNotSupported
+	}
+	return withIDs.UpdateMessage(ctx, message)
+}
+
+// SaveBridgeMessageIDs saves the IDs in the backend holding the message.
+func (s *RoutingStorage) SaveBridgeMessageIDs(ctx context.Context, messageID string, bridgeIDs BridgeMessageIds) error {
+	backend, message, err := s.findMessage(ctx, messageID)
+	if err != nil || message == nil {
+		return err
+	}
+	withIDs, ok := backend.(StorageWithBridgeIDs)
+	if !ok {
+		return ErrStorageNotSupported
+	}
+	return withIDs.SaveBridgeMessageIDs(ctx, messageID, bridgeIDs)
+}
+
+// GetBridgeMessageIDs reads the IDs from the backend holding the message.
+func (s *RoutingStorage) GetBridgeMessageIDs(ctx context.Context, messageID string) (*BridgeMessageIds, error) {
+	backend, message, err := s.findMessage(ctx, messageID)
+	if err != nil || message == nil {
+		return nil, err
+	}
+	withIDs, ok := backend.(StorageWithBridgeIDs)
+	if !ok {
+		return nil, ErrStorageNotSupported
+	}
+	return withIDs.GetBridgeMessageIDs(ctx, messageID)
+}
+
+// FindMessageByBridgeID looks the message up in the backend holding the
+// session.
+func (s *RoutingStorage) FindMessageByBridgeID(ctx context.Context, sessionID, bridge, bridgeMessageID string) (string, error) {
+	backend, err := s.messageBackend(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	index, ok := backend.(StorageWithBridgeMessageIndex)
+	if !ok {
+		return "", ErrStorageNotSupported
+	}
+	return index.FindMessageByBridgeID(ctx, sessionID, bridge, bridgeMessageID)
+}
+
+// GetLastNotified reads the cursor from the backend holding the session.
+func (s *RoutingStorage) GetLastNotified(ctx context.Context, sessionID, bridgeName string) (string, error) {
+	backend, err := s.messageBackend(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	cursors, ok := backend.(StorageWithNotifyCursors)
+	if !ok {
+		return "", ErrStorageNotSupported
+	}
+	return cursors.GetLastNotified(ctx, sessionID, bridgeName)
+}
+
+// SetLastNotified records the cursor in the backend holding the session.
+func (s *RoutingStorage) SetLastNotified(ctx context.Context, sessionID, bridgeName, messageID string) error {
+	backend, err := s.messageBackend(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	cursors, ok := backend.(StorageWithNotifyCursors)
+	if !ok {
+		return ErrStorageNotSupported
+	}
+	return cursors.SetLastNotified(ctx, sessionID, bridgeName, messageID)
+}
+
+// Ensure RoutingStorage implements StorageWithListSessions interface
+var _ StorageWithListSessions = (*RoutingStorage)(nil)
+
+// Ensure RoutingStorage implements StorageWithInactiveSessions interface
+var _ StorageWithInactiveSessions = (*RoutingStorage)(nil)
+
+// Ensure RoutingStorage implements StorageWithBridgeIDs interface
+var _ StorageWithBridgeIDs = (*RoutingStorage)(nil)
+
+// Ensure RoutingStorage implements StorageWithBridgeMessageIndex interface
+var _ StorageWithBridgeMessageIndex = (*RoutingStorage)(nil)
+
+// Ensure RoutingStorage implements StorageWithNotifyCursors interface
+var _ StorageWithNotifyCursors = (*RoutingStorage)(nil)
+
+// Ensure RoutingStorage implements StorageWithDeletionHook interface
+var _ StorageWithDeletionHook = (*RoutingStorage)(nil)
@@ -0,0 +1,70 @@
+package pocketping
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRoutingStorage(t *testing.T) {
+	ctx := context.Background()
+	fallback, eu := NewMemoryStorage(), NewMemoryStorage()
+	storage := NewRoutingStorage(fallback, map[string]Storage{"eu": eu})
+	pp := New(Config{Storage: storage, CountryRegions: map[string]string{"FR": "eu"}})
+
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v-fr", Metadata: &SessionMetadata{Country: "FR"}})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+	euSession := resp.SessionID
+	messageID := sendVisitorMessage(t, pp, euSession, "bonjour")
+	other := connectVisitor(ctx, t, pp, "v-us")
+	sendVisitorMessage(t, pp, other, "hello")
+
+	if s, _ := eu.GetSession(ctx, euSession); s == nil {
+		t.Error("EU session not in the EU backend")
+	}
+	if s, _ := fallback.GetSession(ctx, euSession); s != nil {
+		t.Error("EU session in the fallback backend")
+	}
+	if m, _ := eu.GetMessage(ctx, messageID); m == nil {
+		t.Error("EU message not in the EU backend")
+	}
+	if s, _ := fallback.GetSession(ctx, other); s == nil {
+		t.Error("session without a region not in the fallback backend")
+	}
+
+	// A fresh router, without remembered sessions, finds them all.
+	storage = NewRoutingStorage(fallback, map[string]Storage{"eu": eu})
+	if s, err := storage.GetSessionByVisitorID(ctx, "v-fr"); err != nil || s == nil || s.ID != euSession {
+		t.Errorf("GetSessionByVisitorID = %v, %v", s, err)
+	}
+	if m, err := storage.GetMessage(ctx, messageID); err != nil || m == nil {
+		t.Errorf("GetMessage = %v, %v", m, err)
+	}
+	if messages, _ := storage.GetMessages(ctx, euSession, "", 10); len(messages) != 1 {
+		t.Errorf("GetMessages = %v", messages)
+	}
+	if sessions, err := storage.ListSessions(ctx, nil); err != nil || len(sessions) != 2 {
+		t.Errorf("ListSessions = %d sessions, %v", len(sessions), err)
+	}
+	if err := storage.SaveBridgeMessageIDs(ctx, messageID, BridgeMessageIds{TelegramMessageID: 7}); err != nil {
+		t.Fatalf("SaveBridgeMessageIDs: %v", err)
+	}
+	if ids, _ := eu.GetBridgeMessageIDs(ctx, messageID); ids == nil || ids.TelegramMessageID != 7 {
+		t.Errorf("bridge IDs = %+v", ids)
+	}
+
+	if err := storage.DeleteSession(ctx, euSession); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if s, _ := eu.GetSession(ctx, euSession); s != nil {
+		t.Error("session not deleted from the EU backend")
+	}
+}
+
+func TestRoutingStorageUnsupported(t *testing.T) {
+	storage := NewRoutingStorage(NewMemoryStorage(), map[string]Storage{"eu": statsLessStorage{}})
+	if _, err := storage.ListSessions(context.Background(), nil); err != ErrStorageNotSupported {
+		t.Errorf("ListSessions: err = %v, want ErrStorageNotSupported", err)
+	}
+}
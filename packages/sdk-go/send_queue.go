@@ -0,0 +1,130 @@
+package pocketping
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultWriteTimeout is the write deadline for queued WebSocket sends when
+// Config.WriteTimeout is zero.
+const DefaultWriteTimeout = 10 * time.Second
+
+// ErrSlowClient is returned when a socket's send queue is full and
+// SlowClientDisconnect closed it.
+var ErrSlowClient = errors.New("websocket client too slow: send queue full")
+
+// errSendQueueClosed is returned by writes to a stopped send queue.
+var errSendQueueClosed = errors.New("websocket send queue closed")
+
+// SlowClientPolicy decides what happens to a socket whose send queue is
+// full.
+type SlowClientPolicy string
+
+const (
+	// SlowClientDisconnect closes the socket; the widget reconnects and
+	// catches up (see ResumeWebSocket). The default.
+	SlowClientDisconnect SlowClientPolicy = "disconnect"
+	// SlowClientDrop drops the event and keeps the socket.
+	SlowClientDrop SlowClientPolicy = "drop"
+)
+
+// deadlineSetter is implemented by sockets supporting write deadlines, such
+// as *websocket.Conn.
+type deadlineSetter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// sendQueue is a WebSocketConn whose writes are buffered and written by its
+// own goroutine, so a stuck client can't hold up broadcasts to others.
+type sendQueue struct {
+	conn    WebSocketConn
+	events  chan interface{}
+	timeout time.Duration
+	policy  SlowClientPolicy
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newSendQueue wraps conn in a send queue when Config.SendQueueSize is set,
+// and returns conn itself otherwise.
+func (pp *PocketPing) newSendQueue(conn WebSocketConn) WebSocketConn {
+	if pp.config.SendQueueSize <= 0 {
+		return conn
+	}
+	q := &sendQueue{
+		conn:    conn,
+		events:  make(chan interface{}, pp.config.SendQueueSize),
+		timeout: pp.config.WriteTimeout,
+		policy:  pp.config.SlowClientPolicy,
+		done:    make(chan struct{}),
+	}
+	if q.timeout <= 0 {
+		q.timeout = DefaultWriteTimeout
+	}
+	if q.policy == "" {
+		q.policy = SlowClientDisconnect
+	}
+	go q.run()
+	return q
+}
+
+// WriteJSON queues v without blocking.
+func (q *sendQueue) WriteJSON(v interface{}) error {
+	select {
+	case <-q.done:
+		return errSendQueueClosed
+	default:
+	}
+	select {
+	case q.events <- v:
+		return nil
+	default:
+	}
+	if q.policy == SlowClientDrop {
+		log.Printf("[PocketPing] Dropped event for slow WebSocket client")
+		return nil
+	}
+	log.Printf("[PocketPing] Disconnecting slow WebSocket client")
+	q.Close()
+	return ErrSlowClient
+}
+
+// Close stops the queue and closes the socket.
+func (q *sendQueue) Close() error {
+	q.stop()
+	return q.conn.Close()
+}
+
+// stop ends the writer goroutine, leaving the socket open.
+func (q *sendQueue) stop() {
+	q.stopOnce.Do(func() { close(q.done) })
+}
+
+func (q *sendQueue) run() {
+	deadlines, _ := q.conn.(deadlineSetter)
+	for {
+		select {
+		case <-q.done:
+			return
+		case v := <-q.events:
+			if deadlines != nil {
+				_ = deadlines.SetWriteDeadline(time.Now().Add(q.timeout))
+			}
+			if err := q.conn.WriteJSON(v); err != nil {
+				// Later writes fail, so broadcasts drop the socket.
+				q.Close()
+				return
+			}
+		}
+	}
+}
+
+// stopSendQueue stops conn's writer goroutine if it is a send queue.
+func stopSendQueue(conn WebSocketConn) {
+	if q, ok := conn.(*sendQueue); ok {
+		q.stop()
+	}
+}
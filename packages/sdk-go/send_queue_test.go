@@ -0,0 +1,122 @@
+package pocketping
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stuckConn blocks every write until released.
+type stuckConn struct {
+	release  chan struct{}
+	mu       sync.Mutex
+	attempts int
+	written  int
+	closed   bool
+	deadline time.Time
+}
+
+func newStuckConn() *stuckConn { return &stuckConn{release: make(chan struct{})} }
+
+func (c *stuckConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	c.attempts++
+	c.mu.Unlock()
+	<-c.release
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New("closed")
+	}
+	c.written++
+	return nil
+}
+
+func (c *stuckConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *stuckConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *stuckConn) state() (written int, closed bool, deadline time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written, c.closed, c.deadline
+}
+
+// writing reports whether a write is blocked in WriteJSON.
+func (c *stuckConn) writing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts > c.written
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSendQueueDisconnectsSlowClient(t *testing.T) {
+	pp := New(Config{SendQueueSize: 2, WriteTimeout: time.Second})
+	slow, fast := newStuckConn(), &mockWSConn{}
+	defer close(slow.release)
+	pp.RegisterWebSocket("s1", slow)
+	pp.RegisterWebSocket("s1", fast)
+
+	// One event is stuck in WriteJSON, two are queued, the fourth overflows.
+	for i := 1; i <= 4; i++ {
+		pp.BroadcastToSession("s1", WebSocketEvent{Type: "custom"})
+		waitFor(t, "fast client event", func() bool { return fast.count() == i })
+		if i == 1 {
+			waitFor(t, "slow client write", slow.writing)
+		}
+	}
+
+	_, closed, deadline := slow.state()
+	if !closed {
+		t.Error("slow client not disconnected")
+	}
+	if deadline.IsZero() {
+		t.Error("no write deadline set")
+	}
+	pp.socketsMu.RLock()
+	_, registered := pp.sessionSockets["s1"][slow]
+	pp.socketsMu.RUnlock()
+	if registered {
+		t.Error("slow client still registered")
+	}
+}
+
+func TestSendQueueDropPolicy(t *testing.T) {
+	pp := New(Config{SendQueueSize: 2, SlowClientPolicy: SlowClientDrop})
+	slow := newStuckConn()
+	pp.RegisterWebSocket("s1", slow)
+	pp.BroadcastToSession("s1", WebSocketEvent{Type: "custom"})
+	waitFor(t, "slow client write", slow.writing)
+	for i := 0; i < 4; i++ {
+		pp.BroadcastToSession("s1", WebSocketEvent{Type: "custom"})
+	}
+	close(slow.release)
+
+	// One event was being written and two were queued; the rest dropped.
+	waitFor(t, "queued events", func() bool { written, _, _ := slow.state(); return written == 3 })
+	if _, closed, _ := slow.state(); closed {
+		t.Error("slow client disconnected under SlowClientDrop")
+	}
+	pp.UnregisterWebSocket("s1", slow)
+}
@@ -0,0 +1,41 @@
+package pocketping
+
+import (
+	"context"
+	"net/http"
+)
+
+var (
+	// ErrInvalidSender is returned by HandleMessage for a Sender other than
+	// SenderVisitor, SenderOperator and SenderAI.
+	ErrInvalidSender = newError("invalid_sender", http.StatusBadRequest, "invalid sender")
+	// ErrSenderNotAllowed is returned by HandleVisitorMessage for a request
+	// that claims to come from an operator or the AI.
+	ErrSenderNotAllowed = newError("sender_not_allowed", http.StatusForbidden, "visitors can only send visitor messages")
+)
+
+// validSender reports whether sender is a known role.
+func validSender(sender Sender) bool {
+	switch sender {
+	case SenderVisitor, SenderOperator, SenderAI:
+		return true
+	}
+	return false
+}
+
+// HandleVisitorMessage handles a message from the widget. Unlike
+// HandleMessage, it trusts no role from the request: an empty Sender is a
+// visitor, and any other role is refused with ErrSenderNotAllowed, so a
+// tampered widget can't post as an operator. Use it for the widget's
+// message endpoint, and HandleMessage or SendOperatorMessage for
+// server-side callers.
+func (pp *PocketPing) HandleVisitorMessage(ctx context.Context, request SendMessageRequest) (*SendMessageResponse, error) {
+	switch request.Sender {
+	case "":
+		request.Sender = SenderVisitor
+	case SenderVisitor:
+	default:
+		return nil, ErrSenderNotAllowed
+	}
+	return pp.HandleMessage(ctx, request)
+}
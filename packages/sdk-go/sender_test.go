@@ -0,0 +1,46 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHandleVisitorMessage(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	for _, sender := range []Sender{SenderOperator, SenderAI, "admin"} {
+		_, err := pp.HandleVisitorMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "refund approved", Sender: sender})
+		if !errors.Is(err, ErrSenderNotAllowed) {
+			t.Errorf("sender %q: err = %v, want ErrSenderNotAllowed", sender, err)
+		}
+	}
+
+	resp, err := pp.HandleVisitorMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "hi"})
+	if err != nil {
+		t.Fatalf("HandleVisitorMessage: %v", err)
+	}
+	message, _ := pp.storage.GetMessage(ctx, resp.MessageID)
+	if message.Sender != SenderVisitor {
+		t.Errorf("Sender = %q, want visitor", message.Sender)
+	}
+
+	messages, _ := pp.storage.GetMessages(ctx, sessionID, "", 10)
+	if len(messages) != 1 {
+		t.Errorf("got %d messages, want only the visitor's", len(messages))
+	}
+}
+
+func TestHandleMessageInvalidSender(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	for _, sender := range []Sender{"", "admin"} {
+		if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: "hi", Sender: sender}); !errors.Is(err, ErrInvalidSender) {
+			t.Errorf("sender %q: err = %v, want ErrInvalidSender", sender, err)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Slack App Home quick action IDs. Buttons in the home view carry the session
+// ID as their value; see WebhookConfig.OnSlackAppHomeAction.
+const (
+	SlackAppHomeActionClaim = "pocketping_claim"
+	SlackAppHomeActionClose = "pocketping_close"
+	// SlackAppHomeActionWatch and SlackAppHomeActionUnwatch go to
+	// WebhookConfig.OnWatch instead.
+	SlackAppHomeActionWatch   = "pocketping_watch"
+	SlackAppHomeActionUnwatch = "pocketping_unwatch"
+)
+
+const (
+	// defaultSlackAppHomeMaxSessions keeps the view well under Slack's
+	// 100-block limit (each session renders as a section, an actions row and
+	// a divider).
+	defaultSlackAppHomeMaxSessions = 20
+	// defaultSlackAppHomeWindow is how recent a session's activity must be
+	// for it to count as open.
+	defaultSlackAppHomeWindow = 24 * time.Hour
+	// defaultSlackAppHomeDebounce is how long events are gathered into one
+	// refresh of the Home tab.
+	defaultSlackAppHomeDebounce = 2 * time.Second
+	// defaultSlackAppHomeMaxUsers caps the users whose Home tab is kept up
+	// to date; those who opened it least recently are dropped first.
+	defaultSlackAppHomeMaxUsers = 50
+)
+
+// slackAppHome holds App Home state for a SlackBotBridge.
+type slackAppHome struct {
+	mu sync.Mutex
+	// users maps the users who opened the Home tab to when they last did.
+	users       map[string]time.Time
+	maxSessions int
+	window      time.Duration
+	debounce    time.Duration
+	maxUsers    int
+	// refresh is the pending refresh, nil when there is none.
+	refresh *time.Timer
+}
+
+// WithSlackAppHome enables the Slack App Home tab. The bridge publishes a
+// session overview (open sessions, unreplied counts, claim/close buttons) via
+// views.publish to each user who opens the Home tab, and republishes it in
+// the background when sessions or messages change, once per burst of events.
+//
+// Route app_home_opened events to PublishAppHome with
+// WebhookConfig.OnSlackAppHomeOpened. Listing sessions requires Storage to
+// implement StorageWithListSessions.
+func WithSlackAppHome() SlackBotOption {
+	return func(s *SlackBotBridge) {
+		s.appHome = &slackAppHome{
+			users:       make(map[string]time.Time),
+			maxSessions: defaultSlackAppHomeMaxSessions,
+			window:      defaultSlackAppHomeWindow,
+			debounce:    defaultSlackAppHomeDebounce,
+			maxUsers:    defaultSlackAppHomeMaxUsers,
+		}
+	}
+}
+
+// WithSlackAppHomeLimits overrides how many sessions the Home tab lists and
+// how recent their activity must be to count as open. Non-positive values
+// keep the defaults. Implies WithSlackAppHome.
+func WithSlackAppHomeLimits(maxSessions int, window time.Duration) SlackBotOption {
+	return func(s *SlackBotBridge) {
+		if s.appHome == nil {
+			WithSlackAppHome()(s)
+		}
+		if maxSessions > 0 {
+			s.appHome.maxSessions = maxSessions
+		}
+		if window > 0 {
+			s.appHome.window = window
+		}
+	}
+}
+
+// WithSlackAppHomeRefresh overrides how long events are gathered into one
+// Home tab refresh (default 2s) and how many users' Home tabs are kept up to
+// date (default 50, those who opened it most recently). Non-positive values
+// keep the defaults. Implies WithSlackAppHome.
+func WithSlackAppHomeRefresh(debounce time.Duration, maxUsers int) SlackBotOption {
+	return func(s *SlackBotBridge) {
+		if s.appHome == nil {
+			WithSlackAppHome()(s)
+		}
+		if debounce > 0 {
+			s.appHome.debounce = debounce
+		}
+		if maxUsers > 0 {
+			s.appHome.maxUsers = maxUsers
+		}
+	}
+}
+
+// PublishAppHome publishes the session overview to userID's Home tab and
+// remembers the user so later events refresh their view.
+func (s *SlackBotBridge) PublishAppHome(ctx context.Context, userID string) error {
+	if s.appHome == nil {
+		return fmt.Errorf("slack app home is not enabled")
+	}
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	s.appHome.rememberUser(userID, time.Now())
+
+	view, err := s.buildAppHomeView(ctx)
+	if err != nil {
+		return err
+	}
+	return s.publishView(ctx, userID, view)
+}
+
+// rememberUser records that userID opened the Home tab, forgetting the
+// users who opened it least recently beyond maxUsers.
+func (h *slackAppHome) rememberUser(userID string, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.users[userID] = now
+	for len(h.users) > h.maxUsers {
+		var oldest string
+		for id, openedAt := range h.users {
+			if oldest == "" || openedAt.Before(h.users[oldest]) {
+				oldest = id
+			}
+		}
+		delete(h.users, oldest)
+	}
+}
+
+// refreshAppHome schedules a republish of the Home tab for every user who
+// has opened it, after the debounce delay, unless one is already pending:
+// a burst of events costs one rebuild, and never delays the notification
+// that triggered it.
+func (s *SlackBotBridge) refreshAppHome(ctx context.Context) {
+	if s.appHome == nil {
+		return
+	}
+	h := s.appHome
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.users) == 0 || h.refresh != nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	h.refresh = time.AfterFunc(h.debounce, func() {
+		h.mu.Lock()
+		h.refresh = nil
+		h.mu.Unlock()
+		s.publishAppHomes(ctx)
+	})
+}
+
+// publishAppHomes republishes the Home tab for every remembered user.
+// Errors are logged, matching the other bridge notifications.
+func (s *SlackBotBridge) publishAppHomes(ctx context.Context) {
+	s.appHome.mu.Lock()
+	users := make([]string, 0, len(s.appHome.users))
+	for id := range s.appHome.users {
+		users = append(users, id)
+	}
+	s.appHome.mu.Unlock()
+
+	if len(users) == 0 {
+		return
+	}
+
+	view, err := s.buildAppHomeView(ctx)
+	if err != nil {
+		log.Printf("[SlackBotBridge] App Home error: %v", err)
+		return
+	}
+	for _, userID := range users {
+		if err := s.publishView(ctx, userID, view); err != nil {
+			log.Printf("[SlackBotBridge] App Home publish for %s error: %v", userID, err)
+		}
+	}
+}
+
+// Destroy cancels a pending Home tab refresh.
+func (s *SlackBotBridge) Destroy(ctx context.Context) error {
+	if s.appHome != nil {
+		s.appHome.mu.Lock()
+		if s.appHome.refresh != nil {
+			s.appHome.refresh.Stop()
+			s.appHome.refresh = nil
+		}
+		s.appHome.mu.Unlock()
+	}
+	return s.BaseBridge.Destroy(ctx)
+}
+
+// buildAppHomeView renders the Block Kit home view.
+func (s *SlackBotBridge) buildAppHomeView(ctx context.Context) (map[string]interface{}, error) {
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": "PocketPing sessions"},
+		},
+	}
+
+	sessions, err := s.openSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sessions) == 0 {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": "No open sessions :tada:"},
+		})
+	}
+
+	for _, session := range sessions {
+		unreplied, err := countUnreplied(ctx, s.pp.GetStorage(), session.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprintf("*%s*\n:clock3: %s", s.getVisitorName(session), session.LastActivity.UTC().Format("2006-01-02 15:04 MST"))
+		if unreplied > 0 {
+			text += fmt.Sprintf("  :speech_balloon: %d unreplied", unreplied)
+		}
+		if session.Metadata != nil && session.Metadata.URL != "" {
+			text += fmt.Sprintf("\n:round_pushpin: %s", session.Metadata.URL)
+		}
+
+		blocks = append(blocks,
+			map[string]interface{}{
+				"type": "section",
+				"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+			},
+			map[string]interface{}{
+				"type": "actions",
+				"elements": []map[string]interface{}{
+					{
+						"type":      "button",
+						"action_id": SlackAppHomeActionClaim,
+						"text":      map[string]interface{}{"type": "plain_text", "text": "Claim"},
+						"style":     "primary",
+						"value":     session.ID,
+					},
+					{
+						"type":      "button",
+						"action_id": SlackAppHomeActionClose,
+						"text":      map[string]interface{}{"type": "plain_text", "text": "Close"},
+						"style":     "danger",
+						"value":     session.ID,
+					},
+					{
+						"type":      "button",
+						"action_id": SlackAppHomeActionWatch,
+						"text":      map[string]interface{}{"type": "plain_text", "text": "Watch"},
+						"value":     session.ID,
+					},
+					{
+						"type":      "button",
+						"action_id": SlackAppHomeActionUnwatch,
+						"text":      map[string]interface{}{"type": "plain_text", "text": "Unwatch"},
+						"value":     session.ID,
+					},
+				},
+			},
+			map[string]interface{}{"type": "divider"},
+		)
+	}
+
+	return map[string]interface{}{
+		"type":   "home",
+		"blocks": blocks,
+	}, nil
+}
+
+// openSessions returns recently active sessions, most recent first.
+func (s *SlackBotBridge) openSessions(ctx context.Context) ([]*Session, error) {
+	if s.pp == nil {
+		return nil, nil
+	}
+	lister, ok := s.pp.GetStorage().(StorageWithListSessions)
+	if !ok {
+		return nil, ErrListSessionsUnsupported
+	}
+
+	all, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-s.appHome.window)
+	open := make([]*Session, 0, len(all))
+	for _, session := range all {
+		if session.LastActivity.Before(cutoff) {
+			continue
+		}
+		open = append(open, session)
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].LastActivity.After(open[j].LastActivity)
+	})
+	if len(open) > s.appHome.maxSessions {
+		open = open[:s.appHome.maxSessions]
+	}
+	return open, nil
+}
+
+// countUnreplied counts visitor messages after the last operator or AI reply.
+func countUnreplied(ctx context.Context, storage Storage, sessionID string) (int, error) {
+	const pageSize = 100
+
+	unreplied := 0
+	after := ""
+	for {
+		page, err := storage.GetMessages(ctx, sessionID, after, pageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, msg := range page {
+			if msg.DeletedAt != nil {
+				continue
+			}
+			if msg.Sender == SenderVisitor {
+				unreplied++
+			} else {
+				unreplied = 0
+			}
+		}
+		if len(page) < pageSize {
+			return unreplied, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+type slackViewsPublishPayload struct {
+	UserID string                 `json:"user_id"`
+	View   map[string]interface{} `json:"view"`
+}
+
+func (s *SlackBotBridge) publishView(ctx context.Context, userID string, view map[string]interface{}) error {
+	apiURL := slackAPIBase + "/views.publish"
+
+	body, err := json.Marshal(slackViewsPublishPayload{UserID: userID, View: view})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.BotToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	var slackResp slackResponse
+	if err := json.Unmarshal(respBody, &slackResp); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+
+	if !slackResp.OK {
+		return fmt.Errorf("slack error: %s", slackResp.Error)
+	}
+
+	return nil
+}
@@ -19,6 +19,7 @@ type SlackWebhookBridge struct {
 	IconEmoji  string
 
 	httpClient *http.Client
+	dryRun     bool
 	pp         *PocketPing
 }
 
@@ -46,6 +47,14 @@ func WithSlackWebhookHTTPClient(client *http.Client) SlackWebhookOption {
 	}
 }
 
+// WithSlackWebhookDryRun logs every outgoing Slack payload instead of calling the Slack
+// API, for staging environments (see NewDryRunTransport).
+func WithSlackWebhookDryRun() SlackWebhookOption {
+	return func(s *SlackWebhookBridge) {
+		s.dryRun = true
+	}
+}
+
 // NewSlackWebhookBridge creates a new Slack webhook bridge.
 // Returns an error if configuration is invalid.
 func NewSlackWebhookBridge(webhookURL string, opts ...SlackWebhookOption) (*SlackWebhookBridge, error) {
@@ -68,6 +77,9 @@ func NewSlackWebhookBridge(webhookURL string, opts ...SlackWebhookOption) (*Slac
 	for _, opt := range opts {
 		opt(s)
 	}
+	if s.dryRun {
+		s.httpClient = dryRunClient(s.Name(), s.httpClient)
+	}
 
 	return s, nil
 }
@@ -81,9 +93,18 @@ func MustNewSlackWebhookBridge(webhookURL string, opts ...SlackWebhookOption) *S
 	return s
 }
 
+// Destination returns the dedupe key of the bridge's webhook.
+func (s *SlackWebhookBridge) Destination() string {
+	return BridgeDestination("slack", s.WebhookURL)
+}
+
 // Init initializes the Slack webhook bridge.
 func (s *SlackWebhookBridge) Init(ctx context.Context, pp *PocketPing) error {
 	s.pp = pp
+	if pp != nil && pp.config.DryRun && !s.dryRun {
+		s.dryRun = true
+		s.httpClient = dryRunClient(s.Name(), s.httpClient)
+	}
 	return nil
 }
 
@@ -120,32 +141,28 @@ func (s *SlackWebhookBridge) OnNewSession(ctx context.Context, session *Session)
 		text += fmt.Sprintf("\n:round_pushpin: %s", session.Metadata.URL)
 	}
 
-	err := s.sendWebhookMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackWebhookBridge] OnNewSession error: %v", err)
-	}
-	return nil
+	return s.sendWebhookMessage(ctx, text)
 }
 
 // OnVisitorMessage sends a notification when a visitor sends a message.
 func (s *SlackWebhookBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
 	visitorName := s.getVisitorName(session)
-	text := fmt.Sprintf(":speech_balloon: %s:\n%s", visitorName, message.Content)
+	text := fmt.Sprintf(":speech_balloon: %s:\n%s", visitorName, message.Content+locationText(message.Location))
 	if quote := s.buildReplyQuote(ctx, message); quote != "" {
 		text = quote + "\n" + text
 	}
 
 	// Note: Slack webhooks don't return message timestamps for editing
 	// For full edit/delete support, use SlackBotBridge instead
-	err := s.sendWebhookMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackWebhookBridge] OnVisitorMessage error: %v", err)
-	}
-	return nil
+	return s.sendWebhookMessage(ctx, text)
 }
 
 // OnOperatorMessage is called when an operator sends a message.
 func (s *SlackWebhookBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return s.OnAIMessage(ctx, message, session)
+	}
+
 	if sourceBridge == s.Name() {
 		return nil
 	}
@@ -155,13 +172,21 @@ func (s *SlackWebhookBridge) OnOperatorMessage(ctx context.Context, message *Mes
 		name = "Operator"
 	}
 
-	text := fmt.Sprintf(":office_worker: %s:\n%s", name, message.Content)
+	text := fmt.Sprintf(":office_worker: %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
 
-	err := s.sendWebhookMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackWebhookBridge] OnOperatorMessage error: %v", err)
-	}
-	return nil
+	return s.sendWebhookMessage(ctx, text)
+}
+
+// Notify posts a plain one-line notice.
+func (s *SlackWebhookBridge) Notify(ctx context.Context, session *Session, message string) error {
+	return s.sendWebhookMessage(ctx, message)
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (s *SlackWebhookBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	text := fmt.Sprintf(":robot_face: %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+
+	return s.sendWebhookMessage(ctx, text)
 }
 
 // OnTyping is called when visitor starts/stops typing.
@@ -187,11 +212,7 @@ func (s *SlackWebhookBridge) OnCustomEvent(ctx context.Context, event CustomEven
 		}
 	}
 
-	err := s.sendWebhookMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackWebhookBridge] OnCustomEvent error: %v", err)
-	}
-	return nil
+	return s.sendWebhookMessage(ctx, text)
 }
 
 // OnIdentityUpdate is called when a user identifies themselves.
@@ -205,17 +226,13 @@ func (s *SlackWebhookBridge) OnIdentityUpdate(ctx context.Context, session *Sess
 		text += fmt.Sprintf("\n:name_badge: Name: %s", session.Identity.Name)
 	}
 	if session.Identity.Email != "" {
-		text += fmt.Sprintf("\n:email: Email: %s", session.Identity.Email)
+		text += fmt.Sprintf("\n:email: Email: %s", session.Identity.Email+disposableEmailNote(session))
 	}
 	if session.UserPhone != "" {
 		text += fmt.Sprintf("\n:telephone_receiver: Phone: %s", session.UserPhone)
 	}
 
-	err := s.sendWebhookMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackWebhookBridge] OnIdentityUpdate error: %v", err)
-	}
-	return nil
+	return s.sendWebhookMessage(ctx, text)
 }
 
 type slackWebhookPayload struct {
@@ -269,6 +286,15 @@ func (s *SlackWebhookBridge) getVisitorName(session *Session) string {
 // Ensure SlackWebhookBridge implements Bridge interface
 var _ Bridge = (*SlackWebhookBridge)(nil)
 
+// Ensure SlackWebhookBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*SlackWebhookBridge)(nil)
+
+// Ensure SlackWebhookBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*SlackWebhookBridge)(nil)
+
+// Ensure SlackWebhookBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*SlackWebhookBridge)(nil)
+
 // SlackBotBridge sends notifications to Slack using a bot token.
 // This supports full edit/delete functionality.
 type SlackBotBridge struct {
@@ -277,7 +303,9 @@ type SlackBotBridge struct {
 	ChannelID string
 
 	httpClient *http.Client
+	dryRun     bool
 	pp         *PocketPing
+	appHome    *slackAppHome
 }
 
 // SlackBotOption is a functional option for SlackBotBridge.
@@ -290,6 +318,14 @@ func WithSlackBotHTTPClient(client *http.Client) SlackBotOption {
 	}
 }
 
+// WithSlackBotDryRun logs every outgoing Slack payload instead of calling the Slack
+// API, for staging environments (see NewDryRunTransport).
+func WithSlackBotDryRun() SlackBotOption {
+	return func(s *SlackBotBridge) {
+		s.dryRun = true
+	}
+}
+
 // NewSlackBotBridge creates a new Slack bot bridge.
 // Returns an error if configuration is invalid.
 func NewSlackBotBridge(botToken, channelID string, opts ...SlackBotOption) (*SlackBotBridge, error) {
@@ -311,6 +347,9 @@ func NewSlackBotBridge(botToken, channelID string, opts ...SlackBotOption) (*Sla
 	for _, opt := range opts {
 		opt(s)
 	}
+	if s.dryRun {
+		s.httpClient = dryRunClient(s.Name(), s.httpClient)
+	}
 
 	return s, nil
 }
@@ -324,9 +363,18 @@ func MustNewSlackBotBridge(botToken, channelID string, opts ...SlackBotOption) *
 	return s
 }
 
+// Destination returns the dedupe key of the bridge's channel.
+func (s *SlackBotBridge) Destination() string {
+	return BridgeDestination("slack", s.ChannelID)
+}
+
 // Init initializes the Slack bot bridge.
 func (s *SlackBotBridge) Init(ctx context.Context, pp *PocketPing) error {
 	s.pp = pp
+	if pp != nil && pp.config.DryRun && !s.dryRun {
+		s.dryRun = true
+		s.httpClient = dryRunClient(s.Name(), s.httpClient)
+	}
 	return nil
 }
 
@@ -364,24 +412,22 @@ func (s *SlackBotBridge) OnNewSession(ctx context.Context, session *Session) err
 	}
 
 	_, err := s.postMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackBotBridge] OnNewSession error: %v", err)
-	}
-	return nil
+	s.refreshAppHome(ctx)
+	return err
 }
 
 // OnVisitorMessage sends a notification when a visitor sends a message.
 func (s *SlackBotBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
 	visitorName := s.getVisitorName(session)
-	text := fmt.Sprintf(":speech_balloon: %s:\n%s", visitorName, message.Content)
+	text := fmt.Sprintf(":speech_balloon: %s:\n%s", visitorName, message.Content+locationText(message.Location))
 	if quote := s.buildReplyQuote(ctx, message); quote != "" {
 		text = quote + "\n" + text
 	}
 
 	result, err := s.postMessage(ctx, text)
+	s.refreshAppHome(ctx)
 	if err != nil {
-		log.Printf("[SlackBotBridge] OnVisitorMessage error: %v", err)
-		return nil
+		return err
 	}
 
 	// Save bridge message ID for edit/delete support
@@ -398,6 +444,13 @@ func (s *SlackBotBridge) OnVisitorMessage(ctx context.Context, message *Message,
 
 // OnOperatorMessage is called when an operator sends a message.
 func (s *SlackBotBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return s.OnAIMessage(ctx, message, session)
+	}
+
+	// Replies from any source change unreplied counts on the Home tab.
+	s.refreshAppHome(ctx)
+
 	if sourceBridge == s.Name() {
 		return nil
 	}
@@ -407,20 +460,57 @@ func (s *SlackBotBridge) OnOperatorMessage(ctx context.Context, message *Message
 		name = "Operator"
 	}
 
-	text := fmt.Sprintf(":office_worker: %s:\n%s", name, message.Content)
+	text := fmt.Sprintf(":office_worker: %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
 
 	_, err := s.postMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackBotBridge] OnOperatorMessage error: %v", err)
-	}
-	return nil
+	return err
+}
+
+// Notify posts a plain one-line notice.
+func (s *SlackBotBridge) Notify(ctx context.Context, session *Session, message string) error {
+	_, err := s.postMessage(ctx, message)
+	return err
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (s *SlackBotBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	// AI replies change unreplied counts on the Home tab too.
+	s.refreshAppHome(ctx)
+
+	text := fmt.Sprintf(":robot_face: %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+	blocks := []map[string]interface{}{
+		{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+		},
+		{
+			"type": "actions",
+			"elements": []map[string]interface{}{
+				{
+					"type":      "button",
+					"action_id": TakeoverActionID,
+					"text":      map[string]interface{}{"type": "plain_text", "text": "Take over"},
+					"value":     session.ID,
+				},
+				{
+					"type":      "button",
+					"action_id": HandbackActionID,
+					"text":      map[string]interface{}{"type": "plain_text", "text": "Hand back to AI"},
+					"value":     session.ID,
+				},
+			},
+		},
+	}
+
+	_, err := s.postMessageWithBlocks(ctx, text, blocks)
+	return err
 }
 
 func (s *SlackWebhookBridge) buildReplyQuote(ctx context.Context, message *Message) string {
-	if message.ReplyTo == "" || s.pp == nil {
+	if replyTarget(message) == "" || s.pp == nil {
 		return ""
 	}
-	replyTarget, err := s.pp.GetStorage().GetMessage(ctx, message.ReplyTo)
+	replyTarget, err := s.pp.GetStorage().GetMessage(ctx, replyTarget(message))
 	if err != nil || replyTarget == nil {
 		return ""
 	}
@@ -428,10 +518,10 @@ func (s *SlackWebhookBridge) buildReplyQuote(ctx context.Context, message *Messa
 }
 
 func (s *SlackBotBridge) buildReplyQuote(ctx context.Context, message *Message) string {
-	if message.ReplyTo == "" || s.pp == nil {
+	if replyTarget(message) == "" || s.pp == nil {
 		return ""
 	}
-	replyTarget, err := s.pp.GetStorage().GetMessage(ctx, message.ReplyTo)
+	replyTarget, err := s.pp.GetStorage().GetMessage(ctx, replyTarget(message))
 	if err != nil || replyTarget == nil {
 		return ""
 	}
@@ -482,10 +572,7 @@ func (s *SlackBotBridge) OnCustomEvent(ctx context.Context, event CustomEvent, s
 	}
 
 	_, err := s.postMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackBotBridge] OnCustomEvent error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnIdentityUpdate is called when a user identifies themselves.
@@ -499,17 +586,14 @@ func (s *SlackBotBridge) OnIdentityUpdate(ctx context.Context, session *Session)
 		text += fmt.Sprintf("\n:name_badge: Name: %s", session.Identity.Name)
 	}
 	if session.Identity.Email != "" {
-		text += fmt.Sprintf("\n:email: Email: %s", session.Identity.Email)
+		text += fmt.Sprintf("\n:email: Email: %s", session.Identity.Email+disposableEmailNote(session))
 	}
 	if session.UserPhone != "" {
 		text += fmt.Sprintf("\n:telephone_receiver: Phone: %s", session.UserPhone)
 	}
 
 	_, err := s.postMessage(ctx, text)
-	if err != nil {
-		log.Printf("[SlackBotBridge] OnIdentityUpdate error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnMessageEdit handles message edits.
@@ -530,8 +614,7 @@ func (s *SlackBotBridge) OnMessageEdit(ctx context.Context, sessionID, messageID
 
 	err = s.updateMessage(ctx, bridgeIDs.SlackMessageTS, content+" (edited)")
 	if err != nil {
-		log.Printf("[SlackBotBridge] OnMessageEdit error: %v", err)
-		return nil, nil
+		return nil, err
 	}
 
 	return &BridgeMessageResult{
@@ -556,10 +639,7 @@ func (s *SlackBotBridge) OnMessageDelete(ctx context.Context, sessionID, message
 	}
 
 	err = s.deleteMessage(ctx, bridgeIDs.SlackMessageTS)
-	if err != nil {
-		log.Printf("[SlackBotBridge] OnMessageDelete error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // Slack API helpers
@@ -567,8 +647,9 @@ func (s *SlackBotBridge) OnMessageDelete(ctx context.Context, sessionID, message
 const slackAPIBase = "https://slack.com/api"
 
 type slackPostMessagePayload struct {
-	Channel string `json:"channel"`
-	Text    string `json:"text"`
+	Channel string                   `json:"channel"`
+	Text    string                   `json:"text"`
+	Blocks  []map[string]interface{} `json:"blocks,omitempty"`
 }
 
 type slackUpdateMessagePayload struct {
@@ -589,11 +670,31 @@ type slackResponse struct {
 }
 
 func (s *SlackBotBridge) postMessage(ctx context.Context, text string) (*BridgeMessageResult, error) {
+	return s.postMessageWithBlocks(ctx, text, nil)
+}
+
+// postMessageWithBlocks posts a message with optional Block Kit blocks; text
+// stays the notification fallback.
+func (s *SlackBotBridge) postMessageWithBlocks(ctx context.Context, text string, blocks []map[string]interface{}) (*BridgeMessageResult, error) {
+	return s.postMessageTo(ctx, s.ChannelID, text, blocks)
+}
+
+// SendDirectMessage sends text to an operator in their direct messages with
+// the app.
+func (s *SlackBotBridge) SendDirectMessage(ctx context.Context, userID, text string) error {
+	_, err := s.postMessageTo(ctx, userID, text, nil)
+	return err
+}
+
+// postMessageTo posts a message to a channel, or to a user's direct
+// messages when channel is a user ID.
+func (s *SlackBotBridge) postMessageTo(ctx context.Context, channel, text string, blocks []map[string]interface{}) (*BridgeMessageResult, error) {
 	apiURL := slackAPIBase + "/chat.postMessage"
 
 	payload := slackPostMessagePayload{
-		Channel: s.ChannelID,
+		Channel: channel,
 		Text:    text,
+		Blocks:  blocks,
 	}
 
 	body, err := json.Marshal(payload)
@@ -733,5 +834,17 @@ func (s *SlackBotBridge) getVisitorName(session *Session) string {
 // Ensure SlackBotBridge implements Bridge interface
 var _ Bridge = (*SlackBotBridge)(nil)
 
+// Ensure SlackBotBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*SlackBotBridge)(nil)
+
+// Ensure SlackBotBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*SlackBotBridge)(nil)
+
+// Ensure SlackBotBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*SlackBotBridge)(nil)
+
 // Ensure SlackBotBridge implements BridgeWithEditDelete interface
 var _ BridgeWithEditDelete = (*SlackBotBridge)(nil)
+
+// Ensure SlackBotBridge implements BridgeWithDirectMessages interface
+var _ BridgeWithDirectMessages = (*SlackBotBridge)(nil)
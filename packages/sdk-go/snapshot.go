@@ -0,0 +1,316 @@
+package pocketping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is the snapshot format version written by Snapshot.
+const SnapshotVersion = 1
+
+// Snapshot record types.
+const (
+	SnapshotRecordHeader     = "header"
+	SnapshotRecordSession    = "session"
+	SnapshotRecordMessage    = "message"
+	SnapshotRecordBridgeIDs  = "bridge_ids"
+	SnapshotRecordAttachment = "attachment"
+)
+
+// snapshotPageSize is the GetMessages page size used while exporting.
+const snapshotPageSize = 500
+
+// ErrSnapshotUnsupported is returned by Snapshot when the storage can't list
+// sessions.
+var ErrSnapshotUnsupported = errors.New("Snapshot requires Storage to implement StorageWithListSessions")
+
+// SnapshotRecord is one line of a snapshot stream. Exactly one payload field
+// is set, matching Type.
+type SnapshotRecord struct {
+	Type string `json:"type"`
+
+	// Header fields
+	Version   int        `json:"version,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+
+	Session    *Session          `json:"session,omitempty"`
+	Message    *Message          `json:"message,omitempty"`
+	MessageID  string            `json:"messageId,omitempty"`
+	BridgeIDs  *BridgeMessageIds `json:"bridgeIds,omitempty"`
+	Attachment *Attachment       `json:"attachment,omitempty"`
+}
+
+// SnapshotStats counts the records written by Snapshot or applied by Restore.
+type SnapshotStats struct {
+	Sessions    int `json:"sessions"`
+	Messages    int `json:"messages"`
+	BridgeIDs   int `json:"bridgeIds"`
+	Attachments int `json:"attachments"`
+}
+
+// Snapshot writes the full state of the storage (sessions, messages, bridge
+// message IDs and linked attachments) to w as NDJSON: a header line followed
+// by one SnapshotRecord per line. Each session is followed by its messages,
+// in conversation order.
+//
+// Bridge IDs and attachments are included when the storage implements
+// StorageWithBridgeIDs / StorageWithAttachments. Attachment bytes are not
+// exported, only their metadata and URLs.
+func (pp *PocketPing) Snapshot(ctx context.Context, w io.Writer) (*SnapshotStats, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	now := time.Now()
+	if err := enc.Encode(SnapshotRecord{Type: SnapshotRecordHeader, Version: SnapshotVersion, CreatedAt: &now}); err != nil {
+		return nil, err
+	}
+
+	stats, err := walkStorage(ctx, pp.storage, func(rec *SnapshotRecord) error {
+		return enc.Encode(rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// walkStorage calls fn for every session, message, bridge ID and attachment
+// record in storage, each session followed by its messages in order. It only
+// reads from storage.
+func walkStorage(ctx context.Context, storage Storage, fn func(rec *SnapshotRecord) error) (*SnapshotStats, error) {
+	lister, ok := storage.(StorageWithListSessions)
+	if !ok {
+		return nil, ErrSnapshotUnsupported
+	}
+	bridgeStore, _ := storage.(StorageWithBridgeIDs)
+	attachmentStore, _ := storage.(StorageWithAttachments)
+
+	sessions, err := lister.ListSessions(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	stats := &SnapshotStats{}
+	for _, session := range sessions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := fn(&SnapshotRecord{Type: SnapshotRecordSession, Session: session}); err != nil {
+			return nil, err
+		}
+		stats.Sessions++
+
+		after := ""
+		for {
+			page, err := storage.GetMessages(ctx, session.ID, after, snapshotPageSize)
+			if err != nil {
+				return nil, fmt.Errorf("get messages for %s: %w", session.ID, err)
+			}
+
+			for i := range page {
+				msg := &page[i]
+				if err := fn(&SnapshotRecord{Type: SnapshotRecordMessage, Message: msg}); err != nil {
+					return nil, err
+				}
+				stats.Messages++
+
+				if bridgeStore != nil {
+					ids, err := bridgeStore.GetBridgeMessageIDs(ctx, msg.ID)
+					if err != nil {
+						return nil, fmt.Errorf("get bridge IDs for %s: %w", msg.ID, err)
+					}
+					if ids != nil {
+						if err := fn(&SnapshotRecord{Type: SnapshotRecordBridgeIDs, MessageID: msg.ID, BridgeIDs: ids}); err != nil {
+							return nil, err
+						}
+						stats.BridgeIDs++
+					}
+				}
+
+				if attachmentStore != nil {
+					atts, err := attachmentStore.GetMessageAttachments(ctx, msg.ID)
+					if err != nil {
+						return nil, fmt.Errorf("get attachments for %s: %w", msg.ID, err)
+					}
+					for j := range atts {
+						if err := fn(&SnapshotRecord{Type: SnapshotRecordAttachment, Attachment: &atts[j]}); err != nil {
+							return nil, err
+						}
+						stats.Attachments++
+					}
+				}
+			}
+
+			if len(page) < snapshotPageSize {
+				break
+			}
+			after = page[len(page)-1].ID
+		}
+	}
+
+	return stats, nil
+}
+
+// Restore reads a snapshot produced by Snapshot and writes it into the
+// configured storage. It accepts NDJSON (one record per line) or a JSON array
+// of records.
+//
+// Restore is idempotent: existing sessions are updated, and messages already
+// present are updated (StorageWithBridgeIDs) or left as is. Bridge IDs and
+// attachments are skipped when the storage doesn't support them.
+func (pp *PocketPing) Restore(ctx context.Context, r io.Reader) (*SnapshotStats, error) {
+	stats := &SnapshotStats{}
+	apply := func(rec *SnapshotRecord) error {
+		return restoreRecord(ctx, pp.storage, rec, stats)
+	}
+
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("parse snapshot: %w", err)
+		}
+		for dec.More() {
+			var rec SnapshotRecord
+			if err := dec.Decode(&rec); err != nil {
+				return nil, fmt.Errorf("parse snapshot: %w", err)
+			}
+			if err := apply(&rec); err != nil {
+				return nil, err
+			}
+		}
+		return stats, nil
+	}
+
+	for {
+		var rec SnapshotRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return stats, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("parse snapshot: %w", err)
+		}
+		if err := apply(&rec); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// restoreRecord writes one snapshot record into storage, updating records
+// that already exist.
+func restoreRecord(ctx context.Context, storage Storage, rec *SnapshotRecord, stats *SnapshotStats) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	switch rec.Type {
+	case SnapshotRecordHeader:
+		if rec.Version > SnapshotVersion {
+			return fmt.Errorf("unsupported snapshot version %d", rec.Version)
+		}
+
+	case SnapshotRecordSession:
+		if rec.Session == nil {
+			return fmt.Errorf("session record without session")
+		}
+		existing, err := storage.GetSession(ctx, rec.Session.ID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			err = storage.UpdateSession(ctx, rec.Session)
+		} else {
+			err = storage.CreateSession(ctx, rec.Session)
+		}
+		if err != nil {
+			return fmt.Errorf("restore session %s: %w", rec.Session.ID, err)
+		}
+		stats.Sessions++
+
+	case SnapshotRecordMessage:
+		if rec.Message == nil {
+			return fmt.Errorf("message record without message")
+		}
+		existing, err := storage.GetMessage(ctx, rec.Message.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			err = storage.SaveMessage(ctx, rec.Message)
+		} else if store, ok := storage.(StorageWithBridgeIDs); ok {
+			err = store.UpdateMessage(ctx, rec.Message)
+		}
+		if err != nil {
+			return fmt.Errorf("restore message %s: %w", rec.Message.ID, err)
+		}
+		stats.Messages++
+
+	case SnapshotRecordBridgeIDs:
+		store, ok := storage.(StorageWithBridgeIDs)
+		if !ok || rec.BridgeIDs == nil {
+			return nil
+		}
+		if err := store.SaveBridgeMessageIDs(ctx, rec.MessageID, *rec.BridgeIDs); err != nil {
+			return fmt.Errorf("restore bridge IDs for %s: %w", rec.MessageID, err)
+		}
+		stats.BridgeIDs++
+
+	case SnapshotRecordAttachment:
+		store, ok := storage.(StorageWithAttachments)
+		if !ok || rec.Attachment == nil {
+			return nil
+		}
+		existing, err := store.GetAttachment(ctx, rec.Attachment.ID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			err = store.UpdateAttachment(ctx, rec.Attachment)
+		} else {
+			err = store.SaveAttachment(ctx, rec.Attachment)
+		}
+		if err != nil {
+			return fmt.Errorf("restore attachment %s: %w", rec.Attachment.ID, err)
+		}
+		stats.Attachments++
+
+	default:
+		return fmt.Errorf("unknown snapshot record type %q", rec.Type)
+	}
+
+	return nil
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without
+// consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			return b[0], nil
+		}
+		if _, err := br.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func seedSnapshotStorage(t *testing.T, storage *MemoryStorage) {
+	t.Helper()
+	ctx := context.Background()
+
+	_ = storage.CreateSession(ctx, createTestSession("sess-1", "v1", &UserIdentity{ID: "u1", Email: "a@example.com"}, nil))
+	_ = storage.CreateSession(ctx, createTestSession("sess-2", "v2", nil, nil))
+
+	// More than one page of messages for sess-1
+	for i := 0; i < snapshotPageSize+3; i++ {
+		_ = storage.SaveMessage(ctx, &Message{ID: fmt.Sprintf("m-%04d", i), SessionID: "sess-1", Content: "hi", Sender: SenderVisitor, Timestamp: time.Now()})
+	}
+	_ = storage.SaveMessage(ctx, &Message{ID: "m-other", SessionID: "sess-2", Content: "yo", Sender: SenderOperator, Timestamp: time.Now()})
+	_ = storage.SaveBridgeMessageIDs(ctx, "m-0000", BridgeMessageIds{TelegramMessageID: 42, SlackMessageTS: "1.2"})
+	_ = storage.SaveAttachment(ctx, &Attachment{ID: "att-1", MessageID: "m-other", Filename: "a.png", MimeType: "image/png", Size: 10, Status: AttachmentStatusReady})
+}
+
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := NewMemoryStorage()
+	seedSnapshotStorage(t, source)
+
+	var buf bytes.Buffer
+	stats, err := New(Config{Storage: source}).Snapshot(ctx, &buf)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	want := SnapshotStats{Sessions: 2, Messages: snapshotPageSize + 4, BridgeIDs: 1, Attachments: 1}
+	if *stats != want {
+		t.Errorf("expected snapshot stats %+v, got %+v", want, *stats)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var header SnapshotRecord
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil || header.Type != SnapshotRecordHeader || header.Version != SnapshotVersion {
+		t.Fatalf("expected header line, got %s", lines[0])
+	}
+
+	target := NewMemoryStorage()
+	restored, err := New(Config{Storage: target}).Restore(ctx, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if *restored != want {
+		t.Errorf("expected restore stats %+v, got %+v", want, *restored)
+	}
+
+	session, _ := target.GetSession(ctx, "sess-1")
+	if session == nil || session.Identity == nil || session.Identity.Email != "a@example.com" {
+		t.Errorf("expected sess-1 with identity, got %+v", session)
+	}
+	msgs, _ := target.GetMessages(ctx, "sess-1", "", snapshotPageSize+10)
+	if len(msgs) != snapshotPageSize+3 || msgs[0].ID != "m-0000" || msgs[len(msgs)-1].ID != fmt.Sprintf("m-%04d", snapshotPageSize+2) {
+		t.Errorf("expected messages restored in order, got %d", len(msgs))
+	}
+	ids, _ := target.GetBridgeMessageIDs(ctx, "m-0000")
+	if ids == nil || ids.TelegramMessageID != 42 || ids.SlackMessageTS != "1.2" {
+		t.Errorf("expected bridge IDs restored, got %+v", ids)
+	}
+	att, _ := target.GetAttachment(ctx, "att-1")
+	if att == nil || att.MessageID != "m-other" {
+		t.Errorf("expected attachment restored, got %+v", att)
+	}
+
+	// Restoring twice must not duplicate messages
+	if _, err := New(Config{Storage: target}).Restore(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("second Restore: %v", err)
+	}
+	msgs, _ = target.GetMessages(ctx, "sess-1", "", snapshotPageSize+10)
+	if len(msgs) != snapshotPageSize+3 {
+		t.Errorf("expected idempotent restore, got %d messages", len(msgs))
+	}
+}
+
+func TestRestoreJSONArray(t *testing.T) {
+	ctx := context.Background()
+	input := `  [
+		{"type":"header","version":1},
+		{"type":"session","session":{"id":"s1","visitorId":"v1"}},
+		{"type":"message","message":{"id":"m1","sessionId":"s1","content":"hi","sender":"visitor"}}
+	]`
+
+	storage := NewMemoryStorage()
+	stats, err := New(Config{Storage: storage}).Restore(ctx, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if stats.Sessions != 1 || stats.Messages != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if msg, _ := storage.GetMessage(ctx, "m1"); msg == nil {
+		t.Error("expected message m1 to be restored")
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+
+	if _, err := pp.Restore(ctx, strings.NewReader(`{"type":"header","version":99}`)); err == nil {
+		t.Error("expected error for a newer snapshot version")
+	}
+	if _, err := pp.Restore(ctx, strings.NewReader(`{"type":"bogus"}`)); err == nil {
+		t.Error("expected error for an unknown record type")
+	}
+	if _, err := pp.Restore(ctx, strings.NewReader(`{"type":`)); err == nil {
+		t.Error("expected error for malformed JSON")
+	}
+	if stats, err := pp.Restore(ctx, strings.NewReader("  \n")); err != nil || *stats != (SnapshotStats{}) {
+		t.Errorf("expected empty input to restore nothing, got %+v, %v", stats, err)
+	}
+}
+
+func TestSnapshotRequiresListSessions(t *testing.T) {
+	pp := New(Config{Storage: statsLessStorage{}})
+	if _, err := pp.Snapshot(context.Background(), &bytes.Buffer{}); !errors.Is(err, ErrSnapshotUnsupported) {
+		t.Errorf("expected ErrSnapshotUnsupported, got %v", err)
+	}
+}
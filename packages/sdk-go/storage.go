@@ -2,6 +2,9 @@ package pocketping
 
 import (
 	"context"
+	"encoding/json"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -35,6 +38,51 @@ type StorageWithListSessions interface {
 	ListSessions(ctx context.Context, since *time.Time) ([]*Session, error)
 }
 
+// StorageWithInactiveSessions extends Storage with a lookup of open sessions
+// by last activity. Config.SessionLifecycle uses it instead of listing every
+// session; SQL adapters should back it with an index on the last activity.
+type StorageWithInactiveSessions interface {
+	Storage
+
+	// ListInactiveSessions returns the sessions not ended whose last
+	// activity is before before.
+	ListInactiveSessions(ctx context.Context, before time.Time) ([]*Session, error)
+}
+
+// StorageWithIdentityIndex extends Storage with a lookup of sessions by
+// identity. Required by Config.IdentityConflictPolicy; SQL adapters should
+// back it with an index on the identity ID.
+type StorageWithIdentityIndex interface {
+	Storage
+
+	// FindSessionsByIdentity returns the sessions identified as identityID.
+	FindSessionsByIdentity(ctx context.Context, identityID string) ([]*Session, error)
+}
+
+// StorageWithSearch extends Storage with session/message search.
+// Required by SearchMessages (and the Telegram inline search built on it).
+type StorageWithSearch interface {
+	Storage
+
+	// SearchMessages returns sessions whose visitor identity (email, name) or
+	// message content contains query, case-insensitively, most recently active
+	// first. At most limit results are returned.
+	SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// StorageWithCounts extends Storage with filtered counts, so dashboards don't
+// have to page through every session. Used by CountSessions/CountMessages;
+// SQL adapters should implement it with COUNT queries.
+type StorageWithCounts interface {
+	Storage
+
+	// CountSessions returns the number of sessions matching filter.
+	CountSessions(ctx context.Context, filter SessionCountFilter) (int, error)
+
+	// CountMessages returns the number of messages matching filter.
+	CountMessages(ctx context.Context, filter MessageCountFilter) (int, error)
+}
+
 // StorageWithBridgeIDs extends Storage with bridge message ID operations.
 // Implement this interface to support edit/delete synchronization with bridges.
 type StorageWithBridgeIDs interface {
@@ -50,6 +98,20 @@ type StorageWithBridgeIDs interface {
 	GetBridgeMessageIDs(ctx context.Context, messageID string) (*BridgeMessageIds, error)
 }
 
+// StorageWithBridgeMessageIndex extends StorageWithBridgeIDs with a lookup
+// of messages by their ID on a bridge, kept up to date by
+// SaveBridgeMessageIDs. Operator edits and deletions made on a bridge use it
+// instead of scanning the session's messages; SQL adapters should back it
+// with an index on the bridge IDs.
+type StorageWithBridgeMessageIndex interface {
+	StorageWithBridgeIDs
+
+	// FindMessageByBridgeID returns the ID of the message of sessionID whose
+	// ID on bridge ("telegram", "discord", "slack" or "teams") is
+	// bridgeMessageID, or "" if there is none.
+	FindMessageByBridgeID(ctx context.Context, sessionID, bridge, bridgeMessageID string) (string, error)
+}
+
 // StorageWithAttachments extends Storage with attachment operations.
 // Implement this interface to support file attachments.
 type StorageWithAttachments interface {
@@ -68,8 +130,148 @@ type StorageWithAttachments interface {
 	UpdateAttachment(ctx context.Context, attachment *Attachment) error
 }
 
+// StorageWithNotifyCursors extends Storage with the last visitor message
+// notified to each bridge, per session. Implement it so messages replayed
+// after a restart aren't sent to the bridges again. Bridges are identified
+// by their Deduper key.
+type StorageWithNotifyCursors interface {
+	Storage
+
+	// GetLastNotified returns the ID of the last visitor message notified to
+	// the bridge for the session, or "" if none.
+	GetLastNotified(ctx context.Context, sessionID, bridgeKey string) (string, error)
+
+	// SetLastNotified records messageID as the last visitor message notified
+	// to the bridge for the session.
+	SetLastNotified(ctx context.Context, sessionID, bridgeKey, messageID string) error
+}
+
+// ReplayStore is a per-session buffer of recent WebSocket events, so a
+// reconnecting widget can catch up on what it missed on whichever node it
+// lands (see Config.AffinitySecret and Config.ReplayStore). Every node must
+// use the same one.
+type ReplayStore interface {
+	// AppendSessionEvent buffers an event and returns its sequence number,
+	// which increases per session starting at 1.
+	AppendSessionEvent(ctx context.Context, sessionID string, event WebSocketEvent) (int64, error)
+
+	// GetSessionEvents returns the buffered events after seq, oldest first.
+	GetSessionEvents(ctx context.Context, sessionID string, afterSeq int64) ([]WebSocketEvent, error)
+}
+
+// StorageWithEventReplay extends Storage with a ReplayStore. It is used
+// for replay unless Config.ReplayStore is set; use storage shared by every
+// node.
+type StorageWithEventReplay interface {
+	Storage
+	ReplayStore
+}
+
+// StorageWithDeletionHook extends Storage with a callback for deleted
+// sessions, however they go: DeleteSession, CleanupOldSessions or eviction.
+// PocketPing uses it to drop them from the operator inbox.
+type StorageWithDeletionHook interface {
+	Storage
+
+	// OnSessionDeleted registers hook, called with the ID of each deleted
+	// session. Hooks may run with the storage locked, so they must not call
+	// back into it.
+	OnSessionDeleted(hook func(sessionID string))
+}
+
+// deletionHooks implements StorageWithDeletionHook for the storages
+// embedding it.
+type deletionHooks struct {
+	mu    sync.Mutex
+	hooks []func(sessionID string)
+}
+
+// OnSessionDeleted registers a hook for deleted sessions.
+func (h *deletionHooks) OnSessionDeleted(hook func(sessionID string)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook)
+}
+
+// sessionDeleted runs the hooks for a deleted session.
+func (h *deletionHooks) sessionDeleted(sessionID string) {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.mu.Unlock()
+	for _, hook := range hooks {
+		hook(sessionID)
+	}
+}
+
+// StorageWithAcks extends Storage with the last event sequence number each
+// session's widget acknowledged (see PocketPing.HandleAck).
+type StorageWithAcks interface {
+	Storage
+
+	// GetAckedSeq returns the last acknowledged event Seq, or 0 if none.
+	GetAckedSeq(ctx context.Context, sessionID string) (int64, error)
+
+	// SetAckedSeq records seq as the last acknowledged event Seq.
+	SetAckedSeq(ctx context.Context, sessionID string, seq int64) error
+}
+
+// StorageWithOperatorTokens extends Storage with operator API tokens (see
+// PocketPing.IssueOperatorToken). Tokens are stored by the hash of their
+// secret, never the secret itself.
+type StorageWithOperatorTokens interface {
+	Storage
+
+	// SaveOperatorToken creates or updates a token, by ID.
+	SaveOperatorToken(ctx context.Context, token *OperatorToken) error
+
+	// GetOperatorToken returns the token with the given secret hash, or nil.
+	GetOperatorToken(ctx context.Context, hash string) (*OperatorToken, error)
+
+	// ListOperatorTokens returns every token, revoked ones included.
+	ListOperatorTokens(ctx context.Context) ([]OperatorToken, error)
+
+	// TouchOperatorToken sets a token's LastUsedAt, leaving its other
+	// fields alone, so it can't undo a concurrent revocation. Unknown IDs
+	// are ignored.
+	TouchOperatorToken(ctx context.Context, tokenID string, usedAt time.Time) error
+}
+
+// StorageWithNotes extends Storage with internal notes: operator whispers
+// kept with a session but never shown to the visitor (see
+// PocketPing.HandleWhisper).
+type StorageWithNotes interface {
+	Storage
+
+	// SaveNote persists a new note.
+	SaveNote(ctx context.Context, note *Note) error
+
+	// GetNotes returns the notes of a session, oldest first.
+	GetNotes(ctx context.Context, sessionID string) ([]Note, error)
+}
+
+// StorageWithWatches extends Storage with session watches: the operators
+// subscribed to a session's activity (see PocketPing.WatchSession). Watches
+// are kept per operator, an operator being a platform user ID on a bridge.
+type StorageWithWatches interface {
+	Storage
+
+	// SaveWatch subscribes an operator to a session, replacing their
+	// previous watch of it.
+	SaveWatch(ctx context.Context, watch *Watch) error
+
+	// DeleteWatch unsubscribes an operator from a session, if subscribed.
+	DeleteWatch(ctx context.Context, sessionID, bridge, userID string) error
+
+	// GetSessionWatches returns the watches of a session.
+	GetSessionWatches(ctx context.Context, sessionID string) ([]Watch, error)
+
+	// GetOperatorWatches returns the watches of an operator, oldest first.
+	GetOperatorWatches(ctx context.Context, bridge, userID string) ([]Watch, error)
+}
+
 // MemoryStorage is an in-memory storage adapter.
-// Useful for development and testing. Data is lost on restart.
+// Useful for development and testing. Data is lost on restart unless it is
+// opened with NewPersistentMemoryStorage.
 type MemoryStorage struct {
 	mu               sync.RWMutex
 	sessions         map[string]*Session
@@ -77,27 +279,112 @@ type MemoryStorage struct {
 	messageByID      map[string]*Message          // messageID -> message
 	bridgeMessageIDs map[string]*BridgeMessageIds // messageID -> bridge IDs
 	attachments      map[string]*Attachment       // attachmentID -> attachment
+	lastNotified     map[string]map[string]string // sessionID -> bridge -> messageID
+	replay           map[string][]WebSocketEvent  // sessionID -> recent events
+	replaySeq        map[string]int64             // sessionID -> last event seq
+	ackedSeq         map[string]int64             // sessionID -> last acked event seq
+	operatorTokens   map[string]*OperatorToken    // token ID -> token
+	notes            map[string][]Note            // sessionID -> notes
+	watches          map[string]map[string]Watch  // bridge/userID -> sessionID -> watch
+	identities       map[string]map[string]bool   // identity ID -> session IDs
+	sessionIdentity  map[string]string            // sessionID -> indexed identity ID
+	bridgeIndex      map[string]string            // session/bridge/bridge message ID -> messageID
+
+	// wal is the append-only log (nil for a purely in-memory store).
+	wal *memoryLog
+
+	// limits bound memory use (zero value: unbounded).
+	limits memoryLimits
+
+	deletionHooks
 }
 
 // NewMemoryStorage creates a new in-memory storage adapter.
-func NewMemoryStorage() *MemoryStorage {
-	return &MemoryStorage{
+// By default it is unbounded; see WithMaxSessions, WithMaxMessagesPerSession
+// and WithIdleTTL.
+func NewMemoryStorage(opts ...MemoryStorageOption) *MemoryStorage {
+	m := &MemoryStorage{
 		sessions:         make(map[string]*Session),
 		messages:         make(map[string][]Message),
 		messageByID:      make(map[string]*Message),
 		bridgeMessageIDs: make(map[string]*BridgeMessageIds),
 		attachments:      make(map[string]*Attachment),
+		lastNotified:     make(map[string]map[string]string),
+		replay:           make(map[string][]WebSocketEvent),
+		replaySeq:        make(map[string]int64),
+		ackedSeq:         make(map[string]int64),
+		operatorTokens:   make(map[string]*OperatorToken),
+		notes:            make(map[string][]Note),
+		watches:          make(map[string]map[string]Watch),
+		identities:       make(map[string]map[string]bool),
+		sessionIdentity:  make(map[string]string),
+		bridgeIndex:      make(map[string]string),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // CreateSession creates a new session.
+// May evict idle or least recently active sessions when limits are set.
 func (m *MemoryStorage) CreateSession(ctx context.Context, session *Session) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpCreateSession, Session: session}); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.applyCreateSession(session)
+	evicted, err := m.enforceSessionLimitsLocked(time.Now())
+	m.mu.Unlock()
+
+	m.notifyEvicted(ctx, evicted)
+	return err
+}
 
+func (m *MemoryStorage) applyCreateSession(session *Session) {
 	m.sessions[session.ID] = session
 	m.messages[session.ID] = []Message{}
-	return nil
+	m.indexIdentity(session)
+}
+
+// applyUpdateSession stores session and re-indexes its identity.
+func (m *MemoryStorage) applyUpdateSession(session *Session) {
+	m.sessions[session.ID] = session
+	m.indexIdentity(session)
+}
+
+// indexIdentity files session under its identity ID, moving it from the one
+// it was filed under before.
+func (m *MemoryStorage) indexIdentity(session *Session) {
+	identityID := ""
+	if session.Identity != nil {
+		identityID = session.Identity.ID
+	}
+	if previous, ok := m.sessionIdentity[session.ID]; ok && previous == identityID {
+		return
+	}
+	m.unindexIdentity(session.ID)
+	if identityID == "" {
+		return
+	}
+	if m.identities[identityID] == nil {
+		m.identities[identityID] = make(map[string]bool)
+	}
+	m.identities[identityID][session.ID] = true
+	m.sessionIdentity[session.ID] = identityID
+}
+
+func (m *MemoryStorage) unindexIdentity(sessionID string) {
+	identityID, ok := m.sessionIdentity[sessionID]
+	if !ok {
+		return
+	}
+	delete(m.sessionIdentity, sessionID)
+	delete(m.identities[identityID], sessionID)
+	if len(m.identities[identityID]) == 0 {
+		delete(m.identities, identityID)
+	}
 }
 
 // GetSession retrieves a session by ID.
@@ -133,7 +420,10 @@ func (m *MemoryStorage) UpdateSession(ctx context.Context, session *Session) err
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.sessions[session.ID] = session
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpUpdateSession, Session: session}); err != nil {
+		return err
+	}
+	m.applyUpdateSession(session)
 	return nil
 }
 
@@ -142,23 +432,82 @@ func (m *MemoryStorage) DeleteSession(ctx context.Context, sessionID string) err
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Remove messages for this session from messageByID
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpDeleteSessions, IDs: []string{sessionID}}); err != nil {
+		return err
+	}
+	m.applyDeleteSession(sessionID)
+	return nil
+}
+
+func (m *MemoryStorage) applyDeleteSession(sessionID string) {
+	// Remove messages for this session and their bridge IDs/attachments
 	if msgs, ok := m.messages[sessionID]; ok {
-		for _, msg := range msgs {
-			delete(m.messageByID, msg.ID)
-		}
+		m.forgetMessages(msgs)
 	}
 
+	m.unindexIdentity(sessionID)
 	delete(m.sessions, sessionID)
 	delete(m.messages, sessionID)
-	return nil
+	delete(m.lastNotified, sessionID)
+	delete(m.replay, sessionID)
+	delete(m.replaySeq, sessionID)
+	delete(m.ackedSeq, sessionID)
+	delete(m.notes, sessionID)
+	for key, watches := range m.watches {
+		delete(watches, sessionID)
+		if len(watches) == 0 {
+			delete(m.watches, key)
+		}
+	}
+	m.sessionDeleted(sessionID)
+}
+
+// forgetMessages drops messages from the ID index along with their bridge IDs
+// and attachments. The caller removes them from the session's message list.
+func (m *MemoryStorage) forgetMessages(msgs []Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	ids := make(map[string]struct{}, len(msgs))
+	for _, msg := range msgs {
+		ids[msg.ID] = struct{}{}
+		delete(m.messageByID, msg.ID)
+		if ids := m.bridgeMessageIDs[msg.ID]; ids != nil {
+			for _, key := range bridgeIndexKeys(msg.SessionID, ids) {
+				delete(m.bridgeIndex, key)
+			}
+		}
+		delete(m.bridgeMessageIDs, msg.ID)
+	}
+	for id, att := range m.attachments {
+		if _, ok := ids[att.MessageID]; ok {
+			delete(m.attachments, id)
+		}
+	}
 }
 
 // SaveMessage saves a message.
+// May drop the session's oldest messages when WithMaxMessagesPerSession is set.
 func (m *MemoryStorage) SaveMessage(ctx context.Context, message *Message) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpSaveMessage, Message: message}); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.applySaveMessage(message)
+	evicted, err := m.enforceMessageLimitLocked(message.SessionID)
+	if err == nil {
+		var idle []Eviction
+		idle, err = m.enforceSessionLimitsLocked(time.Now())
+		evicted = append(evicted, idle...)
+	}
+	m.mu.Unlock()
 
+	m.notifyEvicted(ctx, evicted)
+	return err
+}
+
+func (m *MemoryStorage) applySaveMessage(message *Message) {
 	// Check if message already exists (update case)
 	if existing, ok := m.messageByID[message.ID]; ok {
 		// Update existing message
@@ -171,7 +520,7 @@ func (m *MemoryStorage) SaveMessage(ctx context.Context, message *Message) error
 				break
 			}
 		}
-		return nil
+		return
 	}
 
 	// New message
@@ -180,7 +529,6 @@ func (m *MemoryStorage) SaveMessage(ctx context.Context, message *Message) error
 	}
 	m.messages[message.SessionID] = append(m.messages[message.SessionID], *message)
 	m.messageByID[message.ID] = message
-	return nil
 }
 
 // GetMessages retrieves messages for a session.
@@ -234,28 +582,25 @@ func (m *MemoryStorage) CleanupOldSessions(ctx context.Context, olderThan time.T
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	count := 0
 	toDelete := []string{}
 
 	for id, session := range m.sessions {
 		if session.LastActivity.Before(olderThan) {
 			toDelete = append(toDelete, id)
-			count++
 		}
 	}
+	if len(toDelete) == 0 {
+		return 0, nil
+	}
 
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpDeleteSessions, IDs: toDelete}); err != nil {
+		return 0, err
+	}
 	for _, id := range toDelete {
-		// Remove messages for this session from messageByID
-		if msgs, ok := m.messages[id]; ok {
-			for _, msg := range msgs {
-				delete(m.messageByID, msg.ID)
-			}
-		}
-		delete(m.sessions, id)
-		delete(m.messages, id)
+		m.applyDeleteSession(id)
 	}
 
-	return count, nil
+	return len(toDelete), nil
 }
 
 // GetAllSessions returns all sessions. Useful for admin/debug.
@@ -286,6 +631,122 @@ func (m *MemoryStorage) ListSessions(ctx context.Context, since *time.Time) ([]*
 	return sessions, nil
 }
 
+// ListInactiveSessions returns the sessions not ended whose last activity
+// is before before.
+func (m *MemoryStorage) ListInactiveSessions(ctx context.Context, before time.Time) ([]*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []*Session
+	for _, session := range m.sessions {
+		if session.EndedAt == nil && session.LastActivity.Before(before) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// FindSessionsByIdentity returns the sessions identified as identityID.
+// Sessions are shared with callers, so the index is checked against each
+// session's current identity.
+func (m *MemoryStorage) FindSessionsByIdentity(ctx context.Context, identityID string) ([]*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []*Session
+	for id := range m.identities[identityID] {
+		session := m.sessions[id]
+		if session != nil && session.Identity != nil && session.Identity.ID == identityID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// SearchMessages returns sessions whose visitor email/name or message content
+// contains query (case-insensitive), most recently active first. For message
+// matches, the most recent matching message is included in the result.
+func (m *MemoryStorage) SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	needle := strings.ToLower(strings.TrimSpace(query))
+	if needle == "" {
+		return []SearchResult{}, nil
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	results := []SearchResult{}
+	for _, session := range m.sessions {
+		var match *Message
+		msgs := m.messages[session.ID]
+		for i := len(msgs) - 1; i >= 0; i-- {
+			if msgs[i].DeletedAt == nil && strings.Contains(strings.ToLower(msgs[i].Content), needle) {
+				msg := msgs[i]
+				match = &msg
+				break
+			}
+		}
+
+		identityMatch := false
+		if session.Identity != nil {
+			identityMatch = strings.Contains(strings.ToLower(session.Identity.Email), needle) ||
+				strings.Contains(strings.ToLower(session.Identity.Name), needle)
+		}
+
+		if match != nil || identityMatch {
+			results = append(results, SearchResult{Session: session, Message: match})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Session.LastActivity.After(results[j].Session.LastActivity)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// CountSessions returns the number of sessions matching filter.
+func (m *MemoryStorage) CountSessions(ctx context.Context, filter SessionCountFilter) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, session := range m.sessions {
+		if filter.Matches(session) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountMessages returns the number of messages matching filter.
+func (m *MemoryStorage) CountMessages(ctx context.Context, filter MessageCountFilter) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	countIn := func(msgs []Message) {
+		for i := range msgs {
+			if filter.Matches(&msgs[i]) {
+				count++
+			}
+		}
+	}
+	if filter.SessionID != "" {
+		countIn(m.messages[filter.SessionID])
+		return count, nil
+	}
+	for _, msgs := range m.messages {
+		countIn(msgs)
+	}
+	return count, nil
+}
+
 // GetSessionCount returns the total number of sessions.
 func (m *MemoryStorage) GetSessionCount(ctx context.Context) (int, error) {
 	m.mu.RLock()
@@ -303,6 +764,18 @@ func (m *MemoryStorage) UpdateMessage(ctx context.Context, message *Message) err
 		return nil // Message doesn't exist
 	}
 
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpUpdateMessage, Message: message}); err != nil {
+		return err
+	}
+	m.applyUpdateMessage(message)
+	return nil
+}
+
+func (m *MemoryStorage) applyUpdateMessage(message *Message) {
+	if _, ok := m.messageByID[message.ID]; !ok {
+		return
+	}
+
 	// Update in messageByID
 	m.messageByID[message.ID] = message
 
@@ -314,8 +787,6 @@ func (m *MemoryStorage) UpdateMessage(ctx context.Context, message *Message) err
 			break
 		}
 	}
-
-	return nil
 }
 
 // SaveBridgeMessageIDs saves platform-specific message IDs for a message.
@@ -323,23 +794,61 @@ func (m *MemoryStorage) SaveBridgeMessageIDs(ctx context.Context, messageID stri
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	existing := m.bridgeMessageIDs[messageID]
-	if existing != nil {
+	merged := bridgeIDs
+	if existing := m.bridgeMessageIDs[messageID]; existing != nil {
 		// Merge with existing
+		merged = *existing
 		if bridgeIDs.TelegramMessageID != 0 {
-			existing.TelegramMessageID = bridgeIDs.TelegramMessageID
+			merged.TelegramMessageID = bridgeIDs.TelegramMessageID
 		}
 		if bridgeIDs.DiscordMessageID != "" {
-			existing.DiscordMessageID = bridgeIDs.DiscordMessageID
+			merged.DiscordMessageID = bridgeIDs.DiscordMessageID
 		}
 		if bridgeIDs.SlackMessageTS != "" {
-			existing.SlackMessageTS = bridgeIDs.SlackMessageTS
+			merged.SlackMessageTS = bridgeIDs.SlackMessageTS
 		}
+		if bridgeIDs.TeamsMessageID != "" {
+			merged.TeamsMessageID = bridgeIDs.TeamsMessageID
+		}
+	}
+
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpBridgeIDs, MessageID: messageID, BridgeIDs: &merged}); err != nil {
+		return err
+	}
+	m.applyBridgeIDs(messageID, merged)
+	return nil
+}
+
+func (m *MemoryStorage) applyBridgeIDs(messageID string, bridgeIDs BridgeMessageIds) {
+	sessionID := ""
+	if message := m.messageByID[messageID]; message != nil {
+		sessionID = message.SessionID
+	}
+	if existing := m.bridgeMessageIDs[messageID]; existing != nil {
+		for _, key := range bridgeIndexKeys(sessionID, existing) {
+			delete(m.bridgeIndex, key)
+		}
+		*existing = bridgeIDs
 	} else {
 		m.bridgeMessageIDs[messageID] = &bridgeIDs
 	}
+	if sessionID == "" {
+		return
+	}
+	for _, key := range bridgeIndexKeys(sessionID, &bridgeIDs) {
+		m.bridgeIndex[key] = messageID
+	}
+}
 
-	return nil
+// bridgeIndexKeys returns the bridgeIndex keys of a message's bridge IDs.
+func bridgeIndexKeys(sessionID string, ids *BridgeMessageIds) []string {
+	var keys []string
+	for _, bridge := range []string{"telegram", "discord", "slack", "teams"} {
+		if id := bridgeMessageIDOf(ids, bridge); id != "" {
+			keys = append(keys, sessionID+"/"+bridge+"/"+id)
+		}
+	}
+	return keys
 }
 
 // GetBridgeMessageIDs retrieves platform-specific message IDs for a message.
@@ -350,13 +859,167 @@ func (m *MemoryStorage) GetBridgeMessageIDs(ctx context.Context, messageID strin
 	return m.bridgeMessageIDs[messageID], nil
 }
 
+// FindMessageByBridgeID returns the ID of the message of sessionID whose ID
+// on bridge is bridgeMessageID, or "".
+func (m *MemoryStorage) FindMessageByBridgeID(ctx context.Context, sessionID, bridge, bridgeMessageID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.bridgeIndex[sessionID+"/"+bridgePlatform(bridge)+"/"+bridgeMessageID], nil
+}
+
+// GetLastNotified returns the last visitor message notified to a bridge.
+func (m *MemoryStorage) GetLastNotified(ctx context.Context, sessionID, bridgeName string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.lastNotified[sessionID][bridgeName], nil
+}
+
+// SetLastNotified records the last visitor message notified to a bridge.
+func (m *MemoryStorage) SetLastNotified(ctx context.Context, sessionID, bridgeName, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpLastNotified, SessionID: sessionID, Bridge: bridgeName, MessageID: messageID}); err != nil {
+		return err
+	}
+	m.applyLastNotified(sessionID, bridgeName, messageID)
+	return nil
+}
+
+func (m *MemoryStorage) applyLastNotified(sessionID, bridgeName, messageID string) {
+	if m.lastNotified[sessionID] == nil {
+		m.lastNotified[sessionID] = make(map[string]string)
+	}
+	m.lastNotified[sessionID][bridgeName] = messageID
+}
+
+// AppendSessionEvent buffers an event, keeping the last MaxReplayEvents per
+// session. The buffer isn't persisted.
+func (m *MemoryStorage) AppendSessionEvent(ctx context.Context, sessionID string, event WebSocketEvent) (int64, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replaySeq[sessionID]++
+	event.Seq = m.replaySeq[sessionID]
+	event.Data = json.RawMessage(data)
+	events := append(m.replay[sessionID], event)
+	if len(events) > MaxReplayEvents {
+		events = append([]WebSocketEvent(nil), events[len(events)-MaxReplayEvents:]...)
+	}
+	m.replay[sessionID] = events
+	return event.Seq, nil
+}
+
+// GetSessionEvents returns the buffered events after seq.
+func (m *MemoryStorage) GetSessionEvents(ctx context.Context, sessionID string, afterSeq int64) ([]WebSocketEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []WebSocketEvent{}
+	for _, event := range m.replay[sessionID] {
+		if event.Seq > afterSeq {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// GetAckedSeq returns the last acknowledged event Seq.
+func (m *MemoryStorage) GetAckedSeq(ctx context.Context, sessionID string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ackedSeq[sessionID], nil
+}
+
+// SetAckedSeq records the last acknowledged event Seq. Like the replay
+// buffer, it isn't persisted.
+func (m *MemoryStorage) SetAckedSeq(ctx context.Context, sessionID string, seq int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ackedSeq[sessionID] = seq
+	return nil
+}
+
+// SaveOperatorToken creates or updates an operator token.
+func (m *MemoryStorage) SaveOperatorToken(ctx context.Context, token *OperatorToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpOperatorToken, OperatorToken: token}); err != nil {
+		return err
+	}
+	m.applyOperatorToken(token)
+	return nil
+}
+
+func (m *MemoryStorage) applyOperatorToken(token *OperatorToken) {
+	stored := *token
+	m.operatorTokens[token.ID] = &stored
+}
+
+// GetOperatorToken returns the token with the given secret hash.
+func (m *MemoryStorage) GetOperatorToken(ctx context.Context, hash string) (*OperatorToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.operatorTokens {
+		if token.Hash == hash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// TouchOperatorToken records the use of an operator token.
+func (m *MemoryStorage) TouchOperatorToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpTouchToken, IDs: []string{tokenID}, UsedAt: &usedAt}); err != nil {
+		return err
+	}
+	m.applyTouchOperatorToken(tokenID, usedAt)
+	return nil
+}
+
+func (m *MemoryStorage) applyTouchOperatorToken(tokenID string, usedAt time.Time) {
+	if token, ok := m.operatorTokens[tokenID]; ok {
+		token.LastUsedAt = &usedAt
+	}
+}
+
+// ListOperatorTokens returns every operator token, oldest first.
+func (m *MemoryStorage) ListOperatorTokens(ctx context.Context) ([]OperatorToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]OperatorToken, 0, len(m.operatorTokens))
+	for _, token := range m.operatorTokens {
+		tokens = append(tokens, *token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
 // SaveAttachment persists a new attachment.
 func (m *MemoryStorage) SaveAttachment(ctx context.Context, attachment *Attachment) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	stored := *attachment
-	m.attachments[attachment.ID] = &stored
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpPutAttachment, Attachment: attachment}); err != nil {
+		return err
+	}
+	m.applyPutAttachment(attachment)
 	return nil
 }
 
@@ -392,9 +1055,16 @@ func (m *MemoryStorage) UpdateAttachment(ctx context.Context, attachment *Attach
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpPutAttachment, Attachment: attachment}); err != nil {
+		return err
+	}
+	m.applyPutAttachment(attachment)
+	return nil
+}
+
+func (m *MemoryStorage) applyPutAttachment(attachment *Attachment) {
 	stored := *attachment
 	m.attachments[attachment.ID] = &stored
-	return nil
 }
 
 // Ensure MemoryStorage implements Storage interface
@@ -403,8 +1073,145 @@ var _ Storage = (*MemoryStorage)(nil)
 // Ensure MemoryStorage implements StorageWithListSessions interface
 var _ StorageWithListSessions = (*MemoryStorage)(nil)
 
+// Ensure MemoryStorage implements StorageWithInactiveSessions interface
+var _ StorageWithInactiveSessions = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithIdentityIndex interface
+var _ StorageWithIdentityIndex = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithSearch interface
+var _ StorageWithSearch = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithCounts interface
+var _ StorageWithCounts = (*MemoryStorage)(nil)
+
 // Ensure MemoryStorage implements StorageWithBridgeIDs interface
 var _ StorageWithBridgeIDs = (*MemoryStorage)(nil)
 
+// Ensure MemoryStorage implements StorageWithBridgeMessageIndex interface
+var _ StorageWithBridgeMessageIndex = (*MemoryStorage)(nil)
+
+// SaveNote persists a new note.
+func (m *MemoryStorage) SaveNote(ctx context.Context, note *Note) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpSaveNote, Note: note}); err != nil {
+		return err
+	}
+	m.applySaveNote(note)
+	return nil
+}
+
+func (m *MemoryStorage) applySaveNote(note *Note) {
+	m.notes[note.SessionID] = append(m.notes[note.SessionID], *note)
+}
+
+// GetNotes returns the notes of a session, oldest first.
+func (m *MemoryStorage) GetNotes(ctx context.Context, sessionID string) ([]Note, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]Note(nil), m.notes[sessionID]...), nil
+}
+
+// watchKey is the key of an operator's watches.
+func watchKey(bridge, userID string) string {
+	return bridge + "/" + userID
+}
+
+// SaveWatch subscribes an operator to a session, replacing their previous
+// watch of it.
+func (m *MemoryStorage) SaveWatch(ctx context.Context, watch *Watch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpSaveWatch, Watch: watch}); err != nil {
+		return err
+	}
+	m.applySaveWatch(watch)
+	return nil
+}
+
+func (m *MemoryStorage) applySaveWatch(watch *Watch) {
+	key := watchKey(watch.Bridge, watch.UserID)
+	if m.watches[key] == nil {
+		m.watches[key] = make(map[string]Watch)
+	}
+	m.watches[key][watch.SessionID] = *watch
+}
+
+// DeleteWatch unsubscribes an operator from a session, if subscribed.
+func (m *MemoryStorage) DeleteWatch(ctx context.Context, sessionID, bridge, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.watches[watchKey(bridge, userID)][sessionID]; !ok {
+		return nil
+	}
+	watch := &Watch{SessionID: sessionID, Bridge: bridge, UserID: userID}
+	if err := m.logOp(&memoryLogEntry{Op: memoryOpDeleteWatch, Watch: watch}); err != nil {
+		return err
+	}
+	m.applyDeleteWatch(watch)
+	return nil
+}
+
+func (m *MemoryStorage) applyDeleteWatch(watch *Watch) {
+	key := watchKey(watch.Bridge, watch.UserID)
+	delete(m.watches[key], watch.SessionID)
+	if len(m.watches[key]) == 0 {
+		delete(m.watches, key)
+	}
+}
+
+// GetSessionWatches returns the watches of a session.
+func (m *MemoryStorage) GetSessionWatches(ctx context.Context, sessionID string) ([]Watch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []Watch
+	for _, watches := range m.watches {
+		if watch, ok := watches[sessionID]; ok {
+			out = append(out, watch)
+		}
+	}
+	return out, nil
+}
+
+// GetOperatorWatches returns the watches of an operator, oldest first.
+func (m *MemoryStorage) GetOperatorWatches(ctx context.Context, bridge, userID string) ([]Watch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []Watch
+	for _, watch := range m.watches[watchKey(bridge, userID)] {
+		out = append(out, watch)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
 // Ensure MemoryStorage implements StorageWithAttachments interface
 var _ StorageWithAttachments = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithNotifyCursors interface
+var _ StorageWithNotifyCursors = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithEventReplay interface
+var _ StorageWithEventReplay = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithDeletionHook interface
+var _ StorageWithDeletionHook = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithAcks interface
+var _ StorageWithAcks = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithOperatorTokens interface
+var _ StorageWithOperatorTokens = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithNotes interface
+var _ StorageWithNotes = (*MemoryStorage)(nil)
+
+// Ensure MemoryStorage implements StorageWithWatches interface
+var _ StorageWithWatches = (*MemoryStorage)(nil)
@@ -414,3 +414,52 @@ func TestMemoryStorageGetSessionCount(t *testing.T) {
 		t.Errorf("expected 5 sessions, got %d", count)
 	}
 }
+
+func TestMemoryStorageSearchMessages(t *testing.T) {
+	storage := NewMemoryStorage()
+	ctx := context.Background()
+
+	older := &Session{
+		ID:           "sess-old",
+		VisitorID:    "visitor-1",
+		CreatedAt:    time.Now().Add(-time.Hour),
+		LastActivity: time.Now().Add(-time.Hour),
+		Identity:     &UserIdentity{ID: "u1", Email: "alice@example.com"},
+	}
+	newer := &Session{
+		ID:           "sess-new",
+		VisitorID:    "visitor-2",
+		CreatedAt:    time.Now(),
+		LastActivity: time.Now(),
+	}
+	storage.CreateSession(ctx, older)
+	storage.CreateSession(ctx, newer)
+	storage.SaveMessage(ctx, &Message{ID: "m1", SessionID: "sess-new", Content: "My Invoice is wrong", Sender: SenderVisitor})
+	storage.SaveMessage(ctx, &Message{ID: "m2", SessionID: "sess-old", Content: "hello", Sender: SenderVisitor})
+
+	results, err := storage.SearchMessages(ctx, "invoice", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Session.ID != "sess-new" {
+		t.Fatalf("expected sess-new to match content, got %+v", results)
+	}
+	if results[0].Message == nil || results[0].Message.ID != "m1" {
+		t.Errorf("expected matching message m1, got %+v", results[0].Message)
+	}
+
+	results, _ = storage.SearchMessages(ctx, "ALICE@", 10)
+	if len(results) != 1 || results[0].Session.ID != "sess-old" || results[0].Message != nil {
+		t.Errorf("expected identity-only match on sess-old, got %+v", results)
+	}
+
+	results, _ = storage.SearchMessages(ctx, "e", 1)
+	if len(results) != 1 || results[0].Session.ID != "sess-new" {
+		t.Errorf("expected limit=1 to return most recent session, got %+v", results)
+	}
+
+	results, _ = storage.SearchMessages(ctx, "   ", 10)
+	if len(results) != 0 {
+		t.Errorf("expected no results for blank query, got %d", len(results))
+	}
+}
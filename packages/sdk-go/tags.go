@@ -0,0 +1,69 @@
+package pocketping
+
+import (
+	"context"
+	"strings"
+)
+
+// TagSession adds routing tags (e.g. "billing") to a session. Tags are
+// lowercased; Config.TagMentions pings the operators of the session's tags
+// in its notifications.
+func (pp *PocketPing) TagSession(ctx context.Context, sessionID string, tags ...string) (*Session, error) {
+	return pp.updateTags(ctx, sessionID, func(session *Session) {
+		for _, tag := range tags {
+			if tag = normalizeTag(tag); tag != "" && !session.HasTag(tag) {
+				session.Tags = append(session.Tags, tag)
+			}
+		}
+	})
+}
+
+// UntagSession removes routing tags from a session.
+func (pp *PocketPing) UntagSession(ctx context.Context, sessionID string, tags ...string) (*Session, error) {
+	return pp.updateTags(ctx, sessionID, func(session *Session) {
+		kept := session.Tags[:0]
+		for _, tag := range session.Tags {
+			removed := false
+			for _, t := range tags {
+				if normalizeTag(t) == tag {
+					removed = true
+				}
+			}
+			if !removed {
+				kept = append(kept, tag)
+			}
+		}
+		session.Tags = kept
+	})
+}
+
+// HasTag reports whether the session has a routing tag.
+func (s *Session) HasTag(tag string) bool {
+	tag = normalizeTag(tag)
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func (pp *PocketPing) updateTags(ctx context.Context, sessionID string, update func(session *Session)) (*Session, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	update(session)
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "session_update", Data: session})
+	return session, nil
+}
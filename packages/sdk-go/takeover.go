@@ -0,0 +1,66 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action IDs for the takeover buttons posted with AI replies (Slack block
+// action_id, Telegram callback_data prefix). See WebhookConfig.OnOperatorTakeover.
+const (
+	TakeoverActionID = "pocketping_takeover"
+	HandbackActionID = "pocketping_handback"
+)
+
+// TakeOver hands a session from the AI to a human operator: the AI fallback
+// stops replying until HandBack. Every bridge serving the session is told,
+// which doubles as the confirmation on the bridge the operator used.
+func (pp *PocketPing) TakeOver(ctx context.Context, sessionID, operatorName string) (*Session, error) {
+	session, err := pp.setHumanTakeover(ctx, sessionID, true)
+	if err != nil {
+		return nil, err
+	}
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("🙋 %s took over, AI paused", takeoverName(operatorName)))
+	return session, nil
+}
+
+// HandBack re-enables the AI fallback for a session taken over with
+// TakeOver. The AI replies to the next visitor message when no operator is
+// online, without waiting for the takeover delay.
+func (pp *PocketPing) HandBack(ctx context.Context, sessionID, operatorName string) (*Session, error) {
+	session, err := pp.setHumanTakeover(ctx, sessionID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pp.operatorActivityMu.Lock()
+	delete(pp.operatorActivity, sessionID)
+	pp.operatorActivityMu.Unlock()
+
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("🤖 %s handed back to the AI", takeoverName(operatorName)))
+	return session, nil
+}
+
+func (pp *PocketPing) setHumanTakeover(ctx context.Context, sessionID string, takeover bool) (*Session, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	session.HumanTakeover = takeover
+	session.AIActive = !takeover && pp.aiProvider != nil
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func takeoverName(operatorName string) string {
+	if operatorName == "" {
+		return "Operator"
+	}
+	return operatorName
+}
@@ -0,0 +1,249 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// notifyRecordingBridge records Notify calls.
+type notifyRecordingBridge struct {
+	BaseBridge
+	mu      sync.Mutex
+	notices []string
+}
+
+func (b *notifyRecordingBridge) Notify(ctx context.Context, session *Session, message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.notices = append(b.notices, message)
+	return nil
+}
+
+func TestTakeOverSilencesAIUntilHandBack(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeAIProvider{reply: "AI says hi"}
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{
+		AIProvider:      fake,
+		AITakeoverDelay: -1,
+		Bridges:         []Bridge{bridge},
+	})
+	session := newSession(ctx, t, pp)
+
+	updated, err := pp.TakeOver(ctx, session.ID, "Bob")
+	if err != nil {
+		t.Fatalf("TakeOver: %v", err)
+	}
+	if !updated.HumanTakeover || updated.AIActive {
+		t.Errorf("after TakeOver: HumanTakeover=%v AIActive=%v, want true/false", updated.HumanTakeover, updated.AIActive)
+	}
+
+	sendVisitorMessage(t, pp, session.ID, "anyone there?")
+	if fake.callCount() != 0 {
+		t.Errorf("provider calls after takeover = %d, want 0", fake.callCount())
+	}
+
+	updated, err = pp.HandBack(ctx, session.ID, "Bob")
+	if err != nil {
+		t.Fatalf("HandBack: %v", err)
+	}
+	if updated.HumanTakeover || !updated.AIActive {
+		t.Errorf("after HandBack: HumanTakeover=%v AIActive=%v, want false/true", updated.HumanTakeover, updated.AIActive)
+	}
+
+	sendVisitorMessage(t, pp, session.ID, "anyone there?")
+	if fake.callCount() != 1 {
+		t.Errorf("provider calls after handback = %d, want 1", fake.callCount())
+	}
+
+	pp.dispatcher.wait()
+	want := []string{"🙋 Bob took over, AI paused", "🤖 Bob handed back to the AI"}
+	if len(bridge.notices) != len(want) {
+		t.Fatalf("notices = %v, want %v", bridge.notices, want)
+	}
+	for i := range want {
+		if bridge.notices[i] != want[i] {
+			t.Errorf("notice %d = %q, want %q", i, bridge.notices[i], want[i])
+		}
+	}
+}
+
+func TestHandBackSkipsTakeoverDelay(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeAIProvider{reply: "AI says hi"}
+	pp := New(Config{AIProvider: fake, AITakeoverDelay: 3600})
+	session := newSession(ctx, t, pp)
+
+	// An operator reply starts the takeover delay.
+	if _, err := pp.SendOperatorMessage(ctx, session.ID, "hi", "telegram", "Bob"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if _, err := pp.HandBack(ctx, session.ID, "Bob"); err != nil {
+		t.Fatalf("HandBack: %v", err)
+	}
+
+	sendVisitorMessage(t, pp, session.ID, "anyone there?")
+	if fake.callCount() != 1 {
+		t.Errorf("provider calls = %d, want 1", fake.callCount())
+	}
+}
+
+func TestTakeOverUnknownSession(t *testing.T) {
+	pp := New(Config{})
+	if _, err := pp.TakeOver(context.Background(), "missing", "Bob"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("TakeOver error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────
+// Bridge commands and buttons
+// ─────────────────────────────────────────────────────────────────
+
+type takeoverCall struct {
+	sessionID, operatorName, source string
+	takeover                        bool
+}
+
+func takeoverRecorder(calls *[]takeoverCall) OperatorTakeoverCallback {
+	return func(ctx context.Context, sessionID, operatorName, sourceBridge string, takeover bool) {
+		*calls = append(*calls, takeoverCall{sessionID, operatorName, sourceBridge, takeover})
+	}
+}
+
+func TestWebhookHandler_TelegramTakeoverCommands(t *testing.T) {
+	var calls []takeoverCall
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken:   "test-token",
+		OnOperatorTakeover: takeoverRecorder(&calls),
+		OnOperatorMessage: func(ctx context.Context, sessionID, content, operatorName, sourceBridge string, attachments []Attachment, replyTo *int) {
+			t.Errorf("command forwarded as operator message: %q", content)
+		},
+	})
+
+	for _, text := range []string{"/takeover", "/handback@pocketping_bot", "/unknown"} {
+		payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"` + text + `"}}`)
+		handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+	}
+
+	want := []takeoverCall{
+		{"456", "Bob", "telegram", true},
+		{"456", "Bob", "telegram", false},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestWebhookHandler_TelegramTakeoverButton(t *testing.T) {
+	var answered bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/answerCallbackQuery") {
+			answered = true
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer server.Close()
+
+	var calls []takeoverCall
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken:   "test-token",
+		OnOperatorTakeover: takeoverRecorder(&calls),
+	})
+	handler.httpClient = &http.Client{Transport: &webhookTestTransport{telegramURL: server.URL}}
+
+	payload := []byte(`{"callback_query":{"id":"cb1","from":{"id":7,"first_name":"Bob"},"data":"pocketping_takeover:sess-1"}}`)
+	rec := httptest.NewRecorder()
+	handler.HandleTelegramWebhook()(rec, httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if len(calls) != 1 || calls[0] != (takeoverCall{"sess-1", "Bob", "telegram", true}) {
+		t.Errorf("calls = %+v", calls)
+	}
+	if !answered {
+		t.Error("expected answerCallbackQuery")
+	}
+}
+
+func TestWebhookHandler_SlackTakeoverButtons(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"user":{"real_name":"Bob Smith"}}`))
+	}))
+	defer server.Close()
+
+	var calls []takeoverCall
+	handler := NewWebhookHandler(WebhookConfig{
+		SlackBotToken:      "xoxb-test",
+		OnOperatorTakeover: takeoverRecorder(&calls),
+	})
+	handler.httpClient = &http.Client{Transport: &webhookTestTransport{slackURL: server.URL}}
+
+	interaction := `{"type":"block_actions","user":{"id":"U42"},"actions":[` +
+		`{"action_id":"pocketping_takeover","value":"sess-1"},` +
+		`{"action_id":"pocketping_handback","value":"sess-1"}]}`
+	form := url.Values{"payload": {interaction}}.Encode()
+	req := httptest.NewRequest("POST", "/webhooks/slack", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.HandleSlackWebhook()(httptest.NewRecorder(), req)
+
+	want := []takeoverCall{
+		{"sess-1", "Bob Smith", "slack", true},
+		{"sess-1", "Bob Smith", "slack", false},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestWebhookHandler_DiscordTakeoverCommands(t *testing.T) {
+	var calls []takeoverCall
+	handler := NewWebhookHandler(WebhookConfig{OnOperatorTakeover: takeoverRecorder(&calls)})
+
+	payload := []byte(`{"type":2,"channel_id":"T9","member":{"user":{"username":"bob"}},"data":{"name":"takeover"}}`)
+	rec := httptest.NewRecorder()
+	handler.HandleDiscordWebhook()(rec, httptest.NewRequest("POST", "/webhooks/discord", bytes.NewReader(payload)))
+
+	if len(calls) != 1 || calls[0] != (takeoverCall{"T9", "bob", "discord", true}) {
+		t.Errorf("calls = %+v", calls)
+	}
+	if !strings.Contains(rec.Body.String(), "AI paused") {
+		t.Errorf("expected confirmation, got %s", rec.Body.String())
+	}
+}
+
+func TestTelegramBridgeAIMessageHasTakeoverButtons(t *testing.T) {
+	var markup string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		markup = r.PostForm.Get("reply_markup")
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	b := telegramBridgeTo(t, srv)
+	_ = b.OnAIMessage(context.Background(), &Message{Content: "hi", Sender: SenderAI}, sampleSession())
+
+	for _, data := range []string{"pocketping_takeover:sess-1", "pocketping_handback:sess-1"} {
+		if !strings.Contains(markup, data) {
+			t.Errorf("reply_markup %s missing %q", markup, data)
+		}
+	}
+}
@@ -0,0 +1,695 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TeamsWebhookBridge sends notifications to a Microsoft Teams channel via an
+// incoming webhook (a Workflows "post to a channel when a webhook request is
+// received" flow, or a legacy Office 365 connector). Messages are posted as
+// Adaptive Cards.
+type TeamsWebhookBridge struct {
+	BaseBridge
+	WebhookURL string
+
+	httpClient *http.Client
+	dryRun     bool
+	pp         *PocketPing
+}
+
+// TeamsWebhookOption is a functional option for TeamsWebhookBridge.
+type TeamsWebhookOption func(*TeamsWebhookBridge)
+
+// WithTeamsWebhookHTTPClient sets a custom HTTP client.
+func WithTeamsWebhookHTTPClient(client *http.Client) TeamsWebhookOption {
+	return func(t *TeamsWebhookBridge) {
+		t.httpClient = client
+	}
+}
+
+// WithTeamsWebhookDryRun logs every outgoing Teams payload instead of calling the webhook,
+// for staging environments (see NewDryRunTransport).
+func WithTeamsWebhookDryRun() TeamsWebhookOption {
+	return func(t *TeamsWebhookBridge) {
+		t.dryRun = true
+	}
+}
+
+// NewTeamsWebhookBridge creates a new Teams webhook bridge.
+// Returns an error if configuration is invalid.
+func NewTeamsWebhookBridge(webhookURL string, opts ...TeamsWebhookOption) (*TeamsWebhookBridge, error) {
+	// Validate configuration
+	if err := ValidateTeamsWebhookConfig(webhookURL); err != nil {
+		if setupErr, ok := err.(*SetupError); ok {
+			log.Println(setupErr.FormattedGuide())
+		}
+		return nil, err
+	}
+
+	t := &TeamsWebhookBridge{
+		BaseBridge: BaseBridge{BridgeName: "teams-webhook"},
+		WebhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.dryRun {
+		t.httpClient = dryRunClient(t.Name(), t.httpClient)
+	}
+
+	return t, nil
+}
+
+// MustNewTeamsWebhookBridge creates a new Teams webhook bridge or panics on error.
+func MustNewTeamsWebhookBridge(webhookURL string, opts ...TeamsWebhookOption) *TeamsWebhookBridge {
+	t, err := NewTeamsWebhookBridge(webhookURL, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Destination returns the dedupe key of the bridge's webhook.
+func (t *TeamsWebhookBridge) Destination() string {
+	return BridgeDestination("teams", t.WebhookURL)
+}
+
+// Init initializes the Teams webhook bridge.
+func (t *TeamsWebhookBridge) Init(ctx context.Context, pp *PocketPing) error {
+	t.pp = pp
+	if pp != nil && pp.config.DryRun && !t.dryRun {
+		t.dryRun = true
+		t.httpClient = dryRunClient(t.Name(), t.httpClient)
+	}
+	return nil
+}
+
+// OnNewSession sends a notification when a new session is created.
+func (t *TeamsWebhookBridge) OnNewSession(ctx context.Context, session *Session) error {
+	return t.sendWebhookMessage(ctx, teamsNewSessionText(session))
+}
+
+// OnVisitorMessage sends a notification when a visitor sends a message.
+func (t *TeamsWebhookBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	text := fmt.Sprintf("💬 %s:\n%s", teamsVisitorName(session), message.Content+locationText(message.Location))
+	if quote := teamsReplyQuote(ctx, t.pp, message); quote != "" {
+		text = quote + "\n" + text
+	}
+
+	// Note: Teams webhooks don't return message IDs for editing
+	// For full edit/delete support, use TeamsBotBridge instead
+	return t.sendWebhookMessage(ctx, text)
+}
+
+// OnOperatorMessage is called when an operator sends a message.
+func (t *TeamsWebhookBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return t.OnAIMessage(ctx, message, session)
+	}
+
+	if sourceBridge == t.Name() {
+		return nil
+	}
+
+	name := operatorName
+	if name == "" {
+		name = "Operator"
+	}
+
+	text := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
+	return t.sendWebhookMessage(ctx, text)
+}
+
+// Notify posts a plain one-line notice.
+func (t *TeamsWebhookBridge) Notify(ctx context.Context, session *Session, message string) error {
+	return t.sendWebhookMessage(ctx, message)
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (t *TeamsWebhookBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	text := fmt.Sprintf("🤖 %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+	return t.sendWebhookMessage(ctx, text)
+}
+
+// OnTyping is a no-op for webhooks.
+func (t *TeamsWebhookBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
+	return nil
+}
+
+// OnMessageRead is called when messages are marked as read.
+func (t *TeamsWebhookBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status MessageStatus) error {
+	return nil
+}
+
+// OnCustomEvent is called when a custom event is triggered.
+func (t *TeamsWebhookBridge) OnCustomEvent(ctx context.Context, event CustomEvent, session *Session) error {
+	return t.sendWebhookMessage(ctx, teamsCustomEventText(event, session))
+}
+
+// OnIdentityUpdate is called when a user identifies themselves.
+func (t *TeamsWebhookBridge) OnIdentityUpdate(ctx context.Context, session *Session) error {
+	if session.Identity == nil {
+		return nil
+	}
+
+	return t.sendWebhookMessage(ctx, teamsIdentityText(session))
+}
+
+// Teams webhook helpers
+
+type teamsWebhookPayload struct {
+	Type        string                   `json:"type"`
+	Attachments []teamsWebhookAttachment `json:"attachments"`
+}
+
+type teamsWebhookAttachment struct {
+	ContentType string            `json:"contentType"`
+	ContentURL  *string           `json:"contentUrl"`
+	Content     teamsAdaptiveCard `json:"content"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string               `json:"$schema"`
+	Type    string               `json:"type"`
+	Version string               `json:"version"`
+	Body    []teamsCardTextBlock `json:"body"`
+}
+
+type teamsCardTextBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Wrap bool   `json:"wrap"`
+}
+
+func (t *TeamsWebhookBridge) sendWebhookMessage(ctx context.Context, text string) error {
+	// Adaptive Card text blocks need a blank line to break lines
+	payload := teamsWebhookPayload{
+		Type: "message",
+		Attachments: []teamsWebhookAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsAdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []teamsCardTextBlock{{
+					Type: "TextBlock",
+					Text: strings.ReplaceAll(text, "\n", "\n\n"),
+					Wrap: true,
+				}},
+			},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("teams error: %s (status %d)", string(respBody), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Ensure TeamsWebhookBridge implements Bridge interface
+var _ Bridge = (*TeamsWebhookBridge)(nil)
+
+// Ensure TeamsWebhookBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*TeamsWebhookBridge)(nil)
+
+// Ensure TeamsWebhookBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*TeamsWebhookBridge)(nil)
+
+// Ensure TeamsWebhookBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*TeamsWebhookBridge)(nil)
+
+// TeamsBotBridge posts to a Microsoft Teams channel with the Microsoft Graph
+// API, which returns message IDs so edits and deletes can be synced.
+//
+// Graph only accepts channel messages with delegated permissions
+// (ChannelMessage.Send, plus ChannelMessage.ReadWrite for edits and
+// deletes), so AccessToken must return a token of the account posting the
+// notifications, e.g. a service account signed in once and refreshed with
+// golang.org/x/oauth2.
+type TeamsBotBridge struct {
+	BaseBridge
+	TeamID    string
+	ChannelID string
+	// AccessToken returns a Microsoft Graph access token.
+	AccessToken func(ctx context.Context) (string, error)
+
+	httpClient *http.Client
+	dryRun     bool
+	pp         *PocketPing
+}
+
+// TeamsBotOption is a functional option for TeamsBotBridge.
+type TeamsBotOption func(*TeamsBotBridge)
+
+// WithTeamsBotHTTPClient sets a custom HTTP client.
+func WithTeamsBotHTTPClient(client *http.Client) TeamsBotOption {
+	return func(t *TeamsBotBridge) {
+		t.httpClient = client
+	}
+}
+
+// WithTeamsBotDryRun logs every outgoing Graph API request instead of calling Microsoft Graph,
+// for staging environments (see NewDryRunTransport).
+func WithTeamsBotDryRun() TeamsBotOption {
+	return func(t *TeamsBotBridge) {
+		t.dryRun = true
+	}
+}
+
+// NewTeamsBotBridge creates a new Teams bot bridge posting to the channel
+// channelID of the team teamID.
+// Returns an error if configuration is invalid.
+func NewTeamsBotBridge(teamID, channelID string, accessToken func(ctx context.Context) (string, error), opts ...TeamsBotOption) (*TeamsBotBridge, error) {
+	// Validate configuration
+	err := ValidateTeamsBotConfig(teamID, channelID)
+	if err == nil && accessToken == nil {
+		err = NewSetupError("Teams", "access_token")
+	}
+	if err != nil {
+		if setupErr, ok := err.(*SetupError); ok {
+			log.Println(setupErr.FormattedGuide())
+		}
+		return nil, err
+	}
+
+	t := &TeamsBotBridge{
+		BaseBridge:  BaseBridge{BridgeName: "teams-bot"},
+		TeamID:      teamID,
+		ChannelID:   channelID,
+		AccessToken: accessToken,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.dryRun {
+		t.httpClient = dryRunClient(t.Name(), t.httpClient)
+	}
+
+	return t, nil
+}
+
+// MustNewTeamsBotBridge creates a new Teams bot bridge or panics on error.
+func MustNewTeamsBotBridge(teamID, channelID string, accessToken func(ctx context.Context) (string, error), opts ...TeamsBotOption) *TeamsBotBridge {
+	t, err := NewTeamsBotBridge(teamID, channelID, accessToken, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Destination returns the dedupe key of the bridge's channel.
+func (t *TeamsBotBridge) Destination() string {
+	return BridgeDestination("teams", t.TeamID+"/"+t.ChannelID)
+}
+
+// Init initializes the Teams bot bridge.
+func (t *TeamsBotBridge) Init(ctx context.Context, pp *PocketPing) error {
+	t.pp = pp
+	if pp != nil && pp.config.DryRun && !t.dryRun {
+		t.dryRun = true
+		t.httpClient = dryRunClient(t.Name(), t.httpClient)
+	}
+	return nil
+}
+
+// OnNewSession sends a notification when a new session is created.
+func (t *TeamsBotBridge) OnNewSession(ctx context.Context, session *Session) error {
+	_, err := t.sendMessage(ctx, teamsNewSessionText(session))
+	return err
+}
+
+// OnVisitorMessage sends a notification when a visitor sends a message.
+func (t *TeamsBotBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
+	text := fmt.Sprintf("💬 %s:\n%s", teamsVisitorName(session), message.Content+locationText(message.Location))
+	if quote := teamsReplyQuote(ctx, t.pp, message); quote != "" {
+		text = quote + "\n" + text
+	}
+
+	result, err := t.sendMessage(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	// Save bridge message ID for edit/delete support
+	if result != nil && result.TeamsMessageID != "" && t.pp != nil {
+		if storage, ok := t.pp.GetStorage().(StorageWithBridgeIDs); ok {
+			_ = storage.SaveBridgeMessageIDs(ctx, message.ID, BridgeMessageIds{
+				TeamsMessageID: result.TeamsMessageID,
+			})
+		}
+	}
+
+	return nil
+}
+
+// OnOperatorMessage is called when an operator sends a message.
+func (t *TeamsBotBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return t.OnAIMessage(ctx, message, session)
+	}
+
+	if sourceBridge == t.Name() {
+		return nil
+	}
+
+	name := operatorName
+	if name == "" {
+		name = "Operator"
+	}
+
+	text := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
+	_, err := t.sendMessage(ctx, text)
+	return err
+}
+
+// Notify posts a plain one-line notice.
+func (t *TeamsBotBridge) Notify(ctx context.Context, session *Session, message string) error {
+	_, err := t.sendMessage(ctx, message)
+	return err
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (t *TeamsBotBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	text := fmt.Sprintf("🤖 %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+	_, err := t.sendMessage(ctx, text)
+	return err
+}
+
+// OnTyping is a no-op: Graph has no typing indicator for channels.
+func (t *TeamsBotBridge) OnTyping(ctx context.Context, sessionID string, isTyping bool) error {
+	return nil
+}
+
+// OnMessageRead is called when messages are marked as read.
+func (t *TeamsBotBridge) OnMessageRead(ctx context.Context, sessionID string, messageIDs []string, status MessageStatus) error {
+	return nil
+}
+
+// OnCustomEvent is called when a custom event is triggered.
+func (t *TeamsBotBridge) OnCustomEvent(ctx context.Context, event CustomEvent, session *Session) error {
+	_, err := t.sendMessage(ctx, teamsCustomEventText(event, session))
+	return err
+}
+
+// OnIdentityUpdate is called when a user identifies themselves.
+func (t *TeamsBotBridge) OnIdentityUpdate(ctx context.Context, session *Session) error {
+	if session.Identity == nil {
+		return nil
+	}
+
+	_, err := t.sendMessage(ctx, teamsIdentityText(session))
+	return err
+}
+
+// OnMessageEdit handles message edits.
+func (t *TeamsBotBridge) OnMessageEdit(ctx context.Context, sessionID, messageID, content string, editedAt time.Time) (*BridgeMessageResult, error) {
+	if t.pp == nil {
+		return nil, nil
+	}
+
+	storage, ok := t.pp.GetStorage().(StorageWithBridgeIDs)
+	if !ok {
+		return nil, nil
+	}
+
+	bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, messageID)
+	if err != nil || bridgeIDs == nil || bridgeIDs.TeamsMessageID == "" {
+		return nil, nil
+	}
+
+	err = t.editMessage(ctx, bridgeIDs.TeamsMessageID, content+" (edited)")
+	if err != nil {
+		return nil, err
+	}
+
+	return &BridgeMessageResult{
+		TeamsMessageID: bridgeIDs.TeamsMessageID,
+	}, nil
+}
+
+// OnMessageDelete handles message deletions.
+func (t *TeamsBotBridge) OnMessageDelete(ctx context.Context, sessionID, messageID string, deletedAt time.Time) error {
+	if t.pp == nil {
+		return nil
+	}
+
+	storage, ok := t.pp.GetStorage().(StorageWithBridgeIDs)
+	if !ok {
+		return nil
+	}
+
+	bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, messageID)
+	if err != nil || bridgeIDs == nil || bridgeIDs.TeamsMessageID == "" {
+		return nil
+	}
+
+	err = t.deleteMessage(ctx, bridgeIDs.TeamsMessageID)
+	return err
+}
+
+// Microsoft Graph API helpers
+
+const teamsGraphAPIBase = "https://graph.microsoft.com/v1.0"
+
+type teamsItemBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type teamsChatMessagePayload struct {
+	Body teamsItemBody `json:"body"`
+}
+
+type teamsChatMessage struct {
+	ID string `json:"id"`
+}
+
+func (t *TeamsBotBridge) messagesURL() string {
+	return fmt.Sprintf("%s/teams/%s/channels/%s/messages", teamsGraphAPIBase, url.PathEscape(t.TeamID), url.PathEscape(t.ChannelID))
+}
+
+func (t *TeamsBotBridge) sendMessage(ctx context.Context, text string) (*BridgeMessageResult, error) {
+	respBody, err := t.do(ctx, "POST", t.messagesURL(), teamsChatMessagePayload{Body: teamsHTMLBody(text)})
+	if err != nil {
+		return nil, err
+	}
+
+	var msg teamsChatMessage
+	if err := json.Unmarshal(respBody, &msg); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	return &BridgeMessageResult{
+		TeamsMessageID: msg.ID,
+	}, nil
+}
+
+func (t *TeamsBotBridge) editMessage(ctx context.Context, messageID, text string) error {
+	_, err := t.do(ctx, "PATCH", t.messagesURL()+"/"+url.PathEscape(messageID), teamsChatMessagePayload{Body: teamsHTMLBody(text)})
+	return err
+}
+
+func (t *TeamsBotBridge) deleteMessage(ctx context.Context, messageID string) error {
+	_, err := t.do(ctx, "POST", t.messagesURL()+"/"+url.PathEscape(messageID)+"/softDelete", nil)
+	return err
+}
+
+// do sends a Graph API request with payload as its JSON body, if any, and
+// returns the response body.
+func (t *TeamsBotBridge) do(ctx context.Context, method, apiURL string, payload interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal payload: %w", err)
+		}
+		reqBody = bytes.NewReader(body)
+	}
+
+	token, err := t.AccessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("teams error: %s (status %d)", string(respBody), resp.StatusCode)
+	}
+
+	return respBody, nil
+}
+
+// Ensure TeamsBotBridge implements Bridge interface
+var _ Bridge = (*TeamsBotBridge)(nil)
+
+// Ensure TeamsBotBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*TeamsBotBridge)(nil)
+
+// Ensure TeamsBotBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*TeamsBotBridge)(nil)
+
+// Ensure TeamsBotBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*TeamsBotBridge)(nil)
+
+// Ensure TeamsBotBridge implements BridgeWithEditDelete interface
+var _ BridgeWithEditDelete = (*TeamsBotBridge)(nil)
+
+// Shared Teams message formatting
+
+// teamsHTMLBody returns text as a Graph HTML message body.
+func teamsHTMLBody(text string) teamsItemBody {
+	return teamsItemBody{
+		ContentType: "html",
+		Content:     strings.ReplaceAll(html.EscapeString(text), "\n", "<br>"),
+	}
+}
+
+func teamsNewSessionText(session *Session) string {
+	text := "🆕 New chat session\n"
+
+	// Contact info
+	var email string
+	if session.Identity != nil && session.Identity.Email != "" {
+		email = session.Identity.Email
+	}
+	phone := session.UserPhone
+	var userAgent string
+	if session.Metadata != nil && session.Metadata.UserAgent != "" {
+		userAgent = session.Metadata.UserAgent
+	}
+
+	if email != "" {
+		text += fmt.Sprintf("\n📧 %s", email)
+	}
+	if phone != "" {
+		text += fmt.Sprintf("\n📱 %s", phone)
+	}
+	if userAgent != "" {
+		text += fmt.Sprintf("\n🌐 %s", parseUserAgent(userAgent))
+	}
+
+	if email != "" || phone != "" || userAgent != "" {
+		text += "\n"
+	}
+
+	if session.Metadata != nil && session.Metadata.URL != "" {
+		text += fmt.Sprintf("\n📍 %s", session.Metadata.URL)
+	}
+	return text
+}
+
+func teamsCustomEventText(event CustomEvent, session *Session) string {
+	text := fmt.Sprintf("📌 Event from %s: %s", teamsVisitorName(session), event.Name)
+	if len(event.Data) > 0 {
+		dataJSON, err := json.Marshal(event.Data)
+		if err == nil {
+			text += fmt.Sprintf("\n📦 %s", string(dataJSON))
+		}
+	}
+	return text
+}
+
+func teamsIdentityText(session *Session) string {
+	text := fmt.Sprintf("🔐 User identified\n👤 ID: %s", session.Identity.ID)
+	if session.Identity.Name != "" {
+		text += fmt.Sprintf("\n📛 Name: %s", session.Identity.Name)
+	}
+	if session.Identity.Email != "" {
+		text += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email+disposableEmailNote(session))
+	}
+	if session.UserPhone != "" {
+		text += fmt.Sprintf("\n📱 Phone: %s", session.UserPhone)
+	}
+	return text
+}
+
+// teamsReplyQuote returns a one-line quote of the message replied to, or "".
+// Graph can't reply to a specific channel message, only to a thread root.
+func teamsReplyQuote(ctx context.Context, pp *PocketPing, message *Message) string {
+	if replyTarget(message) == "" || pp == nil {
+		return ""
+	}
+	target, err := pp.GetStorage().GetMessage(ctx, replyTarget(message))
+	if err != nil || target == nil {
+		return ""
+	}
+
+	senderLabel := "Visitor"
+	switch target.Sender {
+	case SenderOperator:
+		senderLabel = "Support"
+	case SenderAI:
+		senderLabel = "AI"
+	}
+
+	preview := target.Content
+	if target.DeletedAt != nil {
+		preview = "Message deleted"
+	}
+	if len(preview) > 140 {
+		preview = preview[:140] + "..."
+	}
+
+	return fmt.Sprintf("↩️ %s — %s", senderLabel, preview)
+}
+
+func teamsVisitorName(session *Session) string {
+	if session.Identity != nil && session.Identity.Name != "" {
+		return session.Identity.Name
+	}
+	if session.Identity != nil && session.Identity.Email != "" {
+		return session.Identity.Email
+	}
+	return session.VisitorID
+}
@@ -0,0 +1,216 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// graphTestTransport rewrites Microsoft Graph requests to the test server
+type graphTestTransport struct {
+	baseURL string
+}
+
+func (t *graphTestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "graph.microsoft.com" {
+		newReq, _ := http.NewRequest(req.Method, t.baseURL+req.URL.EscapedPath(), req.Body)
+		newReq.Header = req.Header
+		return http.DefaultTransport.RoundTrip(newReq)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type graphRequest struct {
+	method string
+	path   string
+	html   string
+}
+
+// graphServer fakes the channel messages endpoints, returning ids m1, m2, ...
+func graphServer(t *testing.T) (*httptest.Server, func() []graphRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []graphRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer graph-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload teamsChatMessagePayload
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+
+		mu.Lock()
+		requests = append(requests, graphRequest{method: r.Method, path: r.URL.EscapedPath(), html: payload.Body.Content})
+		n := len(requests)
+		mu.Unlock()
+		switch r.Method {
+		case "POST":
+			if strings.HasSuffix(r.URL.Path, "/softDelete") {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"m` + string(rune('0'+n)) + `"}`))
+		case "PATCH":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []graphRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]graphRequest(nil), requests...)
+	}
+}
+
+func graphToken(ctx context.Context) (string, error) { return "graph-token", nil }
+
+func TestTeamsBotBridgeEditDelete(t *testing.T) {
+	srv, requests := graphServer(t)
+	ctx := context.Background()
+	pp := New(Config{})
+	b := MustNewTeamsBotBridge("team-1", "19:abc@thread.tacv2", graphToken,
+		WithTeamsBotHTTPClient(&http.Client{Transport: &graphTestTransport{baseURL: srv.URL}}))
+	if err := b.Init(ctx, pp); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	s := sampleSession()
+	_ = b.OnVisitorMessage(ctx, &Message{ID: "msg-1", Content: "<b>hi</b>\nthere", Sender: SenderVisitor}, s)
+	ids, _ := pp.GetStorage().(StorageWithBridgeIDs).GetBridgeMessageIDs(ctx, "msg-1")
+	if ids == nil || ids.TeamsMessageID != "m1" {
+		t.Fatalf("bridge ids = %+v", ids)
+	}
+
+	result, err := b.OnMessageEdit(ctx, s.ID, "msg-1", "fixed", time.Now())
+	if err != nil || result == nil || result.TeamsMessageID != "m1" {
+		t.Fatalf("OnMessageEdit = %+v, %v", result, err)
+	}
+	if err := b.OnMessageDelete(ctx, s.ID, "msg-1", time.Now()); err != nil {
+		t.Fatalf("OnMessageDelete: %v", err)
+	}
+
+	const messages = "/v1.0/teams/team-1/channels/19:abc@thread.tacv2/messages"
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("requests = %+v", got)
+	}
+	if got[0].method != "POST" || got[0].path != messages || got[0].html != "💬 Alice:<br>&lt;b&gt;hi&lt;/b&gt;<br>there" {
+		t.Errorf("send = %+v", got[0])
+	}
+	if got[1].method != "PATCH" || got[1].path != messages+"/m1" || got[1].html != "fixed (edited)" {
+		t.Errorf("edit = %+v", got[1])
+	}
+	if got[2].method != "POST" || got[2].path != messages+"/m1/softDelete" {
+		t.Errorf("delete = %+v", got[2])
+	}
+
+	// Messages never posted to Teams are left alone
+	if result, _ := b.OnMessageEdit(ctx, s.ID, "unknown", "x", time.Now()); result != nil {
+		t.Errorf("OnMessageEdit(unknown) = %+v", result)
+	}
+	if len(requests()) != 3 {
+		t.Errorf("requests = %+v", requests())
+	}
+}
+
+func TestTeamsBotBridgeEvents(t *testing.T) {
+	srv, requests := graphServer(t)
+	ctx := context.Background()
+	b := MustNewTeamsBotBridge("team-1", "channel-1", graphToken,
+		WithTeamsBotHTTPClient(&http.Client{Transport: &graphTestTransport{baseURL: srv.URL}}))
+	s := sampleSession()
+	_ = b.Init(ctx, nil)
+	_ = b.OnNewSession(ctx, s)
+	_ = b.OnOperatorMessage(ctx, &Message{Content: "hi"}, s, "slack", "Op")
+	_ = b.OnOperatorMessage(ctx, &Message{Content: "echo"}, s, "teams-bot", "") // skip
+	_ = b.OnOperatorMessage(ctx, &Message{Content: "beep", Sender: SenderAI}, s, "ai", "")
+	_ = b.OnTyping(ctx, "s", true) // no-op
+	_ = b.OnCustomEvent(ctx, CustomEvent{Name: "e", Data: map[string]interface{}{"a": 1}}, s)
+	_ = b.OnIdentityUpdate(ctx, s)
+	_ = b.OnIdentityUpdate(ctx, &Session{ID: "x"}) // nil identity skip
+
+	got := requests()
+	if len(got) != 5 {
+		t.Fatalf("requests = %+v", got)
+	}
+	if !strings.HasPrefix(got[0].html, "🆕 New chat session") || !strings.Contains(got[0].html, "📧 alice@example.com") {
+		t.Errorf("new session = %q", got[0].html)
+	}
+	if got[1].html != "👨‍💼 Op:<br>hi" || !strings.HasPrefix(got[2].html, "🤖 ") {
+		t.Errorf("operator/AI = %q, %q", got[1].html, got[2].html)
+	}
+	if !strings.HasPrefix(got[4].html, "🔐 User identified") {
+		t.Errorf("identity = %q", got[4].html)
+	}
+}
+
+func TestTeamsBotBridgeAccessTokenError(t *testing.T) {
+	b := MustNewTeamsBotBridge("team-1", "channel-1", func(ctx context.Context) (string, error) {
+		return "", errors.New("refresh failed")
+	})
+	if _, err := b.sendMessage(context.Background(), "hi"); err == nil || !strings.Contains(err.Error(), "refresh failed") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTeamsWebhookBridge(t *testing.T) {
+	var texts []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload teamsWebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Type != "message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		card := payload.Attachments[0]
+		if card.ContentType != "application/vnd.microsoft.card.adaptive" || card.Content.Type != "AdaptiveCard" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		texts = append(texts, card.Content.Body[0].Text)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	b := MustNewTeamsWebhookBridge(srv.URL+"/workflows/abc", WithTeamsWebhookHTTPClient(srv.Client()))
+	s := sampleSession()
+	_ = b.Init(ctx, nil)
+	_ = b.OnVisitorMessage(ctx, &Message{Content: "hello"}, s)
+	_ = b.OnOperatorMessage(ctx, &Message{Content: "echo"}, s, "teams-webhook", "") // skip
+	_ = b.OnIdentityUpdate(ctx, s)
+
+	if len(texts) != 2 || texts[0] != "💬 Alice:\n\nhello" || !strings.HasPrefix(texts[1], "🔐 User identified\n\n👤 ID: u1") {
+		t.Errorf("texts = %q", texts)
+	}
+}
+
+func TestTeamsBridgeConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		create  func() error
+		missing string
+	}{
+		{"webhook url", func() error { _, err := NewTeamsWebhookBridge(""); return err }, "webhook_url"},
+		{"insecure webhook url", func() error { _, err := NewTeamsWebhookBridge("http://example.com/hook"); return err }, "valid webhook_url"},
+		{"team", func() error { _, err := NewTeamsBotBridge("", "c", graphToken); return err }, "team_id"},
+		{"channel", func() error { _, err := NewTeamsBotBridge("t", "", graphToken); return err }, "channel_id"},
+		{"token", func() error { _, err := NewTeamsBotBridge("t", "c", nil); return err }, "access_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var setupErr *SetupError
+			if err := tt.create(); !errors.As(err, &setupErr) || setupErr.Missing != tt.missing || setupErr.Bridge != "Teams" {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
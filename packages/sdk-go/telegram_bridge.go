@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,6 +24,7 @@ type TelegramBridge struct {
 	DisableNotification bool
 
 	httpClient *http.Client
+	dryRun     bool
 	pp         *PocketPing
 }
 
@@ -49,6 +52,14 @@ func WithTelegramHTTPClient(client *http.Client) TelegramOption {
 	}
 }
 
+// WithTelegramDryRun logs every outgoing Telegram payload instead of calling the Telegram
+// API, for staging environments (see NewDryRunTransport).
+func WithTelegramDryRun() TelegramOption {
+	return func(t *TelegramBridge) {
+		t.dryRun = true
+	}
+}
+
 // NewTelegramBridge creates a new Telegram bridge.
 // Returns an error if configuration is invalid.
 func NewTelegramBridge(botToken, chatID string, opts ...TelegramOption) (*TelegramBridge, error) {
@@ -71,6 +82,9 @@ func NewTelegramBridge(botToken, chatID string, opts ...TelegramOption) (*Telegr
 	for _, opt := range opts {
 		opt(t)
 	}
+	if t.dryRun {
+		t.httpClient = dryRunClient(t.Name(), t.httpClient)
+	}
 
 	return t, nil
 }
@@ -84,9 +98,18 @@ func MustNewTelegramBridge(botToken, chatID string, opts ...TelegramOption) *Tel
 	return t
 }
 
+// Destination returns the dedupe key of the bridge's chat.
+func (t *TelegramBridge) Destination() string {
+	return BridgeDestination("telegram", t.ChatID)
+}
+
 // Init initializes the Telegram bridge.
 func (t *TelegramBridge) Init(ctx context.Context, pp *PocketPing) error {
 	t.pp = pp
+	if pp != nil && pp.config.DryRun && !t.dryRun {
+		t.dryRun = true
+		t.httpClient = dryRunClient(t.Name(), t.httpClient)
+	}
 	return nil
 }
 
@@ -124,10 +147,7 @@ func (t *TelegramBridge) OnNewSession(ctx context.Context, session *Session) err
 	}
 
 	_, err := t.sendMessage(ctx, text, nil)
-	if err != nil {
-		log.Printf("[TelegramBridge] OnNewSession error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // parseUserAgent parses user agent string to a readable format.
@@ -164,15 +184,15 @@ func parseUserAgent(ua string) string {
 // OnVisitorMessage sends a notification when a visitor sends a message.
 func (t *TelegramBridge) OnVisitorMessage(ctx context.Context, message *Message, session *Session) error {
 	visitorName := t.getVisitorName(session)
-	text := fmt.Sprintf("💬 %s:\n%s", visitorName, message.Content)
+	text := fmt.Sprintf("💬 %s:\n%s", visitorName, message.Content+locationText(message.Location))
 	for _, att := range message.Attachments {
 		text += fmt.Sprintf("\n📎 %s", att.Filename)
 	}
 
 	var replyToMessageID *int64
-	if message.ReplyTo != "" && t.pp != nil {
+	if replyTarget(message) != "" && t.pp != nil {
 		if storage, ok := t.pp.GetStorage().(StorageWithBridgeIDs); ok {
-			bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, message.ReplyTo)
+			bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, replyTarget(message))
 			if err == nil && bridgeIDs != nil && bridgeIDs.TelegramMessageID != 0 {
 				id := bridgeIDs.TelegramMessageID
 				replyToMessageID = &id
@@ -182,8 +202,7 @@ func (t *TelegramBridge) OnVisitorMessage(ctx context.Context, message *Message,
 
 	result, err := t.sendMessage(ctx, text, replyToMessageID)
 	if err != nil {
-		log.Printf("[TelegramBridge] OnVisitorMessage error: %v", err)
-		return nil
+		return err
 	}
 
 	// Save bridge message ID for edit/delete support
@@ -195,11 +214,40 @@ func (t *TelegramBridge) OnVisitorMessage(ctx context.Context, message *Message,
 		}
 	}
 
+	if message.Location != nil && result != nil {
+		if err := t.sendLocation(ctx, message.Location, result.TelegramMessageID); err != nil {
+			log.Printf("[TelegramBridge] sendLocation error: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// OnVisitorAttachments uploads the files of a visitor message as
+// documents, in reply to the message.
+func (t *TelegramBridge) OnVisitorAttachments(ctx context.Context, message *Message, session *Session, attachments []Attachment) error {
+	var replyToMessageID int64
+	if t.pp != nil {
+		if storage, ok := t.pp.GetStorage().(StorageWithBridgeIDs); ok {
+			if bridgeIDs, err := storage.GetBridgeMessageIDs(ctx, message.ID); err == nil && bridgeIDs != nil {
+				replyToMessageID = bridgeIDs.TelegramMessageID
+			}
+		}
+	}
+	for _, att := range attachments {
+		if err := t.sendDocument(ctx, &att, replyToMessageID); err != nil {
+			log.Printf("[TelegramBridge] sendDocument error: %v", err)
+		}
+	}
 	return nil
 }
 
 // OnOperatorMessage is called when an operator sends a message.
 func (t *TelegramBridge) OnOperatorMessage(ctx context.Context, message *Message, session *Session, sourceBridge string, operatorName string) error {
+	if message.Sender == SenderAI {
+		return t.OnAIMessage(ctx, message, session)
+	}
+
 	// Don't echo messages that originated from this bridge
 	if sourceBridge == t.Name() {
 		return nil
@@ -210,13 +258,32 @@ func (t *TelegramBridge) OnOperatorMessage(ctx context.Context, message *Message
 		name = "Operator"
 	}
 
-	text := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content)
+	text := fmt.Sprintf("👨‍💼 %s:\n%s", name, message.Content+attachmentsText(message.Attachments)+quickRepliesText(message.QuickReplies))
 
 	_, err := t.sendMessage(ctx, text, nil)
-	if err != nil {
-		log.Printf("[TelegramBridge] OnOperatorMessage error: %v", err)
+	return err
+}
+
+// Notify posts a plain one-line notice.
+func (t *TelegramBridge) Notify(ctx context.Context, session *Session, message string) error {
+	_, err := t.sendMessage(ctx, message, nil)
+	return err
+}
+
+// OnAIMessage posts an AI reply, labelled so it stands out from operator replies.
+func (t *TelegramBridge) OnAIMessage(ctx context.Context, message *Message, session *Session) error {
+	text := fmt.Sprintf("🤖 %s:\n%s", AIDisplayName, message.Content+quickRepliesText(message.QuickReplies))
+	markup := map[string]interface{}{
+		"inline_keyboard": [][]map[string]string{
+			{
+				{"text": "🙋 Take over", "callback_data": TakeoverActionID + ":" + session.ID},
+				{"text": "🤖 Hand back", "callback_data": HandbackActionID + ":" + session.ID},
+			},
+		},
 	}
-	return nil
+
+	_, err := t.sendMessageWithMarkup(ctx, text, nil, markup)
+	return err
 }
 
 // OnTyping sends a typing indicator.
@@ -225,11 +292,7 @@ func (t *TelegramBridge) OnTyping(ctx context.Context, sessionID string, isTypin
 		return nil
 	}
 
-	err := t.sendChatAction(ctx, "typing")
-	if err != nil {
-		log.Printf("[TelegramBridge] OnTyping error: %v", err)
-	}
-	return nil
+	return t.sendChatAction(ctx, "typing")
 }
 
 // OnMessageRead is called when messages are marked as read.
@@ -251,10 +314,7 @@ func (t *TelegramBridge) OnCustomEvent(ctx context.Context, event CustomEvent, s
 	}
 
 	_, err := t.sendMessage(ctx, text, nil)
-	if err != nil {
-		log.Printf("[TelegramBridge] OnCustomEvent error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnIdentityUpdate is called when a user identifies themselves.
@@ -268,17 +328,14 @@ func (t *TelegramBridge) OnIdentityUpdate(ctx context.Context, session *Session)
 		text += fmt.Sprintf("\n📛 Name: %s", session.Identity.Name)
 	}
 	if session.Identity.Email != "" {
-		text += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email)
+		text += fmt.Sprintf("\n📧 Email: %s", session.Identity.Email+disposableEmailNote(session))
 	}
 	if session.UserPhone != "" {
 		text += fmt.Sprintf("\n📱 Phone: %s", session.UserPhone)
 	}
 
 	_, err := t.sendMessage(ctx, text, nil)
-	if err != nil {
-		log.Printf("[TelegramBridge] OnIdentityUpdate error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // OnMessageEdit handles message edits.
@@ -300,8 +357,7 @@ func (t *TelegramBridge) OnMessageEdit(ctx context.Context, sessionID, messageID
 
 	err = t.editMessageText(ctx, bridgeIDs.TelegramMessageID, content+" (edited)")
 	if err != nil {
-		log.Printf("[TelegramBridge] OnMessageEdit error: %v", err)
-		return nil, nil
+		return nil, err
 	}
 
 	return &BridgeMessageResult{
@@ -327,10 +383,7 @@ func (t *TelegramBridge) OnMessageDelete(ctx context.Context, sessionID, message
 	}
 
 	err = t.deleteMessage(ctx, bridgeIDs.TelegramMessageID)
-	if err != nil {
-		log.Printf("[TelegramBridge] OnMessageDelete error: %v", err)
-	}
-	return nil
+	return err
 }
 
 // Telegram API helpers
@@ -346,8 +399,12 @@ type telegramMessage struct {
 }
 
 func (t *TelegramBridge) sendMessage(ctx context.Context, text string, replyToMessageID *int64) (*BridgeMessageResult, error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+	return t.sendMessageWithMarkup(ctx, text, replyToMessageID, nil)
+}
 
+// sendMessageWithMarkup sends a message with an optional reply_markup (e.g.
+// an inline keyboard).
+func (t *TelegramBridge) sendMessageWithMarkup(ctx context.Context, text string, replyToMessageID *int64, markup interface{}) (*BridgeMessageResult, error) {
 	params := url.Values{}
 	params.Set("chat_id", t.ChatID)
 	params.Set("text", text)
@@ -360,6 +417,29 @@ func (t *TelegramBridge) sendMessage(ctx context.Context, text string, replyToMe
 	if replyToMessageID != nil {
 		params.Set("reply_to_message_id", fmt.Sprintf("%d", *replyToMessageID))
 	}
+	if markup != nil {
+		encoded, err := json.Marshal(markup)
+		if err != nil {
+			return nil, fmt.Errorf("marshal reply markup: %w", err)
+		}
+		params.Set("reply_markup", string(encoded))
+	}
+	return t.postSendMessage(ctx, params)
+}
+
+// SendDirectMessage sends text to an operator's private chat with the bot,
+// always with a notification. The operator must have started the bot.
+func (t *TelegramBridge) SendDirectMessage(ctx context.Context, userID, text string) error {
+	params := url.Values{}
+	params.Set("chat_id", userID)
+	params.Set("text", text)
+	_, err := t.postSendMessage(ctx, params)
+	return err
+}
+
+// postSendMessage calls sendMessage with the given parameters.
+func (t *TelegramBridge) postSendMessage(ctx context.Context, params url.Values) (*BridgeMessageResult, error) {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBufferString(params.Encode()))
 	if err != nil {
@@ -437,6 +517,104 @@ func (t *TelegramBridge) editMessageText(ctx context.Context, messageID int64, t
 	return nil
 }
 
+// sendLocation posts a native location pin, which Telegram shows as a map,
+// as a reply to the message that described it.
+func (t *TelegramBridge) sendLocation(ctx context.Context, location *Location, replyToMessageID int64) error {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendLocation", t.BotToken)
+
+	params := url.Values{}
+	params.Set("chat_id", t.ChatID)
+	params.Set("latitude", strconv.FormatFloat(location.Latitude, 'f', -1, 64))
+	params.Set("longitude", strconv.FormatFloat(location.Longitude, 'f', -1, 64))
+	params.Set("disable_notification", "true")
+	if replyToMessageID != 0 {
+		params.Set("reply_to_message_id", fmt.Sprintf("%d", replyToMessageID))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBufferString(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	var tgResp telegramResponse
+	if err := json.Unmarshal(body, &tgResp); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+
+	if !tgResp.OK {
+		return fmt.Errorf("telegram error: %s", tgResp.Error)
+	}
+
+	return nil
+}
+
+// sendDocument uploads an attachment's Data as a document.
+func (t *TelegramBridge) sendDocument(ctx context.Context, att *Attachment, replyToMessageID int64) error {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendDocument", t.BotToken)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("chat_id", t.ChatID)
+	_ = writer.WriteField("disable_notification", "true")
+	if replyToMessageID != 0 {
+		_ = writer.WriteField("reply_to_message_id", fmt.Sprintf("%d", replyToMessageID))
+	}
+	filename := att.Filename
+	if filename == "" {
+		filename = att.ID
+	}
+	part, err := writer.CreateFormFile("document", filename)
+	if err != nil {
+		return fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := part.Write(att.Data); err != nil {
+		return fmt.Errorf("write form file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close form: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, &body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	var tgResp telegramResponse
+	if err := json.Unmarshal(respBody, &tgResp); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+
+	if !tgResp.OK {
+		return fmt.Errorf("telegram error: %s", tgResp.Error)
+	}
+
+	return nil
+}
+
 func (t *TelegramBridge) deleteMessage(ctx context.Context, messageID int64) error {
 	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/deleteMessage", t.BotToken)
 
@@ -508,5 +686,20 @@ func (t *TelegramBridge) getVisitorName(session *Session) string {
 // Ensure TelegramBridge implements Bridge interface
 var _ Bridge = (*TelegramBridge)(nil)
 
+// Ensure TelegramBridge implements BridgeWithDestination interface
+var _ BridgeWithDestination = (*TelegramBridge)(nil)
+
+// Ensure TelegramBridge implements BridgeWithAIMessage interface
+var _ BridgeWithAIMessage = (*TelegramBridge)(nil)
+
+// Ensure TelegramBridge implements BridgeWithNotify interface
+var _ BridgeWithNotify = (*TelegramBridge)(nil)
+
 // Ensure TelegramBridge implements BridgeWithEditDelete interface
 var _ BridgeWithEditDelete = (*TelegramBridge)(nil)
+
+// Ensure TelegramBridge implements BridgeWithAttachments interface
+var _ BridgeWithAttachments = (*TelegramBridge)(nil)
+
+// Ensure TelegramBridge implements BridgeWithDirectMessages interface
+var _ BridgeWithDirectMessages = (*TelegramBridge)(nil)
@@ -0,0 +1,67 @@
+package pocketping
+
+import "context"
+
+// threadPageSize is how many messages threadMessages reads from storage at a
+// time while filtering a thread.
+const threadPageSize = 100
+
+// WithThread posts an operator message in a thread: threadID is the ID of
+// the message that started it, or of any message already in it.
+func WithThread(threadID string) OperatorMessageOption {
+	return func(o *operatorMessageOptions) {
+		o.threadID = threadID
+	}
+}
+
+// resolveThread returns the thread ID to store on a new message of the
+// session. The root must be a message of the session; naming a message
+// that is itself in a thread resolves to that thread, so threads don't nest.
+func (pp *PocketPing) resolveThread(ctx context.Context, sessionID, threadID string) (string, error) {
+	if threadID == "" {
+		return "", nil
+	}
+	root, err := pp.storage.GetMessage(ctx, threadID)
+	if err != nil {
+		return "", err
+	}
+	if root == nil || root.SessionID != sessionID {
+		return "", ErrThreadNotFound
+	}
+	if root.ThreadID != "" {
+		return root.ThreadID, nil
+	}
+	return root.ID, nil
+}
+
+// threadMessages returns up to limit messages of a thread, its root first,
+// after the message with ID after (if set).
+func (pp *PocketPing) threadMessages(ctx context.Context, sessionID, threadID, after string, limit int) ([]Message, error) {
+	thread := []Message{}
+	for len(thread) < limit {
+		page, err := pp.storage.GetMessages(ctx, sessionID, after, threadPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range page {
+			if (msg.ID == threadID || msg.ThreadID == threadID) && len(thread) < limit {
+				thread = append(thread, msg)
+			}
+		}
+		if len(page) < threadPageSize {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+	return thread, nil
+}
+
+// replyTarget returns the message a bridge should show message as a reply
+// to: its ReplyTo, or else the root of its thread, so threads map onto the
+// platform's replies within the session's topic or thread.
+func replyTarget(message *Message) string {
+	if message.ReplyTo != "" {
+		return message.ReplyTo
+	}
+	return message.ThreadID
+}
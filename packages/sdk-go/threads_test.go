@@ -0,0 +1,86 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestThreads(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	root, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "Billing question", Sender: SenderVisitor})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "Unrelated", Sender: SenderVisitor}); err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+
+	reply, err := pp.SendOperatorMessage(ctx, session.ID, "Which invoice?", "", "Bob", WithThread(root.MessageID))
+	if err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if reply.ThreadID != root.MessageID {
+		t.Errorf("reply thread = %q, want %q", reply.ThreadID, root.MessageID)
+	}
+	broadcast := conn.events[len(conn.events)-1].Data.(*Message)
+	if broadcast.ThreadID != root.MessageID {
+		t.Errorf("broadcast thread = %q, want %q", broadcast.ThreadID, root.MessageID)
+	}
+
+	// Replying to a reply stays in the root's thread.
+	nested, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "March", Sender: SenderVisitor, ThreadID: reply.ID})
+	if err != nil {
+		t.Fatalf("HandleMessage: %v", err)
+	}
+	if nested.ThreadID != root.MessageID {
+		t.Errorf("nested thread = %q, want %q", nested.ThreadID, root.MessageID)
+	}
+
+	thread, err := pp.HandleGetMessages(ctx, GetMessagesRequest{SessionID: session.ID, ThreadID: root.MessageID})
+	if err != nil {
+		t.Fatalf("HandleGetMessages: %v", err)
+	}
+	if len(thread.Messages) != 3 || thread.Messages[0].ID != root.MessageID || thread.Messages[2].ID != nested.MessageID {
+		t.Errorf("thread = %+v", thread.Messages)
+	}
+
+	_, err = pp.HandleMessage(ctx, SendMessageRequest{SessionID: session.ID, Content: "x", Sender: SenderVisitor, ThreadID: "missing"})
+	if !errors.Is(err, ErrThreadNotFound) {
+		t.Errorf("expected ErrThreadNotFound, got %v", err)
+	}
+}
+
+func TestThreadTyping(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket("s1", conn)
+
+	pp.HandleTyping(ctx, TypingRequest{SessionID: "s1", Sender: SenderOperator, IsTyping: true, ThreadID: "m1"})
+	data := conn.events[0].Data.(map[string]interface{})
+	if data["threadId"] != "m1" {
+		t.Errorf("typing data = %v, want threadId m1", data)
+	}
+}
+
+func TestReplyTarget(t *testing.T) {
+	tests := []struct {
+		message Message
+		want    string
+	}{
+		{Message{ID: "m1"}, ""},
+		{Message{ID: "m2", ThreadID: "m1"}, "m1"},
+		{Message{ID: "m3", ThreadID: "m1", ReplyTo: "m2"}, "m2"},
+	}
+	for _, tt := range tests {
+		if got := replyTarget(&tt.message); got != tt.want {
+			t.Errorf("replyTarget(%+v) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Ticket is a ticket created in an external system from a session.
+type Ticket struct {
+	// System names the ticketing system, e.g. "github", "linear", "zendesk".
+	System string `json:"system"`
+	// ID is the ticket's ID in that system (issue number, identifier, ...).
+	ID string `json:"id"`
+	// URL links to the ticket.
+	URL string `json:"url,omitempty"`
+	// CreatedAt is when the ticket was created.
+	CreatedAt time.Time `json:"createdAt"`
+	// CreatedBy is the operator who created it.
+	CreatedBy string `json:"createdBy,omitempty"`
+}
+
+// TicketRequest is what a TicketCreator turns into a ticket.
+type TicketRequest struct {
+	Session *Session
+	// Title is the operator's title, or a default built from the visitor.
+	Title string
+	// Transcript is the conversation as plain text, one message per line.
+	Transcript string
+	// Messages are the session's messages, oldest first.
+	Messages []Message
+	// OperatorName is the operator who asked for the ticket.
+	OperatorName string
+}
+
+// TicketCreator creates tickets in an external system (Zendesk, Linear,
+// GitHub Issues, ...) for PocketPing.CreateTicket. The returned ticket's
+// System and ID are required; CreatedAt and CreatedBy are filled in.
+type TicketCreator interface {
+	CreateTicket(ctx context.Context, request TicketRequest) (*Ticket, error)
+}
+
+// TicketCommandCallback is called when an operator runs /ticket [title] in
+// a session's topic or thread. Typically calls PocketPing.CreateTicket.
+type TicketCommandCallback func(ctx context.Context, sessionID, operatorName, sourceBridge, title string)
+
+// CreateTicket hands a session off to Config.TicketCreator: it creates a
+// ticket from the transcript, records it in Session.Tickets, posts the link
+// on every bridge serving the session and sends it to the visitor.
+func (pp *PocketPing) CreateTicket(ctx context.Context, sessionID, operatorName, title string) (*Ticket, error) {
+	if pp.config.TicketCreator == nil {
+		return nil, ErrTicketCreatorNotConfigured
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	messages, err := pp.allMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = defaultTicketTitle(session)
+	}
+	ticket, err := pp.config.TicketCreator.CreateTicket(ctx, TicketRequest{
+		Session:      session,
+		Title:        title,
+		Transcript:   FormatTranscript(messages),
+		Messages:     messages,
+		OperatorName: operatorName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ticket.CreatedAt.IsZero() {
+		ticket.CreatedAt = time.Now()
+	}
+	if ticket.CreatedBy == "" {
+		ticket.CreatedBy = operatorName
+	}
+
+	session.Tickets = append(session.Tickets, *ticket)
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("🎫 %s created %s ticket %s %s", takeoverName(operatorName), ticket.System, ticket.ID, ticket.URL))
+	content := fmt.Sprintf("We've opened ticket %s for your request.", ticket.ID)
+	if ticket.URL != "" {
+		content += " You can follow it here: " + ticket.URL
+	}
+	if _, err := pp.HandleMessage(ctx, SendMessageRequest{SessionID: sessionID, Content: content, Sender: SenderOperator}); err != nil {
+		return ticket, err
+	}
+	return ticket, nil
+}
+
+// runTicketCommand calls OnTicketCommand in the background, since creating a
+// ticket can outlast the platform's webhook deadline (3 seconds for a
+// Discord interaction), then done, if any.
+func (wh *WebhookHandler) runTicketCommand(ctx context.Context, sessionID, operatorName, sourceBridge, title string, done func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				recoveredPanics.Add(1)
+				log.Printf("[PocketPing] Panic handling /ticket: %v\n%s", rec, debug.Stack())
+			}
+		}()
+		wh.config.OnTicketCommand(ctx, sessionID, operatorName, sourceBridge, title)
+		if done != nil {
+			done(ctx)
+		}
+	}()
+}
+
+// editDiscordInteractionResponse replaces the "thinking" placeholder of a
+// deferred interaction response with content.
+func (wh *WebhookHandler) editDiscordInteractionResponse(ctx context.Context, interaction *DiscordInteraction, content string) {
+	body, _ := json.Marshal(map[string]string{"content": content})
+	apiURL := fmt.Sprintf("%s/webhooks/%s/%s/messages/@original", discordAPIBase, interaction.ApplicationID, interaction.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := wh.httpClient.Do(req)
+	if err != nil {
+		log.Printf("[PocketPing] Discord interaction response edit failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[PocketPing] Discord interaction response edit failed: %s", resp.Status)
+	}
+}
+
+// allMessages returns every message of a session, oldest first.
+func (pp *PocketPing) allMessages(ctx context.Context, sessionID string) ([]Message, error) {
+	var messages []Message
+	after := ""
+	for {
+		page, err := pp.storage.GetMessages(ctx, sessionID, after, threadPageSize)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, page...)
+		if len(page) < threadPageSize {
+			return messages, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// FormatTranscript renders messages as plain text, one line per message:
+// "[2025-01-15 10:00] Visitor: Hello". Deleted messages are left out.
+func FormatTranscript(messages []Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		if msg.DeletedAt != nil {
+			continue
+		}
+		label := "Visitor"
+		switch msg.Sender {
+		case SenderOperator:
+			label = "Support"
+		case SenderAI:
+			label = "AI"
+		}
+		fmt.Fprintf(&b, "[%s] %s: %s\n", msg.Timestamp.UTC().Format("2006-01-02 15:04"), label, msg.Content)
+		for _, att := range msg.Attachments {
+			fmt.Fprintf(&b, "    📎 %s %s\n", att.Filename, att.URL)
+		}
+	}
+	return b.String()
+}
+
+func defaultTicketTitle(session *Session) string {
+	if session.Identity != nil {
+		if session.Identity.Name != "" {
+			return "Chat with " + session.Identity.Name
+		}
+		if session.Identity.Email != "" {
+			return "Chat with " + session.Identity.Email
+		}
+	}
+	return "Chat " + session.ID
+}
+
+// parseTicketCommand recognises /ticket [title] (also as /ticket@bot) and
+// returns the title.
+func parseTicketCommand(text string) (title string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	if command != "/ticket" {
+		return "", false
+	}
+	return strings.Join(fields[1:], " "), true
+}
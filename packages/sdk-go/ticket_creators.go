@@ -0,0 +1,220 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ticketDescription is the ticket body: the transcript plus a few session
+// details.
+func ticketDescription(request TicketRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PocketPing session %s", request.Session.ID)
+	if identity := request.Session.Identity; identity != nil && identity.Email != "" {
+		fmt.Fprintf(&b, " (%s)", identity.Email)
+	}
+	if request.Session.Metadata != nil && request.Session.Metadata.URL != "" {
+		fmt.Fprintf(&b, "\nPage: %s", request.Session.Metadata.URL)
+	}
+	if request.OperatorName != "" {
+		fmt.Fprintf(&b, "\nHanded off by %s", request.OperatorName)
+	}
+	b.WriteString("\n\n")
+	b.WriteString(request.Transcript)
+	return b.String()
+}
+
+// doJSON sends a JSON request (no body when payload is nil) and decodes the
+// response into v. service prefixes the status error.
+func doJSON(ctx context.Context, client *http.Client, service, method, url string, header http.Header, payload, v interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClientOr(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: unexpected status %d", service, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ─────────────────────────────────────────────────────────────────
+// GitHubIssuesTicketCreator
+// ─────────────────────────────────────────────────────────────────
+
+// GitHubIssuesTicketCreator implements TicketCreator by opening GitHub issues.
+type GitHubIssuesTicketCreator struct {
+	// Token is a GitHub token allowed to create issues in the repository (required).
+	Token string
+	// Repo is the repository, "owner/name" (required).
+	Repo string
+	// Labels are added to every issue.
+	Labels []string
+	// BaseURL is the API base URL (default "https://api.github.com").
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// CreateTicket calls POST {baseURL}/repos/{repo}/issues.
+func (c *GitHubIssuesTicketCreator) CreateTicket(ctx context.Context, request TicketRequest) (*Ticket, error) {
+	baseURL := "https://api.github.com"
+	if c.BaseURL != "" {
+		baseURL = strings.TrimRight(c.BaseURL, "/")
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.Token)
+	header.Set("Accept", "application/vnd.github+json")
+
+	payload := map[string]interface{}{"title": request.Title, "body": ticketDescription(request)}
+	if len(c.Labels) > 0 {
+		payload["labels"] = c.Labels
+	}
+	var issue struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := doJSON(ctx, c.HTTPClient, "github", http.MethodPost, baseURL+"/repos/"+c.Repo+"/issues", header, payload, &issue); err != nil {
+		return nil, err
+	}
+	return &Ticket{System: "github", ID: "#" + strconv.Itoa(issue.Number), URL: issue.HTMLURL}, nil
+}
+
+// ─────────────────────────────────────────────────────────────────
+// LinearTicketCreator
+// ─────────────────────────────────────────────────────────────────
+
+// LinearTicketCreator implements TicketCreator by creating Linear issues.
+type LinearTicketCreator struct {
+	// APIKey is a Linear API key (required).
+	APIKey string
+	// TeamID is the ID of the team that owns the issues (required).
+	TeamID string
+	// BaseURL is the GraphQL endpoint (default "https://api.linear.app/graphql").
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// CreateTicket runs the issueCreate GraphQL mutation.
+func (c *LinearTicketCreator) CreateTicket(ctx context.Context, request TicketRequest) (*Ticket, error) {
+	endpoint := "https://api.linear.app/graphql"
+	if c.BaseURL != "" {
+		endpoint = c.BaseURL
+	}
+	header := http.Header{}
+	header.Set("Authorization", c.APIKey)
+
+	payload := map[string]interface{}{
+		"query": `mutation IssueCreate($input: IssueCreateInput!) { issueCreate(input: $input) { success issue { identifier url } } }`,
+		"variables": map[string]interface{}{
+			"input": map[string]string{"teamId": c.TeamID, "title": request.Title, "description": ticketDescription(request)},
+		},
+	}
+	var result struct {
+		Data struct {
+			IssueCreate struct {
+				Success bool `json:"success"`
+				Issue   struct {
+					Identifier string `json:"identifier"`
+					URL        string `json:"url"`
+				} `json:"issue"`
+			} `json:"issueCreate"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := doJSON(ctx, c.HTTPClient, "linear", http.MethodPost, endpoint, header, payload, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("linear: %s", result.Errors[0].Message)
+	}
+	if !result.Data.IssueCreate.Success {
+		return nil, fmt.Errorf("linear: issue not created")
+	}
+	issue := result.Data.IssueCreate.Issue
+	return &Ticket{System: "linear", ID: issue.Identifier, URL: issue.URL}, nil
+}
+
+// ─────────────────────────────────────────────────────────────────
+// ZendeskTicketCreator
+// ─────────────────────────────────────────────────────────────────
+
+// ZendeskTicketCreator implements TicketCreator by creating Zendesk tickets,
+// with the visitor as requester when their email is known.
+type ZendeskTicketCreator struct {
+	// Subdomain is the Zendesk subdomain, as in {subdomain}.zendesk.com (required).
+	Subdomain string
+	// Email and APIToken authenticate an agent (required).
+	Email    string
+	APIToken string
+	// Tags are added to every ticket.
+	Tags []string
+	// BaseURL overrides "https://{subdomain}.zendesk.com".
+	BaseURL string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// CreateTicket calls POST {baseURL}/api/v2/tickets.json.
+func (c *ZendeskTicketCreator) CreateTicket(ctx context.Context, request TicketRequest) (*Ticket, error) {
+	baseURL := "https://" + c.Subdomain + ".zendesk.com"
+	if c.BaseURL != "" {
+		baseURL = strings.TrimRight(c.BaseURL, "/")
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.Email+"/token:"+c.APIToken)))
+
+	ticket := map[string]interface{}{
+		"subject": request.Title,
+		"comment": map[string]string{"body": ticketDescription(request)},
+	}
+	if identity := request.Session.Identity; identity != nil && identity.Email != "" {
+		ticket["requester"] = map[string]string{"email": identity.Email, "name": identity.Name}
+	}
+	if len(c.Tags) > 0 {
+		ticket["tags"] = c.Tags
+	}
+	var result struct {
+		Ticket struct {
+			ID int64 `json:"id"`
+		} `json:"ticket"`
+	}
+	if err := doJSON(ctx, c.HTTPClient, "zendesk", http.MethodPost, baseURL+"/api/v2/tickets.json", header, map[string]interface{}{"ticket": ticket}, &result); err != nil {
+		return nil, err
+	}
+	id := strconv.FormatInt(result.Ticket.ID, 10)
+	return &Ticket{System: "zendesk", ID: id, URL: baseURL + "/agent/tickets/" + id}, nil
+}
+
+var (
+	_ TicketCreator = (*GitHubIssuesTicketCreator)(nil)
+	_ TicketCreator = (*LinearTicketCreator)(nil)
+	_ TicketCreator = (*ZendeskTicketCreator)(nil)
+)
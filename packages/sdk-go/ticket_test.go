@@ -0,0 +1,180 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeTicketCreator struct {
+	requests []TicketRequest
+}
+
+func (f *fakeTicketCreator) CreateTicket(ctx context.Context, request TicketRequest) (*Ticket, error) {
+	f.requests = append(f.requests, request)
+	return &Ticket{System: "github", ID: "#12", URL: "https://github.com/acme/support/issues/12"}, nil
+}
+
+func TestCreateTicket(t *testing.T) {
+	ctx := context.Background()
+	creator := &fakeTicketCreator{}
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{TicketCreator: creator, Bridges: []Bridge{bridge}})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+	sendVisitorMessage(t, pp, session.ID, "My invoice is wrong")
+
+	ticket, err := pp.CreateTicket(ctx, session.ID, "Bob", "")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	if ticket.CreatedBy != "Bob" || ticket.CreatedAt.IsZero() {
+		t.Errorf("ticket = %+v", ticket)
+	}
+
+	request := creator.requests[0]
+	if request.Title != "Chat "+session.ID || !strings.Contains(request.Transcript, "Visitor: My invoice is wrong") {
+		t.Errorf("request = %+v", request)
+	}
+
+	updated, _ := pp.GetSession(ctx, session.ID)
+	if len(updated.Tickets) != 1 || updated.Tickets[0].ID != "#12" {
+		t.Errorf("session tickets = %+v", updated.Tickets)
+	}
+	pp.dispatcher.wait()
+	if len(bridge.notices) != 1 || !strings.Contains(bridge.notices[0], "https://github.com/acme/support/issues/12") {
+		t.Errorf("notices = %q", bridge.notices)
+	}
+	visitor := conn.events[len(conn.events)-1].Data.(*Message)
+	if visitor.Sender != SenderOperator || !strings.Contains(visitor.Content, "https://github.com/acme/support/issues/12") {
+		t.Errorf("visitor message = %+v", visitor)
+	}
+}
+
+func TestCreateTicketWithoutCreator(t *testing.T) {
+	pp := New(Config{})
+	if _, err := pp.CreateTicket(context.Background(), "s1", "Bob", ""); !errors.Is(err, ErrTicketCreatorNotConfigured) {
+		t.Errorf("error = %v, want ErrTicketCreatorNotConfigured", err)
+	}
+}
+
+func TestGitHubIssuesTicketCreator(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/support/issues" || r.Header.Get("Authorization") != "Bearer ghp_x" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number":12,"html_url":"https://github.com/acme/support/issues/12"}`))
+	}))
+	defer server.Close()
+
+	creator := &GitHubIssuesTicketCreator{Token: "ghp_x", Repo: "acme/support", Labels: []string{"support"}, BaseURL: server.URL}
+	ticket, err := creator.CreateTicket(context.Background(), TicketRequest{
+		Session:    &Session{ID: "s1"},
+		Title:      "Invoice",
+		Transcript: "[2025-01-15 10:00] Visitor: hi\n",
+	})
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	if ticket.ID != "#12" || ticket.System != "github" {
+		t.Errorf("ticket = %+v", ticket)
+	}
+	if payload["title"] != "Invoice" || !strings.Contains(payload["body"].(string), "Visitor: hi") {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestZendeskTicketCreatorSetsRequester(t *testing.T) {
+	var payload struct {
+		Ticket map[string]interface{} `json:"ticket"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, ok := r.BasicAuth(); !ok || user != "agent@acme.com/token" {
+			t.Errorf("unexpected auth %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"ticket":{"id":345}}`))
+	}))
+	defer server.Close()
+
+	creator := &ZendeskTicketCreator{Email: "agent@acme.com", APIToken: "tok", BaseURL: server.URL}
+	ticket, err := creator.CreateTicket(context.Background(), TicketRequest{
+		Session: &Session{ID: "s1", Identity: &UserIdentity{ID: "u1", Email: "jane@example.com"}},
+		Title:   "Invoice",
+	})
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	if ticket.ID != "345" || ticket.URL != server.URL+"/agent/tickets/345" {
+		t.Errorf("ticket = %+v", ticket)
+	}
+	if requester, _ := payload.Ticket["requester"].(map[string]interface{}); requester["email"] != "jane@example.com" {
+		t.Errorf("payload = %v", payload.Ticket)
+	}
+}
+
+func TestWebhookHandler_TicketCommands(t *testing.T) {
+	type call struct{ sessionID, operatorName, source, title string }
+	calls := make(chan call, 2)
+	release := make(chan struct{})
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		OnTicketCommand: func(ctx context.Context, sessionID, operatorName, sourceBridge, title string) {
+			<-release
+			calls <- call{sessionID, operatorName, sourceBridge, title}
+		},
+	})
+	edits := make(chan string, 1)
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Content string }
+		json.NewDecoder(r.Body).Decode(&body)
+		edits <- r.Method + " " + r.URL.Path + " " + body.Content
+	}))
+	defer discord.Close()
+	handler.httpClient = &http.Client{Transport: &discordTestTransport{baseURL: discord.URL}}
+
+	// Both webhooks answer before the (blocked) ticket creation finishes.
+	payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"/ticket@pocketping_bot Wrong invoice"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	payload = []byte(`{"type":2,"application_id":"app1","token":"tok1","channel_id":"T9","member":{"user":{"username":"bob"}},"data":{"name":"ticket","options":[{"name":"title","value":"Refund"}]}}`)
+	rec := httptest.NewRecorder()
+	handler.HandleDiscordWebhook()(rec, httptest.NewRequest("POST", "/webhooks/discord", bytes.NewReader(payload)))
+	if !strings.Contains(rec.Body.String(), `"type":5`) {
+		t.Errorf("discord response = %s, want a deferred response", rec.Body.String())
+	}
+	close(release)
+
+	want := map[call]bool{
+		{"456", "Bob", "telegram", "Wrong invoice"}: true,
+		{"T9", "bob", "discord", "Refund"}:          true,
+	}
+	for range want {
+		select {
+		case got := <-calls:
+			if !want[got] {
+				t.Errorf("unexpected call %+v", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ticket command not called")
+		}
+	}
+	select {
+	case edit := <-edits:
+		if edit != "PATCH /api/v10/webhooks/app1/tok1/messages/@original 🎫 Ticket requested" {
+			t.Errorf("edit = %q", edit)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deferred response not edited")
+	}
+}
@@ -0,0 +1,83 @@
+package pocketping
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// EventValidator is implemented by Subscribe payload types that check
+// their own fields.
+type EventValidator interface {
+	Validate() error
+}
+
+// TypedEventOptions tunes Subscribe.
+type TypedEventOptions struct {
+	// Strict rejects payloads with fields T doesn't have.
+	Strict bool
+	// OnInvalid receives the events whose payload didn't decode or
+	// validate. Defaults to logging them.
+	OnInvalid func(event CustomEvent, session *Session, err error)
+}
+
+// Subscribe subscribes to a custom event with a typed handler: the event's
+// Data is decoded into a T, which is validated when it implements
+// EventValidator, before handler is called. Events that don't decode or
+// validate skip handler. Pass the returned subscription to Unsubscribe.
+//
+//	type PlanSelected struct {
+//		Plan  string `json:"plan"`
+//		Seats int    `json:"seats"`
+//	}
+//	pocketping.Subscribe(pp, "plan_selected", func(e PlanSelected, s *pocketping.Session) { ... })
+func Subscribe[T any](pp *PocketPing, eventName string, handler func(T, *Session), opts ...TypedEventOptions) EventSubscription {
+	var options TypedEventOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return pp.SubscribeEvent(eventName, func(event CustomEvent, session *Session) {
+		payload, err := decodeEventData[T](event.Data, options.Strict)
+		if err != nil {
+			if options.OnInvalid != nil {
+				options.OnInvalid(event, session, err)
+				return
+			}
+			log.Printf("[PocketPing] Ignored invalid %q event: %v", event.Name, err)
+			return
+		}
+		handler(payload, session)
+	})
+}
+
+// DecodeEventData decodes a custom event's Data into a T and validates it
+// when T implements EventValidator, for handlers registered with OnEvent.
+func DecodeEventData[T any](event CustomEvent) (T, error) {
+	return decodeEventData[T](event.Data, false)
+}
+
+func decodeEventData[T any](data map[string]interface{}, strict bool) (T, error) {
+	var payload T
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return payload, fmt.Errorf("invalid event data: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&payload); err != nil {
+		return payload, fmt.Errorf("invalid event data: %w", err)
+	}
+	validator, ok := any(payload).(EventValidator)
+	if !ok {
+		validator, ok = any(&payload).(EventValidator)
+	}
+	if ok {
+		if err := validator.Validate(); err != nil {
+			return payload, err
+		}
+	}
+	return payload, nil
+}
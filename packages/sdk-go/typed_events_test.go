@@ -0,0 +1,82 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type planSelected struct {
+	Plan  string `json:"plan"`
+	Seats int    `json:"seats"`
+}
+
+func (p *planSelected) Validate() error {
+	if p.Plan == "" {
+		return errors.New("plan is required")
+	}
+	return nil
+}
+
+func TestSubscribeTyped(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	var got []planSelected
+	var invalid []error
+	sub := Subscribe(pp, "plan_selected", func(e planSelected, session *Session) {
+		if session.ID != sessionID {
+			t.Errorf("session = %s", session.ID)
+		}
+		got = append(got, e)
+	}, TypedEventOptions{OnInvalid: func(event CustomEvent, session *Session, err error) {
+		invalid = append(invalid, err)
+	}})
+
+	events := []map[string]interface{}{
+		{"plan": "pro", "seats": 5},
+		{"seats": 5},                     // fails Validate
+		{"plan": "pro", "seats": "five"}, // wrong type
+	}
+	for _, data := range events {
+		if err := pp.HandleCustomEvent(ctx, sessionID, CustomEvent{Name: "plan_selected", Data: data}); err != nil {
+			t.Fatalf("HandleCustomEvent: %v", err)
+		}
+	}
+	if len(got) != 1 || got[0] != (planSelected{Plan: "pro", Seats: 5}) {
+		t.Errorf("got = %+v", got)
+	}
+	if len(invalid) != 2 {
+		t.Errorf("invalid = %v", invalid)
+	}
+
+	pp.Unsubscribe(sub)
+	_ = pp.HandleCustomEvent(ctx, sessionID, CustomEvent{Name: "plan_selected", Data: events[0]})
+	if len(got) != 1 {
+		t.Error("expected no call after Unsubscribe")
+	}
+}
+
+func TestSubscribeStrict(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+
+	calls := 0
+	Subscribe(pp, "plan_selected", func(e planSelected, session *Session) { calls++ }, TypedEventOptions{Strict: true})
+	_ = pp.HandleCustomEvent(ctx, sessionID, CustomEvent{Name: "plan_selected", Data: map[string]interface{}{"plan": "pro", "coupon": "X"}})
+	if calls != 0 {
+		t.Error("expected the unknown field to be rejected")
+	}
+}
+
+func TestDecodeEventData(t *testing.T) {
+	e, err := DecodeEventData[planSelected](CustomEvent{Data: map[string]interface{}{"plan": "team", "seats": 3.0}})
+	if err != nil || e.Plan != "team" || e.Seats != 3 {
+		t.Errorf("DecodeEventData = %+v, %v", e, err)
+	}
+	if _, err := DecodeEventData[*planSelected](CustomEvent{Data: map[string]interface{}{"seats": 3}}); err == nil {
+		t.Error("expected Validate on a pointer payload")
+	}
+}
@@ -0,0 +1,296 @@
+package pocketping
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Defaults for WaitQueueConfig.
+const (
+	DefaultQueueResponseWindow  = 20
+	DefaultQueueWait            = 2 * time.Minute
+	DefaultQueueDisconnectGrace = time.Minute
+	DefaultQueueIdleTimeout     = 15 * time.Minute
+)
+
+// waitQueueSweepInterval is how often Start's sweep takes abandoned
+// sessions out of the line.
+const waitQueueSweepInterval = 15 * time.Second
+
+// WaitQueueConfig tells visitors waiting for a first operator reply their
+// place in line and how long they'll likely wait (see Config.WaitQueue).
+// A session joins the line with its first visitor message and leaves it
+// with the first operator reply, when it ends, or when its visitor leaves.
+//
+// The line is kept in memory on each node, and only holds the sessions
+// whose messages that node handled: with several replicas, positions are
+// per replica. Use sticky sessions at the load balancer, or a single node.
+type WaitQueueConfig struct {
+	// ResponseWindow is how many recent first-response times the estimate
+	// averages. Defaults to DefaultQueueResponseWindow.
+	ResponseWindow int
+	// DefaultWait is the wait per place in line until a first response has
+	// been timed. Defaults to DefaultQueueWait.
+	DefaultWait time.Duration
+	// DisconnectGrace is how long a session keeps its place once its last
+	// WebSocket on the node closed, e.g. across a page load. Defaults to
+	// DefaultQueueDisconnectGrace.
+	DisconnectGrace time.Duration
+	// IdleTimeout takes a session out of line once its visitor has sent
+	// nothing for this long. Defaults to DefaultQueueIdleTimeout.
+	IdleTimeout time.Duration
+}
+
+// QueueStatus is a waiting session's place in line, in
+// ConnectResponse.Queue and "queue_update" WebSocket events. Position 0
+// means the session isn't waiting (any more).
+type QueueStatus struct {
+	// Position is 1 for the next session to be answered.
+	Position int `json:"position"`
+	// EstimatedWaitSeconds is Position times the recent average first
+	// response time.
+	EstimatedWaitSeconds int `json:"estimatedWaitSeconds"`
+}
+
+// waitQueue is the line of sessions awaiting a first operator reply.
+type waitQueue struct {
+	mu      sync.Mutex
+	waiting []queuedSession
+	// responses are the latest first-response times, oldest first
+	responses []time.Duration
+}
+
+type queuedSession struct {
+	sessionID string
+	since     time.Time
+	// lastActive is the visitor's last message.
+	lastActive time.Time
+	// disconnectedAt is when the session's last socket closed, zero while
+	// one is open (or none ever was).
+	disconnectedAt time.Time
+}
+
+// join adds a session at the end of the line, reporting whether it wasn't
+// in line yet. A session already in line is marked active at since.
+func (q *waitQueue) join(sessionID string, since time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i := q.index(sessionID); i >= 0 {
+		q.waiting[i].lastActive = since
+		return false
+	}
+	q.waiting = append(q.waiting, queuedSession{sessionID: sessionID, since: since, lastActive: since})
+	return true
+}
+
+// disconnected records that a waiting session's last socket closed at at,
+// or reopened (zero at).
+func (q *waitQueue) disconnected(sessionID string, at time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i := q.index(sessionID); i >= 0 {
+		q.waiting[i].disconnectedAt = at
+	}
+}
+
+// expire takes the sessions disconnected for grace, or idle for idle, out
+// of the line and returns their IDs.
+func (q *waitQueue) expire(now time.Time, grace, idle time.Duration) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var expired []string
+	kept := q.waiting[:0]
+	for _, s := range q.waiting {
+		gone := !s.disconnectedAt.IsZero() && now.Sub(s.disconnectedAt) >= grace
+		if gone || now.Sub(s.lastActive) >= idle {
+			expired = append(expired, s.sessionID)
+			continue
+		}
+		kept = append(kept, s)
+	}
+	q.waiting = kept
+	return expired
+}
+
+// leave takes a session out of the line, reporting whether it was in it.
+// A session answered at answeredAt adds its wait to the response times.
+func (q *waitQueue) leave(sessionID string, answeredAt *time.Time, window int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.index(sessionID)
+	if i < 0 {
+		return false
+	}
+	if answeredAt != nil {
+		q.responses = append(q.responses, answeredAt.Sub(q.waiting[i].since))
+		if len(q.responses) > window {
+			q.responses = q.responses[len(q.responses)-window:]
+		}
+	}
+	q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+	return true
+}
+
+func (q *waitQueue) index(sessionID string) int {
+	for i, s := range q.waiting {
+		if s.sessionID == sessionID {
+			return i
+		}
+	}
+	return -1
+}
+
+// statuses returns the status of every waiting session by session ID.
+func (q *waitQueue) statuses(defaultWait time.Duration) map[string]QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	perPlace := defaultWait
+	if len(q.responses) > 0 {
+		var total time.Duration
+		for _, d := range q.responses {
+			total += d
+		}
+		perPlace = total / time.Duration(len(q.responses))
+	}
+	statuses := make(map[string]QueueStatus, len(q.waiting))
+	for i, s := range q.waiting {
+		statuses[s.sessionID] = QueueStatus{
+			Position:             i + 1,
+			EstimatedWaitSeconds: int((time.Duration(i+1) * perPlace).Round(time.Second).Seconds()),
+		}
+	}
+	return statuses
+}
+
+// QueueStatus returns a session's place in this node's line, when
+// Config.WaitQueue is set and the session is waiting for a first operator
+// reply.
+func (pp *PocketPing) QueueStatus(sessionID string) (QueueStatus, bool) {
+	if pp.config.WaitQueue == nil {
+		return QueueStatus{}, false
+	}
+	pp.expireWaitQueue(time.Now())
+	status, ok := pp.waitQueue.statuses(pp.queueDefaultWait())[sessionID]
+	return status, ok
+}
+
+func (pp *PocketPing) queueDefaultWait() time.Duration {
+	if wait := pp.config.WaitQueue.DefaultWait; wait > 0 {
+		return wait
+	}
+	return DefaultQueueWait
+}
+
+// trackWaitQueue moves a session in and out of the line for a new message,
+// before the session is saved: the first operator reply sets
+// Session.FirstResponseAt.
+func (pp *PocketPing) trackWaitQueue(message *Message, session *Session) {
+	if message.Sender == SenderOperator && session.FirstResponseAt == nil {
+		session.FirstResponseAt = &message.Timestamp
+		pp.leaveWaitQueue(session.ID, &message.Timestamp)
+		return
+	}
+	if message.Sender != SenderVisitor || session.FirstResponseAt != nil || pp.config.WaitQueue == nil {
+		return
+	}
+	if pp.waitQueue.join(session.ID, message.Timestamp) {
+		pp.broadcastQueue()
+	}
+}
+
+// leaveWaitQueue takes a session out of the line, answered at answeredAt or
+// given up on (nil), and tells the widgets.
+func (pp *PocketPing) leaveWaitQueue(sessionID string, answeredAt *time.Time) {
+	config := pp.config.WaitQueue
+	if config == nil {
+		return
+	}
+	window := config.ResponseWindow
+	if window <= 0 {
+		window = DefaultQueueResponseWindow
+	}
+	if !pp.waitQueue.leave(sessionID, answeredAt, window) {
+		return
+	}
+	pp.BroadcastToSession(sessionID, WebSocketEvent{Type: "queue_update", Data: QueueStatus{}})
+	pp.broadcastQueue()
+}
+
+// waitQueueDisconnected tracks a session's sockets for
+// WaitQueueConfig.DisconnectGrace: connected is false once its last socket
+// on this node closed.
+func (pp *PocketPing) waitQueueDisconnected(sessionID string, connected bool) {
+	if pp.config.WaitQueue == nil {
+		return
+	}
+	at := time.Time{}
+	if !connected {
+		at = time.Now()
+	}
+	pp.waitQueue.disconnected(sessionID, at)
+}
+
+// expireWaitQueue takes abandoned sessions out of the line (see
+// WaitQueueConfig.DisconnectGrace and IdleTimeout) and tells the widgets.
+func (pp *PocketPing) expireWaitQueue(now time.Time) {
+	config := pp.config.WaitQueue
+	if config == nil {
+		return
+	}
+	grace := config.DisconnectGrace
+	if grace <= 0 {
+		grace = DefaultQueueDisconnectGrace
+	}
+	idle := config.IdleTimeout
+	if idle <= 0 {
+		idle = DefaultQueueIdleTimeout
+	}
+	expired := pp.waitQueue.expire(now, grace, idle)
+	if len(expired) == 0 {
+		return
+	}
+	for _, sessionID := range expired {
+		pp.BroadcastToSession(sessionID, WebSocketEvent{Type: "queue_update", Data: QueueStatus{}})
+	}
+	pp.broadcastQueue()
+}
+
+// startWaitQueue sweeps the line every waitQueueSweepInterval until Stop.
+func (pp *PocketPing) startWaitQueue() {
+	if pp.config.WaitQueue == nil || pp.waitQueueStop != nil {
+		return
+	}
+	if pp.config.Broadcaster != nil {
+		log.Printf("[PocketPing] Warning: the wait queue is kept per node; with a Broadcaster, each replica only counts the sessions it handled")
+	}
+	stop := make(chan struct{})
+	pp.waitQueueStop = stop
+	go func() {
+		ticker := time.NewTicker(waitQueueSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				pp.expireWaitQueue(now)
+			}
+		}
+	}()
+}
+
+// stopWaitQueue ends the sweep started by Start.
+func (pp *PocketPing) stopWaitQueue() {
+	if pp.waitQueueStop != nil {
+		close(pp.waitQueueStop)
+		pp.waitQueueStop = nil
+	}
+}
+
+// broadcastQueue sends every waiting session its place in line.
+func (pp *PocketPing) broadcastQueue() {
+	for sessionID, status := range pp.waitQueue.statuses(pp.queueDefaultWait()) {
+		pp.BroadcastToSession(sessionID, WebSocketEvent{Type: "queue_update", Data: status})
+	}
+}
@@ -0,0 +1,144 @@
+package pocketping
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// lastQueueUpdate returns the last queue_update ws got.
+func lastQueueUpdate(t *testing.T, ws *mockWSConn) QueueStatus {
+	t.Helper()
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for i := len(ws.events) - 1; i >= 0; i-- {
+		if ws.events[i].Type == "queue_update" {
+			return ws.events[i].Data.(QueueStatus)
+		}
+	}
+	t.Fatal("no queue_update")
+	return QueueStatus{}
+}
+
+func TestWaitQueue(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{WaitQueue: &WaitQueueConfig{DefaultWait: time.Minute}})
+	var sessions []string
+	var sockets []*mockWSConn
+	for _, visitor := range []string{"v1", "v2", "v3"} {
+		sessionID := connectVisitor(ctx, t, pp, visitor)
+		ws := &mockWSConn{}
+		pp.RegisterWebSocket(sessionID, ws)
+		sendVisitorMessage(t, pp, sessionID, "Hello?")
+		sendVisitorMessage(t, pp, sessionID, "Anyone?")
+		sessions = append(sessions, sessionID)
+		sockets = append(sockets, ws)
+	}
+
+	if got := lastQueueUpdate(t, sockets[2]); got != (QueueStatus{Position: 3, EstimatedWaitSeconds: 180}) {
+		t.Errorf("third = %+v", got)
+	}
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v2", SessionID: sessions[1]})
+	if err != nil || resp.Queue == nil || resp.Queue.Position != 2 {
+		t.Fatalf("connect queue = %+v, %v", resp.Queue, err)
+	}
+
+	// The first reply moves everyone up, and times the estimate
+	if _, err := pp.SendOperatorMessage(ctx, sessions[0], "Hi!", "", "Ann"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if got := lastQueueUpdate(t, sockets[0]); got.Position != 0 {
+		t.Errorf("answered = %+v", got)
+	}
+	if got := lastQueueUpdate(t, sockets[2]); got.Position != 2 || got.EstimatedWaitSeconds >= 120 {
+		t.Errorf("third after reply = %+v", got)
+	}
+	session, _ := pp.storage.GetSession(ctx, sessions[0])
+	if session.FirstResponseAt == nil {
+		t.Error("FirstResponseAt not set")
+	}
+
+	// Answered sessions don't queue again; ended ones leave
+	sendVisitorMessage(t, pp, sessions[0], "Thanks")
+	if _, err := pp.StartFreshSession(ctx, "v2"); err != nil {
+		t.Fatalf("StartFreshSession: %v", err)
+	}
+	if got, _ := pp.QueueStatus(sessions[2]); got.Position != 1 {
+		t.Errorf("third after handover = %+v", got)
+	}
+	if g := pp.Gauges(); g.QueuedVisitors != 1 {
+		t.Errorf("queued = %d", g.QueuedVisitors)
+	}
+}
+
+func TestWaitQueueDisabled(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{})
+	sessionID := connectVisitor(ctx, t, pp, "v1")
+	sendVisitorMessage(t, pp, sessionID, "Hello?")
+	if _, ok := pp.QueueStatus(sessionID); ok {
+		t.Error("queued without Config.WaitQueue")
+	}
+	resp, _ := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", SessionID: sessionID})
+	if resp.Queue != nil {
+		t.Errorf("queue = %+v", resp.Queue)
+	}
+}
+
+func TestWaitQueueResponseWindow(t *testing.T) {
+	q := &waitQueue{}
+	start := time.Now()
+	for i, wait := range []time.Duration{10 * time.Minute, time.Minute, 3 * time.Minute} {
+		id := string(rune('a' + i))
+		q.join(id, start)
+		answered := start.Add(wait)
+		q.leave(id, &answered, 2)
+	}
+	q.join("next", start)
+	if got := q.statuses(time.Hour)["next"]; got.EstimatedWaitSeconds != 120 {
+		t.Errorf("status = %+v", got)
+	}
+}
+
+func TestWaitQueueExpiry(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{WaitQueue: &WaitQueueConfig{DisconnectGrace: time.Minute, IdleTimeout: 10 * time.Minute}})
+	var sessions []string
+	var sockets []*mockWSConn
+	for _, visitor := range []string{"v1", "v2", "v3"} {
+		sessionID := connectVisitor(ctx, t, pp, visitor)
+		ws := &mockWSConn{}
+		pp.RegisterWebSocket(sessionID, ws)
+		sendVisitorMessage(t, pp, sessionID, "Hello?")
+		sessions = append(sessions, sessionID)
+		sockets = append(sockets, ws)
+	}
+
+	// A page load closes and reopens the socket within the grace period
+	now := time.Now()
+	pp.UnregisterWebSocket(sessions[1], sockets[1])
+	pp.RegisterWebSocket(sessions[1], sockets[1])
+	// The first visitor closed the tab
+	pp.UnregisterWebSocket(sessions[0], sockets[0])
+	pp.expireWaitQueue(now.Add(30 * time.Second))
+	if got, _ := pp.QueueStatus(sessions[2]); got.Position != 3 {
+		t.Fatalf("within grace = %+v", got)
+	}
+	pp.expireWaitQueue(now.Add(2 * time.Minute))
+	if _, ok := pp.QueueStatus(sessions[0]); ok {
+		t.Error("disconnected session still in line")
+	}
+	if got := lastQueueUpdate(t, sockets[2]); got.Position != 2 {
+		t.Errorf("third after disconnect = %+v", got)
+	}
+
+	// Connected but silent visitors leave after IdleTimeout
+	pp.waitQueue.join(sessions[2], now.Add(5*time.Minute)) // a later message
+	pp.expireWaitQueue(now.Add(10*time.Minute + time.Second))
+	if _, ok := pp.QueueStatus(sessions[1]); ok {
+		t.Error("idle session still in line")
+	}
+	if got, _ := pp.QueueStatus(sessions[2]); got.Position != 1 {
+		t.Errorf("active session = %+v", got)
+	}
+}
@@ -0,0 +1,223 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultWarehouseInterval is how often the warehouse sink writes a batch.
+const DefaultWarehouseInterval = 15 * time.Minute
+
+// WarehouseRecordEvent is the record type of custom events in warehouse
+// batches. Sessions and messages use SnapshotRecordSession and
+// SnapshotRecordMessage.
+const WarehouseRecordEvent = "event"
+
+// warehouseCheckpointKey is the object, under Config.WarehousePrefix, that
+// records how far the sink has exported.
+const warehouseCheckpointKey = "_checkpoint.json"
+
+// ErrWarehouseNotConfigured is returned by FlushWarehouse without
+// Config.WarehouseStore.
+var ErrWarehouseNotConfigured = newError("not_configured", http.StatusNotFound, "warehouse sink is not configured")
+
+// ObjectStore is a bucket the warehouse sink writes its batches to. See
+// S3ObjectStore for S3 and GCS.
+type ObjectStore interface {
+	// PutObject stores body at key, replacing any existing object.
+	PutObject(ctx context.Context, key string, body []byte, contentType string) error
+
+	// GetObject returns the object at key, or nil if there is none.
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// WarehouseRecord is one line of a warehouse batch. Exactly one payload
+// field is set, matching Type.
+type WarehouseRecord struct {
+	Type    string       `json:"type"`
+	Session *Session     `json:"session,omitempty"`
+	Message *Message     `json:"message,omitempty"`
+	Event   *CustomEvent `json:"event,omitempty"`
+}
+
+// WarehouseCheckpoint is where the warehouse sink resumes from, stored in
+// the ObjectStore next to the batches.
+type WarehouseCheckpoint struct {
+	// ExportedUntil is the end (exclusive) of the last batch's range.
+	ExportedUntil time.Time `json:"exportedUntil"`
+	// LastBatch is the key of the last batch written, if any.
+	LastBatch string `json:"lastBatch,omitempty"`
+}
+
+// WarehouseBatch describes a batch written by FlushWarehouse.
+type WarehouseBatch struct {
+	// Key is the object key, empty when there was nothing to write.
+	Key      string    `json:"key,omitempty"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Sessions int       `json:"sessions"`
+	Messages int       `json:"messages"`
+	Events   int       `json:"events"`
+}
+
+// warehouseSink batches changes for Config.WarehouseStore.
+type warehouseSink struct {
+	// flushMu serializes flushes; checkpoint is only used under it.
+	flushMu    sync.Mutex
+	checkpoint *WarehouseCheckpoint
+
+	// Custom events since the last batch
+	eventsMu sync.Mutex
+	events   []CustomEvent
+
+	// Closed by Stop to end the flush loop
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (pp *PocketPing) warehouseKey(name string) string {
+	return pp.config.WarehousePrefix + name
+}
+
+// recordWarehouseEvent keeps a custom event for the next batch.
+func (pp *PocketPing) recordWarehouseEvent(event CustomEvent) {
+	if pp.warehouse == nil {
+		return
+	}
+	pp.warehouse.eventsMu.Lock()
+	pp.warehouse.events = append(pp.warehouse.events, event)
+	pp.warehouse.eventsMu.Unlock()
+}
+
+// FlushWarehouse writes a batch to Config.WarehouseStore now: the sessions
+// active and the messages sent since the last checkpoint (see
+// ExportConversations), then the custom events received since the last
+// batch. The checkpoint only moves once the batch is stored, so a failed
+// batch is retried whole by the next flush.
+//
+// Batches are NDJSON objects of WarehouseRecord lines, at
+// {prefix}dt=YYYY-MM-DD/{end time}.ndjson. Sessions still active are
+// exported again in later batches, with their latest state. Custom events
+// are only held in memory until the batch, so a crash loses those of the
+// current interval.
+func (pp *PocketPing) FlushWarehouse(ctx context.Context) (*WarehouseBatch, error) {
+	sink := pp.warehouse
+	if sink == nil {
+		return nil, ErrWarehouseNotConfigured
+	}
+	sink.flushMu.Lock()
+	defer sink.flushMu.Unlock()
+
+	store := pp.config.WarehouseStore
+	if sink.checkpoint == nil {
+		checkpoint := &WarehouseCheckpoint{}
+		data, err := store.GetObject(ctx, pp.warehouseKey(warehouseCheckpointKey))
+		if err != nil {
+			return nil, fmt.Errorf("read warehouse checkpoint: %w", err)
+		}
+		if data != nil {
+			if err := json.Unmarshal(data, checkpoint); err != nil {
+				return nil, fmt.Errorf("parse warehouse checkpoint: %w", err)
+			}
+		}
+		sink.checkpoint = checkpoint
+	}
+
+	batch := &WarehouseBatch{From: sink.checkpoint.ExportedUntil, To: time.Now().UTC()}
+	var buf bytes.Buffer
+	stats, err := pp.ExportConversations(ctx, &buf, ExportOptions{Format: ExportNDJSON, From: batch.From, To: batch.To})
+	if err != nil {
+		return nil, err
+	}
+	batch.Sessions, batch.Messages = stats.Sessions, stats.Messages
+
+	sink.eventsMu.Lock()
+	events := sink.events
+	sink.events = nil
+	sink.eventsMu.Unlock()
+	enc := json.NewEncoder(&buf)
+	for i := range events {
+		if err := enc.Encode(WarehouseRecord{Type: WarehouseRecordEvent, Event: &events[i]}); err != nil {
+			return nil, err
+		}
+	}
+	batch.Events = len(events)
+
+	requeue := func() {
+		sink.eventsMu.Lock()
+		sink.events = append(events, sink.events...)
+		sink.eventsMu.Unlock()
+	}
+
+	checkpoint := WarehouseCheckpoint{ExportedUntil: batch.To, LastBatch: sink.checkpoint.LastBatch}
+	if buf.Len() > 0 {
+		batch.Key = pp.warehouseKey("dt=" + batch.To.Format("2006-01-02") + "/" + batch.To.Format("20060102T150405.000Z") + ".ndjson")
+		if err := store.PutObject(ctx, batch.Key, buf.Bytes(), ExportNDJSON.ContentType()); err != nil {
+			requeue()
+			return nil, fmt.Errorf("write warehouse batch: %w", err)
+		}
+		checkpoint.LastBatch = batch.Key
+	}
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.PutObject(ctx, pp.warehouseKey(warehouseCheckpointKey), data, "application/json"); err != nil {
+		// The batch is stored but the checkpoint didn't move: the next batch
+		// repeats its sessions and messages (delivery is at least once), but
+		// not its events.
+		return nil, fmt.Errorf("write warehouse checkpoint: %w", err)
+	}
+	sink.checkpoint = &checkpoint
+	return batch, nil
+}
+
+// startWarehouse writes a batch each Config.WarehouseInterval until Stop.
+func (pp *PocketPing) startWarehouse() {
+	sink := pp.warehouse
+	if sink == nil || sink.stop != nil {
+		return
+	}
+	interval := pp.config.WarehouseInterval
+	if interval <= 0 {
+		interval = DefaultWarehouseInterval
+	}
+	sink.stop = make(chan struct{})
+	sink.done = make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := pp.FlushWarehouse(context.Background()); err != nil {
+					log.Printf("[PocketPing] Warehouse batch failed: %v", err)
+				}
+			}
+		}
+	}(sink.stop, sink.done)
+}
+
+// stopWarehouse ends the flush loop and writes a last batch.
+func (pp *PocketPing) stopWarehouse(ctx context.Context) {
+	sink := pp.warehouse
+	if sink == nil || sink.stop == nil {
+		return
+	}
+	close(sink.stop)
+	<-sink.done
+	sink.stop, sink.done = nil, nil
+	if _, err := pp.FlushWarehouse(ctx); err != nil {
+		log.Printf("[PocketPing] Warehouse batch failed: %v", err)
+	}
+}
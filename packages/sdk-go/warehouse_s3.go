@@ -0,0 +1,164 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3ObjectStore implements ObjectStore with the S3 REST API, signed with
+// AWS Signature Version 4. It works with any S3-compatible service: for
+// Google Cloud Storage, set Endpoint to "https://storage.googleapis.com",
+// Region to "auto" and use HMAC keys.
+type S3ObjectStore struct {
+	// Bucket is the bucket name (required).
+	Bucket string
+	// Region is the bucket's region (required, e.g. "eu-west-1").
+	Region string
+	// AccessKeyID and SecretAccessKey are the credentials (required).
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials (optional).
+	SessionToken string
+	// Endpoint is the service URL (default "https://s3.{Region}.amazonaws.com").
+	// Objects are addressed path-style: {Endpoint}/{Bucket}/{key}.
+	Endpoint string
+	// HTTPClient is the HTTP client used for requests (default http.DefaultClient).
+	HTTPClient *http.Client
+
+	// now is the signing clock, for tests.
+	now func() time.Time
+}
+
+func (s *S3ObjectStore) endpoint() string {
+	if s.Endpoint != "" {
+		return strings.TrimRight(s.Endpoint, "/")
+	}
+	return "https://s3." + s.Region + ".amazonaws.com"
+}
+
+// PutObject uploads body to key (PUT {Endpoint}/{Bucket}/{key}).
+func (s *S3ObjectStore) PutObject(ctx context.Context, key string, body []byte, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("s3: put %s: unexpected status %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+// GetObject downloads key, or returns nil when it doesn't exist.
+func (s *S3ObjectStore) GetObject(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("s3: get %s: unexpected status %d", key, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3ObjectStore) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	path := "/" + s3Escape(s.Bucket) + "/" + s3Escape(key)
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint()+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, path, body)
+	return httpClientOr(s.HTTPClient).Do(req)
+}
+
+// sign adds the Signature Version 4 headers to req, whose escaped path is
+// path and body is body.
+func (s *S3ObjectStore) sign(req *http.Request, path string, body []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, path, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	for _, part := range []string{s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// s3Escape escapes an object key as Signature Version 4 requires: every
+// byte but unreserved characters and "/".
+func s3Escape(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Ensure S3ObjectStore implements ObjectStore interface
+var _ ObjectStore = (*S3ObjectStore)(nil)
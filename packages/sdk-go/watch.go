@@ -0,0 +1,169 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWatchesNotSupported is returned by WatchSession, UnwatchSession
+	// and OperatorWatches when the storage doesn't implement
+	// StorageWithWatches.
+	ErrWatchesNotSupported = errors.New("storage does not support watches")
+	// ErrInvalidWatch is returned by WatchSession when the watch has no
+	// bridge or user ID.
+	ErrInvalidWatch = newError("invalid_watch", http.StatusBadRequest, "watch needs a bridge and a user ID")
+)
+
+// Watch subscribes an operator to a session: they get a direct message on
+// their bridge for every activity in it, even if the session is routed to
+// another channel or its channel's notifications are muted.
+type Watch struct {
+	SessionID string `json:"sessionId"`
+	// Bridge is the platform the operator is messaged on ("telegram",
+	// "slack", "discord").
+	Bridge string `json:"bridge"`
+	// UserID is the operator's platform user ID, as in OperatorMention.
+	UserID string `json:"userId"`
+	// OperatorName is the operator's display name. Their own messages in
+	// the session aren't sent back to them.
+	OperatorName string    `json:"operatorName,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// BridgeWithDirectMessages is implemented by bridges that can message an
+// operator privately.
+type BridgeWithDirectMessages interface {
+	Bridge
+	// SendDirectMessage sends text to a platform user in a private chat.
+	SendDirectMessage(ctx context.Context, userID, text string) error
+}
+
+// WatchCallback is called when an operator runs /watch (watch is true) or
+// /unwatch in a session's topic or thread, or clicks Watch or Unwatch on
+// the Slack Home tab. Typically calls PocketPing.WatchSession or
+// PocketPing.UnwatchSession.
+type WatchCallback func(ctx context.Context, sessionID, sourceBridge, userID, operatorName string, watch bool)
+
+// WatchSession subscribes an operator to a session's activity and confirms
+// it to them by direct message. Watching again replaces the watch.
+func (pp *PocketPing) WatchSession(ctx context.Context, watch Watch) (*Watch, error) {
+	store, ok := pp.storage.(StorageWithWatches)
+	if !ok {
+		return nil, ErrWatchesNotSupported
+	}
+	watch.Bridge = bridgePlatform(watch.Bridge)
+	if watch.Bridge == "" || watch.UserID == "" {
+		return nil, ErrInvalidWatch
+	}
+	session, err := pp.storage.GetSession(ctx, watch.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	watch.CreatedAt = time.Now()
+	if err := store.SaveWatch(ctx, &watch); err != nil {
+		return nil, err
+	}
+	pp.sendWatchMessage(ctx, session, watch, fmt.Sprintf("👀 You are watching %s: every activity in the conversation will be sent here.", watchSessionName(session)))
+	return &watch, nil
+}
+
+// UnwatchSession unsubscribes an operator from a session.
+func (pp *PocketPing) UnwatchSession(ctx context.Context, sessionID, bridge, userID string) error {
+	store, ok := pp.storage.(StorageWithWatches)
+	if !ok {
+		return ErrWatchesNotSupported
+	}
+	return store.DeleteWatch(ctx, sessionID, bridgePlatform(bridge), userID)
+}
+
+// OperatorWatches returns the sessions an operator watches, oldest first.
+func (pp *PocketPing) OperatorWatches(ctx context.Context, bridge, userID string) ([]Watch, error) {
+	store, ok := pp.storage.(StorageWithWatches)
+	if !ok {
+		return nil, ErrWatchesNotSupported
+	}
+	return store.GetOperatorWatches(ctx, bridgePlatform(bridge), userID)
+}
+
+// notifyWatchers sends a line of session activity to the operators
+// watching it, except the operator it comes from (sourceBridge and
+// operatorName, both empty for visitor and system activity).
+func (pp *PocketPing) notifyWatchers(ctx context.Context, session *Session, line, sourceBridge, operatorName string) {
+	store, ok := pp.storage.(StorageWithWatches)
+	if !ok {
+		return
+	}
+	watches, err := store.GetSessionWatches(ctx, session.ID)
+	if err != nil {
+		log.Printf("[PocketPing] Loading watches of session %s failed: %v", session.ID, err)
+		return
+	}
+	source := bridgePlatform(sourceBridge)
+	text := fmt.Sprintf("👀 %s\n%s", watchSessionName(session), line)
+	for _, watch := range watches {
+		if operatorName != "" && watch.Bridge == source && watch.OperatorName == operatorName {
+			continue
+		}
+		pp.sendWatchMessage(ctx, session, watch, text)
+	}
+}
+
+// sendWatchMessage queues a direct message to a watching operator on the
+// first bridge of their platform that can send one. The session's bridge
+// routing doesn't apply.
+func (pp *PocketPing) sendWatchMessage(ctx context.Context, session *Session, watch Watch, text string) {
+	for i, bridge := range pp.bridges {
+		messenger, ok := bridge.(BridgeWithDirectMessages)
+		if !ok || bridgePlatform(bridge.Name()) != watch.Bridge {
+			continue
+		}
+		b := bridge
+		pp.dispatcher.dispatch(dispatchKey{sessionID: session.ID, bridge: i}, func() {
+			err := pp.deliver(ctx, b, "SendDirectMessage", session.ID, "", func(ctx context.Context) error {
+				return messenger.SendDirectMessage(ctx, watch.UserID, text)
+			})
+			if err != nil {
+				log.Printf("[PocketPing] Direct message to %s on %s failed: %v", watch.UserID, b.Name(), err)
+			}
+		})
+		return
+	}
+}
+
+// watchSessionName names a session's visitor in watch messages.
+func watchSessionName(session *Session) string {
+	if session.Identity != nil && session.Identity.Name != "" {
+		return session.Identity.Name
+	}
+	if session.Identity != nil && session.Identity.Email != "" {
+		return session.Identity.Email
+	}
+	return session.VisitorID
+}
+
+// parseWatchCommand recognises /watch and /unwatch (also as /watch@bot)
+// and reports which one it is.
+func parseWatchCommand(text string) (watch bool, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false, false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	switch command {
+	case "/watch":
+		return true, true
+	case "/unwatch":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,214 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// dmRecordingBridge records the direct messages it sends.
+type dmRecordingBridge struct {
+	BaseBridge
+	mu  sync.Mutex
+	dms []string
+}
+
+func (b *dmRecordingBridge) SendDirectMessage(ctx context.Context, userID, text string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dms = append(b.dms, userID+" "+text)
+	return nil
+}
+
+func (b *dmRecordingBridge) sent() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.dms...)
+}
+
+func TestWatchSession(t *testing.T) {
+	ctx := context.Background()
+	// Routed to another region: watches still reach the operator
+	telegram := &dmRecordingBridge{BaseBridge: BaseBridge{BridgeName: "telegram", BridgeRegions: []string{"eu"}}}
+	pp := New(Config{Bridges: []Bridge{telegram, &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}}})
+	session := newSession(ctx, t, pp)
+
+	if _, err := pp.WatchSession(ctx, Watch{SessionID: session.ID, Bridge: "telegram"}); !errors.Is(err, ErrInvalidWatch) {
+		t.Errorf("watch without user = %v, want ErrInvalidWatch", err)
+	}
+	if _, err := pp.WatchSession(ctx, Watch{SessionID: "missing", Bridge: "telegram", UserID: "42"}); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("watch of missing session = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := pp.WatchSession(ctx, Watch{SessionID: session.ID, Bridge: "telegram-eu", UserID: "42", OperatorName: "Bob"}); err != nil {
+		t.Fatalf("WatchSession: %v", err)
+	}
+
+	sendVisitorMessage(t, pp, session.ID, "where is my order?")
+	if _, err := pp.SendOperatorMessage(ctx, session.ID, "checking", "telegram", "Bob"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	if _, err := pp.SendOperatorMessage(ctx, session.ID, "it shipped", "slack", "Alice"); err != nil {
+		t.Fatalf("SendOperatorMessage: %v", err)
+	}
+	pp.dispatcher.wait()
+
+	name := watchSessionName(session)
+	want := []string{
+		"42 👀 You are watching " + name + ": every activity in the conversation will be sent here.",
+		"42 👀 " + name + "\nVisitor: where is my order?",
+		"42 👀 " + name + "\nAlice: it shipped",
+	}
+	got := telegram.sent()
+	if len(got) != len(want) {
+		t.Fatalf("DMs = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("DM %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	watches, err := pp.OperatorWatches(ctx, "telegram", "42")
+	if err != nil || len(watches) != 1 || watches[0].SessionID != session.ID || watches[0].Bridge != "telegram" {
+		t.Errorf("OperatorWatches = %+v, %v", watches, err)
+	}
+
+	if err := pp.UnwatchSession(ctx, session.ID, "telegram", "42"); err != nil {
+		t.Fatalf("UnwatchSession: %v", err)
+	}
+	sendVisitorMessage(t, pp, session.ID, "hello?")
+	pp.dispatcher.wait()
+	if got := telegram.sent(); len(got) != len(want) {
+		t.Errorf("DMs after unwatch = %q", got[len(want):])
+	}
+}
+
+func TestWatchSessionUnsupportedStorage(t *testing.T) {
+	pp := New(Config{Storage: struct{ Storage }{NewMemoryStorage()}})
+	if _, err := pp.WatchSession(context.Background(), Watch{SessionID: "s1", Bridge: "slack", UserID: "U1"}); !errors.Is(err, ErrWatchesNotSupported) {
+		t.Errorf("WatchSession = %v, want ErrWatchesNotSupported", err)
+	}
+}
+
+func TestWatchesPersist(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.log")
+	storage, err := NewPersistentMemoryStorage(path)
+	if err != nil {
+		t.Fatalf("NewPersistentMemoryStorage: %v", err)
+	}
+	for _, sessionID := range []string{"s1", "s2"} {
+		if err := storage.SaveWatch(ctx, &Watch{SessionID: sessionID, Bridge: "slack", UserID: "U1"}); err != nil {
+			t.Fatalf("SaveWatch: %v", err)
+		}
+	}
+	if err := storage.DeleteWatch(ctx, "s1", "slack", "U1"); err != nil {
+		t.Fatalf("DeleteWatch: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := NewPersistentMemoryStorage(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if watches, _ := reopened.GetOperatorWatches(ctx, "slack", "U1"); len(watches) != 1 || watches[0].SessionID != "s2" {
+		t.Errorf("watches after reopen = %+v", watches)
+	}
+	if watches, _ := reopened.GetSessionWatches(ctx, "s2"); len(watches) != 1 {
+		t.Errorf("session watches after reopen = %+v", watches)
+	}
+}
+
+func TestParseWatchCommand(t *testing.T) {
+	for text, want := range map[string]bool{
+		"/watch":                  true,
+		"/watch@pocketping_bot":   true,
+		"/unwatch":                false,
+		"/unwatch@pocketping_bot": false,
+	} {
+		if got, ok := parseWatchCommand(text); !ok || got != want {
+			t.Errorf("parseWatchCommand(%q) = %v, %v; want %v", text, got, ok, want)
+		}
+	}
+	for _, text := range []string{"watch", "/watcher", "/ticket", ""} {
+		if _, ok := parseWatchCommand(text); ok {
+			t.Errorf("parseWatchCommand(%q) matched", text)
+		}
+	}
+}
+
+func TestWebhookHandler_Watch(t *testing.T) {
+	type call struct {
+		sessionID, source, userID, operatorName string
+		watch                                   bool
+	}
+	var calls []call
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		SlackBotToken:    "xoxb-test",
+		OnWatch: func(ctx context.Context, sessionID, sourceBridge, userID, operatorName string, watch bool) {
+			calls = append(calls, call{sessionID, sourceBridge, userID, operatorName, watch})
+		},
+	})
+	users := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"user":{"real_name":"Bob Smith","name":"bob"}}`))
+	}))
+	defer users.Close()
+	handler.httpClient = &http.Client{Transport: &webhookTestTransport{slackURL: users.URL}}
+
+	payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"/watch"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	payload = []byte(`{"type":2,"channel_id":"T9","member":{"user":{"id":"D1","username":"bob"}},"data":{"name":"unwatch"}}`)
+	handler.HandleDiscordWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/discord", bytes.NewReader(payload)))
+
+	interaction := `{"type":"block_actions","user":{"id":"U42"},"actions":[{"action_id":"pocketping_watch","value":"sess-1"}]}`
+	req := httptest.NewRequest("POST", "/webhooks/slack", strings.NewReader(url.Values{"payload": {interaction}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.HandleSlackWebhook()(httptest.NewRecorder(), req)
+
+	want := []call{
+		{"456", "telegram", "7", "Bob", true},
+		{"T9", "discord", "D1", "bob", false},
+		{"sess-1", "slack", "U42", "Bob Smith", true},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestTelegramBridge_SendDirectMessage(t *testing.T) {
+	var chatID, text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chatID, text = r.FormValue("chat_id"), r.FormValue("text")
+		w.Write([]byte(`{"ok":true,"result":{"message_id":3}}`))
+	}))
+	defer server.Close()
+
+	bridge, err := NewTelegramBridge("test-token", "test-chat")
+	if err != nil {
+		t.Fatalf("NewTelegramBridge: %v", err)
+	}
+	bridge.httpClient = &http.Client{Transport: &testTransport{baseURL: server.URL, token: "test-token"}}
+	if err := bridge.SendDirectMessage(context.Background(), "42", "👀 hi"); err != nil {
+		t.Fatalf("SendDirectMessage: %v", err)
+	}
+	if chatID != "42" || text != "👀 hi" {
+		t.Errorf("sent %q to %q", text, chatID)
+	}
+}
@@ -0,0 +1,78 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// DefaultWebhookMaxBodyBytes caps webhook request bodies (1 MiB).
+	DefaultWebhookMaxBodyBytes int64 = 1 << 20
+	// maxWebhookJSONDepth caps object/array nesting in webhook payloads.
+	// Real Telegram, Slack and Discord updates stay well below it.
+	maxWebhookJSONDepth = 64
+)
+
+// errWebhookJSONTooDeep is returned for payloads nested beyond
+// maxWebhookJSONDepth.
+var errWebhookJSONTooDeep = errors.New("webhook JSON nested too deeply")
+
+// readWebhookBody reads the request body up to MaxBodyBytes. On failure it
+// writes the error response and returns false.
+func (wh *WebhookHandler) readWebhookBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, wh.config.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, ErrPayloadTooLarge)
+			return nil, false
+		}
+		WriteError(w, ErrBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// decodeWebhookJSON unmarshals an untrusted webhook payload after checking
+// its nesting depth.
+func decodeWebhookJSON(data []byte, v interface{}) error {
+	if err := checkJSONDepth(data, maxWebhookJSONDepth); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkJSONDepth scans data and fails if objects and arrays nest deeper than
+// max. Malformed JSON is left for json.Unmarshal to report.
+func checkJSONDepth(data []byte, max int) error {
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > max {
+				return fmt.Errorf("%w (limit %d)", errWebhookJSONTooDeep, max)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
@@ -1,12 +1,15 @@
 package pocketping
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -28,10 +31,61 @@ type OperatorMessageEditCallback func(ctx context.Context, sessionID, bridgeMess
 // OperatorMessageDeleteCallback is called when an operator deletes a message on a bridge
 type OperatorMessageDeleteCallback func(ctx context.Context, sessionID, bridgeMessageID, sourceBridge string, deletedAt time.Time)
 
+// TelegramInlineSearchCallback resolves an inline "@bot search <term>" query to
+// matching sessions. It is only called for members of
+// WebhookConfig.TelegramChatID; from is the one who typed the query, for
+// finer restrictions. Typically backed by PocketPing.SearchMessages.
+type TelegramInlineSearchCallback func(ctx context.Context, term string, from *TelegramUser) ([]SearchResult, error)
+
+// SlackAppHomeOpenedCallback is called when a Slack user opens the bot's Home
+// tab. Typically calls SlackBotBridge.PublishAppHome.
+type SlackAppHomeOpenedCallback func(ctx context.Context, userID string)
+
+// SlackAppHomeActionCallback is called when a Slack user clicks a quick action
+// (SlackAppHomeActionClaim, SlackAppHomeActionClose) on the Home tab.
+type SlackAppHomeActionCallback func(ctx context.Context, actionID, sessionID, userID string)
+
+// OperatorTakeoverCallback is called when an operator takes a session over
+// from the AI (takeover=true) or hands it back (takeover=false), via a
+// /takeover or /handback command or a takeover button. Typically calls
+// PocketPing.TakeOver / PocketPing.HandBack.
+type OperatorTakeoverCallback func(ctx context.Context, sessionID, operatorName, sourceBridge string, takeover bool)
+
+// BridgeContainer identifies where an operator action happened on a bridge.
+type BridgeContainer struct {
+	// Bridge is the source bridge: "telegram", "slack" or "discord".
+	Bridge string
+	// ChatID is the Telegram chat ID or the Slack channel ID (empty for Discord).
+	ChatID string
+	// ThreadID is the Telegram forum topic ID, the Slack thread_ts or the
+	// Discord thread/channel ID. Empty for messages outside a thread.
+	ThreadID string
+	// ReplyToMessageID is the bridge message ID the operator replied to,
+	// if any. Useful for mapping plain (non-forum) chats.
+	ReplyToMessageID string
+}
+
+// SessionResolver maps a bridge container to a PocketPing session ID.
+// Return ok=false to ignore the message.
+type SessionResolver func(ctx context.Context, container BridgeContainer) (sessionID string, ok bool)
+
+// DefaultSessionResolver maps the thread ID 1:1 to the session ID (forum
+// topic, thread_ts, Discord thread) and ignores messages outside a thread.
+func DefaultSessionResolver(ctx context.Context, container BridgeContainer) (string, bool) {
+	if container.ThreadID == "" {
+		return "", false
+	}
+	return container.ThreadID, true
+}
+
 // WebhookConfig holds configuration for bridge webhooks
 type WebhookConfig struct {
 	// Telegram configuration
 	TelegramBotToken string
+	// TelegramChatID is the forum chat ID ("-100…", or "@username" for a
+	// public chat). Inline search is only answered for its members, and
+	// results link to their forum topics.
+	TelegramChatID string
 
 	// Slack configuration
 	SlackBotToken string
@@ -42,6 +96,10 @@ type WebhookConfig struct {
 	// Optional allowlist of bot IDs (for test bots)
 	AllowedBotIDs []string
 
+	// SessionResolver maps bridge containers to sessions.
+	// Defaults to DefaultSessionResolver.
+	SessionResolver SessionResolver
+
 	// Callback for operator messages
 	OnOperatorMessage OperatorMessageCallback
 	// Callback for operator messages with bridge message IDs
@@ -50,6 +108,39 @@ type WebhookConfig struct {
 	OnOperatorMessageEdit OperatorMessageEditCallback
 	// Callback for operator message deletes
 	OnOperatorMessageDelete OperatorMessageDeleteCallback
+	// Callback for Telegram inline session search (@bot search <term>)
+	OnTelegramInlineSearch TelegramInlineSearchCallback
+	// Callback for Slack app_home_opened events
+	OnSlackAppHomeOpened SlackAppHomeOpenedCallback
+	// Callback for Slack App Home quick actions (claim, close)
+	OnSlackAppHomeAction SlackAppHomeActionCallback
+	// Callback for /takeover and /handback (commands and buttons)
+	OnOperatorTakeover OperatorTakeoverCallback
+	// Callback for /ticket [title] (Telegram command, Discord slash command)
+	OnTicketCommand TicketCommandCallback
+	// Callback for /form <name> (Telegram command, Discord slash command)
+	OnFormCommand FormCommandCallback
+	// Callback for operator whispers: "// text" (Telegram, Slack) or
+	// /whisper text (Telegram command, Discord slash command)
+	OnWhisper WhisperCallback
+	// Callback for /charge <amount> <currency> [description] (Telegram
+	// command, Discord slash command)
+	OnChargeCommand PaymentCommandCallback
+	// Callback for /context (Telegram command, Discord slash command)
+	OnContextCommand ContextCommandCallback
+	// Callback for /watch and /unwatch (Telegram command, Discord slash
+	// command, Slack App Home buttons)
+	OnWatch WatchCallback
+
+	// MaxBodyBytes caps webhook request bodies.
+	// Defaults to DefaultWebhookMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// ClockSkew normalizes the platform times passed to
+	// OnOperatorMessageEdit and OnOperatorMessageDelete, whose context holds
+	// the original (see PlatformTime). Defaults to a detector with
+	// DefaultClockSkewThreshold.
+	ClockSkew *ClockSkewDetector
 }
 
 // WebhookHandler handles incoming webhooks from bridges (Telegram, Slack, Discord)
@@ -60,6 +151,15 @@ type WebhookHandler struct {
 
 // NewWebhookHandler creates a new webhook handler
 func NewWebhookHandler(config WebhookConfig) *WebhookHandler {
+	if config.SessionResolver == nil {
+		config.SessionResolver = DefaultSessionResolver
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultWebhookMaxBodyBytes
+	}
+	if config.ClockSkew == nil {
+		config.ClockSkew = NewClockSkewDetector(0)
+	}
 	return &WebhookHandler{
 		config:     config,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
@@ -76,6 +176,23 @@ type TelegramUpdate struct {
 	Message  *TelegramMessage `json:"message,omitempty"`
 	EditedMessage *TelegramMessage `json:"edited_message,omitempty"`
 	MessageReaction *TelegramMessageReaction `json:"message_reaction,omitempty"`
+	InlineQuery     *TelegramInlineQuery     `json:"inline_query,omitempty"`
+	CallbackQuery   *TelegramCallbackQuery   `json:"callback_query,omitempty"`
+}
+
+// TelegramCallbackQuery represents an inline keyboard button press
+type TelegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    *TelegramUser    `json:"from,omitempty"`
+	Message *TelegramMessage `json:"message,omitempty"`
+	Data    string           `json:"data,omitempty"`
+}
+
+// TelegramInlineQuery represents an inline query (@bot <query>)
+type TelegramInlineQuery struct {
+	ID    string        `json:"id"`
+	From  *TelegramUser `json:"from,omitempty"`
+	Query string        `json:"query"`
 }
 
 // TelegramMessage represents a Telegram message
@@ -91,6 +208,7 @@ type TelegramMessage struct {
 	Audio           *TelegramAudio         `json:"audio,omitempty"`
 	Video           *TelegramVideo         `json:"video,omitempty"`
 	Voice           *TelegramVoice         `json:"voice,omitempty"`
+	Location        *TelegramLocation      `json:"location,omitempty"`
 	ReplyToMessage  *TelegramReplyMessage  `json:"reply_to_message,omitempty"`
 	Date            int64                  `json:"date"`
 	EditDate        int64                  `json:"edit_date,omitempty"`
@@ -170,23 +288,44 @@ type TelegramVoice struct {
 	FileSize int    `json:"file_size,omitempty"`
 }
 
+// TelegramLocation represents a location shared in a Telegram message
+type TelegramLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
 // HandleTelegramWebhook returns an http.HandlerFunc for Telegram webhooks
 func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		defer recoverHTTP(w, "telegram webhook")
+
 		if wh.config.TelegramBotToken == "" {
-			http.Error(w, `{"error":"Telegram not configured"}`, http.StatusNotFound)
+			WriteError(w, errTelegramNotConfigured)
 			return
 		}
 
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, `{"error":"Bad request"}`, http.StatusBadRequest)
+		body, ok := wh.readWebhookBody(w, r)
+		if !ok {
 			return
 		}
 
 		var update TelegramUpdate
-		if err := json.Unmarshal(body, &update); err != nil {
-			http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		if err := decodeWebhookJSON(body, &update); err != nil {
+			WriteError(w, ErrInvalidJSON)
+			return
+		}
+
+		// Process inline session search
+		if update.InlineQuery != nil {
+			wh.handleTelegramInlineQuery(r.Context(), update.InlineQuery)
+			writeOK(w)
+			return
+		}
+
+		// Process takeover buttons
+		if update.CallbackQuery != nil {
+			wh.handleTelegramCallbackQuery(r.Context(), update.CallbackQuery)
+			writeOK(w)
 			return
 		}
 
@@ -209,18 +348,15 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 				return
 			}
 
-			topicID := msg.MessageThreadID
-			if topicID == 0 {
+			sessionID, ok := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+			if !ok {
 				writeOK(w)
 				return
 			}
 
 			if wh.config.OnOperatorMessageEdit != nil {
-				editedAt := time.Now()
-				if msg.EditDate > 0 {
-					editedAt = time.Unix(msg.EditDate, 0)
-				}
-				wh.config.OnOperatorMessageEdit(r.Context(), fmt.Sprintf("%d", topicID), fmt.Sprintf("%d", msg.MessageID), text, "telegram", editedAt)
+				ctx, editedAt := normalizePlatformTime(r.Context(), wh.config.ClockSkew, "telegram", telegramTime(msg.EditDate))
+				wh.config.OnOperatorMessageEdit(ctx, sessionID, fmt.Sprintf("%d", msg.MessageID), text, "telegram", editedAt)
 			}
 
 			writeOK(w)
@@ -239,12 +375,11 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 				}
 			}
 
-			if hasTrash && reaction.MessageThreadID != 0 && wh.config.OnOperatorMessageDelete != nil {
-				deletedAt := time.Now()
-				if reaction.Date > 0 {
-					deletedAt = time.Unix(reaction.Date, 0)
+			if hasTrash && wh.config.OnOperatorMessageDelete != nil {
+				if sessionID, ok := wh.config.SessionResolver(r.Context(), telegramContainer(reaction.Chat.ID, reaction.MessageThreadID, nil)); ok {
+					ctx, deletedAt := normalizePlatformTime(r.Context(), wh.config.ClockSkew, "telegram", telegramTime(reaction.Date))
+					wh.config.OnOperatorMessageDelete(ctx, sessionID, fmt.Sprintf("%d", reaction.MessageID), "telegram", deletedAt)
 				}
-				wh.config.OnOperatorMessageDelete(r.Context(), fmt.Sprintf("%d", reaction.MessageThreadID), fmt.Sprintf("%d", reaction.MessageID), "telegram", deletedAt)
 			}
 
 			writeOK(w)
@@ -257,13 +392,94 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 
 			// Handle /delete command (reply-based)
 			if strings.HasPrefix(msg.Text, "/delete") {
-				if msg.MessageThreadID == 0 || msg.ReplyToMessage == nil {
+				if msg.ReplyToMessage == nil {
 					writeOK(w)
 					return
 				}
 
-				if wh.config.OnOperatorMessageDelete != nil {
-					wh.config.OnOperatorMessageDelete(r.Context(), fmt.Sprintf("%d", msg.MessageThreadID), fmt.Sprintf("%d", msg.ReplyToMessage.MessageID), "telegram", time.Now())
+				sessionID, ok := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if ok && wh.config.OnOperatorMessageDelete != nil {
+					ctx, deletedAt := normalizePlatformTime(r.Context(), wh.config.ClockSkew, "telegram", telegramTime(msg.Date))
+					wh.config.OnOperatorMessageDelete(ctx, sessionID, fmt.Sprintf("%d", msg.ReplyToMessage.MessageID), "telegram", deletedAt)
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle /takeover and /handback (topic-based)
+			if takeover, ok := parseTakeoverCommand(msg.Text); ok {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if resolved && wh.config.OnOperatorTakeover != nil {
+					wh.config.OnOperatorTakeover(r.Context(), sessionID, telegramOperatorName(msg.From), "telegram", takeover)
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle /charge <amount> <currency> [description] (topic-based)
+			if amount, currency, description, ok, err := parseChargeCommand(msg.Text); ok {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if err != nil {
+					log.Printf("[TelegramWebhook] Ignoring /charge: %v", err)
+				} else if resolved && wh.config.OnChargeCommand != nil {
+					wh.config.OnChargeCommand(r.Context(), sessionID, telegramOperatorName(msg.From), "telegram", amount, currency, description)
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle /ticket [title] (topic-based)
+			if title, ok := parseTicketCommand(msg.Text); ok {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if resolved && wh.config.OnTicketCommand != nil {
+					wh.runTicketCommand(r.Context(), sessionID, telegramOperatorName(msg.From), "telegram", title, nil)
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle /form <name> (topic-based)
+			if name, ok := parseFormCommand(msg.Text); ok {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if resolved && wh.config.OnFormCommand != nil {
+					wh.config.OnFormCommand(r.Context(), sessionID, telegramOperatorName(msg.From), "telegram", name)
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle /context (topic-based)
+			if isContextCommand(msg.Text) {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if resolved && wh.config.OnContextCommand != nil {
+					wh.config.OnContextCommand(r.Context(), sessionID, telegramOperatorName(msg.From), "telegram")
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle /watch and /unwatch (topic-based)
+			if watch, ok := parseWatchCommand(msg.Text); ok {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if resolved && msg.From != nil && wh.config.OnWatch != nil {
+					wh.config.OnWatch(r.Context(), sessionID, "telegram", strconv.FormatInt(msg.From.ID, 10), telegramOperatorName(msg.From), watch)
+				}
+
+				writeOK(w)
+				return
+			}
+
+			// Handle "// text" and /whisper text (topic-based)
+			if content, ok := parseWhisper(msg.Text); ok {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+				if resolved && content != "" && wh.config.OnWhisper != nil {
+					wh.config.OnWhisper(r.Context(), sessionID, telegramOperatorName(msg.From), "telegram", content)
 				}
 
 				writeOK(w)
@@ -282,6 +498,12 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 				text = msg.Caption
 			}
 
+			// Locations reach the visitor as a map link
+			if msg.Location != nil {
+				location := Location{Latitude: msg.Location.Latitude, Longitude: msg.Location.Longitude}
+				text = strings.TrimPrefix(text+locationText(&location), "\n")
+			}
+
 			// Parse media
 			var media *parsedMedia
 			if len(msg.Photo) > 0 {
@@ -336,18 +558,15 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 				return
 			}
 
-			// Get topic ID (for forum topics)
-			topicID := msg.MessageThreadID
-			if topicID == 0 {
+			// Resolve the session (forum topic ID by default)
+			sessionID, ok := wh.config.SessionResolver(r.Context(), telegramContainer(msg.Chat.ID, msg.MessageThreadID, msg.ReplyToMessage))
+			if !ok {
 				writeOK(w)
 				return
 			}
 
 			// Get operator name
-			operatorName := "Operator"
-			if msg.From != nil && msg.From.FirstName != "" {
-				operatorName = msg.From.FirstName
-			}
+			operatorName := telegramOperatorName(msg.From)
 
 			// Get reply_to_message ID if present (for visual reply linking)
 			var replyToBridgeMessageID *int
@@ -373,11 +592,9 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 
 			// Call callback
 			if wh.config.OnOperatorMessage != nil {
-				sessionID := fmt.Sprintf("%d", topicID)
 				wh.config.OnOperatorMessage(r.Context(), sessionID, text, operatorName, "telegram", attachments, replyToBridgeMessageID)
 			}
 			if wh.config.OnOperatorMessageWithIDs != nil {
-				sessionID := fmt.Sprintf("%d", topicID)
 				wh.config.OnOperatorMessageWithIDs(r.Context(), sessionID, text, operatorName, "telegram", attachments, replyToBridgeMessageID, fmt.Sprintf("%d", msg.MessageID))
 			}
 		}
@@ -386,6 +603,276 @@ func (wh *WebhookHandler) HandleTelegramWebhook() http.HandlerFunc {
 	}
 }
 
+// telegramOperatorName returns the operator's display name for a Telegram user.
+func telegramOperatorName(from *TelegramUser) string {
+	if from != nil && from.FirstName != "" {
+		return from.FirstName
+	}
+	return "Operator"
+}
+
+// parseTakeoverCommand recognises /takeover and /handback (also as
+// /takeover@bot). takeover is false for /handback.
+func parseTakeoverCommand(text string) (takeover bool, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false, false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	switch command {
+	case "/takeover":
+		return true, true
+	case "/handback":
+		return false, true
+	}
+	return false, false
+}
+
+// telegramContainer builds the BridgeContainer for a Telegram message.
+func telegramContainer(chatID int64, topicID int, replyTo *TelegramReplyMessage) BridgeContainer {
+	container := BridgeContainer{
+		Bridge: "telegram",
+		ChatID: fmt.Sprintf("%d", chatID),
+	}
+	if topicID != 0 {
+		container.ThreadID = fmt.Sprintf("%d", topicID)
+	}
+	if replyTo != nil {
+		container.ReplyToMessageID = fmt.Sprintf("%d", replyTo.MessageID)
+	}
+	return container
+}
+
+// telegramInlineSearchLimit caps inline results (Telegram allows at most 50).
+const telegramInlineSearchLimit = 20
+
+// telegramInlinePreviewLength caps the message excerpt of an inline result,
+// in characters.
+const telegramInlinePreviewLength = 100
+
+// handleTelegramInlineQuery answers "@bot search <term>" inline queries with
+// one article per matching session. Inline queries can come from any
+// Telegram user, so only members of the TelegramChatID forum (the operators)
+// are answered. Each article links to the session's forum topic.
+func (wh *WebhookHandler) handleTelegramInlineQuery(ctx context.Context, query *TelegramInlineQuery) {
+	if wh.config.OnTelegramInlineSearch == nil {
+		return
+	}
+
+	term, ok := parseInlineSearchTerm(query.Query)
+	if !ok {
+		return
+	}
+	if !wh.isTelegramOperator(ctx, query.From) {
+		return
+	}
+
+	results, err := wh.config.OnTelegramInlineSearch(ctx, term, query.From)
+	if err != nil {
+		log.Printf("[TelegramWebhook] Inline search failed: %v", err)
+		return
+	}
+	if len(results) > telegramInlineSearchLimit {
+		results = results[:telegramInlineSearchLimit]
+	}
+
+	articles := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		if result.Session == nil {
+			continue
+		}
+		articles = append(articles, wh.buildInlineSearchArticle(result))
+	}
+
+	if err := wh.answerTelegramInlineQuery(ctx, query.ID, articles); err != nil {
+		log.Printf("[TelegramWebhook] answerInlineQuery failed: %v", err)
+	}
+}
+
+// isTelegramOperator reports whether user is a member of the TelegramChatID
+// forum. Without a chat ID nobody is.
+func (wh *WebhookHandler) isTelegramOperator(ctx context.Context, user *TelegramUser) bool {
+	if wh.config.TelegramChatID == "" || user == nil {
+		return false
+	}
+	var member struct {
+		Status   string `json:"status"`
+		IsMember bool   `json:"is_member"`
+	}
+	err := wh.callTelegramAPIResult(ctx, "getChatMember", map[string]interface{}{
+		"chat_id": wh.config.TelegramChatID,
+		"user_id": user.ID,
+	}, &member)
+	if err != nil {
+		log.Printf("[TelegramWebhook] getChatMember failed: %v", err)
+		return false
+	}
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		return member.IsMember
+	}
+	return false
+}
+
+// parseInlineSearchTerm extracts <term> from "search <term>". Matching on the
+// keyword is case-insensitive; an empty term is rejected.
+func parseInlineSearchTerm(query string) (string, bool) {
+	trimmed := strings.TrimSpace(query)
+	fields := strings.Fields(trimmed)
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "search") {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[len(fields[0]):]), true
+}
+
+// buildInlineSearchArticle formats a search result as an InlineQueryResultArticle.
+func (wh *WebhookHandler) buildInlineSearchArticle(result SearchResult) map[string]interface{} {
+	session := result.Session
+
+	title := session.VisitorID
+	if session.Identity != nil && session.Identity.Name != "" {
+		title = session.Identity.Name
+	} else if session.Identity != nil && session.Identity.Email != "" {
+		title = session.Identity.Email
+	}
+
+	description := ""
+	if result.Message != nil {
+		description = result.Message.Content
+	} else if session.Identity != nil && session.Identity.Email != "" {
+		description = session.Identity.Email
+	}
+	if runes := []rune(description); len(runes) > telegramInlinePreviewLength {
+		description = string(runes[:telegramInlinePreviewLength]) + "..."
+	}
+
+	text := fmt.Sprintf("🔎 Session %s\n👤 %s", session.ID, title)
+	if description != "" {
+		text += fmt.Sprintf("\n💬 %s", description)
+	}
+
+	article := map[string]interface{}{
+		"type":        "article",
+		"id":          session.ID,
+		"title":       title,
+		"description": description,
+		"input_message_content": map[string]interface{}{
+			"message_text": text,
+		},
+	}
+
+	if link := wh.telegramTopicLink(session.ID); link != "" {
+		article["reply_markup"] = map[string]interface{}{
+			"inline_keyboard": [][]map[string]string{
+				{{"text": "Open conversation", "url": link}},
+			},
+		}
+	}
+
+	return article
+}
+
+// telegramTopicLink builds a t.me link to the forum topic of a session. The
+// webhook maps topic IDs to session IDs 1:1, so the session ID is the topic ID.
+// Public chats ("@username") get a t.me/<username> link, private supergroups
+// ("-100…") a t.me/c/ one. Returns "" when the chat ID is unknown or the
+// session ID is not a topic ID.
+func (wh *WebhookHandler) telegramTopicLink(sessionID string) string {
+	if _, err := strconv.Atoi(sessionID); err != nil {
+		return ""
+	}
+	chatID := wh.config.TelegramChatID
+	if username, ok := strings.CutPrefix(chatID, "@"); ok && username != "" {
+		return fmt.Sprintf("https://t.me/%s/%s", username, sessionID)
+	}
+	internalID, ok := strings.CutPrefix(chatID, "-100")
+	if !ok || internalID == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/c/%s/%s", internalID, sessionID)
+}
+
+func (wh *WebhookHandler) answerTelegramInlineQuery(ctx context.Context, queryID string, results []map[string]interface{}) error {
+	return wh.callTelegramAPI(ctx, "answerInlineQuery", map[string]interface{}{
+		"inline_query_id": queryID,
+		"results":         results,
+		"cache_time":      0,
+		"is_personal":     true,
+	})
+}
+
+// handleTelegramCallbackQuery handles takeover/handback button presses. The
+// callback data is "<action ID>:<session ID>".
+func (wh *WebhookHandler) handleTelegramCallbackQuery(ctx context.Context, query *TelegramCallbackQuery) {
+	action, sessionID, _ := strings.Cut(query.Data, ":")
+	var takeover bool
+	switch action {
+	case TakeoverActionID:
+		takeover = true
+	case HandbackActionID:
+		takeover = false
+	default:
+		return
+	}
+	if sessionID == "" || wh.config.OnOperatorTakeover == nil {
+		return
+	}
+
+	wh.config.OnOperatorTakeover(ctx, sessionID, telegramOperatorName(query.From), "telegram", takeover)
+
+	// Stop the button's loading spinner.
+	if err := wh.callTelegramAPI(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": query.ID,
+	}); err != nil {
+		log.Printf("[TelegramWebhook] answerCallbackQuery failed: %v", err)
+	}
+}
+
+// callTelegramAPI POSTs a JSON payload to a Bot API method.
+func (wh *WebhookHandler) callTelegramAPI(ctx context.Context, method string, payload interface{}) error {
+	return wh.callTelegramAPIResult(ctx, method, payload, nil)
+}
+
+// callTelegramAPIResult is callTelegramAPI decoding the method's result into
+// out, unless it is nil.
+func (wh *WebhookHandler) callTelegramAPIResult(ctx context.Context, method string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", wh.config.TelegramBotToken, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wh.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result,omitempty"`
+		Description string          `json:"description,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram error: %s", result.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(result.Result, out)
+	}
+	return nil
+}
+
 type parsedMedia struct {
 	fileID   string
 	filename string
@@ -457,6 +944,23 @@ type SlackEvent struct {
 	Message  *SlackEventMessage `json:"message,omitempty"`
 	PreviousMessage *SlackEventMessage `json:"previous_message,omitempty"`
 	DeletedTs string `json:"deleted_ts,omitempty"`
+	Tab       string `json:"tab,omitempty"`
+}
+
+// SlackInteractionPayload represents a Slack interactivity payload
+// (sent form-encoded as payload=<json>).
+type SlackInteractionPayload struct {
+	Type string `json:"type"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Actions []SlackBlockAction `json:"actions,omitempty"`
+}
+
+// SlackBlockAction represents a clicked Block Kit element
+type SlackBlockAction struct {
+	ActionID string `json:"action_id"`
+	Value    string `json:"value,omitempty"`
 }
 
 type SlackEventMessage struct {
@@ -481,20 +985,28 @@ type SlackFile struct {
 // HandleSlackWebhook returns an http.HandlerFunc for Slack webhooks
 func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		defer recoverHTTP(w, "slack webhook")
+
 		if wh.config.SlackBotToken == "" {
-			http.Error(w, `{"error":"Slack not configured"}`, http.StatusNotFound)
+			WriteError(w, errSlackNotConfigured)
 			return
 		}
 
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, `{"error":"Bad request"}`, http.StatusBadRequest)
+		body, ok := wh.readWebhookBody(w, r)
+		if !ok {
+			return
+		}
+
+		// Interactivity requests (button clicks) are form-encoded
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			wh.handleSlackInteraction(r.Context(), body)
+			writeOK(w)
 			return
 		}
 
 		var payload SlackEventPayload
-		if err := json.Unmarshal(body, &payload); err != nil {
-			http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		if err := decodeWebhookJSON(body, &payload); err != nil {
+			WriteError(w, ErrInvalidJSON)
 			return
 		}
 
@@ -508,6 +1020,13 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 		// Handle event callbacks
 		if payload.Type == "event_callback" && payload.Event != nil {
 			event := payload.Event
+			if event.Type == "app_home_opened" {
+				if event.Tab == "home" && event.User != "" && wh.config.OnSlackAppHomeOpened != nil {
+					wh.config.OnSlackAppHomeOpened(r.Context(), event.User)
+				}
+				writeOK(w)
+				return
+			}
 			if event.Type != "message" {
 				writeOK(w)
 				return
@@ -531,7 +1050,7 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 						if event.Message != nil {
 							threadTs = event.Message.ThreadTs
 							messageTs = event.Message.Ts
-							text = event.Message.Text
+							text = wh.resolveSlackText(event.Message.Text)
 						}
 						if threadTs == "" && event.PreviousMessage != nil {
 							threadTs = event.PreviousMessage.ThreadTs
@@ -540,8 +1059,11 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 							messageTs = event.PreviousMessage.Ts
 						}
 
-						if threadTs != "" && messageTs != "" {
-							wh.config.OnOperatorMessageEdit(r.Context(), threadTs, messageTs, text, "slack", time.Now())
+						if messageTs != "" {
+							if sessionID, ok := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "slack", ChatID: event.Channel, ThreadID: threadTs}); ok {
+								ctx, editedAt := normalizePlatformTime(r.Context(), wh.config.ClockSkew, "slack", slackTime(event.Ts))
+								wh.config.OnOperatorMessageEdit(ctx, sessionID, messageTs, text, "slack", editedAt)
+							}
 						}
 					}
 				}
@@ -569,8 +1091,11 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 							messageTs = event.PreviousMessage.Ts
 						}
 
-						if threadTs != "" && messageTs != "" {
-							wh.config.OnOperatorMessageDelete(r.Context(), threadTs, messageTs, "slack", time.Now())
+						if messageTs != "" {
+							if sessionID, ok := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "slack", ChatID: event.Channel, ThreadID: threadTs}); ok {
+								ctx, deletedAt := normalizePlatformTime(r.Context(), wh.config.ClockSkew, "slack", slackTime(event.Ts))
+								wh.config.OnOperatorMessageDelete(ctx, sessionID, messageTs, "slack", deletedAt)
+							}
 						}
 					}
 				}
@@ -579,12 +1104,16 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 				return
 			}
 
-			hasContent := event.Type == "message" && event.ThreadTs != "" && (event.BotID == "" || wh.isAllowedBot(event.BotID)) && event.Subtype == ""
+			hasContent := event.Type == "message" && (event.BotID == "" || wh.isAllowedBot(event.BotID)) && event.Subtype == ""
 			hasFiles := len(event.Files) > 0
 
+			sessionID, resolved := "", false
 			if hasContent && (event.Text != "" || hasFiles) {
-				threadTs := event.ThreadTs
-				text := event.Text
+				sessionID, resolved = wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "slack", ChatID: event.Channel, ThreadID: event.ThreadTs})
+			}
+
+			if resolved {
+				text := wh.resolveSlackText(event.Text)
 
 				// Download files if present
 				var attachments []Attachment
@@ -612,12 +1141,21 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 					}
 				}
 
+				// Whispers stay between operators
+				if content, ok := parseWhisper(text); ok {
+					if content != "" && wh.config.OnWhisper != nil {
+						wh.config.OnWhisper(r.Context(), sessionID, operatorName, "slack", content)
+					}
+					writeOK(w)
+					return
+				}
+
 				// Call callback (Slack reply support TODO)
 				if wh.config.OnOperatorMessage != nil {
-					wh.config.OnOperatorMessage(r.Context(), threadTs, text, operatorName, "slack", attachments, nil)
+					wh.config.OnOperatorMessage(r.Context(), sessionID, text, operatorName, "slack", attachments, nil)
 				}
 				if wh.config.OnOperatorMessageWithIDs != nil {
-					wh.config.OnOperatorMessageWithIDs(r.Context(), threadTs, text, operatorName, "slack", attachments, nil, event.Ts)
+					wh.config.OnOperatorMessageWithIDs(r.Context(), sessionID, text, operatorName, "slack", attachments, nil, event.Ts)
 				}
 			}
 		}
@@ -626,6 +1164,53 @@ func (wh *WebhookHandler) HandleSlackWebhook() http.HandlerFunc {
 	}
 }
 
+// handleSlackInteraction dispatches App Home quick actions and takeover
+// buttons from a block_actions payload.
+func (wh *WebhookHandler) handleSlackInteraction(ctx context.Context, body []byte) {
+	if wh.config.OnSlackAppHomeAction == nil && wh.config.OnOperatorTakeover == nil && wh.config.OnWatch == nil {
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return
+	}
+
+	var payload SlackInteractionPayload
+	if err := decodeWebhookJSON([]byte(form.Get("payload")), &payload); err != nil {
+		log.Printf("[SlackWebhook] Invalid interaction payload: %v", err)
+		return
+	}
+	if payload.Type != "block_actions" {
+		return
+	}
+
+	for _, action := range payload.Actions {
+		switch action.ActionID {
+		case SlackAppHomeActionClaim, SlackAppHomeActionClose:
+			if action.Value != "" && wh.config.OnSlackAppHomeAction != nil {
+				wh.config.OnSlackAppHomeAction(ctx, action.ActionID, action.Value, payload.User.ID)
+			}
+		case TakeoverActionID, HandbackActionID:
+			if action.Value != "" && wh.config.OnOperatorTakeover != nil {
+				operatorName := payload.User.ID
+				if name, err := wh.getSlackUserName(payload.User.ID); err == nil && name != "" {
+					operatorName = name
+				}
+				wh.config.OnOperatorTakeover(ctx, action.Value, operatorName, "slack", action.ActionID == TakeoverActionID)
+			}
+		case SlackAppHomeActionWatch, SlackAppHomeActionUnwatch:
+			if action.Value != "" && wh.config.OnWatch != nil {
+				operatorName := payload.User.ID
+				if name, err := wh.getSlackUserName(payload.User.ID); err == nil && name != "" {
+					operatorName = name
+				}
+				wh.config.OnWatch(ctx, action.Value, "slack", payload.User.ID, operatorName, action.ActionID == SlackAppHomeActionWatch)
+			}
+		}
+	}
+}
+
 func (wh *WebhookHandler) downloadSlackFile(file SlackFile) ([]byte, error) {
 	downloadURL := file.URLPrivateDownload
 	if downloadURL == "" {
@@ -723,8 +1308,9 @@ type DiscordMember struct {
 
 // DiscordInteractionUser represents a Discord user in an interaction
 type DiscordInteractionUser struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
 }
 
 // DiscordInteractionData represents Discord interaction data
@@ -732,6 +1318,13 @@ type DiscordInteractionData struct {
 	Name     string                 `json:"name,omitempty"`
 	CustomID string                 `json:"custom_id,omitempty"`
 	Options  []DiscordCommandOption `json:"options,omitempty"`
+	Resolved *DiscordResolvedData   `json:"resolved,omitempty"`
+}
+
+// DiscordResolvedData holds the users referenced by an interaction's
+// options, by ID.
+type DiscordResolvedData struct {
+	Users map[string]DiscordInteractionUser `json:"users,omitempty"`
 }
 
 // DiscordCommandOption represents a Discord command option
@@ -749,22 +1342,24 @@ const (
 
 // Discord response types
 const (
-	DiscordResponseTypePong                     = 1
-	DiscordResponseTypeChannelMessageWithSource = 4
+	DiscordResponseTypePong                             = 1
+	DiscordResponseTypeChannelMessageWithSource         = 4
+	DiscordResponseTypeDeferredChannelMessageWithSource = 5
 )
 
 // HandleDiscordWebhook returns an http.HandlerFunc for Discord webhooks
 func (wh *WebhookHandler) HandleDiscordWebhook() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, `{"error":"Bad request"}`, http.StatusBadRequest)
+		defer recoverHTTP(w, "discord webhook")
+
+		body, ok := wh.readWebhookBody(w, r)
+		if !ok {
 			return
 		}
 
 		var interaction DiscordInteraction
-		if err := json.Unmarshal(body, &interaction); err != nil {
-			http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		if err := decodeWebhookJSON(body, &interaction); err != nil {
+			WriteError(w, ErrInvalidJSON)
 			return
 		}
 
@@ -777,8 +1372,159 @@ func (wh *WebhookHandler) HandleDiscordWebhook() http.HandlerFunc {
 
 		// Handle Application Commands (slash commands)
 		if interaction.Type == DiscordInteractionTypeApplicationCommand && interaction.Data != nil {
+			if interaction.Data.Name == "takeover" || interaction.Data.Name == "handback" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnOperatorTakeover != nil {
+					takeover := interaction.Data.Name == "takeover"
+					wh.config.OnOperatorTakeover(r.Context(), sessionID, discordInteractionUserName(&interaction), "discord", takeover)
+
+					confirmation := "✅ AI handed back"
+					if takeover {
+						confirmation = "✅ You took over, AI paused"
+					}
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeChannelMessageWithSource,
+						"data": map[string]string{"content": confirmation},
+					})
+					return
+				}
+			}
+
+			if interaction.Data.Name == "watch" || interaction.Data.Name == "unwatch" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnWatch != nil {
+					watch := interaction.Data.Name == "watch"
+					wh.config.OnWatch(r.Context(), sessionID, "discord", discordInteractionUserID(&interaction), discordInteractionUserName(&interaction), watch)
+
+					confirmation := "👀 Stopped watching this conversation"
+					if watch {
+						confirmation = "👀 Watching: you'll get a DM for every activity here"
+					}
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeChannelMessageWithSource,
+						"data": map[string]string{"content": confirmation},
+					})
+					return
+				}
+			}
+
+			if interaction.Data.Name == "ticket" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnTicketCommand != nil {
+					var title string
+					for _, opt := range interaction.Data.Options {
+						if opt.Name == "title" {
+							title = opt.Value
+							break
+						}
+					}
+					// Answer within Discord's 3 seconds; the ticket may take longer
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeDeferredChannelMessageWithSource,
+					})
+					wh.runTicketCommand(r.Context(), sessionID, discordInteractionUserName(&interaction), "discord", title, func(ctx context.Context) {
+						wh.editDiscordInteractionResponse(ctx, &interaction, "🎫 Ticket requested")
+					})
+					return
+				}
+			}
+
+			if interaction.Data.Name == "form" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnFormCommand != nil {
+					var name string
+					for _, opt := range interaction.Data.Options {
+						if opt.Name == "name" {
+							name = opt.Value
+							break
+						}
+					}
+					confirmation := "📋 Sending form..."
+					if strings.TrimSpace(name) == "" {
+						confirmation = "⚠️ Usage: /form name:feedback"
+					} else {
+						wh.config.OnFormCommand(r.Context(), sessionID, discordInteractionUserName(&interaction), "discord", name)
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeChannelMessageWithSource,
+						"data": map[string]string{"content": confirmation},
+					})
+					return
+				}
+			}
+
+			if interaction.Data.Name == "whisper" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnWhisper != nil {
+					var content string
+					for _, opt := range interaction.Data.Options {
+						if opt.Name == "message" {
+							content = strings.TrimSpace(opt.Value)
+							break
+						}
+					}
+					confirmation := "🤫 Shared with the other operators"
+					if content == "" {
+						confirmation = "⚠️ Usage: /whisper message:text"
+					} else {
+						wh.config.OnWhisper(r.Context(), sessionID, discordInteractionUserName(&interaction), "discord", content)
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeChannelMessageWithSource,
+						"data": map[string]string{"content": confirmation},
+					})
+					return
+				}
+			}
+
+			if interaction.Data.Name == "charge" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnChargeCommand != nil {
+					options := make(map[string]string)
+					for _, opt := range interaction.Data.Options {
+						options[opt.Name] = opt.Value
+					}
+					currency := strings.ToUpper(options["currency"])
+					amount, err := ParseAmount(options["amount"], currency)
+					confirmation := "💳 Sending payment request..."
+					if err != nil || len(currency) != 3 {
+						confirmation = "⚠️ Usage: /charge amount:49.90 currency:USD description:Consulting"
+					} else {
+						wh.config.OnChargeCommand(r.Context(), sessionID, discordInteractionUserName(&interaction), "discord", amount, currency, options["description"])
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeChannelMessageWithSource,
+						"data": map[string]string{"content": confirmation},
+					})
+					return
+				}
+			}
+
+			if interaction.Data.Name == "context" {
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
+				if resolved && wh.config.OnContextCommand != nil {
+					wh.config.OnContextCommand(r.Context(), sessionID, discordInteractionUserName(&interaction), "discord")
+
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"type": DiscordResponseTypeChannelMessageWithSource,
+						"data": map[string]string{"content": "📇 Fetching customer context..."},
+					})
+					return
+				}
+			}
+
 			if interaction.Data.Name == "reply" {
-				threadID := interaction.ChannelID
+				sessionID, resolved := wh.config.SessionResolver(r.Context(), BridgeContainer{Bridge: "discord", ThreadID: interaction.ChannelID})
 				var content string
 				for _, opt := range interaction.Data.Options {
 					if opt.Name == "message" {
@@ -787,18 +1533,20 @@ func (wh *WebhookHandler) HandleDiscordWebhook() http.HandlerFunc {
 					}
 				}
 
-				if threadID != "" && content != "" {
+				if resolved && content != "" {
 					// Get operator name
-					operatorName := "Operator"
-					if interaction.Member != nil && interaction.Member.User != nil {
-						operatorName = interaction.Member.User.Username
-					} else if interaction.User != nil {
-						operatorName = interaction.User.Username
+					operatorName := discordInteractionUserName(&interaction)
+					if interaction.Data.Resolved != nil {
+						names := make(map[string]string, len(interaction.Data.Resolved.Users))
+						for id, user := range interaction.Data.Resolved.Users {
+							names[id] = discordUser{Username: user.Username, GlobalName: user.GlobalName}.displayName()
+						}
+						content = ResolveDiscordMentions(content, names)
 					}
 
 					// Call callback (Discord reply support TODO)
 					if wh.config.OnOperatorMessage != nil {
-						wh.config.OnOperatorMessage(r.Context(), threadID, content, operatorName, "discord", nil, nil)
+						wh.config.OnOperatorMessage(r.Context(), sessionID, content, operatorName, "discord", nil, nil)
 					}
 
 					// Respond to interaction
@@ -818,6 +1566,30 @@ func (wh *WebhookHandler) HandleDiscordWebhook() http.HandlerFunc {
 	}
 }
 
+// discordInteractionUserName returns the name of the user who triggered an
+// interaction (guild member or DM user).
+func discordInteractionUserName(interaction *DiscordInteraction) string {
+	if interaction.Member != nil && interaction.Member.User != nil {
+		return interaction.Member.User.Username
+	}
+	if interaction.User != nil {
+		return interaction.User.Username
+	}
+	return "Operator"
+}
+
+// discordInteractionUserID returns the ID of the user who triggered an
+// interaction.
+func discordInteractionUserID(interaction *DiscordInteraction) string {
+	if interaction.Member != nil && interaction.Member.User != nil {
+		return interaction.Member.User.ID
+	}
+	if interaction.User != nil {
+		return interaction.User.ID
+	}
+	return ""
+}
+
 // ─────────────────────────────────────────────────────────────────
 // Helper
 // ─────────────────────────────────────────────────────────────────
@@ -826,3 +1598,23 @@ func writeOK(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(`{"ok":true}`))
 }
+
+// telegramTime returns a Telegram Unix date, or zero when unset.
+func telegramTime(date int64) time.Time {
+	if date <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(date, 0)
+}
+
+// slackTime returns the time of a Slack timestamp ("1700000000.123456"), or
+// zero when it is unset or invalid.
+func slackTime(ts string) time.Time {
+	seconds, micros, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}
+	}
+	usec, _ := strconv.ParseInt((micros + "000000")[:6], 10, 64)
+	return time.Unix(sec, usec*int64(time.Microsecond))
+}
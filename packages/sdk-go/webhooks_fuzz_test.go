@@ -0,0 +1,131 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// offlineTransport fails every request, keeping fuzzed handlers off the network.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
+
+// fuzzWebhookHandler returns a handler with every bridge and callback enabled,
+// so fuzz inputs reach as many parsing paths as possible.
+func fuzzWebhookHandler() *WebhookHandler {
+	wh := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken:         "tok",
+		TelegramChatID:           "-100123",
+		SlackBotToken:            "xoxb-tok",
+		OnOperatorMessage:        func(context.Context, string, string, string, string, []Attachment, *int) {},
+		OnOperatorMessageWithIDs: func(context.Context, string, string, string, string, []Attachment, *int, string) {},
+		OnOperatorMessageEdit:    func(context.Context, string, string, string, string, time.Time) {},
+		OnOperatorMessageDelete:  func(context.Context, string, string, string, time.Time) {},
+		OnTelegramInlineSearch: func(context.Context, string, *TelegramUser) ([]SearchResult, error) {
+			return []SearchResult{{Session: &Session{ID: "s1"}, Message: &Message{Content: "hello"}}}, nil
+		},
+		OnSlackAppHomeOpened: func(context.Context, string) {},
+		OnSlackAppHomeAction: func(context.Context, string, string, string) {},
+		OnOperatorTakeover:   func(context.Context, string, string, string, bool) {},
+	})
+	wh.httpClient = &http.Client{Transport: offlineTransport{}}
+	return wh
+}
+
+// fuzzWebhook posts data to handler and fails on a recovered panic.
+func fuzzWebhook(t *testing.T, handler http.HandlerFunc, contentType string, data []byte) {
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(data))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code == http.StatusInternalServerError {
+		t.Fatalf("handler panicked on %q", data)
+	}
+}
+
+func FuzzHandleTelegramWebhook(f *testing.F) {
+	f.Add([]byte(`{"message":{"message_id":42,"message_thread_id":7,"text":"hello","from":{"id":1,"first_name":"Alice"},"reply_to_message":{"message_id":99}}}`))
+	f.Add([]byte(`{"message":{"message_id":1,"message_thread_id":7,"photo":[{"file_id":"a","file_size":10}],"caption":"pic"}}`))
+	f.Add([]byte(`{"edited_message":{"message_id":5,"message_thread_id":7,"text":"fixed"}}`))
+	f.Add([]byte(`{"message_reaction":{"message_id":5,"chat":{"id":1},"new_reaction":[{"type":"emoji","emoji":"🗑"}]}}`))
+	f.Add([]byte(`{"inline_query":{"id":"q","query":"search billing","from":{"id":1}}}`))
+	f.Add([]byte(`{"callback_query":{"id":"c","data":"takeover","message":{"message_id":1,"message_thread_id":7}}}`))
+	f.Add([]byte(`{"message":{"message_id":1,"message_thread_id":7,"text":"/takeover"}}`))
+	f.Add([]byte(`[`))
+	wh := fuzzWebhookHandler()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzWebhook(t, wh.HandleTelegramWebhook(), "application/json", data)
+	})
+}
+
+func FuzzHandleSlackWebhook(f *testing.F) {
+	f.Add([]byte(`{"type":"url_verification","challenge":"abc"}`), false)
+	f.Add([]byte(`{"type":"event_callback","event":{"type":"message","text":"hi","user":"U1","channel":"C1","thread_ts":"1.2","ts":"1.3"}}`), false)
+	f.Add([]byte(`{"type":"event_callback","event":{"type":"message","subtype":"message_changed","channel":"C1","message":{"text":"x","ts":"1.3","thread_ts":"1.2"}}}`), false)
+	f.Add([]byte(`{"type":"event_callback","event":{"type":"app_home_opened","tab":"home","user":"U1"}}`), false)
+	f.Add([]byte(`payload=%7B%22type%22%3A%22block_actions%22%2C%22actions%22%3A%5B%7B%22action_id%22%3A%22takeover%22%2C%22value%22%3A%22s1%22%7D%5D%7D`), true)
+	wh := fuzzWebhookHandler()
+	f.Fuzz(func(t *testing.T, data []byte, form bool) {
+		contentType := "application/json"
+		if form {
+			contentType = "application/x-www-form-urlencoded"
+		}
+		fuzzWebhook(t, wh.HandleSlackWebhook(), contentType, data)
+	})
+}
+
+func FuzzHandleDiscordWebhook(f *testing.F) {
+	f.Add([]byte(`{"type":1}`))
+	f.Add([]byte(`{"type":2,"channel_id":"123","data":{"name":"takeover"},"member":{"user":{"username":"op"}}}`))
+	f.Add([]byte(`{"type":2,"channel_id":"123","data":{"name":"reply","options":[{"name":"message","value":"hi"}]}}`))
+	f.Add([]byte(`{"type":3,"channel_id":"123","data":{"custom_id":"handback"}}`))
+	wh := fuzzWebhookHandler()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzWebhook(t, wh.HandleDiscordWebhook(), "application/json", data)
+	})
+}
+
+func TestWebhookRejectsOversizedBody(t *testing.T) {
+	wh := NewWebhookHandler(WebhookConfig{TelegramBotToken: "tok", MaxBodyBytes: 64})
+	rec := postWebhook(wh.HandleTelegramWebhook(), `{"message":{"text":"`+strings.Repeat("a", 100)+`"}}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
+
+func TestWebhookRejectsDeeplyNestedJSON(t *testing.T) {
+	wh := NewWebhookHandler(WebhookConfig{TelegramBotToken: "tok"})
+	payload := `{"message":` + strings.Repeat("[", 100) + strings.Repeat("]", 100) + `}`
+	rec := postWebhook(wh.HandleTelegramWebhook(), payload)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	// Brackets inside strings don't count towards the depth.
+	if err := checkJSONDepth([]byte(`{"text":"`+strings.Repeat("[", 100)+`\""}`), 64); err != nil {
+		t.Errorf("checkJSONDepth counted string contents: %v", err)
+	}
+}
+
+func TestWebhookRecoversFromPanic(t *testing.T) {
+	wh := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "tok",
+		OnOperatorMessage: func(context.Context, string, string, string, string, []Attachment, *int) {
+			panic("boom")
+		},
+	})
+	captureLog(t)
+	rec := postWebhook(wh.HandleTelegramWebhook(), `{"message":{"message_id":1,"message_thread_id":7,"text":"hi"}}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
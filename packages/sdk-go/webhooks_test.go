@@ -3,21 +3,27 @@ package pocketping
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 func TestWebhookHandler_TelegramEditedMessage(t *testing.T) {
 	var (
-		called         bool
-		gotSessionID   string
-		gotMessageID   string
-		gotContent     string
-		gotSource      string
-		gotEditedAt    time.Time
-		expectedEdited = time.Unix(1700000000, 0)
+		called       bool
+		gotSessionID string
+		gotMessageID string
+		gotContent   string
+		gotSource    string
+		gotEditedAt  time.Time
+		// Within DefaultClockSkewThreshold, so kept as is
+		expectedEdited = time.Unix(time.Now().Add(-2*time.Second).Unix(), 0)
 	)
 
 	handler := NewWebhookHandler(WebhookConfig{
@@ -32,7 +38,7 @@ func TestWebhookHandler_TelegramEditedMessage(t *testing.T) {
 		},
 	})
 
-	payload := []byte(`{"edited_message":{"message_id":123,"message_thread_id":456,"text":"Updated message","edit_date":1700000000}}`)
+	payload := []byte(fmt.Sprintf(`{"edited_message":{"message_id":123,"message_thread_id":456,"text":"Updated message","edit_date":%d}}`, expectedEdited.Unix()))
 	req := httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload))
 	rec := httptest.NewRecorder()
 
@@ -127,7 +133,8 @@ func TestWebhookHandler_TelegramReactionDelete(t *testing.T) {
 		},
 	})
 
-	payload := []byte(`{"message_reaction":{"message_id":999,"message_thread_id":456,"new_reaction":[{"type":"emoji","emoji":"🗑️"}],"date":1700000000}}`)
+	expectedTime := time.Unix(time.Now().Add(-2*time.Second).Unix(), 0)
+	payload := []byte(fmt.Sprintf(`{"message_reaction":{"message_id":999,"message_thread_id":456,"new_reaction":[{"type":"emoji","emoji":"🗑️"}],"date":%d}}`, expectedTime.Unix()))
 	req := httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload))
 	rec := httptest.NewRecorder()
 
@@ -148,8 +155,307 @@ func TestWebhookHandler_TelegramReactionDelete(t *testing.T) {
 	if gotSource != "telegram" {
 		t.Errorf("expected source 'telegram', got %q", gotSource)
 	}
-	expectedTime := time.Unix(1700000000, 0)
 	if !gotDeletedAt.Equal(expectedTime) {
 		t.Errorf("expected deletedAt %v, got %v", expectedTime, gotDeletedAt)
 	}
 }
+
+// telegramMembersServer fakes the Bot API for inline search: getChatMember
+// answers with status per user ID ("left" for unknown users), and the
+// answerInlineQuery payload is stored in answered.
+func telegramMembersServer(t *testing.T, status map[int64]string, answered *map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getChatMember"):
+			var req struct {
+				UserID int64 `json:"user_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			memberStatus := status[req.UserID]
+			if memberStatus == "" {
+				memberStatus = "left"
+			}
+			fmt.Fprintf(w, `{"ok":true,"result":{"status":%q}}`, memberStatus)
+			return
+		case strings.HasSuffix(r.URL.Path, "/answerInlineQuery"):
+			json.NewDecoder(r.Body).Decode(answered)
+		}
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebhookHandler_TelegramInlineSearch(t *testing.T) {
+	var answered map[string]interface{}
+	server := telegramMembersServer(t, map[int64]string{7: "member"}, &answered)
+
+	var gotTerm string
+	var gotFrom int64
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		TelegramChatID:   "-1001234567890",
+		OnTelegramInlineSearch: func(ctx context.Context, term string, from *TelegramUser) ([]SearchResult, error) {
+			gotTerm = term
+			if from != nil {
+				gotFrom = from.ID
+			}
+			return []SearchResult{{
+				Session: &Session{ID: "42", VisitorID: "v1", Identity: &UserIdentity{ID: "u1", Email: "bob@example.com"}},
+				Message: &Message{ID: "m1", Content: "refund please"},
+			}}, nil
+		},
+	})
+	handler.httpClient = &http.Client{Transport: &webhookTestTransport{telegramURL: server.URL}}
+
+	payload := []byte(`{"inline_query":{"id":"q1","from":{"id":7,"first_name":"Op"},"query":"search refund please"}}`)
+	rec := httptest.NewRecorder()
+	handler.HandleTelegramWebhook()(rec, httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if gotTerm != "refund please" || gotFrom != 7 {
+		t.Errorf("expected term 'refund please' from 7, got %q from %d", gotTerm, gotFrom)
+	}
+	if answered == nil || answered["inline_query_id"] != "q1" {
+		t.Fatalf("expected answerInlineQuery for q1, got %v", answered)
+	}
+	results, _ := answered["results"].([]interface{})
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	article := results[0].(map[string]interface{})
+	if article["title"] != "bob@example.com" || article["description"] != "refund please" {
+		t.Errorf("unexpected article: %v", article)
+	}
+	markup, _ := json.Marshal(article["reply_markup"])
+	if !strings.Contains(string(markup), "https://t.me/c/1234567890/42") {
+		t.Errorf("expected topic link in reply_markup, got %s", markup)
+	}
+}
+
+func TestWebhookHandler_TelegramInlineSearchOperatorsOnly(t *testing.T) {
+	var answered map[string]interface{}
+	server := telegramMembersServer(t, map[int64]string{7: "administrator", 8: "kicked"}, &answered)
+	called := 0
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		TelegramChatID:   "@acme_support",
+		OnTelegramInlineSearch: func(ctx context.Context, term string, from *TelegramUser) ([]SearchResult, error) {
+			called++
+			return []SearchResult{{
+				Session: &Session{ID: "42", VisitorID: "v1"},
+				Message: &Message{ID: "m1", Content: strings.Repeat("é", 150)},
+			}}, nil
+		},
+	})
+	handler.httpClient = &http.Client{Transport: &webhookTestTransport{telegramURL: server.URL}}
+
+	// Strangers and former members get no answer
+	for _, from := range []int64{8, 9} {
+		payload := []byte(fmt.Sprintf(`{"inline_query":{"id":"q1","from":{"id":%d,"first_name":"X"},"query":"search refund"}}`, from))
+		handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+	}
+	if called != 0 || answered != nil {
+		t.Fatalf("non-operators searched: %d calls, answer %v", called, answered)
+	}
+
+	payload := []byte(`{"inline_query":{"id":"q2","from":{"id":7,"first_name":"Op"},"query":"search refund"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+	results, _ := answered["results"].([]interface{})
+	if called != 1 || len(results) != 1 {
+		t.Fatalf("operator search: %d calls, %d results", called, len(results))
+	}
+	article := results[0].(map[string]interface{})
+	// Excerpts are cut by character, not byte
+	if description := article["description"].(string); !utf8.ValidString(description) || description != strings.Repeat("é", 100)+"..." {
+		t.Errorf("description = %q", description)
+	}
+	// Public chats link by username
+	markup, _ := json.Marshal(article["reply_markup"])
+	if !strings.Contains(string(markup), "https://t.me/acme_support/42") {
+		t.Errorf("expected username topic link in reply_markup, got %s", markup)
+	}
+}
+
+func TestWebhookHandler_TelegramInlineQueryIgnoresNonSearch(t *testing.T) {
+	called := false
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		OnTelegramInlineSearch: func(ctx context.Context, term string, from *TelegramUser) ([]SearchResult, error) {
+			called = true
+			return nil, nil
+		},
+	})
+
+	for _, query := range []string{"", "search", "find refund"} {
+		payload := []byte(`{"inline_query":{"id":"q1","query":"` + query + `"}}`)
+		rec := httptest.NewRecorder()
+		handler.HandleTelegramWebhook()(rec, httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+		if rec.Code != http.StatusOK {
+			t.Errorf("query %q: expected status 200, got %d", query, rec.Code)
+		}
+	}
+	if called {
+		t.Error("expected search callback not to be called")
+	}
+}
+
+func TestWebhookHandler_SlackAppHomeOpened(t *testing.T) {
+	var gotUser string
+	handler := NewWebhookHandler(WebhookConfig{
+		SlackBotToken: "xoxb-test",
+		OnSlackAppHomeOpened: func(ctx context.Context, userID string) {
+			gotUser = userID
+		},
+	})
+
+	payload := []byte(`{"type":"event_callback","event":{"type":"app_home_opened","user":"U123","tab":"home"}}`)
+	rec := httptest.NewRecorder()
+	handler.HandleSlackWebhook()(rec, httptest.NewRequest("POST", "/webhooks/slack", bytes.NewReader(payload)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if gotUser != "U123" {
+		t.Errorf("expected user U123, got %q", gotUser)
+	}
+
+	// Opening the Messages tab must not trigger a publish
+	gotUser = ""
+	payload = []byte(`{"type":"event_callback","event":{"type":"app_home_opened","user":"U123","tab":"messages"}}`)
+	handler.HandleSlackWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/slack", bytes.NewReader(payload)))
+	if gotUser != "" {
+		t.Error("expected callback not to be called for the messages tab")
+	}
+}
+
+func TestWebhookHandler_SlackAppHomeAction(t *testing.T) {
+	type call struct{ action, sessionID, userID string }
+	var calls []call
+	handler := NewWebhookHandler(WebhookConfig{
+		SlackBotToken: "xoxb-test",
+		OnSlackAppHomeAction: func(ctx context.Context, actionID, sessionID, userID string) {
+			calls = append(calls, call{actionID, sessionID, userID})
+		},
+	})
+
+	interaction := `{"type":"block_actions","user":{"id":"U42"},"actions":[` +
+		`{"action_id":"pocketping_claim","value":"sess-1"},` +
+		`{"action_id":"unrelated","value":"x"},` +
+		`{"action_id":"pocketping_close","value":"sess-2"}]}`
+	form := url.Values{"payload": {interaction}}.Encode()
+
+	req := httptest.NewRequest("POST", "/webhooks/slack", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.HandleSlackWebhook()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	want := []call{
+		{SlackAppHomeActionClaim, "sess-1", "U42"},
+		{SlackAppHomeActionClose, "sess-2", "U42"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d calls, got %d: %+v", len(want), len(calls), calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d: expected %+v, got %+v", i, want[i], calls[i])
+		}
+	}
+}
+
+func TestWebhookHandler_SessionResolverPlainTelegramChat(t *testing.T) {
+	var (
+		gotContainer BridgeContainer
+		gotSessionID string
+	)
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		SessionResolver: func(ctx context.Context, c BridgeContainer) (string, bool) {
+			gotContainer = c
+			if c.ReplyToMessageID == "" {
+				return "", false
+			}
+			return "sess-for-" + c.ReplyToMessageID, true
+		},
+		OnOperatorMessage: func(ctx context.Context, sessionID, content, operatorName, sourceBridge string, attachments []Attachment, replyTo *int) {
+			gotSessionID = sessionID
+		},
+	})
+
+	// Plain chat (no forum topic): operator replies to a notification
+	payload := []byte(`{"message":{"message_id":10,"chat":{"id":-42},"from":{"id":1,"first_name":"Ann"},"text":"on it","reply_to_message":{"message_id":7}}}`)
+	rec := httptest.NewRecorder()
+	handler.HandleTelegramWebhook()(rec, httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	want := BridgeContainer{Bridge: "telegram", ChatID: "-42", ReplyToMessageID: "7"}
+	if gotContainer != want {
+		t.Errorf("expected container %+v, got %+v", want, gotContainer)
+	}
+	if gotSessionID != "sess-for-7" {
+		t.Errorf("expected resolved session sess-for-7, got %q", gotSessionID)
+	}
+
+	// Resolver rejects: callback must not run
+	gotSessionID = ""
+	payload = []byte(`{"message":{"message_id":11,"chat":{"id":-42},"text":"hello"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+	if gotSessionID != "" {
+		t.Errorf("expected message to be ignored, got session %q", gotSessionID)
+	}
+}
+
+func TestWebhookHandler_SessionResolverSlackAndDiscord(t *testing.T) {
+	var containers []BridgeContainer
+	var sessions []string
+	handler := NewWebhookHandler(WebhookConfig{
+		SlackBotToken: "xoxb-test",
+		SessionResolver: func(ctx context.Context, c BridgeContainer) (string, bool) {
+			containers = append(containers, c)
+			return "mapped-" + c.ThreadID, true
+		},
+		OnOperatorMessage: func(ctx context.Context, sessionID, content, operatorName, sourceBridge string, attachments []Attachment, replyTo *int) {
+			sessions = append(sessions, sessionID)
+		},
+	})
+
+	slackPayload := []byte(`{"type":"event_callback","event":{"type":"message","channel":"C1","thread_ts":"111.222","text":"hi"}}`)
+	handler.HandleSlackWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/slack", bytes.NewReader(slackPayload)))
+
+	discordPayload := []byte(`{"type":2,"channel_id":"T9","data":{"name":"reply","options":[{"name":"message","value":"hello"}]}}`)
+	handler.HandleDiscordWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/discord", bytes.NewReader(discordPayload)))
+
+	wantContainers := []BridgeContainer{
+		{Bridge: "slack", ChatID: "C1", ThreadID: "111.222"},
+		{Bridge: "discord", ThreadID: "T9"},
+	}
+	if len(containers) != len(wantContainers) {
+		t.Fatalf("expected %d resolver calls, got %d", len(wantContainers), len(containers))
+	}
+	for i := range wantContainers {
+		if containers[i] != wantContainers[i] {
+			t.Errorf("container %d: expected %+v, got %+v", i, wantContainers[i], containers[i])
+		}
+	}
+	if len(sessions) != 2 || sessions[0] != "mapped-111.222" || sessions[1] != "mapped-T9" {
+		t.Errorf("unexpected sessions: %v", sessions)
+	}
+}
+
+func TestDefaultSessionResolver(t *testing.T) {
+	if _, ok := DefaultSessionResolver(context.Background(), BridgeContainer{Bridge: "telegram", ChatID: "-1"}); ok {
+		t.Error("expected messages outside a thread to be ignored")
+	}
+	id, ok := DefaultSessionResolver(context.Background(), BridgeContainer{Bridge: "telegram", ThreadID: "42"})
+	if !ok || id != "42" {
+		t.Errorf("expected 42, got %q (ok=%v)", id, ok)
+	}
+}
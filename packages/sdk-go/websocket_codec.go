@@ -0,0 +1,122 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket subprotocols a widget can offer to pick the frame encoding.
+const (
+	// WebSocketProtocolJSON sends events as JSON text frames, like sockets
+	// without a subprotocol.
+	WebSocketProtocolJSON = "pocketping.json"
+	// WebSocketProtocolMsgPack sends events as MessagePack binary frames.
+	WebSocketProtocolMsgPack = "pocketping.msgpack"
+)
+
+// WebSocketReadLimit is the largest frame NewWebSocketConn sockets accept
+// from the client. Bigger frames close the socket.
+const WebSocketReadLimit = 1 << 20
+
+// WebSocketUpgrader returns an upgrader for widget and operator console
+// sockets. It offers both subprotocols (the client's preference wins) and
+// permessage-deflate when Config.WebSocketCompression is set. Set
+// CheckOrigin on it for cross-origin widgets.
+func (pp *PocketPing) WebSocketUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		Subprotocols:      []string{WebSocketProtocolMsgPack, WebSocketProtocolJSON},
+		EnableCompression: pp.config.WebSocketCompression,
+	}
+}
+
+// EncodedConn is a WebSocketConn for a *websocket.Conn that writes in the
+// encoding negotiated during the upgrade.
+type EncodedConn struct {
+	conn    *websocket.Conn
+	msgPack bool
+	// lastPong is the UnixNano time of the last pong, for the heartbeat.
+	lastPong atomic.Int64
+
+	// mu serializes writes, which *websocket.Conn doesn't allow concurrently.
+	mu sync.Mutex
+}
+
+// NewWebSocketConn wraps an upgraded socket. Compressed writes are turned on
+// when the client negotiated permessage-deflate, and reads are limited to
+// WebSocketReadLimit. Pongs are tracked for the heartbeat; they arrive while
+// the socket is being read.
+func NewWebSocketConn(conn *websocket.Conn) *EncodedConn {
+	conn.EnableWriteCompression(true)
+	conn.SetReadLimit(WebSocketReadLimit)
+	c := &EncodedConn{conn: conn, msgPack: conn.Subprotocol() == WebSocketProtocolMsgPack}
+	c.lastPong.Store(time.Now().UnixNano())
+	conn.SetPongHandler(func(string) error {
+		c.lastPong.Store(time.Now().UnixNano())
+		return nil
+	})
+	return c
+}
+
+// MsgPack reports whether the socket uses WebSocketProtocolMsgPack.
+func (c *EncodedConn) MsgPack() bool {
+	return c.msgPack
+}
+
+// WriteJSON writes v as a JSON text frame, or a MessagePack binary frame.
+func (c *EncodedConn) WriteJSON(v interface{}) error {
+	frameType, data := websocket.TextMessage, []byte(nil)
+	var err error
+	if c.msgPack {
+		frameType = websocket.BinaryMessage
+		data, err = MarshalMsgPack(v)
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteMessage(frameType, data)
+}
+
+// ReadJSON reads the next frame into v, decoding MessagePack binary frames
+// and JSON text frames.
+func (c *EncodedConn) ReadJSON(v interface{}) error {
+	frameType, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if frameType == websocket.BinaryMessage {
+		return UnmarshalMsgPack(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SetWriteDeadline sets the deadline for the next write.
+func (c *EncodedConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Ping sends a WebSocket ping frame.
+func (c *EncodedConn) Ping(deadline time.Time) error {
+	return c.conn.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+// LastPong returns when the last pong arrived, or when the socket was
+// wrapped.
+func (c *EncodedConn) LastPong() time.Time {
+	return time.Unix(0, c.lastPong.Load())
+}
+
+// Close closes the socket.
+func (c *EncodedConn) Close() error {
+	return c.conn.Close()
+}
+
+var _ PingableConn = (*EncodedConn)(nil)
@@ -0,0 +1,112 @@
+package pocketping
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// widgetServer upgrades sockets for session s1 and registers them.
+func widgetServer(t *testing.T, pp *PocketPing) string {
+	t.Helper()
+	upgrader := pp.WebSocketUpgrader()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		ws := NewWebSocketConn(conn)
+		pp.RegisterWebSocket("s1", ws)
+		defer pp.UnregisterWebSocket("s1", ws)
+		var frame map[string]interface{}
+		for ws.ReadJSON(&frame) == nil {
+			pp.BroadcastToSession("s1", WebSocketEvent{Type: "echo", Data: frame})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestWebSocketEncodings(t *testing.T) {
+	pp := New(Config{WebSocketCompression: true})
+	url := widgetServer(t, pp)
+
+	tests := []struct {
+		name      string
+		protocols []string
+		frameType int
+	}{
+		{"default", nil, websocket.TextMessage},
+		{"json", []string{WebSocketProtocolJSON}, websocket.TextMessage},
+		{"msgpack", []string{WebSocketProtocolMsgPack, WebSocketProtocolJSON}, websocket.BinaryMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := websocket.Dialer{Subprotocols: tt.protocols, EnableCompression: true}
+			client, resp, err := dialer.Dial(url, nil)
+			if err != nil {
+				t.Fatalf("Dial: %v", err)
+			}
+			defer client.Close()
+			if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+				t.Errorf("extensions = %q, want permessage-deflate", ext)
+			}
+
+			var request []byte
+			if tt.frameType == websocket.BinaryMessage {
+				request, _ = MarshalMsgPack(map[string]string{"text": "ping"})
+			} else {
+				request = []byte(`{"text":"ping"}`)
+			}
+			if err := client.WriteMessage(tt.frameType, request); err != nil {
+				t.Fatalf("WriteMessage: %v", err)
+			}
+
+			frameType, data, err := client.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage: %v", err)
+			}
+			if frameType != tt.frameType {
+				t.Errorf("frame type = %d, want %d", frameType, tt.frameType)
+			}
+			var event struct {
+				Type string            `json:"type"`
+				Data map[string]string `json:"data"`
+			}
+			if err := decodeFrame(frameType, data, &event); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if event.Type != "echo" || event.Data["text"] != "ping" {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func decodeFrame(frameType int, data []byte, v interface{}) error {
+	if frameType == websocket.BinaryMessage {
+		return UnmarshalMsgPack(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	pp := New(Config{})
+	client, _, err := websocket.DefaultDialer.Dial(widgetServer(t, pp), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+
+	frame := `{"text":"` + strings.Repeat("a", WebSocketReadLimit) + `"}`
+	if err := client.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if _, _, err := client.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("ReadMessage: err = %v, want close 1009", err)
+	}
+}
@@ -0,0 +1,177 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// WelcomeChoiceEvent is the custom event the widget sends when the visitor
+// picks a welcome flow quick reply, with data {"step": <step ID>, "value":
+// <QuickReply.Value>}. It reaches OnEvent handlers like any custom event.
+const WelcomeChoiceEvent = "welcome_choice"
+
+// WelcomeStep is one message of a welcome flow. A step with quick replies
+// waits for the visitor's choice; steps without are shown together with the
+// following steps.
+type WelcomeStep struct {
+	// ID identifies the step in choices. Defaults to "step-<index>".
+	ID           string       `json:"id"`
+	Message      string       `json:"message"`
+	QuickReplies []QuickReply `json:"quickReplies,omitempty"`
+}
+
+// WelcomeFlow is a sequence of welcome messages and quick replies shown to new
+// sessions, replacing Config.WelcomeMessage for the pages it targets.
+type WelcomeFlow struct {
+	// Name identifies the flow in the session state.
+	Name string
+	// Pages are URL path patterns (path.Match syntax; a trailing "*" matches
+	// any suffix) the flow applies to, checked against the session's
+	// Metadata.URL. Empty means every page.
+	Pages []string
+	Steps []WelcomeStep
+}
+
+// WelcomeFlowState is a session's progress through its welcome flow.
+type WelcomeFlowState struct {
+	Flow string `json:"flow"`
+	// Step is the index of the first step not yet answered.
+	Step int `json:"step"`
+	// Choices maps step IDs to the chosen values.
+	Choices     map[string]string `json:"choices,omitempty"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+}
+
+// WelcomeFlowPrompt lists the welcome steps to show now: it is sent in
+// ConnectResponse.WelcomeFlow and as "welcome_step" WebSocket events after
+// each choice. Only the last step can have quick replies.
+type WelcomeFlowPrompt struct {
+	Flow  string        `json:"flow"`
+	Steps []WelcomeStep `json:"steps"`
+}
+
+// matches reports whether the flow targets the page at rawURL.
+func (f *WelcomeFlow) matches(rawURL string) bool {
+	if len(f.Pages) == 0 {
+		return true
+	}
+	pagePath := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		pagePath = u.Path
+	}
+	for _, pattern := range f.Pages {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(pagePath, prefix) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, pagePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// step returns step i with its ID and quick reply values defaulted.
+func (f *WelcomeFlow) step(i int) WelcomeStep {
+	step := f.Steps[i]
+	if step.ID == "" {
+		step.ID = fmt.Sprintf("step-%d", i)
+	}
+	step.QuickReplies = normalizeQuickReplies(step.QuickReplies)
+	return step
+}
+
+func (pp *PocketPing) welcomeFlow(name string) *WelcomeFlow {
+	for i := range pp.config.WelcomeFlows {
+		if pp.config.WelcomeFlows[i].Name == name {
+			return &pp.config.WelcomeFlows[i]
+		}
+	}
+	return nil
+}
+
+// startWelcomeFlow assigns a new session the first flow targeting its page.
+func (pp *PocketPing) startWelcomeFlow(session *Session) {
+	pageURL := ""
+	if session.Metadata != nil {
+		pageURL = session.Metadata.URL
+	}
+	for i := range pp.config.WelcomeFlows {
+		flow := &pp.config.WelcomeFlows[i]
+		if len(flow.Steps) > 0 && flow.matches(pageURL) {
+			session.WelcomeFlow = &WelcomeFlowState{Flow: flow.Name}
+			return
+		}
+	}
+}
+
+// welcomePrompt returns the steps to show from the session's current step up
+// to and including the next one with quick replies. Nil when the session has
+// no flow or has finished it.
+func (pp *PocketPing) welcomePrompt(session *Session) *WelcomeFlowPrompt {
+	state := session.WelcomeFlow
+	if state == nil || state.CompletedAt != nil {
+		return nil
+	}
+	flow := pp.welcomeFlow(state.Flow)
+	if flow == nil || state.Step >= len(flow.Steps) {
+		return nil
+	}
+
+	prompt := &WelcomeFlowPrompt{Flow: flow.Name}
+	for i := state.Step; i < len(flow.Steps); i++ {
+		step := flow.step(i)
+		prompt.Steps = append(prompt.Steps, step)
+		if len(step.QuickReplies) > 0 {
+			break
+		}
+	}
+	return prompt
+}
+
+// recordWelcomeChoice stores a welcome_choice event on the session and sends
+// the next steps over WebSocket. Other events are ignored.
+func (pp *PocketPing) recordWelcomeChoice(ctx context.Context, session *Session, event CustomEvent) error {
+	if event.Name != WelcomeChoiceEvent {
+		return nil
+	}
+	prompt := pp.welcomePrompt(session)
+	if prompt == nil {
+		return nil
+	}
+
+	stepID, _ := event.Data["step"].(string)
+	value, _ := event.Data["value"].(string)
+	waiting := prompt.Steps[len(prompt.Steps)-1]
+	if stepID != waiting.ID || len(waiting.QuickReplies) == 0 {
+		return nil
+	}
+
+	state := session.WelcomeFlow
+	if state.Choices == nil {
+		state.Choices = make(map[string]string)
+	}
+	state.Choices[stepID] = value
+	state.Step += len(prompt.Steps)
+
+	// The flow is complete once the remaining steps need no answer.
+	next := pp.welcomePrompt(session)
+	if next == nil || len(next.Steps[len(next.Steps)-1].QuickReplies) == 0 {
+		now := time.Now()
+		state.CompletedAt = &now
+	}
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return err
+	}
+
+	if next != nil {
+		pp.BroadcastToSession(session.ID, WebSocketEvent{
+			Type: "welcome_step",
+			Data: next,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pocketping
+
+import (
+	"context"
+	"testing"
+)
+
+func welcomeFlowConfig() Config {
+	return Config{
+		WelcomeMessage: "Hello!",
+		WelcomeFlows: []WelcomeFlow{
+			{
+				Name:  "pricing",
+				Pages: []string{"/pricing*"},
+				Steps: []WelcomeStep{
+					{Message: "Questions about plans?"},
+					{ID: "team", Message: "How big is your team?", QuickReplies: []QuickReply{{Label: "Just me"}, {Label: "2-10", Value: "small"}}},
+					{Message: "Thanks! An operator will join shortly."},
+				},
+			},
+			{Name: "docs", Pages: []string{"/docs/*"}, Steps: []WelcomeStep{{Message: "Need help with the docs?"}}},
+		},
+	}
+}
+
+func TestWelcomeFlowTargetsPages(t *testing.T) {
+	ctx := context.Background()
+	pp := New(welcomeFlowConfig())
+
+	cases := map[string]string{
+		"https://example.com/pricing":           "pricing",
+		"https://example.com/pricing/teams?x":   "pricing",
+		"https://example.com/docs/install":      "docs",
+		"https://example.com/blog/announcement": "",
+	}
+	for pageURL, wantFlow := range cases {
+		resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v-" + pageURL, Metadata: &SessionMetadata{URL: pageURL}})
+		if err != nil {
+			t.Fatalf("HandleConnect: %v", err)
+		}
+		if wantFlow == "" {
+			if resp.WelcomeFlow != nil || resp.WelcomeMessage != "Hello!" {
+				t.Errorf("%s: flow = %+v, message = %q; want plain welcome", pageURL, resp.WelcomeFlow, resp.WelcomeMessage)
+			}
+			continue
+		}
+		if resp.WelcomeFlow == nil || resp.WelcomeFlow.Flow != wantFlow || resp.WelcomeMessage != "" {
+			t.Errorf("%s: flow = %+v, message = %q; want %s", pageURL, resp.WelcomeFlow, resp.WelcomeMessage, wantFlow)
+		}
+	}
+}
+
+func TestWelcomeFlowChoices(t *testing.T) {
+	ctx := context.Background()
+	pp := New(welcomeFlowConfig())
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", Metadata: &SessionMetadata{URL: "https://example.com/pricing"}})
+	if err != nil {
+		t.Fatalf("HandleConnect: %v", err)
+	}
+
+	// The first prompt runs up to the first step with quick replies.
+	prompt := resp.WelcomeFlow
+	if len(prompt.Steps) != 2 || prompt.Steps[0].ID != "step-0" || prompt.Steps[1].ID != "team" {
+		t.Fatalf("prompt = %+v", prompt)
+	}
+	if got := prompt.Steps[1].QuickReplies[0].Value; got != "Just me" {
+		t.Errorf("defaulted value = %q, want label", got)
+	}
+
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(resp.SessionID, conn)
+
+	// A choice for another step is ignored.
+	_ = pp.HandleCustomEvent(ctx, resp.SessionID, CustomEvent{Name: WelcomeChoiceEvent, Data: map[string]interface{}{"step": "step-0", "value": "x"}})
+	if conn.count() != 0 {
+		t.Fatalf("events = %v, want none", conn.types())
+	}
+
+	if err := pp.HandleCustomEvent(ctx, resp.SessionID, CustomEvent{Name: WelcomeChoiceEvent, Data: map[string]interface{}{"step": "team", "value": "small"}}); err != nil {
+		t.Fatalf("HandleCustomEvent: %v", err)
+	}
+	if types := conn.types(); len(types) != 1 || types[0] != "welcome_step" {
+		t.Fatalf("events = %v, want [welcome_step]", types)
+	}
+	next := conn.events[0].Data.(*WelcomeFlowPrompt)
+	if len(next.Steps) != 1 || next.Steps[0].Message != "Thanks! An operator will join shortly." {
+		t.Errorf("next prompt = %+v", next)
+	}
+
+	session, _ := pp.GetSession(ctx, resp.SessionID)
+	state := session.WelcomeFlow
+	if state.Choices["team"] != "small" || state.CompletedAt == nil {
+		t.Errorf("state = %+v, want completed with team=small", state)
+	}
+
+	// A completed flow is not shown again on reconnect.
+	again, _ := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1", SessionID: resp.SessionID})
+	if again.WelcomeFlow != nil {
+		t.Errorf("reconnect flow = %+v, want nil", again.WelcomeFlow)
+	}
+}
@@ -0,0 +1,117 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotesNotSupported is returned by HandleWhisper and GetNotes when the
+// storage doesn't implement StorageWithNotes.
+var ErrNotesNotSupported = errors.New("storage does not support notes")
+
+// Note is an internal note on a session: an operator whisper shared with
+// the other operators, never shown to the visitor. CreatedAt places it in
+// the conversation's timeline.
+type Note struct {
+	ID        string `json:"id"`
+	SessionID string `json:"sessionId"`
+	Content   string `json:"content"`
+	// OperatorName is who wrote the note.
+	OperatorName string `json:"operatorName,omitempty"`
+	// SourceBridge is the bridge the note was written on, if any.
+	SourceBridge string    `json:"sourceBridge,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// WhisperCallback is called when an operator whispers in a session's topic
+// or thread: a message starting with "//", or /whisper <text>. Typically
+// calls PocketPing.HandleWhisper.
+type WhisperCallback func(ctx context.Context, sessionID, operatorName, sourceBridge, content string)
+
+// HandleWhisper stores an operator whisper as an internal note and shares it
+// with the other operators: it is posted on every bridge but the one it
+// was written on, and sent to the operator consoles as a "note" event. The
+// visitor never sees it.
+func (pp *PocketPing) HandleWhisper(ctx context.Context, sessionID, operatorName, sourceBridge, content string) (*Note, error) {
+	store, ok := pp.storage.(StorageWithNotes)
+	if !ok {
+		return nil, ErrNotesNotSupported
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrNoContent
+	}
+	if err := ValidateContent(content); err != nil {
+		return nil, err
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	note := &Note{
+		ID:           pp.generateID(),
+		SessionID:    session.ID,
+		Content:      content,
+		OperatorName: operatorName,
+		SourceBridge: sourceBridge,
+		CreatedAt:    time.Now(),
+	}
+	if err := store.SaveNote(ctx, note); err != nil {
+		return nil, err
+	}
+
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "note", Data: note})
+	caption := fmt.Sprintf("🤫 %s: %s", takeoverName(operatorName), content)
+	pp.notifyWatchers(ctx, session, caption, sourceBridge, operatorName)
+	source := bridgePlatform(sourceBridge)
+	pp.dispatchToBridges(session.ID, session.Region, func(b Bridge) {
+		notifier, ok := b.(BridgeWithNotify)
+		if !ok || (source != "" && bridgePlatform(b.Name()) == source) {
+			return
+		}
+		_ = pp.deliver(ctx, b, "Notify", session.ID, "", func(ctx context.Context) error {
+			return notifier.Notify(ctx, session, caption)
+		})
+	})
+	return note, nil
+}
+
+// GetNotes returns the internal notes of a session, oldest first.
+func (pp *PocketPing) GetNotes(ctx context.Context, sessionID string) ([]Note, error) {
+	store, ok := pp.storage.(StorageWithNotes)
+	if !ok {
+		return nil, ErrNotesNotSupported
+	}
+	notes, err := store.GetNotes(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].CreatedAt.Before(notes[j].CreatedAt) })
+	return notes, nil
+}
+
+// parseWhisper recognises a whisper, "// text" or /whisper text (also as
+// /whisper@bot), and returns its text.
+func parseWhisper(text string) (content string, ok bool) {
+	text = strings.TrimSpace(text)
+	if rest, found := strings.CutPrefix(text, "//"); found {
+		return strings.TrimSpace(rest), true
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	if command != "/whisper" {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(text, fields[0])), true
+}
@@ -0,0 +1,159 @@
+package pocketping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleWhisper(t *testing.T) {
+	ctx := context.Background()
+	telegram := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "telegram"}}
+	slack := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "slack"}}
+	pp := New(Config{Bridges: []Bridge{telegram, slack}})
+	session := newSession(ctx, t, pp)
+	conn := &mockWSConn{}
+	pp.RegisterWebSocket(session.ID, conn)
+
+	if _, err := pp.HandleWhisper(ctx, session.ID, "Bob", "telegram", "  "); !errors.Is(err, ErrNoContent) {
+		t.Errorf("empty whisper = %v, want ErrNoContent", err)
+	}
+	note, err := pp.HandleWhisper(ctx, session.ID, "Bob", "telegram", "VIP, refund without asking")
+	if err != nil {
+		t.Fatalf("HandleWhisper: %v", err)
+	}
+	if note.SessionID != session.ID || note.OperatorName != "Bob" || note.SourceBridge != "telegram" || note.CreatedAt.IsZero() {
+		t.Errorf("note = %+v", note)
+	}
+	pp.dispatcher.wait()
+
+	// Shared on the other bridges only
+	if len(telegram.notices) != 0 {
+		t.Errorf("echoed on the source bridge: %q", telegram.notices)
+	}
+	if len(slack.notices) != 1 || slack.notices[0] != "🤫 Bob: VIP, refund without asking" {
+		t.Errorf("slack notices = %q", slack.notices)
+	}
+
+	// Never shown to the visitor
+	if types := conn.types(); len(types) != 0 {
+		t.Errorf("widget events = %v", types)
+	}
+	if messages, _ := pp.storage.GetMessages(ctx, session.ID, "", 10); len(messages) != 0 {
+		t.Errorf("whisper saved as a message: %+v", messages)
+	}
+
+	notes, err := pp.GetNotes(ctx, session.ID)
+	if err != nil || len(notes) != 1 || notes[0].ID != note.ID {
+		t.Errorf("GetNotes = %+v, %v", notes, err)
+	}
+}
+
+func TestHandleWhisperUnsupportedStorage(t *testing.T) {
+	pp := New(Config{Storage: struct{ Storage }{NewMemoryStorage()}})
+	if _, err := pp.HandleWhisper(context.Background(), "s1", "Bob", "", "hi"); !errors.Is(err, ErrNotesNotSupported) {
+		t.Errorf("HandleWhisper = %v, want ErrNotesNotSupported", err)
+	}
+}
+
+func TestNotesPersist(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.log")
+	storage, err := NewPersistentMemoryStorage(path)
+	if err != nil {
+		t.Fatalf("NewPersistentMemoryStorage: %v", err)
+	}
+	if err := storage.SaveNote(ctx, &Note{ID: "n1", SessionID: "s1", Content: "call back at 5"}); err != nil {
+		t.Fatalf("SaveNote: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := NewPersistentMemoryStorage(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if notes, _ := reopened.GetNotes(ctx, "s1"); len(notes) != 1 || notes[0].Content != "call back at 5" {
+		t.Errorf("notes after reopen = %+v", notes)
+	}
+}
+
+func TestParseWhisper(t *testing.T) {
+	for text, want := range map[string]string{
+		"// VIP customer":            "VIP customer",
+		"//no space":                 "no space",
+		"/whisper check the logs":    "check the logs",
+		"/whisper@pocketping_bot hi": "hi",
+		"//":                         "",
+	} {
+		if got, ok := parseWhisper(text); !ok || got != want {
+			t.Errorf("parseWhisper(%q) = %q, %v; want %q", text, got, ok, want)
+		}
+	}
+	for _, text := range []string{"hello", "/whisperer hi", "/ticket", "see https://example.com"} {
+		if _, ok := parseWhisper(text); ok {
+			t.Errorf("parseWhisper(%q) matched", text)
+		}
+	}
+}
+
+func TestWebhookHandler_Whisper(t *testing.T) {
+	type call struct{ sessionID, operatorName, source, content string }
+	var calls []call
+	operatorMessages := 0
+	handler := NewWebhookHandler(WebhookConfig{
+		TelegramBotToken: "test-token",
+		OnWhisper: func(ctx context.Context, sessionID, operatorName, sourceBridge, content string) {
+			calls = append(calls, call{sessionID, operatorName, sourceBridge, content})
+		},
+		OnOperatorMessage: func(ctx context.Context, sessionID, content, operatorName, sourceBridge string, attachments []Attachment, replyToBridgeMessageID *int) {
+			operatorMessages++
+		},
+	})
+
+	payload := []byte(`{"message":{"message_id":1,"message_thread_id":456,"from":{"id":7,"first_name":"Bob"},"text":"// VIP, be nice"}}`)
+	handler.HandleTelegramWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/telegram", bytes.NewReader(payload)))
+
+	payload = []byte(`{"type":2,"channel_id":"T9","member":{"user":{"username":"bob"}},"data":{"name":"whisper","options":[{"name":"message","value":"on it"}]}}`)
+	handler.HandleDiscordWebhook()(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhooks/discord", bytes.NewReader(payload)))
+
+	want := []call{
+		{"456", "Bob", "telegram", "VIP, be nice"},
+		{"T9", "bob", "discord", "on it"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+	if operatorMessages != 0 {
+		t.Errorf("whispers reached the visitor as %d operator messages", operatorMessages)
+	}
+}
+
+func TestGatewayHandleMessage_Whisper(t *testing.T) {
+	var got []string
+	g := newTestGateway(DiscordGatewayConfig{
+		OnWhisper: func(ctx context.Context, sessionID, operatorName, sourceBridge, content string) {
+			got = []string{sessionID, operatorName, sourceBridge, content}
+		},
+		OnOperatorMessage: func(ctx context.Context, sid, c, on string, a []Attachment, r *int) {
+			t.Error("whisper reached the visitor as an operator message")
+		},
+	})
+	g.handleMessage(messageCreatePayload{ChannelID: "thread1", Content: "// VIP", Author: discordUser{ID: "u1", Username: "Eve"}})
+
+	want := []string{"thread1", "Eve", "discord", "VIP"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("OnWhisper got %q, want %q", got, want)
+	}
+}
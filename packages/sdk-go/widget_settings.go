@@ -0,0 +1,73 @@
+package pocketping
+
+// Widget launcher positions.
+const (
+	WidgetPositionBottomRight = "bottom-right"
+	WidgetPositionBottomLeft  = "bottom-left"
+)
+
+// WidgetSettings configures the widget from the server. Empty fields leave the
+// widget's own (init option) value in place.
+type WidgetSettings struct {
+	// PrimaryColor is the accent color (CSS color, e.g. "#6366f1").
+	PrimaryColor string `json:"primaryColor,omitempty"`
+	// TextColor is the text color used on the accent color.
+	TextColor string `json:"textColor,omitempty"`
+	// Theme is "light", "dark" or "auto".
+	Theme string `json:"theme,omitempty"`
+	// Position is WidgetPositionBottomRight or WidgetPositionBottomLeft.
+	Position string `json:"position,omitempty"`
+	// LauncherText is shown next to the launcher button.
+	LauncherText string `json:"launcherText,omitempty"`
+	// FileUploadEnabled shows or hides the attachment button.
+	FileUploadEnabled *bool `json:"fileUploadEnabled,omitempty"`
+	// Locale is the widget UI language (BCP 47, e.g. "fr" or "pt-BR").
+	Locale string `json:"locale,omitempty"`
+}
+
+// merge returns a copy of s with the fields set in override replacing its own.
+func (s WidgetSettings) merge(override *WidgetSettings) WidgetSettings {
+	if override == nil {
+		return s
+	}
+	if override.PrimaryColor != "" {
+		s.PrimaryColor = override.PrimaryColor
+	}
+	if override.TextColor != "" {
+		s.TextColor = override.TextColor
+	}
+	if override.Theme != "" {
+		s.Theme = override.Theme
+	}
+	if override.Position != "" {
+		s.Position = override.Position
+	}
+	if override.LauncherText != "" {
+		s.LauncherText = override.LauncherText
+	}
+	if override.FileUploadEnabled != nil {
+		enabled := *override.FileUploadEnabled
+		s.FileUploadEnabled = &enabled
+	}
+	if override.Locale != "" {
+		s.Locale = override.Locale
+	}
+	return s
+}
+
+// widgetSettings resolves the widget settings for a project: the project's
+// override applied over Config.WidgetSettings. Nil when nothing is configured.
+func (pp *PocketPing) widgetSettings(projectID string) *WidgetSettings {
+	base := pp.config.WidgetSettings
+	override := pp.config.ProjectWidgetSettings[projectID]
+	if projectID == "" {
+		override = nil
+	}
+	if base == nil && override == nil {
+		return nil
+	}
+
+	var settings WidgetSettings
+	settings = settings.merge(base).merge(override)
+	return &settings
+}
@@ -0,0 +1,67 @@
+package pocketping
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHandleConnectServerConfig(t *testing.T) {
+	ctx := context.Background()
+	disabled := false
+	pp := New(Config{
+		Storage: NewMemoryStorage(),
+		WidgetSettings: &WidgetSettings{
+			PrimaryColor: "#6366f1",
+			Position:     WidgetPositionBottomRight,
+			LauncherText: "Chat with us",
+			Locale:       "en",
+		},
+		ProjectWidgetSettings: map[string]*WidgetSettings{
+			"proj-fr": {Locale: "fr", LauncherText: "Discutons", FileUploadEnabled: &disabled},
+		},
+	})
+
+	resp, err := pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v1"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if resp.ServerConfig == nil || resp.ServerConfig.LauncherText != "Chat with us" || resp.ServerConfig.FileUploadEnabled != nil {
+		t.Errorf("unexpected default server config: %+v", resp.ServerConfig)
+	}
+
+	resp, err = pp.HandleConnect(ctx, ConnectRequest{VisitorID: "v2", ProjectID: "proj-fr"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	got := resp.ServerConfig
+	if got.Locale != "fr" || got.LauncherText != "Discutons" || got.PrimaryColor != "#6366f1" || got.Position != WidgetPositionBottomRight {
+		t.Errorf("expected project override merged over defaults, got %+v", got)
+	}
+	if got.FileUploadEnabled == nil || *got.FileUploadEnabled {
+		t.Errorf("expected file upload disabled for project, got %v", got.FileUploadEnabled)
+	}
+
+	// The defaults must not be mutated by a project merge.
+	if pp.config.WidgetSettings.Locale != "en" {
+		t.Errorf("defaults mutated: %+v", pp.config.WidgetSettings)
+	}
+}
+
+func TestHandleConnectNoServerConfig(t *testing.T) {
+	pp := New(Config{Storage: NewMemoryStorage()})
+
+	resp, err := pp.HandleConnect(context.Background(), ConnectRequest{VisitorID: "v1", ProjectID: "unknown"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if resp.ServerConfig != nil {
+		t.Errorf("expected no server config, got %+v", resp.ServerConfig)
+	}
+
+	data, _ := json.Marshal(resp)
+	if strings.Contains(string(data), "serverConfig") {
+		t.Errorf("expected serverConfig to be omitted, got %s", data)
+	}
+}
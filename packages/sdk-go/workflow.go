@@ -0,0 +1,312 @@
+package pocketping
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrWorkflowNotFound is returned by StartWorkflow for a name missing from
+// Config.Workflows.
+var ErrWorkflowNotFound = newError("workflow_not_found", http.StatusNotFound, "workflow not found")
+
+// WorkflowCompletedEvent is the custom event triggered when a session
+// finishes a workflow, with data {"workflow": <name>, "answers": <answers by
+// step ID>}.
+const WorkflowCompletedEvent = "workflow_completed"
+
+// DefaultWorkflowInvalidEmail is the prompt repeated after an answer to a
+// collect_email step that isn't an email address, when the step has no
+// Invalid prompt.
+const DefaultWorkflowInvalidEmail = "That doesn't look like an email address, could you check it?"
+
+// WorkflowStepType is what a WorkflowStep does.
+type WorkflowStepType string
+
+// Workflow step types.
+const (
+	// WorkflowStepMessage sends Prompt and goes on.
+	WorkflowStepMessage WorkflowStepType = "message"
+	// WorkflowStepAsk sends Prompt with its QuickReplies and waits for the
+	// visitor's next message. The answer is the chosen quick reply's value,
+	// or the message as typed.
+	WorkflowStepAsk WorkflowStepType = "ask"
+	// WorkflowStepCollectEmail sends Prompt and waits for an email address,
+	// asking again until one is valid. The address is set on the session's
+	// identity.
+	WorkflowStepCollectEmail WorkflowStepType = "collect_email"
+	// WorkflowStepRoute tags the session with Tags (see TagSession), so
+	// Config.TagMentions pings the right operators, and goes on.
+	WorkflowStepRoute WorkflowStepType = "route"
+)
+
+// WorkflowStep is one step of a workflow.
+type WorkflowStep struct {
+	// ID keys the step's answer in WorkflowState.Answers. Defaults to
+	// "step-<index>".
+	ID     string           `json:"id,omitempty"`
+	Type   WorkflowStepType `json:"type"`
+	Prompt string           `json:"prompt,omitempty"`
+	// QuickReplies are offered with an ask step's prompt.
+	QuickReplies []QuickReply `json:"quickReplies,omitempty"`
+	// Invalid is repeated after an invalid collect_email answer. Defaults
+	// to DefaultWorkflowInvalidEmail.
+	Invalid string `json:"invalid,omitempty"`
+	// Tags are the tags of a route step. "{id}" stands for the answer to
+	// ask step id, e.g. "{topic}", when it is one of the step's quick reply
+	// values; otherwise the tag is skipped.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Workflow is a conversation template: ordered steps such as collect
+// email, ask topic, route, run on a session with StartWorkflow.
+type Workflow struct {
+	// Name identifies the workflow in StartWorkflow and the session state.
+	Name  string
+	Steps []WorkflowStep
+}
+
+// WorkflowState is a session's progress through a workflow.
+type WorkflowState struct {
+	Name string `json:"name"`
+	// Step is the index of the step under way: the one waiting for an
+	// answer, or len(Steps) once completed.
+	Step int `json:"step"`
+	// Answers maps step IDs to the visitor's answers.
+	Answers     map[string]string `json:"answers,omitempty"`
+	StartedAt   time.Time         `json:"startedAt"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+}
+
+// step returns step i with its ID and quick reply values defaulted.
+func (w *Workflow) step(i int) WorkflowStep {
+	step := w.Steps[i]
+	if step.ID == "" {
+		step.ID = fmt.Sprintf("step-%d", i)
+	}
+	step.QuickReplies = normalizeQuickReplies(step.QuickReplies)
+	return step
+}
+
+func (pp *PocketPing) workflow(name string) *Workflow {
+	for i := range pp.config.Workflows {
+		if pp.config.Workflows[i].Name == name {
+			return &pp.config.Workflows[i]
+		}
+	}
+	return nil
+}
+
+// workflowRun collects what a workflow does until it waits for the visitor,
+// to be sent once the session is saved.
+type workflowRun struct {
+	prompts []SendMessageRequest
+	notices []string
+	done    bool
+}
+
+// StartWorkflow runs the Config.Workflows workflow called name on a
+// session, replacing any workflow under way. Its prompts are sent as
+// automated (SenderAI) messages, so they don't count as an operator's
+// first reply, and its progress is posted on the bridges.
+func (pp *PocketPing) StartWorkflow(ctx context.Context, sessionID, name string) (*Session, error) {
+	workflow := pp.workflow(name)
+	if workflow == nil || len(workflow.Steps) == 0 {
+		return nil, ErrWorkflowNotFound
+	}
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	session.Workflow = &WorkflowState{Name: workflow.Name, StartedAt: time.Now()}
+	run := &workflowRun{notices: []string{fmt.Sprintf("🧭 Workflow %q started", workflow.Name)}}
+	pp.runWorkflow(workflow, session, run)
+	if err := pp.finishWorkflowRun(ctx, session, run); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CancelWorkflow stops the workflow under way on a session, keeping its
+// answers so far. Visitor messages then go on as usual.
+func (pp *PocketPing) CancelWorkflow(ctx context.Context, sessionID string) (*Session, error) {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	state := session.Workflow
+	if state == nil || state.CompletedAt != nil {
+		return session, nil
+	}
+
+	now := time.Now()
+	state.CompletedAt = &now
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "session_update", Data: session})
+	pp.notifyBridgesNotice(ctx, session, fmt.Sprintf("🧭 Workflow %q cancelled", state.Name))
+	return session, nil
+}
+
+// advanceWorkflow takes a visitor message as the answer to the workflow
+// step waiting for one, and runs the workflow on. It reports whether the
+// message was taken, in which case it gets no AI reply.
+func (pp *PocketPing) advanceWorkflow(ctx context.Context, sessionID string, message *Message) bool {
+	session, err := pp.storage.GetSession(ctx, sessionID)
+	if err != nil || session == nil {
+		return false
+	}
+	state := session.Workflow
+	if state == nil || state.CompletedAt != nil {
+		return false
+	}
+	workflow := pp.workflow(state.Name)
+	if workflow == nil || state.Step >= len(workflow.Steps) {
+		return false
+	}
+
+	step := workflow.step(state.Step)
+	answer := strings.TrimSpace(message.Content)
+	run := &workflowRun{}
+	switch step.Type {
+	case WorkflowStepCollectEmail:
+		disposable, err := pp.checkIdentityEmail(answer)
+		if answer == "" || err != nil {
+			invalid := step.Invalid
+			if invalid == "" {
+				invalid = DefaultWorkflowInvalidEmail
+			}
+			run.prompts = append(run.prompts, SendMessageRequest{SessionID: session.ID, Content: invalid, Sender: SenderAI})
+			if err := pp.finishWorkflowRun(ctx, session, run); err != nil {
+				log.Printf("[PocketPing] Workflow %q step failed: %v", state.Name, err)
+			}
+			return true
+		}
+		if session.Identity == nil {
+			session.Identity = &UserIdentity{ID: session.VisitorID}
+		}
+		session.Identity.Email = answer
+		session.DisposableEmail = disposable
+	case WorkflowStepAsk:
+		if reply, ok := matchQuickReply(step.QuickReplies, answer); ok {
+			answer = reply.Value
+		}
+	default:
+		return false
+	}
+
+	if state.Answers == nil {
+		state.Answers = make(map[string]string)
+	}
+	state.Answers[step.ID] = answer
+	state.Step++
+	run.notices = append(run.notices, fmt.Sprintf("🧭 %s (%d/%d) %s: %s", state.Name, state.Step, len(workflow.Steps), step.ID, answer))
+	pp.runWorkflow(workflow, session, run)
+	if err := pp.finishWorkflowRun(ctx, session, run); err != nil {
+		log.Printf("[PocketPing] Workflow %q step failed: %v", state.Name, err)
+	}
+	return true
+}
+
+// runWorkflow runs the session's workflow from its current step until a
+// step waits for the visitor or the workflow completes.
+func (pp *PocketPing) runWorkflow(workflow *Workflow, session *Session, run *workflowRun) {
+	state := session.Workflow
+	for ; state.Step < len(workflow.Steps); state.Step++ {
+		step := workflow.step(state.Step)
+		switch step.Type {
+		case WorkflowStepAsk, WorkflowStepCollectEmail:
+			run.prompts = append(run.prompts, SendMessageRequest{SessionID: session.ID, Content: step.Prompt, Sender: SenderAI, QuickReplies: step.QuickReplies})
+			return
+		case WorkflowStepRoute:
+			var routed []string
+			for _, tag := range step.Tags {
+				if tag = normalizeTag(expandWorkflowTag(tag, workflow, state.Answers)); tag != "" && !session.HasTag(tag) {
+					session.Tags = append(session.Tags, tag)
+					routed = append(routed, tag)
+				}
+			}
+			if len(routed) > 0 {
+				run.notices = append(run.notices, fmt.Sprintf("🧭 %s routed to %s", state.Name, strings.Join(routed, ", ")))
+			}
+		default:
+			if step.Prompt != "" {
+				run.prompts = append(run.prompts, SendMessageRequest{SessionID: session.ID, Content: step.Prompt, Sender: SenderAI})
+			}
+		}
+	}
+
+	now := time.Now()
+	state.CompletedAt = &now
+	run.notices = append(run.notices, fmt.Sprintf("✅ Workflow %q completed", state.Name))
+	run.done = true
+}
+
+// finishWorkflowRun saves the session, then sends the run's prompts and
+// notices, and the completion event.
+func (pp *PocketPing) finishWorkflowRun(ctx context.Context, session *Session, run *workflowRun) error {
+	if err := pp.storage.UpdateSession(ctx, session); err != nil {
+		return err
+	}
+	pp.notifyOperators(session.ID, WebSocketEvent{Type: "session_update", Data: session})
+
+	for _, prompt := range run.prompts {
+		if _, err := pp.HandleMessage(ctx, prompt); err != nil {
+			return err
+		}
+	}
+	for _, notice := range run.notices {
+		pp.notifyBridgesNotice(ctx, session, notice)
+	}
+	if run.done {
+		state := session.Workflow
+		pp.TriggerEvent(ctx, session.ID, WorkflowCompletedEvent, map[string]interface{}{
+			"workflow": state.Name,
+			"answers":  state.Answers,
+		})
+	}
+	return nil
+}
+
+// expandWorkflowTag replaces the "{id}" references of tag with the answers
+// to those steps. Only answers that are one of the step's quick reply
+// values are used: the visitor could type anything else, so a tag
+// referencing one is dropped.
+func expandWorkflowTag(tag string, workflow *Workflow, answers map[string]string) string {
+	for i := range workflow.Steps {
+		step := workflow.step(i)
+		ref := "{" + step.ID + "}"
+		if !strings.Contains(tag, ref) {
+			continue
+		}
+		answer, ok := answers[step.ID]
+		if !ok || !isQuickReplyValue(step.QuickReplies, answer) {
+			return ""
+		}
+		tag = strings.ReplaceAll(tag, ref, answer)
+	}
+	if strings.ContainsAny(tag, "{}") {
+		return ""
+	}
+	return tag
+}
+
+func isQuickReplyValue(replies []QuickReply, value string) bool {
+	for _, reply := range replies {
+		if reply.Value == value {
+			return true
+		}
+	}
+	return false
+}
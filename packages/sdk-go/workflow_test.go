@@ -0,0 +1,153 @@
+package pocketping
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func intakeWorkflow() Workflow {
+	return Workflow{
+		Name: "intake",
+		Steps: []WorkflowStep{
+			{Type: WorkflowStepMessage, Prompt: "Hi! A few questions first."},
+			{ID: "email", Type: WorkflowStepCollectEmail, Prompt: "What's your email?"},
+			{ID: "topic", Type: WorkflowStepAsk, Prompt: "What is it about?", QuickReplies: []QuickReply{{Label: "Billing", Value: "billing"}, {Label: "Shipping", Value: "shipping"}}},
+			{Type: WorkflowStepRoute, Tags: []string{"{topic}", "intake"}},
+			{Type: WorkflowStepMessage, Prompt: "Thanks, an operator will be with you shortly."},
+		},
+	}
+}
+
+// sessionMessages returns the contents of a session's messages by sender.
+func sessionMessages(ctx context.Context, t *testing.T, pp *PocketPing, sessionID string, sender Sender) []string {
+	t.Helper()
+	messages, err := pp.storage.GetMessages(ctx, sessionID, "", 100)
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	var contents []string
+	for _, m := range messages {
+		if m.Sender == sender {
+			contents = append(contents, m.Content)
+		}
+	}
+	return contents
+}
+
+func TestWorkflowRunsToCompletion(t *testing.T) {
+	ctx := context.Background()
+	bridge := &notifyRecordingBridge{BaseBridge: BaseBridge{BridgeName: "notify"}}
+	pp := New(Config{Bridges: []Bridge{bridge}, Workflows: []Workflow{intakeWorkflow()}})
+	session := newSession(ctx, t, pp)
+	var completed []CustomEvent
+	pp.OnEvent(WorkflowCompletedEvent, func(event CustomEvent, session *Session) {
+		completed = append(completed, event)
+	})
+
+	if _, err := pp.StartWorkflow(ctx, session.ID, "unknown"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("unknown workflow = %v, want ErrWorkflowNotFound", err)
+	}
+	if _, err := pp.StartWorkflow(ctx, session.ID, "intake"); err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	sendVisitorMessage(t, pp, session.ID, "not an email")
+	sendVisitorMessage(t, pp, session.ID, "jane@example.com")
+	sendVisitorMessage(t, pp, session.ID, "Billing")
+
+	prompts := sessionMessages(ctx, t, pp, session.ID, SenderAI)
+	want := []string{"Hi! A few questions first.", "What's your email?", DefaultWorkflowInvalidEmail, "What is it about?", "Thanks, an operator will be with you shortly."}
+	if len(prompts) != len(want) {
+		t.Fatalf("prompts = %q, want %q", prompts, want)
+	}
+	for i := range want {
+		if prompts[i] != want[i] {
+			t.Errorf("prompt %d = %q, want %q", i, prompts[i], want[i])
+		}
+	}
+
+	updated, _ := pp.GetSession(ctx, session.ID)
+	state := updated.Workflow
+	if state == nil || state.CompletedAt == nil || state.Answers["email"] != "jane@example.com" || state.Answers["topic"] != "billing" {
+		t.Fatalf("workflow state = %+v", state)
+	}
+	if updated.Identity == nil || updated.Identity.Email != "jane@example.com" || updated.Identity.ID != updated.VisitorID {
+		t.Errorf("identity = %+v", updated.Identity)
+	}
+	if !updated.HasTag("billing") || !updated.HasTag("intake") {
+		t.Errorf("tags = %v", updated.Tags)
+	}
+	if updated.FirstResponseAt != nil {
+		t.Errorf("workflow prompts counted as a first response")
+	}
+	if len(completed) != 1 || completed[0].Data["workflow"] != "intake" {
+		t.Errorf("completion events = %+v", completed)
+	}
+
+	waitFor(t, "bridge notices", func() bool {
+		bridge.mu.Lock()
+		defer bridge.mu.Unlock()
+		return len(bridge.notices) == 5
+	})
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+	notices := []string{
+		`🧭 Workflow "intake" started`,
+		"🧭 intake (2/5) email: jane@example.com",
+		"🧭 intake (3/5) topic: billing",
+		"🧭 intake routed to billing, intake",
+		`✅ Workflow "intake" completed`,
+	}
+	for i := range notices {
+		if bridge.notices[i] != notices[i] {
+			t.Errorf("notice %d = %q, want %q", i, bridge.notices[i], notices[i])
+		}
+	}
+}
+
+func TestWorkflowCancel(t *testing.T) {
+	ctx := context.Background()
+	pp := New(Config{Workflows: []Workflow{intakeWorkflow()}})
+	session := newSession(ctx, t, pp)
+
+	if _, err := pp.StartWorkflow(ctx, session.ID, "intake"); err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	if _, err := pp.CancelWorkflow(ctx, session.ID); err != nil {
+		t.Fatalf("CancelWorkflow: %v", err)
+	}
+	sendVisitorMessage(t, pp, session.ID, "not an email")
+
+	// The message is no longer an answer, so no prompt follows it
+	if prompts := sessionMessages(ctx, t, pp, session.ID, SenderAI); len(prompts) != 2 {
+		t.Errorf("prompts = %q", prompts)
+	}
+	updated, _ := pp.GetSession(ctx, session.ID)
+	if updated.Workflow.CompletedAt == nil || updated.Workflow.Step != 1 || len(updated.Workflow.Answers) != 0 {
+		t.Errorf("workflow state = %+v", updated.Workflow)
+	}
+}
+
+func TestExpandWorkflowTag(t *testing.T) {
+	workflow := intakeWorkflow()
+	workflow.Steps = append(workflow.Steps, WorkflowStep{ID: "plan", Type: WorkflowStepAsk, QuickReplies: []QuickReply{{Label: "Pro", Value: "pro"}}})
+	answers := map[string]string{"topic": "billing", "plan": "pro", "email": "a@example.com"}
+	for tag, want := range map[string]string{
+		"{topic}":        "billing",
+		"{plan}-{topic}": "pro-billing",
+		"vip":            "vip",
+		"{missing}":      "",
+		"{email}":        "",
+	} {
+		if got := expandWorkflowTag(tag, &workflow, answers); got != want {
+			t.Errorf("expandWorkflowTag(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestExpandWorkflowTagTypedAnswer(t *testing.T) {
+	workflow := intakeWorkflow()
+	if got := expandWorkflowTag("{topic}", &workflow, map[string]string{"topic": "vip"}); got != "" {
+		t.Errorf("typed answer expanded to %q", got)
+	}
+}